@@ -0,0 +1,160 @@
+package markdown
+
+// Building document trees in programs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NewDocument returns the tree of a document consisting of blocks,
+// like the one returned by Parser.Parse, which may be rendered using
+// Render. Nodes of parsed documents may be spliced into it.
+func NewDocument(blocks ...*Node) *Node {
+	return &Node{Kind: LIST, Children: blocks}
+}
+
+// NewText returns a text node. Its text is written literally,
+// markup characters included.
+func NewText(text string) *Node {
+	return &Node{Kind: STR, Text: text}
+}
+
+// NewCode returns a code span.
+func NewCode(code string) *Node {
+	return &Node{Kind: CODE, Text: code}
+}
+
+// NewLineBreak returns a hard line break.
+func NewLineBreak() *Node {
+	return &Node{Kind: LINEBREAK}
+}
+
+// NewEmph returns emphasized inline content.
+func NewEmph(children ...*Node) *Node {
+	return &Node{Kind: EMPH, Children: children}
+}
+
+// NewStrong returns strongly emphasized inline content.
+func NewStrong(children ...*Node) *Node {
+	return &Node{Kind: STRONG, Children: children}
+}
+
+// NewLink returns a link to url, with an optional title, whose
+// text is label.
+func NewLink(url, title string, label ...*Node) *Node {
+	return &Node{Kind: LINK, URL: url, Title: title, Children: label}
+}
+
+// NewImage returns an image, whose alternative text is alt.
+func NewImage(url, title string, alt ...*Node) *Node {
+	return &Node{Kind: IMAGE, URL: url, Title: title, Children: alt}
+}
+
+// NewHeading returns a heading of the given level, which is
+// kept within the range from 1 to 6.
+func NewHeading(level int, children ...*Node) *Node {
+	switch {
+	case level < 1:
+		level = 1
+	case level > 6:
+		level = 6
+	}
+	return &Node{Kind: H1 + ElementKind(level-1), Children: children}
+}
+
+// NewParagraph returns a paragraph.
+func NewParagraph(children ...*Node) *Node {
+	return &Node{Kind: PARA, Children: children}
+}
+
+// NewPlain returns inline content as a block not written as a
+// paragraph, like the text of the items of tight lists.
+func NewPlain(children ...*Node) *Node {
+	return &Node{Kind: PLAIN, Children: children}
+}
+
+// NewCodeBlock returns a code block. A newline is appended to
+// code, unless it ends in one.
+func NewCodeBlock(code string) *Node {
+	if !strings.HasSuffix(code, "\n") {
+		code += "\n"
+	}
+	return &Node{Kind: VERBATIM, Text: code}
+}
+
+// NewBlockQuote returns a block quote.
+func NewBlockQuote(blocks ...*Node) *Node {
+	return &Node{Kind: BLOCKQUOTE, Children: []*Node{{Kind: LIST, Children: blocks}}}
+}
+
+// NewHorizontalRule returns a thematic break.
+func NewHorizontalRule() *Node {
+	return &Node{Kind: HRULE}
+}
+
+// NewListItem returns an item of a list, consisting of blocks.
+// Items of tight lists contain plain blocks instead of paragraphs,
+// see NewPlain.
+func NewListItem(blocks ...*Node) *Node {
+	return &Node{Kind: LISTITEM, Children: []*Node{{Kind: LIST, Children: blocks}}}
+}
+
+// NewList returns a bulleted, or an ordered list, numbered from 1,
+// of items made by NewListItem. The list is loose, with paragraphs
+// separated by blank lines, if an item contains a paragraph.
+func NewList(ordered bool, items ...*Node) *Node {
+	list := &Node{Kind: BULLETLIST, Text: "-", Children: items}
+	if ordered {
+		list.Kind, list.Text = ORDEREDLIST, "1."
+	}
+	for _, item := range items {
+		for _, c := range item.Children {
+			for _, b := range c.Children {
+				if b.Kind == PARA {
+					list.Loose = true
+				}
+			}
+		}
+	}
+	for i, item := range items {
+		item.Text = "-"
+		if ordered {
+			item.Text = strconv.Itoa(i+1) + "."
+		}
+		item.Loose = list.Loose
+	}
+	return list
+}
+
+// NewTable returns a table with an optional header row, and rows
+// of the body, made by NewTableRow. Align holds a character for
+// each column: 'l', 'c', or 'r', for left, centered, or right
+// aligned text; missing ones are taken as 'l'.
+func NewTable(align string, head *Node, body ...*Node) *Node {
+	columns := 0
+	for _, row := range append([]*Node{head}, body...) {
+		if row != nil && len(row.Children) > columns {
+			columns = len(row.Children)
+		}
+	}
+	if len(align) < columns {
+		align += strings.Repeat("l", columns-len(align))
+	}
+	t := &Node{Kind: TABLE, Children: []*Node{{Kind: TABLESEPARATOR, Text: align}}}
+	if head != nil {
+		t.Children = append(t.Children, &Node{Kind: TABLEHEAD, Children: []*Node{head}})
+	}
+	t.Children = append(t.Children, &Node{Kind: TABLEBODY, Children: body})
+	return t
+}
+
+// NewTableRow returns a row of a table, consisting of cells,
+// each containing inline content.
+func NewTableRow(cells ...[]*Node) *Node {
+	row := &Node{Kind: TABLEROW}
+	for _, c := range cells {
+		row.Children = append(row.Children, &Node{Kind: TABLECELL, Children: c})
+	}
+	return row
+}
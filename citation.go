@@ -0,0 +1,84 @@
+package markdown
+
+// Citations, like "[see @doe99, p. 33]"
+
+import (
+	"strings"
+)
+
+// A Citation refers to a work, as written within a citation like
+// "[see @doe99, pp. 33-35; -@smith04]"; see Extensions.Citations.
+type Citation struct {
+	Key            string // "doe99"
+	Prefix         string // text preceding the key, like "see"
+	Suffix         string // text following the key, like "pp. 33-35", without the comma
+	SuppressAuthor bool   // set by a "-" preceding the "@", as in "-@smith04"
+}
+
+// citationText is used as a predicate by the grammar, following
+// a '['; it advances *pos to the ']' closing a citation on the
+// same line, and reports whether there is one.
+func (p *yyParser) citationText(pos *int) bool {
+	s := p.Buffer[*pos:]
+	end := strings.IndexAny(s, "]\n")
+	if end == -1 || s[end] != ']' {
+		return false
+	}
+	if _, ok := parseCitations(s[:end]); !ok {
+		return false
+	}
+	*pos += end
+	return true
+}
+
+// parseCitations splits the text of a citation, between brackets,
+// into the citations separated by semicolons. Each must contain
+// a key, preceded by '@'.
+func parseCitations(s string) (cites []Citation, ok bool) {
+	for _, item := range strings.Split(s, ";") {
+		at := -1
+		for i := 0; i < len(item); i++ {
+			if item[i] == '@' && (i == 0 || item[i-1] == ' ' || item[i-1] == '-') {
+				at = i
+				break
+			}
+		}
+		if at == -1 {
+			return nil, false
+		}
+		var c Citation
+		prefix := item[:at]
+		if strings.HasSuffix(prefix, "-") {
+			c.SuppressAuthor = true
+			prefix = prefix[:len(prefix)-1]
+		}
+		c.Prefix = strings.TrimSpace(prefix)
+		rest := item[at+1:]
+		n := citationKeyLen(rest)
+		if n == 0 {
+			return nil, false
+		}
+		c.Key = rest[:n]
+		c.Suffix = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest[n:]), ","))
+		cites = append(cites, c)
+	}
+	return cites, true
+}
+
+// citationKeyLen returns the length of the key starting s. Keys
+// start with a letter, a digit, or '_', and may contain internal
+// punctuation, like "doe:99.a", but don't end with it.
+func citationKeyLen(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '_', c >= 0x80:
+			n = i + 1
+		case strings.IndexByte(":.#$%&-+?<>~/", c) != -1 && i > 0:
+		default:
+			return n
+		}
+	}
+	return n
+}
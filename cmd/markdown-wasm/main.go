@@ -0,0 +1,128 @@
+//go:build js && wasm
+
+// Command markdown-wasm makes the converter available to JavaScript,
+// e.g. for previews in the browser, when compiled to WebAssembly:
+//
+//	GOOS=js GOARCH=wasm go build -o markdown.wasm github.com/knieriem/markdown/cmd/markdown-wasm
+//
+// Once the module has been started, using the wasm_exec.js file that
+// comes with Go, it defines an object markdown with these functions:
+//
+//	markdown.toHTML(source, options)     // returns the HTML of a document
+//	markdown.toText(source, options)     // returns a document as plain text
+//	markdown.htmlBlocks(source, options) // returns an array of the HTML of each block
+//
+// The optional options object switches on extensions, whose names,
+// as listed by Extensions.List, are compared ignoring case, like
+// {smart: true, notes: true, table: true}. For HTML output, the keys
+// headingIDs, sourcePos, and email set the HTMLOptions of these
+// names, and rawHTML may be "pass", "drop", or "escape". Other keys
+// are ignored.
+package main
+
+import (
+	"strconv"
+	"strings"
+	"syscall/js"
+
+	"github.com/knieriem/markdown"
+)
+
+/* parsers are kept for reuse, by the extensions selected */
+var parsers = map[string]*markdown.Parser{}
+
+var rawHTMLPolicies = map[string]markdown.HTMLPolicy{
+	"pass":   markdown.PassHTML,
+	"drop":   markdown.DropHTML,
+	"escape": markdown.EscapeHTML,
+}
+
+// options converts a JavaScript options object.
+func options(v js.Value) (x markdown.Extensions, opt markdown.HTMLOptions) {
+	if v.Type() != js.TypeObject {
+		return
+	}
+	list := x.List()
+	keys := js.Global().Get("Object").Call("keys", v)
+	for i := 0; i < keys.Length(); i++ {
+		key := keys.Index(i).String()
+		val := v.Get(key)
+		switch key {
+		case "headingIDs":
+			opt.HeadingIDs = val.Truthy()
+		case "sourcePos":
+			opt.SourcePos = val.Truthy()
+		case "email":
+			opt.Email = val.Truthy()
+		case "rawHTML":
+			if val.Type() == js.TypeString {
+				x.RawHTML = rawHTMLPolicies[val.String()]
+			}
+		default:
+			for _, e := range list {
+				if strings.EqualFold(e.Name, key) {
+					x.Set(e.Name, val.Truthy())
+				}
+			}
+		}
+	}
+	return
+}
+
+// parser returns a parser for the extensions x.
+func parser(x markdown.Extensions) *markdown.Parser {
+	key := strconv.Itoa(int(x.RawHTML))
+	for _, e := range x.List() {
+		if e.Enabled {
+			key += " " + e.Name
+		}
+	}
+	p := parsers[key]
+	if p == nil {
+		p = markdown.NewParser(&x)
+		parsers[key] = p
+	}
+	return p
+}
+
+// convert calls fn with the source and the options passed
+// from JavaScript.
+func convert(fn func(src string, x markdown.Extensions, opt *markdown.HTMLOptions) any) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return js.Undefined()
+		}
+		opts := js.Undefined()
+		if len(args) > 1 {
+			opts = args[1]
+		}
+		x, opt := options(opts)
+		return fn(args[0].String(), x, &opt)
+	})
+}
+
+func main() {
+	m := js.Global().Get("Object").New()
+	m.Set("toHTML", convert(func(src string, x markdown.Extensions, opt *markdown.HTMLOptions) any {
+		var b strings.Builder
+		parser(x).Markdown(strings.NewReader(src), markdown.ToHTMLWithOptions(&b, opt))
+		return b.String()
+	}))
+	m.Set("toText", convert(func(src string, x markdown.Extensions, _ *markdown.HTMLOptions) any {
+		var b strings.Builder
+		parser(x).Markdown(strings.NewReader(src), markdown.ToText(&b))
+		return b.String()
+	}))
+	m.Set("htmlBlocks", convert(func(src string, x markdown.Extensions, opt *markdown.HTMLOptions) any {
+		blocks := markdown.HTMLBlocks(parser(x).Parse(strings.NewReader(src)), opt)
+		a := make([]any, len(blocks))
+		for i, b := range blocks {
+			a[i] = b
+		}
+		return a
+	}))
+	js.Global().Set("markdown", m)
+
+	/* the functions stay available while the program runs */
+	select {}
+}
@@ -2,49 +2,182 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/knieriem/markdown"
+	"io"
 	"log"
 	"os"
+	"strings"
 )
 
-var format = flag.String("t", "html", "output format")
+var format = flag.String("t", "html", "output format: html, groff-mm, text, opml, or ast-json")
+
+// An extFlag switches an extension on or off.
+type extFlag struct {
+	x    *markdown.Extensions
+	name string
+}
+
+func (f extFlag) String() string {
+	if f.x != nil {
+		for _, e := range f.x.List() {
+			if e.Name == f.name && e.Enabled {
+				return "true"
+			}
+		}
+	}
+	return "false"
+}
+
+func (f extFlag) Set(s string) error {
+	on := s == "true" || s == "1"
+	if !on && s != "false" && s != "0" {
+		return fmt.Errorf("invalid value %q", s)
+	}
+	f.x.Set(f.name, on)
+	return nil
+}
+
+func (f extFlag) IsBoolFlag() bool { return true }
+
+var rawHTMLPolicies = map[string]markdown.HTMLPolicy{
+	"pass":   markdown.PassHTML,
+	"drop":   markdown.DropHTML,
+	"escape": markdown.EscapeHTML,
+}
 
 func main() {
 	var opt markdown.Extensions
 	flag.BoolVar(&opt.Notes, "notes", false, "turn on footnote syntax")
 	flag.BoolVar(&opt.Smart, "smart", false, "turn on smart quotes, dashes, and ellipses")
 	flag.BoolVar(&opt.Dlists, "dlists", false, "support definitions lists")
+	flag.BoolVar(&opt.Mark, "mark", false, "support ==highlighted text==")
+	flag.BoolVar(&opt.Critic, "critic", false, "support CriticMarkup")
+	flag.BoolVar(&opt.HardWraps, "hardwraps", false, "turn line breaks within paragraphs into <br/>")
+	flag.BoolVar(&opt.ParenLists, "parenlists", false, "accept \"1)\" as ordered list marker")
+	flag.BoolVar(&opt.StrictAtx, "strictatx", false, "require a space after the #'s of headings")
+	flag.BoolVar(&opt.StrictEntities, "strictentities", false, "show unknown named entities as text")
+	flag.BoolVar(&opt.TOC, "toc", false, "replace [TOC] markers by tables of contents, and add ids to headings")
+	flag.BoolVar(&opt.Comments, "comments", false, "omit %% comments %% and <!--- comments --->")
+	flag.BoolVar(&opt.UnicodeSpaces, "unicodespaces", false, "treat no-break and other Unicode spaces as white space")
+	flag.IntVar(&opt.MaxLineLength, "maxline", 0, "take text beyond `n` bytes of a line literally, if positive")
+	include := flag.Bool("include", false, "expand {{include: path}} directives, reading files relative to the current directory")
+	print := flag.Bool("print", false, "optimize HTML output for printing")
+	email := flag.Bool("email", false, "write HTML for email clients")
+	sidenotes := flag.Bool("sidenotes", false, "write footnotes as sidenotes")
+	keepComments := flag.Bool("keepcomments", false, "write comments as HTML comments, with -comments")
+	shift := flag.Int("shift", 0, "add `n` to the levels of headings")
+	trace := flag.Bool("trace", false, "write the rules of the grammar tried to standard error")
+	rawHTML := flag.String("rawhtml", "pass", "`policy` for raw HTML in the input: pass, drop, or escape")
+
+	/* the other extensions get flags named like their fields */
+	for _, e := range opt.List() {
+		if name := strings.ToLower(e.Name); flag.Lookup(name) == nil {
+			flag.Var(extFlag{&opt, e.Name}, name, e.Description)
+		}
+	}
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [FILE]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [FILE...]\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 	flag.Parse()
 
-	r := os.Stdin
-	if flag.NArg() > 0 {
-		f, err := os.Open(flag.Arg(0))
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer f.Close()
-		r = f
+	policy, ok := rawHTMLPolicies[*rawHTML]
+	if !ok {
+		log.Fatalf("unknown raw HTML policy %q", *rawHTML)
 	}
+	opt.RawHTML = policy
 
+	// groff output has no use for HTML entities
+	if *format == "groff-mm" {
+		opt.DecodeEntities = true
+	}
+	if *include {
+		opt.Include = os.ReadFile
+	}
 	p := markdown.NewParser(&opt)
-
-	startPProf()
-	defer stopPProf()
+	if *trace {
+		p.SetTrace(os.Stderr)
+	}
 
 	w := bufio.NewWriter(os.Stdout)
 
+	var f markdown.Formatter
 	switch *format {
 	case "groff-mm":
-		p.Markdown(r, markdown.ToGroffMM(w))
+		f = markdown.ToGroffMM(w)
+	case "text":
+		f = markdown.ToText(w)
+	case "html", "opml", "ast-json":
+		f = markdown.ToHTMLWithOptions(w, &markdown.HTMLOptions{HeadingIDs: opt.TOC, Print: *print, Email: *email, Sidenotes: *sidenotes, Comments: *keepComments})
 	default:
-		p.Markdown(r, markdown.ToHTML(w))
+		log.Fatalf("unknown output format %q", *format)
+	}
+	if *shift != 0 {
+		f = markdown.ShiftHeadings(f, *shift)
+	}
+
+	startPProf()
+	err := convert(p, f, w, flag.Args())
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	stopPProf()
+	if err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+// convert converts the named files, or standard input, if
+// there are none, one after the other.
+func convert(p *markdown.Parser, f markdown.Formatter, w *bufio.Writer, names []string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	for _, name := range names {
+		var r io.Reader = os.Stdin
+		var file *os.File
+		if name != "" {
+			var err error
+			if file, err = os.Open(name); err != nil {
+				return err
+			}
+			r = file
+		}
+		var err error
+		switch *format {
+		case "opml":
+			o := &markdown.Outline{Summaries: true}
+			p.Markdown(r, markdown.ToOutline(o))
+			err = o.WriteOPML(w, name)
+		case "ast-json":
+			var b []byte
+			if b, err = json.MarshalIndent(p.Parse(r), "", "\t"); err == nil {
+				_, err = w.Write(append(b, '\n'))
+			}
+		default:
+			p.Markdown(r, f)
+		}
+		if file != nil {
+			file.Close()
+		}
+
+		if name == "" {
+			name = "<stdin>"
+		}
+		for _, d := range p.Diagnostics() {
+			fmt.Fprintf(os.Stderr, "%s:%v\n", name, d)
+		}
+		if err == nil {
+			err = p.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
 	}
-	w.Flush()
+	return nil
 }
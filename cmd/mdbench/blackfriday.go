@@ -0,0 +1,13 @@
+//go:build blackfriday
+
+package main
+
+import (
+	"github.com/russross/blackfriday/v2"
+)
+
+func init() {
+	engines = append(engines, engine{"russross/blackfriday", func(src []byte) []byte {
+		return blackfriday.Run(src)
+	}})
+}
@@ -0,0 +1,19 @@
+//go:build goldmark
+
+package main
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+)
+
+func init() {
+	engines = append(engines, engine{"yuin/goldmark", func(src []byte) []byte {
+		var buf bytes.Buffer
+		if err := goldmark.Convert(src, &buf); err != nil {
+			return nil
+		}
+		return buf.Bytes()
+	}})
+}
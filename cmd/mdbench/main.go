@@ -0,0 +1,119 @@
+// Command mdbench compares the speed, allocations, and output of
+// this package with other Go Markdown libraries, running them on
+// the same corpus of documents. Libraries other than this one are
+// included if the command is built with their tags:
+//
+//	go build -tags 'goldmark blackfriday'
+//
+// Usage: mdbench [-benchtime d] FILE...
+//
+// If no files are given, the Markdown test suite in ../../tests is used.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/knieriem/markdown"
+)
+
+// An engine converts Markdown to HTML.
+type engine struct {
+	name   string
+	render func(src []byte) []byte
+}
+
+// engines lists the libraries to compare; the first one, this
+// package, is the reference for output differences.
+var engines = []engine{
+	{"knieriem/markdown", render},
+}
+
+func render(src []byte) []byte {
+	var buf bytes.Buffer
+	markdown.NewParser(nil).Markdown(bytes.NewReader(src), markdown.ToHTML(&buf))
+	return buf.Bytes()
+}
+
+var benchtime = flag.Duration("benchtime", time.Second, "run each library for at least `d`")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [FILE...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	testing.Init()
+	flag.Set("test.benchtime", benchtime.String())
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files, _ = filepath.Glob("../../tests/md1.0.3/*.text")
+	}
+	if len(files) == 0 {
+		log.Fatal("no input files")
+	}
+	corpus := make([][]byte, len(files))
+	size := 0
+	for i, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		corpus[i] = b
+		size += len(b)
+	}
+
+	var ref [][]byte
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "library\tms/corpus\tMB/s\tallocs/corpus\tkB/corpus\toutput differs\t\n")
+	for _, e := range engines {
+		out := make([][]byte, len(corpus))
+		for i, src := range corpus {
+			out[i] = e.render(src)
+		}
+		differs := "-"
+		if ref == nil {
+			ref = out
+		} else {
+			n := 0
+			for i := range out {
+				if normalize(out[i]) != normalize(ref[i]) {
+					n++
+				}
+			}
+			differs = fmt.Sprintf("%d/%d", n, len(out))
+		}
+
+		r := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				for _, src := range corpus {
+					e.render(src)
+				}
+			}
+		})
+		mbs := float64(size) * float64(r.N) / r.T.Seconds() / 1e6
+		fmt.Fprintf(w, "%s\t%.2f\t%.1f\t%d\t%d\t%s\t\n", e.name,
+			float64(r.NsPerOp())/1e6, mbs, r.AllocsPerOp(), r.AllocedBytesPerOp()/1024, differs)
+	}
+	w.Flush()
+}
+
+// normalize reduces differences between libraries that don't
+// affect the rendered document, like white space between tags,
+// or the way void elements are closed.
+func normalize(html []byte) string {
+	s := strings.Join(strings.Fields(string(html)), " ")
+	s = strings.NewReplacer("> <", "><", " />", ">", "/>", ">").Replace(s)
+	return s
+}
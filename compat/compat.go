@@ -0,0 +1,59 @@
+/*
+Package markdown mirrors the API of the upstream package, so that
+programs written for it can switch to this fork by changing their
+import path to
+
+	"github.com/knieriem/markdown/compat"
+
+The Extensions type has the upstream fields, in the upstream order,
+so that even unkeyed composite literals keep compiling. All other
+names refer to those of the main package; the parser returned by
+NewParser, for instance, has the new methods, like Diagnostics, too.
+New programs should import the main package directly.
+*/
+package markdown
+
+import (
+	"github.com/knieriem/markdown"
+)
+
+// Markdown Extensions, as defined by the upstream package.
+type Extensions struct {
+	Smart        bool
+	Notes        bool
+	FilterHTML   bool
+	FilterStyles bool
+	Dlists       bool
+	Table        bool
+}
+
+type (
+	Parser    = markdown.Parser
+	Formatter = markdown.Formatter
+	Writer    = markdown.Writer
+)
+
+// NewParser creates an instance of a parser. It can be reused
+// so that stacks and buffers need not be allocated anew for
+// each Markdown call.
+func NewParser(x *Extensions) *Parser {
+	if x == nil {
+		return markdown.NewParser(nil)
+	}
+	return markdown.NewParser(&markdown.Extensions{
+		Smart:        x.Smart,
+		Notes:        x.Notes,
+		FilterHTML:   x.FilterHTML,
+		FilterStyles: x.FilterStyles,
+		Dlists:       x.Dlists,
+		Table:        x.Table,
+	})
+}
+
+func ToHTML(w Writer) Formatter {
+	return markdown.ToHTML(w)
+}
+
+func ToGroffMM(w Writer) Formatter {
+	return markdown.ToGroffMM(w)
+}
@@ -0,0 +1,142 @@
+package markdown
+
+// Container blocks, like Pandoc's fenced divs and MkDocs' admonitions
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A container holds the parts of a container block.
+type container struct {
+	class string
+	title string
+	body  string /* Markdown text of the contents, unindented */
+}
+
+// parseContainer parses a container block at the start of s. It
+// returns the number of bytes of s the block extends over, or 0.
+//
+// A fenced container starts with a line like "::: warning Title",
+// and ends with a line of at least three colons; containers may be
+// nested. An admonition starts with a line like `!!! note "Title"`,
+// and contains the following lines indented by four spaces.
+func parseContainer(s string) (c container, n int) {
+	line, s := cutLine(s)
+	switch {
+	case strings.HasPrefix(line, ":::"):
+		_, class, title := containerOpening(line, ':')
+		if class == "" {
+			return c, 0
+		}
+		c.class, c.title = class, title
+		n = len(line)
+		var body strings.Builder
+		depth := 0
+		for s != "" {
+			line, s = cutLine(s)
+			n += len(line)
+			if f, cl, _ := containerOpening(line, ':'); f >= 3 {
+				if cl != "" {
+					depth++
+				} else if depth == 0 {
+					c.body = body.String()
+					return c, n
+				} else {
+					depth--
+				}
+			}
+			body.WriteString(line)
+		}
+		return c, 0 /* not closed */
+
+	case strings.HasPrefix(line, "!!!"):
+		fence, class, title := containerOpening(line, '!')
+		if fence != 3 || class == "" {
+			return c, 0
+		}
+		if title == "" && !strings.Contains(line, `""`) {
+			r, size := utf8.DecodeRuneInString(class)
+			title = string(unicode.ToUpper(r)) + class[size:]
+		}
+		c.class, c.title = class, title
+		n = len(line)
+		var body strings.Builder
+		end := n
+		for s != "" {
+			line, s = cutLine(s)
+			blank := strings.TrimSpace(line) == ""
+			if !blank && !strings.HasPrefix(line, "    ") {
+				break
+			}
+			n += len(line)
+			if blank {
+				body.WriteString("\n")
+				continue
+			}
+			body.WriteString(line[4:])
+			end = n
+		}
+		c.body = strings.TrimRight(body.String(), "\n") + "\n"
+		return c, end
+	}
+	return c, 0
+}
+
+// containerOpening splits a fence line made of marker runes into the
+// length of the fence, the class and the title; quotes around the
+// title are removed.
+func containerOpening(line string, marker byte) (fence int, class, title string) {
+	for fence < len(line) && line[fence] == marker {
+		fence++
+	}
+	if fence < 3 {
+		return 0, "", ""
+	}
+	rest := strings.TrimSpace(line[fence:])
+	if i := strings.IndexAny(rest, " \t"); i != -1 {
+		class, title = rest[:i], strings.TrimSpace(rest[i:])
+	} else {
+		class = rest
+	}
+	class = strings.TrimPrefix(class, ".")
+	if len(title) >= 2 && title[0] == '"' && title[len(title)-1] == '"' {
+		title = title[1 : len(title)-1]
+	}
+	return fence, class, title
+}
+
+// cutLine returns the first line of s, including its line
+// ending, and the rest of s.
+func cutLine(s string) (line, rest string) {
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		return s[:i+1], s[i+1:]
+	}
+	return s, ""
+}
+
+// containerBlock is used as a predicate by the grammar; it
+// advances *pos past a container block.
+func (p *yyParser) containerBlock(pos *int) bool {
+	if !p.extension.Containers {
+		return false
+	}
+	_, n := parseContainer(p.Buffer[*pos:])
+	*pos += n
+	return n > 0
+}
+
+// mkContainer returns the element for text, which has been
+// matched by containerBlock. The body is kept as a RAW child,
+// which is parsed by processRawBlocks.
+func (p *yyParser) mkContainer(text string) *element {
+	c, _ := parseContainer(text)
+	el := p.mkElem(CONTAINER)
+	el.contents.str = c.class
+	el.contents.link = &link{title: c.title}
+	raw := p.mkString(c.body + "\n")
+	raw.key = RAW
+	el.children = raw
+	return el
+}
@@ -0,0 +1,165 @@
+package markdown
+
+// Diagnostics about questionable input
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Severity classifies diagnostics.
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// A Diagnostic describes a problem found in the input, like
+// a reference defined more than once. Diagnostics do not stop
+// the conversion.
+type Diagnostic struct {
+	Pos      Position
+	File     string // path of the included file containing Pos, if any
+	Severity Severity
+	Message  string
+}
+
+// String formats d like "line:col: severity: message", preceded
+// by "file:", if the problem is located in an included file.
+func (d Diagnostic) String() string {
+	var file string
+	if d.File != "" {
+		file = d.File + ":"
+	}
+	return file + strconv.Itoa(d.Pos.Line) + ":" + strconv.Itoa(d.Pos.Col) + ": " +
+		d.Severity.String() + ": " + d.Message
+}
+
+// Diagnostics returns the problems found during the most
+// recent call of Markdown, in the order of their positions.
+func (p *Parser) Diagnostics() []Diagnostic {
+	return p.diags
+}
+
+func (p *Parser) diagnose(pos Position, sev Severity, msg string) {
+	p.diags = append(p.diags, Diagnostic{Pos: pos, Severity: sev, Message: msg})
+}
+
+// A DuplicatePolicy selects which of several definitions
+// of the same reference label is used.
+type DuplicatePolicy int
+
+const (
+	FirstWins       DuplicatePolicy = iota // use the first definition, warn about the others
+	LastWins                               // use the last definition, warn about the others
+	DuplicatesError                        // use the first definition, report the others as errors
+)
+
+// checkReference reports a reference block that repeats the
+// label of a previous one.
+func (p *Parser) checkReference(ref *element, src string, span Span, defs map[string]Position) {
+	key, ok := p.yy.extension.labelKey(ref.contents.link.label)
+	if !ok {
+		return
+	}
+	first, dup := defs[key]
+	if !dup {
+		defs[key] = span.Start
+		return
+	}
+	sev := Warning
+	if p.yy.extension.DuplicateRefs == DuplicatesError {
+		sev = Error
+	}
+	p.diagnose(span.Start, sev, "reference "+refLabel(src[span.Start.off:span.End.off+1])+
+		" already defined at line "+strconv.Itoa(first.Line))
+}
+
+// checkNotes reports references to undefined notes, with the
+// given labels, found within a block.
+func (p *Parser) checkNotes(labels []string, lines *lineCounter, span Span) {
+	text := lines.src[span.Start.off : span.End.off+1]
+	i := 0
+	for _, label := range labels {
+		pos := span.Start
+		ref := "[^" + label + "]"
+		if j := strings.Index(text[i:], ref); j != -1 {
+			i += j
+			pos = lines.posAfter(span.Start, span.Start.off+i)
+			i += len(ref)
+		}
+		p.diagnose(pos, Warning, "undefined note "+ref)
+	}
+}
+
+// checkTable reports the problems found with the rows of the
+// tables within a block. If the block is a table, the problems are
+// reported at the lines of their rows, and its caption label, if
+// found in labels already, is reported as well.
+func (p *Parser) checkTable(tree *element, rows []tableProblem, lines *lineCounter, span Span, labels map[string]Position) {
+	if tree.key != TABLE {
+		for _, r := range rows {
+			p.diagnose(span.Start, Warning, r.msg)
+		}
+		return
+	}
+	var starts []Position /* Positions of the non-blank lines. */
+	text := lines.src[span.Start.off : span.End.off+1]
+	pos := span.Start
+	for i := 0; i < len(text); {
+		n := strings.IndexByte(text[i:], '\n') + 1
+		if n == 0 {
+			n = len(text) - i
+		}
+		if strings.TrimSpace(text[i:i+n]) != "" {
+			pos = lines.posAfter(pos, span.Start.off+i)
+			starts = append(starts, pos)
+		}
+		i += n
+	}
+	var dup *Diagnostic /* A caption label used before. */
+	if c := tableCaption(tree); c != nil {
+		if label := captionLabel(c); label != "" {
+			/* a leading caption looks like "[Caption][label]", a trailing one is the last line */
+			pos = starts[len(starts)-1]
+			if first := strings.TrimSpace(text[:strings.IndexByte(text+"\n", '\n')]); strings.HasPrefix(first, "[") && strings.HasSuffix(first, "]") {
+				pos = span.Start
+			}
+			if prev, ok := labels[label]; ok {
+				dup = &Diagnostic{Pos: pos, Severity: Warning, Message: "table label \"" + label + "\" already used at line " + strconv.Itoa(prev.Line)}
+			} else {
+				labels[label] = pos
+			}
+		}
+	}
+	if dup != nil && dup.Pos == span.Start {
+		p.diags = append(p.diags, *dup)
+		dup = nil
+	}
+	for _, r := range rows {
+		pos := span.Start
+		if r.line < len(starts) {
+			pos = starts[r.line]
+		}
+		p.diagnose(pos, Warning, r.msg)
+	}
+	if dup != nil {
+		p.diags = append(p.diags, *dup)
+	}
+}
+
+// refLabel returns the bracketed label of a reference definition.
+func refLabel(def string) string {
+	if i := strings.Index(def, "]:"); i != -1 {
+		def = def[:i+1]
+	}
+	return strings.TrimLeft(def, " ")
+}
@@ -8,17 +8,20 @@ Usage example:
 	import (
 		"github.com/knieriem/markdown"
 		"os"
-		"bufio"
 	)
 
 	func main() {
-		p := markdown.NewParser(&markdown.Extensions{Smart: true})
-
-		w := bufio.NewWriter(os.Stdout)
-		p.Markdown(os.Stdin, markdown.ToHTML(w))
-		w.Flush()
+		p := markdown.NewParser(markdown.WithSmart())
+		p.Markdown(os.Stdin, markdown.ToHTML(os.Stdout))
 	}
 
+Formatters write to any io.Writer; output is buffered, unless the
+writer is a Writer, like a *bufio.Writer or *bytes.Buffer, and
+flushed at the end of each document.
+
+Options are passed to NewParser as the results of the With
+functions, or as an *Extensions, which sets all extensions at once.
+
 [1]: https://github.com/jgm/peg-markdown/
 */
 package markdown
@@ -0,0 +1,153 @@
+package markdown
+
+// Documents owning the nodes of their trees
+
+import (
+	"io"
+	"sync"
+)
+
+/*
+The elements built while parsing live in the parser's elemHeap,
+and are overwritten by the blocks that follow, and by the next
+document. A Document holds copies of them, as Nodes, which are
+allocated in rows of a nodeArena owned by the document. Released
+arenas are pooled, and handed to the documents parsed next.
+*/
+
+// A Document is a parsed document, as returned by
+// Parser.ParseDocument. Its nodes do not depend on the parser,
+// and stay valid while the parser is reused, until Release is
+// called.
+type Document struct {
+	Root *Node // a node of kind LIST, like the one returned by Parser.Parse
+
+	arena *nodeArena
+	spans map[*Node]Span /* Source spans of the top-level blocks. */
+}
+
+// ParseDocument parses a document, like Parse, and returns it
+// together with the memory holding its nodes, which is reused for
+// other documents once Release has been called.
+func (p *Parser) ParseDocument(src io.Reader) *Document {
+	a, _ := arenaPool.Get().(*nodeArena)
+	if a == nil {
+		a = new(nodeArena)
+	}
+	f := &docOut{treeOut: treeOut{arena: a, p: p}, spans: make(map[*Node]Span)}
+	f.root = a.node()
+	f.root.Kind = LIST
+	p.Markdown(src, f)
+	return &Document{Root: f.root, arena: a, spans: f.spans}
+}
+
+// docOut builds the tree of a Document, and records the
+// spans of its blocks.
+type docOut struct {
+	treeOut
+	span  Span
+	spans map[*Node]Span
+}
+
+func (f *docOut) setSpan(span Span) {
+	f.span = span
+}
+
+func (f *docOut) FormatBlock(tree *element) {
+	i := len(f.root.Children)
+	f.treeOut.FormatBlock(tree)
+	for _, n := range f.root.Children[i:] {
+		f.spans[n] = f.span
+	}
+}
+
+// Release returns the memory of the document's nodes for reuse.
+// Neither the Root of the document, nor any node reachable from it,
+// nor a slice of children, may be used after Release; nodes that
+// need to be kept must be copied before. Calling Release again has
+// no effect. Documents that are not released are garbage collected
+// as usual.
+func (d *Document) Release() {
+	if d.arena == nil {
+		return
+	}
+	d.arena.reset()
+	arenaPool.Put(d.arena)
+	d.arena = nil
+	d.Root = nil
+	d.spans = nil
+}
+
+var arenaPool sync.Pool
+
+const arenaRowSize = 256
+
+// A nodeArena allocates nodes, and slices of children, in rows.
+type nodeArena struct {
+	rows  [][]Node
+	iRow  int
+	row   []Node /* Unused part of rows[iRow]. */
+	lists [][]*Node
+	iList int
+	list  []*Node /* Unused part of lists[iList]. */
+}
+
+// node returns a zeroed node.
+func (a *nodeArena) node() *Node {
+	if len(a.row) == 0 {
+		if a.rows != nil {
+			a.iRow++
+		}
+		if a.iRow == len(a.rows) {
+			a.rows = append(a.rows, make([]Node, arenaRowSize))
+		}
+		a.row = a.rows[a.iRow]
+	}
+	n := &a.row[0]
+	a.row = a.row[1:]
+	return n
+}
+
+// children returns an empty slice with capacity for n nodes.
+func (a *nodeArena) children(n int) []*Node {
+	if n > arenaRowSize/4 {
+		return make([]*Node, 0, n)
+	}
+	if len(a.list) < n {
+		if a.lists != nil {
+			a.iList++
+		}
+		if a.iList == len(a.lists) {
+			a.lists = append(a.lists, make([]*Node, arenaRowSize))
+		}
+		a.list = a.lists[a.iList]
+	}
+	s := a.list[:0:n]
+	a.list = a.list[n:]
+	return s
+}
+
+// reset clears the nodes handed out, so that the strings they
+// refer to may be collected, and makes all rows available again.
+func (a *nodeArena) reset() {
+	for i := 0; i <= a.iRow && i < len(a.rows); i++ {
+		r := a.rows[i]
+		for j := range r {
+			r[j] = Node{}
+		}
+	}
+	for i := 0; i <= a.iList && i < len(a.lists); i++ {
+		l := a.lists[i]
+		for j := range l {
+			l[j] = nil
+		}
+	}
+	a.iRow, a.iList = 0, 0
+	a.row, a.list = nil, nil
+	if a.rows != nil {
+		a.row = a.rows[0]
+	}
+	if a.lists != nil {
+		a.list = a.lists[0]
+	}
+}
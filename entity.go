@@ -0,0 +1,46 @@
+package markdown
+
+// Validation and decoding of character entities
+
+import (
+	"html"
+	"strings"
+)
+
+// mkEntity returns the element for an entity like "&amp;" or
+// "&#38;", depending on the options StrictEntities and
+// DecodeEntities.
+func (p *yyParser) mkEntity(text string) *element {
+	if p.extension.StrictEntities || p.extension.DecodeEntities {
+		s, ok := decodeEntity(text)
+		switch {
+		case !ok && p.extension.StrictEntities:
+			// shown as literal text, its ampersand escaped
+			return p.mkString(text)
+		case ok && p.extension.DecodeEntities:
+			return p.mkString(s)
+		}
+	}
+	el := p.mkString(text)
+	el.key = HTML
+	return el
+}
+
+// decodeEntity returns the text an entity stands for, and whether
+// the entity is valid: numeric, or named in the HTML5 entity table.
+func decodeEntity(text string) (s string, ok bool) {
+	if strings.HasPrefix(text, "&#") {
+		return html.UnescapeString(text), true
+	}
+	s = html.UnescapeString(text)
+
+	// For unknown names, html.UnescapeString may still decode a
+	// prefix that is a legacy entity without semicolon, like
+	// "&amp" in "&ampx;", leaving the rest of the name, and the
+	// semicolon. No entity's value contains a semicolon, except
+	// that of "&semi;".
+	if s == text || len(s) > 1 && strings.HasSuffix(s, ";") {
+		return "", false
+	}
+	return s, true
+}
@@ -0,0 +1,45 @@
+package markdown
+
+// Listing the extensions that can be switched on and off
+
+import (
+	"reflect"
+)
+
+// An ExtensionInfo describes an extension that is switched on or
+// off by a boolean field of Extensions.
+type ExtensionInfo struct {
+	Name        string // name of the field, like "Smart"
+	Description string // a short description, like "typographic quotes, dashes, and ellipses"
+	Enabled     bool
+}
+
+// List returns the extensions that can be switched on and off,
+// in the order of the fields of Extensions, with their state in x,
+// so that programs, like command line tools or configuration
+// dialogs, can offer them without knowing each one. Extensions
+// configured by values of other types, like TableRows, are not
+// listed.
+func (x *Extensions) List() []ExtensionInfo {
+	v := reflect.ValueOf(x).Elem()
+	t := v.Type()
+	var list []ExtensionInfo
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if d, ok := f.Tag.Lookup("desc"); ok && f.Type.Kind() == reflect.Bool {
+			list = append(list, ExtensionInfo{f.Name, d, v.Field(i).Bool()})
+		}
+	}
+	return list
+}
+
+// Set switches the extension with the given name, as returned by
+// List, on or off, and reports whether there is such an extension.
+func (x *Extensions) Set(name string, on bool) bool {
+	f, ok := reflect.TypeOf(x).Elem().FieldByName(name)
+	if !ok || f.Type.Kind() != reflect.Bool || f.Tag.Get("desc") == "" {
+		return false
+	}
+	reflect.ValueOf(x).Elem().FieldByIndex(f.Index).SetBool(on)
+	return true
+}
@@ -0,0 +1,33 @@
+package markdown
+
+// Figures: images forming paragraphs of their own
+
+// figure returns a paragraph consisting of an image, which may be
+// followed by a caption on the next lines, as a FIGURE element. Its
+// children are the image and, if present, a LIST of the inline
+// elements of the caption. Other paragraphs are returned unchanged.
+func (p *yyParser) figure(para *element) *element {
+	img := para.children
+	if img == nil || img.key != IMAGE {
+		return para
+	}
+	caption := img.next
+	newline := false
+	for caption != nil && (caption.key == SPACE || caption.key == LINEBREAK) {
+		if caption.key == LINEBREAK || caption.contents.str == "\n" {
+			newline = true
+		}
+		caption = caption.next
+	}
+	if caption != nil && !newline {
+		/* text follows on the line of the image */
+		return para
+	}
+	img.next = nil
+	if caption != nil {
+		img.next = p.mkElem(LIST)
+		img.next.children = caption
+	}
+	para.key = FIGURE
+	return para
+}
@@ -0,0 +1,101 @@
+package markdown
+
+// Formatting parts of a document, for editors
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// A TextEdit replaces the source text between the byte
+// offsets Start and End with NewText. Offsets refer to the
+// text as passed to FormatRange, with tabs not expanded.
+type TextEdit struct {
+	Start, End int
+	NewText    string
+}
+
+// FormatRange reformats the top-level blocks of src that overlap the
+// byte range from start up to end, or that contain start, if the range
+// is empty, as needed by an editor's "format selection" command. The
+// text of each of these blocks, consisting of whole lines without the
+// final line ending, is passed to format, which returns its
+// replacement. The returned edits turn src into the formatted text;
+// they are reduced to the bytes that actually change, and ordered by
+// their offsets.
+func (p *Parser) FormatRange(src string, start, end int, format func(block string) string) (edits []TextEdit) {
+	x := p.Index(strings.NewReader(src))
+
+	/*
+	 * Blocks are taken as whole lines of src, while spans exclude trailing white space.
+	 * A line ends before its "\n", "\r\n", or "\r"; a byte order mark is not part of the first line.
+	 */
+	lineStarts := []int{0}
+	if strings.HasPrefix(src, utf8BOM) {
+		lineStarts[0] = len(utf8BOM)
+	}
+	var lineEnds []int
+	for i := 0; i < len(src); i++ {
+		switch {
+		case src[i] == '\r' && i+1 < len(src) && src[i+1] == '\n':
+			lineEnds = append(lineEnds, i)
+			i++
+		case src[i] == '\n', src[i] == '\r':
+			lineEnds = append(lineEnds, i)
+		default:
+			continue
+		}
+		lineStarts = append(lineStarts, i+1)
+	}
+	lineEnd := func(line int) int {
+		if line <= len(lineEnds) {
+			return lineEnds[line-1]
+		}
+		return len(src)
+	}
+
+	for _, b := range x.Blocks {
+		if b.Span.Start.Line > len(lineStarts) {
+			break
+		}
+		bs := lineStarts[b.Span.Start.Line-1]
+		be := lineEnd(b.Span.End.Line)
+		if start == end {
+			if start < bs || start > be {
+				continue
+			}
+		} else if end <= bs || start >= be {
+			continue
+		}
+		if e, ok := minimalEdit(src[bs:be], format(src[bs:be])); ok {
+			e.Start += bs
+			e.End += bs
+			edits = append(edits, e)
+		}
+	}
+	return edits
+}
+
+// minimalEdit returns the edit turning old into new, omitting their
+// common prefix and suffix. Ok is false if they are equal.
+func minimalEdit(old, new string) (e TextEdit, ok bool) {
+	if old == new {
+		return e, false
+	}
+	i := 0
+	for i < len(old) && i < len(new) && old[i] == new[i] {
+		i++
+	}
+	/* keep multi-byte characters intact */
+	for i > 0 && (i < len(old) && !utf8.RuneStart(old[i]) || i < len(new) && !utf8.RuneStart(new[i])) {
+		i--
+	}
+	j := 0
+	for j < len(old)-i && j < len(new)-i && old[len(old)-1-j] == new[len(new)-1-j] {
+		j++
+	}
+	for j > 0 && (!utf8.RuneStart(old[len(old)-j]) || !utf8.RuneStart(new[len(new)-j])) {
+		j--
+	}
+	return TextEdit{i, len(old) - j, new[i : len(new)-j]}, true
+}
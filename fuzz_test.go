@@ -0,0 +1,50 @@
+package markdown
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fuzzExtensions returns extensions with the boolean fields of
+// Extensions set according to the bits of flags, in field order.
+func fuzzExtensions(flags uint64) *Extensions {
+	x := new(Extensions)
+	v := reflect.ValueOf(x).Elem()
+	bit := uint(0)
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.Bool {
+			f.SetBool(flags&(1<<bit) != 0)
+			bit++
+		}
+	}
+	return x
+}
+
+// FuzzParse parses and formats arbitrary input, using combinations
+// of extensions. The inputs of the conformance tests, and inputs
+// found to crash the parser, stored in testdata/fuzz, form the
+// seed corpus.
+func FuzzParse(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join("tests", "*", "*.text"))
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b, uint64(0))
+		f.Add(b, ^uint64(0))
+	}
+	f.Fuzz(func(t *testing.T, input []byte, flags uint64) {
+		if len(input) > 4096 {
+			/* keep the pathological backtracking of some inputs in bounds */
+			return
+		}
+		p := NewParser(fuzzExtensions(flags), WithMaxDepth(16))
+		var buf bytes.Buffer
+		p.Markdown(bytes.NewReader(input), ToHTML(&buf))
+		p.Markdown(bytes.NewReader(input), ToText(&buf))
+	})
+}
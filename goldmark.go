@@ -0,0 +1,552 @@
+//go:build goldmark
+
+package markdown
+
+// Conversion into and from goldmark's AST, built with tag "goldmark"
+
+import (
+	"strconv"
+	"strings"
+
+	gast "github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// A GoldmarkDocument receives a document converted into
+// the AST of github.com/yuin/goldmark.
+type GoldmarkDocument struct {
+	Node   *gast.Document
+	Source []byte // text the segments of the nodes refer to
+}
+
+type goldmarkOut struct {
+	doc *GoldmarkDocument
+
+	notes     []*element       /* Bodies of notes, in the order of their numbers. */
+	noteNums  map[*element]int /* Numbers of the notes, by body. */
+	noteLinks [][]*extast.FootnoteLink
+}
+
+// ToGoldmark returns a formatter that converts the blocks of a
+// document into goldmark nodes, appending them to d.Node, which is
+// created if nil. The result can be rendered by goldmark's renderers,
+// using d.Source as source, if the extensions Table, Strikethrough,
+// DefinitionList, and Footnote are enabled. Elements goldmark has no
+// counterpart for are reduced to their content; highlighted text,
+// for instance, loses its highlighting, and tables their captions.
+func ToGoldmark(d *GoldmarkDocument) Formatter {
+	return &goldmarkOut{doc: d}
+}
+
+func (g *goldmarkOut) FormatBlock(tree *element) {
+	if g.doc.Node == nil {
+		g.doc.Node = gast.NewDocument()
+	}
+	g.elist(g.doc.Node, tree)
+}
+
+func (g *goldmarkOut) Finish() {
+	if g.doc.Node == nil {
+		g.doc.Node = gast.NewDocument()
+	}
+	if len(g.notes) > 0 {
+		list := extast.NewFootnoteList()
+		/* notes referenced from within notes are appended while converting */
+		for i := 0; i < len(g.notes); i++ {
+			fn := extast.NewFootnote([]byte(strconv.Itoa(i + 1)))
+			fn.Index = i + 1
+			g.elist(fn, g.notes[i])
+			back := extast.NewFootnoteBacklink(fn.Index)
+			back.RefCount = len(g.noteLinks[i])
+			if last := fn.LastChild(); last != nil && (last.Kind() == gast.KindParagraph || last.Kind() == gast.KindTextBlock) {
+				last.AppendChild(last, back)
+			} else {
+				fn.AppendChild(fn, back)
+			}
+			list.AppendChild(list, fn)
+		}
+		for _, links := range g.noteLinks {
+			for _, l := range links {
+				l.RefCount = len(links)
+			}
+		}
+		list.Count = len(g.notes)
+		g.doc.Node.AppendChild(g.doc.Node, list)
+	}
+	g.notes = nil
+	g.noteNums = nil
+	g.noteLinks = nil
+}
+
+func (g *goldmarkOut) elist(parent gast.Node, list *element) {
+	for ; list != nil; list = list.next {
+		g.elem(parent, list)
+	}
+}
+
+// elem appends the node converted from elt to parent.
+func (g *goldmarkOut) elem(parent gast.Node, elt *element) {
+	var n gast.Node
+
+	switch elt.key {
+	case SPACE, STR:
+		n = g.text(elt.contents.str)
+	case LINEBREAK:
+		t := gast.NewTextSegment(g.segment(""))
+		t.SetHardLineBreak(true)
+		n = t
+	case ELLIPSIS:
+		n = g.text("…")
+	case EMDASH:
+		n = g.text("—")
+	case ENDASH:
+		n = g.text("–")
+	case APOSTROPHE:
+		n = g.text("’")
+	case SINGLEQUOTED:
+		parent.AppendChild(parent, g.text("‘"))
+		g.elist(parent, elt.children)
+		n = g.text("’")
+	case DOUBLEQUOTED:
+		parent.AppendChild(parent, g.text("“"))
+		g.elist(parent, elt.children)
+		n = g.text("”")
+	case CODE:
+		n = gast.NewCodeSpan()
+		n.AppendChild(n, g.text(elt.contents.str))
+	case HTML:
+		s := elt.contents.str
+		if v, ok := decodeEntity(s); ok && strings.HasPrefix(s, "&") {
+			n = g.text(v)
+			break
+		}
+		h := gast.NewRawHTML()
+		h.Segments.Append(g.segment(s))
+		n = h
+	case LINK, IMAGE:
+		l := gast.NewLink()
+		l.Destination = []byte(elt.contents.link.url)
+		l.Title = []byte(elt.contents.link.title)
+		g.elist(l, elt.contents.link.label)
+		n = l
+		if elt.key == IMAGE {
+			n = gast.NewImage(l)
+		}
+	case EMPH:
+		n = gast.NewEmphasis(1)
+		g.elist(n, elt.children)
+	case STRONG:
+		n = gast.NewEmphasis(2)
+		g.elist(n, elt.children)
+	case CRITICDEL:
+		n = extast.NewStrikethrough()
+		g.elist(n, elt.children)
+	case LIST, MARK, CRITICINS, CRITICSUB, CRITICHIGHLIGHT:
+		g.elist(parent, elt.children)
+	case CRITICCOMMENT, COMMENT, COMMENTBLOCK, REFERENCE:
+		/* Nonprinting */
+	case H1, H2, H3, H4, H5, H6:
+		n = gast.NewHeading(elt.key - H1 + 1)
+		g.elist(n, elt.children)
+	case PLAIN:
+		n = gast.NewTextBlock()
+		g.elist(n, elt.children)
+	case PARA:
+		n = gast.NewParagraph()
+		g.elist(n, elt.children)
+	case FIGURE:
+		/* the image and its caption, as paragraphs */
+		p := gast.NewParagraph()
+		g.elem(p, elt.children)
+		parent.AppendChild(parent, p)
+		if caption := elt.children.next; caption != nil {
+			n = gast.NewParagraph()
+			g.elist(n, caption.children)
+		}
+	case HRULE:
+		n = gast.NewThematicBreak()
+	case EXTBLOCK:
+		if info, _ := ElementKind(elt.children.key).info(); info.fallback == FallbackRaw {
+			n = gast.NewHTMLBlock(gast.HTMLBlockType7)
+			g.lines(n, strings.TrimSuffix(elt.children.contents.str, "\n")+"\n")
+			break
+		}
+		n = gast.NewParagraph()
+		g.elist(n, elt.children)
+	case CONTAINER:
+		if title := elt.contents.link.title; title != "" {
+			p := gast.NewParagraph()
+			p.AppendChild(p, g.text(title))
+			parent.AppendChild(parent, p)
+		}
+		g.elist(parent, elt.children)
+	case HTMLBLOCK:
+		n = gast.NewHTMLBlock(gast.HTMLBlockType7)
+		g.lines(n, strings.TrimSuffix(elt.contents.str, "\n")+"\n")
+	case VERBATIM:
+		n = gast.NewCodeBlock()
+		g.lines(n, elt.contents.str)
+	case BULLETLIST, ORDEREDLIST:
+		marker := byte('-')
+		if m := elt.children.contents.str; m != "" {
+			marker = m[len(m)-1]
+		}
+		l := gast.NewList(marker)
+		l.Start = 1
+		if elt.key == ORDEREDLIST {
+			l.Start = listStart(elt)
+		}
+		l.IsTight = !elt.loose
+		g.elist(l, elt.children)
+		n = l
+	case LISTITEM:
+		n = gast.NewListItem(0)
+		g.elist(n, elt.children)
+	case BLOCKQUOTE:
+		n = gast.NewBlockquote()
+		g.elist(n, elt.children)
+	case DEFINITIONLIST:
+		n = extast.NewDefinitionList(0, nil)
+		g.elist(n, elt.children)
+	case DEFTITLE:
+		n = extast.NewDefinitionTerm()
+		g.elist(n, elt.children)
+	case DEFDATA:
+		n = extast.NewDefinitionDescription()
+		g.elist(n, elt.children)
+	case NOTE:
+		/* note blocks, having contents.str set, are not printed */
+		if elt.contents.str == "" {
+			n = g.noteLink(elt.children)
+		}
+	case UNDEFNOTE:
+		n = g.text("[^" + elt.contents.str + "]")
+	case PLACEHOLDER:
+		n = g.text("{{" + elt.contents.str + "}}")
+	case CITATION:
+		n = g.text("[" + elt.contents.str + "]")
+	case TABLE:
+		n = g.table(elt)
+	default:
+		info, ok := ElementKind(elt.key).info()
+		if !ok {
+			/* unknown kinds, e.g. of nodes built by programs, and RAW, which the parser replaces */
+			g.elist(parent, elt.children)
+			break
+		}
+		switch info.fallback {
+		case FallbackText:
+			n = g.text(elt.contents.str)
+		case FallbackRaw:
+			h := gast.NewRawHTML()
+			h.Segments.Append(g.segment(elt.contents.str))
+			n = h
+		}
+	}
+	if n != nil {
+		parent.AppendChild(parent, n)
+	}
+}
+
+// noteLink returns a link to the note with the given body, which is
+// numbered at its first reference, like in HTML output.
+func (g *goldmarkOut) noteLink(body *element) *extast.FootnoteLink {
+	nn, ok := g.noteNums[body]
+	if !ok {
+		g.notes = append(g.notes, body)
+		g.noteLinks = append(g.noteLinks, nil)
+		nn = len(g.notes)
+		if g.noteNums == nil {
+			g.noteNums = make(map[*element]int)
+		}
+		g.noteNums[body] = nn
+	}
+	l := extast.NewFootnoteLink(nn)
+	l.RefIndex = len(g.noteLinks[nn-1])
+	g.noteLinks[nn-1] = append(g.noteLinks[nn-1], l)
+	return l
+}
+
+// table converts a table; the first row of its head becomes goldmark's
+// table header. Captions, and column and row spans, are not
+// represented; cells covered by a row span are empty. Cells
+// containing blocks other than a single paragraph keep them.
+func (g *goldmarkOut) table(elt *element) *extast.Table {
+	t := extast.NewTable()
+	for c := elt.children; c != nil; c = c.next {
+		if c.key == TABLESEPARATOR {
+			for _, a := range c.contents.str {
+				t.Alignments = append(t.Alignments, goldmarkAlignment(a))
+			}
+		}
+	}
+	for c := elt.children; c != nil; c = c.next {
+		if c.key != TABLEHEAD && c.key != TABLEBODY {
+			continue
+		}
+		for r := c.children; r != nil; r = r.next {
+			row := extast.NewTableRow(t.Alignments)
+			i := 0
+			for cell := r.children; cell != nil; cell = cell.next {
+				tc := extast.NewTableCell()
+				if i < len(t.Alignments) {
+					tc.Alignment = t.Alignments[i]
+				}
+				if b := cellBlocks(cell); b != nil && b.next == nil && (b.key == PLAIN || b.key == PARA) {
+					/* a cell of a single paragraph keeps its inlines */
+					g.elist(tc, b.children)
+				} else {
+					g.elist(tc, cell.children)
+				}
+				row.AppendChild(row, tc)
+				i++
+			}
+			if c.key == TABLEHEAD && t.FirstChild() == nil {
+				t.AppendChild(t, extast.NewTableHeader(row))
+			} else {
+				t.AppendChild(t, row)
+			}
+		}
+	}
+	return t
+}
+
+func goldmarkAlignment(a rune) extast.Alignment {
+	switch a {
+	case 'l', 'L':
+		return extast.AlignLeft
+	case 'r', 'R':
+		return extast.AlignRight
+	case 'c', 'C':
+		return extast.AlignCenter
+	}
+	return extast.AlignNone
+}
+
+// lines adds a segment to b for each line of s.
+func (g *goldmarkOut) lines(b gast.Node, s string) {
+	for s != "" {
+		i := strings.IndexByte(s, '\n') + 1
+		if i == 0 {
+			i = len(s)
+		}
+		b.Lines().Append(g.segment(s[:i]))
+		s = s[i:]
+	}
+}
+
+// segment appends s to the source, returning its segment.
+func (g *goldmarkOut) segment(s string) text.Segment {
+	start := len(g.doc.Source)
+	g.doc.Source = append(g.doc.Source, s...)
+	return text.NewSegment(start, len(g.doc.Source))
+}
+
+// text returns a node for s, which is escaped, but
+// otherwise rendered literally.
+func (g *goldmarkOut) text(s string) *gast.Text {
+	return gast.NewRawTextSegment(g.segment(s))
+}
+
+// FromGoldmark converts a document parsed by goldmark from source
+// into a tree like the one returned by Parser.Parse, which may be
+// rendered by the formatters of this package, see Render. Nodes of
+// goldmark's extensions Table, Strikethrough, DefinitionList,
+// Footnote, and TaskList are converted as well; nodes without a
+// counterpart, like those of other extensions, are reduced to
+// their content.
+func FromGoldmark(doc gast.Node, source []byte) *Node {
+	c := &goldmarkIn{source: source, footnotes: make(map[int]*extast.Footnote)}
+	gast.Walk(doc, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if fn, ok := n.(*extast.Footnote); ok && entering {
+			c.footnotes[fn.Index] = fn
+		}
+		return gast.WalkContinue, nil
+	})
+	return NewDocument(c.nodes(doc)...)
+}
+
+type goldmarkIn struct {
+	source    []byte
+	footnotes map[int]*extast.Footnote /* Footnotes, by index. */
+	notes     map[int]*Node            /* Bodies of the footnotes converted. */
+}
+
+// nodes returns the nodes converted from the children of n.
+func (c *goldmarkIn) nodes(n gast.Node) (list []*Node) {
+	for n := n.FirstChild(); n != nil; n = n.NextSibling() {
+		list = append(list, c.node(n)...)
+	}
+	return list
+}
+
+// node converts n; a text may result in two nodes, the second one
+// a line break, and nodes without counterpart in their children.
+func (c *goldmarkIn) node(n gast.Node) []*Node {
+	var m *Node
+
+	switch n := n.(type) {
+	case *gast.Text:
+		m = NewText(string(n.Segment.Value(c.source)))
+		if n.HardLineBreak() {
+			return []*Node{m, NewLineBreak()}
+		} else if n.SoftLineBreak() {
+			return []*Node{m, {Kind: SPACE, Text: "\n"}}
+		}
+	case *gast.String:
+		m = NewText(string(n.Value))
+	case *gast.CodeSpan:
+		var b strings.Builder
+		for t := n.FirstChild(); t != nil; t = t.NextSibling() {
+			switch t := t.(type) {
+			case *gast.Text:
+				b.Write(t.Segment.Value(c.source))
+			case *gast.String:
+				b.Write(t.Value)
+			}
+		}
+		m = NewCode(b.String())
+	case *gast.Emphasis:
+		if n.Level == 2 {
+			m = NewStrong(c.nodes(n)...)
+		} else {
+			m = NewEmph(c.nodes(n)...)
+		}
+	case *gast.Link:
+		m = NewLink(string(n.Destination), string(n.Title), c.nodes(n)...)
+	case *gast.Image:
+		m = NewImage(string(n.Destination), string(n.Title), c.nodes(n)...)
+	case *gast.AutoLink:
+		url := string(n.URL(c.source))
+		if n.AutoLinkType == gast.AutoLinkEmail && !strings.HasPrefix(strings.ToLower(url), "mailto:") {
+			url = "mailto:" + url
+		}
+		m = NewLink(url, "", NewText(string(n.Label(c.source))))
+	case *gast.RawHTML:
+		m = &Node{Kind: HTML, Text: c.segments(n.Segments)}
+	case *gast.Heading:
+		m = NewHeading(n.Level, c.nodes(n)...)
+	case *gast.Paragraph:
+		m = NewParagraph(c.nodes(n)...)
+	case *gast.TextBlock:
+		m = NewPlain(c.nodes(n)...)
+	case *gast.ThematicBreak:
+		m = NewHorizontalRule()
+	case *gast.CodeBlock, *gast.FencedCodeBlock:
+		m = NewCodeBlock(c.segments(n.Lines()))
+	case *gast.HTMLBlock:
+		s := c.segments(n.Lines())
+		if n.HasClosure() {
+			s += string(n.ClosureLine.Value(c.source))
+		}
+		m = &Node{Kind: HTMLBLOCK, Text: strings.TrimSuffix(s, "\n")}
+	case *gast.Blockquote:
+		m = NewBlockQuote(c.nodes(n)...)
+	case *gast.List:
+		m = c.list(n)
+	case *gast.ListItem:
+		m = NewListItem(c.nodes(n)...)
+	case *extast.Strikethrough:
+		m = &Node{Kind: CRITICDEL, Children: c.nodes(n)}
+	case *extast.Table:
+		m = c.table(n)
+	case *extast.DefinitionList:
+		m = &Node{Kind: DEFINITIONLIST, Children: c.nodes(n)}
+	case *extast.DefinitionTerm:
+		m = &Node{Kind: DEFTITLE, Children: c.nodes(n)}
+	case *extast.DefinitionDescription:
+		m = &Node{Kind: DEFDATA, Children: []*Node{{Kind: LIST, Children: c.nodes(n)}}}
+	case *extast.FootnoteLink:
+		m = &Node{Kind: NOTE, Children: []*Node{c.note(n.Index)}}
+	case *extast.FootnoteList, *extast.FootnoteBacklink:
+		/* notes are printed where formatters print them */
+		return nil
+	case *extast.TaskCheckBox:
+		if n.IsChecked {
+			m = NewText("[x] ")
+		} else {
+			m = NewText("[ ] ")
+		}
+	default:
+		return c.nodes(n)
+	}
+	return []*Node{m}
+}
+
+// note returns the body of the footnote with the given index,
+// which is shared by the NOTE nodes referring to it.
+func (c *goldmarkIn) note(index int) *Node {
+	if body, ok := c.notes[index]; ok {
+		return body
+	}
+	body := &Node{Kind: LIST}
+	if c.notes == nil {
+		c.notes = make(map[int]*Node)
+	}
+	c.notes[index] = body /* before converting, notes may refer to themselves */
+	if fn := c.footnotes[index]; fn != nil {
+		body.Children = c.nodes(fn)
+	}
+	return body
+}
+
+// list converts a list, numbering the items of ordered lists
+// from its start.
+func (c *goldmarkIn) list(n *gast.List) *Node {
+	list := &Node{Kind: BULLETLIST, Loose: !n.IsTight, Children: c.nodes(n)}
+	if n.IsOrdered() {
+		list.Kind = ORDEREDLIST
+	}
+	for i, item := range list.Children {
+		item.Text = string(n.Marker)
+		if n.IsOrdered() {
+			item.Text = strconv.Itoa(n.Start+i) + item.Text
+		}
+		item.Loose = list.Loose
+	}
+	list.Text = string(n.Marker)
+	if len(list.Children) > 0 {
+		list.Text = list.Children[0].Text
+	}
+	return list
+}
+
+// table converts a table, whose header becomes its head.
+func (c *goldmarkIn) table(n *extast.Table) *Node {
+	var align strings.Builder
+	for _, a := range n.Alignments {
+		switch a {
+		case extast.AlignRight:
+			align.WriteByte('r')
+		case extast.AlignCenter:
+			align.WriteByte('c')
+		default:
+			align.WriteByte('l')
+		}
+	}
+	var head *Node
+	var body []*Node
+	for r := n.FirstChild(); r != nil; r = r.NextSibling() {
+		var cells [][]*Node
+		for cell := r.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, c.nodes(cell))
+		}
+		if _, ok := r.(*extast.TableHeader); ok && head == nil {
+			head = NewTableRow(cells...)
+		} else {
+			body = append(body, NewTableRow(cells...))
+		}
+	}
+	return NewTable(align.String(), head, body...)
+}
+
+// segments returns the text of segments of the source.
+func (c *goldmarkIn) segments(s *text.Segments) string {
+	var b strings.Builder
+	for i := 0; i < s.Len(); i++ {
+		seg := s.At(i)
+		b.Write(seg.Value(c.source))
+	}
+	return b.String()
+}
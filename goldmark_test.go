@@ -0,0 +1,64 @@
+//go:build goldmark
+
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	gtext "github.com/yuin/goldmark/text"
+)
+
+func TestGoldmark(t *testing.T) {
+	const input = "# Head\n\n*a* **b** `c<d` [link](/u \"t\") &copy; <span>x</span>\n" +
+		"line  \nbreak[^n]\n\n    code <\n\n* one\n* two\n\n<div>\nblock\n</div>\n\n[^n]: Note.\n"
+	const want = "<h1>Head</h1>\n" +
+		"<p><em>a</em> <strong>b</strong> <code>c&lt;d</code> <a href=\"/u\" title=\"t\">link</a> © <span>x</span>\n" +
+		"line<br />\nbreak<sup id=\"fnref:1\"><a href=\"#fn:1\" class=\"footnote-ref\" role=\"doc-noteref\">1</a></sup></p>\n" +
+		"<pre><code>code &lt;\n</code></pre>\n<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<div>\nblock\n</div>\n" +
+		"<div class=\"footnotes\" role=\"doc-endnotes\">\n<hr />\n<ol>\n<li id=\"fn:1\">\n" +
+		"<p>Note.&#160;<a href=\"#fnref:1\" class=\"footnote-backref\" role=\"doc-backlink\">&#x21a9;&#xfe0e;</a></p>\n" +
+		"</li>\n</ol>\n</div>\n"
+
+	var d GoldmarkDocument
+	NewParser(&Extensions{Notes: true}).Markdown(strings.NewReader(input), ToGoldmark(&d))
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.DefinitionList, extension.Footnote),
+		goldmark.WithRendererOptions(html.WithUnsafe(), html.WithXHTML()))
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, d.Source, d.Node); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFromGoldmark(t *testing.T) {
+	const input = "# Head\n\n*a* **b** `c<d` [link](/u \"t\") <http://x.org> ~~del~~ <span>x</span>\n" +
+		"line  \nbreak[^n]\n\n```go\ncode <\n```\n\n3. one\n4. two\n\n- a\n\n- b\n\n> quote\n\n<div>\nblock\n</div>\n\n" +
+		"| a | b |\n|:--|--:|\n| *c* | d |\n\nTerm\n:   Def\n\n[^n]: Note.\n"
+	const want = "<h1>Head</h1>\n\n" +
+		"<p><em>a</em> <strong>b</strong> <code>c&lt;d</code> <a href=\"/u\" title=\"t\">link</a> <a href=\"http://x.org\">http://x.org</a> <del>del</del> <span>x</span>\n" +
+		"line<br/>\nbreak<a class=\"noteref\" id=\"fnref1\" href=\"#fn1\" title=\"Jump to note 1\">[1]</a></p>\n\n" +
+		"<pre><code>code &lt;\n</code></pre>\n\n<ol start=\"3\">\n<li>one</li>\n<li>two</li>\n</ol>\n\n" +
+		"<ul>\n<li><p>a</p></li>\n<li><p>b</p></li>\n</ul>\n\n<blockquote>\n<p>quote</p>\n</blockquote>\n\n<div>\nblock\n</div>\n\n" +
+		"<table>\n<colgroup>\n<col style=\"text-align:left;\"/>\n<col style=\"text-align:right;\"/>\n</colgroup>\n\n" +
+		"<thead>\n<tr>\n\t<th style=\"text-align:left;\">a</th>\n\t<th style=\"text-align:right;\">b</th>\n</tr>\n</thead>\n\n" +
+		"<tbody>\n<tr>\n\t<td style=\"text-align:left;\"><em>c</em></td>\n\t<td style=\"text-align:right;\">d</td>\n</tr>\n</tbody>\n</table>\n\n" +
+		"<dl>\n<dt>Term</dt><dd>Def</dd>\n</dl>\n\n" +
+		"<hr/>\n<ol id=\"notes\">\n<li id=\"fn1\">\n<p>Note.</p> <a href=\"#fnref1\" title=\"Jump back to reference\">[back]</a>\n</li>\n</ol>\n"
+
+	md := goldmark.New(goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.DefinitionList, extension.Footnote))
+	src := []byte(input)
+	tree := FromGoldmark(md.Parser().Parse(gtext.NewReader(src)), src)
+	var buf bytes.Buffer
+	Render(tree, ToHTML(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
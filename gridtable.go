@@ -0,0 +1,214 @@
+package markdown
+
+// Pandoc's grid tables
+
+import (
+	"strings"
+)
+
+// A gridTable is a grid table, split into the text of its cells.
+type gridTable struct {
+	align string     // alignment of each column, as in a TABLESEPARATOR
+	head  [][]string // rows preceding a border of '=', if any
+	body  [][]string
+}
+
+// gridTableText is used as a predicate by the grammar, at the
+// start of a line; it advances *pos over the grid table starting
+// there, and reports whether there is one.
+func (p *yyParser) gridTableText(pos *int) bool {
+	_, n := parseGridTable(p.Buffer[*pos:])
+	*pos += n
+	return n > 0
+}
+
+// gridTable returns the TABLE of a grid table. The text of each
+// cell becomes a RAW element, which is parsed into blocks later;
+// cells of several paragraphs end with a paragraph, rather than
+// plain text.
+func (p *yyParser) gridTable(text string) *element {
+	t, _ := parseGridTable(text)
+	sep := p.mkString(t.align)
+	sep.key = TABLESEPARATOR
+	a := cons(sep, nil)
+	for _, part := range []struct {
+		key  int
+		rows [][]string
+	}{{TABLEHEAD, t.head}, {TABLEBODY, t.body}} {
+		if len(part.rows) == 0 {
+			continue
+		}
+		var rows *element
+		for _, cells := range part.rows {
+			var row *element
+			for _, s := range cells {
+				cell := p.mkElem(TABLECELL)
+				if s != "" {
+					cell.children = p.mkElem(RAW)
+					cell.children.contents.str = s + "\n"
+					cell.children.loose = strings.Contains(s, "\n\n")
+				}
+				row = cons(cell, row)
+			}
+			rows = cons(p.mkList(TABLEROW, row), rows)
+		}
+		a = cons(p.mkList(part.key, rows), a)
+	}
+	return p.mkList(TABLE, a)
+}
+
+// parseGridTable parses the grid table at the start of s, and
+// returns it with its length, which is 0 if there is none. The
+// borders of the rows, like "+---+:--:+", must be the same for
+// all rows, and each line of a row must have a '|' below each
+// '+'; cells spanning several columns or rows are not supported.
+// Columns are counted in runes.
+func parseGridTable(s string) (t gridTable, n int) {
+	line, rest := nextLine(s)
+	cols, head, ok := gridBorder(line)
+	if !ok || head {
+		return t, 0
+	}
+	t.align = gridAlign(line, cols)
+	var rows [][]string
+	var lines [][]rune /* Lines of the current row. */
+	headEnd := -1
+	for end := len(line); rest != ""; {
+		line, rest = nextLine(rest)
+		end += len(line)
+		if strings.HasPrefix(line, "+") {
+			c, head, ok := gridBorder(line)
+			if !ok || len(lines) == 0 || !sameCols(c, cols) || head && headEnd != -1 {
+				break
+			}
+			rows = append(rows, gridCells(lines, cols))
+			lines = nil
+			if head {
+				headEnd = len(rows)
+				t.align = gridAlign(line, cols)
+			}
+			n = end
+			continue
+		}
+		r, ok := gridLine(line, cols)
+		if !ok {
+			break
+		}
+		lines = append(lines, r)
+	}
+	if len(rows) == 0 {
+		return t, 0
+	}
+	if headEnd == -1 {
+		headEnd = 0
+	}
+	t.head, t.body = rows[:headEnd], rows[headEnd:]
+	return t, n
+}
+
+// gridLine returns the runes of a line of a row of a grid table,
+// and reports whether it has a '|' in each of the columns cols.
+func gridLine(line string, cols []int) ([]rune, bool) {
+	r := []rune(strings.TrimRight(line, " \t\r\n"))
+	if len(r) != cols[len(cols)-1]+1 {
+		return nil, false
+	}
+	for _, c := range cols {
+		if r[c] != '|' {
+			return nil, false
+		}
+	}
+	return r, true
+}
+
+// nextLine splits off the first line of s, including its newline.
+func nextLine(s string) (line, rest string) {
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		return s[:i+1], s[i+1:]
+	}
+	return s, ""
+}
+
+// gridBorder returns the rune columns of the '+' of a border line
+// of a grid table, and whether it separates the head from the body,
+// using '=' instead of '-'.
+func gridBorder(line string) (cols []int, head, ok bool) {
+	r := []rune(strings.TrimRight(line, " \t\r\n"))
+	if len(r) < 3 || r[0] != '+' || r[len(r)-1] != '+' {
+		return nil, false, false
+	}
+	for i, c := range r {
+		switch c {
+		case '+':
+			if i > 0 && r[i-1] == '+' {
+				return nil, false, false
+			}
+			cols = append(cols, i)
+		case '=':
+			head = true
+		case '-', ':':
+		default:
+			return nil, false, false
+		}
+	}
+	return cols, head, true
+}
+
+func sameCols(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// gridAlign returns the alignment of the columns of a grid table
+// set by colons in a border line, like "+:---+---:+:---:+".
+func gridAlign(line string, cols []int) string {
+	r := []rune(line)
+	var b strings.Builder
+	for i := 0; i+1 < len(cols); i++ {
+		left, right := r[cols[i]+1] == ':', r[cols[i+1]-1] == ':'
+		switch {
+		case left && right:
+			b.WriteByte('c')
+		case right:
+			b.WriteByte('r')
+		default:
+			b.WriteByte('l')
+		}
+	}
+	return b.String()
+}
+
+// gridCells returns the text of the cells of a row of a grid table,
+// with the indentation common to their lines removed, and leading
+// and trailing blank lines dropped.
+func gridCells(lines [][]rune, cols []int) []string {
+	cells := make([]string, len(cols)-1)
+	for i := range cells {
+		text := make([]string, len(lines))
+		indent := -1
+		for j, r := range lines {
+			s := strings.TrimRight(string(r[cols[i]+1:cols[i+1]]), " ")
+			if s != "" {
+				k := len(s) - len(strings.TrimLeft(s, " "))
+				if indent == -1 || k < indent {
+					indent = k
+				}
+			}
+			text[j] = s
+		}
+		for j, s := range text {
+			if s != "" {
+				text[j] = s[indent:]
+			}
+		}
+		cells[i] = strings.Trim(strings.Join(text, "\n"), "\n")
+	}
+	return cells
+}
@@ -0,0 +1,55 @@
+package markdown
+
+// Hashtags, like "#golang"
+
+import (
+	"strings"
+)
+
+// Tags returns the hashtags found during the most recent call of
+// Markdown, without "#", in the order of their first occurrence;
+// see Extensions.Hashtags. Tags in footnotes come first, since
+// notes are parsed before the rest of the document.
+func (p *Parser) Tags() []string {
+	return p.yy.state.tags
+}
+
+// tagStart is used as a predicate by the grammar; it reports
+// whether a hashtag may start at pos, i.e. whether the character
+// preceding it, if any, ends a word. Marks like "&#", as in an
+// entity, "/#" and "_#" don't.
+func (p *yyParser) tagStart(pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	c := p.Buffer[pos-1]
+	switch {
+	case c >= 0x80, c == '#', c == '&', c == '/', c == '_':
+		return false
+	case c >= '0' && c <= '9', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		return false
+	}
+	return true
+}
+
+// hashtag records a tag, and returns the element representing it:
+// a link, if Extensions.HashtagURL returns a URL for it, or else
+// its text.
+func (p *yyParser) hashtag(tag string) *element {
+	if strings.Trim(tag, "0123456789") == "" {
+		return p.mkString("#" + tag)
+	}
+	if !p.state.tagSet[tag] {
+		if p.state.tagSet == nil {
+			p.state.tagSet = make(map[string]bool)
+		}
+		p.state.tagSet[tag] = true
+		p.state.tags = append(p.state.tags, tag)
+	}
+	if p.extension.HashtagURL != nil {
+		if url := p.extension.HashtagURL(tag); url != "" {
+			return p.mkLink(p.mkString("#"+tag), url, "")
+		}
+	}
+	return p.mkString("#" + tag)
+}
@@ -0,0 +1,280 @@
+package markdown
+
+// Recognition of HTML blocks enclosed in balanced tags
+
+import (
+	"strings"
+)
+
+// DefaultHTMLBlockTags lists the names of the elements that, if
+// Extensions.HTMLBlockTags is nil, may enclose an HTML block.
+// Custom elements, whose names contain a hyphen, are accepted too.
+var DefaultHTMLBlockTags = []string{
+	"address", "article", "aside", "audio", "blockquote", "canvas",
+	"caption", "center", "colgroup", "dd", "details", "dialog", "dir",
+	"div", "dl", "dt", "fieldset", "figcaption", "figure", "footer",
+	"form", "frameset", "h1", "h2", "h3", "h4", "h5", "h6", "header",
+	"hgroup", "iframe", "legend", "li", "main", "menu", "nav", "noframes",
+	"noscript", "object", "ol", "optgroup", "option", "p", "picture", "pre",
+	"search", "section", "summary", "table", "tbody", "td", "template",
+	"tfoot", "th", "thead", "tr", "ul", "video",
+}
+
+// isHTMLBlockTag reports whether an element named name,
+// in lower case, may enclose an HTML block.
+func (x *Extensions) isHTMLBlockTag(name string) bool {
+	tags := x.HTMLBlockTags
+	if tags == nil {
+		if strings.Contains(name, "-") && name[0] >= 'a' && name[0] <= 'z' {
+			return true
+		}
+		tags = DefaultHTMLBlockTags
+	}
+	for _, t := range tags {
+		if strings.EqualFold(t, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlBlockInTags matches an HTML block enclosed in balanced tags,
+// like <div> ... </div>, at *pos, and advances *pos past it. Nested
+// elements of the same name must be balanced too.
+func (p *yyParser) htmlBlockInTags(pos *int) bool {
+	s := p.Buffer[*pos:]
+	name, n := htmlOpenTag(s)
+	if n == 0 || !p.extension.isHTMLBlockTag(name) {
+		return false
+	}
+	if n = htmlElementLen(s, name, n); n == 0 {
+		return false
+	}
+	*pos += n
+	return true
+}
+
+// An HTMLBlockStyle selects how HTML blocks are recognized.
+type HTMLBlockStyle int
+
+const (
+	// HTML blocks consist of balanced tags, like in peg-markdown.
+	// Text following the closing tag on the same line, or an HTML
+	// block starting within a paragraph, joins the paragraph.
+	LegacyHTMLBlocks HTMLBlockStyle = iota
+
+	// Like CommonMark's HTML blocks of types 6 and 7: a line starting
+	// with a tag of an element that may enclose an HTML block, see
+	// Extensions.HTMLBlockTags, starts an HTML block, also within a
+	// paragraph; so does a line consisting of a single tag of any other
+	// element, but not within a paragraph. The block ends at the next
+	// blank line. Comments, and elements like <script>, are
+	// recognized as in legacy mode.
+	CommonMarkHTMLBlocks
+)
+
+// commonMarkHTMLBlock matches an HTML block, delimited like in
+// CommonMark, at *pos, and advances *pos to the blank line
+// following it.
+func (p *yyParser) commonMarkHTMLBlock(pos *int) bool {
+	if p.extension.HTMLBlocks != CommonMarkHTMLBlocks {
+		return false
+	}
+	s := p.Buffer[*pos:]
+	if !p.extension.htmlBlockStart(s) && !htmlTagLine(s) {
+		return false
+	}
+	n := 0
+	for n < len(s) {
+		line := s[n:]
+		if i := strings.IndexByte(line, '\n'); i != -1 {
+			line = line[:i+1]
+		}
+		if strings.TrimLeft(line, " \t\r\n") == "" {
+			break
+		}
+		n += len(line)
+	}
+	*pos += len(strings.TrimRight(s[:n], "\r\n"))
+	return true
+}
+
+// htmlBlockInterrupts is used by the grammar to end a paragraph
+// at the line at pos, if it starts an HTML block.
+func (p *yyParser) htmlBlockInterrupts(pos int) bool {
+	return p.extension.HTMLBlocks == CommonMarkHTMLBlocks && p.extension.htmlBlockStart(p.Buffer[pos:])
+}
+
+// htmlBlockStart reports whether s starts with an opening or
+// closing tag of an element that may enclose an HTML block,
+// which is followed by white space, or the end of the tag.
+func (x *Extensions) htmlBlockStart(s string) bool {
+	if !strings.HasPrefix(s, "<") {
+		return false
+	}
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		i++
+	}
+	j := i + htmlNameLen(s[i:])
+	if j == i || !x.isHTMLBlockTag(strings.ToLower(s[i:j])) {
+		return false
+	}
+	return j == len(s) || strings.IndexByte(" \t\r\n>", s[j]) != -1 || strings.HasPrefix(s[j:], "/>")
+}
+
+// htmlTagLine reports whether the line at the start of s consists
+// of a single opening or closing tag, and optional white space.
+func htmlTagLine(s string) bool {
+	name, n := htmlOpenTag(s)
+	if n == 0 {
+		name, n = htmlCloseTag(s)
+	}
+	switch name {
+	case "", "script", "style", "pre", "textarea":
+		return false
+	}
+	if i := strings.IndexByte(s[:n], '\n'); i != -1 {
+		return false /* the tag spans lines */
+	}
+	i := sp(s, n)
+	return i == len(s) || s[i] == '\n' || s[i] == '\r'
+}
+
+// htmlElementLen returns the length of the element at the start of s,
+// whose opening tag, of length i, has already been matched; or zero,
+// if there is no matching closing tag.
+func htmlElementLen(s, name string, i int) int {
+	for i < len(s) {
+		if s[i] != '<' {
+			i++
+			continue
+		}
+		if cname, n := htmlCloseTag(s[i:]); n != 0 && cname == name {
+			return i + n
+		}
+		if oname, n := htmlOpenTag(s[i:]); n != 0 && oname == name {
+			if n = htmlElementLen(s[i:], name, n); n != 0 {
+				i += n
+				continue
+			}
+		}
+		i++
+	}
+	return 0
+}
+
+// htmlOpenTag matches an opening tag like <name attr="value"> at the
+// start of s, returning the element name in lower case, and the
+// length of the tag, which is zero if there is no match.
+func htmlOpenTag(s string) (name string, n int) {
+	if !strings.HasPrefix(s, "<") {
+		return "", 0
+	}
+	i := spnl(s, 1)
+	j := i + htmlNameLen(s[i:])
+	if j == i {
+		return "", 0
+	}
+	name = strings.ToLower(s[i:j])
+	i = spnl(s, j)
+	for {
+		k := htmlAttributeLen(s[i:])
+		if k == 0 {
+			break
+		}
+		i += k
+	}
+	if i == len(s) || s[i] != '>' {
+		return "", 0
+	}
+	return name, i + 1
+}
+
+// htmlCloseTag matches a closing tag like </name> at the start of s.
+func htmlCloseTag(s string) (name string, n int) {
+	if !strings.HasPrefix(s, "<") {
+		return "", 0
+	}
+	i := spnl(s, 1)
+	if i == len(s) || s[i] != '/' {
+		return "", 0
+	}
+	i++
+	j := i + htmlNameLen(s[i:])
+	if j == i {
+		return "", 0
+	}
+	name = strings.ToLower(s[i:j])
+	i = spnl(s, j)
+	if i == len(s) || s[i] != '>' {
+		return "", 0
+	}
+	return name, i + 1
+}
+
+// htmlAttributeLen returns the length of the attribute at the start
+// of s, including trailing white space, like the grammar's
+// HtmlAttribute rule, or zero if there is none.
+func htmlAttributeLen(s string) int {
+	i := htmlNameLen(s)
+	if i == 0 {
+		return 0
+	}
+	i = spnl(s, i)
+	if i < len(s) && s[i] == '=' {
+		j := spnl(s, i+1)
+		end := 0
+		if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+			if k := strings.IndexByte(s[j+1:], s[j]); k != -1 {
+				end = j + k + 2
+			}
+		} else {
+			k := j
+			for k < len(s) && !strings.ContainsRune("> \t\r\n", rune(s[k])) {
+				k++
+			}
+			if k > j {
+				end = k
+			}
+		}
+		if end != 0 {
+			i = end
+		}
+	}
+	return spnl(s, i)
+}
+
+// htmlNameLen returns the length of the run of ASCII letters,
+// digits, and hyphens at the start of s.
+func htmlNameLen(s string) int {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+// spnl skips optional white space in s, starting at i, that
+// may include a single line break, like the grammar's Spnl rule.
+func spnl(s string, i int) int {
+	i = sp(s, i)
+	if i < len(s) && (s[i] == '\n' || s[i] == '\r') {
+		if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+			i++
+		}
+		i = sp(s, i+1)
+	}
+	return i
+}
+
+func sp(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,230 @@
+package markdown
+
+// Transclusion of files using include directives
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultIncludeDepth limits the nesting of included
+// files, unless Extensions.MaxIncludeDepth is set.
+const defaultIncludeDepth = 8
+
+// source reads and preformats a document, expanding
+// include directives, if enabled.
+func (p *Parser) source(r io.Reader) string {
+	s, m := p.preformat(r)
+	p.includeDiags = p.includeDiags[:0]
+	p.srcMap = nil
+	p.offsets = m
+	if p.yy.extension.Include == nil {
+		return s
+	}
+	var b strings.Builder
+	var failed []includeError
+	p.include(&b, s, m, nil, &failed)
+	s = b.String()
+	lines := newLineCounter(s, nil)
+	for _, e := range failed {
+		p.includeDiags = append(p.includeDiags, Diagnostic{Pos: lines.pos(e.off), Severity: Error, Message: e.msg})
+	}
+	if len(p.srcMap) == 1 {
+		/* nothing has been included */
+		p.srcMap = nil
+	} else {
+		/* positions are mapped to the files by mapDiagnostics */
+		p.offsets = nil
+	}
+	return s
+}
+
+type includeError struct {
+	off int /* offset of the directive in the expanded text */
+	msg string
+}
+
+// include writes s to b, replacing include directives by the
+// preformatted text of the files they refer to; m holds the edits
+// made while preformatting s, and stack the paths of the files
+// being included. Directives within code blocks and code spans
+// are left alone.
+func (p *Parser) include(b *strings.Builder, s string, m offsetMap, stack []string, failed *[]includeError) {
+	max := p.yy.extension.MaxIncludeDepth
+	if max == 0 {
+		max = defaultIncludeDepth
+	}
+	var file string
+	if len(stack) > 0 {
+		file = stack[len(stack)-1]
+	}
+	text := s
+	p.srcMap = append(p.srcMap, sourceSeg{b.Len(), file, text, 0, m})
+	blank := true /* whether the preceding line is blank */
+	for s != "" {
+		line, rest := nextLine(s)
+		s = rest
+		switch {
+		case strings.TrimSpace(line) == "":
+			blank = true
+			b.WriteString(line)
+			continue
+		case blank && strings.HasPrefix(line, "    "):
+			/* a line of an indented code block */
+			b.WriteString(line)
+			continue
+		}
+		blank = false
+		path, ok := includeDirective(line)
+		if !ok {
+			/* lines spanned by code spans starting in line */
+			n := codeSpanEnd(line + s)
+			b.WriteString(line)
+			b.WriteString(s[:n-len(line)])
+			s = s[n-len(line):]
+			continue
+		}
+		var msg string
+		for _, f := range stack {
+			if f == path {
+				msg = "include cycle: " + strings.Join(append(stack, path), " -> ")
+			}
+		}
+		if msg == "" && len(stack) == max {
+			msg = "include depth limit of " + strconv.Itoa(max) + " exceeded"
+		}
+		var data []byte
+		if msg == "" {
+			var err error
+			if data, err = p.yy.extension.Include(path); err != nil {
+				msg = "cannot include " + path + ": " + err.Error()
+			}
+		}
+		if msg != "" {
+			*failed = append(*failed, includeError{b.Len(), msg})
+			b.WriteString(line)
+			continue
+		}
+		inc, incMap := p.preformat(bytes.NewReader(data))
+		inc = strings.TrimSuffix(inc, "\n\n")
+		if inc != "" && !strings.HasSuffix(inc, "\n") {
+			inc += "\n"
+		}
+		p.include(b, inc, incMap, append(stack, path), failed)
+		p.srcMap = append(p.srcMap, sourceSeg{b.Len(), file, text, len(text) - len(s), m})
+	}
+}
+
+// codeSpanEnd returns the length of the lines at the start of s
+// spanned by the code spans starting in its first line; it is
+// the length of that line if none continues beyond it. As in the
+// grammar, a code span doesn't extend beyond a blank line.
+func codeSpanEnd(s string) int {
+	i := 0
+	for i < len(s) && s[i] != '\n' {
+		switch s[i] {
+		case '\\':
+			i++
+			if i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case '`':
+			n := 1
+			for i+n < len(s) && s[i+n] == '`' {
+				n++
+			}
+			i += n
+			if j := closingTicksPara(s[i:], n); j != -1 {
+				i += j + n
+			}
+		default:
+			i++
+		}
+	}
+	if i < len(s) {
+		i++
+	}
+	return i
+}
+
+// closingTicksPara returns the offset in s of a run of n
+// backticks closing a code span, which may be on one of the
+// lines following the first, up to a blank line, or -1.
+func closingTicksPara(s string, n int) int {
+	for off := 0; ; {
+		if j := closingTicks(s[off:], n); j != -1 {
+			return off + j
+		}
+		i := strings.IndexByte(s[off:], '\n')
+		if i == -1 {
+			return -1
+		}
+		off += i + 1
+		line, _ := nextLine(s[off:])
+		if strings.TrimSpace(line) == "" {
+			return -1
+		}
+	}
+}
+
+// A sourceMap locates the parts of a document expanded by include
+// directives in the files they have been read from.
+type sourceMap []sourceSeg
+
+// A sourceSeg is a part of the expanded text, starting at off,
+// which has been copied from text, the preformatted contents of
+// file, starting at offset fileOff; offsets holds the edits made
+// while preformatting. File is empty for the document itself.
+type sourceSeg struct {
+	off     int
+	file    string
+	text    string
+	fileOff int
+	offsets offsetMap
+}
+
+// mapDiagnostics replaces the positions of the diagnostics, which
+// are located in the expanded text, by those in the files the
+// text has been read from.
+func (p *Parser) mapDiagnostics() {
+	lines := make(map[string]*lineCounter)
+	for i := range p.diags {
+		d := &p.diags[i]
+		off := d.Pos.off
+		j := sort.Search(len(p.srcMap), func(j int) bool { return p.srcMap[j].off > off }) - 1
+		seg := p.srcMap[j]
+		off += seg.fileOff - seg.off
+		if off > len(seg.text) {
+			off = len(seg.text)
+		}
+		c := lines[seg.file]
+		if c == nil || c.src != seg.text || c.off > off {
+			c = newLineCounter(seg.text, seg.offsets)
+			lines[seg.file] = c
+		}
+		d.File = seg.file
+		d.Pos = c.pos(off)
+	}
+}
+
+// includeDirective returns the path of a line like
+// "{{include: path}}".
+func includeDirective(line string) (path string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{{include:") || !strings.HasSuffix(line, "}}") {
+		return "", false
+	}
+	path = strings.TrimSpace(line[len("{{include:") : len(line)-len("}}")])
+	return path, path != ""
+}
+
+// sortDiagnostics orders the diagnostics by position,
+// after those of include directives have been added.
+func (p *Parser) sortDiagnostics() {
+	sort.SliceStable(p.diags, func(i, j int) bool {
+		return p.diags[i].Pos.off < p.diags[j].Pos.off
+	})
+}
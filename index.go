@@ -0,0 +1,235 @@
+package markdown
+
+// Queries about positions in a document, for editors
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// A Block is a top-level block of a document.
+type Block struct {
+	Kind ElementKind // e.g. "PARA", or "BULLETLIST"
+	Span Span
+	Node *Node // the tree of the block, as returned by Parser.Parse
+}
+
+// A Ref is a reference link, like [text][label], or a note
+// reference, like [^label], found in the source text.
+type Ref struct {
+	Kind  SymbolKind // ReferenceSymbol, or NoteSymbol
+	Label string
+	Span  Span
+	Def   *Symbol // the definition, or nil, if the label is undefined
+}
+
+// A DocumentIndex describes the structure of a document.
+// Offsets are those of the source text; see Position.
+type DocumentIndex struct {
+	DocumentSymbols
+	Blocks []Block
+
+	nodes [][]nodeSpan /* Nodes of each block, children before their parents. */
+	lines *lineCounter
+}
+
+// A nodeSpan is the range of the source text of a node.
+type nodeSpan struct {
+	node       *Node
+	start, end int
+	ref        *refSpan /* set for references, and the labels of definitions */
+}
+
+type refSpan struct {
+	kind       SymbolKind
+	label      string
+	start, end int
+}
+
+type indexOut struct {
+	symbolsOut
+	x    *DocumentIndex
+	p    *Parser
+	tree treeOut
+	t    tokenizer
+	list []nodeSpan /* Nodes of the current block. */
+}
+
+func (f *indexOut) FormatBlock(tree *element) {
+	f.tree.elems = make(map[*element]*Node)
+	nodes := f.tree.nodes(tree)
+	f.x.Blocks = append(f.x.Blocks, Block{ElementKind(tree.key), f.span, nodes[0]})
+	f.list = nil
+	f.t.notes = f.p.yy.state.notes
+	f.t.block(tree, f.span)
+	f.x.nodes = append(f.x.nodes, f.list)
+	f.symbolsOut.FormatBlock(tree)
+}
+
+func (f *indexOut) Finish() {
+	f.tree.Finish()
+	f.symbolsOut.Finish()
+}
+
+// extent records the source range of an element of the
+// current block, with the reference it forms, if any.
+func (f *indexOut) extent(elt *element, start, end int) {
+	n := f.tree.elems[elt]
+	if n == nil {
+		return
+	}
+	ns := nodeSpan{node: n, start: start, end: end}
+	ref := &refSpan{kind: ReferenceSymbol, start: start, end: end}
+	switch elt.key {
+	case LINK, IMAGE:
+		ref.label = elt.contents.link.ref
+	case LIST:
+		/* [text][label], with label undefined */
+		ref.label = undefinedRef(elt)
+	case REFERENCE:
+		ref.label = plainText(elt.contents.link.label)
+		if k := closingBracket(f.t.src[:end], start); k != -1 {
+			ref.end = k + 1
+		}
+	case NOTE, UNDEFNOTE:
+		ref.kind = NoteSymbol
+		ref.label = elt.contents.str
+		if elt.key == NOTE && ref.label == "" {
+			ref.label = f.noteLabel(elt)
+		} else if elt.key == NOTE {
+			/* the label of the definition */
+			ref.end = start + len("[^"+ref.label+"]")
+		}
+	}
+	if ref.label != "" {
+		ns.ref = ref
+	}
+	f.list = append(f.list, ns)
+}
+
+// noteLabel returns the label of the note referenced by elt,
+// or "", if it is an inline note.
+func (f *indexOut) noteLabel(elt *element) string {
+	for label, note := range f.p.yy.state.notes {
+		if note.children == elt.children {
+			return label
+		}
+	}
+	return ""
+}
+
+// undefinedRef returns the label of a reference link, like
+// [text][label], whose label is undefined, which the grammar
+// turns into a LIST of its parts, or "".
+func undefinedRef(elt *element) string {
+	var parts [7]*element
+	i := 0
+	for c := elt.children; c != nil; c = c.next {
+		if i == len(parts) {
+			return ""
+		}
+		parts[i] = c
+		i++
+	}
+	if i != len(parts) || parts[4].key != STR || parts[4].contents.str != "[" || parts[5].key != LIST {
+		return ""
+	}
+	return plainText(parts[5].children)
+}
+
+// Index parses a document, like Markdown, and returns
+// an index of its structure.
+func (p *Parser) Index(src io.Reader) *DocumentIndex {
+	s := p.source(src)
+	x := &DocumentIndex{lines: newLineCounter(s, p.offsets)}
+	f := &indexOut{symbolsOut: symbolsOut{d: &x.DocumentSymbols}, x: x, p: p}
+	f.t = tokenizer{src: s, extent: f.extent}
+	p.markdown(s, f)
+	return x
+}
+
+// At returns the top-level block containing the byte at offset,
+// the innermost node of its tree whose source text contains the
+// byte, and the reference at offset, if there is one, which refers
+// to its definition. Ok is false if offset lies between blocks.
+func (x *DocumentIndex) At(offset int) (b Block, n *Node, ref *Ref, ok bool) {
+	i := sort.Search(len(x.Blocks), func(i int) bool {
+		return x.Blocks[i].Span.End.Offset >= offset
+	})
+	if i == len(x.Blocks) || x.Blocks[i].Span.Start.Offset > offset {
+		return b, nil, nil, false
+	}
+	b = x.Blocks[i]
+	n = b.Node
+	size := -1
+	var r *refSpan
+	off := x.lines.m.parsed(offset) /* nodes are located in the text as parsed */
+	for _, ns := range x.nodes[i] {
+		if off < ns.start || off >= ns.end {
+			continue
+		}
+		if size == -1 || ns.end-ns.start < size {
+			n, size = ns.node, ns.end-ns.start
+		}
+		if r == nil && ns.ref != nil && off < ns.ref.end {
+			/* children come first, so it is the innermost reference */
+			r = ns.ref
+		}
+	}
+	if r == nil {
+		return b, n, nil, true
+	}
+	ref = &Ref{Kind: r.kind, Label: r.label, Def: x.definition(r.kind, r.label)}
+	ref.Span.Start = x.lines.posAfter(b.Span.Start, r.start)
+	ref.Span.End = x.lines.posAfter(b.Span.Start, r.end-1)
+	return b, n, ref, true
+}
+
+// definition returns the symbol defining a label.
+func (x *DocumentIndex) definition(kind SymbolKind, label string) *Symbol {
+	label = strings.Join(strings.Fields(label), " ")
+	for i := range x.Symbols {
+		sym := &x.Symbols[i]
+		if sym.Kind != kind {
+			continue
+		}
+		if kind == NoteSymbol && sym.Name == label ||
+			kind == ReferenceSymbol && strings.EqualFold(strings.Join(strings.Fields(sym.Name), " "), label) {
+			return sym
+		}
+	}
+	return nil
+}
+
+// closingBracket returns the index of the bracket closing
+// the one at text[i], or -1.
+func closingBracket(text string, i int) int {
+	depth := 0
+	for ; i < len(text); i++ {
+		switch text[i] {
+		case '[':
+			if !isEscaped(text, i) {
+				depth++
+			}
+		case ']':
+			if !isEscaped(text, i) {
+				if depth--; depth == 0 {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// isEscaped reports whether text[i] is preceded
+// by an odd number of backslashes.
+func isEscaped(text string, i int) bool {
+	n := 0
+	for i > 0 && text[i-1] == '\\' {
+		n++
+		i--
+	}
+	return n%2 == 1
+}
@@ -0,0 +1,381 @@
+package markdown
+
+// Element kinds registered by extensions
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// An ElementKind identifies a kind of element, a built-in one,
+// like PARA, or one added by an extension; see RegisterKind.
+//
+// The values of built-in kinds are stable: new kinds are added
+// after the existing ones, and kinds are not renumbered, so that
+// values stored, e.g. in serialized trees, stay valid. Registered
+// kinds are numbered from FirstRegisteredKind, in the order of
+// registration. In text, kinds are represented by their names.
+type ElementKind int
+
+// FirstRegisteredKind is the value of the kind registered first.
+const FirstRegisteredKind ElementKind = 1000
+
+// A Fallback determines how a formatter without specific
+// support for a registered element kind renders its elements.
+type Fallback int
+
+const (
+	FallbackText Fallback = iota // print the element's content as text
+	FallbackRaw                  // copy the content to HTML output unchanged; text otherwise
+	FallbackOmit                 // print nothing
+)
+
+type kindInfo struct {
+	name     string
+	fallback Fallback
+}
+
+var kinds struct {
+	sync.RWMutex
+	list []kindInfo
+}
+
+// RegisterKind allocates a new element kind, named name, whose
+// elements are rendered according to fallback by formatters that
+// don't know about it. Kinds are usually registered during
+// package initialization.
+func RegisterKind(name string, fallback Fallback) ElementKind {
+	kinds.Lock()
+	defer kinds.Unlock()
+	kinds.list = append(kinds.list, kindInfo{name, fallback})
+	return FirstRegisteredKind + ElementKind(len(kinds.list)-1)
+}
+
+func (k ElementKind) info() (info kindInfo, ok bool) {
+	i := int(k - FirstRegisteredKind)
+	kinds.RLock()
+	defer kinds.RUnlock()
+	if i < 0 || i >= len(kinds.list) {
+		return
+	}
+	return kinds.list[i], true
+}
+
+// String returns the name of the kind.
+func (k ElementKind) String() string {
+	return keyName(int(k))
+}
+
+// ParseElementKind returns the kind with the given name,
+// which may be a built-in or a registered one.
+func ParseElementKind(name string) (k ElementKind, ok bool) {
+	for key, s := range keynames {
+		if s == name {
+			return ElementKind(key), true
+		}
+	}
+	kinds.RLock()
+	defer kinds.RUnlock()
+	for i, info := range kinds.list {
+		if info.name == name {
+			return FirstRegisteredKind + ElementKind(i), true
+		}
+	}
+	return 0, false
+}
+
+// MarshalText returns the name of the kind.
+func (k ElementKind) MarshalText() ([]byte, error) {
+	name := keyName(int(k))
+	if name == "" {
+		return nil, errors.New("markdown: unknown element kind " + strconv.Itoa(int(k)))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText sets the kind to the one named by text.
+func (k *ElementKind) UnmarshalText(text []byte) error {
+	kind, ok := ParseElementKind(string(text))
+	if !ok {
+		return errors.New("markdown: unknown element kind " + strconv.Quote(string(text)))
+	}
+	*k = kind
+	return nil
+}
+
+// keyName returns the name of an element key, which may
+// be a built-in one, or a registered kind.
+func keyName(key int) string {
+	if key >= 0 && key < numVAL {
+		return keynames[key]
+	}
+	if info, ok := ElementKind(key).info(); ok {
+		return info.name
+	}
+	return ""
+}
+
+// An InlineSyntax adds inline syntax, producing elements of
+// registered kinds, to the parser.
+type InlineSyntax struct {
+	Name string // used to report conflicts
+
+	// Characters that may start the syntax. They are treated
+	// as special characters while parsing inline text. If empty,
+	// the first byte of Prefix is used.
+	Triggers string
+
+	// If set, the syntax is tried only for text starting with Prefix.
+	Prefix string
+
+	// Syntax of higher priority is tried first; syntax of
+	// the same priority in the order of Extensions.Inline.
+	Priority int
+
+	// Parse is called for text starting with one of the Triggers.
+	// It returns the length of the matched prefix, zero if there
+	// is none, and the kind and content of the resulting element.
+	// The result must not depend on the text after the match.
+	Parse func(text string) (n int, kind ElementKind, content string)
+}
+
+func (syn *InlineSyntax) triggers() string {
+	if syn.Triggers == "" && syn.Prefix != "" {
+		return syn.Prefix[:1]
+	}
+	return syn.Triggers
+}
+
+// A Conflict describes inline syntax that competes with other
+// syntax for text starting with the same trigger character.
+type Conflict struct {
+	Syntax  string // name of an InlineSyntax
+	Other   string // name of another InlineSyntax, or "built-in"
+	Trigger byte
+}
+
+func (c Conflict) Error() string {
+	return "inline syntax " + c.Syntax + " conflicts with " + c.Other +
+		" syntax at " + strconv.QuoteRune(rune(c.Trigger))
+}
+
+// builtinTriggers returns the characters that start the
+// built-in inline syntax enabled by x.
+func (x *Extensions) builtinTriggers() string {
+	s := "*_`&[]()<!#\\'\""
+	if x.Smart {
+		s += ".-"
+	}
+	if x.Notes {
+		s += "^"
+	}
+	if x.Mark {
+		s += "="
+	}
+	if x.Critic {
+		s += "{+-~=>"
+	}
+	if x.Placeholders {
+		s += "{"
+	}
+	if x.Comments {
+		s += "%"
+	}
+	return s
+}
+
+// InlineConflicts reports inline syntax that would shadow the
+// built-in syntax enabled by x, and pairs of inline syntax of equal
+// priority, sharing a trigger character, whose prefixes don't rule
+// out that both match the same text. The one listed first in
+// x.Inline is tried first then, see InlineSyntax.Priority, so text
+// may silently be parsed by it instead of the other. Raising the
+// priority of one of them makes the choice explicit.
+func (x *Extensions) InlineConflicts() (list []Conflict) {
+	builtin := x.builtinTriggers()
+	for i := range x.Inline {
+		syn := &x.Inline[i]
+		trig := syn.triggers()
+		for k := 0; k < len(trig); k++ {
+			if strings.IndexByte(builtin, trig[k]) != -1 {
+				list = append(list, Conflict{syn.Name, "built-in", trig[k]})
+			}
+		}
+		for j := range x.Inline[:i] {
+			other := &x.Inline[j]
+			if other.Priority != syn.Priority ||
+				!strings.HasPrefix(syn.Prefix, other.Prefix) && !strings.HasPrefix(other.Prefix, syn.Prefix) {
+				continue
+			}
+			for k := 0; k < len(trig); k++ {
+				if strings.IndexByte(other.triggers(), trig[k]) != -1 {
+					list = append(list, Conflict{syn.Name, other.Name, trig[k]})
+					break
+				}
+			}
+		}
+	}
+	return list
+}
+
+// sortInline orders x.Inline by priority.
+func (x *Extensions) sortInline() {
+	list := make([]InlineSyntax, len(x.Inline))
+	copy(list, x.Inline)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Priority > list[j].Priority
+	})
+	x.Inline = list
+}
+
+// isInlineTrigger reports whether the byte at s[i] may
+// start the syntax of an inline extension.
+func (x *Extensions) isInlineTrigger(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	for j := range x.Inline {
+		if strings.IndexByte(x.Inline[j].triggers(), s[i]) != -1 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchInline matches the syntax of an inline extension at s,
+// returning the matching syntax, and the length of the match.
+func (x *Extensions) matchInline(s string) (syn *InlineSyntax, n int) {
+	if s == "" {
+		return nil, 0
+	}
+	for i := range x.Inline {
+		syn = &x.Inline[i]
+		if strings.IndexByte(syn.triggers(), s[0]) == -1 || !strings.HasPrefix(s, syn.Prefix) {
+			continue
+		}
+		if n, _, _ = syn.Parse(s); n > 0 {
+			return syn, n
+		}
+	}
+	return nil, 0
+}
+
+// inlineExtension is used as a predicate by the grammar; it
+// advances *pos past the syntax of an inline extension.
+func (p *yyParser) inlineExtension(pos *int) bool {
+	_, n := p.extension.matchInline(p.Buffer[*pos:])
+	*pos += n
+	return n > 0
+}
+
+// mkInlineExtension returns the element for text, which has
+// been matched by inlineExtension.
+func (p *yyParser) mkInlineExtension(text string) *element {
+	syn, _ := p.extension.matchInline(text)
+	_, kind, content := syn.Parse(text)
+	el := p.mkString(content)
+	el.key = int(kind)
+	return el
+}
+
+// A BlockSyntax adds block syntax, producing elements of registered
+// kinds, to the parser. Blocks are rendered like paragraphs by
+// formatters that don't know their kind.
+type BlockSyntax struct {
+	Name string
+
+	// Characters that may start the first line of the block, after
+	// up to three spaces of indentation. If empty, the first byte
+	// of Prefix is used.
+	Triggers string
+
+	// If set, the syntax is tried only for blocks starting with Prefix.
+	Prefix string
+
+	// Syntax of higher priority is tried first; syntax of
+	// the same priority in the order of Extensions.Blocks.
+	// All block syntax is tried before the built-in syntax.
+	Priority int
+
+	// Parse is called for text starting with one of the Triggers,
+	// with the indentation removed. It returns the length of the
+	// matched prefix, zero if there is none, and the kind and
+	// content of the resulting element. The match is extended to
+	// the end of its last line. The result must not depend on the
+	// text after the match.
+	Parse func(text string) (n int, kind ElementKind, content string)
+}
+
+func (syn *BlockSyntax) triggers() string {
+	if syn.Triggers == "" && syn.Prefix != "" {
+		return syn.Prefix[:1]
+	}
+	return syn.Triggers
+}
+
+// sortBlocks orders x.Blocks by priority.
+func (x *Extensions) sortBlocks() {
+	list := make([]BlockSyntax, len(x.Blocks))
+	copy(list, x.Blocks)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Priority > list[j].Priority
+	})
+	x.Blocks = list
+}
+
+// matchBlock matches the syntax of a block extension at s, the
+// beginning of a line, returning the matching syntax, and the
+// length of the match, including the indentation.
+func (x *Extensions) matchBlock(s string) (syn *BlockSyntax, n int) {
+	indent := 0
+	for indent < 3 && indent < len(s) && s[indent] == ' ' {
+		indent++
+	}
+	text := s[indent:]
+	if text == "" {
+		return nil, 0
+	}
+	for i := range x.Blocks {
+		syn = &x.Blocks[i]
+		if strings.IndexByte(syn.triggers(), text[0]) == -1 || !strings.HasPrefix(text, syn.Prefix) {
+			continue
+		}
+		if n, _, _ = syn.Parse(text); n > 0 {
+			if n < len(text) && text[n-1] != '\n' {
+				if j := strings.IndexByte(text[n:], '\n'); j != -1 {
+					n += j + 1
+				} else {
+					n = len(text)
+				}
+			}
+			return syn, indent + n
+		}
+	}
+	return nil, 0
+}
+
+// blockExtension is used as a predicate by the grammar; it
+// advances *pos past the syntax of a block extension.
+func (p *yyParser) blockExtension(pos *int) bool {
+	if len(p.extension.Blocks) == 0 {
+		return false
+	}
+	_, n := p.extension.matchBlock(p.Buffer[*pos:])
+	*pos += n
+	return n > 0
+}
+
+// mkBlockExtension returns the element for text, which has
+// been matched by blockExtension.
+func (p *yyParser) mkBlockExtension(text string) *element {
+	syn, _ := p.extension.matchBlock(text)
+	_, kind, content := syn.Parse(strings.TrimLeft(text, " "))
+	el := p.mkString(content)
+	el.key = int(kind)
+	block := p.mkElem(EXTBLOCK)
+	block.children = el
+	return block
+}
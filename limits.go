@@ -0,0 +1,95 @@
+package markdown
+
+// Limits on the size of documents, and of their output
+
+import (
+	"strconv"
+)
+
+// A LimitError reports that a document exceeded a limit set by
+// Options.MaxNodes or Options.MaxOutput; see Parser.Err.
+type LimitError struct {
+	Limit string // "nodes", or "output bytes"
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return "markdown: document exceeds the limit of " + strconv.Itoa(e.Max) + " " + e.Limit
+}
+
+// Err returns a *LimitError if the most recent call of Markdown, or
+// Parse, was aborted because the document exceeded a limit, or nil.
+func (p *Parser) Err() error {
+	if p.err == nil {
+		return nil
+	}
+	return p.err
+}
+
+// An outputLimiter is a formatter whose output can be limited.
+// Writes beyond the limit are dropped.
+type outputLimiter interface {
+	limitOutput(max int)
+	outputExceeded() bool
+}
+
+func (w *baseWriter) limitOutput(max int) {
+	w.lines.max, w.lines.n, w.lines.exceeded = max, 0, false
+}
+
+func (w *baseWriter) outputExceeded() bool {
+	return w.lines.exceeded
+}
+
+func (s *shiftFormatter) limitOutput(max int) {
+	if l, ok := s.f.(outputLimiter); ok {
+		l.limitOutput(max)
+	}
+}
+
+func (s *shiftFormatter) outputExceeded() bool {
+	l, ok := s.f.(outputLimiter)
+	return ok && l.outputExceeded()
+}
+
+func (t *titleFormatter) limitOutput(max int) {
+	if l, ok := t.f.(outputLimiter); ok {
+		l.limitOutput(max)
+	}
+}
+
+func (t *titleFormatter) outputExceeded() bool {
+	l, ok := t.f.(outputLimiter)
+	return ok && l.outputExceeded()
+}
+
+// A nodeCounter counts the elements of block trees, as Nodes
+// of the document's tree would. Bodies of notes are counted
+// once, however often they are referenced.
+type nodeCounter struct {
+	n     int
+	notes map[*element]bool
+}
+
+func (c *nodeCounter) count(list *element) {
+	for ; list != nil; list = list.next {
+		c.n++
+		if l := list.contents.link; l != nil {
+			switch list.key {
+			case LINK, IMAGE, REFERENCE:
+				c.count(l.label)
+				continue
+			}
+		}
+		if list.key == NOTE && list.children != nil {
+			if c.notes[list.children] {
+				continue
+			}
+			if c.notes == nil {
+				c.notes = make(map[*element]bool)
+			}
+			c.notes[list.children] = true
+		}
+		c.count(list.children)
+	}
+}
@@ -0,0 +1,65 @@
+package markdown
+
+// Building list items
+
+import (
+	"strings"
+)
+
+// mkListItem returns a LISTITEM element for the lines of an item,
+// given as a reversed list of strings. The text of the item is kept
+// in RAW children, to be parsed by processRawBlocks. RAW elements in
+// lines, made by mkContinuation, start new chunks of text that are
+// parsed one by one, like a nested list following a line of text
+// without a blank line in between.
+func (p *yyParser) mkListItem(lines *element) *element {
+	var chunks *element
+	var b strings.Builder
+	flush := func() {
+		raw := p.mkString(b.String())
+		raw.key = RAW
+		chunks = cons(raw, chunks)
+		b.Reset()
+	}
+	for l := reverse(lines); l != nil; l = l.next {
+		if l.key == RAW {
+			flush()
+		}
+		b.WriteString(l.contents.str)
+	}
+	flush()
+	item := p.mkElem(LISTITEM)
+	item.children = reverse(chunks)
+	return item
+}
+
+// mkContinuation returns the text of a continuation block of a list
+// item, given as a reversed list of strings. If the block does not
+// follow a blank line, the list ends with an empty RAW element, and
+// the result is a RAW element, too, starting a new chunk.
+func (p *yyParser) mkContinuation(lines *element) *element {
+	first := lines
+	for first != nil && first.next != nil {
+		first = first.next
+	}
+	el := p.mkStringFromList(lines, false)
+	if first != nil && first.key == RAW {
+		el.key = RAW
+	}
+	return el
+}
+
+// markLoose marks an item of a loose list. Its last chunk of text
+// is parsed with looseEnd set, so that its last block ends like one
+// followed by a blank line, resulting in a paragraph, for example.
+func markLoose(item *element) {
+	item.loose = true
+	last := item.children
+	if last == nil {
+		return
+	}
+	for last.next != nil {
+		last = last.next
+	}
+	last.loose = true
+}
@@ -0,0 +1,28 @@
+package markdown
+
+// Limiting the parsing of long lines
+
+import (
+	"strings"
+)
+
+// lineTail is used as a predicate by the grammar. If *pos is at
+// least Extensions.MaxLineLength bytes past the start of its line,
+// it advances *pos to the end of the line, so that the rest of the
+// line is taken as literal text.
+func (p *yyParser) lineTail(pos *int) bool {
+	max := p.extension.MaxLineLength
+	if max <= 0 || *pos < max {
+		return false
+	}
+	s := p.Buffer
+	if strings.IndexByte(s[*pos-max:*pos], '\n') != -1 {
+		return false
+	}
+	n := strings.IndexByte(s[*pos:], '\n')
+	if n == -1 {
+		n = len(s) - *pos
+	}
+	*pos += n
+	return n > 0
+}
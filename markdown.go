@@ -20,8 +20,8 @@ package markdown
 import (
 	"bytes"
 	"io"
-	"log"
 	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -36,37 +36,426 @@ const (
 
 // Markdown Extensions.
 type Extensions struct {
-	Smart        bool
-	Notes        bool
-	FilterHTML   bool
-	FilterStyles bool
-	Dlists       bool
-	Table        bool
+	Smart        bool `desc:"typographic quotes, dashes, and ellipses"`
+	Notes        bool `desc:"footnotes, like [^1] and ^[inline notes]"`
+	FilterHTML   bool `desc:"drop raw HTML"`
+	FilterStyles bool `desc:"drop <style> blocks"`
+	Dlists       bool `desc:"definition lists"`
+	Table        bool `desc:"tables"`
+	Mark         bool `desc:"==highlighted text=="`
+	Critic       bool `desc:"CriticMarkup changes and comments"`      // CriticMarkup: {++ins++}, {--del--}, {~~old~>new~~}, {==mark==}, {>>comment<<}
+	HardWraps    bool `desc:"line breaks within paragraphs as <br/>"` // render line breaks within paragraphs as <br/>, like GFM comments
+	ParenLists   bool `desc:"ordered list markers like \"1)\""`       // accept "1)", besides "1.", as ordered list markers
+
+	// Render references to undefined notes as placeholders,
+	// and report them as diagnostics; effective if Notes is set.
+	UndefinedNotes bool `desc:"mark references to undefined notes"`
+
+	// How table rows whose cells don't match the columns of the
+	// separator line are handled; effective if Table is set.
+	TableRows TableRows
+
+	// If TableContinuation is set, a table row with a cell ending
+	// in " \", a space and a backslash, is continued by the next
+	// row, as in MultiMarkdown: the cells of the latter are appended
+	// to those of the same columns, after a line break.
+	TableContinuation bool `desc:"table rows continued by a trailing backslash"`
+
+	// If TableRowspan is set, a table cell containing only "^^"
+	// extends the cell above it by a row, as in MultiMarkdown.
+	TableRowspan bool `desc:"table cells spanning rows, marked by ^^ below"`
+
+	// If GridTables is set, Pandoc's grid tables are recognized,
+	// whose cells, bordered by lines like "+---+---+", may span
+	// several lines and contain blocks, like lists.
+	GridTables bool `desc:"Pandoc grid tables, whose cells may contain blocks"`
+
+	// If TableBlocks is set, the contents of the cells of pipe
+	// tables are parsed as blocks, rather than inlines, so that a
+	// cell may contain a list, or, if rows are continued, see
+	// TableContinuation, several paragraphs.
+	TableBlocks bool `desc:"table cells containing blocks, like lists"`
+
+	DuplicateRefs DuplicatePolicy // which of several definitions of a reference label is used
+	LabelMatch    LabelMatch      // how reference labels are compared
+
+	// If set, NormalizeLabel is applied to the text of reference
+	// labels before they are compared, e.g. norm.NFC.String from
+	// golang.org/x/text/unicode/norm, so that labels written using
+	// composed and decomposed characters match.
+	NormalizeLabel func(string) string
+
+	// Names of the elements that may enclose an HTML block;
+	// DefaultHTMLBlockTags, and custom elements, if nil.
+	HTMLBlockTags []string
+
+	// How HTML blocks are delimited, and whether they interrupt
+	// paragraphs.
+	HTMLBlocks HTMLBlockStyle
+
+	// How raw HTML in the input is treated. If unset, raw HTML is
+	// passed through, or dropped if FilterHTML is set.
+	RawHTML HTMLPolicy
+
+	// Entity options. With StrictEntities, named entities not in
+	// the HTML5 entity table are shown as literal text. With
+	// DecodeEntities, entities are decoded to UTF-8 text, for
+	// formatters other than HTML.
+	StrictEntities bool `desc:"unknown named entities as text"`
+	DecodeEntities bool `desc:"entities decoded to UTF-8 text"`
+
+	// Inline syntax added by extensions, tried before the built-in
+	// syntax, by priority; see InlineConflicts.
+	Inline []InlineSyntax
+
+	// Block syntax added by extensions, tried before the
+	// built-in syntax, by priority.
+	Blocks []BlockSyntax
+
+	// Container blocks, like "::: warning" ... ":::", and
+	// admonitions, like `!!! note "Title"`, followed by
+	// indented lines.
+	Containers bool `desc:"container blocks and admonitions"`
+
+	// If set, lines like "{{include: path}}" are replaced by the
+	// text Include returns for path. Included text may contain
+	// further directives, nested up to MaxIncludeDepth levels, or
+	// 8, if zero; paths are passed unchanged, so relative paths
+	// must be resolved by Include. Failed includes are left in
+	// place, and reported as diagnostics. Directives within code
+	// blocks and code spans are not expanded.
+	Include         func(path string) ([]byte, error)
+	MaxIncludeDepth int
+
+	// If positive, inline syntax is recognized only within the
+	// first MaxLineLength bytes of a line; the rest of a longer
+	// line, e.g. of minified code, is taken as literal text,
+	// which keeps the time spent parsing it linear.
+	MaxLineLength int
+
+	// With StrictAtx, the #'s starting an ATX heading must be
+	// followed by a space, so that "#hashtag" is not a heading.
+	// KeepAtxClosing keeps a closing sequence of #'s as part of
+	// the heading text, instead of removing it.
+	StrictAtx      bool `desc:"require a space after the #'s of headings"`
+	KeepAtxClosing bool `desc:"keep closing #'s not preceded by a space"`
+
+	// Restrictions of setext headings, which are underlined by rows
+	// of '=' or '-': NoSetext disables them; SetextMinUnderline
+	// requires underlines of at least that many characters; with
+	// SetextSingleLine, only a line starting a block, not the last
+	// line of a paragraph, becomes a heading, if underlined. A line
+	// of '-' characters not underlining a heading then is a horizontal
+	// rule, which ends a paragraph, and needs no blank line after it.
+	NoSetext           bool `desc:"no setext headings"`
+	SetextMinUnderline int
+	SetextSingleLine   bool `desc:"setext headings of single lines only"`
+
+	// If TOC is set, a paragraph consisting of "[TOC]" is replaced
+	// by a table of contents, a list of links to the top-level
+	// headings; "[TOC local]" lists only the subsections of the
+	// section it appears in. The links refer to the identifiers
+	// generated with HTMLOptions.HeadingIDs.
+	TOC bool `desc:"tables of contents for [TOC] markers"`
+
+	// If TableRefs is set, "[#label]" refers to the captioned table
+	// with the given label, and is rendered as a link named after
+	// the number of the table, like "Table 2". Tables are numbered
+	// in the order of their captions; the label of a table without
+	// an explicit one is derived from its caption.
+	TableRefs bool `desc:"numbered table captions, and references to them"`
+
+	// If Figures is set, a paragraph consisting of an image, which
+	// may be followed by a caption on the next lines, is a figure.
+	// Without a caption, the title of the image is used, if any.
+	Figures bool `desc:"images with captions as figures"`
+
+	// If Hashtags is set, words like "#tag", preceded by white
+	// space or punctuation, are hashtags, which Parser.Tags lists.
+	// Tags consisting of digits only, like "#1", are ignored. If
+	// HashtagURL is set, it is called with each tag, without "#",
+	// and if it returns a URL, the tag is rendered as a link to it.
+	// As with StrictAtx, the #'s of an ATX heading must be followed
+	// by a space, so that a line may start with a tag.
+	Hashtags   bool `desc:"#hashtags"`
+	HashtagURL func(tag string) (url string)
+
+	// If Placeholders is set, names enclosed in double braces, like
+	// "{{first_name}}", are placeholders, which FillPlaceholders
+	// replaces by values. Formatters write the remaining ones like
+	// "{{name}}", unaffected by smart punctuation and emphasis. Names
+	// consist of letters, digits, and "_.-", and start with a
+	// letter or "_"; spaces may surround them.
+	Placeholders bool `desc:"{{placeholders}} of templates"`
+
+	// If Citations is set, bracketed references to works, like
+	// "[@doe99]" or "[see @doe99, pp. 33-35; also -@smith04]", in the
+	// style of Pandoc, are citations, which HTMLOptions.Cite may
+	// render. Each of the references separated by semicolons must
+	// contain a key preceded by '@'; a '-' before it suppresses the
+	// author's name. Brackets followed by '(' or '[' are links.
+	Citations bool `desc:"citations, like [@doe99, p. 33]"`
+
+	// If Comments is set, text enclosed in "%%", or in "<!---" and
+	// "--->", is a comment, which formatters omit, so that authors
+	// may leave notes in the source that are not published. Comments
+	// on lines of their own, which may contain blank lines, are
+	// blocks; those within paragraphs end at blank lines. If
+	// HTMLOptions.Comments is set, they are written as HTML comments.
+	Comments bool `desc:"%% comments %% omitted from the output"`
+
+	// If UnicodeSpaces is set, no-break spaces (U+00A0), and the
+	// other Unicode space separators, as found in text pasted from
+	// word processors, are white space, like spaces and tabs: they
+	// separate words, e.g. for emphasis, and may follow list
+	// markers. They are written as ordinary spaces.
+	UnicodeSpaces bool `desc:"no-break and other Unicode spaces as white space"`
+
+	// If ReplaceInvalidUTF8 is set, bytes of the input that are not
+	// part of valid UTF-8 sequences are replaced by U+FFFD, the
+	// replacement character, as suggested by CommonMark. NUL bytes
+	// are always replaced.
+	ReplaceInvalidUTF8 bool `desc:"replace invalid UTF-8 by U+FFFD"`
+
+	// Core inline syntax may be turned off, e.g. for comments,
+	// where only a subset of Markdown is allowed; the markup is then
+	// taken as literal text. NoEmphasis turns off emphasis and strong
+	// emphasis; with NoImages, images become links, unless NoLinks
+	// is set, too. NoLinks leaves autolinks alone. To show raw HTML
+	// as text, set RawHTML to EscapeHTML.
+	NoEmphasis      bool `desc:"no emphasis"`
+	NoCode          bool `desc:"no code spans"`
+	NoLinks         bool `desc:"no links"`
+	NoImages        bool `desc:"no images"`
+	NoAutolinks     bool `desc:"no <http://...> autolinks"`       // <http://...>
+	NoAutolinkEmail bool `desc:"no <user@example.com> autolinks"` // <user@example.com>
+
+	// Definition list options, effective if Dlists is set.
+	DefMarkers   string // runes accepted as definition markers; ":~" if empty
+	DefBlankLine bool   `desc:"require a blank line between terms and definitions"` // require a blank line between a term and its definitions
+}
+
+// An HTMLPolicy selects what happens to raw HTML in the input.
+type HTMLPolicy int
+
+const (
+	PassHTML   HTMLPolicy = iota // copy HTML to the output
+	DropHTML                     // discard HTML
+	EscapeHTML                   // show HTML as literal text, e.g. "<custom-tag>"
+)
+
+func (x *Extensions) rawHTMLPolicy() HTMLPolicy {
+	if x.RawHTML == PassHTML && x.FilterHTML {
+		return DropHTML
+	}
+	return x.RawHTML
+}
+
+// startList is used as a predicate by the grammar at the first
+// marker of a list; it records the delimiter of an ordered list, like
+// the ')' of "1)", see sameDelim.
+func (p *yyParser) startList(pos int) bool {
+	p.listDelim = enumDelim(p.Buffer[pos:])
+	return true
+}
+
+// sameDelim is used as a predicate by the grammar; it reports
+// whether the list marker at pos may continue the list being parsed:
+// an ordered list ends at a marker with a different delimiter.
+func (p *yyParser) sameDelim(pos int) bool {
+	d := enumDelim(p.Buffer[pos:])
+	return p.listDelim == 0 || d == 0 || d == p.listDelim
+}
+
+// enumDelim returns the delimiter of the enumerator s starts
+// with, or 0, if there is none.
+func enumDelim(s string) byte {
+	s = strings.TrimLeft(s, " ")
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i == 0 || i == len(s) {
+		return 0
+	}
+	return s[i]
+}
+
+// atxSpace is used as a predicate by the grammar; it reports
+// whether the opening #'s of an ATX heading, ending at pos, are
+// followed by white space, if required.
+func (p *yyParser) atxSpace(pos int) bool {
+	if !p.extension.StrictAtx && !p.extension.Hashtags || pos == len(p.Buffer) {
+		return true
+	}
+	switch p.Buffer[pos] {
+	case ' ', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
+
+// primes maps quotes used as marks of feet and inches
+// to the corresponding prime characters.
+var primes = map[string]string{
+	"'":  "′",
+	"\"": "″",
+}
+
+// prime is used as a predicate by the grammar; it reports whether
+// the quote at pos is part of a measurement in feet and inches,
+// like 6'2", which is printed using primes, and, in Smart mode,
+// neither opens nor closes a quotation.
+func (p *yyParser) prime(pos int) bool {
+	s := p.Buffer
+	if pos >= len(s) {
+		return false
+	}
+	switch s[pos] {
+	case '\'':
+		i := pos + 1
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		return pos > 0 && isDigit(s[pos-1]) && i > pos+1 && i < len(s) && s[i] == '"'
+	case '"':
+		i := pos
+		for i > 0 && isDigit(s[i-1]) {
+			i--
+		}
+		return i < pos && i > 1 && s[i-1] == '\'' && isDigit(s[i-2])
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// setextUnderline is used as a predicate by the grammar; it
+// reports whether the underline of a setext heading at pos is
+// long enough.
+func (p *yyParser) setextUnderline(pos int) bool {
+	min := p.extension.SetextMinUnderline
+	s := p.Buffer[pos:]
+	if min <= 1 || s == "" {
+		return true
+	}
+	n := 1
+	for n < len(s) && s[n] == s[0] {
+		n++
+	}
+	return n >= min
+}
+
+// setextSplits reports whether the last line of a paragraph
+// may become a setext heading; otherwise a rule of '-' characters
+// ends the paragraph.
+func (p *yyParser) setextSplits() bool {
+	return !p.extension.NoSetext && !p.extension.SetextSingleLine
+}
+
+// isDefMarker reports whether s starts with one of the runes
+// accepted as a definition list marker.
+func (x *Extensions) isDefMarker(s string) bool {
+	if s == "" {
+		return false
+	}
+	markers := x.DefMarkers
+	if markers == "" {
+		markers = ":~"
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return strings.ContainsRune(markers, r)
 }
 
 type Parser struct {
 	yy           yyParser
 	preformatBuf *bytes.Buffer
+	diags        []Diagnostic
+	noteUndefs   map[*element][]string /* undefined notes referenced by notes */
+	session      *Session              /* set while parsing a fragment of a session */
+	includeDiags []Diagnostic          /* problems found while expanding include directives */
+	srcMap       sourceMap             /* files of the parts of the expanded text, if any have been included */
+	offsets      offsetMap             /* edits made to the source while preformatting it, unless anything has been included */
+	maxDepth     int                   /* nesting limit of blocks, if positive */
+	depth        int                   /* nesting level of the blocks being processed */
+	trace        io.Writer             /* see SetTrace */
+	traceDepth   int
+	untraced     []func() bool /* rules of the grammar, if tracing has been set up */
+	maxNodes     int           /* limit of the elements of a document, if positive */
+	maxOutput    int           /* limit of the output of formatters, if positive */
+	err          *LimitError   /* see Err */
 }
 
-// NewParser creates an instance of a parser. It can be reused
-// so that stacks and buffers need not be allocated anew for
-// each Markdown call.
-func NewParser(x *Extensions) (p *Parser) {
-	p = new(Parser)
-	if x != nil {
-		p.yy.state.extension = *x
+// NewParser creates an instance of a parser, configured by
+// options, like NewParser(WithNotes(), WithTables()), or, as
+// before, NewParser(&Extensions{Notes: true, Table: true}). It can
+// be reused so that stacks and buffers need not be allocated anew
+// for each Markdown call.
+func NewParser(options ...Option) (p *Parser) {
+	var opt Options
+	for _, o := range options {
+		if o != nil {
+			o.apply(&opt)
+		}
 	}
+	p = new(Parser)
+	p.maxDepth = opt.MaxDepth
+	p.maxNodes = opt.MaxNodes
+	p.maxOutput = opt.MaxOutput
+	p.yy.state.extension = opt.Extensions
+	p.yy.state.extension.sortInline()
+	p.yy.state.extension.sortBlocks()
+	p.yy.state.special = p.yy.state.extension.specialChars()
+	p.yy.state.refs = make(mapStore)
 	p.yy.Init()
 	p.yy.state.heap.init(1024)
 	p.preformatBuf = bytes.NewBuffer(make([]byte, 0, 32768))
 	return
 }
 
+// Reset releases the memory a parser retains after parsing a
+// document: the text of the document, the elements parsed from it,
+// its references and notes, and buffers that have grown with the
+// size of earlier input. A long-running program, like a server,
+// that keeps parsers for reuse, may call Reset after rendering a
+// large document, so that this memory can be garbage collected.
+// Extensions, and a reference store set by SetReferenceStore, are
+// kept; so are diagnostics, which refer to no document text.
+func (p *Parser) Reset() {
+	p.yy.ResetBuffer("")
+	p.yy.Init() /* drops the stacks of thunks and values */
+	if p.untraced != nil {
+		p.untraced = nil
+		p.traceRules()
+	}
+	p.yy.state.heap = elemHeap{}
+	p.yy.state.heap.init(1024)
+	p.yy.state.tree = nil
+	p.yy.state.notes = nil
+	p.yy.state.inlineNotes = false
+	p.yy.state.undefNotes = nil
+	p.yy.state.tableRows = nil
+	if _, ok := p.yy.state.refs.(mapStore); ok {
+		p.yy.state.refs = make(mapStore)
+	}
+	p.noteUndefs = nil
+	p.includeDiags = nil
+	p.offsets = nil
+	p.preformatBuf = bytes.NewBuffer(make([]byte, 0, 32768))
+}
+
 // A Formatter is called repeatedly, one Markdown block at a time,
 // while the document is parsed. At the end of a document the Finish
 // method is called, which may, for example, print footnotes.
 // A Formatter can be reused.
+//
+// The elements passed to FormatBlock belong to the parser, which
+// reuses them for the blocks that follow, and for later documents.
+// A formatter must not keep them beyond Finish; those of blocks
+// referring to notes stay valid until then. Trees that need to be
+// kept are returned by Parser.Parse and Parser.ParseDocument.
 type Formatter interface {
 	FormatBlock(*element)
 	Finish()
@@ -75,31 +464,118 @@ type Formatter interface {
 // Markdown parses input from an io.Reader into a tree, and sends
 // parsed blocks to a Formatter
 func (p *Parser) Markdown(src io.Reader, f Formatter) {
-	s := p.preformat(src)
+	p.markdown(p.source(src), f)
+}
 
+// markdown parses the preformatted text s.
+func (p *Parser) markdown(s string, f Formatter) {
+	p.diags = append(p.diags[:0], p.includeDiags...)
+	p.yy.state.tags, p.yy.state.tagSet = nil, nil
 	p.parseRule(ruleReferences, s)
 	if p.yy.extension.Notes {
-		p.parseRule(ruleNotes, s)
+		p.parseNotes(s)
+		if p.session != nil {
+			p.session.addNotes()
+		}
+		p.processNotes()
 	}
 	savedPos := p.yy.state.heap.Pos()
 
+	var toc *tocState
+	if p.yy.extension.TOC && strings.Contains(s, "[TOC") {
+		toc = p.tocHeadings(s)
+	}
+	p.yy.state.tables = nil
+	if p.yy.extension.TableRefs && strings.Contains(s, "[#") {
+		p.yy.state.tables = p.tableNumbers(s)
+	}
+
+	var refDefs map[string]Position
+	tableLabels := make(map[string]Position)
+
+	sf, _ := f.(spanFormatter)
+	lim, _ := f.(outputLimiter)
+	if lim != nil {
+		lim.limitOutput(p.maxOutput)
+	}
+	var nodes nodeCounter
+	p.err = nil
+	lines := newLineCounter(s, p.offsets)
 	for {
+		start := len(lines.src) - len(s)
 		tree := p.parseRule(ruleDocblock, s)
 		if tree == nil {
 			break
 		}
 		s = p.yy.ResetBuffer("")
 		tree = p.processRawBlocks(tree)
-		f.FormatBlock(tree)
-		p.yy.state.heap.setPos(savedPos)
+		if toc != nil {
+			tree = toc.block(&p.yy, tree, lines.src[start:len(lines.src)-len(s)])
+		}
+		undef := p.undefinedNotes(tree)
+		rows := p.yy.state.tableRows
+		p.yy.state.tableRows = nil
+		if sf != nil || tree.key == REFERENCE || tree.key == TABLE || undef != nil || rows != nil {
+			if span, ok := lines.span(start, len(lines.src)-len(s)); ok {
+				if tree.key == REFERENCE {
+					if refDefs == nil {
+						refDefs = make(map[string]Position)
+					}
+					p.checkReference(tree, lines.src, span, refDefs)
+				}
+				if undef != nil {
+					p.checkNotes(undef, lines, span)
+				}
+				if tree.key == TABLE || rows != nil {
+					p.checkTable(tree, rows, lines, span, tableLabels)
+				}
+				if sf != nil {
+					sf.setSpan(span)
+				}
+			}
+		}
+		if p.maxNodes > 0 {
+			nodes.count(tree)
+			if nodes.n > p.maxNodes {
+				p.err = &LimitError{"nodes", p.maxNodes}
+			}
+		}
+		if p.err == nil {
+			f.FormatBlock(tree)
+		}
+		if p.yy.state.inlineNotes {
+			/* keep the block, formatters may print its notes at the end */
+			savedPos = p.yy.state.heap.Pos()
+			p.yy.state.inlineNotes = false
+		} else {
+			p.yy.state.heap.setPos(savedPos)
+		}
+		if p.err == nil && lim != nil && lim.outputExceeded() {
+			p.err = &LimitError{"output bytes", p.maxOutput}
+		}
+		if p.err != nil {
+			/* the rest of the document is dropped */
+			break
+		}
 	}
 	f.Finish()
+	if p.err == nil && lim != nil && lim.outputExceeded() {
+		/* notes, printed at the end, may exceed the limit as well */
+		p.err = &LimitError{"output bytes", p.maxOutput}
+	}
+	if len(p.includeDiags) > 0 {
+		p.sortDiagnostics()
+	}
+	if p.srcMap != nil {
+		p.mapDiagnostics()
+	}
 }
 
 func (p *Parser) parseRule(rule int, s string) (tree *element) {
 	old := p.yy.ResetBuffer(s)
 	if old != "" && strings.Trim(old, "\r\n ") != "" {
-		log.Fatalln("Buffer not empty", "["+old+"]")
+		/* not expected: the text left by an earlier parse is dropped */
+		p.diagnose(Position{}, Error, "internal error: unparsed text dropped")
 	}
 	err := p.yy.Parse(rule)
 	switch rule {
@@ -119,60 +595,152 @@ func (p *Parser) parseRule(rule int, s string) (tree *element) {
 func (p *Parser) processRawBlocks(input *element) *element {
 
 	for current := input; current != nil; current = current.next {
+		nested := false
 		if current.key == RAW {
-			/* \001 is used to indicate boundaries between nested lists when there
-			 * is no blank line.  We split the string by \001 and parse
-			 * each chunk separately.
+			/* Consecutive RAW elements, like the chunks of text of a
+			 * list item, see mkListItem, are parsed one by one, and
+			 * replaced by a single list of the resulting blocks.
 			 */
-			current.key = LIST
-			current.children = nil
-			listEnd := &current.children
-			for _, contents := range strings.Split(current.contents.str, "\001") {
-				if list := p.parseRule(ruleDoc, contents); list != nil {
-					*listEnd = list
-					for list.next != nil {
-						list = list.next
+			end := current.next
+			for end != nil && end.key == RAW {
+				end = end.next
+			}
+			var blocks *element
+			if p.maxDepth > 0 && p.depth >= p.maxDepth {
+				/* too deeply nested: keep the text */
+				var text strings.Builder
+				for c := current; c != end; c = c.next {
+					text.WriteString(c.contents.str)
+				}
+				current.key = PLAIN
+				blocks = p.yy.mkString(strings.TrimRight(text.String(), "\n"))
+			} else {
+				nested = true
+				listEnd := &blocks
+				for c := current; c != end; c = c.next {
+					p.yy.state.looseEnd = c.loose
+					list := p.parseRule(ruleDoc, c.contents.str)
+					p.yy.state.looseEnd = false
+					if list != nil {
+						*listEnd = list
+						for list.next != nil {
+							list = list.next
+						}
+						listEnd = &list.next
 					}
-					listEnd = &list.next
 				}
+				current.key = LIST
 			}
+			current.children = blocks
 			current.contents.str = ""
+			current.loose = false
+			current.next = end
 		}
-		if current.children != nil {
+		/* Notes are processed in advance, see processNotes; the
+		 * children of note references point to shared note bodies,
+		 * which may refer back to the note being processed.
+		 */
+		if current.children != nil && current.key != NOTE {
+			if nested {
+				p.depth++
+			}
 			current.children = p.processRawBlocks(current.children)
+			if nested {
+				p.depth--
+			}
 		}
 	}
 	return input
 }
 
+// parseNotes indexes the notes defined in s, including those
+// within block quotes and list items, at any depth. Of notes with
+// equal labels, top-level ones take precedence over nested ones.
+func (p *Parser) parseNotes(s string) {
+	p.yy.state.notes = nil
+	p.yy.state.noteBlocks = nil
+	p.parseRule(ruleNotes, s)
+	for len(p.yy.state.noteBlocks) > 0 {
+		b := p.yy.state.noteBlocks[0]
+		p.yy.state.noteBlocks = p.yy.state.noteBlocks[1:]
+		if strings.Contains(b, "[^") {
+			p.parseRule(ruleNotes, b)
+		}
+	}
+	p.yy.state.noteBlocks = nil
+}
+
+// processNotes parses the bodies of all footnotes, before the
+// document's blocks are parsed, so that the resulting elements
+// outlive the blocks referring to them. A note body may contain
+// references to other notes, or to itself.
+func (p *Parser) processNotes() {
+	p.noteUndefs = nil
+	for _, note := range p.yy.state.notes {
+		p.yy.state.undefNotes = nil
+		p.yy.state.tableRows = nil
+		note.children = p.processRawBlocks(note.children)
+		if undef := p.yy.state.undefNotes; undef != nil {
+			if p.noteUndefs == nil {
+				p.noteUndefs = make(map[*element][]string)
+			}
+			p.noteUndefs[note] = undef
+		}
+	}
+	p.yy.state.undefNotes = nil
+	p.yy.state.tableRows = nil
+}
+
+// undefinedNotes returns the labels of undefined notes referenced
+// from within a block; for note definitions, from within their
+// bodies, which have been parsed in advance.
+func (p *Parser) undefinedNotes(tree *element) (labels []string) {
+	if tree.key == NOTE && tree.contents.str != "" {
+		return p.noteUndefs[p.yy.state.notes[tree.contents.str]]
+	}
+	labels = p.yy.state.undefNotes
+	p.yy.state.undefNotes = nil
+	return
+}
+
 const (
 	TABSTOP = 4
+	utf8BOM = "\xef\xbb\xbf"
 )
 
-/* preformat - allocate and copy text buffer while
- * performing tab expansion.
- */
-func (p *Parser) preformat(r io.Reader) (s string) {
+// preformat allocates and copies the text buffer while
+// performing tab expansion.
+//
+// A byte order mark starting the text is dropped, and lines ending
+// in a carriage return only, as on classic Mac OS, get a newline
+// instead; CRLF line endings are left to the grammar. NUL bytes, and
+// invalid UTF-8, if Extensions.ReplaceInvalidUTF8 is set, are
+// replaced, see sanitize. The edits changing offsets are returned
+// in m.
+func (p *Parser) preformat(r io.Reader) (s string, m offsetMap) {
 	charstotab := TABSTOP
 	buf := make([]byte, 32768)
 
 	b := p.preformatBuf
 	b.Reset()
+	src := 0 /* source offset of buf[0] */
+	n, err := io.ReadFull(r, buf[:len(utf8BOM)])
+	if string(buf[:n]) == utf8BOM {
+		m = append(m, offsetEdit{0, 0, 0, n})
+		src, n = n, 0
+	}
 	for {
-		n, err := r.Read(buf)
-		if err != nil {
-			break
-		}
 		i0 := 0
 		for i, c := range buf[:n] {
 			switch c {
 			case '\t':
 				b.Write(buf[i0:i])
+				m = append(m, offsetEdit{b.Len(), charstotab, src + i, 1})
 				for ; charstotab > 0; charstotab-- {
 					b.WriteByte(' ')
 				}
 				i0 = i + 1
-			case '\n':
+			case '\n', '\r':
 				b.Write(buf[i0 : i+1])
 				i0 = i + 1
 				charstotab = TABSTOP
@@ -184,8 +752,65 @@ func (p *Parser) preformat(r io.Reader) (s string) {
 			}
 		}
 		b.Write(buf[i0:n])
+		if err != nil {
+			break
+		}
+		src += n
+		n, err = r.Read(buf)
 	}
 
+	text := b.Bytes()
+	for i, c := range text {
+		if c == '\r' && (i+1 == len(text) || text[i+1] != '\n') {
+			text[i] = '\n'
+		}
+	}
 	b.WriteString("\n\n")
-	return b.String()
+	return p.sanitize(b.String(), m)
+}
+
+// sanitize replaces NUL bytes, which the parser uses to build keys
+// of reference labels, and, if Extensions.ReplaceInvalidUTF8 is set,
+// bytes not part of valid UTF-8 sequences, by U+FFFD, as CommonMark
+// suggests. The edits m, made before, are moved accordingly.
+func (p *Parser) sanitize(s string, m offsetMap) (string, offsetMap) {
+	invalid := p.yy.extension.ReplaceInvalidUTF8 && !utf8.ValidString(s)
+	if !invalid && strings.IndexByte(s, 0) == -1 {
+		return s, m
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	edits := make(offsetMap, 0, len(m))
+	pending := m
+	replace := func(i int) {
+		edits = append(edits, offsetEdit{b.Len(), len(string(utf8.RuneError)), m.source(i), 1})
+		b.WriteRune(utf8.RuneError)
+	}
+	for i := 0; i < len(s); {
+		for len(pending) > 0 && pending[0].off == i {
+			/* edits are located at spaces, which are copied */
+			e := pending[0]
+			e.off = b.Len()
+			edits = append(edits, e)
+			pending = pending[1:]
+		}
+		c := s[i]
+		switch {
+		case c == 0:
+			replace(i)
+			i++
+		case c < utf8.RuneSelf || !invalid:
+			b.WriteByte(c)
+			i++
+		default:
+			r, n := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && n == 1 {
+				replace(i)
+			} else {
+				b.WriteString(s[i : i+n])
+			}
+			i += n
+		}
+	}
+	return b.String(), edits
 }
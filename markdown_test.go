@@ -2,13 +2,25 @@ package markdown
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
+	"html"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 )
 
+var update = flag.Bool("update", false, "update the .html and .mm files of the test suites in ./tests")
+
 const (
 	TestHtml = 1 << iota
 	TestGroff
@@ -16,8 +28,10 @@ const (
 
 // for each pair of .text/.html files in the given subdirectory
 // of `./tests' compare the expected html output with
-// the output of Parser.Markdown.
-func runDirTests(dir string, t *testing.T, testsToRun int) {
+// the output of Parser.Markdown, using the extensions x.
+// With -update, the expected output is replaced by the
+// current one.
+func runDirTests(dir string, x *Extensions, t *testing.T, testsToRun int) {
 
 	dirPath := filepath.Join("tests", dir)
 	f, err := os.Open(dirPath)
@@ -30,12 +44,13 @@ func runDirTests(dir string, t *testing.T, testsToRun int) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	sort.Strings(names)
 
 	var buf bytes.Buffer
 	fHTML := ToHTML(&buf)
 	fGroff := ToGroffMM(&buf)
 
-	p := NewParser(&Extensions{Table: true})
+	p := NewParser(x)
 
 	for _, name := range names {
 		if filepath.Ext(name) != ".text" {
@@ -59,8 +74,6 @@ func runDirTests(dir string, t *testing.T, testsToRun int) {
 // is, for each test, available in either a .html or a .mm file accompanying
 // the .text file, with the output of this package's Markdown processor.
 func compareOutput(w *bytes.Buffer, f Formatter, ext string, textPath string, p *Parser) (err error) {
-	var bOrig bytes.Buffer
-
 	r, err := os.Open(textPath)
 	if err != nil {
 		return
@@ -74,24 +87,95 @@ func compareOutput(w *bytes.Buffer, f Formatter, ext string, textPath string, p
 	base := textPath[:len(textPath)-len(".text")]
 	refPath := base + ext
 
-	r, err = os.Open(refPath)
+	if *update {
+		return os.WriteFile(refPath, w.Bytes(), 0666)
+	}
+	want, err := os.ReadFile(refPath)
 	if err != nil {
 		return
 	}
-	defer r.Close()
-	bOrig.ReadFrom(r)
-	if bytes.Compare(bOrig.Bytes(), w.Bytes()) != 0 {
-		err = fmt.Errorf("test %q failed", refPath)
+	if line, got, exp, ok := firstDiff(w.String(), string(want)); !ok {
+		err = fmt.Errorf("test %q failed at line %d: got %q, want %q", refPath, line, got, exp)
 	}
 	return
 }
 
+// firstDiff compares two texts line by line, and returns the
+// first differing lines, if any.
+func firstDiff(got, want string) (line int, g, w string, equal bool) {
+	gl := strings.SplitAfter(got, "\n")
+	wl := strings.SplitAfter(want, "\n")
+	for i := 0; i < len(gl) || i < len(wl); i++ {
+		g, w = "", ""
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if g != w {
+			return i + 1, g, w, false
+		}
+	}
+	return 0, "", "", true
+}
+
 func TestMarkdown103(t *testing.T) {
-	runDirTests("md1.0.3", t, TestHtml|TestGroff)
+	runDirTests("md1.0.3", &Extensions{Table: true}, t, TestHtml|TestGroff)
 }
 
+// TestMultiMarkdown compares the tables converted from the files of
+// the MultiMarkdown suite with those of MultiMarkdown's output; the
+// rest of the documents differs in known ways, see tests/README.md.
 func TestMultiMarkdown(t *testing.T) {
-	runDirTests("MultiMarkdown", t, TestHtml)
+	names, err := filepath.Glob(filepath.Join("tests", "MultiMarkdown", "*.text"))
+	if err != nil || len(names) == 0 {
+		t.Fatal("no test files", err)
+	}
+	p := NewParser(&Extensions{Table: true, Notes: true, Smart: true})
+	for _, name := range names {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile(strings.TrimSuffix(name, ".text") + ".html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		p.Markdown(bytes.NewReader(src), ToHTMLWithOptions(&buf, &HTMLOptions{ASCII: true}))
+		got, exp := htmlTables(buf.String()), htmlTables(string(want))
+		if exp == "" {
+			t.Errorf("%s: no tables expected", name)
+		}
+		if line, g, w, ok := firstDiff(got, exp); !ok {
+			t.Errorf("%s: tables differ at line %d: got %q, want %q", name, line, g, w)
+		}
+	}
+}
+
+// noteRef matches a link to a note, whose markup differs
+// between MultiMarkdown and this package.
+var noteRef = regexp.MustCompile(`<a [^>]*>(\[[0-9]+\])</a>`)
+
+// htmlTables returns the tables of an HTML document, with
+// links to notes reduced to their text.
+func htmlTables(doc string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(doc, "<table")
+		if i == -1 {
+			break
+		}
+		n := strings.Index(doc[i:], "</table>")
+		if n == -1 {
+			break
+		}
+		n += len("</table>")
+		b.WriteString(doc[i:i+n] + "\n")
+		doc = doc[i+n:]
+	}
+	return noteRef.ReplaceAllString(b.String(), "$1")
 }
 
 // This test will make the test run fail with a
@@ -114,3 +198,2486 @@ func TestTrailingWhitespaceBug(t *testing.T) {
 	p := NewParser(nil)
 	p.Markdown(strings.NewReader(input), ToHTML(&buf))
 }
+
+// convert runs the HTML formatter on a Markdown input string.
+func convert(x *Extensions, input string) string {
+	var buf bytes.Buffer
+	p := NewParser(x)
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	return buf.String()
+}
+
+func TestDefinitionLists(t *testing.T) {
+	x := &Extensions{Dlists: true}
+	dash := &Extensions{Dlists: true, DefMarkers: "-"}
+	blank := &Extensions{Dlists: true, DefBlankLine: true}
+	tests := []struct {
+		x           *Extensions
+		input, want string
+	}{
+		{x, "Term\n: one\n~ two\n", "<dl>\n<dt>Term</dt><dd>one</dd>\n<dd>two</dd>\n</dl>\n"},
+		{x, "Term\n: para one\n\n    para two\n",
+			"<dl>\n<dt>Term</dt><dd><p>para one</p>\n\n<p>para two</p></dd>\n</dl>\n"},
+		{x, "Term\n\n: def\n\n* item\n",
+			"<dl>\n<dt>Term</dt><dd><p>def</p></dd>\n</dl>\n\n<ul>\n<li>item</li>\n</ul>\n"},
+
+		/* custom markers replace the default ones */
+		{dash, "Term\n- one\n", "<dl>\n<dt>Term</dt><dd>one</dd>\n</dl>\n"},
+		{dash, "Term\n: one\n", "<p>Term\n: one</p>\n"},
+
+		/* with DefBlankLine, only terms followed by a blank line start a list */
+		{blank, "Term\n\n: def\n", "<dl>\n<dt>Term</dt><dd><p>def</p></dd>\n</dl>\n"},
+		{blank, "Term\n: def\n", "<p>Term\n: def</p>\n"},
+		{x, "Term\n\n: def\n", "<dl>\n<dt>Term</dt><dd><p>def</p></dd>\n</dl>\n"},
+	}
+	for _, tt := range tests {
+		if got := convert(tt.x, tt.input); got != tt.want {
+			t.Errorf("input %q:\ngot  %q\nwant %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLASCII(t *testing.T) {
+	const input = "Ünïcode \"smart\" -- text... &copy; &amp;\n"
+	const want = "<p>&#220;n&#239;code &#8220;smart&#8221; &#8212; text&#8230; &#169; &amp;</p>\n"
+
+	var buf bytes.Buffer
+	p := NewParser(&Extensions{Smart: true})
+	p.Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{ASCII: true}))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMark(t *testing.T) {
+	const input = "a ==marked *text*== b = c == d\n"
+	const want = "<p>a <mark>marked <em>text</em></mark> b = c == d</p>\n"
+
+	if got := convert(&Extensions{Mark: true}, input); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNewlines(t *testing.T) {
+	const input = "a\r\nb\r\n\r\n    code\r\n\r\n\r\n"
+	tests := []struct {
+		opt  HTMLOptions
+		want string
+	}{
+		{HTMLOptions{}, "<p>a\nb</p>\n\n<pre><code>code\n</code></pre>\n"},
+		{HTMLOptions{Newline: "\r\n"}, "<p>a\r\nb</p>\r\n\r\n<pre><code>code\r\n</code></pre>\r\n"},
+		{HTMLOptions{NoFinalNewline: true}, "<p>a\nb</p>\n\n<pre><code>code\n</code></pre>"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		NewParser(nil).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &test.opt))
+		if got := buf.String(); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.opt, got, test.want)
+		}
+	}
+}
+
+func TestCriticMarkup(t *testing.T) {
+	const input = "a {++b++} {--c--} {~~d~>e~~} {==f==}{>>g<<}\n"
+	tests := []struct {
+		mode CriticMode
+		want string
+	}{
+		{CriticMarkup, `<p>a <ins>b</ins> <del>c</del> <del>d</del><ins>e</ins> <mark>f</mark><span class="critic comment">g</span></p>` + "\n"},
+		{CriticAccept, "<p>a b  e f</p>\n"},
+		{CriticReject, "<p>a  c d f</p>\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		p := NewParser(&Extensions{Critic: true})
+		p.Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{Critic: test.mode}))
+		if got := buf.String(); got != test.want {
+			t.Errorf("mode %d: got %q, want %q", test.mode, got, test.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"héllo", 2, "hé"},
+		{"héllo", 2, "hé"},
+		{"a👍🏽b", 2, "a👍🏽"},
+		{"👩‍👩‍👧x", 1, "👩‍👩‍👧"},
+		{"🇩🇪🇫🇷", 1, "🇩🇪"},
+		{"a\r\nb", 2, "a\r\n"},
+		{"abc", 5, "abc"},
+	}
+	for _, test := range tests {
+		got, cut := Truncate(test.s, test.n)
+		if got != test.want || cut != (got != test.s) {
+			t.Errorf("Truncate(%q, %d) = %q, %v; want %q", test.s, test.n, got, cut, test.want)
+		}
+	}
+	if got, _ := TruncateBytes("aé", 2); got != "a" {
+		t.Errorf("TruncateBytes split a combining sequence: %q", got)
+	}
+	if n := GraphemeCount("🇩🇪é👍🏽"); n != 3 {
+		t.Errorf("GraphemeCount = %d, want 3", n)
+	}
+}
+
+func TestHighlighter(t *testing.T) {
+	const input = "    a < b\n\ntext\n\n    skip\n"
+	const want = "<div class=\"hl\">a < b\n</div>\n\n<p>text</p>\n\n<pre><code>skip\n</code></pre>\n"
+
+	hl := func(lang, code string) (string, bool) {
+		if lang != "" || strings.HasPrefix(code, "skip") {
+			return "", false
+		}
+		return `<div class="hl">` + code + "</div>", true
+	}
+	var buf bytes.Buffer
+	NewParser(nil).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{Highlighter: hl}))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSourcePos(t *testing.T) {
+	const input = "# Head\n\n\npara\nline\n\n* a\n* b\n\n---\n"
+	const want = `<h1 data-sourcepos="1:1-1:6">Head</h1>
+
+<p data-sourcepos="4:1-5:4">para
+line</p>
+
+<ul data-sourcepos="7:1-8:3">
+<li>a</li>
+<li>b</li>
+</ul>
+
+<hr data-sourcepos="10:1-10:3" />
+`
+	var buf bytes.Buffer
+	NewParser(nil).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{SourcePos: true}))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+type sharedRefs struct {
+	mapStore
+}
+
+func (s sharedRefs) Reset() {
+	s.mapStore.Reset()
+	s.Define("GO", Reference{URL: "https://golang.org/"})
+}
+
+func TestReferenceStore(t *testing.T) {
+	const input = "[Go] and [Peg][] and [*x*].\n\n[peg]: /peg \"PEG\"\n[*X*]: /x\n[PEG]: /other\n"
+	const want = `<p><a href="https://golang.org/">Go</a> and <a href="/peg" title="PEG">Peg</a> and <a href="/x"><em>x</em></a>.</p>` + "\n"
+
+	var buf bytes.Buffer
+	p := NewParser(nil)
+	p.SetReferenceStore(sharedRefs{make(mapStore)})
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDuplicateReferences(t *testing.T) {
+	const input = "[a]\n\n[a]: /one\n\n[A]: /two\n"
+	tests := []struct {
+		policy DuplicatePolicy
+		want   string
+		diag   string
+	}{
+		{FirstWins, `<p><a href="/one">a</a></p>` + "\n", "5:1: warning: reference [A] already defined at line 3"},
+		{LastWins, `<p><a href="/two">a</a></p>` + "\n", "5:1: warning: reference [A] already defined at line 3"},
+		{DuplicatesError, `<p><a href="/one">a</a></p>` + "\n", "5:1: error: reference [A] already defined at line 3"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		p := NewParser(&Extensions{DuplicateRefs: test.policy})
+		p.Markdown(strings.NewReader(input), ToHTML(&buf))
+		if got := buf.String(); got != test.want {
+			t.Errorf("policy %d: got %q, want %q", test.policy, got, test.want)
+		}
+		if d := p.Diagnostics(); len(d) != 1 || d[0].String() != test.diag {
+			t.Errorf("policy %d: diagnostics %v, want %q", test.policy, d, test.diag)
+		}
+	}
+}
+
+func TestReferenceTitles(t *testing.T) {
+	/* titles may follow on the next line; URLs may be enclosed in angle brackets */
+	const input = "[a], [b], [c], [d]\n\n[a]: /one\n    \"One\"\n[b]: /two\n\t'Two'\n[c]: </three>\n  (Three)\n[d]: <http://example.com/>  \"Four\"\n"
+	want := `<p><a href="/one" title="One">a</a>, <a href="/two" title="Two">b</a>, ` +
+		`<a href="/three" title="Three">c</a>, <a href="http://example.com/" title="Four">d</a></p>` + "\n"
+	var buf bytes.Buffer
+	NewParser(nil).Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReferenceImageAlt(t *testing.T) {
+	/* images in reference style without alternative text use the label of the reference */
+	const input = "![][Logo] ![ ][logo] ![Alt][logo] ![logo] ![](/x.png)\n\n[logo]: /logo.png\n"
+	want := `<p><img src="/logo.png" alt="Logo" /> <img src="/logo.png" alt="logo" /> ` +
+		`<img src="/logo.png" alt="Alt" /> <img src="/logo.png" alt="logo" /> <img src="/x.png" alt="" /></p>` + "\n"
+	p := NewParser(nil)
+	var buf bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var got []string
+	for _, n := range p.Parse(strings.NewReader(input)).Children[0].Children {
+		if n.Kind == IMAGE {
+			var alt strings.Builder
+			for _, c := range n.Children {
+				alt.WriteString(c.Text)
+			}
+			got = append(got, alt.String()+"/"+n.Ref)
+		}
+	}
+	if s := strings.Join(got, " "); s != "Logo/Logo logo/logo Alt/logo logo/logo /" {
+		t.Errorf("got alt/ref %q", s)
+	}
+}
+
+func TestLinkDestinations(t *testing.T) {
+	const input = "[text](<my file.png>) ![i](<a b.png> \"T\") [x](foo(bar)) [y](<a(b>) [z](my file) [r]\n\n" +
+		"[r]: <my file.png> \"R\"\n"
+	want := `<p><a href="my%20file.png">text</a> <img src="a%20b.png" alt="i" title="T" /> ` +
+		`<a href="foo(bar)">x</a> <a href="a(b">y</a> [z](my file) <a href="my%20file.png" title="R">r</a></p>` + "\n"
+	var buf bytes.Buffer
+	NewParser(nil).Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHTML5(t *testing.T) {
+	const input = "a  \nb ![i](x.png)\n\n---\n"
+	const want = "<p>a<br>\nb <img src=\"x.png\" alt=\"i\"></p>\n\n<hr>\n"
+
+	var buf bytes.Buffer
+	NewParser(nil).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{Flavor: HTML5}))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNestedNotes(t *testing.T) {
+	const input = "a[^1] b[^1]\n\n[^1]: one[^2]\n\n[^2]: two[^1]\n"
+	const want = `<p>a<a class="noteref" id="fnref1" href="#fn1" title="Jump to note 1">[1]</a> b<a class="noteref" href="#fn1" title="Jump to note 1">[1]</a></p>
+
+<hr/>
+<ol id="notes">
+<li id="fn1">
+<p>one<a class="noteref" id="fnref2" href="#fn2" title="Jump to note 2">[2]</a></p> <a href="#fnref1" title="Jump back to reference">[back]</a>
+</li>
+<li id="fn2">
+<p>two<a class="noteref" href="#fn1" title="Jump to note 1">[1]</a></p> <a href="#fnref2" title="Jump back to reference">[back]</a>
+</li>
+</ol>
+`
+	if got := convert(&Extensions{Notes: true}, input); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNoteList(t *testing.T) {
+	const notes = `<hr/>
+<ol id="notes">
+<li id="fn1">
+<p>one</p> <a href="#fnref1" title="Jump back to reference">[back]</a>
+</li>
+<li id="fn2">
+<p>two</p> <a href="#fnref2" title="Jump back to reference">[back]</a>
+</li>
+</ol>
+`
+	p := NewParser(&Extensions{Notes: true})
+	render := func(input string, opt *HTMLOptions) string {
+		var buf bytes.Buffer
+		p.Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, opt))
+		return buf.String()
+	}
+
+	var list NoteList
+	opt := &HTMLOptions{Notes: &list}
+	a := render("a[^x]\n\n[^x]: one\n", opt)
+	b := render("b[^x]\n\n[^x]: two\n", opt)
+	if want := `<p>b<a class="noteref" id="fnref2" href="#fn2" title="Jump to note 2">[2]</a></p>` + "\n"; b != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+	var buf bytes.Buffer
+	list.WriteHTML(&buf, nil)
+	if got := buf.String(); got != notes {
+		t.Errorf("got %q, want %q", got, notes)
+	}
+
+	/* fragments numbered independently */
+	var l1, l2 NoteList
+	a1 := render("a[^x]\n\n[^x]: one\n", &HTMLOptions{Notes: &l1})
+	b2 := render("b[^x]\n\n[^x]: two\n", &HTMLOptions{Notes: &l2})
+	r := l1.Merge(&l2)
+	if a1 != a || r.Replace(b2) != b {
+		t.Errorf("merged fragments differ: %q, %q", a1, r.Replace(b2))
+	}
+	buf.Reset()
+	l1.WriteHTML(&buf, nil)
+	if got := buf.String(); got != notes {
+		t.Errorf("got %q, want %q", got, notes)
+	}
+
+	if got := render("a[^x]\n\n[^x]: one\n", &HTMLOptions{NoNotes: true}); got != a {
+		t.Errorf("got %q, want %q", got, a)
+	}
+}
+
+func TestRawHTMLPolicy(t *testing.T) {
+	const input = "<div>x</div>\n\na <custom-tag>b\n"
+	tests := []struct {
+		x    Extensions
+		want string
+	}{
+		{Extensions{}, "<div>x</div>\n\n<p>a <custom-tag>b</p>\n"},
+		{Extensions{FilterHTML: true}, "<p>a b</p>\n"},
+		{Extensions{RawHTML: DropHTML}, "<p>a b</p>\n"},
+		{Extensions{RawHTML: EscapeHTML}, "<p>&lt;div&gt;x&lt;/div&gt;</p>\n\n<p>a &lt;custom-tag&gt;b</p>\n"},
+		{Extensions{RawHTML: EscapeHTML, FilterHTML: true}, "<p>&lt;div&gt;x&lt;/div&gt;</p>\n\n<p>a &lt;custom-tag&gt;b</p>\n"},
+	}
+	for _, test := range tests {
+		if got := convert(&test.x, input); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.x, got, test.want)
+		}
+	}
+}
+
+func TestHTMLBlockTags(t *testing.T) {
+	const input = "<section>\n<section>*a*</section>\n</section>\n\n<my-widget>b</my-widget>\n"
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{nil, "<section>\n<section>*a*</section>\n</section>\n\n<my-widget>b</my-widget>\n"},
+		{[]string{"section"}, "<section>\n<section>*a*</section>\n</section>\n\n<p><my-widget>b</my-widget></p>\n"},
+	}
+	for _, test := range tests {
+		if got := convert(&Extensions{HTMLBlockTags: test.tags}, input); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.tags, got, test.want)
+		}
+	}
+}
+
+func TestUndefinedNotes(t *testing.T) {
+	const input = "a[^1] b[^x]\n\n* c [^y]\n\n[^1]: one [^z]\n"
+	var buf bytes.Buffer
+	p := NewParser(&Extensions{Notes: true, UndefinedNotes: true})
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); !strings.Contains(got, `b<sup class="undefined-note">[^x]</sup>`) {
+		t.Errorf("no placeholder in %q", got)
+	}
+	want := []string{
+		"1:8: warning: undefined note [^x]",
+		"3:5: warning: undefined note [^y]",
+		"5:11: warning: undefined note [^z]",
+	}
+	d := p.Diagnostics()
+	if len(d) != len(want) {
+		t.Fatalf("got diagnostics %v, want %q", d, want)
+	}
+	for i := range want {
+		if d[i].String() != want[i] {
+			t.Errorf("got %q, want %q", d[i], want[i])
+		}
+	}
+}
+
+func TestHTMLSpecial(t *testing.T) {
+	const input = "<!DOCTYPE html>\n\n<![CDATA[\n*a* < b\n]]>\n\nx <?php echo 1; ?> y\n"
+	const want = "<!DOCTYPE html>\n\n<![CDATA[\n*a* < b\n]]>\n\n<p>x <?php echo 1; ?> y</p>\n"
+
+	if got := convert(nil, input); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+var (
+	mentionKind = RegisterKind("MENTION", FallbackText)
+	emojiKind   = RegisterKind("EMOJI", FallbackOmit)
+)
+
+func TestInlineSyntax(t *testing.T) {
+	const input = "hi @bob :wave: x@ & @, *@carol*\n"
+	const want = "<p>hi <a href=\"/bob\">@bob</a>  x@ &amp; @, <em><a href=\"/carol\">@carol</a></em></p>\n"
+
+	word := func(s string) int {
+		n := 1
+		for n < len(s) && s[n] >= 'a' && s[n] <= 'z' {
+			n++
+		}
+		return n
+	}
+	x := &Extensions{Inline: []InlineSyntax{
+		{Triggers: "@", Parse: func(s string) (int, ElementKind, string) {
+			if n := word(s); n > 1 {
+				return n, mentionKind, s[1:n]
+			}
+			return 0, 0, ""
+		}},
+		{Triggers: ":", Parse: func(s string) (int, ElementKind, string) {
+			if n := word(s); n > 1 && n < len(s) && s[n] == ':' {
+				return n + 1, emojiKind, s[1:n]
+			}
+			return 0, 0, ""
+		}},
+	}}
+	var buf bytes.Buffer
+	mention := func(name string) string { return `<a href="/` + name + `">@` + name + "</a>" }
+	opt := &HTMLOptions{Kinds: map[ElementKind]func(string) string{mentionKind: mention}}
+	NewParser(x).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, opt))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// fallbacks
+	const wantText = "<p>hi bob  x@ &amp; @, <em>carol</em></p>\n"
+	if got := convert(x, input); got != wantText {
+		t.Errorf("got %q, want %q", got, wantText)
+	}
+	if s := mentionKind.String(); s != "MENTION" {
+		t.Errorf("kind name %q", s)
+	}
+}
+
+var mathKind = RegisterKind("MATH", FallbackRaw)
+
+func TestBlockSyntax(t *testing.T) {
+	const input = "a\n\n$$\nx < y\n$$\n\n* item\n\n    $$\n    z\n    $$\n\n$$ no\n"
+	const want = "<p>a</p>\n\n<div class=\"math\">x &lt; y</div>\n\n<ul>\n<li><p>item</p>\n\n<div class=\"math\">z</div></li>\n</ul>\n\n<p>$$ no</p>\n"
+	const wantRaw = "<p>a</p>\n\nx < y\n\n<ul>\n<li><p>item</p>\n\nz</li>\n</ul>\n\n<p>$$ no</p>\n"
+
+	x := &Extensions{Blocks: []BlockSyntax{{
+		Prefix: "$$\n",
+		Parse: func(s string) (int, ElementKind, string) {
+			if end := strings.Index(s[3:], "\n$$\n"); end != -1 {
+				return 3 + end + 4, mathKind, s[3 : 3+end]
+			}
+			return 0, 0, ""
+		},
+	}}}
+	var buf bytes.Buffer
+	math := func(tex string) string { return `<div class="math">` + html.EscapeString(tex) + "</div>" }
+	opt := &HTMLOptions{Kinds: map[ElementKind]func(string) string{mathKind: math}}
+	NewParser(x).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, opt))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := convert(x, input); got != wantRaw {
+		t.Errorf("got %q, want %q", got, wantRaw)
+	}
+}
+
+func TestEntities(t *testing.T) {
+	const input = "&copy; &#65; &#x42; &bogus; &ampx; &semi;\n"
+	for _, tc := range []struct {
+		x    Extensions
+		want string
+	}{
+		{Extensions{}, "<p>&copy; &#65; &#x42; &bogus; &ampx; &semi;</p>\n"},
+		{Extensions{StrictEntities: true}, "<p>&copy; &#65; &#x42; &amp;bogus; &amp;ampx; &semi;</p>\n"},
+		{Extensions{DecodeEntities: true}, "<p>© A B &bogus; &ampx; ;</p>\n"},
+	} {
+		if got := convert(&tc.x, input); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.x, got, tc.want)
+		}
+	}
+}
+
+func TestInlineConflicts(t *testing.T) {
+	parse := func(skip int, label string) func(string) (int, ElementKind, string) {
+		return func(s string) (int, ElementKind, string) {
+			n := strings.IndexAny(s, " \n")
+			if n == -1 {
+				n = len(s)
+			}
+			return n, mentionKind, label + s[skip:n]
+		}
+	}
+	x := &Extensions{Mark: true, Inline: []InlineSyntax{
+		{Name: "tag", Triggers: "#@", Parse: parse(0, "tag:")},
+		{Name: "mention", Prefix: "@", Parse: parse(0, "")},
+		{Name: "team", Prefix: "@@", Priority: 1, Parse: parse(2, "team:")},
+		{Name: "equals", Prefix: "==", Priority: 1, Parse: parse(0, "")},
+	}}
+	var got []string
+	for _, c := range x.InlineConflicts() {
+		got = append(got, c.Error())
+	}
+	want := []string{
+		"inline syntax tag conflicts with built-in syntax at '#'",
+		"inline syntax mention conflicts with tag syntax at '@'",
+		"inline syntax equals conflicts with built-in syntax at '='",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	/* triggers of optional built-in syntax */
+	for _, tc := range []struct {
+		x      Extensions
+		prefix string
+		want   string
+	}{
+		{Extensions{}, "{", ""},
+		{Extensions{Placeholders: true}, "{", "inline syntax var conflicts with built-in syntax at '{'"},
+		{Extensions{}, "%", ""},
+		{Extensions{Comments: true}, "%", "inline syntax var conflicts with built-in syntax at '%'"},
+	} {
+		tc.x.Inline = []InlineSyntax{{Name: "var", Prefix: tc.prefix, Parse: parse(0, "")}}
+		got := ""
+		for _, c := range tc.x.InlineConflicts() {
+			got += c.Error()
+		}
+		if got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+
+	// priorities
+	const input = "@@x @y\n"
+	const wantHTML = "<p>team:x tag:@y</p>\n"
+	if got := convert(x, input); got != wantHTML {
+		t.Errorf("got %q, want %q", got, wantHTML)
+	}
+}
+
+func TestHardWraps(t *testing.T) {
+	const input = "one\ntwo  \nthree\n\n* a\n  b\n"
+	const want = "<p>one<br/>\ntwo<br/>\nthree</p>\n\n<ul>\n<li>a<br/>\n b</li>\n</ul>\n"
+
+	if got := convert(&Extensions{HardWraps: true}, input); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHeadingLevels(t *testing.T) {
+	const input = "# One\n\n## Two\n\n##### Five\n"
+	for _, tc := range []struct {
+		opt  HTMLOptions
+		want string
+	}{
+		{HTMLOptions{HeadingOffset: 2}, "<h3>One</h3>\n\n<h4>Two</h4>\n\n<h6>Five</h6>\n"},
+		{HTMLOptions{HeadingOffset: 1, MaxHeadingLevel: 3}, "<h2>One</h2>\n\n<h3>Two</h3>\n\n<h3>Five</h3>\n"},
+		{HTMLOptions{HeadingOffset: -1}, "<h1>One</h1>\n\n<h1>Two</h1>\n\n<h4>Five</h4>\n"},
+	} {
+		var buf bytes.Buffer
+		NewParser(nil).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &tc.opt))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.opt, got, tc.want)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	const input = "# Intro *here*\n\ntext\n\n* a\n* b\n\n## Sub\n\n    code\n    more\n\n[ref]: /url\n\n# Next\n\n[^n]: Note.\n"
+	var d DocumentSymbols
+	NewParser(&Extensions{Notes: true}).Markdown(strings.NewReader(input), ToSymbols(&d))
+
+	var got []string
+	for _, s := range d.Symbols {
+		got = append(got, fmt.Sprintf("%d %d %s %v", s.Kind, s.Level, s.Name, s.Span))
+	}
+	for _, f := range d.Folds {
+		got = append(got, fmt.Sprintf("%d %v", f.Kind, f.Span))
+	}
+	want := []string{
+		"0 1 Intro here 1:1-1:14",
+		"0 2 Sub 8:1-8:6",
+		"1 0 ref 13:1-13:11",
+		"0 1 Next 15:1-15:6",
+		"2 0 n 17:1-17:11",
+		"0 1:1-13:11",
+		"1 5:1-6:3",
+		"0 8:1-13:11",
+		"2 10:1-11:8",
+		"0 15:1-17:11",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestIndex(t *testing.T) {
+	const input = "See [the site][Site] and [x](/y),\n" +
+		"[site], [undefined], [b][nope] and note[^n].\n\n" +
+		"[site]: http://example.com\n\n[^n]: A note.\n"
+	x := NewParser(&Extensions{Notes: true}).Index(strings.NewReader(input))
+
+	for _, tc := range []struct {
+		offset int
+		want   string
+	}{
+		{6, "PARA STR 1 Site 1:5-1:20 4:1-4:26"},
+		{17, "PARA LINK 1 Site 1:5-1:20 4:1-4:26"},
+		{27, "PARA LINK"},
+		{36, "PARA STR 1 site 2:1-2:6 4:1-4:26"},
+		{45, "PARA STR"},
+		{58, "PARA STR 1 nope 2:22-2:30 -"},
+		{76, "PARA NOTE 2 n 2:40-2:43 6:1-6:13"},
+		{79, ""},
+		{81, "REFERENCE REFERENCE 1 site 4:1-4:6 4:1-4:26"},
+		{95, "REFERENCE REFERENCE"},
+	} {
+		b, n, ref, ok := x.At(tc.offset)
+		got := ""
+		if ok {
+			got = b.Kind.String() + " " + n.Kind.String()
+		}
+		if ref != nil {
+			def := "-"
+			if ref.Def != nil {
+				def = ref.Def.Span.String()
+			}
+			got += fmt.Sprintf(" %d %s %v %s", ref.Kind, ref.Label, ref.Span, def)
+		}
+		if got != tc.want {
+			t.Errorf("offset %d: got %q, want %q", tc.offset, got, tc.want)
+		}
+	}
+
+	/* brackets within code spans are not references */
+	x = NewParser(nil).Index(strings.NewReader("Code `a[site]b` here.\n\n[site]: http://example.com\n"))
+	if _, n, ref, _ := x.At(8); n.Kind != CODE || ref != nil {
+		t.Errorf("code span: got %v, reference %v", n.Kind, ref)
+	}
+
+	/* offsets are those of the source, with tabs not expanded */
+	const tabs = "\ufeffa\tb\tc [a][r]\n\n[r]: /u\n"
+	x = NewParser(nil).Index(strings.NewReader(tabs))
+	b, n, ref, ok := x.At(strings.Index(tabs, "[a]"))
+	if !ok || b.Span.Start.Offset != 3 || b.Span.End.Offset != strings.Index(tabs, "\n")-1 || n.Kind != LINK || ref == nil || ref.Label != "r" {
+		t.Errorf("tabs: got %v %v, %v, reference %v", b.Kind, b.Span, n.Kind, ref)
+	}
+}
+
+func TestLabelMatch(t *testing.T) {
+	const input = "[STRAẞE][] [e\u0301té]\n\n[straße]: /a\n[Été]: /b\n"
+	for _, tc := range []struct {
+		x    Extensions
+		want string
+	}{
+		{Extensions{}, "<p>[STRAẞE][] [e\u0301té]</p>\n"},
+		{Extensions{LabelMatch: FoldedLabels}, "<p><a href=\"/a\">STRAẞE</a> [e\u0301té]</p>\n"},
+		{Extensions{NormalizeLabel: func(s string) string { return strings.Replace(s, "e\u0301", "é", -1) }},
+			"<p>[STRAẞE][] <a href=\"/b\">e\u0301té</a></p>\n"},
+	} {
+		if got := convert(&tc.x, input); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	const input = "# Getting *started*!\n\n## Getting started\n\n[a]: /a\n[A]: /b\n[b c]: /c\n\n[^n]: Note.\n"
+	var d DocumentSymbols
+	NewParser(&Extensions{Notes: true}).Markdown(strings.NewReader(input), ToSymbols(&d))
+
+	var got []string
+	for _, kind := range []SymbolKind{HeadingSymbol, ReferenceSymbol, NoteSymbol} {
+		for _, s := range d.Definitions(kind) {
+			got = append(got, fmt.Sprintf("%s%s %d:%d", s.Anchor, s.Name, s.Span.Start.Line, s.Span.Start.Col))
+		}
+	}
+	want := []string{"getting-startedGetting started! 1:1", "getting-started-1Getting started 3:1", "a 5:1", "b c 7:1", "n 9:1"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	const wantHTML = "<h1 id=\"getting-started\">Getting <em>started</em>!</h1>\n\n<h2 id=\"getting-started-1\">Getting started</h2>\n"
+	NewParser(nil).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{HeadingIDs: true}))
+	if got := buf.String(); got != wantHTML {
+		t.Errorf("got %q, want %q", got, wantHTML)
+	}
+}
+
+func TestFormatRange(t *testing.T) {
+	const src = "para one\n\n*\tx\n*\ty\n\npara three\n"
+	bullets := func(block string) string {
+		return strings.Replace(strings.Replace(block, "*\t", "- ", -1), "para", "Para", 1)
+	}
+	tests := []struct {
+		start, end int
+		want       []TextEdit
+	}{
+		{12, 12, []TextEdit{{10, 16, "- x\n- "}}},
+		{3, 12, []TextEdit{{0, 1, "P"}, {10, 16, "- x\n- "}}},
+		{9, 10, nil},
+		{20, 30, []TextEdit{{19, 20, "P"}}},
+	}
+	p := NewParser(nil)
+	for _, tt := range tests {
+		got := p.FormatRange(src, tt.start, tt.end, bullets)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("FormatRange(%d, %d): got %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+
+	/* line endings and a byte order mark are not part of a block */
+	upper := func(block string) string { return strings.ToUpper(block) + "!" }
+	for _, tt := range []struct {
+		src  string
+		want []TextEdit
+	}{
+		{"ab\r\ncd\r\n\r\nef\r\n", []TextEdit{{0, 6, "AB\r\nCD!"}}},
+		{"\ufeffab\n", []TextEdit{{3, 5, "AB!"}}},
+		{"\ufeffab\r\ncd", []TextEdit{{3, 9, "AB\r\nCD!"}}},
+	} {
+		got := p.FormatRange(tt.src, 4, 4, upper)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("FormatRange(%q): got %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	chapters := []string{
+		"[Go][] and [x].[^n]\n\n[x]: /one\n",
+		"[x] and [y].[^n]\n\n[^n]: Note.\n",
+		"[go]: https://golang.org/\n[x]: /three\n[y]: /y\n",
+	}
+	want := []string{
+		`<p><a href="https://golang.org/">Go</a> and <a href="/one">x</a>.<a class="noteref" id="fnref1" href="#fn1" title="Jump to note 1">[1]</a></p>` + "\n",
+		`<p><a href="/one">x</a> and <a href="/y">y</a>.<a class="noteref" id="fnref2" href="#fn2" title="Jump to note 2">[2]</a></p>` + "\n",
+		"\n",
+	}
+	s := NewParser(&Extensions{Notes: true}).NewSession()
+	for _, c := range chapters {
+		s.Add(strings.NewReader(c))
+	}
+	var notes NoteList
+	for i := 0; i < s.Len(); i++ {
+		var buf bytes.Buffer
+		s.Markdown(i, ToHTMLWithOptions(&buf, &HTMLOptions{Notes: &notes}))
+		if got := buf.String(); got != want[i] {
+			t.Errorf("chapter %d: got %q, want %q", i, got, want[i])
+		}
+	}
+	if len(notes.Notes) != 2 || notes.Notes[1].HTML != "<p>Note.</p>" {
+		t.Errorf("unexpected notes: %+v", notes.Notes)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	files := map[string]string{
+		"a.md":    "# A\n\n{{include: refs.md}}\n",
+		"refs.md": "[go]: https://golang.org/\n",
+		"loop.md": "{{include: loop.md}}\n",
+	}
+	include := func(path string) ([]byte, error) {
+		if s, ok := files[path]; ok {
+			return []byte(s), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	const input = "[Go]\n\n{{include: a.md}}\n\n{{include: none.md}}\n\n{{include: loop.md}}\n"
+	const want = `<p><a href="https://golang.org/">Go</a></p>
+
+<h1>A</h1>
+
+<p>{{include: none.md}}</p>
+
+<p>{{include: loop.md}}</p>
+`
+	wantDiags := []string{
+		"5:1: error: cannot include none.md: file does not exist",
+		"loop.md:1:1: error: include cycle: loop.md -> loop.md",
+	}
+	var buf bytes.Buffer
+	p := NewParser(&Extensions{Include: include})
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := fmt.Sprint(p.Diagnostics()); got != fmt.Sprint(wantDiags) {
+		t.Errorf("diagnostics: got %v, want %v", got, wantDiags)
+	}
+
+	buf.Reset()
+	p = NewParser(&Extensions{Include: include, MaxIncludeDepth: 1})
+	p.Markdown(strings.NewReader("{{include: a.md}}\n"), ToHTML(&buf))
+	if got := fmt.Sprint(p.Diagnostics()); got != "[a.md:3:1: error: include depth limit of 1 exceeded]" {
+		t.Errorf("depth limit: got %v", got)
+	}
+
+	/* directives within code are left alone */
+	buf.Reset()
+	p = NewParser(&Extensions{Include: include})
+	p.Markdown(strings.NewReader("    {{include: a.md}}\n\n```\n{{include: a.md}}\n```\n"), ToHTML(&buf))
+	if got, want := buf.String(), "<pre><code>{{include: a.md}}\n</code></pre>\n\n<p><code>\n{{include: a.md}}\n</code></p>\n"; got != want {
+		t.Errorf("code: got %q, want %q", got, want)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	const src = "# Head *em*\n\n> A [link](/u \"t\") and `code`\\*\n\n* [r][] &amp;\n\n[r]: /r\n"
+	want := []string{
+		"1:1-1:1 0 #", "1:3-1:6 1 Head", "1:8-1:8 4 *", "1:9-1:10 2 em", "1:11-1:11 4 *",
+		"3:1-3:1 11 >", "3:5-3:5 4 [", "3:6-3:9 6 link", "3:10-3:10 4 ]", "3:11-3:11 4 (",
+		"3:12-3:13 7 /u", "3:15-3:15 4 \"", "3:16-3:16 8 t", "3:17-3:18 4 \")",
+		"3:24-3:29 5 `code`", "3:30-3:30 15 \\",
+		"5:1-5:1 10 *", "5:3-5:3 4 [", "5:4-5:4 6 r", "5:5-5:5 4 ]", "5:6-5:6 4 [", "5:7-5:7 4 ]", "5:9-5:13 14 &amp;",
+		"7:1-7:1 4 [", "7:2-7:2 9 r", "7:3-7:3 4 ]", "7:4-7:4 4 :", "7:6-7:7 7 /r",
+	}
+	var got []string
+	for _, tok := range NewParser(nil).Tokens(strings.NewReader(src)) {
+		got = append(got, fmt.Sprintf("%v %d %s", tok.Span, tok.Kind, src[tok.Span.Start.Offset:tok.Span.End.Offset+1]))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	/* offsets are those of the source; columns count tabs expanded */
+	const tabs = "a\tb *y*\n"
+	got = nil
+	for _, tok := range NewParser(nil).Tokens(strings.NewReader(tabs)) {
+		got = append(got, fmt.Sprintf("%d %v %s", tok.Span.Start.Offset, tok.Span, tabs[tok.Span.Start.Offset:tok.Span.End.Offset+1]))
+	}
+	if want := "4 1:7-1:7 *,5 1:8-1:8 y,6 1:9-1:9 *"; strings.Join(got, ",") != want {
+		t.Errorf("tabs: got %q, want %q", strings.Join(got, ","), want)
+	}
+}
+
+func TestSourceOffsets(t *testing.T) {
+	/* a byte order mark, a tab, and a NUL byte change offsets */
+	const src = "\ufeffa\tb\x00\n\n[r]: /a\n\n[r]: /b\n"
+	p := NewParser(nil)
+	p.Markdown(strings.NewReader(src), ToHTML(io.Discard))
+	d := p.Diagnostics()
+	if len(d) != 1 {
+		t.Fatalf("got %v", d)
+	}
+	if want := strings.LastIndex(src, "[r]"); d[0].Pos.Offset != want || d[0].Pos.Line != 5 {
+		t.Errorf("got offset %d, line %d, want %d, 5", d[0].Pos.Offset, d[0].Pos.Line, want)
+	}
+}
+
+func TestIDPrefix(t *testing.T) {
+	const input = "# Intro\n\na[^1] <me@example.com>\n\n[^1]: Note, [up](#intro).\n"
+	const want = `<h1 id="ch1-intro">Intro</h1>
+
+<p>a<a class="md-noteref" id="ch1-fnref1" href="#ch1-fn1" title="Jump to note 1">[1]</a> `
+	render := func(opt *HTMLOptions) string {
+		var buf bytes.Buffer
+		NewParser(&Extensions{Notes: true}).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, opt))
+		return buf.String()
+	}
+	opt := &HTMLOptions{HeadingIDs: true, IDPrefix: "ch1-", ClassPrefix: "md-", Seed: 1}
+	got := render(opt)
+	if !strings.HasPrefix(got, want) || !strings.Contains(got, `<li id="ch1-fn1">`) || !strings.Contains(got, `href="#ch1-fnref1"`) ||
+		!strings.Contains(got, `<ol id="ch1-notes">`) || !strings.Contains(got, `<a href="#ch1-intro">up</a>`) {
+		t.Errorf("got %q", got)
+	}
+	for i := 0; i < 3; i++ {
+		if again := render(opt); again != got {
+			t.Fatalf("output varies with seed: %q, %q", got, again)
+		}
+	}
+}
+
+func TestHTMLBlocks(t *testing.T) {
+	for _, tc := range []struct {
+		style       HTMLBlockStyle
+		input, want string
+	}{
+		{LegacyHTMLBlocks, "<span>x</span> text\nmore\n", "<p><span>x</span> text\nmore</p>\n"},
+		{CommonMarkHTMLBlocks, "<span>x</span> text\nmore\n", "<p><span>x</span> text\nmore</p>\n"},
+		{LegacyHTMLBlocks, "text\n<div>x</div>\nmore\n", "<p>text\n<div>x</div>\nmore</p>\n"},
+		{CommonMarkHTMLBlocks, "text\n<div>x</div>\nmore\n", "<p>text</p>\n\n<div>x</div>\nmore\n"},
+		{LegacyHTMLBlocks, "<div>x</div> trailing\n", "<p><div>x</div> trailing</p>\n"},
+		{CommonMarkHTMLBlocks, "<div>x</div> trailing\n", "<div>x</div> trailing\n"},
+		{CommonMarkHTMLBlocks, "text\n<span>\nmore\n", "<p>text\n<span>\nmore</p>\n"},
+		{CommonMarkHTMLBlocks, "<span>\n*x*\n\npara\n", "<span>\n*x*\n\n<p>para</p>\n"},
+	} {
+		var buf bytes.Buffer
+		NewParser(&Extensions{HTMLBlocks: tc.style}).Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("style %d, %q: got %q, want %q", tc.style, tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestContainers(t *testing.T) {
+	for _, tc := range []struct {
+		input, want string
+	}{
+		{"::: warning\nBe *careful*.\n:::\n\nafter\n",
+			"<div class=\"warning\">\n<p>Be <em>careful</em>.</p>\n</div>\n\n<p>after</p>\n"},
+		{":::: outer\n::: inner Nested\nhi\n:::\n::::\n",
+			"<div class=\"outer\">\n<div class=\"inner\">\n<p class=\"title\">Nested</p>\n<p>hi</p>\n</div>\n</div>\n"},
+		{"!!! note \"Read this\"\n    Body.\n\n    More.\n\nafter\n",
+			"<div class=\"note\">\n<p class=\"title\">Read this</p>\n<p>Body.</p>\n\n<p>More.</p>\n</div>\n\n<p>after</p>\n"},
+		{"!!! tip\n    x\n", "<div class=\"tip\">\n<p class=\"title\">Tip</p>\n<p>x</p>\n</div>\n"},
+		{"::: open\nnot closed\n", "<p>::: open\nnot closed</p>\n"},
+	} {
+		var buf bytes.Buffer
+		NewParser(&Extensions{Containers: true}).Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestMaxLineLength(t *testing.T) {
+	const input = "a *b* xxxxxxxxxxxxxxxxxxxx *c* <b>\n*d* next\n"
+	const want = "<p>a <em>b</em> xxxxxxxxxxxxxxxxxxxx *c* &lt;b&gt;\n<em>d</em> next</p>\n"
+	var buf bytes.Buffer
+	NewParser(&Extensions{MaxLineLength: 12}).Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// longLine returns a paragraph consisting of a single line
+// of minified JSON, about n bytes long.
+func longLine(n int) string {
+	var b strings.Builder
+	b.WriteString("Data:\n\n[")
+	for i := 0; b.Len() < n; i++ {
+		fmt.Fprintf(&b, `{"id_%d":%d,"name":"a_b*c","html":"<b>x</b>","tags":["x","y"]},`, i, i)
+	}
+	b.WriteString("{}]\n\nAfter.\n")
+	return b.String()
+}
+
+func BenchmarkLongLine(b *testing.B) {
+	input := longLine(1 << 17)
+	for _, max := range []int{0, 4096} {
+		b.Run(fmt.Sprintf("max=%d", max), func(b *testing.B) {
+			p := NewParser(&Extensions{MaxLineLength: max})
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				p.Markdown(strings.NewReader(input), ToHTML(&buf))
+			}
+		})
+	}
+}
+
+// prose returns a document of n paragraphs of mostly plain text,
+// with some emphasis, links, and code, and a heading for every ten
+// paragraphs.
+func prose(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i%10 == 0 {
+			fmt.Fprintf(&b, "## Section %d\n\n", i/10+1)
+		}
+		b.WriteString("It was the best of times, it was the worst of times, it was the age of\n" +
+			"wisdom, it was the age of foolishness, it was the *epoch of belief*, it\n" +
+			"was the epoch of incredulity, it was the season of [Light](/light), it was\n" +
+			"the season of Darkness, it was the spring of hope, it was the `winter` of\n" +
+			"despair, we had everything before us, we had nothing before us.\n\n")
+	}
+	return b.String()
+}
+
+func BenchmarkProse(b *testing.B) {
+	input := prose(1000)
+	for _, tc := range []struct {
+		name string
+		x    *Extensions
+	}{
+		{"plain", nil},
+		{"extensions", &Extensions{Smart: true, Notes: true, Table: true, Dlists: true}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			p := NewParser(tc.x)
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				p.Markdown(strings.NewReader(input), ToHTML(&buf))
+			}
+		})
+	}
+}
+
+func TestOptions(t *testing.T) {
+	render := func(p *Parser, input string) string {
+		var buf bytes.Buffer
+		p.Markdown(strings.NewReader(input), ToHTML(&buf))
+		return buf.String()
+	}
+	const input = "a[^1] \"b\"\n\n[^1]: Note.\n"
+	want := render(NewParser(&Extensions{Notes: true, Smart: true}), input)
+	if got := render(NewParser(WithNotes(), WithSmart()), input); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := render(NewParser(&Extensions{Table: true}, WithExtensions(Extensions{}), WithOptions(Options{Extensions: Extensions{Notes: true}}), WithSmart()), input); got != want {
+		t.Errorf("options applied in wrong order: %q", got)
+	}
+
+	const nested = "> a\n>\n> > b\n> >\n> > > *c*\n"
+	const limited = "<blockquote>\n<p>a</p>\n\n<blockquote>\nb\n\n&gt; *c*\n</blockquote>\n</blockquote>\n"
+	if got := render(NewParser(WithMaxDepth(1)), nested); got != limited {
+		t.Errorf("got %q, want %q", got, limited)
+	}
+	if got := render(NewParser(WithMaxDepth(3)), nested); got != render(NewParser(), nested) {
+		t.Errorf("nesting limited below depth: %q", got)
+	}
+}
+
+func TestListStart(t *testing.T) {
+	for _, tc := range []struct {
+		input, want string
+		parens      bool
+	}{
+		{"3. a\n4. b\n", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n", false},
+		{"1. a\n2. b\n", "<ol>\n<li>a</li>\n<li>b</li>\n</ol>\n", false},
+		{"007) a\n8) b\n", "<ol start=\"7\">\n<li>a</li>\n<li>b</li>\n</ol>\n", true},
+		{"3) a\n", "<p>3) a</p>\n", false},
+		{"3) a\n7. b\n", "<ol start=\"3\">\n<li>a</li>\n</ol>\n\n<ol start=\"7\">\n<li>b</li>\n</ol>\n", true},
+		{"1. a\n\n2. b\n\n3) c\n", "<ol>\n<li><p>a</p></li>\n<li><p>b</p></li>\n</ol>\n\n<ol start=\"3\">\n<li>c</li>\n</ol>\n", true},
+	} {
+		var buf bytes.Buffer
+		NewParser(&Extensions{ParenLists: tc.parens}).Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	var b strings.Builder
+	for i := 0; b.Len() < 4<<20; i++ {
+		fmt.Fprintf(&b, "- item *%d* with a [link][r%d]\n\n  > quoted\n\n[r%d]: /url/%d\n\n", i, i, i, i)
+	}
+	input := b.String()
+	heap := func() uint64 {
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		return m.HeapAlloc
+	}
+
+	p := NewParser(nil)
+	var out bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&out))
+	input = ""
+	b.Reset()
+	out.Reset()
+	before := heap()
+	p.Reset()
+	after := heap()
+	if before < after || before-after < 4<<20 {
+		t.Errorf("Reset released %d bytes of %d", int64(before)-int64(after), before)
+	}
+
+	/* the parser remains usable */
+	p.Markdown(strings.NewReader("[a]\n\n[a]: /b\n"), ToHTML(&out))
+	if got := out.String(); got != "<p><a href=\"/b\">a</a></p>\n" {
+		t.Errorf("after Reset: got %q", got)
+	}
+	runtime.KeepAlive(p)
+}
+
+func TestShiftHeadings(t *testing.T) {
+	const input = "# A\n\n> ## B\n\n###### C\n\nx[^1]\n\n[^1]: y[^1]\n\n    #### N\n"
+	const want = "<h3>A</h3>\n\n<blockquote>\n<h4>B</h4>\n</blockquote>\n\n<h6>C</h6>\n\n"
+	var buf bytes.Buffer
+	NewParser(WithNotes()).Markdown(strings.NewReader(input), ShiftHeadings(ToHTML(&buf), 2))
+	got := buf.String()
+	if !strings.HasPrefix(got, want) || !strings.Contains(got, "<h6>N</h6>") {
+		t.Errorf("got %q", got)
+	}
+	buf.Reset()
+	NewParser(nil).Markdown(strings.NewReader("## A\n"), ShiftHeadings(ToHTML(&buf), -3))
+	if got := buf.String(); got != "<h1>A</h1>\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// treeFormatter collects the blocks of a document.
+type treeFormatter struct {
+	blocks []*element
+}
+
+func (f *treeFormatter) FormatBlock(tree *element) { f.blocks = append(f.blocks, tree) }
+func (f *treeFormatter) Finish()                   {}
+
+func TestLooseLists(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		loose bool
+	}{
+		{"- a\n- b\n", false},
+		{"- a\n\n- b\n", true},
+		{"1. a\n   - b\n2. c\n", false},
+		{"1. a\n\n   - b\n\n2. c\n", true},
+	} {
+		var f treeFormatter
+		NewParser(nil).Markdown(strings.NewReader(tc.input), &f)
+		if len(f.blocks) != 1 {
+			t.Fatalf("%q: %d blocks", tc.input, len(f.blocks))
+		}
+		list := f.blocks[0]
+		if list.loose != tc.loose {
+			t.Errorf("%q: list loose = %v", tc.input, list.loose)
+		}
+		for item := list.children; item != nil; item = item.next {
+			if item.loose != tc.loose {
+				t.Errorf("%q: item loose = %v", tc.input, item.loose)
+			}
+			var check func(*element)
+			check = func(e *element) {
+				for ; e != nil; e = e.next {
+					if e.key == RAW || strings.Contains(e.contents.str, "\001") {
+						t.Errorf("%q: unprocessed text %q", tc.input, e.contents.str)
+					}
+					check(e.children)
+				}
+			}
+			check(item.children)
+		}
+	}
+}
+
+// TestLooseItemEnds checks that the last blocks of loose items end
+// like blocks followed by a blank line.
+func TestLooseItemEnds(t *testing.T) {
+	for _, tc := range []struct {
+		input, want string
+	}{
+		{"- a\n\n- b\n", "<ul>\n<li><p>a</p></li>\n<li><p>b</p></li>\n</ul>\n"},
+		{"- a\n\n- b  \n", "<ul>\n<li><p>a</p></li>\n<li><p>b </p></li>\n</ul>\n"},
+		{"- a\n\n- b\n\n    ***\n", "<ul>\n<li><p>a</p></li>\n<li><p>b</p>\n\n<hr /></li>\n</ul>\n"},
+		{"T\n\n:   a\n\n:   b\n\n    | x |\n    |---|\n    | 1 |\n",
+			"<dl>\n<dt>T</dt><dd><p>a</p></dd>\n<dd><p>b</p>\n\n<table>\n<colgroup>\n<col style=\"text-align:left;\"/>\n</colgroup>\n\n" +
+				"<thead>\n<tr>\n\t<th style=\"text-align:left;\">x</th>\n</tr>\n</thead>\n\n" +
+				"<tbody>\n<tr>\n\t<td style=\"text-align:left;\">1</td>\n</tr>\n</tbody>\n</table></dd>\n</dl>\n"},
+	} {
+		var buf bytes.Buffer
+		NewParser(&Extensions{Table: true, Dlists: true}).Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestReconcileTitle(t *testing.T) {
+	for _, tc := range []struct {
+		input, title string
+		mode         TitleMode
+		want         string
+	}{
+		{"[r]: /u\n\n# My  *Project*\n\ntext\n", "my project", StripTitle, "<p>text</p>\n"},
+		{"# Other\n\ntext\n", "My Project", StripTitle, "<h1>Other</h1>\n\n<p>text</p>\n"},
+		{"text\n", "My Project", InjectTitle, "<h1>My Project</h1>\n\n<p>text</p>\n"},
+		{"# Own\n", "My Project", InjectTitle, "<h1>Own</h1>\n"},
+		{"", "My Project", InjectTitle, "<h1>My Project</h1>\n"},
+	} {
+		var buf bytes.Buffer
+		NewParser(nil).Markdown(strings.NewReader(tc.input), ReconcileTitle(ToHTML(&buf), tc.title, tc.mode))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestSetext(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		x     Extensions
+		want  string
+	}{
+		{"Title\n==\n", Extensions{}, "<h1>Title</h1>\n"},
+		{"Title\n==\n", Extensions{NoSetext: true}, "<p>Title\n==</p>\n"},
+		{"Title\n==\n", Extensions{SetextMinUnderline: 3}, "<p>Title\n==</p>\n"},
+		{"Title\n---\n", Extensions{SetextMinUnderline: 3}, "<h2>Title</h2>\n"},
+		{"foo\nbar\n---\n", Extensions{}, "<p>foo</p>\n\n<h2>bar</h2>\n"},
+		{"foo\nbar\n---\n", Extensions{SetextSingleLine: true}, "<p>foo\nbar</p>\n\n<hr />\n"},
+		{"foo\n---\nbar\n", Extensions{NoSetext: true}, "<p>foo</p>\n\n<hr />\n\n<p>bar</p>\n"},
+		{"foo\n***\nbar\n", Extensions{NoSetext: true}, "<p>foo\n***\nbar</p>\n"},
+		{"bar\n---\n", Extensions{SetextSingleLine: true}, "<h2>bar</h2>\n"},
+	} {
+		var buf bytes.Buffer
+		NewParser(&tc.x).Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%q %+v: got %q, want %q", tc.input, tc.x, got, tc.want)
+		}
+	}
+}
+
+func TestTOC(t *testing.T) {
+	const input = "[TOC]\n\n# A\n\n## A1\n\n[TOC local]\n\n### A1a\n\n## A2\n\n# B\n\n[TOC local]\n\ntext\n"
+	const want = `<ul>
+<li><a href="#a">A</a>
+
+<ul>
+<li><a href="#a1">A1</a>
+
+<ul>
+<li><a href="#a1a">A1a</a></li>
+</ul></li>
+<li><a href="#a2">A2</a></li>
+</ul></li>
+<li><a href="#b">B</a></li>
+</ul>
+
+<h1 id="a">A</h1>
+
+<h2 id="a1">A1</h2>
+
+<ul>
+<li><a href="#a1a">A1a</a></li>
+</ul>
+
+<h3 id="a1a">A1a</h3>
+
+<h2 id="a2">A2</h2>
+
+<h1 id="b">B</h1>
+
+<p>text</p>
+`
+	var buf bytes.Buffer
+	NewParser(&Extensions{TOC: true}).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{HeadingIDs: true}))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// plainWriter hides all methods of its writer but Write.
+type plainWriter struct {
+	w *bytes.Buffer
+	n int /* Calls of Write. */
+}
+
+func (w *plainWriter) Write(b []byte) (int, error) {
+	w.n++
+	return w.w.Write(b)
+}
+
+func TestIOWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &plainWriter{w: &buf}
+	p := NewParser(nil)
+	f := ToHTML(w)
+	for i := 1; i <= 2; i++ {
+		p.Markdown(strings.NewReader("# Title\n\nSome *text*.\r\n"), f)
+		want := strings.Repeat("<h1>Title</h1>\n\n<p>Some <em>text</em>.</p>\n", i)
+		if got := buf.String(); got != want {
+			t.Errorf("document %d: got %q, want %q", i, got, want)
+		}
+		if w.n != i {
+			t.Errorf("document %d: %d writes, want %d", i, w.n, i)
+		}
+	}
+}
+
+func TestDisabledInlines(t *testing.T) {
+	const input = "*a* **b** `c` [d](/e) ![f](/g) <http://h> <i@j.k>\n"
+	for _, tc := range []struct {
+		x    Extensions
+		want string
+	}{
+		{Extensions{NoEmphasis: true}, "<p>*a* **b** <code>"},
+		{Extensions{NoCode: true}, "</strong> `c` <a"},
+		{Extensions{NoLinks: true}, "</code> [d](/e) <img"},
+		{Extensions{NoImages: true}, `</a> !<a href="/g">f</a> <a`},
+		{Extensions{NoImages: true, NoLinks: true}, "</code> [d](/e) ![f](/g) <a"},
+		{Extensions{NoAutolinks: true}, ` &lt;http://h&gt; <a href="`},
+		{Extensions{NoAutolinkEmail: true}, " &lt;i@j.k&gt;</p>"},
+	} {
+		if got := convert(&tc.x, input); !strings.Contains(got, tc.want) {
+			t.Errorf("%+v: got %q, want %q", tc.x, got, tc.want)
+		}
+	}
+}
+
+func TestHTMLElements(t *testing.T) {
+	const input = "> quote\n\n1. `code`\n\n<!-- -->\n\n    verbatim\n"
+	opt := &HTMLOptions{Elements: map[ElementKind]HTMLElement{
+		BLOCKQUOTE:  {Tag: "aside", Class: "quote"},
+		CODE:        {Class: "inline-code"},
+		ORDEREDLIST: {Class: "steps"},
+		LISTITEM:    {Tag: "li", Class: "step"},
+		VERBATIM:    {Class: "code-block"},
+	}}
+	const want = "<aside class=\"quote\">\n<p>quote</p>\n</aside>\n\n" +
+		"<ol class=\"steps\">\n<li class=\"step\"><code class=\"inline-code\">code</code></li>\n</ol>\n\n" +
+		"<!-- -->\n\n" +
+		"<pre class=\"code-block\"><code>verbatim\n</code></pre>\n"
+	var buf bytes.Buffer
+	NewParser(nil).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, opt))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTreeDiff(t *testing.T) {
+	p := NewParser(nil)
+	a := p.Parse(strings.NewReader("# Title\n\nSome *text*.\n\n- one\n- two\n"))
+	b := p.Parse(strings.NewReader("# Title\n\nSome **text**.\n\n- one\n- three\n"))
+	if a.Equal(b) || !a.Equal(p.Parse(strings.NewReader("# Title\n\nSome *text*.\n\n- one\n- two\n"))) {
+		t.Error("Equal failed")
+	}
+	if d := Diff(a, a); d != "" {
+		t.Errorf("diff of equal trees: %q", d)
+	}
+	const want = ` LIST
+   PARA
+-    EMPH
++    STRONG
+   BULLETLIST
+     LISTITEM "-"
+       LIST
+         PLAIN
+-          STR "two"
++          STR "three"
+`
+	if d := Diff(a, b); d != want {
+		t.Errorf("got\n%s\nwant\n%s", d, want)
+	}
+}
+
+func TestParseNotes(t *testing.T) {
+	p := NewParser(&Extensions{Notes: true})
+	tree := p.Parse(strings.NewReader("Text.[^n]\n\n[^n]: A *note*.\n\n    More.\n"))
+	const want = `LIST
+  PARA
+    STR "Text."
+    NOTE
+      LIST
+        PARA
+          STR "A"
+          SPACE " "
+          EMPH
+            STR "note"
+          STR "."
+        PARA
+          STR "More."
+  NOTE "n"
+    LIST
+      PARA
+        STR "A"
+        SPACE " "
+        EMPH
+          STR "note"
+        STR "."
+      PARA
+        STR "More."
+`
+	if got := tree.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	/* the body is shared */
+	if ref, def := tree.Children[0].Children[1], tree.Children[1]; ref.Children[0] != def.Children[0] {
+		t.Error("reference and definition have different bodies")
+	}
+}
+
+func TestDocument(t *testing.T) {
+	const input = "# Title\n\nSome *text*, and a [link](url \"title\")[^n].\n\n- one\n- two\n\n[^n]: A note.\n"
+	p := NewParser(&Extensions{Notes: true})
+	want := p.Parse(strings.NewReader(input))
+
+	/* a document stays valid while the parser is reused */
+	doc := p.ParseDocument(strings.NewReader(input))
+	other := p.ParseDocument(strings.NewReader("Another *document*.\n"))
+	p.Parse(strings.NewReader(strings.Repeat("More text.\n\n", 1000)))
+	if d := Diff(want, doc.Root); d != "" {
+		t.Errorf("document changed:\n%s", d)
+	}
+
+	/* released memory is reused for the next documents */
+	other.Release()
+	other.Release()
+	if other.Root != nil {
+		t.Error("Root kept after Release")
+	}
+	for i := 0; i < 3; i++ {
+		d := p.ParseDocument(strings.NewReader(input))
+		if !want.Equal(d.Root) {
+			t.Errorf("got\n%swant\n%s", d.Root, want)
+		}
+		d.Release()
+	}
+	if d := Diff(want, doc.Root); d != "" {
+		t.Errorf("document changed:\n%s", d)
+	}
+	doc.Release()
+}
+
+func TestDocumentOutline(t *testing.T) {
+	const input = "Intro\n\n# A\n\ntext\n\n### B *x*\n\nmore\n\n## B x\n\n# C\n\nend\n"
+	doc := NewParser(nil).ParseDocument(strings.NewReader(input))
+	var b strings.Builder
+	var write func(sections []*OutlineSection, indent string)
+	write = func(sections []*OutlineSection, indent string) {
+		for _, s := range sections {
+			fmt.Fprintf(&b, "%s%d %s %v %v\n", indent, s.Level, s.ID, s.Span, s.Heading.Kind)
+			write(s.Children, indent+"  ")
+		}
+	}
+	write(doc.Outline(), "")
+	const want = `1 a 3:1-11:6 H1
+  3 b-x 7:1-9:4 H3
+  2 b-x-1 11:1-11:6 H2
+1 c 13:1-15:3 H1
+`
+	if b.String() != want {
+		t.Errorf("got\n%swant\n%s", b.String(), want)
+	}
+	doc.Release()
+	if doc.Outline() != nil {
+		t.Error("outline of a released document")
+	}
+}
+
+func TestConcurrentRender(t *testing.T) {
+	const input = "# Title\n\nSome *text*, a [link](url)[^n].\n\n- one\n- two\n\n[^n]: A note.\n"
+	p := NewParser(&Extensions{Notes: true, Smart: true})
+	doc := p.ParseDocument(strings.NewReader(input))
+	defer doc.Release()
+
+	formatters := []func(w io.Writer) Formatter{
+		ToHTML,
+		ToGroffMM,
+		ToText,
+		func(w io.Writer) Formatter {
+			return ToHTMLWithOptions(w, &HTMLOptions{Sidenotes: true, HeadingIDs: true})
+		},
+	}
+	want := make([]string, len(formatters))
+	for i, f := range formatters {
+		var buf bytes.Buffer
+		Render(doc.Root, f(&buf))
+		want[i] = buf.String()
+	}
+
+	/* formatters keep their state to themselves, so a tree may be rendered concurrently */
+	var wg sync.WaitGroup
+	for i := 0; i < 4*len(formatters); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var buf bytes.Buffer
+			Render(doc.Root, formatters[i](&buf))
+			if buf.String() != want[i] {
+				t.Errorf("formatter %d: got %q, want %q", i, buf.String(), want[i])
+			}
+		}(i % len(formatters))
+	}
+	wg.Wait()
+}
+
+func TestTrace(t *testing.T) {
+	p := NewParser(nil)
+	if len(ruleNames) != len(p.yy.rules) {
+		t.Fatalf("%d rule names for %d rules", len(ruleNames), len(p.yy.rules))
+	}
+	var trace bytes.Buffer
+	p.SetTrace(&trace)
+	p.Markdown(strings.NewReader("*a*\n"), ToHTML(io.Discard))
+	for _, w := range []string{
+		"\n    Para 0 \"*a*\\n",
+		"\n          Strong 0 fail\n",
+		"\n          Emph 0 \"*a*\\n",
+		"\n            EmphStar 0-3 ok\n",
+		"\n          Emph 0-3 ok\n",
+	} {
+		if !strings.Contains(trace.String(), w) {
+			t.Errorf("missing %q in trace", w)
+		}
+	}
+
+	/* tracing survives Reset, and can be turned off */
+	p.Reset()
+	trace.Reset()
+	p.Markdown(strings.NewReader("a\n"), ToHTML(io.Discard))
+	if !strings.Contains(trace.String(), "    Para 0 \"a\\n") {
+		t.Error("no trace after Reset")
+	}
+	p.SetTrace(nil)
+	trace.Reset()
+	p.Markdown(strings.NewReader("a\n"), ToHTML(io.Discard))
+	if trace.Len() != 0 {
+		t.Errorf("trace written after SetTrace(nil): %q", trace.String())
+	}
+}
+
+func TestBuild(t *testing.T) {
+	const input = "# Report\n\nSome *text* with `code`, and a [link](http://example.com \"title\").\n\n" +
+		"- one\n- two\n\nThen:\n\n1. first\n\n2. second\n\n" +
+		"| a | b |\n|---|--:|\n| **1** | 2 |\n\n" +
+		"    code\n\n> quoted  \n> text\n\n---\n\n![alt](img.png)\n"
+	p := NewParser(&Extensions{Table: true})
+	var want bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&want))
+
+	/* parsed content may be spliced into built documents */
+	para := p.Parse(strings.NewReader("Some *text* with `code`, and a [link](http://example.com \"title\").\n")).Children[0]
+	doc := NewDocument(
+		NewHeading(1, NewText("Report")),
+		para,
+		NewList(false, NewListItem(NewPlain(NewText("one"))), NewListItem(NewPlain(NewText("two")))),
+		NewParagraph(NewText("Then:")),
+		NewList(true, NewListItem(NewParagraph(NewText("first"))), NewListItem(NewParagraph(NewText("second")))),
+		NewTable("lr",
+			NewTableRow([]*Node{NewText("a")}, []*Node{NewText("b")}),
+			NewTableRow([]*Node{NewStrong(NewText("1"))}, []*Node{NewText("2")})),
+		NewCodeBlock("code"),
+		NewBlockQuote(NewParagraph(NewText("quoted"), NewLineBreak(), NewText("text"))),
+		NewHorizontalRule(),
+		NewParagraph(NewImage("img.png", "", NewText("alt"))),
+	)
+	var got bytes.Buffer
+	Render(doc, ToHTML(&got))
+	if got.String() != want.String() {
+		t.Errorf("got\n%s\nwant\n%s", got.String(), want.String())
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	const input = "Dear {{ first_name }}, your *{{item}}* \"{{x}}\" ships {{date}}. {{not valid}}\n\n" +
+		"[Track it]({{url}}/track \"{{item}}\")\n\n`{{code}}`\n"
+	p := NewParser(&Extensions{Placeholders: true, Smart: true})
+
+	var buf bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	want := "<p>Dear {{first_name}}, your <em>{{item}}</em> &ldquo;{{x}}&rdquo; ships {{date}}. {{not valid}}</p>\n\n" +
+		"<p><a href=\"{{url}}/track\" title=\"{{item}}\">Track it</a></p>\n\n" +
+		"<p><code>{{code}}</code></p>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	values := map[string]string{"first_name": "Ann", "item": "<book>", "url": "http://example.com", "x": "y"}
+	Render(p.Parse(strings.NewReader(input)), ToHTML(&buf), FillPlaceholders(values))
+	want = "<p>Dear Ann, your <em>&lt;book&gt;</em> &ldquo;y&rdquo; ships {{date}}. {{not valid}}</p>\n\n" +
+		"<p><a href=\"http://example.com/track\" title=\"&lt;book&gt;\">Track it</a></p>\n\n" +
+		"<p><code>{{code}}</code></p>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCitations(t *testing.T) {
+	const input = "As shown [see @doe99, pp. 33-35; -@smith04] and [@doe99].\n\n" +
+		"Not [@x](y), nor [me@x.org] or [@].\n"
+	p := NewParser(&Extensions{Citations: true})
+
+	var buf bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	want := "<p>As shown <span class=\"citation\" data-cites=\"doe99 smith04\">[see @doe99, pp. 33-35; -@smith04]</span> " +
+		"and <span class=\"citation\" data-cites=\"doe99\">[@doe99]</span>.</p>\n\n" +
+		"<p>Not <a href=\"y\">@x</a>, nor [me@x.org] or [@].</p>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var cites [][]Citation
+	buf.Reset()
+	p.Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{
+		Cite: func(c []Citation) string {
+			cites = append(cites, c)
+			return "(cite)"
+		},
+		Bibliography: func(keys []string) string {
+			return "<ul><li>" + strings.Join(keys, "</li><li>") + "</li></ul>"
+		},
+	}))
+	want = "<p>As shown (cite) and (cite).</p>\n\n" +
+		"<p>Not <a href=\"y\">@x</a>, nor [me@x.org] or [@].</p>\n\n" +
+		"<ul><li>doe99</li><li>smith04</li></ul>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	wantCites := [][]Citation{
+		{{Key: "doe99", Prefix: "see", Suffix: "pp. 33-35"}, {Key: "smith04", SuppressAuthor: true}},
+		{{Key: "doe99"}},
+	}
+	if !reflect.DeepEqual(cites, wantCites) {
+		t.Errorf("got citations %+v, want %+v", cites, wantCites)
+	}
+}
+
+func TestComments(t *testing.T) {
+	const input = "Text %% todo: <check> %% and <!--- more ---> here.\n\n%%\nA draft,\n\nin two paragraphs.\n%%\n\n" +
+		"Open %% here\n\nand here %%.\n"
+	p := NewParser(&Extensions{Comments: true})
+
+	var buf bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	want := "<p>Text  and  here.</p>\n\n<p>Open %% here</p>\n\n<p>and here %%.</p>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	p.Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{Comments: true}))
+	want = "<p>Text <!-- todo: &lt;check&gt; --> and <!-- more --> here.</p>\n\n" +
+		"<!--\nA draft,\n\nin two paragraphs.\n-->\n\n<p>Open %% here</p>\n\n<p>and here %%.</p>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	p.Markdown(strings.NewReader(input), ToText(&buf))
+	if got := buf.String(); strings.Contains(got, "todo") || strings.Contains(got, "draft") {
+		t.Errorf("comments in text output %q", got)
+	}
+
+	var comments []string
+	for _, tok := range p.Tokens(strings.NewReader(input)) {
+		if tok.Kind == CommentToken {
+			comments = append(comments, input[tok.Span.Start.Offset:tok.Span.End.Offset+1])
+		}
+	}
+	if want := []string{" todo: <check> ", " more ", "\nA draft,\n\nin two paragraphs.\n"}; !reflect.DeepEqual(comments, want) {
+		t.Errorf("got comment tokens %q, want %q", comments, want)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	input := strings.Repeat("[a][r] ", 100) + "\n\nMore *text*.\n\n[r]: /" + strings.Repeat("x", 100) + "\n"
+
+	var buf bytes.Buffer
+	p := NewParser(WithMaxOutput(1000))
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	if err, ok := p.Err().(*LimitError); !ok || err.Limit != "output bytes" || err.Max != 1000 {
+		t.Errorf("got error %v, want output limit", p.Err())
+	}
+	if buf.Len() > 1000 || strings.Contains(buf.String(), "More") {
+		t.Errorf("got %d bytes: %q", buf.Len(), buf.String())
+	}
+
+	/* notes printed at the end count as well */
+	buf.Reset()
+	p = NewParser(WithNotes(), WithMaxOutput(200))
+	p.Markdown(strings.NewReader("a[^n]\n\n[^n]: "+strings.Repeat("x", 750)+"\n"), ToHTML(&buf))
+	if err, ok := p.Err().(*LimitError); !ok || err.Limit != "output bytes" {
+		t.Errorf("got error %v, want output limit", p.Err())
+	}
+	if buf.Len() > 200 {
+		t.Errorf("got %d bytes: %q", buf.Len(), buf.String())
+	}
+
+	/* the limit is reset for each document */
+	buf.Reset()
+	p.Markdown(strings.NewReader("Short.\n"), ToHTML(&buf))
+	if p.Err() != nil || buf.String() != "<p>Short.</p>\n" {
+		t.Errorf("got %q, error %v", buf.String(), p.Err())
+	}
+
+	p = NewParser(WithMaxNodes(150))
+	tree := p.Parse(strings.NewReader(input))
+	if err, ok := p.Err().(*LimitError); !ok || err.Limit != "nodes" {
+		t.Errorf("got error %v, want node limit", p.Err())
+	}
+	if len(tree.Children) != 0 {
+		t.Errorf("got %d blocks, want none", len(tree.Children))
+	}
+	p = NewParser(WithMaxNodes(1000))
+	p.Parse(strings.NewReader(input))
+	if p.Err() != nil {
+		t.Errorf("got error %v", p.Err())
+	}
+}
+
+func TestDiffHTML(t *testing.T) {
+	p := NewParser(WithNotes())
+	parse := func(s string) *Node { return p.Parse(strings.NewReader(s)) }
+	prev := parse("# Title\n\nOne.\n\nTwo[^n].\n\nThree.\n\n[r]: /url\n\n[^n]: A note.\n")
+
+	blocks := HTMLBlocks(prev, nil)
+	want := []string{"<h1>Title</h1>", "<p>One.</p>", "<p>Two<a", "<p>Three.</p>", "<hr"}
+	if len(blocks) != len(want) {
+		t.Fatalf("got blocks %q", blocks)
+	}
+	for i, b := range blocks {
+		if !strings.HasPrefix(b, want[i]) {
+			t.Errorf("block %d: got %q, want prefix %q", i, b, want[i])
+		}
+	}
+
+	for _, tc := range []struct {
+		cur  string
+		want []Patch
+	}{
+		{"# Title\n\nOne.\n\nTwo[^n].\n\nThree.\n\n[^n]: A note.\n", nil},
+		{"# Title\n\nOne!\n\nTwo[^n].\n\nThree.\n\n[^n]: A note.\n", []Patch{
+			{PatchReplace, 1, "<p>One!</p>"},
+		}},
+		{"# Title\n\nOne.\n\nNew.\n\nTwo[^n].\n\nThree.\n\n[^n]: A note.\n", []Patch{
+			{PatchInsert, 2, "<p>New.</p>"},
+		}},
+		{"# Title\n\nTwo[^n].\n\nThree.\n\n[^n]: A note.\n", []Patch{
+			{Op: PatchDelete, Index: 1},
+		}},
+		{"# Title\n\nA.\n\nB.\n\nC.\n\nThree.\n", []Patch{
+			{PatchReplace, 1, "<p>A.</p>"},
+			{PatchReplace, 2, "<p>B.</p>"},
+			{PatchInsert, 3, "<p>C.</p>"},
+			{Op: PatchDelete, Index: 5},
+		}},
+	} {
+		got := DiffHTML(prev, parse(tc.cur), nil)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %+v, want %+v", tc.cur, got, tc.want)
+		}
+
+		/* applying the patches gives the blocks of the new page */
+		page := append([]string(nil), blocks...)
+		for _, pt := range got {
+			switch pt.Op {
+			case PatchInsert:
+				page = append(page[:pt.Index], append([]string{pt.HTML}, page[pt.Index:]...)...)
+			case PatchReplace:
+				page[pt.Index] = pt.HTML
+			case PatchDelete:
+				page = append(page[:pt.Index], page[pt.Index+1:]...)
+			}
+		}
+		if b := HTMLBlocks(parse(tc.cur), nil); !reflect.DeepEqual(page, b) {
+			t.Errorf("%q: patched page %q, want %q", tc.cur, page, b)
+		}
+	}
+}
+
+func TestExtensionList(t *testing.T) {
+	var x Extensions
+	if !x.Set("Notes", true) || !x.Notes {
+		t.Errorf("Set(\"Notes\") failed")
+	}
+	for _, name := range []string{"notes", "TableRows", "Bogus"} {
+		if x.Set(name, true) {
+			t.Errorf("Set(%q) succeeded", name)
+		}
+	}
+
+	/* every boolean field is listed, with a description */
+	list := x.List()
+	n := 0
+	typ := reflect.TypeOf(x)
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Type.Kind() == reflect.Bool {
+			n++
+		}
+	}
+	if len(list) != n {
+		t.Errorf("got %d extensions, want %d", len(list), n)
+	}
+	for _, e := range list {
+		if e.Description == "" || e.Enabled != (e.Name == "Notes") {
+			t.Errorf("got %+v", e)
+		}
+	}
+	if list[0].Name != "Smart" {
+		t.Errorf("got %q first, want Smart", list[0].Name)
+	}
+}
+
+func TestSniffImage(t *testing.T) {
+	for _, tc := range []struct{ data, want string }{
+		{"\x89PNG\r\n\x1a\n...", "image/png"},
+		{"\xff\xd8\xff\xe0", "image/jpeg"},
+		{"GIF89a", "image/gif"},
+		{"RIFF\x00\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"\n<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\"/>", "image/svg+xml"},
+		{"text", "application/octet-stream"},
+	} {
+		if got := sniffImage([]byte(tc.data)); got != tc.want {
+			t.Errorf("%q: got %s, want %s", tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestUnknownKinds(t *testing.T) {
+	/* nodes of unknown kinds are written as their children, instead of stopping the program */
+	doc := NewDocument(NewParagraph(NewText("a "), &Node{Kind: 9999, Children: []*Node{NewEmph(NewText("b"))}}))
+	var buf bytes.Buffer
+	Render(doc, ToHTML(&buf))
+	if want := "<p>a <em>b</em></p>\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	buf.Reset()
+	Render(doc, ToGroffMM(&buf))
+	if !strings.Contains(buf.String(), "b") {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestTransform(t *testing.T) {
+	const input = "# Title\n\nSee [a](b/c.html), [top](#top), and ![pic](img.png)[^n], again[^n].\n\n[^n]: A note.\n"
+	p := NewParser(&Extensions{Notes: true})
+
+	/* rendering an unchanged tree gives the output of Markdown */
+	var want, got bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&want))
+	Render(p.Parse(strings.NewReader(input)), ToHTML(&got))
+	if got.String() != want.String() {
+		t.Errorf("got\n%s\nwant\n%s", got.String(), want.String())
+	}
+
+	base, _ := url.Parse("http://example.com/doc/")
+	load := func(url string) ([]byte, error) {
+		return []byte("PNG"), nil
+	}
+	got.Reset()
+	Render(p.Parse(strings.NewReader(input)), ToHTML(&got), ShiftHeadingLevels(1), InlineImages(load), AbsoluteLinks(base))
+	for _, w := range []string{
+		"<h2>Title</h2>",
+		`<a href="http://example.com/doc/b/c.html">a</a>`,
+		`<a href="#top">top</a>`,
+		`<img src="data:image/png;base64,UE5H" alt="pic" />`,
+	} {
+		if !strings.Contains(got.String(), w) {
+			t.Errorf("missing %q in %q", w, got.String())
+		}
+	}
+}
+
+func TestNotesInBlocks(t *testing.T) {
+	const input = "Text[^a].\n\n" +
+		"> Quote[^b]\n>\n> [^b]: In a quote.\n>\n>     Continued.\n\n" +
+		"1.  Item[^c]\n\n    [^c]: In an item.\n\n    - Nested[^d][^e]\n\n        > [^d]: Deep.\n        >\n        > [^e]: Ignored.\n\n" +
+		"[^a]: Top.\n\n[^e]: Top-level notes take precedence.\n"
+	var buf bytes.Buffer
+	NewParser(&Extensions{Notes: true}).Markdown(strings.NewReader(input), ToText(&buf))
+	for _, w := range []string{
+		"Text[1].", "Quote[2]", "Item[3]", "Nested[4][5]",
+		"[1] Top.", "[2] In a quote.\n\n    Continued.", "[3] In an item.", "[4] Deep.",
+		"[5] Top-level notes take precedence.",
+	} {
+		if !strings.Contains(buf.String(), w) {
+			t.Errorf("missing %q in %q", w, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "Ignored") {
+		t.Errorf("top-level note overridden: %q", buf.String())
+	}
+}
+
+func TestSidenotes(t *testing.T) {
+	const input = "Text[^a] and more[^a].\n\n[^a]: A *note*[^a].\n\n    Second paragraph.\n"
+	var buf bytes.Buffer
+	NewParser(&Extensions{Notes: true}).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{Sidenotes: true, IDPrefix: "x-"}))
+	note := func(n string) string {
+		return `<label for="x-sn` + n + `" class="margin-toggle sidenote-number"></label>` +
+			`<input type="checkbox" id="x-sn` + n + `" class="margin-toggle"/>` +
+			`<span class="sidenote">A <em>note</em>.<br/>Second paragraph.</span>`
+	}
+	want := "<p>Text" + note("1") + " and more" + note("2") + ".</p>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	const input = "#go and #news/2024, not a#b, &#35;x, #1. See (#go).\n#start of a line\n\n# Heading #h\n"
+	p := NewParser(&Extensions{Hashtags: true, HashtagURL: func(tag string) string {
+		if tag == "h" {
+			return ""
+		}
+		return "/tags/" + tag
+	}})
+	var buf bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	want := `<p><a href="/tags/go">#go</a> and <a href="/tags/news/2024">#news/2024</a>, not a#b, &#35;x, #1. See (<a href="/tags/go">#go</a>).
+<a href="/tags/start">#start</a> of a line</p>
+
+<h1>Heading #h</h1>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got, want := strings.Join(p.Tags(), " "), "go news/2024 start h"; got != want {
+		t.Errorf("tags: got %q, want %q", got, want)
+	}
+}
+
+func TestTableExtraCells(t *testing.T) {
+	/* rows may have more cells than the separator line has columns */
+	const input = "| a | b |\n|---|\n| 1 | 2 | 3 |\n"
+	var buf bytes.Buffer
+	NewParser(&Extensions{Table: true}).Markdown(strings.NewReader(input), ToHTML(&buf))
+	want := "\t<td style=\"text-align:left;\">1</td>\n\t<td>2</td>\n\t<td>3</td>\n"
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	const input = "![a *b* \"q\" & `c<d>`](u.png \"t <y> & z\")\n\n" +
+		"[<&>\"](u?a=1&b=2 \"t<>&\")\n\n" +
+		"`<&>\"`\n\n" +
+		"    <pre>&\"\n"
+	const want = "<p><img src=\"u.png\" alt=\"a b &quot;q&quot; &amp; c&lt;d&gt;\" title=\"t &lt;y&gt; &amp; z\" /></p>\n\n" +
+		"<p><a href=\"u?a=1&amp;b=2\" title=\"t&lt;&gt;&amp;\">&lt;&amp;&gt;&quot;</a></p>\n\n" +
+		"<p><code>&lt;&amp;&gt;&quot;</code></p>\n\n" +
+		"<pre><code>&lt;pre&gt;&amp;&quot;\n</code></pre>\n"
+	var buf bytes.Buffer
+	NewParser(nil).Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := EscapeText(`a<b>&"c'`); got != "a&lt;b&gt;&amp;&quot;c'" {
+		t.Errorf("EscapeText: got %q", got)
+	}
+}
+
+func TestInputNormalization(t *testing.T) {
+	const want = "<h1>Title</h1>\n\n<p>Some\ntext.</p>\n\n<pre><code>code\n</code></pre>\n"
+	for _, input := range []string{
+		"# Title\n\nSome\ntext.\n\n\tcode\n",
+		"\ufeff# Title\n\nSome\ntext.\n\n\tcode\n",
+		"\ufeff# Title\r\n\r\nSome\r\ntext.\r\n\r\n\tcode\r\n",
+		"# Title\r\rSome\rtext.\r\r\tcode\r",
+	} {
+		for _, r := range []io.Reader{
+			strings.NewReader(input),
+			iotest.OneByteReader(strings.NewReader(input)),
+			iotest.DataErrReader(strings.NewReader(input)),
+		} {
+			var buf bytes.Buffer
+			NewParser(nil).Markdown(r, ToHTML(&buf))
+			if got := buf.String(); got != want {
+				t.Errorf("%q: got %q, want %q", input, got, want)
+			}
+		}
+	}
+}
+
+func TestInvalidInput(t *testing.T) {
+	for _, tc := range []struct {
+		input, want string
+		replace     bool
+	}{
+		{"a\x00b\n", "<p>a\ufffdb</p>\n", false},
+		{"caf\xe9 \xff\xfe\n", "<p>caf\xe9 \xff\xfe</p>\n", false},
+		{"caf\xe9 \xff\xfe \u00e9\n", "<p>caf\ufffd \ufffd\ufffd \u00e9</p>\n", true},
+
+		/* NUL bytes can't make labels of different markup equal */
+		{"[a\x00EMPH(b\x00)]\n\n[a*b*]: /url\n", "<p>[a\ufffdEMPH(b\ufffd)]</p>\n", false},
+	} {
+		var buf bytes.Buffer
+		NewParser(&Extensions{ReplaceInvalidUTF8: tc.replace}).Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestUnicodeSpaces(t *testing.T) {
+	const input = "-\u00a0item\n-\u00a0two\n\nword\u00a0*emph*\u3000more, \u00a9 caf\u00e9\n"
+	const want = "<ul>\n<li>item</li>\n<li>two</li>\n</ul>\n\n" +
+		"<p>word <em>emph</em> more, \u00a9 caf\u00e9</p>\n"
+	var buf bytes.Buffer
+	NewParser(&Extensions{UnicodeSpaces: true}).Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	/* without the extension, no-break spaces are part of words */
+	buf.Reset()
+	NewParser(nil).Markdown(strings.NewReader(input), ToHTML(&buf))
+	if got := buf.String(); !strings.HasPrefix(got, "<p>-\u00a0item\n") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestTableRows(t *testing.T) {
+	const input = "Text\n\n| a | b |\n|---|---|\n| 1 | 2 | 3 |\n| x |\n| y ||| z |\n\n"
+	p := NewParser(&Extensions{Table: true, TableRows: StrictRows})
+	var buf bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	for _, w := range []string{
+		"\t<td style=\"text-align:left;\">1</td>\n\t<td style=\"text-align:left;\">2 | 3</td>\n</tr>",
+		"\t<td style=\"text-align:left;\">x</td>\n\t<td style=\"text-align:left;\"></td>\n</tr>",
+		"\t<td style=\"text-align:left;\" colspan=\"2\">y | z</td>\n</tr>",
+	} {
+		if !strings.Contains(buf.String(), w) {
+			t.Errorf("missing %q in %q", w, buf.String())
+		}
+	}
+	var diags []string
+	for _, d := range p.Diagnostics() {
+		diags = append(diags, d.String())
+	}
+	want := []string{
+		"5:1: warning: table row 2: 3 columns, expected 2",
+		"6:1: warning: table row 3: 1 columns, expected 2",
+		"7:1: warning: table row 4: 4 columns, expected 2",
+	}
+	if strings.Join(diags, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics %q, want %q", diags, want)
+	}
+}
+
+func TestTableDiagnostics(t *testing.T) {
+	const input = "| a | b | c |\n|---|---|\n| 1 | 2 |\n| 3 | 4 | 5 |\n[First][t]\n\n" +
+		"[Second][t]\n| a | b |\n|---|---|\n| 1 | long \\\n| | continued \\\n| 2 | 3 | 4 |\n\n" +
+		"| x |\n|---|\n| y |\n\n[Third][t]\n\n" +
+		"> | a |\n> |---|\n> | 1 | 2 |\n\n"
+	p := NewParser(&Extensions{Table: true, TableContinuation: true})
+	var buf bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	var diags []string
+	for _, d := range p.Diagnostics() {
+		diags = append(diags, d.String())
+	}
+	want := []string{
+		"1:1: warning: table header row 1: 3 columns, separator line has 2",
+		"4:1: warning: table row 3: 3 columns, separator line has 2",
+		"7:1: warning: table label \"t\" already used at line 5",
+		"10:1: warning: table row 2: 3 columns, separator line has 2",
+		"20:1: warning: table row 2: 2 columns, separator line has 1",
+	}
+	if strings.Join(diags, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics\n%s\nwant\n%s", strings.Join(diags, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTableContinuation(t *testing.T) {
+	const input = "| a | b |\n|---|---|\n| 1 | long \\\n| | continued \\\n| 2 | again |\n| x | y \\\n\n"
+	var buf bytes.Buffer
+	NewParser(&Extensions{Table: true, TableContinuation: true}).Markdown(strings.NewReader(input), ToHTML(&buf))
+	want := "<tbody>\n<tr>\n" +
+		"\t<td style=\"text-align:left;\">1<br/>\n2</td>\n" +
+		"\t<td style=\"text-align:left;\">long<br/>\ncontinued<br/>\nagain</td>\n" +
+		"</tr>\n<tr>\n" +
+		"\t<td style=\"text-align:left;\">x</td>\n" +
+		"\t<td style=\"text-align:left;\">y \\</td>\n" +
+		"</tr>\n</tbody>"
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTableRowspan(t *testing.T) {
+	const input = "| a | b |\n|---|---|\n| 1 | 2 |\n| ^^ | 3 |\n| ^^ | ^^ |\n\n"
+	p := NewParser(&Extensions{Table: true, TableRowspan: true})
+	var buf bytes.Buffer
+	p.Markdown(strings.NewReader(input), ToHTML(&buf))
+	want := "<tbody>\n<tr>\n" +
+		"\t<td style=\"text-align:left;\" rowspan=\"3\">1</td>\n" +
+		"\t<td style=\"text-align:left;\">2</td>\n" +
+		"</tr>\n<tr>\n" +
+		"\t<td style=\"text-align:left;\" rowspan=\"2\">3</td>\n" +
+		"</tr>\n<tr>\n" +
+		"</tr>\n</tbody>"
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	body := p.Parse(strings.NewReader(input)).Children[0].Children[2]
+	var spans []string
+	for _, row := range body.Children {
+		for _, cell := range row.Children {
+			if len(cell.Children) != 0 && cell.Children[0].Kind == ROWSPAN {
+				spans = append(spans, cell.Children[0].Text)
+			} else {
+				spans = append(spans, "-")
+			}
+		}
+	}
+	if s := strings.Join(spans, " "); s != "3 - 0 2 0 0" {
+		t.Errorf("got row spans %q", s)
+	}
+}
+
+func TestGridTables(t *testing.T) {
+	const input = "+-------+--------:+-----------+\n" +
+		"| Fruit | Price   | Notes     |\n" +
+		"+=======+========:+===========+\n" +
+		"| Bänke | 1.34    | - wrapper |\n" +
+		"|       |         | - color   |\n" +
+		"+-------+---------+-----------+\n" +
+		"| Pear  |         | one       |\n" +
+		"|       |         |           |\n" +
+		"|       |         | *two*     |\n" +
+		"+-------+---------+-----------+\n\n" +
+		"+---+---+\n| a | b\n+---+---+\n"
+	var buf bytes.Buffer
+	NewParser(&Extensions{GridTables: true}).Markdown(strings.NewReader(input), ToHTML(&buf))
+	for _, want := range []string{
+		"\t<th style=\"text-align:left;\">Fruit</th>\n\t<th style=\"text-align:right;\">Price</th>\n",
+		"\t<td style=\"text-align:left;\">Bänke</td>\n\t<td style=\"text-align:right;\">1.34</td>\n" +
+			"\t<td style=\"text-align:left;\"><ul>\n<li>wrapper</li>\n<li>color</li>\n</ul></td>\n",
+		"\t<td style=\"text-align:right;\"></td>\n" +
+			"\t<td style=\"text-align:left;\"><p>one</p>\n\n<p><em>two</em></p></td>\n</tr>\n</tbody>\n</table>\n\n<p>",
+		"<p>+---+---+\n| a | b\n+---+---+</p>",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestTableBlocks(t *testing.T) {
+	const input = "| a | b |\n|---|---|\n| - x \\ | one \\\n| - y \\ | \\\n| | two `a|b` |\n| plain | *em* \\| c |\n\n"
+	var buf bytes.Buffer
+	x := &Extensions{Table: true, TableBlocks: true, TableContinuation: true}
+	NewParser(x).Markdown(strings.NewReader(input), ToHTML(&buf))
+	want := "<tbody>\n<tr>\n" +
+		"\t<td style=\"text-align:left;\"><ul>\n<li>x</li>\n<li>y</li>\n</ul></td>\n" +
+		"\t<td style=\"text-align:left;\"><p>one</p>\n\n<p>two <code>a|b</code></p></td>\n" +
+		"</tr>\n<tr>\n" +
+		"\t<td style=\"text-align:left;\">plain</td>\n" +
+		"\t<td style=\"text-align:left;\"><em>em</em> | c</td>\n" +
+		"</tr>\n</tbody>"
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTablePipes(t *testing.T) {
+	/* code spans and escaped pipes don't divide cells */
+	const input = "| a | b |\n|---|---|\n| `x | y` | c |\n|d `e|f` g|\\||\n| `` p | q `` | `unclosed | z |\n\n"
+	var buf bytes.Buffer
+	NewParser(&Extensions{Table: true}).Markdown(strings.NewReader(input), ToHTML(&buf))
+	cell := func(s string) string {
+		return "\t<td style=\"text-align:left;\">" + s + "</td>\n"
+	}
+	want := "<tbody>\n<tr>\n" + cell("<code>x | y</code>") + cell("c") +
+		"</tr>\n<tr>\n" + cell("d <code>e|f</code> g") + cell("|") +
+		"</tr>\n<tr>\n" + cell("<code>p | q</code>") + cell("`unclosed") + "\t<td>z</td>\n" +
+		"</tr>\n</tbody>"
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTableHeaders(t *testing.T) {
+	p := NewParser(&Extensions{Table: true})
+	for _, tc := range []struct {
+		input string
+		head  int /* rows of the header, or -1 if there is none */
+	}{
+		{"| a | b |\n|---|--:|\n| c | d |\n\n", 1},
+		{"| a | b |\n| e | f |\n|---|--:|\n| c | d |\n\n", 2},
+		{"|---|--:|\n| c | d |\n| g | h |\n\n", -1},
+	} {
+		table := p.Parse(strings.NewReader(tc.input)).Children[0]
+		head := -1
+		for _, part := range table.Children {
+			if part.Kind == TABLEHEAD {
+				head = len(part.Children)
+			}
+		}
+		if table.Kind != TABLE || head != tc.head {
+			t.Errorf("%q: got %d header rows, want %d:\n%s", tc.input, head, tc.head, table)
+		}
+
+		/* the columns are aligned, with or without a header */
+		var buf bytes.Buffer
+		p.Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got := buf.String(); !strings.Contains(got, "<col style=\"text-align:right;\"/>") ||
+			!strings.Contains(got, "<td style=\"text-align:right;\">d</td>") ||
+			strings.Contains(got, "<thead>") != (tc.head > 0) {
+			t.Errorf("%q: got\n%s", tc.input, got)
+		}
+	}
+}
+
+func TestTableRefs(t *testing.T) {
+	const input = "See [#second] and [#first].\n\n| a |\n|---|\n| 1 |\n[The first table][first]\n\n| b |\n|---|\n| 2 |\n[Second]\n\n[#none]\n"
+	for _, tc := range []struct {
+		x    Extensions
+		want []string
+	}{
+		{Extensions{Table: true, TableRefs: true}, []string{
+			`<p>See <a href="#second">Table 2</a> and <a href="#first">Table 1</a>.</p>`,
+			"<table id=\"first\">\n<caption>The first table</caption>\n",
+			"<table id=\"second\">\n<caption>Second</caption>\n",
+			"<p>[#none]</p>",
+		}},
+		{Extensions{Table: true}, []string{
+			"<p>See [#second] and [#first].</p>",
+		}},
+	} {
+		var buf bytes.Buffer
+		NewParser(&tc.x).Markdown(strings.NewReader(input), ToHTML(&buf))
+		got := buf.String()
+		for _, w := range tc.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%+v: missing %q in %q", tc.x, w, got)
+			}
+		}
+	}
+}
+
+func TestFigures(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		x     Extensions
+		want  string
+	}{
+		{"![Alt](/a.png \"Title\")\n", Extensions{Figures: true},
+			"<figure>\n<img src=\"/a.png\" alt=\"Alt\" title=\"Title\" />\n<figcaption>Title</figcaption>\n</figure>\n"},
+		{"![Alt](/a.png)\nThe *caption*\n", Extensions{Figures: true},
+			"<figure>\n<img src=\"/a.png\" alt=\"Alt\" />\n<figcaption>The <em>caption</em></figcaption>\n</figure>\n"},
+		{"![Alt](/a.png)\n", Extensions{Figures: true},
+			"<figure>\n<img src=\"/a.png\" alt=\"Alt\" />\n</figure>\n"},
+		{"![Alt](/a.png) text\n", Extensions{Figures: true},
+			"<p><img src=\"/a.png\" alt=\"Alt\" /> text</p>\n"},
+		{"Text ![Alt](/a.png)\n", Extensions{Figures: true},
+			"<p>Text <img src=\"/a.png\" alt=\"Alt\" /></p>\n"},
+		{"![Alt](/a.png)\nThe caption\n", Extensions{},
+			"<p><img src=\"/a.png\" alt=\"Alt\" />\nThe caption</p>\n"},
+	} {
+		var buf bytes.Buffer
+		NewParser(&tc.x).Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestAtxHeadings(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		x     Extensions
+		want  string
+	}{
+		{"#tag\n", Extensions{}, "<h1>tag</h1>\n"},
+		{"#tag\n", Extensions{StrictAtx: true}, "<p>#tag</p>\n"},
+		{"text\n#tag\n", Extensions{StrictAtx: true}, "<p>text\n#tag</p>\n"},
+		{"# Title\n", Extensions{StrictAtx: true}, "<h1>Title</h1>\n"},
+		{"# Title ##\n", Extensions{}, "<h1>Title</h1>\n"},
+		{"# Title ##\n", Extensions{KeepAtxClosing: true}, "<h1>Title ##</h1>\n"},
+	} {
+		var buf bytes.Buffer
+		NewParser(&tc.x).Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%q %+v: got %q, want %q", tc.input, tc.x, got, tc.want)
+		}
+	}
+}
+
+func TestPrintProfile(t *testing.T) {
+	const input = "# Title\n\nA[^a], [link](http://x.org/), <http://y.org>.\n\n[^a]: Note A.\n\n## Part\n\nB[^b], [up](#title).\n\n[^b]: Note B.\n"
+	const want = `<h1>Title</h1>
+
+<p>A<a class="noteref" id="fnref1" href="#fn1" title="Jump to note 1">[1]</a>, <a href="http://x.org/">link</a> (http://x.org/), <a href="http://y.org">http://y.org</a>.</p>
+
+<hr/>
+<ol class="notes">
+<li id="fn1">
+<p>Note A.</p> <a href="#fnref1" title="Jump back to reference">[back]</a>
+</li>
+</ol>
+
+<h2 style="break-before: page">Part</h2>
+
+<p>B<a class="noteref" id="fnref2" href="#fn2" title="Jump to note 2">[2]</a>, <a href="#title">up</a>.</p>
+
+<hr/>
+<ol class="notes" start="2">
+<li id="fn2">
+<p>Note B.</p> <a href="#fnref2" title="Jump back to reference">[back]</a>
+</li>
+</ol>
+`
+	var buf bytes.Buffer
+	NewParser(&Extensions{Notes: true}).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{Print: true}))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEmailHTML(t *testing.T) {
+	const input = "::: note Title\nA[^a] [link](http://x.org/ \"t\").\n:::\n\n[^a]: Note.\n\n| a | b |\n|---|--:|\n| 1 | 2 |\n"
+	const want = `<div>
+<p><strong>Title</strong></p>
+<p>A<sup>[1]</sup> <a href="http://x.org/">link</a>.</p>
+</div>
+
+<p><strong>a</strong> | <strong>b</strong></p>
+
+<p>1 | 2</p>
+
+<hr/>
+<ol>
+<li>
+<p>Note.</p>
+</li>
+</ol>
+`
+	var buf bytes.Buffer
+	p := NewParser(&Extensions{Notes: true, Table: true, Containers: true})
+	p.Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &HTMLOptions{Email: true, HeadingIDs: true}))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	const input = "# Title\n\nSome *text*[^a], a [link](http://x.org/).\n\n> quoted\n\n    code\n\n1. one\n2. two\n    - nested\n\n[^a]: Note.\n"
+	const want = `Title
+=====
+
+Some text[1], a link (http://x.org/).
+
+> quoted
+
+    code
+
+1. one
+2. two
+   - nested
+
+----
+
+[1] Note.
+`
+	var buf bytes.Buffer
+	NewParser(&Extensions{Notes: true}).Markdown(strings.NewReader(input), ToText(&buf))
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLinkAttributes(t *testing.T) {
+	const input = "[a](https://x.org/) [b](/local) ![i](i.png)\n"
+	for _, tc := range []struct {
+		opt  HTMLOptions
+		want string
+	}{
+		{HTMLOptions{}, `<p><a href="https://x.org/">a</a> <a href="/local">b</a> <img src="i.png" alt="i" /></p>` + "\n"},
+		{HTMLOptions{ExternalLinks: true, LazyImages: true, Flavor: HTML5},
+			`<p><a href="https://x.org/" target="_blank" rel="nofollow noopener noreferrer">a</a> <a href="/local">b</a> <img src="i.png" alt="i" loading="lazy" decoding="async"></p>` + "\n"},
+		{HTMLOptions{ExternalLinks: true, IsExternal: func(url string) bool { return url == "/local" }},
+			`<p><a href="https://x.org/">a</a> <a href="/local" target="_blank" rel="nofollow noopener noreferrer">b</a> <img src="i.png" alt="i" /></p>` + "\n"},
+	} {
+		var buf bytes.Buffer
+		NewParser(nil).Markdown(strings.NewReader(input), ToHTMLWithOptions(&buf, &tc.opt))
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestElementKinds(t *testing.T) {
+	/* values of built-in kinds must not change */
+	for kind, value := range map[int]int{
+		LIST: 0, RAW: 1, SPACE: 2, LINEBREAK: 3, ELLIPSIS: 4, EMDASH: 5,
+		ENDASH: 6, APOSTROPHE: 7, SINGLEQUOTED: 8, DOUBLEQUOTED: 9, STR: 10,
+		LINK: 11, IMAGE: 12, CODE: 13, HTML: 14, EMPH: 15, STRONG: 16,
+		PLAIN: 17, PARA: 18, LISTITEM: 19, BULLETLIST: 20, ORDEREDLIST: 21,
+		H1: 22, H2: 23, H3: 24, H4: 25, H5: 26, H6: 27, BLOCKQUOTE: 28,
+		VERBATIM: 29, HTMLBLOCK: 30, HRULE: 31, REFERENCE: 32, NOTE: 33,
+		TABLE: 34, TABLEHEAD: 35, TABLEBODY: 36, TABLEROW: 37, TABLECELL: 38,
+		CELLSPAN: 39, TABLECAPTION: 40, TABLELABEL: 41, TABLESEPARATOR: 42,
+		DEFINITIONLIST: 43, DEFTITLE: 44, DEFDATA: 45, MARK: 46,
+		CRITICINS: 47, CRITICDEL: 48, CRITICSUB: 49, CRITICHIGHLIGHT: 50,
+		CRITICCOMMENT: 51, UNDEFNOTE: 52, EXTBLOCK: 53, CONTAINER: 54,
+		FIGURE: 55, PLACEHOLDER: 56, CITATION: 57, COMMENT: 58, COMMENTBLOCK: 59,
+		ROWSPAN: 60,
+	} {
+		if kind != value {
+			t.Errorf("kind %v has value %d, want %d", ElementKind(kind), kind, value)
+		}
+		name, err := ElementKind(kind).MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var k ElementKind
+		if err := k.UnmarshalText(name); err != nil || k != ElementKind(kind) {
+			t.Errorf("%s: got %v, %v", name, k, err)
+		}
+	}
+	k := RegisterKind("test-kind", FallbackText)
+	if k < FirstRegisteredKind {
+		t.Errorf("registered kind %d", k)
+	}
+	if got, ok := ParseElementKind("test-kind"); !ok || got != k || k.String() != "test-kind" {
+		t.Errorf("ParseElementKind(%q) = %v, %v", "test-kind", got, ok)
+	}
+	if _, err := ElementKind(-1).MarshalText(); err == nil {
+		t.Error("no error for unknown kind")
+	}
+}
+
+func TestOutline(t *testing.T) {
+	const input = "# A & B\n\nIntro *text*.\n\nMore.\n\n### A1\n\n## A2\n\nText.\n\n# C\n"
+	const want = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+	<head>
+		<title>Doc</title>
+	</head>
+	<body>
+		<outline text="A &amp; B" _note="Intro text.">
+			<outline text="A1"></outline>
+			<outline text="A2" _note="Text."></outline>
+		</outline>
+		<outline text="C"></outline>
+	</body>
+</opml>
+`
+	o := &Outline{Summaries: true}
+	NewParser(nil).Markdown(strings.NewReader(input), ToOutline(o))
+	if n := o.Nodes[0].Children[0]; n.Level != 3 || n.Anchor != "a1" {
+		t.Errorf("got %+v", n)
+	}
+	var buf bytes.Buffer
+	if err := o.WriteOPML(&buf, "Doc"); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	const input = "Preface.\n\n# One\n\nSee [x][].\n\n## One.1\n\nA note[^n].\n\n[^n]: Note.\n\n# Two\n\n[x]: /x\n"
+	secs := NewParser(&Extensions{Notes: true}).Split(strings.NewReader(input), 1, nil)
+	want := []Section{
+		{"", "Preface.\n\n[x]: /x\n", "<p>Preface.</p>\n"},
+		{"One", "# One\n\nSee [x][].\n\n## One.1\n\nA note[^n].\n\n[^n]: Note.\n\n[x]: /x\n",
+			"<h1>One</h1>\n\n<p>See <a href=\"/x\">x</a>.</p>\n\n<h2>One.1</h2>\n\n" +
+				"<p>A note<a class=\"noteref\" id=\"fnref1\" href=\"#fn1\" title=\"Jump to note 1\">[1]</a>.</p>\n\n" +
+				"<hr/>\n<ol id=\"notes\">\n<li id=\"fn1\">\n<p>Note.</p> <a href=\"#fnref1\" title=\"Jump back to reference\">[back]</a>\n</li>\n</ol>\n"},
+		{"Two", "# Two\n\n[x]: /x\n", "<h1>Two</h1>\n"},
+	}
+	if len(secs) != len(want) {
+		t.Fatalf("got %d sections: %q", len(secs), secs)
+	}
+	for i := range want {
+		if secs[i] != want[i] {
+			t.Errorf("section %d: got %q, want %q", i, secs[i], want[i])
+		}
+	}
+}
+
+func TestSmartQuotes(t *testing.T) {
+	/* cases as in the SmartyPants test suite */
+	for _, tc := range []struct{ input, want string }{
+		{`"Isn't this fun?"`, "&ldquo;Isn&rsquo;t this fun?&rdquo;"},
+		{`'Isn't this fun?'`, "&lsquo;Isn&rsquo;t this fun?&rsquo;"},
+		{`"he said 'hi'"`, "&ldquo;he said &lsquo;hi&rsquo;&rdquo;"},
+		{"\"he said\n'hi there'\"", "&ldquo;he said\n&lsquo;hi there&rsquo;&rdquo;"},
+		{`"It's the kids' toys," she said`, "&ldquo;It&rsquo;s the kids&rsquo; toys,&rdquo; she said"},
+		{`the '80s`, "the &rsquo;80s"},
+		{`'the '90s,' he said`, "&lsquo;the &rsquo;90s,&rsquo; he said"},
+		{`'99 was 'fine'`, "&rsquo;99 was &lsquo;fine&rsquo;"},
+		{`He is 6'2" tall`, "He is 6′2″ tall"},
+		{`"He is 6'2"," I said`, "&ldquo;He is 6′2″,&rdquo; I said"},
+	} {
+		var buf bytes.Buffer
+		NewParser(&Extensions{Smart: true}).Markdown(strings.NewReader(tc.input), ToHTML(&buf))
+		if got, want := buf.String(), "<p>"+tc.want+"</p>\n"; got != want {
+			t.Errorf("%q: got %q, want %q", tc.input, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package markdown
+
+// Footnotes collected across separately rendered fragments
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Note is the body of a footnote, rendered as HTML.
+type Note struct {
+	Num  int    // number shown at the note's references
+	HTML string // the body, without the link back to the reference
+}
+
+// A NoteList receives the footnotes of documents rendered with
+// HTMLOptions.Notes set, so that the notes of several fragments
+// can be printed once, at the end of the assembled page.
+type NoteList struct {
+	Notes []Note
+}
+
+// WriteHTML prints the notes like the section ToHTML appends
+// to a document, using opt, which may be nil, for details of
+// the output.
+func (l *NoteList) WriteHTML(w io.Writer, opt *HTMLOptions) {
+	if len(l.Notes) == 0 {
+		return
+	}
+	f := ToHTMLWithOptions(w, opt).(*htmlOut)
+	f.sp().s(f.void("<hr/>")).s("\n").s(f.notesTag())
+	for _, n := range l.Notes {
+		f.printNote(n.Num, func() {
+			f.s(n.HTML).pad(0) /* the body ends with text */
+		})
+	}
+	f.br().s("</ol>")
+	f.finish(!f.opt.NoFinalNewline)
+}
+
+// Merge appends the notes of m, numbered independently of those
+// already in l, renumbering them to follow the notes of l. The
+// returned replacer renumbers the note references in the HTML
+// output m has been collected from, which should be passed
+// through it before being assembled.
+func (l *NoteList) Merge(m *NoteList) *strings.Replacer {
+	offset := len(l.Notes)
+	var pairs []string
+	for _, n := range m.Notes {
+		nn := n.Num + offset
+		pairs = append(pairs,
+			fmt.Sprintf(`fnref%d"`, n.Num), fmt.Sprintf(`fnref%d"`, nn),
+			fmt.Sprintf(`fn%d"`, n.Num), fmt.Sprintf(`fn%d"`, nn),
+			fmt.Sprintf(`"Jump to note %d">[%d]`, n.Num, n.Num), fmt.Sprintf(`"Jump to note %d">[%d]`, nn, nn))
+	}
+	r := strings.NewReplacer(pairs...)
+	for _, n := range m.Notes {
+		l.Notes = append(l.Notes, Note{n.Num + offset, r.Replace(n.HTML)})
+	}
+	return r
+}
+
+// collectNotes renders the bodies of the endnotes into
+// the note list of the options.
+func (w *htmlOut) collectNotes() {
+	saved := w.baseWriter
+	defer func() { w.baseWriter = saved }()
+
+	/* notes referenced from within notes are appended while rendering */
+	for i := 0; i < len(w.endNotes); i++ {
+		var b strings.Builder
+		w.setOutput(&b)
+		w.elist(w.endNotes[i])
+		w.finish(false)
+		w.opt.Notes.Notes = append(w.opt.Notes.Notes, Note{w.noteBase + i + 1, b.String()})
+	}
+}
@@ -0,0 +1,122 @@
+package markdown
+
+// Configuring parsers using functional options
+
+// Options configure a Parser. They are usually set by passing the
+// results of the With functions to NewParser.
+type Options struct {
+	Extensions
+
+	// If positive, MaxDepth limits the nesting of blocks, like
+	// block quotes, list items, and containers. The content of
+	// blocks nested more deeply is taken as literal text.
+	MaxDepth int
+
+	// If positive, MaxNodes limits the number of elements of a
+	// document, and MaxOutput the number of bytes written for it by
+	// the HTML, text, and groff formatters, also when wrapped by
+	// ShiftHeadings or ReconcileTitle, so that services rendering
+	// untrusted input are protected from documents that expand
+	// into large output. When a limit is exceeded, the rest of the
+	// document is dropped, the formatter's Finish method is called,
+	// and Parser.Err returns a *LimitError; the output is then
+	// incomplete, and should be discarded.
+	MaxNodes  int
+	MaxOutput int
+}
+
+// An Option changes the Options of a parser created by NewParser.
+// Options are applied in order. An *Extensions is an Option, too,
+// which replaces the extensions set so far; a nil *Extensions,
+// or a nil Option, is ignored.
+type Option interface {
+	apply(*Options)
+}
+
+type optionFunc func(*Options)
+
+func (f optionFunc) apply(o *Options) {
+	f(o)
+}
+
+func (x *Extensions) apply(o *Options) {
+	if x != nil {
+		o.Extensions = *x
+	}
+}
+
+// WithOptions sets all options at once.
+func WithOptions(opt Options) Option {
+	return optionFunc(func(o *Options) { *o = opt })
+}
+
+// WithExtensions sets the extensions.
+func WithExtensions(x Extensions) Option {
+	return optionFunc(func(o *Options) { o.Extensions = x })
+}
+
+// WithSmart turns on smart quotes, dashes, and ellipses.
+func WithSmart() Option {
+	return optionFunc(func(o *Options) { o.Smart = true })
+}
+
+// WithNotes turns on footnote syntax.
+func WithNotes() Option {
+	return optionFunc(func(o *Options) { o.Notes = true })
+}
+
+// WithTables turns on table syntax.
+func WithTables() Option {
+	return optionFunc(func(o *Options) { o.Table = true })
+}
+
+// WithDlists turns on definition lists.
+func WithDlists() Option {
+	return optionFunc(func(o *Options) { o.Dlists = true })
+}
+
+// WithContainers turns on container blocks and admonitions.
+func WithContainers() Option {
+	return optionFunc(func(o *Options) { o.Containers = true })
+}
+
+// WithRawHTML selects how raw HTML in the input is treated.
+func WithRawHTML(policy HTMLPolicy) Option {
+	return optionFunc(func(o *Options) { o.RawHTML = policy })
+}
+
+// WithInline adds inline syntax.
+func WithInline(syn ...InlineSyntax) Option {
+	return optionFunc(func(o *Options) { o.Inline = append(o.Inline[:len(o.Inline):len(o.Inline)], syn...) })
+}
+
+// WithBlocks adds block syntax.
+func WithBlocks(syn ...BlockSyntax) Option {
+	return optionFunc(func(o *Options) { o.Blocks = append(o.Blocks[:len(o.Blocks):len(o.Blocks)], syn...) })
+}
+
+// WithInclude turns on include directives, reading
+// files using the function include.
+func WithInclude(include func(path string) ([]byte, error)) Option {
+	return optionFunc(func(o *Options) { o.Include = include })
+}
+
+// WithMaxDepth limits the nesting of blocks to n levels.
+func WithMaxDepth(n int) Option {
+	return optionFunc(func(o *Options) { o.MaxDepth = n })
+}
+
+// WithMaxNodes limits the number of elements of a document to n.
+func WithMaxNodes(n int) Option {
+	return optionFunc(func(o *Options) { o.MaxNodes = n })
+}
+
+// WithMaxOutput limits the output written for a document to n bytes.
+func WithMaxOutput(n int) Option {
+	return optionFunc(func(o *Options) { o.MaxOutput = n })
+}
+
+// WithMaxLineLength limits inline parsing to the first n bytes of a line.
+func WithMaxLineLength(n int) Option {
+	return optionFunc(func(o *Options) { o.MaxLineLength = n })
+}
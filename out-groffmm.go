@@ -20,7 +20,7 @@ package markdown
 // groff mm output functions
 
 import (
-	"log"
+	"io"
 	"strings"
 )
 
@@ -28,12 +28,16 @@ type troffOut struct {
 	baseWriter
 	inListItem bool
 	escape     *strings.Replacer
+
+	inNote    bool
+	noteQueue []*element        /* bodies of notes referenced from within a note */
+	notesSeen map[*element]bool /* notes printed or queued while inNote */
 }
 
 // Returns a formatter that writes the document in groff mm format.
-func ToGroffMM(w Writer) Formatter {
+func ToGroffMM(w io.Writer) Formatter {
 	f := new(troffOut)
-	f.baseWriter = baseWriter{w, 2}
+	f.baseWriter = newBaseWriter(w, "")
 	f.escape = strings.NewReplacer(`\`, `\e`)
 	return f
 }
@@ -41,8 +45,7 @@ func (f *troffOut) FormatBlock(tree *element) {
 	f.elist(tree)
 }
 func (f *troffOut) Finish() {
-	f.WriteByte('\n')
-	f.padded = 2
+	f.finish(true)
 }
 
 func (h *troffOut) sp() *troffOut {
@@ -128,11 +131,13 @@ func (w *troffOut) elem(elt *element, isFirst bool) *troffOut {
 		w.inline(`\fI`, elt, `\fR`)
 	case STRONG:
 		w.inline(`\fB`, elt, `\fR`)
+	case MARK, CRITICSUB, CRITICHIGHLIGHT:
+		w.children(elt)
+	case CRITICINS:
+		w.children(elt) /* changes are shown accepted */
+	case CRITICDEL, CRITICCOMMENT, COMMENT, COMMENTBLOCK:
 	case LIST:
 		w.children(elt)
-	case RAW:
-		/* Shouldn't occur - these are handled by process_raw_blocks() */
-		log.Fatalf("RAW")
 	case H1, H2, H3, H4, H5, H6:
 		h := ".H " + string('1'+elt.key-H1) + ` "` /* assumes H1 ... H6 are in order */
 		w.br().inline(h, elt, `"`)
@@ -144,8 +149,23 @@ func (w *troffOut) elem(elt *element, isFirst bool) *troffOut {
 		} else {
 			w.br().children(elt)
 		}
+	case FIGURE:
+		img := elt.children
+		w.req("P\n").elem(img, false)
+		if img.next != nil {
+			w.req("br\n").children(img.next)
+		} else if title := img.contents.link.title; title != "" {
+			w.req("br\n").str(title)
+		}
 	case HRULE:
 		w.br().s(`\l'\n(.lu*8u/10u'`)
+	case EXTBLOCK:
+		w.req("P\n").children(elt)
+	case CONTAINER:
+		if title := elt.contents.link.title; title != "" {
+			w.req("P\n").s(`\fB`).str(title).s(`\fP`)
+		}
+		w.children(elt)
 	case HTMLBLOCK:
 		/* don't print HTML block */
 	case VERBATIM:
@@ -178,19 +198,58 @@ func (w *troffOut) elem(elt *element, isFirst bool) *troffOut {
 		/* if contents.str == 0, then print note; else ignore, since this
 		 * is a note block that has been incorporated into the notes list */
 		if elt.contents.str == "" {
-			w.s("\\*F\n")
-			w.s(".FS\n")
-			w.skipPadding()
-			w.children(elt)
-			w.req("FE")
+			w.noteRef(elt.children)
 		}
+	case UNDEFNOTE:
+		w.str("[^" + elt.contents.str + "]")
+	case PLACEHOLDER:
+		w.str("{{" + elt.contents.str + "}}")
+	case CITATION:
+		w.str("[" + elt.contents.str + "]")
 	case REFERENCE:
 		/* Nonprinting */
 	default:
-		log.Fatalf("troffOut.elem encountered unknown element key = %d\n", elt.key)
+		info, ok := ElementKind(elt.key).info()
+		if !ok {
+			/* unknown kinds, e.g. of nodes built by programs, and RAW, which the parser replaces */
+			w.children(elt)
+			break
+		}
+		if info.fallback != FallbackOmit {
+			w.str(elt.contents.str)
+		}
 	}
 	if s != "" {
 		w.s(s)
 	}
 	return w
 }
+
+// noteRef writes a footnote mark, and the footnote itself. As mm
+// does not support nested footnotes, notes referenced from within
+// a note are printed after it; each of them only once, so that
+// notes referring to each other do not loop.
+func (w *troffOut) noteRef(body *element) {
+	if w.inNote {
+		if !w.notesSeen[body] {
+			w.notesSeen[body] = true
+			w.noteQueue = append(w.noteQueue, body)
+			w.s("\\*F")
+		}
+		return
+	}
+	w.s("\\*F\n")
+	w.notesSeen = map[*element]bool{body: true}
+	w.noteQueue = append(w.noteQueue[:0], body)
+	for i := 0; i < len(w.noteQueue); i++ {
+		if i > 0 {
+			w.br()
+		}
+		w.s(".FS\n")
+		w.skipPadding()
+		w.inNote = true
+		w.elist(w.noteQueue[i])
+		w.inNote = false
+		w.req("FE")
+	}
+}
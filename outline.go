@@ -0,0 +1,173 @@
+package markdown
+
+// Outlines of the heading hierarchy, and their export as OPML
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// An Outline receives the headings of a document, nested
+// according to their levels.
+type Outline struct {
+	// If Summaries is set, the first paragraph of each section,
+	// preceding any subsection, is kept as its summary.
+	Summaries bool
+
+	Nodes []*OutlineNode // the top-level sections
+}
+
+// An OutlineNode is a section of a document.
+type OutlineNode struct {
+	Text     string // heading text, without markup
+	Level    int
+	Anchor   string // identifier of the heading, see HTMLOptions.HeadingIDs
+	Summary  string // text of the first paragraph, see Outline.Summaries
+	Children []*OutlineNode
+}
+
+type outlineOut struct {
+	o       *Outline
+	open    []*OutlineNode /* Sections containing the current block. */
+	anchors anchors
+}
+
+// ToOutline returns a formatter that collects the heading
+// hierarchy of a document into o. Only top-level blocks are
+// taken into account. Each section is a child of the closest
+// preceding one of a lower level, also if levels are skipped.
+func ToOutline(o *Outline) Formatter {
+	return &outlineOut{o: o}
+}
+
+func (f *outlineOut) FormatBlock(tree *element) {
+	for ; tree != nil; tree = tree.next {
+		f.block(tree)
+	}
+}
+
+func (f *outlineOut) block(elt *element) {
+	switch elt.key {
+	case H1, H2, H3, H4, H5, H6:
+		n := &OutlineNode{Text: plainText(elt.children), Level: elt.key - H1 + 1}
+		if f.anchors == nil {
+			f.anchors = make(anchors)
+		}
+		n.Anchor = f.anchors.add(n.Text)
+		i := len(f.open)
+		for i > 0 && f.open[i-1].Level >= n.Level {
+			i--
+		}
+		f.open = f.open[:i]
+		if i == 0 {
+			f.o.Nodes = append(f.o.Nodes, n)
+		} else {
+			parent := f.open[i-1]
+			parent.Children = append(parent.Children, n)
+		}
+		f.open = append(f.open, n)
+	case PARA:
+		if !f.o.Summaries || len(f.open) == 0 {
+			break
+		}
+		if n := f.open[len(f.open)-1]; n.Summary == "" && n.Children == nil {
+			n.Summary = plainText(elt.children)
+		}
+	}
+}
+
+func (f *outlineOut) Finish() {
+	f.open = nil
+	f.anchors = nil
+}
+
+// An OutlineSection is a section of a Document, as returned by
+// Document.Outline.
+type OutlineSection struct {
+	Heading  *Node // node of kind H1 to H6
+	Level    int
+	ID       string // identifier of the heading, see HTMLOptions.HeadingIDs
+	Span     Span   // from the heading to the end of the section's last block
+	Children []*OutlineSection
+}
+
+// Outline returns the sections of the document, each starting at
+// a top-level heading, nested like those of ToOutline. A section
+// ends before the next heading of the same or a lower level, or at
+// the end of the document. Spans are those of the blocks parsed;
+// top-level blocks added to the tree later are not located.
+func (d *Document) Outline() (sections []*OutlineSection) {
+	if d.Root == nil {
+		return nil
+	}
+	var open []*OutlineSection
+	var end Position /* End of the last block. */
+	ids := make(anchors)
+	for _, n := range d.Root.Children {
+		span, located := d.spans[n]
+		if n.Kind >= H1 && n.Kind <= H6 {
+			s := &OutlineSection{Heading: n, Level: int(n.Kind-H1) + 1}
+			s.ID = ids.add(plainText(newElements(n.Children, make(map[*Node]*element))))
+			i := len(open)
+			for i > 0 && open[i-1].Level >= s.Level {
+				i--
+				open[i].Span.End = end
+			}
+			open = open[:i]
+			if i == 0 {
+				sections = append(sections, s)
+			} else {
+				open[i-1].Children = append(open[i-1].Children, s)
+			}
+			open = append(open, s)
+			s.Span.Start = span.Start
+		}
+		if located {
+			end = span.End
+		}
+	}
+	for _, s := range open {
+		s.Span.End = end
+	}
+	return sections
+}
+
+// opml is the structure of an OPML 2.0 document.
+type opml struct {
+	XMLName  xml.Name      `xml:"opml"`
+	Version  string        `xml:"version,attr"`
+	Title    string        `xml:"head>title"`
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Note     string        `xml:"_note,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// WriteOPML writes the outline as an OPML document with the given
+// title, e.g. for mind-mapping tools. Summaries are written as the
+// notes of the outline elements, in the _note attribute.
+func (o *Outline) WriteOPML(w io.Writer, title string) error {
+	doc := opml{Version: "2.0", Title: title, Outlines: opmlOutlines(o.Nodes)}
+	b, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if _, err = w.Write(b); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func opmlOutlines(nodes []*OutlineNode) (list []opmlOutline) {
+	for _, n := range nodes {
+		list = append(list, opmlOutline{n.Text, n.Summary, opmlOutlines(n.Children)})
+	}
+	return list
+}
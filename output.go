@@ -20,12 +20,21 @@ package markdown
 // HTML output functions
 
 import (
+	"bufio"
 	"fmt"
-	"log"
+	"html"
+	"io"
 	"math/rand"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
+// A Writer receives output efficiently, like a *bufio.Writer,
+// *bytes.Buffer, or *strings.Builder. Formatters write to a Writer
+// directly; any other io.Writer, like an http.ResponseWriter, is
+// buffered by the formatter, and the buffer is flushed at the end
+// of each document.
 type Writer interface {
 	Write([]byte) (int, error)
 	WriteString(string) (int, error)
@@ -36,35 +45,309 @@ type Writer interface {
 type baseWriter struct {
 	Writer
 	padded int
+	lines  *lineWriter
 }
 
+func newBaseWriter(w io.Writer, nl string) baseWriter {
+	lw := &lineWriter{nl: nl}
+	if ww, ok := w.(Writer); ok {
+		lw.w = ww
+	} else {
+		lw.buf = bufio.NewWriter(w)
+		lw.w = lw.buf
+	}
+	if nl == "" {
+		lw.nl = "\n"
+	}
+	return baseWriter{lw, 2, lw}
+}
+
+// finish terminates the output with a single line ending,
+// or none if final is false, and resets the padding state.
+func (w *baseWriter) finish(final bool) {
+	w.lines.finish(final)
+	w.padded = 2
+}
+
+// An HTMLElement replaces the HTML element written for an
+// element kind; see HTMLOptions.Elements.
+type HTMLElement struct {
+	Tag   string // element name, like "aside"; the default one, if empty
+	Class string // value of a class attribute to add; ignored in Email mode
+}
+
+// HTMLOptions control details of the HTML output.
+type HTMLOptions struct {
+	// Emit all non-ASCII characters as numeric character
+	// references, and smart punctuation and entities as
+	// numeric references too, for systems that are limited
+	// to ASCII or Latin-1 storage.
+	ASCII bool
+
+	// Line ending used throughout the output; "\n" if empty.
+	// Email messages, for example, require "\r\n".
+	Newline string
+
+	// Omit the line ending that otherwise terminates the output.
+	NoFinalNewline bool
+
+	// How CriticMarkup changes are rendered.
+	Critic CriticMode
+
+	// Add data-sourcepos="line:col-line:col" attributes to the
+	// HTML elements of top-level blocks, locating them in the
+	// source text, e.g. for synchronized scrolling in editors.
+	SourcePos bool
+
+	// Output flavor, which determines how void elements,
+	// like <br />, are written.
+	Flavor Flavor
+
+	// If set, Highlighter is called for each code block, with
+	// its language, and its unescaped text. If ok is true, the
+	// returned HTML replaces the usual <pre><code> element;
+	// EscapeText escapes text for it. The language is the info
+	// string of a fenced code block; for indented code blocks,
+	// the only kind parsed so far, it is empty, and highlighters
+	// have to guess it, if needed.
+	Highlighter func(lang, code string) (html string, ok bool)
+
+	// HTML renderers for elements of kinds registered by
+	// extensions, called with the element's content, which is
+	// unescaped, and may be passed to EscapeText. Elements
+	// of other registered kinds are rendered according to the
+	// fallback given to RegisterKind.
+	Kinds map[ElementKind]func(content string) (html string)
+
+	// HeadingOffset is added to the level of each heading, e.g.
+	// 2 to render "# Title" as <h3>, when a document is embedded
+	// into an existing page. Levels are kept within 1 and
+	// MaxHeadingLevel, or 6, if MaxHeadingLevel is zero.
+	HeadingOffset   int
+	MaxHeadingLevel int
+
+	// Add id attributes to headings, derived from their text,
+	// like GitHub does, e.g. id="getting-started".
+	HeadingIDs bool
+
+	// IDPrefix is prepended to the identifiers generated for
+	// headings, notes, and table captions, e.g. "ch1-" for
+	// id="ch1-fn1", so that documents sharing a page get distinct
+	// identifiers. Links to fragments, like "#getting-started",
+	// get the prefix too, so that they still lead to the headings
+	// of the document.
+	IDPrefix string
+
+	// ClassPrefix is prepended to the class names of the elements
+	// the HTML writer marks, like "noteref" or "undefined-note".
+	// Classes given in the document, e.g. of containers, are kept.
+	ClassPrefix string
+
+	// Elements changes the HTML elements written for elements of
+	// the given kinds, or adds classes to them, e.g. to render block
+	// quotes as <aside class="quote">. It applies to kinds written
+	// as a single HTML element: PARA, H1 to H6, BLOCKQUOTE, VERBATIM
+	// (the pre element), the kinds of lists and their items, TABLE,
+	// and inline kinds like EMPH, STRONG, MARK, and CODE.
+	Elements map[ElementKind]HTMLElement
+
+	// If not zero, Seed initializes the pseudo-random choices made
+	// when obfuscating email addresses, so that the output doesn't
+	// vary between runs, e.g. in snapshot tests.
+	Seed int64
+
+	// If Notes is set, the bodies of footnotes are rendered into
+	// it instead of a section at the end of the output, numbered
+	// after the notes it already holds. Fragments rendered with
+	// the same list may then share a section written by
+	// NoteList.WriteHTML.
+	Notes *NoteList
+
+	// If Sidenotes is set, notes are rendered where they are
+	// referenced, as sidenotes in the style of Tufte CSS: a label
+	// showing the note number, a hidden checkbox toggling the
+	// note on narrow screens, and a span of class "sidenote"
+	// containing the note, whose paragraphs are separated by line
+	// breaks. No section listing the notes is written. Sidenotes
+	// is ignored in Email mode.
+	Sidenotes bool
+
+	// If Cite is set, it is called with the works referred to by
+	// each citation, see Extensions.Citations, and returns the HTML
+	// written instead of the citation, like "(Doe 1999, 33)".
+	// Otherwise citations are written as they appear in the text,
+	// within a span of class "citation", whose data-cites attribute
+	// lists the keys. If Bibliography is set, it is called at the
+	// end of the output, following the notes, with the keys of the
+	// works cited, in the order of their first citation, and the
+	// HTML returned, like a section listing the works, is written.
+	Cite         func(cites []Citation) (html string)
+	Bibliography func(keys []string) (html string)
+
+	// Write comments, see Extensions.Comments, as HTML comments,
+	// instead of omitting them, e.g. for drafts. Comments is ignored
+	// in Email mode.
+	Comments bool
+
+	// Omit the section listing the footnotes, e.g. if a caller
+	// prints it itself.
+	NoNotes bool
+
+	// Print adjusts the output for printing, or conversion to
+	// PDF: level 1 and 2 headings not starting the document are
+	// preceded by page breaks; footnotes are listed at the end of
+	// each such section, instead of the end of the document; and
+	// the URLs of links are shown after the link text.
+	Print bool
+
+	// Add loading="lazy" and decoding="async" attributes to
+	// images, so that browsers load them when they are needed.
+	LazyImages bool
+
+	// If ExternalLinks is set, links to other sites get the
+	// attributes target="_blank" and rel="nofollow noopener
+	// noreferrer". IsExternal decides which links are external;
+	// if it is nil, links whose URLs start with "http:", "https:",
+	// or "//" are.
+	ExternalLinks bool
+	IsExternal    func(url string) bool
+
+	// Email restricts the output to HTML that strict email clients
+	// display reliably: elements have no class, id, style or title
+	// attributes, tables are written as paragraphs, one per row,
+	// and notes are numbered without links. HeadingIDs, SourcePos,
+	// Highlighter and Print are ignored. A plain text alternative
+	// can be produced using ToText.
+	Email bool
+}
+
+// A Flavor selects the dialect of the HTML output.
+type Flavor int
+
+const (
+	XHTML Flavor = iota // void elements are closed, as in <br />
+	HTML5               // void elements are written without slash, as in <br>
+)
+
+// A CriticMode selects how CriticMarkup is rendered.
+type CriticMode int
+
+const (
+	CriticMarkup CriticMode = iota // show changes using ins, del and mark elements
+	CriticAccept                   // apply all changes, drop comments
+	CriticReject                   // discard all changes, drop comments
+)
+
 type htmlOut struct {
 	baseWriter
 	obfuscate bool
+	opt       HTMLOptions
+	posAttr   string // attributes for the next block tag, like data-sourcepos
+	anchors   anchors
+	rand      *rand.Rand // source of obfuscation choices, if seeded
+
+	endNotes  []*element       /* Bodies of endnotes to print after main content. */
+	noteNums  map[*element]int /* Numbers of the endnotes, by body. */
+	noteBase  int              /* Number of notes preceding those of the document. */
+	notesDone int              /* Number of endnotes printed at the ends of sections. */
+	printed   bool             /* A block has been printed. */
+
+	cited    []string        /* Keys of the works cited, in order. */
+	citedSet map[string]bool /* The keys in cited. */
 
-	notenum  int
-	endNotes []*element /* List of endnotes to print after main content. */
+	sidenotes    int               /* Number of sidenotes printed. */
+	sidenoteOpen map[*element]bool /* Bodies of sidenotes being printed. */
 
 	tableColumn    int
 	tableAlignment string
 	cellType       rune
 }
 
-func ToHTML(w Writer) Formatter {
+func ToHTML(w io.Writer) Formatter {
+	return ToHTMLWithOptions(w, nil)
+}
+
+// ToHTMLWithOptions returns a formatter like ToHTML, whose
+// output can be adjusted using opt, which may be nil.
+func ToHTMLWithOptions(w io.Writer, opt *HTMLOptions) Formatter {
 	f := new(htmlOut)
-	f.baseWriter = baseWriter{w, 2}
+	if opt != nil {
+		f.opt = *opt
+	}
+	if f.opt.Email {
+		f.opt.HeadingIDs = false
+		f.opt.SourcePos = false
+		f.opt.Highlighter = nil
+		f.opt.Print = false
+		f.opt.Comments = false
+	}
+	f.setOutput(w)
+	if f.opt.Seed != 0 {
+		f.rand = rand.New(rand.NewSource(f.opt.Seed))
+	}
 	return f
 }
+
+// isExternal reports whether a link leads to another site.
+func (w *htmlOut) isExternal(url string) bool {
+	if w.opt.IsExternal != nil {
+		return w.opt.IsExternal(url)
+	}
+	u := strings.ToLower(url)
+	return strings.HasPrefix(u, "http:") || strings.HasPrefix(u, "https:") || strings.HasPrefix(u, "//")
+}
+
+// intn returns a pseudo-random number in [0, n).
+func (w *htmlOut) intn(n int) int {
+	if w.rand != nil {
+		return w.rand.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+func (f *htmlOut) setOutput(w io.Writer) {
+	f.baseWriter = newBaseWriter(w, f.opt.Newline)
+	if f.opt.ASCII {
+		f.Writer = asciiWriter{f.Writer}
+	}
+}
 func (f *htmlOut) FormatBlock(tree *element) {
+	if f.opt.Print {
+		f.printSection(tree)
+	}
 	f.elist(tree)
+	f.posAttr = ""
+}
+func (f *htmlOut) setSpan(s Span) {
+	if f.opt.SourcePos {
+		f.posAttr = ` data-sourcepos="` + s.String() + `"`
+	}
 }
 func (f *htmlOut) Finish() {
 	if len(f.endNotes) != 0 {
+		switch {
+		case f.opt.Notes != nil:
+			f.collectNotes()
+		case f.opt.NoNotes:
+		case f.opt.Print:
+			f.printSectionNotes()
+		default:
+			f.sp()
+			f.printEndnotes()
+		}
+		f.endNotes = f.endNotes[:0]
+		f.noteNums = nil
+	}
+	if f.opt.Bibliography != nil && len(f.cited) != 0 {
 		f.sp()
-		f.printEndnotes()
+		f.s(f.opt.Bibliography(f.cited))
 	}
-	f.WriteByte('\n')
-	f.padded = 2
+	f.cited, f.citedSet = nil, nil
+	f.notesDone = 0
+	f.sidenotes = 0
+	f.printed = false
+	f.anchors = nil
+	f.finish(!f.opt.NoFinalNewline)
 }
 
 // pad - add a number of newlines, the value of the
@@ -100,7 +383,17 @@ func (w *htmlOut) s(s string) *htmlOut {
 	return w
 }
 
-/* print string, escaping for HTML
+// EscapeText escapes the characters of s that are special in HTML
+// text and in attribute values enclosed in double quotes: '&', '<',
+// '>', and '"'. The HTML formatter escapes text, code, URLs, titles,
+// and alt texts this way.
+func EscapeText(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+/* print string, escaping for HTML, like EscapeText
  * If obfuscate selected, convert characters to hex or decimal entities at random
  */
 func (w *htmlOut) str(s string) *htmlOut {
@@ -120,7 +413,7 @@ func (w *htmlOut) str(s string) *htmlOut {
 			ws = "&quot;"
 		default:
 			if o && r < 128 && r >= 0 {
-				if rand.Intn(2) == 0 {
+				if w.intn(2) == 0 {
 					ws = fmt.Sprintf("&#%d;", r)
 				} else {
 					ws = fmt.Sprintf("&#%x;", r)
@@ -144,17 +437,117 @@ func (w *htmlOut) str(s string) *htmlOut {
 	return w
 }
 
+// print a named character reference, or, in ASCII mode,
+// the corresponding numeric one
+func (w *htmlOut) entity(name string) *htmlOut {
+	if w.opt.ASCII {
+		return w.str(html.UnescapeString(name))
+	}
+	return w.s(name)
+}
+
 func (w *htmlOut) children(el *element) *htmlOut {
 	return w.elist(el.children)
 }
+func (w *htmlOut) codeBlock(lang, code string) *htmlOut {
+	if w.opt.Highlighter != nil {
+		if html, ok := w.opt.Highlighter(lang, code); ok {
+			return w.s(html)
+		}
+	}
+	pre, name := w.elemTag("<pre>", VERBATIM)
+	return w.blockTag(pre).s("<code>").str(code).s("</code></" + name + ">")
+}
+
+// print the opening tag of a block element, adding
+// a pending data-sourcepos attribute
+func (w *htmlOut) blockTag(tag string) *htmlOut {
+	if w.posAttr == "" {
+		return w.s(tag)
+	}
+	i := len(tag) - 1
+	if strings.HasSuffix(tag, " />") {
+		i -= 2
+	}
+	w.s(tag[:i]).s(w.posAttr).s(tag[i:])
+	w.posAttr = ""
+	return w
+}
+
+// void returns the tag of a void element, given as e.g. "<br/>"
+// or "<hr />", written in the selected flavor
+func (w *htmlOut) void(tag string) string {
+	if w.opt.Flavor == HTML5 {
+		return strings.TrimRight(tag[:len(tag)-2], " ") + ">"
+	}
+	return tag
+}
+
+func (w *htmlOut) block(tag string, el *element) *htmlOut {
+	tag, name := w.elemTag(tag, el.key)
+	return w.blockTag(tag).children(el).s("</" + name + ">")
+}
+
+// elemTag returns the start tag of the HTML element for the
+// given element kind, and the element's name, given the default
+// start tag, as changed by HTMLOptions.Elements.
+func (w *htmlOut) elemTag(tag string, key int) (start, name string) {
+	name = tag[1 : len(tag)-1]
+	attrs := ""
+	if i := strings.IndexByte(name, ' '); i != -1 {
+		name, attrs = name[:i], name[i:]
+	}
+	e, ok := w.opt.Elements[ElementKind(key)]
+	if !ok {
+		return tag, name
+	}
+	if e.Tag != "" {
+		name = e.Tag
+	}
+	if e.Class != "" && !w.opt.Email {
+		attrs += ` class="` + html.EscapeString(e.Class) + `"`
+	}
+	return "<" + name + attrs + ">", name
+}
+
+// headingLevel returns the level of the HTML element
+// for a heading, adjusted according to the options.
+func (w *htmlOut) headingLevel(key int) int {
+	max := w.opt.MaxHeadingLevel
+	if max < 1 || max > 6 {
+		max = 6
+	}
+	level := key - H1 + 1 + w.opt.HeadingOffset /* assumes H1 ... H6 are in order */
+	if level > max {
+		level = max
+	}
+	if level < 1 {
+		level = 1
+	}
+	return level
+}
+
 func (w *htmlOut) inline(tag string, el *element) *htmlOut {
-	return w.s(tag).children(el).s("</").s(tag[1:])
+	tag, name := w.elemTag(tag, el.key)
+	return w.s(tag).children(el).s("</" + name + ">")
 }
 func (w *htmlOut) listBlock(tag string, el *element) *htmlOut {
-	return w.sp().s(tag).elist(el.children).br().s("</").s(tag[1:])
+	tag, name := w.elemTag(tag, el.key)
+	return w.sp().blockTag(tag).elist(el.children).br().s("</" + name + ">")
 }
 func (w *htmlOut) listItem(tag string, el *element) *htmlOut {
-	return w.br().s(tag).skipPadding().elist(el.children).s("</").s(tag[1:])
+	tag, name := w.elemTag(tag, el.key)
+	return w.br().s(tag).skipPadding().elist(el.children).s("</" + name + ">")
+}
+
+// listStart returns the number of the first item of an ordered
+// list, taken from its marker, like "3." or "3)".
+func listStart(list *element) int {
+	n, err := strconv.Atoi(strings.TrimRight(list.contents.str, ".)"))
+	if err != nil {
+		return 1
+	}
+	return n
 }
 
 /* print a list of elements
@@ -175,69 +568,150 @@ func (w *htmlOut) elem(elt *element) *htmlOut {
 	case SPACE:
 		s = elt.contents.str
 	case LINEBREAK:
-		s = "<br/>\n"
+		s = w.void("<br/>") + "\n"
 	case STR:
 		w.str(elt.contents.str)
 	case ELLIPSIS:
-		s = "&hellip;"
+		w.entity("&hellip;")
 	case EMDASH:
-		s = "&mdash;"
+		w.entity("&mdash;")
 	case ENDASH:
-		s = "&ndash;"
+		w.entity("&ndash;")
 	case APOSTROPHE:
-		s = "&rsquo;"
+		w.entity("&rsquo;")
 	case SINGLEQUOTED:
-		w.s("&lsquo;").children(elt).s("&rsquo;")
+		w.entity("&lsquo;").children(elt).entity("&rsquo;")
 	case DOUBLEQUOTED:
-		w.s("&ldquo;").children(elt).s("&rdquo;")
+		w.entity("&ldquo;").children(elt).entity("&rdquo;")
 	case CODE:
-		w.s("<code>").str(elt.contents.str).s("</code>")
+		tag, name := w.elemTag("<code>", elt.key)
+		w.s(tag).str(elt.contents.str).s("</" + name + ">")
 	case HTML:
-		s = elt.contents.str
+		if s = elt.contents.str; strings.HasPrefix(s, "&") && strings.HasSuffix(s, ";") {
+			w.entity(s)
+			s = ""
+		}
 	case LINK:
 		o := w.obfuscate
 		if strings.Index(elt.contents.link.url, "mailto:") == 0 {
 			w.obfuscate = true /* obfuscate mailto: links */
 		}
-		w.s(`<a href="`).str(elt.contents.link.url).s(`"`)
-		if len(elt.contents.link.title) > 0 {
+		w.s(`<a href="`).str(w.href(elt.contents.link.url)).s(`"`)
+		if len(elt.contents.link.title) > 0 && !w.opt.Email {
 			w.s(` title="`).str(elt.contents.link.title).s(`"`)
 		}
+		if w.opt.ExternalLinks && w.isExternal(elt.contents.link.url) {
+			w.s(` target="_blank" rel="nofollow noopener noreferrer"`)
+		}
 		w.s(">").elist(elt.contents.link.label).s("</a>")
+		if w.opt.Print && showURL(elt.contents.link) {
+			w.s(" (").str(elt.contents.link.url).s(")")
+		}
 		w.obfuscate = o
 	case IMAGE:
 		w.s(`<img src="`).str(elt.contents.link.url).s(`" alt="`)
-		w.elist(elt.contents.link.label).s(`"`)
-		if len(elt.contents.link.title) > 0 {
+		w.str(plainText(elt.contents.link.label)).s(`"`)
+		if len(elt.contents.link.title) > 0 && !w.opt.Email {
 			w.s(` title="`).str(elt.contents.link.title).s(`"`)
 		}
-		w.s(" />")
+		if w.opt.LazyImages {
+			w.s(` loading="lazy" decoding="async"`)
+		}
+		w.s(w.void(" />"))
 	case EMPH:
 		w.inline("<em>", elt)
 	case STRONG:
 		w.inline("<strong>", elt)
+	case MARK:
+		w.inline("<mark>", elt)
+	case CRITICINS:
+		switch w.opt.Critic {
+		case CriticMarkup:
+			w.inline("<ins>", elt)
+		case CriticAccept:
+			w.children(elt)
+		}
+	case CRITICDEL:
+		switch w.opt.Critic {
+		case CriticMarkup:
+			w.inline("<del>", elt)
+		case CriticReject:
+			w.children(elt)
+		}
+	case CRITICSUB:
+		w.children(elt)
+	case CRITICHIGHLIGHT:
+		if w.opt.Critic == CriticMarkup {
+			w.inline("<mark>", elt)
+		} else {
+			w.children(elt)
+		}
+	case CRITICCOMMENT:
+		if w.opt.Critic == CriticMarkup && !w.opt.Email {
+			w.s(`<span` + w.class("critic comment") + `>`).str(elt.contents.str).s("</span>")
+		}
 	case LIST:
 		w.children(elt)
-	case RAW:
-		/* Shouldn't occur - these are handled by process_raw_blocks() */
-		log.Fatalf("RAW")
 	case H1, H2, H3, H4, H5, H6:
-		h := "<h" + string('1'+elt.key-H1) + ">" /* assumes H1 ... H6 are in order */
-		w.sp().inline(h, elt)
+		h := "<h" + strconv.Itoa(w.headingLevel(elt.key)) + ">"
+		w.sp()
+		if w.opt.HeadingIDs {
+			if w.anchors == nil {
+				w.anchors = make(anchors)
+			}
+			w.posAttr = ` id="` + w.opt.IDPrefix + w.anchors.add(plainText(elt.children)) + `"` + w.posAttr
+		}
+		w.block(h, elt)
 	case PLAIN:
 		w.br().children(elt)
 	case PARA:
-		w.sp().inline("<p>", elt)
+		w.sp().block("<p>", elt)
+	case FIGURE:
+		img := elt.children
+		if w.opt.Email {
+			w.sp().s("<p>").elem(img).s("</p>")
+			if img.next != nil {
+				w.sp().block("<p>", img.next)
+			}
+			break
+		}
+		w.sp().blockTag("<figure>").s("\n").elem(img)
+		if img.next != nil {
+			w.s("\n<figcaption>").children(img.next).s("</figcaption>")
+		} else if title := img.contents.link.title; title != "" {
+			w.s("\n<figcaption>").str(title).s("</figcaption>")
+		}
+		w.s("\n</figure>")
 	case HRULE:
-		w.sp().s("<hr />")
+		w.sp().blockTag(w.void("<hr />"))
+	case EXTBLOCK:
+		w.sp().children(elt)
+	case CONTAINER:
+		if w.opt.Email {
+			w.sp().s("<div>\n")
+			if title := elt.contents.link.title; title != "" {
+				w.s("<p><strong>").str(title).s("</strong></p>\n")
+			}
+			w.skipPadding().children(elt).br().s("</div>")
+			break
+		}
+		w.sp().blockTag(`<div class="` + EscapeText(elt.contents.str) + `">`).s("\n")
+		if title := elt.contents.link.title; title != "" {
+			w.s(`<p` + w.class("title") + `>`).str(title).s("</p>\n")
+		}
+		w.skipPadding().children(elt).br().s("</div>")
 	case HTMLBLOCK:
 		w.sp().s(elt.contents.str)
 	case VERBATIM:
-		w.sp().s("<pre><code>").str(elt.contents.str).s("</code></pre>")
+		w.sp().codeBlock("", elt.contents.str)
 	case BULLETLIST:
 		w.listBlock("<ul>", elt)
 	case ORDEREDLIST:
-		w.listBlock("<ol>", elt)
+		if n := listStart(elt); n != 1 {
+			w.listBlock(`<ol start="`+strconv.Itoa(n)+`">`, elt)
+		} else {
+			w.listBlock("<ol>", elt)
+		}
 	case DEFINITIONLIST:
 		w.listBlock("<dl>", elt)
 	case DEFTITLE:
@@ -247,7 +721,8 @@ func (w *htmlOut) elem(elt *element) *htmlOut {
 	case LISTITEM:
 		w.listItem("<li>", elt)
 	case BLOCKQUOTE:
-		w.sp().s("<blockquote>\n").skipPadding().children(elt).br().s("</blockquote>")
+		tag, name := w.elemTag("<blockquote>", elt.key)
+		w.sp().blockTag(tag).s("\n").skipPadding().children(elt).br().s("</" + name + ">")
 	case REFERENCE:
 		/* Nonprinting */
 	case NOTE:
@@ -255,49 +730,94 @@ func (w *htmlOut) elem(elt *element) *htmlOut {
 		 * is a note block that has been incorporated into the notes list
 		 */
 		if elt.contents.str == "" {
-			w.endNotes = append(w.endNotes, elt) /* add an endnote to global endnotes list */
-			w.notenum++
-			nn := w.notenum
-			s = fmt.Sprintf(`<a class="noteref" id="fnref%d" href="#fn%d" title="Jump to note %d">[%d]</a>`,
-				nn, nn, nn, nn)
+			if w.opt.Sidenotes && !w.opt.Email {
+				w.sidenote(elt.children)
+				break
+			}
+			/* A note referenced repeatedly, possibly from within
+			 * itself, is printed once, with the number assigned
+			 * at its first reference.
+			 */
+			if nn, ok := w.noteNums[elt.children]; ok && w.opt.Email {
+				s = fmt.Sprintf("<sup>[%d]</sup>", nn)
+				break
+			} else if ok {
+				s = fmt.Sprintf(`<a%s href="#%sfn%d" title="Jump to note %d">[%d]</a>`,
+					w.class("noteref"), w.opt.IDPrefix, nn, nn, nn)
+				break
+			}
+			if len(w.endNotes) == 0 && w.opt.Notes != nil {
+				w.noteBase = len(w.opt.Notes.Notes)
+			}
+			w.endNotes = append(w.endNotes, elt.children) /* add an endnote to global endnotes list */
+			nn := w.noteBase + len(w.endNotes)
+			if w.noteNums == nil {
+				w.noteNums = make(map[*element]int)
+			}
+			w.noteNums[elt.children] = nn
+			if w.opt.Email {
+				s = fmt.Sprintf("<sup>[%d]</sup>", nn)
+				break
+			}
+			s = fmt.Sprintf(`<a%s id="%sfnref%d" href="#%sfn%d" title="Jump to note %d">[%d]</a>`,
+				w.class("noteref"), w.opt.IDPrefix, nn, w.opt.IDPrefix, nn, nn, nn)
+		}
+	case UNDEFNOTE:
+		w.s(`<sup` + w.class("undefined-note") + `>`).str("[^" + elt.contents.str + "]").s("</sup>")
+	case PLACEHOLDER:
+		w.str("{{" + elt.contents.str + "}}")
+	case CITATION:
+		w.citation(elt.contents.str)
+	case COMMENT:
+		if w.opt.Comments {
+			w.s("<!--" + EscapeText(elt.contents.str) + "-->")
+		}
+	case COMMENTBLOCK:
+		if w.opt.Comments {
+			w.sp().s("<!--" + EscapeText(elt.contents.str) + "-->")
 		}
 	case TABLE:
-		w.s("\n\n<table>\n")
+		if w.opt.Email {
+			w.emailTable(elt)
+			break
+		}
+		id := ""
+		if c := tableCaption(elt); c != nil {
+			id = fmt.Sprintf(` id="%s%s"`, w.opt.IDPrefix, captionLabel(c))
+		}
+		tag, name := w.elemTag("<table"+id+">", elt.key)
+		w.sp().blockTag(tag).s("\n")
+		w.cellType = 'd'
 		w.children(elt)
-		w.s("</table>\n")
+		w.s("</" + name + ">")
+		w.padded = 0 /* cells, which may contain blocks, leave it set */
 	case TABLESEPARATOR:
 		w.tableAlignment = elt.contents.str
-	case TABLECAPTION:
-		label := ""
-		if elt.children.key == TABLELABEL {
-			label = labelFromElementList(elt.children.children)
-		} else {
-			label = labelFromElementList(elt.children)
-		}
-		w.s(fmt.Sprintf("<caption id=\"%s\">", label))
-		w.children(elt)
-		w.s("</caption>\n")
-	case TABLELABEL:
-		break
-	case TABLEHEAD:
 		w.s("<colgroup>\n")
 		for _, alignmentChar := range w.tableAlignment {
 			switch alignmentChar {
 			case 'r':
-				w.s("<col style=\"text-align:right;\"/>\n")
+				w.s(w.void("<col style=\"text-align:right;\"/>") + "\n")
 			case 'R':
-				w.s("<col style=\"text-align:right;\" class=\"extended\"/>\n")
+				w.s(w.void("<col style=\"text-align:right;\""+w.class("extended")+"/>") + "\n")
 			case 'c':
-				w.s("<col style=\"text-align:center;\"/>\n")
+				w.s(w.void("<col style=\"text-align:center;\"/>") + "\n")
 			case 'C':
-				w.s("<col style=\"text-align:center;\" class=\"extended\"/>\n")
+				w.s(w.void("<col style=\"text-align:center;\""+w.class("extended")+"/>") + "\n")
 			case 'l':
-				w.s("<col style=\"text-align:left;\"/>\n")
+				w.s(w.void("<col style=\"text-align:left;\"/>") + "\n")
 			case 'L':
-				w.s("<col style=\"text-align:left;\" class=\"extended\"/>\n")
+				w.s(w.void("<col style=\"text-align:left;\""+w.class("extended")+"/>") + "\n")
 			}
 		}
 		w.s("</colgroup>\n")
+	case TABLECAPTION:
+		w.s("<caption>")
+		w.children(elt)
+		w.s("</caption>\n")
+	case TABLELABEL:
+		break
+	case TABLEHEAD:
 		w.cellType = 'h'
 		w.s("\n<thead>\n")
 		w.children(elt)
@@ -313,7 +833,17 @@ func (w *htmlOut) elem(elt *element) *htmlOut {
 		w.children(elt)
 		w.s("</tr>\n")
 	case TABLECELL:
-		switch w.tableAlignment[w.tableColumn] {
+		rows := cellRows(elt)
+		if rows == 0 {
+			/* covered by a cell above */
+			w.tableColumn++
+			break
+		}
+		var align byte
+		if w.tableColumn < len(w.tableAlignment) {
+			align = w.tableAlignment[w.tableColumn]
+		}
+		switch align {
 		case 'r':
 			w.s(fmt.Sprintf("\t<t%c style=\"text-align:right;\"", w.cellType))
 		case 'R':
@@ -326,10 +856,16 @@ func (w *htmlOut) elem(elt *element) *htmlOut {
 			w.s(fmt.Sprintf("\t<t%c style=\"text-align:left;\"", w.cellType))
 		case 'L':
 			w.s(fmt.Sprintf("\t<t%c style=\"text-align:left;\"", w.cellType))
+		default:
+			/* a cell beyond the columns of the separator line */
+			w.s(fmt.Sprintf("\t<t%c", w.cellType))
 		}
 		if elt.children != nil && elt.children.key == CELLSPAN {
 			w.s(fmt.Sprintf(" colspan=\"%d\"", len(elt.children.contents.str)+1))
 		}
+		if rows > 1 {
+			w.s(fmt.Sprintf(" rowspan=\"%d\"", rows))
+		}
 		w.s(">")
 		w.padded = 2
 		if elt.children != nil {
@@ -337,10 +873,25 @@ func (w *htmlOut) elem(elt *element) *htmlOut {
 		}
 		w.s(fmt.Sprintf("</t%c>\n", w.cellType))
 		w.tableColumn++
-	case CELLSPAN:
+	case CELLSPAN, ROWSPAN:
 		break
 	default:
-		log.Fatalf("htmlOut.elem encountered unknown element key = %d\n", elt.key)
+		info, ok := ElementKind(elt.key).info()
+		if !ok {
+			/* unknown kinds, e.g. of nodes built by programs, and RAW, which the parser replaces */
+			w.children(elt)
+			break
+		}
+		if render := w.opt.Kinds[ElementKind(elt.key)]; render != nil {
+			s = render(elt.contents.str)
+			break
+		}
+		switch info.fallback {
+		case FallbackText:
+			w.str(elt.contents.str)
+		case FallbackRaw:
+			s = elt.contents.str
+		}
 	}
 	if s != "" {
 		w.s(s)
@@ -349,19 +900,174 @@ func (w *htmlOut) elem(elt *element) *htmlOut {
 }
 
 func (w *htmlOut) printEndnotes() {
-	counter := 0
+	w.s(w.void("<hr/>")).s("\n").s(w.notesTag())
+	/* notes referenced from within notes are appended while printing */
+	for i := 0; i < len(w.endNotes); i++ {
+		w.printNote(i+1, func() { w.elist(w.endNotes[i]) })
+	}
+	w.br().s("</ol>")
+}
 
-	w.s("<hr/>\n<ol id=\"notes\">")
-	for _, elt := range w.endNotes {
-		counter++
-		w.br().s(fmt.Sprintf("<li id=\"fn%d\">\n", counter)).skipPadding()
-		w.children(elt)
-		w.s(fmt.Sprintf(" <a href=\"#fnref%d\" title=\"Jump back to reference\">[back]</a>", counter))
-		w.br().s("</li>")
+// notesTag returns the opening tag of the list of notes.
+func (w *htmlOut) notesTag() string {
+	if w.opt.Email {
+		return "<ol>"
+	}
+	return `<ol id="` + w.opt.IDPrefix + `notes">`
+}
+
+// class returns the class attribute for the given, space
+// separated, class names, with ClassPrefix prepended, or
+// nothing in Email mode.
+func (w *htmlOut) class(names string) string {
+	if w.opt.Email {
+		return ""
+	}
+	if p := w.opt.ClassPrefix; p != "" {
+		names = p + strings.ReplaceAll(names, " ", " "+p)
+	}
+	return ` class="` + names + `"`
+}
+
+// href returns the URL of a link, with IDPrefix inserted
+// into references to fragments of the document.
+func (w *htmlOut) href(url string) string {
+	if strings.HasPrefix(url, "#") && len(url) > 1 {
+		return "#" + w.opt.IDPrefix + url[1:]
+	}
+	return url
+}
+
+// sidenote prints a note at its reference, as a sidenote. A note
+// referenced repeatedly is printed at each reference; a reference
+// within the note itself is dropped.
+func (w *htmlOut) sidenote(body *element) {
+	if w.sidenoteOpen[body] {
+		return
+	}
+	if w.sidenoteOpen == nil {
+		w.sidenoteOpen = make(map[*element]bool)
+	}
+	w.sidenoteOpen[body] = true
+	w.sidenotes++
+	id := w.opt.IDPrefix + "sn" + strconv.Itoa(w.sidenotes)
+	w.s(`<label for="` + id + `"` + w.class("margin-toggle sidenote-number") + `></label>`)
+	w.s(w.void(`<input type="checkbox" id="` + id + `"` + w.class("margin-toggle") + `/>`))
+	w.s(`<span` + w.class("sidenote") + `>`)
+	w.sidenoteBlocks(body, true)
+	w.s("</span>")
+	delete(w.sidenoteOpen, body)
+}
+
+// sidenoteBlocks prints the blocks of a sidenote, writing the
+// contents of paragraphs without the enclosing p elements.
+func (w *htmlOut) sidenoteBlocks(list *element, first bool) bool {
+	for b := list; b != nil; b = b.next {
+		switch b.key {
+		case LIST:
+			first = w.sidenoteBlocks(b.children, first)
+			continue
+		}
+		if !first {
+			w.s(w.void("<br/>"))
+		}
+		first = false
+		switch b.key {
+		case PARA, PLAIN:
+			w.children(b)
+		default:
+			w.elem(b)
+		}
+	}
+	return first
+}
+
+// printSection handles a top-level block in Print mode: a level 1
+// or 2 heading ends the previous section, whose notes are printed,
+// and starts a new page, unless nothing has been printed yet.
+func (w *htmlOut) printSection(tree *element) {
+	if tree.key >= H1 && tree.key <= H6 && w.headingLevel(tree.key) <= 2 {
+		if w.opt.Notes == nil && !w.opt.NoNotes {
+			w.printSectionNotes()
+		}
+		if w.printed {
+			w.posAttr = ` style="break-before: page"` + w.posAttr
+		}
+	}
+	if !nonprinting(tree) {
+		w.printed = true
+	}
+}
+
+// printSectionNotes prints the notes referenced since
+// the end of the previous section.
+func (w *htmlOut) printSectionNotes() {
+	if w.notesDone == len(w.endNotes) {
+		return
+	}
+	w.sp().s(w.void("<hr/>")).s("\n<ol" + w.class("notes"))
+	if w.notesDone > 0 {
+		w.s(` start="` + strconv.Itoa(w.notesDone+1) + `"`)
+	}
+	w.s(">")
+	/* notes referenced from within notes are appended while printing */
+	for ; w.notesDone < len(w.endNotes); w.notesDone++ {
+		i := w.notesDone
+		w.printNote(i+1, func() { w.elist(w.endNotes[i]) })
 	}
 	w.br().s("</ol>")
 }
 
+// showURL reports whether the URL of a link is shown in Print
+// mode, and in plain text output: not for links within the document, or autolinks, whose
+// text is the URL already.
+func showURL(l *link) bool {
+	text := plainText(l.label)
+	return !strings.HasPrefix(l.url, "#") && l.url != text && l.url != "mailto:"+text
+}
+
+// printNote prints the list item of a note, whose
+// body is printed by body.
+func (w *htmlOut) printNote(num int, body func()) {
+	if w.opt.Email {
+		w.br().s("<li>\n").skipPadding()
+		body()
+		w.br().s("</li>")
+		return
+	}
+	w.br().s(fmt.Sprintf("<li id=\"%sfn%d\">\n", w.opt.IDPrefix, num)).skipPadding()
+	body()
+	w.s(fmt.Sprintf(" <a href=\"#%sfnref%d\" title=\"Jump back to reference\">[back]</a>", w.opt.IDPrefix, num))
+	w.br().s("</li>")
+}
+
+// emailTable prints a table in Email mode, as a paragraph for each
+// row, with cells separated by vertical bars, and header cells in
+// bold; a caption is printed as a paragraph preceding the rows.
+func (w *htmlOut) emailTable(table *element) {
+	for part := table.children; part != nil; part = part.next {
+		switch part.key {
+		case TABLECAPTION:
+			w.sp().s("<p>").elist(part.children).s("</p>")
+		case TABLEHEAD, TABLEBODY:
+			for row := part.children; row != nil; row = row.next {
+				w.sp().s("<p>")
+				for cell := row.children; cell != nil; cell = cell.next {
+					if cell != row.children {
+						w.s(" | ")
+					}
+					if part.key == TABLEHEAD {
+						w.inline("<strong>", cell)
+					} else {
+						w.children(cell)
+					}
+				}
+				w.s("</p>")
+			}
+		}
+	}
+}
+
 func rawElementToString(elt *element) string {
 	if elt.key == LINK {
 		return rawElementListToString(elt.contents.link.label)
@@ -383,8 +1089,13 @@ func rawElementListToString(list *element) string {
 }
 
 func labelFromElementList(list *element) string {
+	return labelFromString(rawElementListToString(list))
+}
+
+// labelFromString returns the identifier of a table, as derived
+// from the text of its label or caption.
+func labelFromString(str string) string {
 	valid := false
-	str := rawElementListToString(list)
 	label := ""
 
 	for _, c := range str {
@@ -407,3 +1118,187 @@ func labelFromElementList(list *element) string {
 
 	return strings.ToLower(label)
 }
+
+// asciiWriter replaces non-ASCII characters by
+// numeric character references.
+type asciiWriter struct {
+	Writer
+}
+
+func (w asciiWriter) Write(b []byte) (int, error) {
+	return w.WriteString(string(b))
+}
+
+func (w asciiWriter) WriteString(s string) (n int, err error) {
+	i0 := 0
+	for i, r := range s {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		if _, err = w.Writer.WriteString(s[i0:i]); err != nil {
+			return
+		}
+		if _, err = w.WriteRune(r); err != nil {
+			return
+		}
+		i0 = i + utf8.RuneLen(r)
+	}
+	if _, err = w.Writer.WriteString(s[i0:]); err != nil {
+		return
+	}
+	return len(s), nil
+}
+
+func (w asciiWriter) WriteRune(r rune) (int, error) {
+	if r < utf8.RuneSelf {
+		return w.Writer.WriteRune(r)
+	}
+	return w.Writer.WriteString("&#" + strconv.Itoa(int(r)) + ";")
+}
+
+// lineWriter converts line endings, whether "\n", "\r\n" or "\r",
+// to nl. Trailing line endings are held back until more text
+// follows, so that finish can terminate the output predictably.
+type lineWriter struct {
+	w       Writer
+	buf     *bufio.Writer // buffer of an io.Writer that isn't a Writer
+	nl      string
+	pending int  // number of line endings held back
+	cr      bool // last byte written was '\r'
+
+	max      int  // limit of the output, in bytes, if positive
+	n        int  // bytes written, if limited
+	exceeded bool // output has been dropped
+}
+
+// allow reports whether k more bytes may be written, and
+// counts them, if the output is limited.
+func (w *lineWriter) allow(k int) bool {
+	if w.max <= 0 {
+		return true
+	}
+	if w.exceeded || w.n+k > w.max {
+		w.exceeded = true
+		return false
+	}
+	w.n += k
+	return true
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	return w.WriteString(string(b))
+}
+
+func (w *lineWriter) WriteString(s string) (n int, err error) {
+	for n < len(s) {
+		switch c := s[n]; c {
+		case '\n', '\r':
+			if c == '\r' || !w.cr {
+				w.pending++
+			}
+			w.cr = c == '\r'
+			n++
+			continue
+		}
+		i := strings.IndexAny(s[n:], "\r\n")
+		if i == -1 {
+			i = len(s) - n
+		}
+		if err = w.flush(); err != nil {
+			return
+		}
+		if !w.allow(i) {
+			return len(s), nil
+		}
+		if _, err = w.w.WriteString(s[n : n+i]); err != nil {
+			return
+		}
+		w.cr = false
+		n += i
+	}
+	return
+}
+
+func (w *lineWriter) WriteRune(r rune) (int, error) {
+	if r == '\n' || r == '\r' {
+		return w.WriteString(string(r))
+	}
+	if err := w.flush(); err != nil {
+		return 0, err
+	}
+	w.cr = false
+	k := utf8.RuneLen(r)
+	if k == -1 {
+		k = utf8.RuneLen(utf8.RuneError)
+	}
+	if !w.allow(k) {
+		return k, nil
+	}
+	return w.w.WriteRune(r)
+}
+
+func (w *lineWriter) WriteByte(c byte) error {
+	if c == '\n' || c == '\r' {
+		_, err := w.WriteString(string(rune(c)))
+		return err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	w.cr = false
+	if !w.allow(1) {
+		return nil
+	}
+	return w.w.WriteByte(c)
+}
+
+func (w *lineWriter) flush() error {
+	for ; w.pending > 0; w.pending-- {
+		if !w.allow(len(w.nl)) {
+			continue
+		}
+		if _, err := w.w.WriteString(w.nl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish drops any line endings held back, and writes
+// a single one, if final is set.
+func (w *lineWriter) finish(final bool) {
+	w.pending = 0
+	w.cr = false
+	if final && w.allow(len(w.nl)) {
+		w.w.WriteString(w.nl)
+	}
+	if w.buf != nil {
+		w.buf.Flush()
+	}
+}
+
+// citation writes a citation, and records the keys of the works
+// it refers to.
+func (w *htmlOut) citation(text string) {
+	cites, _ := parseCitations(text)
+	keys := make([]string, len(cites))
+	for i, c := range cites {
+		keys[i] = c.Key
+		if !w.citedSet[c.Key] {
+			if w.citedSet == nil {
+				w.citedSet = make(map[string]bool)
+			}
+			w.citedSet[c.Key] = true
+			w.cited = append(w.cited, c.Key)
+		}
+	}
+	switch {
+	case w.opt.Cite != nil:
+		w.s(w.opt.Cite(cites))
+	case w.opt.Email:
+		w.str("[" + text + "]")
+	default:
+		w.s(`<span` + w.class("citation") + ` data-cites="` + EscapeText(strings.Join(keys, " ")) + `">`)
+		w.str("[" + text + "]").s("</span>")
+	}
+}
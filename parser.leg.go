@@ -23,7 +23,6 @@ package markdown
 import (
 	"fmt"
 	"io"
-	"log"
 	"strings"
 )
 
@@ -31,12 +30,16 @@ const (
 	parserIfaceVersion_16 = iota
 )
 
-// Semantic value of a parsing action.
+// Semantic value of a parsing action. Actions build lists of
+// elements linked by next, often in reverse order; they are kept
+// internal. Parser.Parse returns trees of Nodes, whose children are
+// slices, to users of the package.
 type element struct {
 	key int
 	contents
 	children *element
 	next     *element
+	loose    bool /* List, or list item, with blank lines between blocks. */
 }
 
 // Information (label, URL and title) for a link.
@@ -44,6 +47,7 @@ type link struct {
 	label *element
 	url   string
 	title string
+	ref   string /* Label of the reference, for links in reference style. */
 }
 
 // Union for contents of an Element (string, list, or link).
@@ -53,6 +57,8 @@ type contents struct {
 }
 
 // Types of semantic values returned by parsers.
+// The values are exposed as ElementKinds, and must stay
+// stable: new types are added at the end.
 const (
 	LIST = iota /* A generic list of values. For ordered and bullet lists, see below. */
 	RAW         /* Raw markdown to be processed further */
@@ -100,15 +106,42 @@ const (
 	DEFINITIONLIST
 	DEFTITLE
 	DEFDATA
+	MARK
+	CRITICINS
+	CRITICDEL
+	CRITICSUB
+	CRITICHIGHLIGHT
+	CRITICCOMMENT
+	UNDEFNOTE
+	EXTBLOCK
+	CONTAINER
+	FIGURE
+	PLACEHOLDER
+	CITATION
+	COMMENT
+	COMMENTBLOCK
+	ROWSPAN
 	numVAL
 )
 
 type state struct {
 	extension  Extensions
 	heap       elemHeap
-	tree       *element /* Results of parse. */
-	references *element /* List of link references found. */
-	notes      *element /* List of footnotes found. */
+	tree       *element            /* Results of parse. */
+	refs       ReferenceStore      /* Link references found. */
+	notes      map[string]*element /* Footnotes found, by label. */
+	noteBlocks []string            /* Contents of quotes and list items, to be searched for notes. */
+
+	inlineNotes bool           /* Inline notes have been parsed since the flag was cleared. */
+	undefNotes  []string       /* Labels of references to undefined notes, since cleared. */
+	tables      map[string]int /* Numbers of the captioned tables, by label. */
+	tableRows   []tableProblem /* Problems with the rows of tables, since cleared. */
+	tags        []string       /* Hashtags found, see Parser.Tags. */
+	tagSet      map[string]bool
+	special     [256]bool      /* Bytes NormalChar doesn't match. */
+	tracePos    int            /* Position found by TracePosition, see Parser.SetTrace. */
+	listDelim   byte           /* Delimiter of the ordered list being parsed, 0 for bullet lists. */
+	looseEnd    bool           /* The text parsed ends an item of a loose list. */
 }
 
 
@@ -144,102 +177,6 @@ const (
 	ruleEnumerator
 	ruleOrderedList
 	ruleListBlockLine
-	ruleHtmlBlockOpenAddress
-	ruleHtmlBlockCloseAddress
-	ruleHtmlBlockAddress
-	ruleHtmlBlockOpenBlockquote
-	ruleHtmlBlockCloseBlockquote
-	ruleHtmlBlockBlockquote
-	ruleHtmlBlockOpenCenter
-	ruleHtmlBlockCloseCenter
-	ruleHtmlBlockCenter
-	ruleHtmlBlockOpenDir
-	ruleHtmlBlockCloseDir
-	ruleHtmlBlockDir
-	ruleHtmlBlockOpenDiv
-	ruleHtmlBlockCloseDiv
-	ruleHtmlBlockDiv
-	ruleHtmlBlockOpenDl
-	ruleHtmlBlockCloseDl
-	ruleHtmlBlockDl
-	ruleHtmlBlockOpenFieldset
-	ruleHtmlBlockCloseFieldset
-	ruleHtmlBlockFieldset
-	ruleHtmlBlockOpenForm
-	ruleHtmlBlockCloseForm
-	ruleHtmlBlockForm
-	ruleHtmlBlockOpenH1
-	ruleHtmlBlockCloseH1
-	ruleHtmlBlockH1
-	ruleHtmlBlockOpenH2
-	ruleHtmlBlockCloseH2
-	ruleHtmlBlockH2
-	ruleHtmlBlockOpenH3
-	ruleHtmlBlockCloseH3
-	ruleHtmlBlockH3
-	ruleHtmlBlockOpenH4
-	ruleHtmlBlockCloseH4
-	ruleHtmlBlockH4
-	ruleHtmlBlockOpenH5
-	ruleHtmlBlockCloseH5
-	ruleHtmlBlockH5
-	ruleHtmlBlockOpenH6
-	ruleHtmlBlockCloseH6
-	ruleHtmlBlockH6
-	ruleHtmlBlockOpenMenu
-	ruleHtmlBlockCloseMenu
-	ruleHtmlBlockMenu
-	ruleHtmlBlockOpenNoframes
-	ruleHtmlBlockCloseNoframes
-	ruleHtmlBlockNoframes
-	ruleHtmlBlockOpenNoscript
-	ruleHtmlBlockCloseNoscript
-	ruleHtmlBlockNoscript
-	ruleHtmlBlockOpenOl
-	ruleHtmlBlockCloseOl
-	ruleHtmlBlockOl
-	ruleHtmlBlockOpenP
-	ruleHtmlBlockCloseP
-	ruleHtmlBlockP
-	ruleHtmlBlockOpenPre
-	ruleHtmlBlockClosePre
-	ruleHtmlBlockPre
-	ruleHtmlBlockOpenTable
-	ruleHtmlBlockCloseTable
-	ruleHtmlBlockTable
-	ruleHtmlBlockOpenUl
-	ruleHtmlBlockCloseUl
-	ruleHtmlBlockUl
-	ruleHtmlBlockOpenDd
-	ruleHtmlBlockCloseDd
-	ruleHtmlBlockDd
-	ruleHtmlBlockOpenDt
-	ruleHtmlBlockCloseDt
-	ruleHtmlBlockDt
-	ruleHtmlBlockOpenFrameset
-	ruleHtmlBlockCloseFrameset
-	ruleHtmlBlockFrameset
-	ruleHtmlBlockOpenLi
-	ruleHtmlBlockCloseLi
-	ruleHtmlBlockLi
-	ruleHtmlBlockOpenTbody
-	ruleHtmlBlockCloseTbody
-	ruleHtmlBlockTbody
-	ruleHtmlBlockOpenTd
-	ruleHtmlBlockCloseTd
-	ruleHtmlBlockTd
-	ruleHtmlBlockOpenTfoot
-	ruleHtmlBlockCloseTfoot
-	ruleHtmlBlockTfoot
-	ruleHtmlBlockOpenTh
-	ruleHtmlBlockCloseTh
-	ruleHtmlBlockTh
-	ruleHtmlBlockOpenThead
-	ruleHtmlBlockCloseThead
-	ruleHtmlBlockThead
-	ruleHtmlBlockOpenTr
-	ruleHtmlBlockCloseTr
-	ruleHtmlBlockTr
 	ruleHtmlBlockOpenScript
 	ruleHtmlBlockCloseScript
 	ruleHtmlBlockScript
@@ -379,13 +316,47 @@ const (
 	ruleRightAlign
 	ruleCellDivider
 	ruleTableCaption
+	ruleDefMarkChar
+	ruleUtf8Tail
+	ruleDefListTight
+	ruleDefListLoose
+	ruleDefItem
+	ruleDefItemTight
+	ruleMark
+	ruleCritic
+	ruleCriticIns
+	ruleCriticDel
+	ruleCriticSub
+	ruleCriticHighlight
+	ruleCriticComment
+	ruleHtmlSpecial
+	ruleHtmlCdata
+	ruleHtmlProcessing
+	ruleHtmlDeclaration
+	ruleExtInline
+	ruleExtBlock
+	ruleContainer
+	ruleLineTail
+	ruleListMarker
+	rulePrime
+	ruleTableRef
+	ruleHashtag
+	ruleNormalChars
+	ruleTracePosition
+	rulePlaceholder
+	ruleCitation
+	ruleComment
+	ruleCommentBlock
+	ruleGridTable
+	ruleAngleSource
+	ruleBlankLines
 )
 
 type yyParser struct {
 	state
 	Buffer string
 	Min, Max int
-	rules [266]func() bool
+	rules [204]func() bool
 	ResetBuffer	func(string) string
 }
 
@@ -475,7 +446,8 @@ func (p *yyParser) Init() {
 		/* 3 Para */
 		func(yytext string, _ int) {
 			a := yyval[yyp-1]
-			 yy = a; yy.key = PARA 
+			 yy = a; yy.key = PARA
+              if p.extension.Figures { yy = p.figure(yy) } 
 			yyval[yyp-1] = a
 		},
 		/* 4 Plain */
@@ -619,8 +591,7 @@ func (p *yyParser) Init() {
 			b := yyval[yyp-1]
 			a := yyval[yyp-2]
 			
-                  li := b.children
-                  li.contents.str += "\n\n"
+                  markLoose(b)
                   a = cons(b, a)
               
 			yyval[yyp-1] = b
@@ -628,56 +599,65 @@ func (p *yyParser) Init() {
 		},
 		/* 27 ListLoose */
 		func(yytext string, _ int) {
-			a := yyval[yyp-2]
 			b := yyval[yyp-1]
-			 yy = p.mkList(LIST, a) 
-			yyval[yyp-2] = a
+			a := yyval[yyp-2]
+			 yy = p.mkList(LIST, a)
+              yy.loose = true 
 			yyval[yyp-1] = b
+			yyval[yyp-2] = a
 		},
 		/* 28 ListItem */
 		func(yytext string, _ int) {
+			m := yyval[yyp-2]
 			a := yyval[yyp-1]
 			 a = cons(yy, a) 
+			yyval[yyp-2] = m
 			yyval[yyp-1] = a
 		},
 		/* 29 ListItem */
 		func(yytext string, _ int) {
+			m := yyval[yyp-2]
 			a := yyval[yyp-1]
 			 a = cons(yy, a) 
+			yyval[yyp-2] = m
 			yyval[yyp-1] = a
 		},
 		/* 30 ListItem */
 		func(yytext string, _ int) {
+			m := yyval[yyp-2]
 			a := yyval[yyp-1]
 			
-               raw := p.mkStringFromList(a, false)
-               raw.key = RAW
-               yy = p.mkElem(LISTITEM)
-               yy.children = raw
+               yy = p.mkListItem(a)
+               yy.contents.str = m.contents.str
             
+			yyval[yyp-2] = m
 			yyval[yyp-1] = a
 		},
 		/* 31 ListItemTight */
 		func(yytext string, _ int) {
+			m := yyval[yyp-2]
 			a := yyval[yyp-1]
 			 a = cons(yy, a) 
+			yyval[yyp-2] = m
 			yyval[yyp-1] = a
 		},
 		/* 32 ListItemTight */
 		func(yytext string, _ int) {
+			m := yyval[yyp-2]
 			a := yyval[yyp-1]
 			 a = cons(yy, a) 
+			yyval[yyp-2] = m
 			yyval[yyp-1] = a
 		},
 		/* 33 ListItemTight */
 		func(yytext string, _ int) {
+			m := yyval[yyp-2]
 			a := yyval[yyp-1]
 			
-               raw := p.mkStringFromList(a, false)
-               raw.key = RAW
-               yy = p.mkElem(LISTITEM)
-               yy.children = raw
+               yy = p.mkListItem(a)
+               yy.contents.str = m.contents.str
             
+			yyval[yyp-2] = m
 			yyval[yyp-1] = a
 		},
 		/* 34 ListBlock */
@@ -702,7 +682,7 @@ func (p *yyParser) Init() {
 		func(yytext string, _ int) {
 			a := yyval[yyp-1]
 			   if len(yytext) == 0 {
-                                   a = cons(p.mkString("\001"), a) // block separator
+                                   a = cons(p.mkElem(RAW), a) // block separator
                               } else {
                                    a = cons(p.mkString(yytext), a)
                               }
@@ -718,22 +698,17 @@ func (p *yyParser) Init() {
 		/* 39 ListContinuationBlock */
 		func(yytext string, _ int) {
 			a := yyval[yyp-1]
-			  yy = p.mkStringFromList(a, false) 
+			  yy = p.mkContinuation(a) 
 			yyval[yyp-1] = a
 		},
 		/* 40 OrderedList */
 		func(yytext string, _ int) {
-			 yy.key = ORDEREDLIST 
+			 yy.key = ORDEREDLIST
+                yy.contents.str = yy.children.contents.str 
 		},
 		/* 41 HtmlBlock */
 		func(yytext string, _ int) {
-			   if p.extension.FilterHTML {
-                    yy = p.mkList(LIST, nil)
-                } else {
-                    yy = p.mkString(yytext)
-                    yy.key = HTMLBLOCK
-                }
-            
+			 yy = p.rawHTML(yytext, HTMLBLOCK) 
 		},
 		/* 42 StyleBlock */
 		func(yytext string, _ int) {
@@ -806,12 +781,16 @@ func (p *yyParser) Init() {
 		},
 		/* 53 Entity */
 		func(yytext string, _ int) {
-			 yy = p.mkString(yytext); yy.key = HTML 
+			 yy = p.mkEntity(yytext) 
 		},
 		/* 54 NormalEndline */
 		func(yytext string, _ int) {
-			 yy = p.mkString("\n")
-                    yy.key = SPACE 
+			 if p.extension.HardWraps {
+                        yy = p.mkElem(LINEBREAK)
+                    } else {
+                        yy = p.mkString("\n")
+                        yy.key = SPACE
+                    } 
 		},
 		/* 55 TerminalEndline */
 		func(yytext string, _ int) {
@@ -913,6 +892,7 @@ func (p *yyParser) Init() {
 		func(yytext string, _ int) {
 				if yy.key == LINK {
 			yy.key = IMAGE
+			p.imageAlt(yy)
 		} else {
 			result := yy
 			yy.children = cons(p.mkString("!"), result.children)
@@ -925,7 +905,8 @@ func (p *yyParser) Init() {
 			b := yyval[yyp-2]
 			
                            if match, found := p.findReference(b.children); found {
-                               yy = p.mkLink(a.children, match.url, match.title);
+                               yy = p.mkLink(a.children, match.URL, match.Title);
+                               yy.contents.link.ref = plainText(b.children)
                                a = nil
                                b = nil
                            } else {
@@ -935,15 +916,16 @@ func (p *yyParser) Init() {
                                yy = result
                            }
                        
-			yyval[yyp-2] = b
 			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
 		/* 71 ReferenceLinkSingle */
 		func(yytext string, _ int) {
 			a := yyval[yyp-1]
 			
                            if match, found := p.findReference(a.children); found {
-                               yy = p.mkLink(a.children, match.url, match.title)
+                               yy = p.mkLink(a.children, match.URL, match.Title)
+                               yy.contents.link.ref = plainText(a.children)
                                a = nil
                            } else {
                                result := p.mkElem(LIST)
@@ -994,9 +976,9 @@ func (p *yyParser) Init() {
               t = nil
               l = nil
               yy.key = REFERENCE 
-			yyval[yyp-3] = s
 			yyval[yyp-1] = t
 			yyval[yyp-2] = l
+			yyval[yyp-3] = s
 		},
 		/* 78 Label */
 		func(yytext string, _ int) {
@@ -1012,8 +994,7 @@ func (p *yyParser) Init() {
 		},
 		/* 80 RefSrc */
 		func(yytext string, _ int) {
-			 yy = p.mkString(yytext)
-           yy.key = HTML 
+			 yy = p.mkString(yytext) 
 		},
 		/* 81 RefTitle */
 		func(yytext string, _ int) {
@@ -1031,7 +1012,7 @@ func (p *yyParser) Init() {
 		func(yytext string, _ int) {
 			a := yyval[yyp-2]
 			b := yyval[yyp-1]
-			 p.references = reverse(a) 
+			 p.indexReferences(reverse(a)) 
 			yyval[yyp-1] = b
 			yyval[yyp-2] = a
 		},
@@ -1041,13 +1022,7 @@ func (p *yyParser) Init() {
 		},
 		/* 85 RawHtml */
 		func(yytext string, _ int) {
-			   if p.extension.FilterHTML {
-                    yy = p.mkList(LIST, nil)
-                } else {
-                    yy = p.mkString(yytext)
-                    yy.key = HTML
-                }
-            
+			 yy = p.rawHTML(yytext, HTML) 
 		},
 		/* 86 StartList */
 		func(yytext string, _ int) {
@@ -1094,16 +1069,16 @@ func (p *yyParser) Init() {
 			b := yyval[yyp-1]
 			a := yyval[yyp-2]
 			 a = cons(b, a) 
-			yyval[yyp-2] = a
 			yyval[yyp-1] = b
+			yyval[yyp-2] = a
 		},
 		/* 95 DoubleQuoted */
 		func(yytext string, _ int) {
 			b := yyval[yyp-1]
 			a := yyval[yyp-2]
 			 yy = p.mkList(DOUBLEQUOTED, a) 
-			yyval[yyp-2] = a
 			yyval[yyp-1] = b
+			yyval[yyp-2] = a
 		},
 		/* 96 NoteReference */
 		func(yytext string, _ int) {
@@ -1113,6 +1088,10 @@ func (p *yyParser) Init() {
                         yy = p.mkElem(NOTE)
                         yy.children = match.children
                         yy.contents.str = ""
+                    } else if p.extension.UndefinedNotes {
+                        yy = p.mkString(ref.contents.str)
+                        yy.key = UNDEFNOTE
+                        p.undefNotes = append(p.undefNotes, ref.contents.str)
                     } else {
                         yy = p.mkString("[^"+ref.contents.str+"]")
                     }
@@ -1159,7 +1138,8 @@ func (p *yyParser) Init() {
 		func(yytext string, _ int) {
 			a := yyval[yyp-1]
 			 yy = p.mkList(NOTE, a)
-                  yy.contents.str = "" 
+                  yy.contents.str = ""
+                  p.inlineNotes = true 
 			yyval[yyp-1] = a
 		},
 		/* 103 Notes */
@@ -1172,9 +1152,9 @@ func (p *yyParser) Init() {
 		},
 		/* 104 Notes */
 		func(yytext string, _ int) {
-			b := yyval[yyp-2]
 			a := yyval[yyp-1]
-			 p.notes = reverse(a) 
+			b := yyval[yyp-2]
+			 a = cons(b, a) 
 			yyval[yyp-1] = a
 			yyval[yyp-2] = b
 		},
@@ -1251,60 +1231,71 @@ func (p *yyParser) Init() {
 		func(yytext string, _ int) {
 			b := yyval[yyp-1]
 			a := yyval[yyp-2]
-			 b = cons(yy, b) 
+			c := yyval[yyp-3]
+			 b = cons(yy, b); c = yy 
 			yyval[yyp-1] = b
 			yyval[yyp-2] = a
+			yyval[yyp-3] = c
 		},
 		/* 116 Table */
 		func(yytext string, _ int) {
 			b := yyval[yyp-1]
 			a := yyval[yyp-2]
+			c := yyval[yyp-3]
 			 yy.key = TABLEHEAD; a = cons(yy, a) 
-			yyval[yyp-2] = a
 			yyval[yyp-1] = b
+			yyval[yyp-2] = a
+			yyval[yyp-3] = c
 		},
 		/* 117 Table */
 		func(yytext string, _ int) {
 			b := yyval[yyp-1]
 			a := yyval[yyp-2]
+			c := yyval[yyp-3]
 			 append_list(yy, a) 
-			yyval[yyp-2] = a
 			yyval[yyp-1] = b
+			yyval[yyp-2] = a
+			yyval[yyp-3] = c
 		},
 		/* 118 Table */
 		func(yytext string, _ int) {
-			a := yyval[yyp-2]
 			b := yyval[yyp-1]
-			 a = cons(yy, a) 
-			yyval[yyp-2] = a
+			a := yyval[yyp-2]
+			c := yyval[yyp-3]
+			 a = yy 
 			yyval[yyp-1] = b
+			yyval[yyp-2] = a
+			yyval[yyp-3] = c
 		},
 		/* 119 Table */
 		func(yytext string, _ int) {
-			a := yyval[yyp-2]
 			b := yyval[yyp-1]
+			a := yyval[yyp-2]
+			c := yyval[yyp-3]
 			 a = cons(yy, a) 
 			yyval[yyp-1] = b
 			yyval[yyp-2] = a
+			yyval[yyp-3] = c
 		},
 		/* 120 Table */
 		func(yytext string, _ int) {
-			a := yyval[yyp-2]
 			b := yyval[yyp-1]
-			 b = cons(yy, b) 
-			yyval[yyp-2] = a
+			a := yyval[yyp-2]
+			c := yyval[yyp-3]
+			 a = cons(yy, a) 
 			yyval[yyp-1] = b
+			yyval[yyp-2] = a
+			yyval[yyp-3] = c
 		},
 		/* 121 Table */
 		func(yytext string, _ int) {
 			b := yyval[yyp-1]
 			a := yyval[yyp-2]
-			
-        if b != nil { append_list(b,a) }
-        yy = p.mkList(TABLE, a)
-    
+			c := yyval[yyp-3]
+			 b = cons(yy, b) 
 			yyval[yyp-1] = b
 			yyval[yyp-2] = a
+			yyval[yyp-3] = c
 		},
 		/* 122 TableBody */
 		func(yytext string, _ int) {
@@ -1346,13 +1337,13 @@ func (p *yyParser) Init() {
 		/* 128 FullCell */
 		func(yytext string, _ int) {
 			a := yyval[yyp-1]
-			 a = cons(yy, a) 
+			 a = p.mkElem(RAW); a.contents.str = yytext 
 			yyval[yyp-1] = a
 		},
 		/* 129 FullCell */
 		func(yytext string, _ int) {
 			a := yyval[yyp-1]
-			 yy = p.mkList(TABLECELL, a) 
+			 a = cons(yy, a) 
 			yyval[yyp-1] = a
 		},
 		/* 130 EmptyCell */
@@ -1425,8460 +1416,4719 @@ func (p *yyParser) Init() {
 			yyval[yyp-1] = b
 			yyval[yyp-2] = a
 		},
-
-		/* yyPush */
-		func(_ string, count int) {
-			yyp += count
-			if yyp >= len(yyval) {
-				s := make([]*element, cap(yyval)+256)
-				copy(s, yyval)
-				yyval = s
-			}
+		/* 141 DefListTight */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			 a = cons(yy, a) 
+			yyval[yyp-1] = a
 		},
-		/* yyPop */
-		func(_ string, count int) {
-			yyp -= count
+		/* 142 DefListTight */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			 yy = p.mkList(LIST, a) 
+			yyval[yyp-1] = a
 		},
-		/* yySet */
-		func(_ string, count int) {
-			yyval[yyp+count] = yy
+		/* 143 DefListLoose */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			
+                  markLoose(b)
+                  a = cons(b, a)
+              
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-	}
-	const (
-		yyPush = 141 + iota
-		yyPop
-		yySet
-	)
-
-	type thunk struct {
-		action uint16
-		begin, end int
-	}
-	var thunkPosition, begin, end int
-	thunks := make([]thunk, 32)
-	doarg := func(action uint16, arg int) {
-		if thunkPosition == len(thunks) {
-			newThunks := make([]thunk, 2*len(thunks))
-			copy(newThunks, thunks)
-			thunks = newThunks
-		}
-		t := &thunks[thunkPosition]
-		thunkPosition++
-		t.action = action
-		if arg != 0 {
-			t.begin = arg // use begin to store an argument
-		} else {
-			t.begin = begin
-		}
-		t.end = end
-	}
-	do := func(action uint16) {
-		doarg(action, 0)
-	}
-
-	p.ResetBuffer = func(s string) (old string) {
-		if position < len(p.Buffer) {
-			old = p.Buffer[position:]
-		}
-		p.Buffer = s
-		thunkPosition = 0
-		position = 0
-		p.Min = 0
-		p.Max = 0
-		end = 0
-		return
-	}
-
-	commit := func(thunkPosition0 int) bool {
-		if thunkPosition0 == 0 {
-			s := ""
-			for _, t := range thunks[:thunkPosition] {
-				b := t.begin
-				if b >= 0 && b <= t.end {
-					s = p.Buffer[b:t.end]
-				}
-				magic := b
-				actions[t.action](s, magic)
-			}
-			p.Min = position
-			thunkPosition = 0
-			return true
-		}
-		return false
-	}
-	matchDot := func() bool {
-		if position < len(p.Buffer) {
-			position++
-			return true
-		} else if position >= p.Max {
-			p.Max = position
-		}
-		return false
-	}
-
-	matchChar := func(c byte) bool {
-		if (position < len(p.Buffer)) && (p.Buffer[position] == c) {
-			position++
-			return true
-		} else if position >= p.Max {
-			p.Max = position
-		}
-		return false
-	}
-
-	peekChar := func(c byte) bool {
-		return position < len(p.Buffer) && p.Buffer[position] == c
-	}
-
-	matchString := func(s string) bool {
-		length := len(s)
-		next := position + length
-		if (next <= len(p.Buffer)) && p.Buffer[position] == s[0] && (p.Buffer[position:next] == s) {
-			position = next
-			return true
-		} else if position >= p.Max {
-			p.Max = position
-		}
-		return false
-	}
-
-	classes := [...][32]uint8{
-	3:	{0, 0, 0, 0, 50, 232, 255, 3, 254, 255, 255, 135, 254, 255, 255, 71, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-	1:	{0, 0, 0, 0, 10, 111, 0, 80, 0, 0, 0, 184, 1, 0, 0, 56, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-	0:	{0, 0, 0, 0, 0, 0, 255, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-	4:	{0, 0, 0, 0, 0, 0, 255, 3, 254, 255, 255, 7, 254, 255, 255, 7, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-	7:	{0, 0, 0, 0, 0, 0, 255, 3, 126, 0, 0, 0, 126, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-	2:	{0, 0, 0, 0, 0, 0, 0, 0, 254, 255, 255, 7, 254, 255, 255, 7, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-	5:	{0, 0, 0, 0, 0, 0, 255, 3, 254, 255, 255, 7, 254, 255, 255, 7, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-	6:	{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-	}
-	matchClass := func(class uint) bool {
-		if (position < len(p.Buffer)) &&
-			((classes[class][p.Buffer[position]>>3] & (1 << (p.Buffer[position] & 7))) != 0) {
-			position++
-			return true
-		} else if position >= p.Max {
-			p.Max = position
-		}
-		return false
-	}
-	peekClass := func(class uint) bool {
-		if (position < len(p.Buffer)) &&
-			((classes[class][p.Buffer[position]>>3] & (1 << (p.Buffer[position] & 7))) != 0) {
-			return true
-		}
-		return false
-	}
-
-
-	p.rules = [...]func() bool{
-
-		/* 0 Doc <- (StartList (Block { a = cons(yy, a) })* { p.tree = reverse(a) } commit) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l0
-			}
-			doarg(yySet, -1)
-		l1:
-			{
-				position2 := position
-				if !p.rules[ruleBlock]() {
-					goto l2
-				}
-				do(0)
-				goto l1
-			l2:
-				position = position2
-			}
-			do(1)
-			if !(commit(thunkPosition0)) {
-				goto l0
-			}
-			doarg(yyPop, 1)
-			return true
-		l0:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 144 DefListLoose */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 yy = p.mkList(LIST, a)
+              yy.loose = true 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-		/* 1 Docblock <- (Block { p.tree = yy } commit) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			if !p.rules[ruleBlock]() {
-				goto l3
-			}
-			do(2)
-			if !(commit(thunkPosition0)) {
-				goto l3
-			}
-			return true
-		l3:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 145 DefItem */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			 a = cons(yy, a) 
+			yyval[yyp-1] = a
 		},
-		/* 2 Block <- (BlankLine* (BlockQuote / Verbatim / Note / Reference / HorizontalRule / Heading / DefinitionList / OrderedList / BulletList / HtmlBlock / StyleBlock / (&{p.extension.Table} Table) / Para / Plain)) */
-		func() bool {
-			position0 := position
-		l5:
-			if !p.rules[ruleBlankLine]() {
-				goto l6
-			}
-			goto l5
-		l6:
-			if !p.rules[ruleBlockQuote]() {
-				goto l8
-			}
-			goto l7
-		l8:
-			if !p.rules[ruleVerbatim]() {
-				goto l9
-			}
-			goto l7
-		l9:
-			if !p.rules[ruleNote]() {
-				goto l10
-			}
-			goto l7
-		l10:
-			if !p.rules[ruleReference]() {
-				goto l11
-			}
-			goto l7
-		l11:
-			if !p.rules[ruleHorizontalRule]() {
-				goto l12
-			}
-			goto l7
-		l12:
-			if !p.rules[ruleHeading]() {
-				goto l13
-			}
-			goto l7
-		l13:
-			if !p.rules[ruleDefinitionList]() {
-				goto l14
-			}
-			goto l7
-		l14:
-			if !p.rules[ruleOrderedList]() {
-				goto l15
-			}
-			goto l7
-		l15:
-			if !p.rules[ruleBulletList]() {
-				goto l16
-			}
-			goto l7
-		l16:
-			if !p.rules[ruleHtmlBlock]() {
-				goto l17
-			}
-			goto l7
-		l17:
-			if !p.rules[ruleStyleBlock]() {
-				goto l18
-			}
-			goto l7
-		l18:
-			if !(p.extension.Table) {
-				goto l19
-			}
-			if !p.rules[ruleTable]() {
-				goto l19
-			}
-			goto l7
-		l19:
-			if !p.rules[rulePara]() {
-				goto l20
-			}
-			goto l7
-		l20:
-			if !p.rules[rulePlain]() {
-				goto l4
-			}
-		l7:
-			return true
-		l4:
-			position = position0
-			return false
+		/* 146 DefItem */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			 a = cons(yy, a) 
+			yyval[yyp-1] = a
 		},
-		/* 3 Para <- (NonindentSpace Inlines BlankLine+ { yy = a; yy.key = PARA }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleNonindentSpace]() {
-				goto l21
-			}
-			if !p.rules[ruleInlines]() {
-				goto l21
-			}
-			doarg(yySet, -1)
-			if !p.rules[ruleBlankLine]() {
-				goto l21
-			}
-		l22:
-			if !p.rules[ruleBlankLine]() {
-				goto l23
-			}
-			goto l22
-		l23:
-			do(3)
-			doarg(yyPop, 1)
-			return true
-		l21:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 147 DefItem */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			
+               yy = p.mkListItem(a)
+            
+			yyval[yyp-1] = a
 		},
-		/* 4 Plain <- (Inlines { yy = a; yy.key = PLAIN }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleInlines]() {
-				goto l24
-			}
-			doarg(yySet, -1)
-			do(4)
-			doarg(yyPop, 1)
-			return true
-		l24:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 148 DefItemTight */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			 a = cons(yy, a) 
+			yyval[yyp-1] = a
 		},
-		/* 5 AtxInline <- (!Newline !(Sp? '#'* Sp Newline) Inline) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleNewline]() {
-				goto l26
-			}
-			goto l25
-		l26:
-			{
-				position27 := position
-				if !p.rules[ruleSp]() {
-					goto l28
-				}
-			l28:
-			l30:
-				if !matchChar('#') {
-					goto l31
-				}
-				goto l30
-			l31:
-				if !p.rules[ruleSp]() {
-					goto l27
-				}
-				if !p.rules[ruleNewline]() {
-					goto l27
-				}
-				goto l25
-			l27:
-				position = position27
-			}
-			if !p.rules[ruleInline]() {
-				goto l25
-			}
-			return true
-		l25:
-			position = position0
-			return false
+		/* 149 DefItemTight */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			 a = cons(yy, a) 
+			yyval[yyp-1] = a
 		},
-		/* 6 AtxStart <- (&'#' < ('######' / '#####' / '####' / '###' / '##' / '#') > { yy = p.mkElem(H1 + (len(yytext) - 1)) }) */
-		func() bool {
-			position0 := position
-			if !peekChar('#') {
-				goto l32
-			}
-			begin = position
-			if !matchString("######") {
-				goto l34
-			}
-			goto l33
-		l34:
-			if !matchString("#####") {
-				goto l35
-			}
-			goto l33
-		l35:
-			if !matchString("####") {
-				goto l36
-			}
-			goto l33
-		l36:
-			if !matchString("###") {
-				goto l37
-			}
-			goto l33
-		l37:
-			if !matchString("##") {
-				goto l38
-			}
-			goto l33
-		l38:
-			if !matchChar('#') {
-				goto l32
-			}
-		l33:
-			end = position
-			do(5)
-			return true
-		l32:
-			position = position0
-			return false
+		/* 150 DefItemTight */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			
+               yy = p.mkListItem(a)
+            
+			yyval[yyp-1] = a
 		},
-		/* 7 AtxHeading <- (AtxStart Sp? StartList (AtxInline { a = cons(yy, a) })+ (Sp? '#'* Sp)? Newline { yy = p.mkList(s.key, a)
-              s = nil }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !p.rules[ruleAtxStart]() {
-				goto l39
-			}
-			doarg(yySet, -1)
-			if !p.rules[ruleSp]() {
-				goto l40
-			}
-		l40:
-			if !p.rules[ruleStartList]() {
-				goto l39
-			}
-			doarg(yySet, -2)
-			if !p.rules[ruleAtxInline]() {
-				goto l39
-			}
-			do(6)
-		l42:
-			{
-				position43 := position
-				if !p.rules[ruleAtxInline]() {
-					goto l43
-				}
-				do(6)
-				goto l42
-			l43:
-				position = position43
-			}
-			{
-				position44 := position
-				if !p.rules[ruleSp]() {
-					goto l46
-				}
-			l46:
-			l48:
-				if !matchChar('#') {
-					goto l49
-				}
-				goto l48
-			l49:
-				if !p.rules[ruleSp]() {
-					goto l44
-				}
-				goto l45
-			l44:
-				position = position44
-			}
-		l45:
-			if !p.rules[ruleNewline]() {
-				goto l39
-			}
-			do(7)
-			doarg(yyPop, 2)
-			return true
-		l39:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 151 Mark */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 a = cons(b, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-		/* 8 SetextHeading <- (SetextHeading1 / SetextHeading2) */
-		func() bool {
-			if !p.rules[ruleSetextHeading1]() {
-				goto l52
-			}
-			goto l51
-		l52:
-			if !p.rules[ruleSetextHeading2]() {
-				goto l50
-			}
-		l51:
-			return true
-		l50:
-			return false
+		/* 152 Mark */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 yy = p.mkList(MARK, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-		/* 9 SetextBottom1 <- ('='+ Newline) */
-		func() bool {
-			position0 := position
-			if !matchChar('=') {
-				goto l53
-			}
-		l54:
-			if !matchChar('=') {
-				goto l55
-			}
-			goto l54
-		l55:
-			if !p.rules[ruleNewline]() {
-				goto l53
-			}
-			return true
-		l53:
-			position = position0
-			return false
+		/* 153 CriticIns */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 a = cons(b, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-		/* 10 SetextBottom2 <- ('-'+ Newline) */
-		func() bool {
-			position0 := position
-			if !matchChar('-') {
-				goto l56
-			}
-		l57:
-			if !matchChar('-') {
-				goto l58
-			}
-			goto l57
-		l58:
-			if !p.rules[ruleNewline]() {
-				goto l56
-			}
-			return true
-		l56:
-			position = position0
-			return false
+		/* 154 CriticIns */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 yy = p.mkList(CRITICINS, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-		/* 11 SetextHeading1 <- (&(RawLine SetextBottom1) StartList (!Endline Inline { a = cons(yy, a) })+ Sp? Newline SetextBottom1 { yy = p.mkList(H1, a) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			{
-				position60 := position
-				if !p.rules[ruleRawLine]() {
-					goto l59
-				}
-				if !p.rules[ruleSetextBottom1]() {
-					goto l59
-				}
-				position = position60
-			}
-			if !p.rules[ruleStartList]() {
-				goto l59
-			}
-			doarg(yySet, -1)
-			if !p.rules[ruleEndline]() {
-				goto l63
-			}
-			goto l59
-		l63:
-			if !p.rules[ruleInline]() {
-				goto l59
-			}
-			do(8)
-		l61:
-			{
-				position62 := position
-				if !p.rules[ruleEndline]() {
-					goto l64
-				}
-				goto l62
-			l64:
-				if !p.rules[ruleInline]() {
-					goto l62
-				}
-				do(8)
-				goto l61
-			l62:
-				position = position62
-			}
-			if !p.rules[ruleSp]() {
-				goto l65
-			}
-		l65:
-			if !p.rules[ruleNewline]() {
-				goto l59
-			}
-			if !p.rules[ruleSetextBottom1]() {
-				goto l59
-			}
-			do(9)
-			doarg(yyPop, 1)
-			return true
-		l59:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 155 CriticDel */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 a = cons(b, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-		/* 12 SetextHeading2 <- (&(RawLine SetextBottom2) StartList (!Endline Inline { a = cons(yy, a) })+ Sp? Newline SetextBottom2 { yy = p.mkList(H2, a) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			{
-				position68 := position
-				if !p.rules[ruleRawLine]() {
-					goto l67
-				}
-				if !p.rules[ruleSetextBottom2]() {
-					goto l67
-				}
-				position = position68
-			}
-			if !p.rules[ruleStartList]() {
-				goto l67
-			}
-			doarg(yySet, -1)
-			if !p.rules[ruleEndline]() {
-				goto l71
-			}
-			goto l67
-		l71:
-			if !p.rules[ruleInline]() {
-				goto l67
-			}
-			do(10)
-		l69:
-			{
-				position70 := position
-				if !p.rules[ruleEndline]() {
-					goto l72
-				}
-				goto l70
-			l72:
-				if !p.rules[ruleInline]() {
-					goto l70
-				}
-				do(10)
-				goto l69
-			l70:
-				position = position70
-			}
-			if !p.rules[ruleSp]() {
-				goto l73
-			}
-		l73:
-			if !p.rules[ruleNewline]() {
-				goto l67
-			}
-			if !p.rules[ruleSetextBottom2]() {
-				goto l67
-			}
-			do(11)
-			doarg(yyPop, 1)
-			return true
-		l67:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 156 CriticDel */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 yy = p.mkList(CRITICDEL, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-		/* 13 Heading <- (SetextHeading / AtxHeading) */
-		func() bool {
-			if !p.rules[ruleSetextHeading]() {
-				goto l77
-			}
-			goto l76
-		l77:
-			if !p.rules[ruleAtxHeading]() {
-				goto l75
-			}
-		l76:
-			return true
-		l75:
-			return false
+		/* 157 CriticSub */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			c := yyval[yyp-3]
+			 a = cons(b, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
+			yyval[yyp-3] = c
 		},
-		/* 14 BlockQuote <- (BlockQuoteRaw {  yy = p.mkElem(BLOCKQUOTE)
-                yy.children = a
-             }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleBlockQuoteRaw]() {
-				goto l78
-			}
-			doarg(yySet, -1)
-			do(12)
-			doarg(yyPop, 1)
-			return true
-		l78:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 158 CriticSub */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			c := yyval[yyp-3]
+			 c = cons(b, c) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
+			yyval[yyp-3] = c
 		},
-		/* 15 BlockQuoteRaw <- (StartList ('>' ' '? Line { a = cons(yy, a) } (!'>' !BlankLine Line { a = cons(yy, a) })* (BlankLine { a = cons(p.mkString("\n"), a) })*)+ {   yy = p.mkStringFromList(a, true)
-                     yy.key = RAW
-                 }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l79
-			}
-			doarg(yySet, -1)
-			if !matchChar('>') {
-				goto l79
-			}
-			matchChar(' ')
-			if !p.rules[ruleLine]() {
-				goto l79
-			}
-			do(13)
-		l82:
-			{
-				position83, thunkPosition83 := position, thunkPosition
-				if peekChar('>') {
-					goto l83
-				}
-				if !p.rules[ruleBlankLine]() {
-					goto l84
-				}
-				goto l83
-			l84:
-				if !p.rules[ruleLine]() {
-					goto l83
-				}
-				do(14)
-				goto l82
-			l83:
-				position, thunkPosition = position83, thunkPosition83
-			}
-		l85:
-			{
-				position86 := position
-				if !p.rules[ruleBlankLine]() {
-					goto l86
-				}
-				do(15)
-				goto l85
-			l86:
-				position = position86
-			}
-		l80:
-			{
-				position81, thunkPosition81 := position, thunkPosition
-				if !matchChar('>') {
-					goto l81
-				}
-				matchChar(' ')
-				if !p.rules[ruleLine]() {
-					goto l81
-				}
-				do(13)
-			l87:
-				{
-					position88, thunkPosition88 := position, thunkPosition
-					if peekChar('>') {
-						goto l88
-					}
-					if !p.rules[ruleBlankLine]() {
-						goto l89
-					}
-					goto l88
-				l89:
-					if !p.rules[ruleLine]() {
-						goto l88
-					}
-					do(14)
-					goto l87
-				l88:
-					position, thunkPosition = position88, thunkPosition88
-				}
-			l90:
-				{
-					position91 := position
-					if !p.rules[ruleBlankLine]() {
-						goto l91
-					}
-					do(15)
-					goto l90
-				l91:
-					position = position91
-				}
-				goto l80
-			l81:
-				position, thunkPosition = position81, thunkPosition81
-			}
-			do(16)
-			doarg(yyPop, 1)
-			return true
-		l79:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 159 CriticSub */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			c := yyval[yyp-3]
+			 yy = p.mkElem(CRITICSUB)
+              yy.children = cons(p.mkList(CRITICDEL, a), p.mkList(CRITICINS, c)) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
+			yyval[yyp-3] = c
 		},
-		/* 16 NonblankIndentedLine <- (!BlankLine IndentedLine) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleBlankLine]() {
-				goto l93
-			}
-			goto l92
-		l93:
-			if !p.rules[ruleIndentedLine]() {
-				goto l92
-			}
-			return true
-		l92:
-			position = position0
-			return false
+		/* 160 CriticHighlight */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 a = cons(b, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-		/* 17 VerbatimChunk <- (StartList (BlankLine { a = cons(p.mkString("\n"), a) })* (NonblankIndentedLine { a = cons(yy, a) })+ { yy = p.mkStringFromList(a, false) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l94
-			}
-			doarg(yySet, -1)
-		l95:
-			{
-				position96 := position
-				if !p.rules[ruleBlankLine]() {
-					goto l96
-				}
-				do(17)
-				goto l95
-			l96:
-				position = position96
-			}
-			if !p.rules[ruleNonblankIndentedLine]() {
-				goto l94
-			}
-			do(18)
-		l97:
-			{
-				position98 := position
-				if !p.rules[ruleNonblankIndentedLine]() {
-					goto l98
-				}
-				do(18)
-				goto l97
-			l98:
-				position = position98
-			}
-			do(19)
-			doarg(yyPop, 1)
-			return true
-		l94:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 161 CriticHighlight */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 yy = p.mkList(CRITICHIGHLIGHT, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
 		},
-		/* 18 Verbatim <- (StartList (VerbatimChunk { a = cons(yy, a) })+ { yy = p.mkStringFromList(a, false)
-                 yy.key = VERBATIM }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l99
-			}
-			doarg(yySet, -1)
-			if !p.rules[ruleVerbatimChunk]() {
-				goto l99
-			}
-			do(20)
-		l100:
-			{
-				position101, thunkPosition101 := position, thunkPosition
-				if !p.rules[ruleVerbatimChunk]() {
-					goto l101
-				}
-				do(20)
-				goto l100
-			l101:
-				position, thunkPosition = position101, thunkPosition101
-			}
-			do(21)
-			doarg(yyPop, 1)
-			return true
-		l99:
-			position, thunkPosition = position0, thunkPosition0
-			return false
+		/* 162 CriticComment */
+		func(yytext string, _ int) {
+			 yy = p.mkString(yytext)
+              yy.key = CRITICCOMMENT 
 		},
-		/* 19 HorizontalRule <- (NonindentSpace ((&[_] ('_' Sp '_' Sp '_' (Sp '_')*)) | (&[\-] ('-' Sp '-' Sp '-' (Sp '-')*)) | (&[*] ('*' Sp '*' Sp '*' (Sp '*')*))) Sp Newline BlankLine+ { yy = p.mkElem(HRULE) }) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleNonindentSpace]() {
-				goto l102
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l102
-				}
-				switch p.Buffer[position] {
-				case '_':
-					position++ // matchChar
-					if !p.rules[ruleSp]() {
-						goto l102
-					}
-					if !matchChar('_') {
-						goto l102
-					}
-					if !p.rules[ruleSp]() {
-						goto l102
-					}
-					if !matchChar('_') {
-						goto l102
-					}
-				l104:
-					{
-						position105 := position
-						if !p.rules[ruleSp]() {
-							goto l105
-						}
-						if !matchChar('_') {
-							goto l105
-						}
-						goto l104
-					l105:
-						position = position105
-					}
-					break
-				case '-':
-					position++ // matchChar
-					if !p.rules[ruleSp]() {
-						goto l102
-					}
-					if !matchChar('-') {
-						goto l102
-					}
-					if !p.rules[ruleSp]() {
-						goto l102
-					}
-					if !matchChar('-') {
-						goto l102
-					}
-				l106:
-					{
-						position107 := position
-						if !p.rules[ruleSp]() {
-							goto l107
-						}
-						if !matchChar('-') {
-							goto l107
-						}
-						goto l106
-					l107:
-						position = position107
-					}
-					break
-				case '*':
-					position++ // matchChar
-					if !p.rules[ruleSp]() {
-						goto l102
-					}
-					if !matchChar('*') {
-						goto l102
-					}
-					if !p.rules[ruleSp]() {
-						goto l102
-					}
-					if !matchChar('*') {
-						goto l102
-					}
-				l108:
-					{
-						position109 := position
-						if !p.rules[ruleSp]() {
-							goto l109
-						}
-						if !matchChar('*') {
-							goto l109
-						}
-						goto l108
-					l109:
-						position = position109
-					}
-					break
-				default:
-					goto l102
-				}
-			}
-			if !p.rules[ruleSp]() {
-				goto l102
-			}
-			if !p.rules[ruleNewline]() {
-				goto l102
-			}
-			if !p.rules[ruleBlankLine]() {
-				goto l102
-			}
-		l110:
-			if !p.rules[ruleBlankLine]() {
-				goto l111
-			}
-			goto l110
-		l111:
-			do(22)
-			return true
-		l102:
-			position = position0
-			return false
+		/* 163 ExtInline */
+		func(yytext string, _ int) {
+			 yy = p.mkInlineExtension(yytext) 
 		},
-		/* 20 Bullet <- (!HorizontalRule NonindentSpace ((&[\-] '-') | (&[*] '*') | (&[+] '+')) Spacechar+) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			if !p.rules[ruleHorizontalRule]() {
-				goto l113
-			}
-			goto l112
-		l113:
-			if !p.rules[ruleNonindentSpace]() {
-				goto l112
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l112
-				}
-				switch p.Buffer[position] {
-				case '-':
-					position++ // matchChar
-					break
-				case '*':
-					position++ // matchChar
-					break
-				case '+':
-					position++ // matchChar
-					break
-				default:
-					goto l112
-				}
-			}
-			if !p.rules[ruleSpacechar]() {
-				goto l112
+		/* 164 ExtBlock */
+		func(yytext string, _ int) {
+			 yy = p.mkBlockExtension(yytext) 
+		},
+		/* 165 Container */
+		func(yytext string, _ int) {
+			 yy = p.mkContainer(yytext) 
+		},
+		/* 166 LineTail */
+		func(yytext string, _ int) {
+			 yy = p.mkString(yytext) 
+		},
+		/* 167 ListMarker */
+		func(yytext string, _ int) {
+			 yy = p.mkString(strings.TrimSpace(yytext)) 
+		},
+		/* 168 Prime */
+		func(yytext string, _ int) {
+			 yy = p.mkString(primes[yytext]) 
+		},
+		/* 169 TableRef */
+		func(yytext string, _ int) {
+			 yy = p.tableRef(yytext) 
+		},
+		/* 170 Hashtag */
+		func(yytext string, _ int) {
+			 yy = p.hashtag(yytext) 
+		},
+		/* 171 Placeholder */
+		func(yytext string, _ int) {
+			 yy = p.mkElem(PLACEHOLDER); yy.contents.str = yytext 
+		},
+		/* 172 Notes */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 a = cons(b, a) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
+		},
+		/* 173 Notes */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			b := yyval[yyp-2]
+			 p.indexNotes(reverse(a)) 
+			yyval[yyp-1] = a
+			yyval[yyp-2] = b
+		},
+		/* 174 Citation */
+		func(yytext string, _ int) {
+			 yy = p.mkElem(CITATION); yy.contents.str = yytext 
+		},
+		/* 175 Comment */
+		func(yytext string, _ int) {
+			 yy = p.mkElem(COMMENT); yy.contents.str = yytext 
+		},
+		/* 176 CommentBlock */
+		func(yytext string, _ int) {
+			 yy = p.mkElem(COMMENTBLOCK); yy.contents.str = yytext 
+		},
+		/* 177 Table */
+		func(yytext string, _ int) {
+			b := yyval[yyp-1]
+			a := yyval[yyp-2]
+			c := yyval[yyp-3]
+			
+        if b != nil { append_list(b,a) }
+        yy = p.mkList(TABLE, a)
+        p.normalizeTable(yy, c != nil)
+    
+			yyval[yyp-1] = b
+			yyval[yyp-2] = a
+			yyval[yyp-3] = c
+		},
+		/* 178 GridTable */
+		func(yytext string, _ int) {
+			 yy = p.gridTable(yytext) 
+		},
+		/* 179 FullCell */
+		func(yytext string, _ int) {
+			a := yyval[yyp-1]
+			 yy = p.mkList(TABLECELL, a) 
+			yyval[yyp-1] = a
+		},
+		/* 180 AngleSource */
+		func(yytext string, _ int) {
+			 yy = p.mkString(strings.ReplaceAll(yytext, " ", "%20")) 
+		},
+		/* 181 RefSrc */
+		func(yytext string, _ int) {
+			 yy.key = HTML 
+		},
+
+		/* yyPush */
+		func(_ string, count int) {
+			yyp += count
+			if yyp >= len(yyval) {
+				s := make([]*element, cap(yyval)+256)
+				copy(s, yyval)
+				yyval = s
 			}
-		l115:
-			if !p.rules[ruleSpacechar]() {
-				goto l116
+		},
+		/* yyPop */
+		func(_ string, count int) {
+			yyp -= count
+		},
+		/* yySet */
+		func(_ string, count int) {
+			yyval[yyp+count] = yy
+		},
+	}
+	const (
+		yyPush = 182 + iota
+		yyPop
+		yySet
+	)
+
+	type thunk struct {
+		action uint16
+		begin, end int
+	}
+	var thunkPosition, begin, end int
+	thunks := make([]thunk, 32)
+	doarg := func(action uint16, arg int) {
+		if thunkPosition == len(thunks) {
+			newThunks := make([]thunk, 2*len(thunks))
+			copy(newThunks, thunks)
+			thunks = newThunks
+		}
+		t := &thunks[thunkPosition]
+		thunkPosition++
+		t.action = action
+		if arg != 0 {
+			t.begin = arg // use begin to store an argument
+		} else {
+			t.begin = begin
+		}
+		t.end = end
+	}
+	do := func(action uint16) {
+		doarg(action, 0)
+	}
+
+	p.ResetBuffer = func(s string) (old string) {
+		if position < len(p.Buffer) {
+			old = p.Buffer[position:]
+		}
+		p.Buffer = s
+		thunkPosition = 0
+		position = 0
+		p.Min = 0
+		p.Max = 0
+		end = 0
+		return
+	}
+
+	commit := func(thunkPosition0 int) bool {
+		if thunkPosition0 == 0 {
+			s := ""
+			for _, t := range thunks[:thunkPosition] {
+				b := t.begin
+				if b >= 0 && b <= t.end {
+					s = p.Buffer[b:t.end]
+				}
+				magic := b
+				actions[t.action](s, magic)
 			}
-			goto l115
-		l116:
+			p.Min = position
+			thunkPosition = 0
 			return true
-		l112:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 21 BulletList <- (&Bullet (ListTight / ListLoose) { yy.key = BULLETLIST }) */
+		}
+		return false
+	}
+	matchDot := func() bool {
+		if position < len(p.Buffer) {
+			position++
+			return true
+		} else if position >= p.Max {
+			p.Max = position
+		}
+		return false
+	}
+
+	matchChar := func(c byte) bool {
+		if (position < len(p.Buffer)) && (p.Buffer[position] == c) {
+			position++
+			return true
+		} else if position >= p.Max {
+			p.Max = position
+		}
+		return false
+	}
+
+	peekChar := func(c byte) bool {
+		return position < len(p.Buffer) && p.Buffer[position] == c
+	}
+
+	matchString := func(s string) bool {
+		length := len(s)
+		next := position + length
+		if (next <= len(p.Buffer)) && p.Buffer[position] == s[0] && (p.Buffer[position:next] == s) {
+			position = next
+			return true
+		} else if position >= p.Max {
+			p.Max = position
+		}
+		return false
+	}
+
+	classes := [...][32]uint8{
+	3:	{0, 0, 0, 0, 50, 232, 255, 3, 254, 255, 255, 135, 254, 255, 255, 71, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	1:	{0, 0, 0, 0, 10, 111, 0, 80, 0, 0, 0, 184, 1, 0, 0, 56, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	0:	{0, 0, 0, 0, 0, 0, 255, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	4:	{0, 0, 0, 0, 0, 0, 255, 3, 254, 255, 255, 7, 254, 255, 255, 7, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	7:	{0, 0, 0, 0, 0, 0, 255, 3, 126, 0, 0, 0, 126, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	2:	{0, 0, 0, 0, 0, 0, 0, 0, 254, 255, 255, 7, 254, 255, 255, 7, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	5:	{0, 0, 0, 0, 0, 0, 255, 3, 254, 255, 255, 7, 254, 255, 255, 7, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	6:	{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	8:	{0, 0, 0, 0, 0, 160, 0, 0, 0, 0, 0, 128, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	9:	{0, 0, 0, 0, 0, 0, 0, 0, 254, 255, 255, 135, 254, 255, 255, 7, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	10:	{0, 0, 0, 0, 0, 96, 255, 3, 254, 255, 255, 135, 254, 255, 255, 7, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	}
+	matchClass := func(class uint) bool {
+		if (position < len(p.Buffer)) &&
+			((classes[class][p.Buffer[position]>>3] & (1 << (p.Buffer[position] & 7))) != 0) {
+			position++
+			return true
+		} else if position >= p.Max {
+			p.Max = position
+		}
+		return false
+	}
+	peekClass := func(class uint) bool {
+		if (position < len(p.Buffer)) &&
+			((classes[class][p.Buffer[position]>>3] & (1 << (p.Buffer[position] & 7))) != 0) {
+			return true
+		}
+		return false
+	}
+
+
+	p.rules = [...]func() bool{
+
+		/* 0 Doc <- (StartList (Block { a = cons(yy, a) })* { p.tree = reverse(a) } commit) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l0
+			}
+			doarg(yySet, -1)
+		l1:
 			{
-				position118 := position
-				if !p.rules[ruleBullet]() {
-					goto l117
+				position2 := position
+				if !p.rules[ruleBlock]() {
+					goto l2
 				}
-				position = position118
-			}
-			if !p.rules[ruleListTight]() {
-				goto l120
+				do(0)
+				goto l1
+			l2:
+				position = position2
 			}
-			goto l119
-		l120:
-			if !p.rules[ruleListLoose]() {
-				goto l117
+			do(1)
+			if !(commit(thunkPosition0)) {
+				goto l0
 			}
-		l119:
-			do(23)
+			doarg(yyPop, 1)
 			return true
-		l117:
+		l0:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 22 ListTight <- (StartList (ListItemTight { a = cons(yy, a) })+ BlankLine* !((&[:~] DefMarker) | (&[*+\-] Bullet) | (&[0-9] Enumerator)) { yy = p.mkList(LIST, a) }) */
+		/* 1 Docblock <- (Block { p.tree = yy } commit) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l121
-			}
-			doarg(yySet, -1)
-			if !p.rules[ruleListItemTight]() {
-				goto l121
-			}
-			do(24)
-		l122:
-			{
-				position123, thunkPosition123 := position, thunkPosition
-				if !p.rules[ruleListItemTight]() {
-					goto l123
-				}
-				do(24)
-				goto l122
-			l123:
-				position, thunkPosition = position123, thunkPosition123
-			}
-		l124:
-			if !p.rules[ruleBlankLine]() {
-				goto l125
+			if !p.rules[ruleBlock]() {
+				goto l3
 			}
-			goto l124
-		l125:
-			{
-				if position == len(p.Buffer) {
-					goto l126
-				}
-				switch p.Buffer[position] {
-				case ':', '~':
-					if !p.rules[ruleDefMarker]() {
-						goto l126
-					}
-					break
-				case '*', '+', '-':
-					if !p.rules[ruleBullet]() {
-						goto l126
-					}
-					break
-				default:
-					if !p.rules[ruleEnumerator]() {
-						goto l126
-					}
-				}
+			do(2)
+			if !(commit(thunkPosition0)) {
+				goto l3
 			}
-			goto l121
-		l126:
-			do(25)
-			doarg(yyPop, 1)
 			return true
-		l121:
+		l3:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 23 ListLoose <- (StartList (ListItem BlankLine* {
-                  li := b.children
-                  li.contents.str += "\n\n"
-                  a = cons(b, a)
-              })+ { yy = p.mkList(LIST, a) }) */
+		/* 2 Block <- (BlankLine* (ExtBlock / Container / BlockQuote / Verbatim / Note / Reference / HorizontalRule / Heading / DefinitionList / OrderedList / BulletList / CommentBlock / HtmlBlock / StyleBlock / GridTable / (&{p.extension.Table} Table) / Para / Plain)) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !p.rules[ruleStartList]() {
-				goto l128
-			}
-			doarg(yySet, -2)
-			if !p.rules[ruleListItem]() {
-				goto l128
-			}
-			doarg(yySet, -1)
-		l131:
+		l5:
 			if !p.rules[ruleBlankLine]() {
-				goto l132
+				goto l6
 			}
-			goto l131
-		l132:
-			do(26)
-		l129:
+			goto l5
+		l6:
 			{
-				position130, thunkPosition130 := position, thunkPosition
-				if !p.rules[ruleListItem]() {
-					goto l130
-				}
-				doarg(yySet, -1)
-			l133:
-				if !p.rules[ruleBlankLine]() {
-					goto l134
+				position9, thunkPosition9 := position, thunkPosition
+				if !p.rules[ruleExtBlock]() {
+					goto l10
+				}
+				goto l7
+			l10:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleContainer]() {
+					goto l11
+				}
+				goto l7
+			l11:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleBlockQuote]() {
+					goto l12
+				}
+				goto l7
+			l12:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleVerbatim]() {
+					goto l13
+				}
+				goto l7
+			l13:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleNote]() {
+					goto l14
+				}
+				goto l7
+			l14:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleReference]() {
+					goto l15
+				}
+				goto l7
+			l15:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleHorizontalRule]() {
+					goto l16
+				}
+				goto l7
+			l16:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleHeading]() {
+					goto l17
+				}
+				goto l7
+			l17:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleDefinitionList]() {
+					goto l18
+				}
+				goto l7
+			l18:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleOrderedList]() {
+					goto l19
+				}
+				goto l7
+			l19:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleBulletList]() {
+					goto l20
+				}
+				goto l7
+			l20:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleCommentBlock]() {
+					goto l1438
+				}
+				goto l7
+			l1438:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleHtmlBlock]() {
+					goto l1443
+				}
+				goto l7
+			l1443:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleStyleBlock]() {
+					goto l1528
+				}
+				goto l7
+			l1528:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[ruleGridTable]() {
+					goto l1529
+				}
+				goto l7
+			l1529:
+				position, thunkPosition = position9, thunkPosition9
+				if !(p.extension.Table) {
+					goto l1566
+				}
+				if !p.rules[ruleTable]() {
+					goto l1566
+				}
+				goto l7
+			l1566:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[rulePara]() {
+					goto l1567
+				}
+				goto l7
+			l1567:
+				position, thunkPosition = position9, thunkPosition9
+				if !p.rules[rulePlain]() {
+					goto l4
 				}
-				goto l133
-			l134:
-				do(26)
-				goto l129
-			l130:
-				position, thunkPosition = position130, thunkPosition130
 			}
-			do(27)
-			doarg(yyPop, 2)
+		l7:
 			return true
-		l128:
+		l4:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 24 ListItem <- (((&[:~] DefMarker) | (&[*+\-] Bullet) | (&[0-9] Enumerator)) StartList ListBlock { a = cons(yy, a) } (ListContinuationBlock { a = cons(yy, a) })* {
-               raw := p.mkStringFromList(a, false)
-               raw.key = RAW
-               yy = p.mkElem(LISTITEM)
-               yy.children = raw
-            }) */
+		/* 3 Para <- (NonindentSpace Inlines BlankLines { yy = a; yy.key = PARA
+              if p.extension.Figures { yy = p.figure(yy) } }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
 			doarg(yyPush, 1)
-			{
-				if position == len(p.Buffer) {
-					goto l135
-				}
-				switch p.Buffer[position] {
-				case ':', '~':
-					if !p.rules[ruleDefMarker]() {
-						goto l135
-					}
-					break
-				case '*', '+', '-':
-					if !p.rules[ruleBullet]() {
-						goto l135
-					}
-					break
-				default:
-					if !p.rules[ruleEnumerator]() {
-						goto l135
-					}
-				}
+			if !p.rules[ruleNonindentSpace]() {
+				goto l21
 			}
-			if !p.rules[ruleStartList]() {
-				goto l135
+			if !p.rules[ruleInlines]() {
+				goto l21
 			}
 			doarg(yySet, -1)
-			if !p.rules[ruleListBlock]() {
-				goto l135
-			}
-			do(28)
-		l137:
-			{
-				position138, thunkPosition138 := position, thunkPosition
-				if !p.rules[ruleListContinuationBlock]() {
-					goto l138
-				}
-				do(29)
-				goto l137
-			l138:
-				position, thunkPosition = position138, thunkPosition138
+			if !p.rules[ruleBlankLines]() {
+				goto l21
 			}
-			do(30)
+			do(3)
 			doarg(yyPop, 1)
 			return true
-		l135:
+		l21:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 25 ListItemTight <- (((&[:~] DefMarker) | (&[*+\-] Bullet) | (&[0-9] Enumerator)) StartList ListBlock { a = cons(yy, a) } (!BlankLine ListContinuationBlock { a = cons(yy, a) })* !ListContinuationBlock {
-               raw := p.mkStringFromList(a, false)
-               raw.key = RAW
-               yy = p.mkElem(LISTITEM)
-               yy.children = raw
-            }) */
+		/* 4 Plain <- (Inlines { yy = a; yy.key = PLAIN }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
 			doarg(yyPush, 1)
-			{
-				if position == len(p.Buffer) {
-					goto l139
-				}
-				switch p.Buffer[position] {
-				case ':', '~':
-					if !p.rules[ruleDefMarker]() {
-						goto l139
-					}
-					break
-				case '*', '+', '-':
-					if !p.rules[ruleBullet]() {
-						goto l139
-					}
-					break
-				default:
-					if !p.rules[ruleEnumerator]() {
-						goto l139
-					}
-				}
-			}
-			if !p.rules[ruleStartList]() {
-				goto l139
+			if !p.rules[ruleInlines]() {
+				goto l24
 			}
 			doarg(yySet, -1)
-			if !p.rules[ruleListBlock]() {
-				goto l139
-			}
-			do(31)
-		l141:
-			{
-				position142, thunkPosition142 := position, thunkPosition
-				if !p.rules[ruleBlankLine]() {
-					goto l143
-				}
-				goto l142
-			l143:
-				if !p.rules[ruleListContinuationBlock]() {
-					goto l142
-				}
-				do(32)
-				goto l141
-			l142:
-				position, thunkPosition = position142, thunkPosition142
-			}
-			if !p.rules[ruleListContinuationBlock]() {
-				goto l144
-			}
-			goto l139
-		l144:
-			do(33)
+			do(4)
 			doarg(yyPop, 1)
 			return true
-		l139:
+		l24:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 26 ListBlock <- (StartList !BlankLine Line { a = cons(yy, a) } (ListBlockLine { a = cons(yy, a) })* { yy = p.mkStringFromList(a, false) }) */
+		/* 5 AtxInline <- (!Newline !(Sp? (&{!p.extension.KeepAtxClosing} '#'*)? Sp Newline) Inline) */
 		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l145
-			}
-			doarg(yySet, -1)
-			if !p.rules[ruleBlankLine]() {
-				goto l146
-			}
-			goto l145
-		l146:
-			if !p.rules[ruleLine]() {
-				goto l145
+			position0 := position
+			if !p.rules[ruleNewline]() {
+				goto l26
 			}
-			do(34)
-		l147:
+			goto l25
+		l26:
 			{
-				position148 := position
-				if !p.rules[ruleListBlockLine]() {
-					goto l148
+				position27 := position
+				if !p.rules[ruleSp]() {
+					goto l28
 				}
-				do(35)
-				goto l147
-			l148:
-				position = position148
+			l28:
+				{
+					position1454 := position
+					if !(!p.extension.KeepAtxClosing) {
+						goto l1454
+					}
+				l30:
+					if !matchChar('#') {
+						goto l31
+					}
+					goto l30
+				l31:
+					goto l1455
+				l1454:
+					position = position1454
+				}
+			l1455:
+				if !p.rules[ruleSp]() {
+					goto l27
+				}
+				if !p.rules[ruleNewline]() {
+					goto l27
+				}
+				goto l25
+			l27:
+				position = position27
+			}
+			if !p.rules[ruleInline]() {
+				goto l25
 			}
-			do(36)
-			doarg(yyPop, 1)
 			return true
-		l145:
-			position, thunkPosition = position0, thunkPosition0
+		l25:
+			position = position0
 			return false
 		},
-		/* 27 ListContinuationBlock <- (StartList (< BlankLine* > {   if len(yytext) == 0 {
-                                   a = cons(p.mkString("\001"), a) // block separator
-                              } else {
-                                   a = cons(p.mkString(yytext), a)
-                              }
-                          }) (Indent ListBlock { a = cons(yy, a) })+ {  yy = p.mkStringFromList(a, false) }) */
+		/* 6 AtxStart <- (&'#' < ('######' / '#####' / '####' / '###' / '##' / '#') > &{p.atxSpace(position)} { yy = p.mkElem(H1 + (len(yytext) - 1)) }) */
 		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l149
+			position0 := position
+			if !peekChar('#') {
+				goto l32
 			}
-			doarg(yySet, -1)
 			begin = position
-		l150:
-			if !p.rules[ruleBlankLine]() {
-				goto l151
-			}
-			goto l150
-		l151:
-			end = position
-			do(37)
-			if !p.rules[ruleIndent]() {
-				goto l149
+			if !matchString("######") {
+				goto l34
 			}
-			if !p.rules[ruleListBlock]() {
-				goto l149
+			goto l33
+		l34:
+			if !matchString("#####") {
+				goto l35
 			}
-			do(38)
-		l152:
-			{
-				position153, thunkPosition153 := position, thunkPosition
-				if !p.rules[ruleIndent]() {
-					goto l153
-				}
-				if !p.rules[ruleListBlock]() {
-					goto l153
-				}
-				do(38)
-				goto l152
-			l153:
-				position, thunkPosition = position153, thunkPosition153
-			}
-			do(39)
-			doarg(yyPop, 1)
-			return true
-		l149:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 28 Enumerator <- (NonindentSpace [0-9]+ '.' Spacechar+) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleNonindentSpace]() {
-				goto l154
-			}
-			if !matchClass(0) {
-				goto l154
+			goto l33
+		l35:
+			if !matchString("####") {
+				goto l36
 			}
-		l155:
-			if !matchClass(0) {
-				goto l156
+			goto l33
+		l36:
+			if !matchString("###") {
+				goto l37
 			}
-			goto l155
-		l156:
-			if !matchChar('.') {
-				goto l154
+			goto l33
+		l37:
+			if !matchString("##") {
+				goto l38
 			}
-			if !p.rules[ruleSpacechar]() {
-				goto l154
+			goto l33
+		l38:
+			if !matchChar('#') {
+				goto l32
 			}
-		l157:
-			if !p.rules[ruleSpacechar]() {
-				goto l158
+		l33:
+			end = position
+			if !(p.atxSpace(position)) {
+				goto l32
 			}
-			goto l157
-		l158:
+			do(5)
 			return true
-		l154:
+		l32:
 			position = position0
 			return false
 		},
-		/* 29 OrderedList <- (&Enumerator (ListTight / ListLoose) { yy.key = ORDEREDLIST }) */
+		/* 7 AtxHeading <- (AtxStart Sp? StartList (AtxInline { a = cons(yy, a) })+ (Sp? '#'* Sp)? Newline { yy = p.mkList(s.key, a)
+              s = nil }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleAtxStart]() {
+				goto l39
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleSp]() {
+				goto l40
+			}
+		l40:
+			if !p.rules[ruleStartList]() {
+				goto l39
+			}
+			doarg(yySet, -2)
+			if !p.rules[ruleAtxInline]() {
+				goto l39
+			}
+			do(6)
+		l42:
 			{
-				position160 := position
-				if !p.rules[ruleEnumerator]() {
-					goto l159
+				position43 := position
+				if !p.rules[ruleAtxInline]() {
+					goto l43
 				}
-				position = position160
+				do(6)
+				goto l42
+			l43:
+				position = position43
 			}
-			if !p.rules[ruleListTight]() {
-				goto l162
+			{
+				position44 := position
+				if !p.rules[ruleSp]() {
+					goto l46
+				}
+			l46:
+			l48:
+				if !matchChar('#') {
+					goto l49
+				}
+				goto l48
+			l49:
+				if !p.rules[ruleSp]() {
+					goto l44
+				}
+				goto l45
+			l44:
+				position = position44
 			}
-			goto l161
-		l162:
-			if !p.rules[ruleListLoose]() {
-				goto l159
+		l45:
+			if !p.rules[ruleNewline]() {
+				goto l39
 			}
-		l161:
-			do(40)
+			do(7)
+			doarg(yyPop, 2)
 			return true
-		l159:
+		l39:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 30 ListBlockLine <- (!BlankLine !((&[:~] DefMarker) | (&[\t *+\-0-9] (Indent? ((&[*+\-] Bullet) | (&[0-9] Enumerator))))) !HorizontalRule OptionallyIndentedLine) */
+		/* 8 SetextHeading <- (&{!p.extension.NoSetext} (SetextHeading1 / SetextHeading2)) */
 		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			if !p.rules[ruleBlankLine]() {
-				goto l164
-			}
-			goto l163
-		l164:
-			{
-				position165 := position
-				{
-					if position == len(p.Buffer) {
-						goto l165
-					}
-					switch p.Buffer[position] {
-					case ':', '~':
-						if !p.rules[ruleDefMarker]() {
-							goto l165
-						}
-						break
-					default:
-						if !p.rules[ruleIndent]() {
-							goto l167
-						}
-					l167:
-						{
-							if position == len(p.Buffer) {
-								goto l165
-							}
-							switch p.Buffer[position] {
-							case '*', '+', '-':
-								if !p.rules[ruleBullet]() {
-									goto l165
-								}
-								break
-							default:
-								if !p.rules[ruleEnumerator]() {
-									goto l165
-								}
-							}
-						}
-					}
-				}
-				goto l163
-			l165:
-				position = position165
+			if !(!p.extension.NoSetext) {
+				goto l50
 			}
-			if !p.rules[ruleHorizontalRule]() {
-				goto l170
+			if !p.rules[ruleSetextHeading1]() {
+				goto l52
 			}
-			goto l163
-		l170:
-			if !p.rules[ruleOptionallyIndentedLine]() {
-				goto l163
+			goto l51
+		l52:
+			if !p.rules[ruleSetextHeading2]() {
+				goto l50
 			}
+		l51:
 			return true
-		l163:
-			position, thunkPosition = position0, thunkPosition0
+		l50:
 			return false
 		},
-		/* 31 HtmlBlockOpenAddress <- ('<' Spnl ((&[A] 'ADDRESS') | (&[a] 'address')) Spnl HtmlAttribute* '>') */
+		/* 9 SetextBottom1 <- (&{p.setextUnderline(position)} '='+ Newline) */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l171
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l171
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l171
-				}
-				switch p.Buffer[position] {
-				case 'A':
-					position++
-					if !matchString("DDRESS") {
-						goto l171
-					}
-					break
-				case 'a':
-					position++
-					if !matchString("ddress") {
-						goto l171
-					}
-					break
-				default:
-					goto l171
-				}
+			if !(p.setextUnderline(position)) {
+				goto l53
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l171
+			if !matchChar('=') {
+				goto l53
 			}
-		l173:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l174
+		l54:
+			if !matchChar('=') {
+				goto l55
 			}
-			goto l173
-		l174:
-			if !matchChar('>') {
-				goto l171
+			goto l54
+		l55:
+			if !p.rules[ruleNewline]() {
+				goto l53
 			}
 			return true
-		l171:
+		l53:
 			position = position0
 			return false
 		},
-		/* 32 HtmlBlockCloseAddress <- ('<' Spnl '/' ((&[A] 'ADDRESS') | (&[a] 'address')) Spnl '>') */
+		/* 10 SetextBottom2 <- (&{p.setextUnderline(position)} '-'+ Newline) */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l175
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l175
-			}
-			if !matchChar('/') {
-				goto l175
+			if !(p.setextUnderline(position)) {
+				goto l56
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l175
-				}
-				switch p.Buffer[position] {
-				case 'A':
-					position++
-					if !matchString("DDRESS") {
-						goto l175
-					}
-					break
-				case 'a':
-					position++
-					if !matchString("ddress") {
-						goto l175
-					}
-					break
-				default:
-					goto l175
-				}
+			if !matchChar('-') {
+				goto l56
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l175
+		l57:
+			if !matchChar('-') {
+				goto l58
 			}
-			if !matchChar('>') {
-				goto l175
+			goto l57
+		l58:
+			if !p.rules[ruleNewline]() {
+				goto l56
 			}
 			return true
-		l175:
+		l56:
 			position = position0
 			return false
 		},
-		/* 33 HtmlBlockAddress <- (HtmlBlockOpenAddress (HtmlBlockAddress / (!HtmlBlockCloseAddress .))* HtmlBlockCloseAddress) */
+		/* 11 SetextHeading1 <- (&(RawLine SetextBottom1) StartList (!Endline Inline { a = cons(yy, a) })+ Sp? Newline SetextBottom1 { yy = p.mkList(H1, a) }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenAddress]() {
-				goto l177
-			}
-		l178:
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
 			{
-				position179 := position
-				if !p.rules[ruleHtmlBlockAddress]() {
-					goto l181
-				}
-				goto l180
-			l181:
-				if !p.rules[ruleHtmlBlockCloseAddress]() {
-					goto l182
+				position60 := position
+				if !p.rules[ruleRawLine]() {
+					goto l59
 				}
-				goto l179
-			l182:
-				if !matchDot() {
-					goto l179
+				if !p.rules[ruleSetextBottom1]() {
+					goto l59
 				}
-			l180:
-				goto l178
-			l179:
-				position = position179
+				position = position60
 			}
-			if !p.rules[ruleHtmlBlockCloseAddress]() {
-				goto l177
+			if !p.rules[ruleStartList]() {
+				goto l59
 			}
-			return true
-		l177:
-			position = position0
-			return false
-		},
-		/* 34 HtmlBlockOpenBlockquote <- ('<' Spnl ((&[B] 'BLOCKQUOTE') | (&[b] 'blockquote')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l183
+			doarg(yySet, -1)
+			if !p.rules[ruleEndline]() {
+				goto l63
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l183
+			goto l59
+		l63:
+			if !p.rules[ruleInline]() {
+				goto l59
 			}
+			do(8)
+		l61:
 			{
-				if position == len(p.Buffer) {
-					goto l183
+				position62 := position
+				if !p.rules[ruleEndline]() {
+					goto l64
 				}
-				switch p.Buffer[position] {
-				case 'B':
-					position++
-					if !matchString("LOCKQUOTE") {
-						goto l183
-					}
-					break
-				case 'b':
-					position++
-					if !matchString("lockquote") {
-						goto l183
-					}
-					break
-				default:
-					goto l183
+				goto l62
+			l64:
+				if !p.rules[ruleInline]() {
+					goto l62
 				}
+				do(8)
+				goto l61
+			l62:
+				position = position62
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l183
+			if !p.rules[ruleSp]() {
+				goto l65
 			}
-		l185:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l186
+		l65:
+			if !p.rules[ruleNewline]() {
+				goto l59
 			}
-			goto l185
-		l186:
-			if !matchChar('>') {
-				goto l183
+			if !p.rules[ruleSetextBottom1]() {
+				goto l59
 			}
+			do(9)
+			doarg(yyPop, 1)
 			return true
-		l183:
-			position = position0
+		l59:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 35 HtmlBlockCloseBlockquote <- ('<' Spnl '/' ((&[B] 'BLOCKQUOTE') | (&[b] 'blockquote')) Spnl '>') */
+		/* 12 SetextHeading2 <- (&(RawLine SetextBottom2) StartList (!Endline Inline { a = cons(yy, a) })+ Sp? Newline SetextBottom2 { yy = p.mkList(H2, a) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l187
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l187
-			}
-			if !matchChar('/') {
-				goto l187
-			}
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
 			{
-				if position == len(p.Buffer) {
-					goto l187
+				position68 := position
+				if !p.rules[ruleRawLine]() {
+					goto l67
 				}
-				switch p.Buffer[position] {
-				case 'B':
-					position++
-					if !matchString("LOCKQUOTE") {
-						goto l187
-					}
-					break
-				case 'b':
-					position++
-					if !matchString("lockquote") {
-						goto l187
-					}
-					break
-				default:
-					goto l187
+				if !p.rules[ruleSetextBottom2]() {
+					goto l67
 				}
+				position = position68
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l187
+			if !p.rules[ruleStartList]() {
+				goto l67
 			}
-			if !matchChar('>') {
-				goto l187
+			doarg(yySet, -1)
+			if !p.rules[ruleEndline]() {
+				goto l71
 			}
-			return true
-		l187:
-			position = position0
-			return false
-		},
-		/* 36 HtmlBlockBlockquote <- (HtmlBlockOpenBlockquote (HtmlBlockBlockquote / (!HtmlBlockCloseBlockquote .))* HtmlBlockCloseBlockquote) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenBlockquote]() {
-				goto l189
+			goto l67
+		l71:
+			if !p.rules[ruleInline]() {
+				goto l67
 			}
-		l190:
+			do(10)
+		l69:
 			{
-				position191 := position
-				if !p.rules[ruleHtmlBlockBlockquote]() {
-					goto l193
-				}
-				goto l192
-			l193:
-				if !p.rules[ruleHtmlBlockCloseBlockquote]() {
-					goto l194
+				position70 := position
+				if !p.rules[ruleEndline]() {
+					goto l72
 				}
-				goto l191
-			l194:
-				if !matchDot() {
-					goto l191
+				goto l70
+			l72:
+				if !p.rules[ruleInline]() {
+					goto l70
 				}
-			l192:
-				goto l190
-			l191:
-				position = position191
+				do(10)
+				goto l69
+			l70:
+				position = position70
+			}
+			if !p.rules[ruleSp]() {
+				goto l73
+			}
+		l73:
+			if !p.rules[ruleNewline]() {
+				goto l67
 			}
-			if !p.rules[ruleHtmlBlockCloseBlockquote]() {
-				goto l189
+			if !p.rules[ruleSetextBottom2]() {
+				goto l67
 			}
+			do(11)
+			doarg(yyPop, 1)
 			return true
-		l189:
-			position = position0
+		l67:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 37 HtmlBlockOpenCenter <- ('<' Spnl ((&[C] 'CENTER') | (&[c] 'center')) Spnl HtmlAttribute* '>') */
+		/* 13 Heading <- (SetextHeading / AtxHeading) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l195
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l195
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l195
-				}
-				switch p.Buffer[position] {
-				case 'C':
-					position++
-					if !matchString("ENTER") {
-						goto l195
-					}
-					break
-				case 'c':
-					position++
-					if !matchString("enter") {
-						goto l195
-					}
-					break
-				default:
-					goto l195
-				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l195
-			}
-		l197:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l198
+			if !p.rules[ruleSetextHeading]() {
+				goto l77
 			}
-			goto l197
-		l198:
-			if !matchChar('>') {
-				goto l195
+			goto l76
+		l77:
+			if !p.rules[ruleAtxHeading]() {
+				goto l75
 			}
+		l76:
 			return true
-		l195:
-			position = position0
+		l75:
 			return false
 		},
-		/* 38 HtmlBlockCloseCenter <- ('<' Spnl '/' ((&[C] 'CENTER') | (&[c] 'center')) Spnl '>') */
+		/* 14 BlockQuote <- (BlockQuoteRaw {  yy = p.mkElem(BLOCKQUOTE)
+                yy.children = a
+             }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l199
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l199
-			}
-			if !matchChar('/') {
-				goto l199
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l199
-				}
-				switch p.Buffer[position] {
-				case 'C':
-					position++
-					if !matchString("ENTER") {
-						goto l199
-					}
-					break
-				case 'c':
-					position++
-					if !matchString("enter") {
-						goto l199
-					}
-					break
-				default:
-					goto l199
-				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l199
-			}
-			if !matchChar('>') {
-				goto l199
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleBlockQuoteRaw]() {
+				goto l78
 			}
+			doarg(yySet, -1)
+			do(12)
+			doarg(yyPop, 1)
 			return true
-		l199:
-			position = position0
+		l78:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 39 HtmlBlockCenter <- (HtmlBlockOpenCenter (HtmlBlockCenter / (!HtmlBlockCloseCenter .))* HtmlBlockCloseCenter) */
+		/* 15 BlockQuoteRaw <- (StartList ('>' ' '? Line { a = cons(yy, a) } (!'>' !BlankLine Line { a = cons(yy, a) })* (BlankLine { a = cons(p.mkString("\n"), a) })*)+ {   yy = p.mkStringFromList(a, true)
+                     yy.key = RAW
+                 }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenCenter]() {
-				goto l201
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l79
+			}
+			doarg(yySet, -1)
+			if !matchChar('>') {
+				goto l79
+			}
+			matchChar(' ')
+			if !p.rules[ruleLine]() {
+				goto l79
 			}
-		l202:
+			do(13)
+		l82:
 			{
-				position203 := position
-				if !p.rules[ruleHtmlBlockCenter]() {
-					goto l205
+				position83, thunkPosition83 := position, thunkPosition
+				if peekChar('>') {
+					goto l83
 				}
-				goto l204
-			l205:
-				if !p.rules[ruleHtmlBlockCloseCenter]() {
-					goto l206
+				if !p.rules[ruleBlankLine]() {
+					goto l84
 				}
-				goto l203
-			l206:
-				if !matchDot() {
-					goto l203
+				goto l83
+			l84:
+				if !p.rules[ruleLine]() {
+					goto l83
 				}
-			l204:
-				goto l202
-			l203:
-				position = position203
-			}
-			if !p.rules[ruleHtmlBlockCloseCenter]() {
-				goto l201
-			}
-			return true
-		l201:
-			position = position0
-			return false
-		},
-		/* 40 HtmlBlockOpenDir <- ('<' Spnl ((&[D] 'DIR') | (&[d] 'dir')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l207
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l207
+				do(14)
+				goto l82
+			l83:
+				position, thunkPosition = position83, thunkPosition83
 			}
+		l85:
 			{
-				if position == len(p.Buffer) {
-					goto l207
+				position86 := position
+				if !p.rules[ruleBlankLine]() {
+					goto l86
 				}
-				switch p.Buffer[position] {
-				case 'D':
-					position++
-					if !matchString("IR") {
-						goto l207
-					}
-					break
-				case 'd':
-					position++
-					if !matchString("ir") {
-						goto l207
-					}
-					break
-				default:
-					goto l207
-				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l207
-			}
-		l209:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l210
-			}
-			goto l209
-		l210:
-			if !matchChar('>') {
-				goto l207
-			}
-			return true
-		l207:
-			position = position0
-			return false
-		},
-		/* 41 HtmlBlockCloseDir <- ('<' Spnl '/' ((&[D] 'DIR') | (&[d] 'dir')) Spnl '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l211
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l211
-			}
-			if !matchChar('/') {
-				goto l211
+				do(15)
+				goto l85
+			l86:
+				position = position86
 			}
+		l80:
 			{
-				if position == len(p.Buffer) {
-					goto l211
+				position81, thunkPosition81 := position, thunkPosition
+				if !matchChar('>') {
+					goto l81
 				}
-				switch p.Buffer[position] {
-				case 'D':
-					position++
-					if !matchString("IR") {
-						goto l211
+				matchChar(' ')
+				if !p.rules[ruleLine]() {
+					goto l81
+				}
+				do(13)
+			l87:
+				{
+					position88, thunkPosition88 := position, thunkPosition
+					if peekChar('>') {
+						goto l88
 					}
-					break
-				case 'd':
-					position++
-					if !matchString("ir") {
-						goto l211
+					if !p.rules[ruleBlankLine]() {
+						goto l89
 					}
-					break
-				default:
-					goto l211
+					goto l88
+				l89:
+					if !p.rules[ruleLine]() {
+						goto l88
+					}
+					do(14)
+					goto l87
+				l88:
+					position, thunkPosition = position88, thunkPosition88
 				}
+			l90:
+				{
+					position91 := position
+					if !p.rules[ruleBlankLine]() {
+						goto l91
+					}
+					do(15)
+					goto l90
+				l91:
+					position = position91
+				}
+				goto l80
+			l81:
+				position, thunkPosition = position81, thunkPosition81
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l211
-			}
-			if !matchChar('>') {
-				goto l211
-			}
+			do(16)
+			doarg(yyPop, 1)
 			return true
-		l211:
-			position = position0
+		l79:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 42 HtmlBlockDir <- (HtmlBlockOpenDir (HtmlBlockDir / (!HtmlBlockCloseDir .))* HtmlBlockCloseDir) */
+		/* 16 NonblankIndentedLine <- (!BlankLine IndentedLine) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenDir]() {
-				goto l213
-			}
-		l214:
-			{
-				position215 := position
-				if !p.rules[ruleHtmlBlockDir]() {
-					goto l217
-				}
-				goto l216
-			l217:
-				if !p.rules[ruleHtmlBlockCloseDir]() {
-					goto l218
-				}
-				goto l215
-			l218:
-				if !matchDot() {
-					goto l215
-				}
-			l216:
-				goto l214
-			l215:
-				position = position215
+			if !p.rules[ruleBlankLine]() {
+				goto l93
 			}
-			if !p.rules[ruleHtmlBlockCloseDir]() {
-				goto l213
+			goto l92
+		l93:
+			if !p.rules[ruleIndentedLine]() {
+				goto l92
 			}
 			return true
-		l213:
+		l92:
 			position = position0
 			return false
 		},
-		/* 43 HtmlBlockOpenDiv <- ('<' Spnl ((&[D] 'DIV') | (&[d] 'div')) Spnl HtmlAttribute* '>') */
+		/* 17 VerbatimChunk <- (StartList (BlankLine { a = cons(p.mkString("\n"), a) })* (NonblankIndentedLine { a = cons(yy, a) })+ { yy = p.mkStringFromList(a, false) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l219
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l219
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l94
 			}
+			doarg(yySet, -1)
+		l95:
 			{
-				if position == len(p.Buffer) {
-					goto l219
-				}
-				switch p.Buffer[position] {
-				case 'D':
-					position++
-					if !matchString("IV") {
-						goto l219
-					}
-					break
-				case 'd':
-					position++
-					if !matchString("iv") {
-						goto l219
-					}
-					break
-				default:
-					goto l219
+				position96 := position
+				if !p.rules[ruleBlankLine]() {
+					goto l96
 				}
+				do(17)
+				goto l95
+			l96:
+				position = position96
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l219
-			}
-		l221:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l222
+			if !p.rules[ruleNonblankIndentedLine]() {
+				goto l94
 			}
-			goto l221
-		l222:
-			if !matchChar('>') {
-				goto l219
+			do(18)
+		l97:
+			{
+				position98 := position
+				if !p.rules[ruleNonblankIndentedLine]() {
+					goto l98
+				}
+				do(18)
+				goto l97
+			l98:
+				position = position98
 			}
+			do(19)
+			doarg(yyPop, 1)
 			return true
-		l219:
-			position = position0
+		l94:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 44 HtmlBlockCloseDiv <- ('<' Spnl '/' ((&[D] 'DIV') | (&[d] 'div')) Spnl '>') */
+		/* 18 Verbatim <- (StartList (VerbatimChunk { a = cons(yy, a) })+ { yy = p.mkStringFromList(a, false)
+                 yy.key = VERBATIM }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l223
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l223
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l99
 			}
-			if !matchChar('/') {
-				goto l223
+			doarg(yySet, -1)
+			if !p.rules[ruleVerbatimChunk]() {
+				goto l99
 			}
+			do(20)
+		l100:
 			{
-				if position == len(p.Buffer) {
-					goto l223
-				}
-				switch p.Buffer[position] {
-				case 'D':
-					position++
-					if !matchString("IV") {
-						goto l223
-					}
-					break
-				case 'd':
-					position++
-					if !matchString("iv") {
-						goto l223
-					}
-					break
-				default:
-					goto l223
+				position101, thunkPosition101 := position, thunkPosition
+				if !p.rules[ruleVerbatimChunk]() {
+					goto l101
 				}
+				do(20)
+				goto l100
+			l101:
+				position, thunkPosition = position101, thunkPosition101
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l223
-			}
-			if !matchChar('>') {
-				goto l223
-			}
+			do(21)
+			doarg(yyPop, 1)
 			return true
-		l223:
-			position = position0
+		l99:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 45 HtmlBlockDiv <- (HtmlBlockOpenDiv (HtmlBlockDiv / (!HtmlBlockCloseDiv .))* HtmlBlockCloseDiv) */
+		/* 19 HorizontalRule <- (NonindentSpace (('*' Sp '*' Sp '*' (Sp '*')* Sp Newline BlankLines) / ('-' Sp '-' Sp '-' (Sp '-')* Sp Newline (BlankLines / &{!p.setextSplits()})) / ('_' Sp '_' Sp '_' (Sp '_')* Sp Newline BlankLines)) { yy = p.mkElem(HRULE) }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenDiv]() {
-				goto l225
+			position0, thunkPosition0 := position, thunkPosition
+			if !p.rules[ruleNonindentSpace]() {
+				goto l102
 			}
-		l226:
 			{
-				position227 := position
-				if !p.rules[ruleHtmlBlockDiv]() {
-					goto l229
+				position106, thunkPosition106 := position, thunkPosition
+				if !matchChar('*') {
+					goto l107
 				}
-				goto l228
-			l229:
-				if !p.rules[ruleHtmlBlockCloseDiv]() {
-					goto l230
+				if !p.rules[ruleSp]() {
+					goto l107
 				}
-				goto l227
-			l230:
-				if !matchDot() {
-					goto l227
+				if !matchChar('*') {
+					goto l107
 				}
-			l228:
-				goto l226
-			l227:
-				position = position227
-			}
-			if !p.rules[ruleHtmlBlockCloseDiv]() {
-				goto l225
-			}
-			return true
-		l225:
-			position = position0
-			return false
-		},
-		/* 46 HtmlBlockOpenDl <- ('<' Spnl ((&[D] 'DL') | (&[d] 'dl')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l231
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l231
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l231
+				if !p.rules[ruleSp]() {
+					goto l107
 				}
-				switch p.Buffer[position] {
-				case 'D':
-					position++ // matchString(`DL`)
-					if !matchChar('L') {
-						goto l231
+				if !matchChar('*') {
+					goto l107
+				}
+			l108:
+				{
+					position109, thunkPosition109 := position, thunkPosition
+					if !p.rules[ruleSp]() {
+						goto l109
 					}
-					break
-				case 'd':
-					position++ // matchString(`dl`)
-					if !matchChar('l') {
-						goto l231
+					if !matchChar('*') {
+						goto l109
 					}
-					break
-				default:
-					goto l231
+					goto l108
+				l109:
+					position, thunkPosition = position109, thunkPosition109
 				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l231
-			}
-		l233:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l234
-			}
-			goto l233
-		l234:
-			if !matchChar('>') {
-				goto l231
-			}
-			return true
-		l231:
-			position = position0
-			return false
-		},
-		/* 47 HtmlBlockCloseDl <- ('<' Spnl '/' ((&[D] 'DL') | (&[d] 'dl')) Spnl '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l235
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l235
-			}
-			if !matchChar('/') {
-				goto l235
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l235
+				if !p.rules[ruleSp]() {
+					goto l107
 				}
-				switch p.Buffer[position] {
-				case 'D':
-					position++ // matchString(`DL`)
-					if !matchChar('L') {
-						goto l235
-					}
-					break
-				case 'd':
-					position++ // matchString(`dl`)
-					if !matchChar('l') {
-						goto l235
+				if !p.rules[ruleNewline]() {
+					goto l107
+				}
+				if !p.rules[ruleBlankLines]() {
+					goto l107
+				}
+				goto l104
+			l107:
+				position, thunkPosition = position106, thunkPosition106
+				if !matchChar('-') {
+					goto l110
+				}
+				if !p.rules[ruleSp]() {
+					goto l110
+				}
+				if !matchChar('-') {
+					goto l110
+				}
+				if !p.rules[ruleSp]() {
+					goto l110
+				}
+				if !matchChar('-') {
+					goto l110
+				}
+			l111:
+				{
+					position1586, thunkPosition1586 := position, thunkPosition
+					if !p.rules[ruleSp]() {
+						goto l1586
 					}
-					break
-				default:
-					goto l235
+					if !matchChar('-') {
+						goto l1586
+					}
+					goto l111
+				l1586:
+					position, thunkPosition = position1586, thunkPosition1586
 				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l235
-			}
-			if !matchChar('>') {
-				goto l235
-			}
-			return true
-		l235:
-			position = position0
-			return false
-		},
-		/* 48 HtmlBlockDl <- (HtmlBlockOpenDl (HtmlBlockDl / (!HtmlBlockCloseDl .))* HtmlBlockCloseDl) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenDl]() {
-				goto l237
-			}
-		l238:
-			{
-				position239 := position
-				if !p.rules[ruleHtmlBlockDl]() {
-					goto l241
+				if !p.rules[ruleSp]() {
+					goto l110
 				}
-				goto l240
-			l241:
-				if !p.rules[ruleHtmlBlockCloseDl]() {
-					goto l242
+				if !p.rules[ruleNewline]() {
+					goto l110
 				}
-				goto l239
-			l242:
-				if !matchDot() {
-					goto l239
+				if !p.rules[ruleBlankLines]() {
+					goto l1588
+				}
+				goto l1587
+			l1588:
+				if !(!p.setextSplits()) {
+					goto l110
+				}
+			l1587:
+				goto l104
+			l110:
+				position, thunkPosition = position106, thunkPosition106
+				if !matchChar('_') {
+					goto l102
+				}
+				if !p.rules[ruleSp]() {
+					goto l102
+				}
+				if !matchChar('_') {
+					goto l102
+				}
+				if !p.rules[ruleSp]() {
+					goto l102
+				}
+				if !matchChar('_') {
+					goto l102
+				}
+			l1590:
+				{
+					position1591, thunkPosition1591 := position, thunkPosition
+					if !p.rules[ruleSp]() {
+						goto l1591
+					}
+					if !matchChar('_') {
+						goto l1591
+					}
+					goto l1590
+				l1591:
+					position, thunkPosition = position1591, thunkPosition1591
+				}
+				if !p.rules[ruleSp]() {
+					goto l102
+				}
+				if !p.rules[ruleNewline]() {
+					goto l102
+				}
+				if !p.rules[ruleBlankLines]() {
+					goto l102
 				}
-			l240:
-				goto l238
-			l239:
-				position = position239
-			}
-			if !p.rules[ruleHtmlBlockCloseDl]() {
-				goto l237
 			}
+		l104:
+			do(22)
 			return true
-		l237:
-			position = position0
+		l102:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 49 HtmlBlockOpenFieldset <- ('<' Spnl ((&[F] 'FIELDSET') | (&[f] 'fieldset')) Spnl HtmlAttribute* '>') */
+		/* 20 Bullet <- (!HorizontalRule NonindentSpace ((&[\-] '-') | (&[*] '*') | (&[+] '+')) Spacechar+) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l243
+			position0, thunkPosition0 := position, thunkPosition
+			if !p.rules[ruleHorizontalRule]() {
+				goto l113
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l243
+			goto l112
+		l113:
+			if !p.rules[ruleNonindentSpace]() {
+				goto l112
 			}
 			{
 				if position == len(p.Buffer) {
-					goto l243
+					goto l112
 				}
 				switch p.Buffer[position] {
-				case 'F':
-					position++
-					if !matchString("IELDSET") {
-						goto l243
-					}
+				case '-':
+					position++ // matchChar
 					break
-				case 'f':
-					position++
-					if !matchString("ieldset") {
-						goto l243
-					}
+				case '*':
+					position++ // matchChar
+					break
+				case '+':
+					position++ // matchChar
 					break
 				default:
-					goto l243
+					goto l112
 				}
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l243
-			}
-		l245:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l246
+			if !p.rules[ruleSpacechar]() {
+				goto l112
 			}
-			goto l245
-		l246:
-			if !matchChar('>') {
-				goto l243
+		l115:
+			if !p.rules[ruleSpacechar]() {
+				goto l116
 			}
+			goto l115
+		l116:
 			return true
-		l243:
-			position = position0
+		l112:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 50 HtmlBlockCloseFieldset <- ('<' Spnl '/' ((&[F] 'FIELDSET') | (&[f] 'fieldset')) Spnl '>') */
+		/* 21 BulletList <- (&Bullet &{p.startList(position)} (ListTight / ListLoose) { yy.key = BULLETLIST }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l247
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l247
-			}
-			if !matchChar('/') {
-				goto l247
-			}
+			position0, thunkPosition0 := position, thunkPosition
 			{
-				if position == len(p.Buffer) {
-					goto l247
-				}
-				switch p.Buffer[position] {
-				case 'F':
-					position++
-					if !matchString("IELDSET") {
-						goto l247
-					}
-					break
-				case 'f':
-					position++
-					if !matchString("ieldset") {
-						goto l247
-					}
-					break
-				default:
-					goto l247
+				position119, thunkPosition119 := position, thunkPosition
+				if !p.rules[ruleBullet]() {
+					goto l117
 				}
+				position, thunkPosition = position119, thunkPosition119
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l247
+			if !(p.startList(position)) {
+				goto l117
 			}
-			if !matchChar('>') {
-				goto l247
+			if !p.rules[ruleListTight]() {
+				goto l1601
 			}
+			goto l120
+		l1601:
+			if !p.rules[ruleListLoose]() {
+				goto l117
+			}
+		l120:
+			do(23)
 			return true
-		l247:
-			position = position0
+		l117:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 51 HtmlBlockFieldset <- (HtmlBlockOpenFieldset (HtmlBlockFieldset / (!HtmlBlockCloseFieldset .))* HtmlBlockCloseFieldset) */
+		/* 22 ListTight <- (StartList (&{p.sameDelim(position)} ListItemTight { a = cons(yy, a) })+ BlankLine* !(Bullet / (&{p.sameDelim(position)} Enumerator) / DefMarker) { yy = p.mkList(LIST, a) }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenFieldset]() {
-				goto l249
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l121
+			}
+			doarg(yySet, -1)
+			if !(p.sameDelim(position)) {
+				goto l121
 			}
-		l250:
+			if !p.rules[ruleListItemTight]() {
+				goto l121
+			}
+			do(24)
+		l122:
 			{
-				position251 := position
-				if !p.rules[ruleHtmlBlockFieldset]() {
-					goto l253
-				}
-				goto l252
-			l253:
-				if !p.rules[ruleHtmlBlockCloseFieldset]() {
-					goto l254
+				position123, thunkPosition123 := position, thunkPosition
+				if !(p.sameDelim(position)) {
+					goto l123
 				}
-				goto l251
-			l254:
-				if !matchDot() {
-					goto l251
+				if !p.rules[ruleListItemTight]() {
+					goto l123
 				}
-			l252:
-				goto l250
-			l251:
-				position = position251
+				do(24)
+				goto l122
+			l123:
+				position, thunkPosition = position123, thunkPosition123
+			}
+		l124:
+			if !p.rules[ruleBlankLine]() {
+				goto l125
 			}
-			if !p.rules[ruleHtmlBlockCloseFieldset]() {
-				goto l249
+			goto l124
+		l125:
+			{
+				position126, thunkPosition126 := position, thunkPosition
+				{
+					position1323, thunkPosition1323 := position, thunkPosition
+					if !p.rules[ruleBullet]() {
+						goto l1602
+					}
+					goto l1322
+				l1602:
+					position, thunkPosition = position1323, thunkPosition1323
+					if !(p.sameDelim(position)) {
+						goto l1603
+					}
+					if !p.rules[ruleEnumerator]() {
+						goto l1603
+					}
+					goto l1322
+				l1603:
+					position, thunkPosition = position1323, thunkPosition1323
+					if !p.rules[ruleDefMarker]() {
+						goto l1321
+					}
+				}
+			l1322:
+				goto l121
+			l1321:
+				position, thunkPosition = position126, thunkPosition126
 			}
+			do(25)
+			doarg(yyPop, 1)
 			return true
-		l249:
-			position = position0
+		l121:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 52 HtmlBlockOpenForm <- ('<' Spnl ((&[F] 'FORM') | (&[f] 'form')) Spnl HtmlAttribute* '>') */
+		/* 23 ListLoose <- (StartList (&{p.sameDelim(position)} ListItem BlankLine* {
+                  markLoose(b)
+                  a = cons(b, a)
+              })+ { yy = p.mkList(LIST, a)
+              yy.loose = true }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l255
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleStartList]() {
+				goto l128
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l255
+			doarg(yySet, -2)
+			if !(p.sameDelim(position)) {
+				goto l128
+			}
+			if !p.rules[ruleListItem]() {
+				goto l128
+			}
+			doarg(yySet, -1)
+		l129:
+			if !p.rules[ruleBlankLine]() {
+				goto l130
 			}
+			goto l129
+		l130:
+			do(26)
+		l131:
 			{
-				if position == len(p.Buffer) {
-					goto l255
+				position132, thunkPosition132 := position, thunkPosition
+				if !(p.sameDelim(position)) {
+					goto l132
 				}
-				switch p.Buffer[position] {
-				case 'F':
-					position++
-					if !matchString("ORM") {
-						goto l255
-					}
-					break
-				case 'f':
-					position++
-					if !matchString("orm") {
-						goto l255
-					}
-					break
-				default:
-					goto l255
+				if !p.rules[ruleListItem]() {
+					goto l132
 				}
+				doarg(yySet, -1)
+			l133:
+				if !p.rules[ruleBlankLine]() {
+					goto l134
+				}
+				goto l133
+			l134:
+				do(26)
+				goto l131
+			l132:
+				position, thunkPosition = position132, thunkPosition132
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l255
-			}
-		l257:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l258
-			}
-			goto l257
-		l258:
-			if !matchChar('>') {
-				goto l255
-			}
+			do(27)
+			doarg(yyPop, 2)
 			return true
-		l255:
-			position = position0
+		l128:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 53 HtmlBlockCloseForm <- ('<' Spnl '/' ((&[F] 'FORM') | (&[f] 'form')) Spnl '>') */
+		/* 24 ListItem <- (ListMarker StartList ListBlock { a = cons(yy, a) } (ListContinuationBlock { a = cons(yy, a) })* {
+               yy = p.mkListItem(a)
+               yy.contents.str = m.contents.str
+            }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l259
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleListMarker]() {
+				goto l135
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l259
+			doarg(yySet, -2)
+			if !p.rules[ruleStartList]() {
+				goto l135
 			}
-			if !matchChar('/') {
-				goto l259
+			doarg(yySet, -1)
+			if !p.rules[ruleListBlock]() {
+				goto l135
 			}
+			do(28)
+		l137:
 			{
-				if position == len(p.Buffer) {
-					goto l259
-				}
-				switch p.Buffer[position] {
-				case 'F':
-					position++
-					if !matchString("ORM") {
-						goto l259
-					}
-					break
-				case 'f':
-					position++
-					if !matchString("orm") {
-						goto l259
-					}
-					break
-				default:
-					goto l259
+				position138, thunkPosition138 := position, thunkPosition
+				if !p.rules[ruleListContinuationBlock]() {
+					goto l138
 				}
+				do(29)
+				goto l137
+			l138:
+				position, thunkPosition = position138, thunkPosition138
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l259
-			}
-			if !matchChar('>') {
-				goto l259
-			}
+			do(30)
+			doarg(yyPop, 2)
 			return true
-		l259:
-			position = position0
+		l135:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 54 HtmlBlockForm <- (HtmlBlockOpenForm (HtmlBlockForm / (!HtmlBlockCloseForm .))* HtmlBlockCloseForm) */
+		/* 25 ListItemTight <- (ListMarker StartList ListBlock { a = cons(yy, a) } (!BlankLine ListContinuationBlock { a = cons(yy, a) })* !ListContinuationBlock {
+               yy = p.mkListItem(a)
+               yy.contents.str = m.contents.str
+            }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenForm]() {
-				goto l261
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleListMarker]() {
+				goto l139
 			}
-		l262:
+			doarg(yySet, -2)
+			if !p.rules[ruleStartList]() {
+				goto l139
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleListBlock]() {
+				goto l139
+			}
+			do(31)
+		l141:
 			{
-				position263 := position
-				if !p.rules[ruleHtmlBlockForm]() {
-					goto l265
-				}
-				goto l264
-			l265:
-				if !p.rules[ruleHtmlBlockCloseForm]() {
-					goto l266
+				position142, thunkPosition142 := position, thunkPosition
+				if !p.rules[ruleBlankLine]() {
+					goto l143
 				}
-				goto l263
-			l266:
-				if !matchDot() {
-					goto l263
+				goto l142
+			l143:
+				if !p.rules[ruleListContinuationBlock]() {
+					goto l142
 				}
-			l264:
-				goto l262
-			l263:
-				position = position263
+				do(32)
+				goto l141
+			l142:
+				position, thunkPosition = position142, thunkPosition142
 			}
-			if !p.rules[ruleHtmlBlockCloseForm]() {
-				goto l261
+			if !p.rules[ruleListContinuationBlock]() {
+				goto l144
 			}
+			goto l139
+		l144:
+			do(33)
+			doarg(yyPop, 2)
 			return true
-		l261:
-			position = position0
+		l139:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 55 HtmlBlockOpenH1 <- ('<' Spnl ((&[H] 'H1') | (&[h] 'h1')) Spnl HtmlAttribute* '>') */
+		/* 26 ListBlock <- (StartList !BlankLine Line { a = cons(yy, a) } (ListBlockLine { a = cons(yy, a) })* { yy = p.mkStringFromList(a, false) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l267
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l145
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l267
+			doarg(yySet, -1)
+			if !p.rules[ruleBlankLine]() {
+				goto l146
+			}
+			goto l145
+		l146:
+			if !p.rules[ruleLine]() {
+				goto l145
 			}
+			do(34)
+		l147:
 			{
-				if position == len(p.Buffer) {
-					goto l267
-				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H1`)
-					if !matchChar('1') {
-						goto l267
-					}
-					break
-				case 'h':
-					position++ // matchString(`h1`)
-					if !matchChar('1') {
-						goto l267
-					}
-					break
-				default:
-					goto l267
+				position148 := position
+				if !p.rules[ruleListBlockLine]() {
+					goto l148
 				}
+				do(35)
+				goto l147
+			l148:
+				position = position148
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l267
-			}
-		l269:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l270
-			}
-			goto l269
-		l270:
-			if !matchChar('>') {
-				goto l267
-			}
+			do(36)
+			doarg(yyPop, 1)
 			return true
-		l267:
-			position = position0
+		l145:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 56 HtmlBlockCloseH1 <- ('<' Spnl '/' ((&[H] 'H1') | (&[h] 'h1')) Spnl '>') */
+		/* 27 ListContinuationBlock <- (StartList (< BlankLine* > {   if len(yytext) == 0 {
+                                   a = cons(p.mkElem(RAW), a) // block separator
+                              } else {
+                                   a = cons(p.mkString(yytext), a)
+                              }
+                          }) (Indent ListBlock { a = cons(yy, a) })+ {  yy = p.mkContinuation(a) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l271
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l149
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l271
+			doarg(yySet, -1)
+			begin = position
+		l150:
+			if !p.rules[ruleBlankLine]() {
+				goto l151
 			}
-			if !matchChar('/') {
-				goto l271
+			goto l150
+		l151:
+			end = position
+			do(37)
+			if !p.rules[ruleIndent]() {
+				goto l149
+			}
+			if !p.rules[ruleListBlock]() {
+				goto l149
 			}
+			do(38)
+		l152:
 			{
-				if position == len(p.Buffer) {
-					goto l271
+				position153, thunkPosition153 := position, thunkPosition
+				if !p.rules[ruleIndent]() {
+					goto l153
 				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H1`)
-					if !matchChar('1') {
-						goto l271
-					}
-					break
-				case 'h':
-					position++ // matchString(`h1`)
-					if !matchChar('1') {
-						goto l271
-					}
-					break
-				default:
-					goto l271
+				if !p.rules[ruleListBlock]() {
+					goto l153
 				}
+				do(38)
+				goto l152
+			l153:
+				position, thunkPosition = position153, thunkPosition153
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l271
-			}
-			if !matchChar('>') {
-				goto l271
-			}
+			do(39)
+			doarg(yyPop, 1)
 			return true
-		l271:
-			position = position0
+		l149:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 57 HtmlBlockH1 <- (HtmlBlockOpenH1 (HtmlBlockH1 / (!HtmlBlockCloseH1 .))* HtmlBlockCloseH1) */
+		/* 28 Enumerator <- (NonindentSpace [0-9]+ ('.' / (&{p.extension.ParenLists} ')')) Spacechar+) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenH1]() {
-				goto l273
-			}
-		l274:
-			{
-				position275 := position
-				if !p.rules[ruleHtmlBlockH1]() {
-					goto l277
-				}
-				goto l276
-			l277:
-				if !p.rules[ruleHtmlBlockCloseH1]() {
-					goto l278
-				}
-				goto l275
-			l278:
-				if !matchDot() {
-					goto l275
-				}
-			l276:
-				goto l274
-			l275:
-				position = position275
+			if !p.rules[ruleNonindentSpace]() {
+				goto l154
 			}
-			if !p.rules[ruleHtmlBlockCloseH1]() {
-				goto l273
+			if !matchClass(0) {
+				goto l154
 			}
-			return true
-		l273:
-			position = position0
-			return false
-		},
-		/* 58 HtmlBlockOpenH2 <- ('<' Spnl ((&[H] 'H2') | (&[h] 'h2')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l279
+		l155:
+			if !matchClass(0) {
+				goto l156
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l279
+			goto l155
+		l156:
+			if !matchChar('.') {
+				goto l1450
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l279
-				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H2`)
-					if !matchChar('2') {
-						goto l279
-					}
-					break
-				case 'h':
-					position++ // matchString(`h2`)
-					if !matchChar('2') {
-						goto l279
-					}
-					break
-				default:
-					goto l279
-				}
+			goto l1451
+		l1450:
+			if !(p.extension.ParenLists) {
+				goto l154
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l279
+			if !matchChar(')') {
+				goto l154
 			}
-		l281:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l282
+		l1451:
+			if !p.rules[ruleSpacechar]() {
+				goto l154
 			}
-			goto l281
-		l282:
-			if !matchChar('>') {
-				goto l279
+		l157:
+			if !p.rules[ruleSpacechar]() {
+				goto l158
 			}
+			goto l157
+		l158:
 			return true
-		l279:
+		l154:
 			position = position0
 			return false
 		},
-		/* 59 HtmlBlockCloseH2 <- ('<' Spnl '/' ((&[H] 'H2') | (&[h] 'h2')) Spnl '>') */
+		/* 29 OrderedList <- (&Enumerator &{p.startList(position)} (ListTight / ListLoose) { yy.key = ORDEREDLIST
+                yy.contents.str = yy.children.contents.str }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l283
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l283
-			}
-			if !matchChar('/') {
-				goto l283
-			}
+			position0, thunkPosition0 := position, thunkPosition
 			{
-				if position == len(p.Buffer) {
-					goto l283
-				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H2`)
-					if !matchChar('2') {
-						goto l283
-					}
-					break
-				case 'h':
-					position++ // matchString(`h2`)
-					if !matchChar('2') {
-						goto l283
-					}
-					break
-				default:
-					goto l283
+				position161, thunkPosition161 := position, thunkPosition
+				if !p.rules[ruleEnumerator]() {
+					goto l159
 				}
+				position, thunkPosition = position161, thunkPosition161
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l283
+			if !(p.startList(position)) {
+				goto l159
 			}
-			if !matchChar('>') {
-				goto l283
+			if !p.rules[ruleListTight]() {
+				goto l1604
+			}
+			goto l162
+		l1604:
+			if !p.rules[ruleListLoose]() {
+				goto l159
 			}
+		l162:
+			do(40)
 			return true
-		l283:
-			position = position0
+		l159:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 60 HtmlBlockH2 <- (HtmlBlockOpenH2 (HtmlBlockH2 / (!HtmlBlockCloseH2 .))* HtmlBlockCloseH2) */
+		/* 30 ListBlockLine <- (!BlankLine !((Indent? ((&[*+\-] Bullet) | (&[0-9] Enumerator))) / DefMarker) !HorizontalRule OptionallyIndentedLine) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenH2]() {
-				goto l285
+			position0, thunkPosition0 := position, thunkPosition
+			if !p.rules[ruleBlankLine]() {
+				goto l164
 			}
-		l286:
+			goto l163
+		l164:
 			{
-				position287 := position
-				if !p.rules[ruleHtmlBlockH2]() {
-					goto l289
-				}
-				goto l288
-			l289:
-				if !p.rules[ruleHtmlBlockCloseH2]() {
-					goto l290
-				}
-				goto l287
-			l290:
-				if !matchDot() {
-					goto l287
+				position165 := position
+				{
+					position1331 := position
+					if !p.rules[ruleIndent]() {
+						goto l167
+					}
+				l167:
+					{
+						if position == len(p.Buffer) {
+							goto l1332
+						}
+						switch p.Buffer[position] {
+						case '*', '+', '-':
+							if !p.rules[ruleBullet]() {
+								goto l1332
+							}
+							break
+						default:
+							if !p.rules[ruleEnumerator]() {
+								goto l1332
+							}
+						}
+					}
+					goto l1330
+				l1332:
+					position = position1331
+					if !p.rules[ruleDefMarker]() {
+						goto l165
+					}
 				}
-			l288:
-				goto l286
-			l287:
-				position = position287
+			l1330:
+				goto l163
+			l165:
+				position = position165
 			}
-			if !p.rules[ruleHtmlBlockCloseH2]() {
-				goto l285
+			if !p.rules[ruleHorizontalRule]() {
+				goto l170
+			}
+			goto l163
+		l170:
+			if !p.rules[ruleOptionallyIndentedLine]() {
+				goto l163
 			}
 			return true
-		l285:
-			position = position0
+		l163:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 61 HtmlBlockOpenH3 <- ('<' Spnl ((&[H] 'H3') | (&[h] 'h3')) Spnl HtmlAttribute* '>') */
+		/* 31 HtmlBlockOpenScript <- ('<' Spnl ((&[S] 'SCRIPT') | (&[s] 'script')) Spnl HtmlAttribute* '>') */
 		func() bool {
 			position0 := position
 			if !matchChar('<') {
-				goto l291
+				goto l555
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l291
+				goto l555
 			}
 			{
 				if position == len(p.Buffer) {
-					goto l291
+					goto l555
 				}
 				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H3`)
-					if !matchChar('3') {
-						goto l291
+				case 'S':
+					position++
+					if !matchString("CRIPT") {
+						goto l555
 					}
 					break
-				case 'h':
-					position++ // matchString(`h3`)
-					if !matchChar('3') {
-						goto l291
+				case 's':
+					position++
+					if !matchString("cript") {
+						goto l555
 					}
 					break
 				default:
-					goto l291
+					goto l555
 				}
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l291
+				goto l555
 			}
-		l293:
+		l557:
 			if !p.rules[ruleHtmlAttribute]() {
-				goto l294
+				goto l558
 			}
-			goto l293
-		l294:
+			goto l557
+		l558:
 			if !matchChar('>') {
-				goto l291
+				goto l555
 			}
 			return true
-		l291:
+		l555:
 			position = position0
 			return false
 		},
-		/* 62 HtmlBlockCloseH3 <- ('<' Spnl '/' ((&[H] 'H3') | (&[h] 'h3')) Spnl '>') */
+		/* 32 HtmlBlockCloseScript <- ('<' Spnl '/' ((&[S] 'SCRIPT') | (&[s] 'script')) Spnl '>') */
 		func() bool {
 			position0 := position
 			if !matchChar('<') {
-				goto l295
+				goto l559
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l295
+				goto l559
 			}
 			if !matchChar('/') {
-				goto l295
+				goto l559
 			}
 			{
 				if position == len(p.Buffer) {
-					goto l295
+					goto l559
 				}
 				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H3`)
-					if !matchChar('3') {
-						goto l295
+				case 'S':
+					position++
+					if !matchString("CRIPT") {
+						goto l559
 					}
 					break
-				case 'h':
-					position++ // matchString(`h3`)
-					if !matchChar('3') {
-						goto l295
+				case 's':
+					position++
+					if !matchString("cript") {
+						goto l559
 					}
 					break
 				default:
-					goto l295
+					goto l559
 				}
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l295
+				goto l559
 			}
 			if !matchChar('>') {
-				goto l295
+				goto l559
 			}
 			return true
-		l295:
+		l559:
 			position = position0
 			return false
 		},
-		/* 63 HtmlBlockH3 <- (HtmlBlockOpenH3 (HtmlBlockH3 / (!HtmlBlockCloseH3 .))* HtmlBlockCloseH3) */
+		/* 33 HtmlBlockScript <- (HtmlBlockOpenScript (!HtmlBlockCloseScript .)* HtmlBlockCloseScript) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenH3]() {
-				goto l297
+			if !p.rules[ruleHtmlBlockOpenScript]() {
+				goto l561
 			}
-		l298:
+		l562:
 			{
-				position299 := position
-				if !p.rules[ruleHtmlBlockH3]() {
-					goto l301
-				}
-				goto l300
-			l301:
-				if !p.rules[ruleHtmlBlockCloseH3]() {
-					goto l302
+				position563 := position
+				if !p.rules[ruleHtmlBlockCloseScript]() {
+					goto l564
 				}
-				goto l299
-			l302:
+				goto l563
+			l564:
 				if !matchDot() {
-					goto l299
+					goto l563
 				}
-			l300:
-				goto l298
-			l299:
-				position = position299
+				goto l562
+			l563:
+				position = position563
 			}
-			if !p.rules[ruleHtmlBlockCloseH3]() {
-				goto l297
+			if !p.rules[ruleHtmlBlockCloseScript]() {
+				goto l561
 			}
 			return true
-		l297:
+		l561:
 			position = position0
 			return false
 		},
-		/* 64 HtmlBlockOpenH4 <- ('<' Spnl ((&[H] 'H4') | (&[h] 'h4')) Spnl HtmlAttribute* '>') */
+		/* 34 HtmlBlockOpenHead <- ('<' Spnl ((&[H] 'HEAD') | (&[h] 'head')) Spnl HtmlAttribute* '>') */
 		func() bool {
 			position0 := position
 			if !matchChar('<') {
-				goto l303
+				goto l565
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l303
+				goto l565
 			}
 			{
 				if position == len(p.Buffer) {
-					goto l303
+					goto l565
 				}
 				switch p.Buffer[position] {
 				case 'H':
-					position++ // matchString(`H4`)
-					if !matchChar('4') {
-						goto l303
+					position++
+					if !matchString("EAD") {
+						goto l565
 					}
 					break
 				case 'h':
-					position++ // matchString(`h4`)
-					if !matchChar('4') {
-						goto l303
+					position++
+					if !matchString("ead") {
+						goto l565
 					}
 					break
 				default:
-					goto l303
+					goto l565
 				}
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l303
+				goto l565
 			}
-		l305:
+		l567:
 			if !p.rules[ruleHtmlAttribute]() {
-				goto l306
+				goto l568
 			}
-			goto l305
-		l306:
+			goto l567
+		l568:
 			if !matchChar('>') {
-				goto l303
+				goto l565
 			}
 			return true
-		l303:
+		l565:
 			position = position0
 			return false
 		},
-		/* 65 HtmlBlockCloseH4 <- ('<' Spnl '/' ((&[H] 'H4') | (&[h] 'h4')) Spnl '>') */
+		/* 35 HtmlBlockCloseHead <- ('<' Spnl '/' ((&[H] 'HEAD') | (&[h] 'head')) Spnl '>') */
 		func() bool {
 			position0 := position
 			if !matchChar('<') {
-				goto l307
+				goto l569
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l307
+				goto l569
 			}
 			if !matchChar('/') {
-				goto l307
+				goto l569
 			}
 			{
 				if position == len(p.Buffer) {
-					goto l307
+					goto l569
 				}
 				switch p.Buffer[position] {
 				case 'H':
-					position++ // matchString(`H4`)
-					if !matchChar('4') {
-						goto l307
+					position++
+					if !matchString("EAD") {
+						goto l569
 					}
 					break
 				case 'h':
-					position++ // matchString(`h4`)
-					if !matchChar('4') {
-						goto l307
+					position++
+					if !matchString("ead") {
+						goto l569
 					}
 					break
 				default:
-					goto l307
+					goto l569
 				}
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l307
+				goto l569
 			}
 			if !matchChar('>') {
-				goto l307
+				goto l569
 			}
 			return true
-		l307:
+		l569:
 			position = position0
 			return false
 		},
-		/* 66 HtmlBlockH4 <- (HtmlBlockOpenH4 (HtmlBlockH4 / (!HtmlBlockCloseH4 .))* HtmlBlockCloseH4) */
+		/* 36 HtmlBlockHead <- (HtmlBlockOpenHead (!HtmlBlockCloseHead .)* HtmlBlockCloseHead) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenH4]() {
-				goto l309
+			if !p.rules[ruleHtmlBlockOpenHead]() {
+				goto l571
 			}
-		l310:
+		l572:
 			{
-				position311 := position
-				if !p.rules[ruleHtmlBlockH4]() {
-					goto l313
-				}
-				goto l312
-			l313:
-				if !p.rules[ruleHtmlBlockCloseH4]() {
-					goto l314
+				position573 := position
+				if !p.rules[ruleHtmlBlockCloseHead]() {
+					goto l574
 				}
-				goto l311
-			l314:
+				goto l573
+			l574:
 				if !matchDot() {
-					goto l311
+					goto l573
 				}
-			l312:
-				goto l310
-			l311:
-				position = position311
+				goto l572
+			l573:
+				position = position573
 			}
-			if !p.rules[ruleHtmlBlockCloseH4]() {
-				goto l309
+			if !p.rules[ruleHtmlBlockCloseHead]() {
+				goto l571
 			}
 			return true
-		l309:
+		l571:
 			position = position0
 			return false
 		},
-		/* 67 HtmlBlockOpenH5 <- ('<' Spnl ((&[H] 'H5') | (&[h] 'h5')) Spnl HtmlAttribute* '>') */
+		/* 37 HtmlBlockInTags <- (HtmlBlockScript / HtmlBlockHead / (&'<' &{p.htmlBlockInTags(&position)})) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l315
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l315
-			}
+			position0, thunkPosition0 := position, thunkPosition
 			{
-				if position == len(p.Buffer) {
-					goto l315
+				position577, thunkPosition577 := position, thunkPosition
+				if !p.rules[ruleHtmlBlockScript]() {
+					goto l578
 				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H5`)
-					if !matchChar('5') {
-						goto l315
-					}
-					break
-				case 'h':
-					position++ // matchString(`h5`)
-					if !matchChar('5') {
-						goto l315
+				goto l576
+			l578:
+				position, thunkPosition = position577, thunkPosition577
+				if !p.rules[ruleHtmlBlockHead]() {
+					goto l579
+				}
+				goto l576
+			l579:
+				position, thunkPosition = position577, thunkPosition577
+				{
+					position580, thunkPosition580 := position, thunkPosition
+					if !matchChar('<') {
+						goto l575
 					}
-					break
-				default:
-					goto l315
+					position, thunkPosition = position580, thunkPosition580
+				}
+				if !(p.htmlBlockInTags(&position)) {
+					goto l575
 				}
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l315
-			}
-		l317:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l318
-			}
-			goto l317
-		l318:
-			if !matchChar('>') {
-				goto l315
-			}
+		l576:
 			return true
-		l315:
-			position = position0
+		l575:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 68 HtmlBlockCloseH5 <- ('<' Spnl '/' ((&[H] 'H5') | (&[h] 'h5')) Spnl '>') */
+		/* 38 HtmlBlock <- (&'<' < (&{p.commonMarkHTMLBlock(&position)} / HtmlBlockInTags / HtmlComment / HtmlSpecial / HtmlBlockSelfClosing) > BlankLines { yy = p.rawHTML(yytext, HTMLBLOCK) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l319
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l319
-			}
-			if !matchChar('/') {
-				goto l319
-			}
+			position0, thunkPosition0 := position, thunkPosition
 			{
-				if position == len(p.Buffer) {
-					goto l319
-				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H5`)
-					if !matchChar('5') {
-						goto l319
-					}
-					break
-				case 'h':
-					position++ // matchString(`h5`)
-					if !matchChar('5') {
-						goto l319
-					}
-					break
-				default:
-					goto l319
+				position611, thunkPosition611 := position, thunkPosition
+				if !matchChar('<') {
+					goto l610
 				}
+				position, thunkPosition = position611, thunkPosition611
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l319
+			begin = position
+			if !(p.commonMarkHTMLBlock(&position)) {
+				goto l613
 			}
-			if !matchChar('>') {
-				goto l319
+			goto l612
+		l613:
+			if !p.rules[ruleHtmlBlockInTags]() {
+				goto l614
 			}
-			return true
-		l319:
-			position = position0
-			return false
-		},
-		/* 69 HtmlBlockH5 <- (HtmlBlockOpenH5 (HtmlBlockH5 / (!HtmlBlockCloseH5 .))* HtmlBlockCloseH5) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenH5]() {
-				goto l321
+			goto l612
+		l614:
+			if !p.rules[ruleHtmlComment]() {
+				goto l615
 			}
-		l322:
-			{
-				position323 := position
-				if !p.rules[ruleHtmlBlockH5]() {
-					goto l325
-				}
-				goto l324
-			l325:
-				if !p.rules[ruleHtmlBlockCloseH5]() {
-					goto l326
-				}
-				goto l323
-			l326:
-				if !matchDot() {
-					goto l323
-				}
-			l324:
-				goto l322
-			l323:
-				position = position323
+			goto l612
+		l615:
+			if !p.rules[ruleHtmlSpecial]() {
+				goto l1430
+			}
+			goto l612
+		l1430:
+			if !p.rules[ruleHtmlBlockSelfClosing]() {
+				goto l610
 			}
-			if !p.rules[ruleHtmlBlockCloseH5]() {
-				goto l321
+		l612:
+			end = position
+			if !p.rules[ruleBlankLines]() {
+				goto l610
 			}
+			do(41)
 			return true
-		l321:
-			position = position0
+		l610:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 70 HtmlBlockOpenH6 <- ('<' Spnl ((&[H] 'H6') | (&[h] 'h6')) Spnl HtmlAttribute* '>') */
+		/* 39 HtmlBlockSelfClosing <- ('<' Spnl HtmlBlockType Spnl HtmlAttribute* '/' Spnl '>') */
 		func() bool {
 			position0 := position
 			if !matchChar('<') {
-				goto l327
+				goto l616
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l327
+				goto l616
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l327
-				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H6`)
-					if !matchChar('6') {
-						goto l327
-					}
-					break
-				case 'h':
-					position++ // matchString(`h6`)
-					if !matchChar('6') {
-						goto l327
-					}
-					break
-				default:
-					goto l327
-				}
+			if !p.rules[ruleHtmlBlockType]() {
+				goto l616
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l327
+				goto l616
 			}
-		l329:
+		l617:
 			if !p.rules[ruleHtmlAttribute]() {
-				goto l330
+				goto l618
+			}
+			goto l617
+		l618:
+			if !matchChar('/') {
+				goto l616
+			}
+			if !p.rules[ruleSpnl]() {
+				goto l616
 			}
-			goto l329
-		l330:
 			if !matchChar('>') {
-				goto l327
+				goto l616
 			}
 			return true
-		l327:
+		l616:
 			position = position0
 			return false
 		},
-		/* 71 HtmlBlockCloseH6 <- ('<' Spnl '/' ((&[H] 'H6') | (&[h] 'h6')) Spnl '>') */
+		/* 40 HtmlBlockType <- ('dir' / 'div' / 'dl' / 'fieldset' / 'form' / 'h1' / 'h2' / 'h3' / 'h4' / 'h5' / 'h6' / 'noframes' / 'p' / 'table' / 'dd' / 'tbody' / 'td' / 'tfoot' / 'th' / 'thead' / 'DIR' / 'DIV' / 'DL' / 'FIELDSET' / 'FORM' / 'H1' / 'H2' / 'H3' / 'H4' / 'H5' / 'H6' / 'NOFRAMES' / 'P' / 'TABLE' / 'DD' / 'TBODY' / 'TD' / 'TFOOT' / 'TH' / 'THEAD' / ((&[S] 'SCRIPT') | (&[T] 'TR') | (&[L] 'LI') | (&[F] 'FRAMESET') | (&[D] 'DT') | (&[U] 'UL') | (&[P] 'PRE') | (&[O] 'OL') | (&[N] 'NOSCRIPT') | (&[M] 'MENU') | (&[I] 'ISINDEX') | (&[H] 'HR') | (&[C] 'CENTER') | (&[B] 'BLOCKQUOTE') | (&[A] 'ADDRESS') | (&[s] 'script') | (&[t] 'tr') | (&[l] 'li') | (&[f] 'frameset') | (&[d] 'dt') | (&[u] 'ul') | (&[p] 'pre') | (&[o] 'ol') | (&[n] 'noscript') | (&[m] 'menu') | (&[i] 'isindex') | (&[h] 'hr') | (&[c] 'center') | (&[b] 'blockquote') | (&[a] 'address'))) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l331
+			if !matchString("dir") {
+				goto l621
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l331
+			goto l620
+		l621:
+			if !matchString("div") {
+				goto l622
 			}
-			if !matchChar('/') {
-				goto l331
+			goto l620
+		l622:
+			if !matchString("dl") {
+				goto l623
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l331
-				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++ // matchString(`H6`)
-					if !matchChar('6') {
-						goto l331
-					}
-					break
-				case 'h':
-					position++ // matchString(`h6`)
-					if !matchChar('6') {
-						goto l331
-					}
-					break
-				default:
-					goto l331
-				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l331
+			goto l620
+		l623:
+			if !matchString("fieldset") {
+				goto l624
 			}
-			if !matchChar('>') {
-				goto l331
+			goto l620
+		l624:
+			if !matchString("form") {
+				goto l625
 			}
-			return true
-		l331:
-			position = position0
-			return false
-		},
-		/* 72 HtmlBlockH6 <- (HtmlBlockOpenH6 (HtmlBlockH6 / (!HtmlBlockCloseH6 .))* HtmlBlockCloseH6) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenH6]() {
-				goto l333
+			goto l620
+		l625:
+			if !matchString("h1") {
+				goto l626
 			}
-		l334:
-			{
-				position335 := position
-				if !p.rules[ruleHtmlBlockH6]() {
-					goto l337
-				}
-				goto l336
-			l337:
-				if !p.rules[ruleHtmlBlockCloseH6]() {
-					goto l338
-				}
-				goto l335
-			l338:
-				if !matchDot() {
-					goto l335
-				}
-			l336:
-				goto l334
-			l335:
-				position = position335
+			goto l620
+		l626:
+			if !matchString("h2") {
+				goto l627
 			}
-			if !p.rules[ruleHtmlBlockCloseH6]() {
-				goto l333
+			goto l620
+		l627:
+			if !matchString("h3") {
+				goto l628
 			}
-			return true
-		l333:
-			position = position0
-			return false
-		},
-		/* 73 HtmlBlockOpenMenu <- ('<' Spnl ((&[M] 'MENU') | (&[m] 'menu')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l339
+			goto l620
+		l628:
+			if !matchString("h4") {
+				goto l629
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l339
+			goto l620
+		l629:
+			if !matchString("h5") {
+				goto l630
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l339
-				}
-				switch p.Buffer[position] {
-				case 'M':
-					position++
-					if !matchString("ENU") {
-						goto l339
-					}
-					break
-				case 'm':
-					position++
-					if !matchString("enu") {
-						goto l339
-					}
-					break
-				default:
-					goto l339
-				}
+			goto l620
+		l630:
+			if !matchString("h6") {
+				goto l631
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l339
+			goto l620
+		l631:
+			if !matchString("noframes") {
+				goto l632
 			}
-		l341:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l342
+			goto l620
+		l632:
+			if !matchChar('p') {
+				goto l633
 			}
-			goto l341
-		l342:
-			if !matchChar('>') {
-				goto l339
+			goto l620
+		l633:
+			if !matchString("table") {
+				goto l634
 			}
-			return true
-		l339:
-			position = position0
-			return false
-		},
-		/* 74 HtmlBlockCloseMenu <- ('<' Spnl '/' ((&[M] 'MENU') | (&[m] 'menu')) Spnl '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l343
+			goto l620
+		l634:
+			if !matchString("dd") {
+				goto l635
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l343
+			goto l620
+		l635:
+			if !matchString("tbody") {
+				goto l636
 			}
-			if !matchChar('/') {
-				goto l343
+			goto l620
+		l636:
+			if !matchString("td") {
+				goto l637
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l343
-				}
-				switch p.Buffer[position] {
-				case 'M':
-					position++
-					if !matchString("ENU") {
-						goto l343
-					}
-					break
-				case 'm':
-					position++
-					if !matchString("enu") {
-						goto l343
-					}
-					break
-				default:
-					goto l343
-				}
+			goto l620
+		l637:
+			if !matchString("tfoot") {
+				goto l638
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l343
+			goto l620
+		l638:
+			if !matchString("th") {
+				goto l639
 			}
-			if !matchChar('>') {
-				goto l343
+			goto l620
+		l639:
+			if !matchString("thead") {
+				goto l640
 			}
-			return true
-		l343:
-			position = position0
-			return false
-		},
-		/* 75 HtmlBlockMenu <- (HtmlBlockOpenMenu (HtmlBlockMenu / (!HtmlBlockCloseMenu .))* HtmlBlockCloseMenu) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenMenu]() {
-				goto l345
+			goto l620
+		l640:
+			if !matchString("DIR") {
+				goto l641
 			}
-		l346:
-			{
-				position347 := position
-				if !p.rules[ruleHtmlBlockMenu]() {
-					goto l349
-				}
-				goto l348
-			l349:
-				if !p.rules[ruleHtmlBlockCloseMenu]() {
-					goto l350
-				}
-				goto l347
-			l350:
-				if !matchDot() {
-					goto l347
-				}
-			l348:
-				goto l346
-			l347:
-				position = position347
+			goto l620
+		l641:
+			if !matchString("DIV") {
+				goto l642
 			}
-			if !p.rules[ruleHtmlBlockCloseMenu]() {
-				goto l345
+			goto l620
+		l642:
+			if !matchString("DL") {
+				goto l643
 			}
-			return true
-		l345:
-			position = position0
-			return false
-		},
-		/* 76 HtmlBlockOpenNoframes <- ('<' Spnl ((&[N] 'NOFRAMES') | (&[n] 'noframes')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l351
+			goto l620
+		l643:
+			if !matchString("FIELDSET") {
+				goto l644
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l351
+			goto l620
+		l644:
+			if !matchString("FORM") {
+				goto l645
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l351
-				}
-				switch p.Buffer[position] {
-				case 'N':
-					position++
-					if !matchString("OFRAMES") {
-						goto l351
-					}
-					break
-				case 'n':
-					position++
-					if !matchString("oframes") {
-						goto l351
-					}
-					break
-				default:
-					goto l351
-				}
+			goto l620
+		l645:
+			if !matchString("H1") {
+				goto l646
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l351
+			goto l620
+		l646:
+			if !matchString("H2") {
+				goto l647
 			}
-		l353:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l354
+			goto l620
+		l647:
+			if !matchString("H3") {
+				goto l648
 			}
-			goto l353
-		l354:
-			if !matchChar('>') {
-				goto l351
+			goto l620
+		l648:
+			if !matchString("H4") {
+				goto l649
 			}
-			return true
-		l351:
-			position = position0
-			return false
-		},
-		/* 77 HtmlBlockCloseNoframes <- ('<' Spnl '/' ((&[N] 'NOFRAMES') | (&[n] 'noframes')) Spnl '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l355
+			goto l620
+		l649:
+			if !matchString("H5") {
+				goto l650
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l355
+			goto l620
+		l650:
+			if !matchString("H6") {
+				goto l651
 			}
-			if !matchChar('/') {
-				goto l355
+			goto l620
+		l651:
+			if !matchString("NOFRAMES") {
+				goto l652
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l355
-				}
-				switch p.Buffer[position] {
-				case 'N':
-					position++
-					if !matchString("OFRAMES") {
-						goto l355
-					}
-					break
-				case 'n':
-					position++
-					if !matchString("oframes") {
-						goto l355
-					}
-					break
-				default:
-					goto l355
-				}
+			goto l620
+		l652:
+			if !matchChar('P') {
+				goto l653
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l355
+			goto l620
+		l653:
+			if !matchString("TABLE") {
+				goto l654
 			}
-			if !matchChar('>') {
-				goto l355
+			goto l620
+		l654:
+			if !matchString("DD") {
+				goto l655
 			}
-			return true
-		l355:
-			position = position0
-			return false
-		},
-		/* 78 HtmlBlockNoframes <- (HtmlBlockOpenNoframes (HtmlBlockNoframes / (!HtmlBlockCloseNoframes .))* HtmlBlockCloseNoframes) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenNoframes]() {
-				goto l357
+			goto l620
+		l655:
+			if !matchString("TBODY") {
+				goto l656
 			}
-		l358:
-			{
-				position359 := position
-				if !p.rules[ruleHtmlBlockNoframes]() {
-					goto l361
-				}
-				goto l360
-			l361:
-				if !p.rules[ruleHtmlBlockCloseNoframes]() {
-					goto l362
-				}
-				goto l359
-			l362:
-				if !matchDot() {
-					goto l359
-				}
-			l360:
-				goto l358
-			l359:
-				position = position359
+			goto l620
+		l656:
+			if !matchString("TD") {
+				goto l657
 			}
-			if !p.rules[ruleHtmlBlockCloseNoframes]() {
-				goto l357
+			goto l620
+		l657:
+			if !matchString("TFOOT") {
+				goto l658
 			}
-			return true
-		l357:
-			position = position0
-			return false
-		},
-		/* 79 HtmlBlockOpenNoscript <- ('<' Spnl ((&[N] 'NOSCRIPT') | (&[n] 'noscript')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l363
+			goto l620
+		l658:
+			if !matchString("TH") {
+				goto l659
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l363
+			goto l620
+		l659:
+			if !matchString("THEAD") {
+				goto l660
 			}
+			goto l620
+		l660:
 			{
 				if position == len(p.Buffer) {
-					goto l363
+					goto l619
 				}
 				switch p.Buffer[position] {
+				case 'S':
+					position++
+					if !matchString("CRIPT") {
+						goto l619
+					}
+					break
+				case 'T':
+					position++ // matchString(`TR`)
+					if !matchChar('R') {
+						goto l619
+					}
+					break
+				case 'L':
+					position++ // matchString(`LI`)
+					if !matchChar('I') {
+						goto l619
+					}
+					break
+				case 'F':
+					position++
+					if !matchString("RAMESET") {
+						goto l619
+					}
+					break
+				case 'D':
+					position++ // matchString(`DT`)
+					if !matchChar('T') {
+						goto l619
+					}
+					break
+				case 'U':
+					position++ // matchString(`UL`)
+					if !matchChar('L') {
+						goto l619
+					}
+					break
+				case 'P':
+					position++
+					if !matchString("RE") {
+						goto l619
+					}
+					break
+				case 'O':
+					position++ // matchString(`OL`)
+					if !matchChar('L') {
+						goto l619
+					}
+					break
 				case 'N':
 					position++
 					if !matchString("OSCRIPT") {
-						goto l363
+						goto l619
+					}
+					break
+				case 'M':
+					position++
+					if !matchString("ENU") {
+						goto l619
+					}
+					break
+				case 'I':
+					position++
+					if !matchString("SINDEX") {
+						goto l619
+					}
+					break
+				case 'H':
+					position++ // matchString(`HR`)
+					if !matchChar('R') {
+						goto l619
+					}
+					break
+				case 'C':
+					position++
+					if !matchString("ENTER") {
+						goto l619
+					}
+					break
+				case 'B':
+					position++
+					if !matchString("LOCKQUOTE") {
+						goto l619
+					}
+					break
+				case 'A':
+					position++
+					if !matchString("DDRESS") {
+						goto l619
+					}
+					break
+				case 's':
+					position++
+					if !matchString("cript") {
+						goto l619
+					}
+					break
+				case 't':
+					position++ // matchString(`tr`)
+					if !matchChar('r') {
+						goto l619
+					}
+					break
+				case 'l':
+					position++ // matchString(`li`)
+					if !matchChar('i') {
+						goto l619
+					}
+					break
+				case 'f':
+					position++
+					if !matchString("rameset") {
+						goto l619
+					}
+					break
+				case 'd':
+					position++ // matchString(`dt`)
+					if !matchChar('t') {
+						goto l619
+					}
+					break
+				case 'u':
+					position++ // matchString(`ul`)
+					if !matchChar('l') {
+						goto l619
+					}
+					break
+				case 'p':
+					position++
+					if !matchString("re") {
+						goto l619
+					}
+					break
+				case 'o':
+					position++ // matchString(`ol`)
+					if !matchChar('l') {
+						goto l619
 					}
 					break
 				case 'n':
 					position++
 					if !matchString("oscript") {
-						goto l363
+						goto l619
+					}
+					break
+				case 'm':
+					position++
+					if !matchString("enu") {
+						goto l619
+					}
+					break
+				case 'i':
+					position++
+					if !matchString("sindex") {
+						goto l619
+					}
+					break
+				case 'h':
+					position++ // matchString(`hr`)
+					if !matchChar('r') {
+						goto l619
+					}
+					break
+				case 'c':
+					position++
+					if !matchString("enter") {
+						goto l619
+					}
+					break
+				case 'b':
+					position++
+					if !matchString("lockquote") {
+						goto l619
+					}
+					break
+				case 'a':
+					position++
+					if !matchString("ddress") {
+						goto l619
 					}
 					break
 				default:
-					goto l363
+					goto l619
 				}
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l363
-			}
-		l365:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l366
-			}
-			goto l365
-		l366:
-			if !matchChar('>') {
-				goto l363
-			}
+		l620:
 			return true
-		l363:
-			position = position0
+		l619:
 			return false
 		},
-		/* 80 HtmlBlockCloseNoscript <- ('<' Spnl '/' ((&[N] 'NOSCRIPT') | (&[n] 'noscript')) Spnl '>') */
+		/* 41 StyleOpen <- ('<' Spnl ((&[S] 'STYLE') | (&[s] 'style')) Spnl HtmlAttribute* '>') */
 		func() bool {
 			position0 := position
 			if !matchChar('<') {
-				goto l367
+				goto l662
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l367
-			}
-			if !matchChar('/') {
-				goto l367
+				goto l662
 			}
 			{
 				if position == len(p.Buffer) {
-					goto l367
+					goto l662
 				}
 				switch p.Buffer[position] {
-				case 'N':
+				case 'S':
 					position++
-					if !matchString("OSCRIPT") {
-						goto l367
+					if !matchString("TYLE") {
+						goto l662
 					}
 					break
-				case 'n':
+				case 's':
 					position++
-					if !matchString("oscript") {
-						goto l367
+					if !matchString("tyle") {
+						goto l662
 					}
 					break
 				default:
-					goto l367
+					goto l662
 				}
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l367
+				goto l662
 			}
+		l664:
+			if !p.rules[ruleHtmlAttribute]() {
+				goto l665
+			}
+			goto l664
+		l665:
 			if !matchChar('>') {
-				goto l367
+				goto l662
 			}
 			return true
-		l367:
+		l662:
 			position = position0
 			return false
 		},
-		/* 81 HtmlBlockNoscript <- (HtmlBlockOpenNoscript (HtmlBlockNoscript / (!HtmlBlockCloseNoscript .))* HtmlBlockCloseNoscript) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenNoscript]() {
-				goto l369
-			}
-		l370:
-			{
-				position371 := position
-				if !p.rules[ruleHtmlBlockNoscript]() {
-					goto l373
-				}
-				goto l372
-			l373:
-				if !p.rules[ruleHtmlBlockCloseNoscript]() {
-					goto l374
-				}
-				goto l371
-			l374:
-				if !matchDot() {
-					goto l371
-				}
-			l372:
-				goto l370
-			l371:
-				position = position371
-			}
-			if !p.rules[ruleHtmlBlockCloseNoscript]() {
-				goto l369
-			}
-			return true
-		l369:
-			position = position0
-			return false
-		},
-		/* 82 HtmlBlockOpenOl <- ('<' Spnl ((&[O] 'OL') | (&[o] 'ol')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l375
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l375
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l375
-				}
-				switch p.Buffer[position] {
-				case 'O':
-					position++ // matchString(`OL`)
-					if !matchChar('L') {
-						goto l375
-					}
-					break
-				case 'o':
-					position++ // matchString(`ol`)
-					if !matchChar('l') {
-						goto l375
-					}
-					break
-				default:
-					goto l375
-				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l375
-			}
-		l377:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l378
-			}
-			goto l377
-		l378:
-			if !matchChar('>') {
-				goto l375
-			}
-			return true
-		l375:
-			position = position0
-			return false
-		},
-		/* 83 HtmlBlockCloseOl <- ('<' Spnl '/' ((&[O] 'OL') | (&[o] 'ol')) Spnl '>') */
+		/* 42 StyleClose <- ('<' Spnl '/' ((&[S] 'STYLE') | (&[s] 'style')) Spnl '>') */
 		func() bool {
 			position0 := position
 			if !matchChar('<') {
-				goto l379
+				goto l666
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l379
+				goto l666
 			}
 			if !matchChar('/') {
-				goto l379
+				goto l666
 			}
 			{
 				if position == len(p.Buffer) {
-					goto l379
+					goto l666
 				}
 				switch p.Buffer[position] {
-				case 'O':
-					position++ // matchString(`OL`)
-					if !matchChar('L') {
-						goto l379
+				case 'S':
+					position++
+					if !matchString("TYLE") {
+						goto l666
 					}
 					break
-				case 'o':
-					position++ // matchString(`ol`)
-					if !matchChar('l') {
-						goto l379
+				case 's':
+					position++
+					if !matchString("tyle") {
+						goto l666
 					}
 					break
 				default:
-					goto l379
+					goto l666
 				}
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l379
+				goto l666
 			}
 			if !matchChar('>') {
-				goto l379
+				goto l666
 			}
 			return true
-		l379:
+		l666:
 			position = position0
 			return false
 		},
-		/* 84 HtmlBlockOl <- (HtmlBlockOpenOl (HtmlBlockOl / (!HtmlBlockCloseOl .))* HtmlBlockCloseOl) */
+		/* 43 InStyleTags <- (StyleOpen (!StyleClose .)* StyleClose) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenOl]() {
-				goto l381
+			if !p.rules[ruleStyleOpen]() {
+				goto l668
 			}
-		l382:
+		l669:
 			{
-				position383 := position
-				if !p.rules[ruleHtmlBlockOl]() {
-					goto l385
-				}
-				goto l384
-			l385:
-				if !p.rules[ruleHtmlBlockCloseOl]() {
-					goto l386
+				position670 := position
+				if !p.rules[ruleStyleClose]() {
+					goto l671
 				}
-				goto l383
-			l386:
+				goto l670
+			l671:
 				if !matchDot() {
-					goto l383
+					goto l670
 				}
-			l384:
-				goto l382
-			l383:
-				position = position383
+				goto l669
+			l670:
+				position = position670
 			}
-			if !p.rules[ruleHtmlBlockCloseOl]() {
-				goto l381
+			if !p.rules[ruleStyleClose]() {
+				goto l668
 			}
 			return true
-		l381:
+		l668:
 			position = position0
 			return false
 		},
-		/* 85 HtmlBlockOpenP <- ('<' Spnl ((&[P] 'P') | (&[p] 'p')) Spnl HtmlAttribute* '>') */
+		/* 44 StyleBlock <- (< InStyleTags > BlankLine* {   if p.extension.FilterStyles {
+                        yy = p.mkList(LIST, nil)
+                    } else {
+                        yy = p.mkString(yytext)
+                        yy.key = HTMLBLOCK
+                    }
+                }) */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l387
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l387
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l387
-				}
-				switch p.Buffer[position] {
-				case 'P':
-					position++ // matchChar
-					break
-				case 'p':
-					position++ // matchChar
-					break
-				default:
-					goto l387
-				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l387
-			}
-		l389:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l390
+			begin = position
+			if !p.rules[ruleInStyleTags]() {
+				goto l672
 			}
-			goto l389
-		l390:
-			if !matchChar('>') {
-				goto l387
+			end = position
+		l673:
+			if !p.rules[ruleBlankLine]() {
+				goto l674
 			}
+			goto l673
+		l674:
+			do(42)
 			return true
-		l387:
+		l672:
 			position = position0
 			return false
 		},
-		/* 86 HtmlBlockCloseP <- ('<' Spnl '/' ((&[P] 'P') | (&[p] 'p')) Spnl '>') */
+		/* 45 Inlines <- (StartList ((!Endline Inline { a = cons(yy, a) }) / (Endline &Inline { a = cons(c, a) }))+ Endline? { yy = p.mkList(LIST, a) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l391
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l391
-			}
-			if !matchChar('/') {
-				goto l391
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleStartList]() {
+				goto l675
 			}
+			doarg(yySet, -1)
 			{
-				if position == len(p.Buffer) {
-					goto l391
-				}
-				switch p.Buffer[position] {
-				case 'P':
-					position++ // matchChar
-					break
-				case 'p':
-					position++ // matchChar
-					break
-				default:
-					goto l391
+				position678 := position
+				if !p.rules[ruleEndline]() {
+					goto l680
 				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l391
-			}
-			if !matchChar('>') {
-				goto l391
-			}
-			return true
-		l391:
-			position = position0
-			return false
-		},
-		/* 87 HtmlBlockP <- (HtmlBlockOpenP (HtmlBlockP / (!HtmlBlockCloseP .))* HtmlBlockCloseP) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenP]() {
-				goto l393
-			}
-		l394:
-			{
-				position395 := position
-				if !p.rules[ruleHtmlBlockP]() {
-					goto l397
+				goto l679
+			l680:
+				if !p.rules[ruleInline]() {
+					goto l679
 				}
-				goto l396
-			l397:
-				if !p.rules[ruleHtmlBlockCloseP]() {
-					goto l398
+				do(43)
+				goto l678
+			l679:
+				position = position678
+				if !p.rules[ruleEndline]() {
+					goto l675
 				}
-				goto l395
-			l398:
-				if !matchDot() {
-					goto l395
+				doarg(yySet, -2)
+				{
+					position681 := position
+					if !p.rules[ruleInline]() {
+						goto l675
+					}
+					position = position681
 				}
-			l396:
-				goto l394
-			l395:
-				position = position395
-			}
-			if !p.rules[ruleHtmlBlockCloseP]() {
-				goto l393
-			}
-			return true
-		l393:
-			position = position0
-			return false
-		},
-		/* 88 HtmlBlockOpenPre <- ('<' Spnl ((&[P] 'PRE') | (&[p] 'pre')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l399
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l399
+				do(44)
 			}
+		l678:
+		l676:
 			{
-				if position == len(p.Buffer) {
-					goto l399
-				}
-				switch p.Buffer[position] {
-				case 'P':
-					position++
-					if !matchString("RE") {
-						goto l399
+				position677, thunkPosition677 := position, thunkPosition
+				{
+					position682 := position
+					if !p.rules[ruleEndline]() {
+						goto l684
 					}
-					break
-				case 'p':
-					position++
-					if !matchString("re") {
-						goto l399
+					goto l683
+				l684:
+					if !p.rules[ruleInline]() {
+						goto l683
 					}
-					break
-				default:
-					goto l399
+					do(43)
+					goto l682
+				l683:
+					position = position682
+					if !p.rules[ruleEndline]() {
+						goto l677
+					}
+					doarg(yySet, -2)
+					{
+						position685 := position
+						if !p.rules[ruleInline]() {
+							goto l677
+						}
+						position = position685
+					}
+					do(44)
 				}
+			l682:
+				goto l676
+			l677:
+				position, thunkPosition = position677, thunkPosition677
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l399
-			}
-		l401:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l402
-			}
-			goto l401
-		l402:
-			if !matchChar('>') {
-				goto l399
+			if !p.rules[ruleEndline]() {
+				goto l686
 			}
+		l686:
+			do(45)
+			doarg(yyPop, 2)
 			return true
-		l399:
-			position = position0
+		l675:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 89 HtmlBlockClosePre <- ('<' Spnl '/' ((&[P] 'PRE') | (&[p] 'pre')) Spnl '>') */
+		/* 46 Inline <- (LineTail / ExtInline / Str / Endline / UlOrStarLine / Space / Strong / Emph / Mark / Critic / Image / TableRef / Hashtag / Placeholder / Citation / Comment / Link / NoteReference / InlineNote / Code / RawHtml / Entity / EscapedChar / Smart / Symbol) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l403
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l403
+			if !p.rules[ruleLineTail]() {
+				goto l690
 			}
-			if !matchChar('/') {
-				goto l403
+			goto l689
+		l690:
+			if !p.rules[ruleExtInline]() {
+				goto l691
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l403
-				}
-				switch p.Buffer[position] {
-				case 'P':
-					position++
-					if !matchString("RE") {
-						goto l403
-					}
-					break
-				case 'p':
-					position++
-					if !matchString("re") {
-						goto l403
-					}
-					break
-				default:
-					goto l403
-				}
+			goto l689
+		l691:
+			if !p.rules[ruleStr]() {
+				goto l692
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l403
+			goto l689
+		l692:
+			if !p.rules[ruleEndline]() {
+				goto l693
 			}
-			if !matchChar('>') {
-				goto l403
+			goto l689
+		l693:
+			if !p.rules[ruleUlOrStarLine]() {
+				goto l694
+			}
+			goto l689
+		l694:
+			if !p.rules[ruleSpace]() {
+				goto l695
+			}
+			goto l689
+		l695:
+			if !p.rules[ruleStrong]() {
+				goto l696
+			}
+			goto l689
+		l696:
+			if !p.rules[ruleEmph]() {
+				goto l697
+			}
+			goto l689
+		l697:
+			if !p.rules[ruleMark]() {
+				goto l698
+			}
+			goto l689
+		l698:
+			if !p.rules[ruleCritic]() {
+				goto l699
+			}
+			goto l689
+		l699:
+			if !p.rules[ruleImage]() {
+				goto l700
+			}
+			goto l689
+		l700:
+			if !p.rules[ruleTableRef]() {
+				goto l701
+			}
+			goto l689
+		l701:
+			if !p.rules[ruleHashtag]() {
+				goto l702
+			}
+			goto l689
+		l702:
+			if !p.rules[rulePlaceholder]() {
+				goto l703
+			}
+			goto l689
+		l703:
+			if !p.rules[ruleCitation]() {
+				goto l704
+			}
+			goto l689
+		l704:
+			if !p.rules[ruleComment]() {
+				goto l1361
+			}
+			goto l689
+		l1361:
+			if !p.rules[ruleLink]() {
+				goto l1376
+			}
+			goto l689
+		l1376:
+			if !p.rules[ruleNoteReference]() {
+				goto l1433
+			}
+			goto l689
+		l1433:
+			if !p.rules[ruleInlineNote]() {
+				goto l1445
+			}
+			goto l689
+		l1445:
+			if !p.rules[ruleCode]() {
+				goto l1480
+			}
+			goto l689
+		l1480:
+			if !p.rules[ruleRawHtml]() {
+				goto l1481
+			}
+			goto l689
+		l1481:
+			if !p.rules[ruleEntity]() {
+				goto l1514
+			}
+			goto l689
+		l1514:
+			if !p.rules[ruleEscapedChar]() {
+				goto l1521
+			}
+			goto l689
+		l1521:
+			if !p.rules[ruleSmart]() {
+				goto l1527
+			}
+			goto l689
+		l1527:
+			if !p.rules[ruleSymbol]() {
+				goto l688
 			}
+		l689:
 			return true
-		l403:
-			position = position0
+		l688:
 			return false
 		},
-		/* 90 HtmlBlockPre <- (HtmlBlockOpenPre (HtmlBlockPre / (!HtmlBlockClosePre .))* HtmlBlockClosePre) */
+		/* 47 Space <- (Spacechar+ { yy = p.mkString(" ")
+          yy.key = SPACE }) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenPre]() {
-				goto l405
-			}
-		l406:
-			{
-				position407 := position
-				if !p.rules[ruleHtmlBlockPre]() {
-					goto l409
-				}
-				goto l408
-			l409:
-				if !p.rules[ruleHtmlBlockClosePre]() {
-					goto l410
-				}
-				goto l407
-			l410:
-				if !matchDot() {
-					goto l407
-				}
-			l408:
-				goto l406
-			l407:
-				position = position407
+			if !p.rules[ruleSpacechar]() {
+				goto l705
 			}
-			if !p.rules[ruleHtmlBlockClosePre]() {
-				goto l405
+		l706:
+			if !p.rules[ruleSpacechar]() {
+				goto l707
 			}
+			goto l706
+		l707:
+			do(46)
 			return true
-		l405:
+		l705:
 			position = position0
 			return false
 		},
-		/* 91 HtmlBlockOpenTable <- ('<' Spnl ((&[T] 'TABLE') | (&[t] 'table')) Spnl HtmlAttribute* '>') */
+		/* 48 Str <- (StartList < NormalChars > { a = cons(p.mkString(yytext), a) } (StrChunk { a = cons(yy, a) })* { if a.next == nil { yy = a; } else { yy = p.mkList(LIST, a) } }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l411
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l708
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l411
+			doarg(yySet, -1)
+			begin = position
+			if !p.rules[ruleNormalChars]() {
+				goto l708
 			}
+			end = position
+			do(47)
+		l709:
 			{
-				if position == len(p.Buffer) {
-					goto l411
+				position710, thunkPosition710 := position, thunkPosition
+				if !p.rules[ruleStrChunk]() {
+					goto l710
 				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++
-					if !matchString("ABLE") {
-						goto l411
+				do(48)
+				goto l709
+			l710:
+				position, thunkPosition = position710, thunkPosition710
+			}
+			do(49)
+			doarg(yyPop, 1)
+			return true
+		l708:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 49 StrChunk <- ((< (NormalChars / ('_'+ &Alphanumeric))+ > { yy = p.mkString(yytext) }) / AposChunk) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			{
+				position715, thunkPosition715 := position, thunkPosition
+				begin = position
+				{
+					position718, thunkPosition718 := position, thunkPosition
+					if !p.rules[ruleNormalChars]() {
+						goto l719
 					}
-					break
-				case 't':
-					position++
-					if !matchString("able") {
-						goto l411
+					goto l717
+				l719:
+					position, thunkPosition = position718, thunkPosition718
+					if !matchChar('_') {
+						goto l716
+					}
+				l720:
+					if !matchChar('_') {
+						goto l721
+					}
+					goto l720
+				l721:
+					{
+						position723, thunkPosition723 := position, thunkPosition
+						if !p.rules[ruleAlphanumeric]() {
+							goto l716
+						}
+						position, thunkPosition = position723, thunkPosition723
 					}
-					break
-				default:
-					goto l411
+				}
+			l717:
+			l724:
+				{
+					position725, thunkPosition725 := position, thunkPosition
+					{
+						position1502, thunkPosition1502 := position, thunkPosition
+						if !p.rules[ruleNormalChars]() {
+							goto l1503
+						}
+						goto l726
+					l1503:
+						position, thunkPosition = position1502, thunkPosition1502
+						if !matchChar('_') {
+							goto l725
+						}
+					l1504:
+						if !matchChar('_') {
+							goto l1505
+						}
+						goto l1504
+					l1505:
+						{
+							position1506, thunkPosition1506 := position, thunkPosition
+							if !p.rules[ruleAlphanumeric]() {
+								goto l725
+							}
+							position, thunkPosition = position1506, thunkPosition1506
+						}
+					}
+				l726:
+					goto l724
+				l725:
+					position, thunkPosition = position725, thunkPosition725
+				}
+				end = position
+				do(50)
+				goto l714
+			l716:
+				position, thunkPosition = position715, thunkPosition715
+				if !p.rules[ruleAposChunk]() {
+					goto l713
 				}
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l411
-			}
-		l413:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l414
-			}
-			goto l413
-		l414:
-			if !matchChar('>') {
-				goto l411
-			}
+		l714:
 			return true
-		l411:
-			position = position0
+		l713:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 92 HtmlBlockCloseTable <- ('<' Spnl '/' ((&[T] 'TABLE') | (&[t] 'table')) Spnl '>') */
+		/* 50 AposChunk <- (&{p.extension.Smart && !p.prime(position)} '\'' &Alphanumeric { yy = p.mkElem(APOSTROPHE) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l415
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l415
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.Smart && !p.prime(position)) {
+				goto l728
 			}
-			if !matchChar('/') {
-				goto l415
+			if !matchChar('\'') {
+				goto l728
 			}
 			{
-				if position == len(p.Buffer) {
-					goto l415
-				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++
-					if !matchString("ABLE") {
-						goto l415
-					}
-					break
-				case 't':
-					position++
-					if !matchString("able") {
-						goto l415
-					}
-					break
-				default:
-					goto l415
+				position1456, thunkPosition1456 := position, thunkPosition
+				if !p.rules[ruleAlphanumeric]() {
+					goto l728
 				}
+				position, thunkPosition = position1456, thunkPosition1456
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l415
-			}
-			if !matchChar('>') {
-				goto l415
-			}
+			do(51)
 			return true
-		l415:
-			position = position0
+		l728:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 93 HtmlBlockTable <- (HtmlBlockOpenTable (HtmlBlockTable / (!HtmlBlockCloseTable .))* HtmlBlockCloseTable) */
+		/* 51 EscapedChar <- ('\\' !Newline < [-\\`|*_{}[\]()#+.!><] > { yy = p.mkString(yytext) }) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenTable]() {
-				goto l417
+			if !matchChar('\\') {
+				goto l730
 			}
-		l418:
-			{
-				position419 := position
-				if !p.rules[ruleHtmlBlockTable]() {
-					goto l421
-				}
-				goto l420
-			l421:
-				if !p.rules[ruleHtmlBlockCloseTable]() {
-					goto l422
-				}
-				goto l419
-			l422:
-				if !matchDot() {
-					goto l419
-				}
-			l420:
-				goto l418
-			l419:
-				position = position419
+			if !p.rules[ruleNewline]() {
+				goto l731
 			}
-			if !p.rules[ruleHtmlBlockCloseTable]() {
-				goto l417
+			goto l730
+		l731:
+			begin = position
+			if !matchClass(1) {
+				goto l730
 			}
+			end = position
+			do(52)
 			return true
-		l417:
+		l730:
 			position = position0
 			return false
 		},
-		/* 94 HtmlBlockOpenUl <- ('<' Spnl ((&[U] 'UL') | (&[u] 'ul')) Spnl HtmlAttribute* '>') */
+		/* 52 Entity <- ((HexEntity / DecEntity / CharEntity) { yy = p.mkEntity(yytext) }) */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l423
+			if !p.rules[ruleHexEntity]() {
+				goto l734
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l423
+			goto l733
+		l734:
+			if !p.rules[ruleDecEntity]() {
+				goto l735
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l423
-				}
-				switch p.Buffer[position] {
-				case 'U':
-					position++ // matchString(`UL`)
-					if !matchChar('L') {
-						goto l423
-					}
-					break
-				case 'u':
-					position++ // matchString(`ul`)
-					if !matchChar('l') {
-						goto l423
-					}
-					break
-				default:
-					goto l423
-				}
+			goto l733
+		l735:
+			if !p.rules[ruleCharEntity]() {
+				goto l732
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l423
+		l733:
+			do(53)
+			return true
+		l732:
+			position = position0
+			return false
+		},
+		/* 53 Endline <- (LineBreak / TerminalEndline / NormalEndline) */
+		func() bool {
+			if !p.rules[ruleLineBreak]() {
+				goto l738
 			}
-		l425:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l426
+			goto l737
+		l738:
+			if !p.rules[ruleTerminalEndline]() {
+				goto l739
 			}
-			goto l425
-		l426:
-			if !matchChar('>') {
-				goto l423
+			goto l737
+		l739:
+			if !p.rules[ruleNormalEndline]() {
+				goto l736
 			}
+		l737:
 			return true
-		l423:
-			position = position0
+		l736:
 			return false
 		},
-		/* 95 HtmlBlockCloseUl <- ('<' Spnl '/' ((&[U] 'UL') | (&[u] 'ul')) Spnl '>') */
+		/* 54 NormalEndline <- (Sp Newline !BlankLines !'>' !AtxStart !(&{p.setextSplits()} Line (SetextBottom1 / SetextBottom2)) !(&{!p.setextSplits()} &(NonindentSpace '-') HorizontalRule) &{!p.htmlBlockInterrupts(position)} { if p.extension.HardWraps {
+                        yy = p.mkElem(LINEBREAK)
+                    } else {
+                        yy = p.mkString("\n")
+                        yy.key = SPACE
+                    } }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l427
+			position0, thunkPosition0 := position, thunkPosition
+			if !p.rules[ruleSp]() {
+				goto l740
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l427
+			if !p.rules[ruleNewline]() {
+				goto l740
 			}
-			if !matchChar('/') {
-				goto l427
+			if !p.rules[ruleBlankLines]() {
+				goto l741
+			}
+			goto l740
+		l741:
+			if !matchChar('>') {
+				goto l742
+			}
+			goto l740
+		l742:
+			if !p.rules[ruleAtxStart]() {
+				goto l743
 			}
+			goto l740
+		l743:
 			{
-				if position == len(p.Buffer) {
-					goto l427
+				position1453, thunkPosition1453 := position, thunkPosition
+				if !(p.setextSplits()) {
+					goto l1597
 				}
-				switch p.Buffer[position] {
-				case 'U':
-					position++ // matchString(`UL`)
-					if !matchChar('L') {
-						goto l427
-					}
-					break
-				case 'u':
-					position++ // matchString(`ul`)
-					if !matchChar('l') {
-						goto l427
-					}
-					break
-				default:
-					goto l427
+				if !p.rules[ruleLine]() {
+					goto l1597
 				}
+				if !p.rules[ruleSetextBottom1]() {
+					goto l1600
+				}
+				goto l1598
+			l1600:
+				if !p.rules[ruleSetextBottom2]() {
+					goto l1597
+				}
+			l1598:
+				goto l740
+			l1597:
+				position, thunkPosition = position1453, thunkPosition1453
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l427
-			}
-			if !matchChar('>') {
-				goto l427
-			}
-			return true
-		l427:
-			position = position0
-			return false
-		},
-		/* 96 HtmlBlockUl <- (HtmlBlockOpenUl (HtmlBlockUl / (!HtmlBlockCloseUl .))* HtmlBlockCloseUl) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenUl]() {
-				goto l429
-			}
-		l430:
 			{
-				position431 := position
-				if !p.rules[ruleHtmlBlockUl]() {
-					goto l433
+				position1623, thunkPosition1623 := position, thunkPosition
+				if !(!p.setextSplits()) {
+					goto l1624
 				}
-				goto l432
-			l433:
-				if !p.rules[ruleHtmlBlockCloseUl]() {
-					goto l434
+				{
+					position1625, thunkPosition1625 := position, thunkPosition
+					if !p.rules[ruleNonindentSpace]() {
+						goto l1624
+					}
+					if !matchChar('-') {
+						goto l1624
+					}
+					position, thunkPosition = position1625, thunkPosition1625
 				}
-				goto l431
-			l434:
-				if !matchDot() {
-					goto l431
+				if !p.rules[ruleHorizontalRule]() {
+					goto l1624
 				}
-			l432:
-				goto l430
-			l431:
-				position = position431
+				goto l740
+			l1624:
+				position, thunkPosition = position1623, thunkPosition1623
 			}
-			if !p.rules[ruleHtmlBlockCloseUl]() {
-				goto l429
+			if !(!p.htmlBlockInterrupts(position)) {
+				goto l740
 			}
+			do(54)
 			return true
-		l429:
-			position = position0
+		l740:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 97 HtmlBlockOpenDd <- ('<' Spnl ((&[D] 'DD') | (&[d] 'dd')) Spnl HtmlAttribute* '>') */
+		/* 55 TerminalEndline <- (Sp Newline Eof &{!p.looseEnd} { yy = nil }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l435
+			position0, thunkPosition0 := position, thunkPosition
+			if !p.rules[ruleSp]() {
+				goto l749
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l435
+			if !p.rules[ruleNewline]() {
+				goto l749
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l435
-				}
-				switch p.Buffer[position] {
-				case 'D':
-					position++ // matchString(`DD`)
-					if !matchChar('D') {
-						goto l435
-					}
-					break
-				case 'd':
-					position++ // matchString(`dd`)
-					if !matchChar('d') {
-						goto l435
-					}
-					break
-				default:
-					goto l435
-				}
+			if !p.rules[ruleEof]() {
+				goto l749
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l435
+			if !(!p.looseEnd) {
+				goto l749
 			}
-		l437:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l438
+			do(55)
+			return true
+		l749:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 56 LineBreak <- ('  ' NormalEndline { yy = p.mkElem(LINEBREAK) }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			if !matchString("  ") {
+				goto l750
 			}
-			goto l437
-		l438:
-			if !matchChar('>') {
-				goto l435
+			if !p.rules[ruleNormalEndline]() {
+				goto l750
 			}
+			do(56)
 			return true
-		l435:
-			position = position0
+		l750:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 98 HtmlBlockCloseDd <- ('<' Spnl '/' ((&[D] 'DD') | (&[d] 'dd')) Spnl '>') */
+		/* 57 Symbol <- (< SpecialChar > { yy = p.mkString(yytext) }) */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l439
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l439
-			}
-			if !matchChar('/') {
-				goto l439
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l439
-				}
-				switch p.Buffer[position] {
-				case 'D':
-					position++ // matchString(`DD`)
-					if !matchChar('D') {
-						goto l439
-					}
-					break
-				case 'd':
-					position++ // matchString(`dd`)
-					if !matchChar('d') {
-						goto l439
-					}
-					break
-				default:
-					goto l439
-				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l439
-			}
-			if !matchChar('>') {
-				goto l439
+			begin = position
+			if !p.rules[ruleSpecialChar]() {
+				goto l751
 			}
+			end = position
+			do(57)
 			return true
-		l439:
+		l751:
 			position = position0
 			return false
 		},
-		/* 99 HtmlBlockDd <- (HtmlBlockOpenDd (HtmlBlockDd / (!HtmlBlockCloseDd .))* HtmlBlockCloseDd) */
+		/* 58 UlOrStarLine <- ((UlLine / StarLine) { yy = p.mkString(yytext) }) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenDd]() {
-				goto l441
-			}
-		l442:
-			{
-				position443 := position
-				if !p.rules[ruleHtmlBlockDd]() {
-					goto l445
-				}
-				goto l444
-			l445:
-				if !p.rules[ruleHtmlBlockCloseDd]() {
-					goto l446
-				}
-				goto l443
-			l446:
-				if !matchDot() {
-					goto l443
-				}
-			l444:
-				goto l442
-			l443:
-				position = position443
+			if !p.rules[ruleUlLine]() {
+				goto l754
 			}
-			if !p.rules[ruleHtmlBlockCloseDd]() {
-				goto l441
+			goto l753
+		l754:
+			if !p.rules[ruleStarLine]() {
+				goto l752
 			}
+		l753:
+			do(58)
 			return true
-		l441:
+		l752:
 			position = position0
 			return false
 		},
-		/* 100 HtmlBlockOpenDt <- ('<' Spnl ((&[D] 'DT') | (&[d] 'dt')) Spnl HtmlAttribute* '>') */
+		/* 59 StarLine <- ((&[*] (< '****' '*'* >)) | (&[\t ] (< Spacechar '*'+ &Spacechar >))) */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l447
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l447
-			}
 			{
 				if position == len(p.Buffer) {
-					goto l447
+					goto l755
 				}
 				switch p.Buffer[position] {
-				case 'D':
-					position++ // matchString(`DT`)
-					if !matchChar('T') {
-						goto l447
+				case '*':
+					begin = position
+					if !matchString("****") {
+						goto l755
+					}
+				l757:
+					if !matchChar('*') {
+						goto l758
 					}
+					goto l757
+				l758:
+					end = position
 					break
-				case 'd':
-					position++ // matchString(`dt`)
-					if !matchChar('t') {
-						goto l447
+				case '\t', ' ':
+					begin = position
+					if !p.rules[ruleSpacechar]() {
+						goto l755
+					}
+					if !matchChar('*') {
+						goto l755
+					}
+				l759:
+					if !matchChar('*') {
+						goto l760
+					}
+					goto l759
+				l760:
+					{
+						position761 := position
+						if !p.rules[ruleSpacechar]() {
+							goto l755
+						}
+						position = position761
 					}
+					end = position
 					break
 				default:
-					goto l447
+					goto l755
 				}
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l447
-			}
-		l449:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l450
-			}
-			goto l449
-		l450:
-			if !matchChar('>') {
-				goto l447
-			}
 			return true
-		l447:
+		l755:
 			position = position0
 			return false
 		},
-		/* 101 HtmlBlockCloseDt <- ('<' Spnl '/' ((&[D] 'DT') | (&[d] 'dt')) Spnl '>') */
+		/* 60 UlLine <- ((&[_] (< '____' '_'* >)) | (&[\t ] (< Spacechar '_'+ &Spacechar >))) */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l451
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l451
-			}
-			if !matchChar('/') {
-				goto l451
-			}
 			{
 				if position == len(p.Buffer) {
-					goto l451
+					goto l762
 				}
 				switch p.Buffer[position] {
-				case 'D':
-					position++ // matchString(`DT`)
-					if !matchChar('T') {
-						goto l451
+				case '_':
+					begin = position
+					if !matchString("____") {
+						goto l762
 					}
-					break
-				case 'd':
-					position++ // matchString(`dt`)
-					if !matchChar('t') {
-						goto l451
+				l764:
+					if !matchChar('_') {
+						goto l765
+					}
+					goto l764
+				l765:
+					end = position
+					break
+				case '\t', ' ':
+					begin = position
+					if !p.rules[ruleSpacechar]() {
+						goto l762
+					}
+					if !matchChar('_') {
+						goto l762
+					}
+				l766:
+					if !matchChar('_') {
+						goto l767
+					}
+					goto l766
+				l767:
+					{
+						position768 := position
+						if !p.rules[ruleSpacechar]() {
+							goto l762
+						}
+						position = position768
 					}
+					end = position
 					break
 				default:
-					goto l451
+					goto l762
 				}
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l451
-			}
-			if !matchChar('>') {
-				goto l451
-			}
 			return true
-		l451:
+		l762:
 			position = position0
 			return false
 		},
-		/* 102 HtmlBlockDt <- (HtmlBlockOpenDt (HtmlBlockDt / (!HtmlBlockCloseDt .))* HtmlBlockCloseDt) */
+		/* 61 Emph <- (&{!p.extension.NoEmphasis} ((&[_] EmphUl) | (&[*] EmphStar))) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenDt]() {
-				goto l453
+			if !(!p.extension.NoEmphasis) {
+				goto l769
 			}
-		l454:
 			{
-				position455 := position
-				if !p.rules[ruleHtmlBlockDt]() {
-					goto l457
-				}
-				goto l456
-			l457:
-				if !p.rules[ruleHtmlBlockCloseDt]() {
-					goto l458
+				if position == len(p.Buffer) {
+					goto l769
 				}
-				goto l455
-			l458:
-				if !matchDot() {
-					goto l455
+				switch p.Buffer[position] {
+				case '_':
+					if !p.rules[ruleEmphUl]() {
+						goto l769
+					}
+					break
+				case '*':
+					if !p.rules[ruleEmphStar]() {
+						goto l769
+					}
+					break
+				default:
+					goto l769
 				}
-			l456:
-				goto l454
-			l455:
-				position = position455
-			}
-			if !p.rules[ruleHtmlBlockCloseDt]() {
-				goto l453
 			}
 			return true
-		l453:
-			position = position0
+		l769:
 			return false
 		},
-		/* 103 HtmlBlockOpenFrameset <- ('<' Spnl ((&[F] 'FRAMESET') | (&[f] 'frameset')) Spnl HtmlAttribute* '>') */
+		/* 62 Whitespace <- ((&[\n\r] Newline) | (&[\t ] Spacechar)) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l459
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l459
-			}
 			{
 				if position == len(p.Buffer) {
-					goto l459
+					goto l771
 				}
 				switch p.Buffer[position] {
-				case 'F':
-					position++
-					if !matchString("RAMESET") {
-						goto l459
+				case '\n', '\r':
+					if !p.rules[ruleNewline]() {
+						goto l771
 					}
 					break
-				case 'f':
-					position++
-					if !matchString("rameset") {
-						goto l459
+				case '\t', ' ':
+					if !p.rules[ruleSpacechar]() {
+						goto l771
 					}
 					break
 				default:
-					goto l459
+					goto l771
 				}
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l459
-			}
-		l461:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l462
-			}
-			goto l461
-		l462:
-			if !matchChar('>') {
-				goto l459
-			}
 			return true
-		l459:
-			position = position0
+		l771:
 			return false
 		},
-		/* 104 HtmlBlockCloseFrameset <- ('<' Spnl '/' ((&[F] 'FRAMESET') | (&[f] 'frameset')) Spnl '>') */
+		/* 63 EmphStar <- ('*' !Whitespace StartList ((!'*' Inline { a = cons(b, a) }) / (StrongStar { a = cons(b, a) }))+ '*' { yy = p.mkList(EMPH, a) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l463
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !matchChar('*') {
+				goto l773
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l463
+			if !p.rules[ruleWhitespace]() {
+				goto l774
 			}
-			if !matchChar('/') {
-				goto l463
+			goto l773
+		l774:
+			if !p.rules[ruleStartList]() {
+				goto l773
 			}
+			doarg(yySet, -2)
 			{
-				if position == len(p.Buffer) {
-					goto l463
+				position777, thunkPosition777 := position, thunkPosition
+				if peekChar('*') {
+					goto l778
 				}
-				switch p.Buffer[position] {
-				case 'F':
-					position++
-					if !matchString("RAMESET") {
-						goto l463
+				if !p.rules[ruleInline]() {
+					goto l778
+				}
+				doarg(yySet, -1)
+				do(59)
+				goto l777
+			l778:
+				position, thunkPosition = position777, thunkPosition777
+				if !p.rules[ruleStrongStar]() {
+					goto l773
+				}
+				doarg(yySet, -1)
+				do(60)
+			}
+		l777:
+		l775:
+			{
+				position776, thunkPosition776 := position, thunkPosition
+				{
+					position779, thunkPosition779 := position, thunkPosition
+					if peekChar('*') {
+						goto l780
 					}
-					break
-				case 'f':
-					position++
-					if !matchString("rameset") {
-						goto l463
+					if !p.rules[ruleInline]() {
+						goto l780
 					}
-					break
-				default:
-					goto l463
+					doarg(yySet, -1)
+					do(59)
+					goto l779
+				l780:
+					position, thunkPosition = position779, thunkPosition779
+					if !p.rules[ruleStrongStar]() {
+						goto l776
+					}
+					doarg(yySet, -1)
+					do(60)
 				}
+			l779:
+				goto l775
+			l776:
+				position, thunkPosition = position776, thunkPosition776
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l463
-			}
-			if !matchChar('>') {
-				goto l463
+			if !matchChar('*') {
+				goto l773
 			}
+			do(61)
+			doarg(yyPop, 2)
 			return true
-		l463:
-			position = position0
+		l773:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 105 HtmlBlockFrameset <- (HtmlBlockOpenFrameset (HtmlBlockFrameset / (!HtmlBlockCloseFrameset .))* HtmlBlockCloseFrameset) */
+		/* 64 EmphUl <- ('_' !Whitespace StartList ((!'_' Inline { a = cons(b, a) }) / (StrongUl { a = cons(b, a) }))+ '_' { yy = p.mkList(EMPH, a) }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenFrameset]() {
-				goto l465
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !matchChar('_') {
+				goto l781
+			}
+			if !p.rules[ruleWhitespace]() {
+				goto l782
+			}
+			goto l781
+		l782:
+			if !p.rules[ruleStartList]() {
+				goto l781
 			}
-		l466:
+			doarg(yySet, -2)
 			{
-				position467 := position
-				if !p.rules[ruleHtmlBlockFrameset]() {
-					goto l469
+				position785, thunkPosition785 := position, thunkPosition
+				if peekChar('_') {
+					goto l786
 				}
-				goto l468
-			l469:
-				if !p.rules[ruleHtmlBlockCloseFrameset]() {
-					goto l470
+				if !p.rules[ruleInline]() {
+					goto l786
 				}
-				goto l467
-			l470:
-				if !matchDot() {
-					goto l467
+				doarg(yySet, -1)
+				do(62)
+				goto l785
+			l786:
+				position, thunkPosition = position785, thunkPosition785
+				if !p.rules[ruleStrongUl]() {
+					goto l781
+				}
+				doarg(yySet, -1)
+				do(63)
+			}
+		l785:
+		l783:
+			{
+				position784, thunkPosition784 := position, thunkPosition
+				{
+					position787, thunkPosition787 := position, thunkPosition
+					if peekChar('_') {
+						goto l788
+					}
+					if !p.rules[ruleInline]() {
+						goto l788
+					}
+					doarg(yySet, -1)
+					do(62)
+					goto l787
+				l788:
+					position, thunkPosition = position787, thunkPosition787
+					if !p.rules[ruleStrongUl]() {
+						goto l784
+					}
+					doarg(yySet, -1)
+					do(63)
 				}
-			l468:
-				goto l466
-			l467:
-				position = position467
+			l787:
+				goto l783
+			l784:
+				position, thunkPosition = position784, thunkPosition784
 			}
-			if !p.rules[ruleHtmlBlockCloseFrameset]() {
-				goto l465
+			if !matchChar('_') {
+				goto l781
 			}
+			do(64)
+			doarg(yyPop, 2)
 			return true
-		l465:
-			position = position0
+		l781:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 106 HtmlBlockOpenLi <- ('<' Spnl ((&[L] 'LI') | (&[l] 'li')) Spnl HtmlAttribute* '>') */
+		/* 65 Strong <- (&{!p.extension.NoEmphasis} ((&[_] StrongUl) | (&[*] StrongStar))) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l471
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l471
+			if !(!p.extension.NoEmphasis) {
+				goto l789
 			}
 			{
 				if position == len(p.Buffer) {
-					goto l471
+					goto l789
 				}
 				switch p.Buffer[position] {
-				case 'L':
-					position++ // matchString(`LI`)
-					if !matchChar('I') {
-						goto l471
+				case '_':
+					if !p.rules[ruleStrongUl]() {
+						goto l789
 					}
 					break
-				case 'l':
-					position++ // matchString(`li`)
-					if !matchChar('i') {
-						goto l471
+				case '*':
+					if !p.rules[ruleStrongStar]() {
+						goto l789
 					}
 					break
 				default:
-					goto l471
+					goto l789
 				}
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l471
-			}
-		l473:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l474
-			}
-			goto l473
-		l474:
-			if !matchChar('>') {
-				goto l471
-			}
 			return true
-		l471:
-			position = position0
+		l789:
 			return false
 		},
-		/* 107 HtmlBlockCloseLi <- ('<' Spnl '/' ((&[L] 'LI') | (&[l] 'li')) Spnl '>') */
+		/* 66 StrongStar <- ('**' !Whitespace StartList (!'**' Inline { a = cons(b, a) })+ '**' { yy = p.mkList(STRONG, a) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l475
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l475
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !matchString("**") {
+				goto l791
 			}
-			if !matchChar('/') {
-				goto l475
+			if !p.rules[ruleWhitespace]() {
+				goto l792
+			}
+			goto l791
+		l792:
+			if !p.rules[ruleStartList]() {
+				goto l791
+			}
+			doarg(yySet, -2)
+			if !matchString("**") {
+				goto l795
 			}
+			goto l791
+		l795:
+			if !p.rules[ruleInline]() {
+				goto l791
+			}
+			doarg(yySet, -1)
+			do(65)
+		l793:
 			{
-				if position == len(p.Buffer) {
-					goto l475
+				position794, thunkPosition794 := position, thunkPosition
+				if !matchString("**") {
+					goto l796
 				}
-				switch p.Buffer[position] {
-				case 'L':
-					position++ // matchString(`LI`)
-					if !matchChar('I') {
-						goto l475
-					}
-					break
-				case 'l':
-					position++ // matchString(`li`)
-					if !matchChar('i') {
-						goto l475
-					}
-					break
-				default:
-					goto l475
+				goto l794
+			l796:
+				if !p.rules[ruleInline]() {
+					goto l794
 				}
+				doarg(yySet, -1)
+				do(65)
+				goto l793
+			l794:
+				position, thunkPosition = position794, thunkPosition794
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l475
-			}
-			if !matchChar('>') {
-				goto l475
+			if !matchString("**") {
+				goto l791
 			}
+			do(66)
+			doarg(yyPop, 2)
 			return true
-		l475:
-			position = position0
+		l791:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 108 HtmlBlockLi <- (HtmlBlockOpenLi (HtmlBlockLi / (!HtmlBlockCloseLi .))* HtmlBlockCloseLi) */
+		/* 67 StrongUl <- ('__' !Whitespace StartList (!'__' Inline { a = cons(b, a) })+ '__' { yy = p.mkList(STRONG, a) }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenLi]() {
-				goto l477
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !matchString("__") {
+				goto l797
 			}
-		l478:
+			if !p.rules[ruleWhitespace]() {
+				goto l798
+			}
+			goto l797
+		l798:
+			if !p.rules[ruleStartList]() {
+				goto l797
+			}
+			doarg(yySet, -2)
+			if !matchString("__") {
+				goto l801
+			}
+			goto l797
+		l801:
+			if !p.rules[ruleInline]() {
+				goto l797
+			}
+			doarg(yySet, -1)
+			do(67)
+		l799:
 			{
-				position479 := position
-				if !p.rules[ruleHtmlBlockLi]() {
-					goto l481
-				}
-				goto l480
-			l481:
-				if !p.rules[ruleHtmlBlockCloseLi]() {
-					goto l482
+				position800, thunkPosition800 := position, thunkPosition
+				if !matchString("__") {
+					goto l802
 				}
-				goto l479
-			l482:
-				if !matchDot() {
-					goto l479
+				goto l800
+			l802:
+				if !p.rules[ruleInline]() {
+					goto l800
 				}
-			l480:
-				goto l478
-			l479:
-				position = position479
+				doarg(yySet, -1)
+				do(67)
+				goto l799
+			l800:
+				position, thunkPosition = position800, thunkPosition800
 			}
-			if !p.rules[ruleHtmlBlockCloseLi]() {
-				goto l477
+			if !matchString("__") {
+				goto l797
 			}
+			do(68)
+			doarg(yyPop, 2)
 			return true
-		l477:
-			position = position0
+		l797:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 109 HtmlBlockOpenTbody <- ('<' Spnl ((&[T] 'TBODY') | (&[t] 'tbody')) Spnl HtmlAttribute* '>') */
+		/* 68 Image <- (&{!p.extension.NoImages} '!' (ExplicitLink / ReferenceLink) {	if yy.key == LINK {
+			yy.key = IMAGE
+			p.imageAlt(yy)
+		} else {
+			result := yy
+			yy.children = cons(p.mkString("!"), result.children)
+		}
+	}) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l483
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l483
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l483
-				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++
-					if !matchString("BODY") {
-						goto l483
-					}
-					break
-				case 't':
-					position++
-					if !matchString("body") {
-						goto l483
-					}
-					break
-				default:
-					goto l483
-				}
+			position0, thunkPosition0 := position, thunkPosition
+			if !(!p.extension.NoImages) {
+				goto l803
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l483
+			if !matchChar('!') {
+				goto l803
 			}
-		l485:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l486
+			if !p.rules[ruleExplicitLink]() {
+				goto l805
 			}
-			goto l485
-		l486:
-			if !matchChar('>') {
-				goto l483
+			goto l804
+		l805:
+			if !p.rules[ruleReferenceLink]() {
+				goto l803
 			}
+		l804:
+			do(69)
 			return true
-		l483:
-			position = position0
+		l803:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 110 HtmlBlockCloseTbody <- ('<' Spnl '/' ((&[T] 'TBODY') | (&[t] 'tbody')) Spnl '>') */
+		/* 69 Link <- ((&{!p.extension.NoLinks} (ExplicitLink / ReferenceLink)) / AutoLink) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l487
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l487
-			}
-			if !matchChar('/') {
-				goto l487
+			if !(!p.extension.NoLinks) {
+				goto l809
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l487
-				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++
-					if !matchString("BODY") {
-						goto l487
-					}
-					break
-				case 't':
-					position++
-					if !matchString("body") {
-						goto l487
-					}
-					break
-				default:
-					goto l487
-				}
+			if !p.rules[ruleExplicitLink]() {
+				goto l808
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l487
+			goto l807
+		l808:
+			if !p.rules[ruleReferenceLink]() {
+				goto l809
 			}
-			if !matchChar('>') {
-				goto l487
+			goto l807
+		l809:
+			if !p.rules[ruleAutoLink]() {
+				goto l806
 			}
+		l807:
 			return true
-		l487:
-			position = position0
+		l806:
 			return false
 		},
-		/* 111 HtmlBlockTbody <- (HtmlBlockOpenTbody (HtmlBlockTbody / (!HtmlBlockCloseTbody .))* HtmlBlockCloseTbody) */
+		/* 70 ReferenceLink <- (ReferenceLinkDouble / ReferenceLinkSingle) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenTbody]() {
-				goto l489
-			}
-		l490:
-			{
-				position491 := position
-				if !p.rules[ruleHtmlBlockTbody]() {
-					goto l493
-				}
-				goto l492
-			l493:
-				if !p.rules[ruleHtmlBlockCloseTbody]() {
-					goto l494
-				}
-				goto l491
-			l494:
-				if !matchDot() {
-					goto l491
-				}
-			l492:
-				goto l490
-			l491:
-				position = position491
+			if !p.rules[ruleReferenceLinkDouble]() {
+				goto l812
 			}
-			if !p.rules[ruleHtmlBlockCloseTbody]() {
-				goto l489
+			goto l811
+		l812:
+			if !p.rules[ruleReferenceLinkSingle]() {
+				goto l810
 			}
+		l811:
 			return true
-		l489:
-			position = position0
+		l810:
 			return false
 		},
-		/* 112 HtmlBlockOpenTd <- ('<' Spnl ((&[T] 'TD') | (&[t] 'td')) Spnl HtmlAttribute* '>') */
+		/* 71 ReferenceLinkDouble <- (Label < Spnl > !'[]' Label {
+                           if match, found := p.findReference(b.children); found {
+                               yy = p.mkLink(a.children, match.URL, match.Title);
+                               yy.contents.link.ref = plainText(b.children)
+                               a = nil
+                               b = nil
+                           } else {
+                               result := p.mkElem(LIST)
+                               result.children = cons(p.mkString("["), cons(a, cons(p.mkString("]"), cons(p.mkString(yytext),
+                                                   cons(p.mkString("["), cons(b, p.mkString("]")))))))
+                               yy = result
+                           }
+                       }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l495
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l495
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l495
-				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++ // matchString(`TD`)
-					if !matchChar('D') {
-						goto l495
-					}
-					break
-				case 't':
-					position++ // matchString(`td`)
-					if !matchChar('d') {
-						goto l495
-					}
-					break
-				default:
-					goto l495
-				}
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleLabel]() {
+				goto l813
 			}
+			doarg(yySet, -1)
+			begin = position
 			if !p.rules[ruleSpnl]() {
-				goto l495
+				goto l813
 			}
-		l497:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l498
+			end = position
+			if !matchString("[]") {
+				goto l814
 			}
-			goto l497
-		l498:
-			if !matchChar('>') {
-				goto l495
+			goto l813
+		l814:
+			if !p.rules[ruleLabel]() {
+				goto l813
 			}
+			doarg(yySet, -2)
+			do(70)
+			doarg(yyPop, 2)
 			return true
-		l495:
-			position = position0
+		l813:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 113 HtmlBlockCloseTd <- ('<' Spnl '/' ((&[T] 'TD') | (&[t] 'td')) Spnl '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l499
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l499
-			}
-			if !matchChar('/') {
-				goto l499
+		/* 72 ReferenceLinkSingle <- (Label < (Spnl '[]')? > {
+                           if match, found := p.findReference(a.children); found {
+                               yy = p.mkLink(a.children, match.URL, match.Title)
+                               yy.contents.link.ref = plainText(a.children)
+                               a = nil
+                           } else {
+                               result := p.mkElem(LIST)
+                               result.children = cons(p.mkString("["), cons(a, cons(p.mkString("]"), p.mkString(yytext))));
+                               yy = result
+                           }
+                       }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleLabel]() {
+				goto l815
 			}
+			doarg(yySet, -1)
+			begin = position
 			{
-				if position == len(p.Buffer) {
-					goto l499
+				position816, thunkPosition816 := position, thunkPosition
+				if !p.rules[ruleSpnl]() {
+					goto l816
 				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++ // matchString(`TD`)
-					if !matchChar('D') {
-						goto l499
-					}
-					break
-				case 't':
-					position++ // matchString(`td`)
-					if !matchChar('d') {
-						goto l499
-					}
-					break
-				default:
-					goto l499
+				if !matchString("[]") {
+					goto l816
 				}
+				goto l817
+			l816:
+				position, thunkPosition = position816, thunkPosition816
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l499
-			}
-			if !matchChar('>') {
-				goto l499
-			}
+		l817:
+			end = position
+			do(71)
+			doarg(yyPop, 1)
 			return true
-		l499:
-			position = position0
+		l815:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 114 HtmlBlockTd <- (HtmlBlockOpenTd (HtmlBlockTd / (!HtmlBlockCloseTd .))* HtmlBlockCloseTd) */
+		/* 73 ExplicitLink <- (Label '(' Sp Source Spnl Title Sp ')' { yy = p.mkLink(l.children, s.contents.str, t.contents.str)
+                  s = nil
+                  t = nil
+                  l = nil }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenTd]() {
-				goto l501
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 3)
+			if !p.rules[ruleLabel]() {
+				goto l818
 			}
-		l502:
-			{
-				position503 := position
-				if !p.rules[ruleHtmlBlockTd]() {
-					goto l505
-				}
-				goto l504
-			l505:
-				if !p.rules[ruleHtmlBlockCloseTd]() {
-					goto l506
-				}
-				goto l503
-			l506:
-				if !matchDot() {
-					goto l503
-				}
-			l504:
-				goto l502
-			l503:
-				position = position503
+			doarg(yySet, -1)
+			if !matchChar('(') {
+				goto l818
+			}
+			if !p.rules[ruleSp]() {
+				goto l818
+			}
+			if !p.rules[ruleSource]() {
+				goto l818
+			}
+			doarg(yySet, -3)
+			if !p.rules[ruleSpnl]() {
+				goto l818
+			}
+			if !p.rules[ruleTitle]() {
+				goto l818
+			}
+			doarg(yySet, -2)
+			if !p.rules[ruleSp]() {
+				goto l818
 			}
-			if !p.rules[ruleHtmlBlockCloseTd]() {
-				goto l501
+			if !matchChar(')') {
+				goto l818
 			}
+			do(72)
+			doarg(yyPop, 3)
 			return true
-		l501:
-			position = position0
+		l818:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 115 HtmlBlockOpenTfoot <- ('<' Spnl ((&[T] 'TFOOT') | (&[t] 'tfoot')) Spnl HtmlAttribute* '>') */
+		/* 74 Source <- (AngleSource / (< SourceContents > { yy = p.mkString(yytext) })) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l507
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l507
-			}
+			position0, thunkPosition0 := position, thunkPosition
 			{
-				if position == len(p.Buffer) {
-					goto l507
+				position1496, thunkPosition1496 := position, thunkPosition
+				if !p.rules[ruleAngleSource]() {
+					goto l1497
 				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++
-					if !matchString("FOOT") {
-						goto l507
-					}
-					break
-				case 't':
-					position++
-					if !matchString("foot") {
-						goto l507
-					}
-					break
-				default:
-					goto l507
+				goto l820
+			l1497:
+				position, thunkPosition = position1496, thunkPosition1496
+				begin = position
+				if !p.rules[ruleSourceContents]() {
+					goto l819
 				}
+				end = position
+				do(73)
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l507
-			}
-		l509:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l510
-			}
-			goto l509
-		l510:
-			if !matchChar('>') {
-				goto l507
-			}
+		l820:
 			return true
-		l507:
-			position = position0
+		l819:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 116 HtmlBlockCloseTfoot <- ('<' Spnl '/' ((&[T] 'TFOOT') | (&[t] 'tfoot')) Spnl '>') */
+		/* 75 SourceContents <- ((!'(' !')' !'>' Nonspacechar)+ / ('(' SourceContents ')'))* */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l511
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l511
-			}
-			if !matchChar('/') {
-				goto l511
-			}
+		l823:
 			{
+				position824 := position
 				if position == len(p.Buffer) {
-					goto l511
+					goto l826
 				}
 				switch p.Buffer[position] {
-				case 'T':
-					position++
-					if !matchString("FOOT") {
-						goto l511
-					}
-					break
-				case 't':
-					position++
-					if !matchString("foot") {
-						goto l511
+				case '(', ')', '>':
+					goto l826
+				default:
+					if !p.rules[ruleNonspacechar]() {
+						goto l826
 					}
-					break
+				}
+			l827:
+				if position == len(p.Buffer) {
+					goto l828
+				}
+				switch p.Buffer[position] {
+				case '(', ')', '>':
+					goto l828
 				default:
-					goto l511
+					if !p.rules[ruleNonspacechar]() {
+						goto l828
+					}
 				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l511
-			}
-			if !matchChar('>') {
-				goto l511
+				goto l827
+			l828:
+				goto l825
+			l826:
+				if !matchChar('(') {
+					goto l824
+				}
+				if !p.rules[ruleSourceContents]() {
+					goto l824
+				}
+				if !matchChar(')') {
+					goto l824
+				}
+			l825:
+				goto l823
+			l824:
+				position = position824
 			}
 			return true
-		l511:
-			position = position0
-			return false
 		},
-		/* 117 HtmlBlockTfoot <- (HtmlBlockOpenTfoot (HtmlBlockTfoot / (!HtmlBlockCloseTfoot .))* HtmlBlockCloseTfoot) */
+		/* 76 Title <- ((TitleSingle / TitleDouble / (< '' >)) { yy = p.mkString(yytext) }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenTfoot]() {
-				goto l513
-			}
-		l514:
-			{
-				position515 := position
-				if !p.rules[ruleHtmlBlockTfoot]() {
-					goto l517
-				}
-				goto l516
-			l517:
-				if !p.rules[ruleHtmlBlockCloseTfoot]() {
-					goto l518
-				}
-				goto l515
-			l518:
-				if !matchDot() {
-					goto l515
-				}
-			l516:
-				goto l514
-			l515:
-				position = position515
+			if !p.rules[ruleTitleSingle]() {
+				goto l831
 			}
-			if !p.rules[ruleHtmlBlockCloseTfoot]() {
-				goto l513
+			goto l830
+		l831:
+			if !p.rules[ruleTitleDouble]() {
+				goto l832
 			}
+			goto l830
+		l832:
+			begin = position
+			end = position
+		l830:
+			do(74)
 			return true
-		l513:
-			position = position0
-			return false
 		},
-		/* 118 HtmlBlockOpenTh <- ('<' Spnl ((&[T] 'TH') | (&[t] 'th')) Spnl HtmlAttribute* '>') */
+		/* 77 TitleSingle <- ('\'' < (!('\'' Sp ((&[)] ')') | (&[\n\r] Newline))) .)* > '\'') */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l519
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l519
+			if !matchChar('\'') {
+				goto l833
 			}
+			begin = position
+		l834:
 			{
-				if position == len(p.Buffer) {
-					goto l519
-				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++ // matchString(`TH`)
-					if !matchChar('H') {
-						goto l519
+				position835 := position
+				{
+					position836 := position
+					if !matchChar('\'') {
+						goto l836
 					}
-					break
-				case 't':
-					position++ // matchString(`th`)
-					if !matchChar('h') {
-						goto l519
+					if !p.rules[ruleSp]() {
+						goto l836
 					}
-					break
-				default:
-					goto l519
+					{
+						if position == len(p.Buffer) {
+							goto l836
+						}
+						switch p.Buffer[position] {
+						case ')':
+							position++ // matchChar
+							break
+						case '\n', '\r':
+							if !p.rules[ruleNewline]() {
+								goto l836
+							}
+							break
+						default:
+							goto l836
+						}
+					}
+					goto l835
+				l836:
+					position = position836
 				}
+				if !matchDot() {
+					goto l835
+				}
+				goto l834
+			l835:
+				position = position835
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l519
-			}
-		l521:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l522
-			}
-			goto l521
-		l522:
-			if !matchChar('>') {
-				goto l519
+			end = position
+			if !matchChar('\'') {
+				goto l833
 			}
 			return true
-		l519:
+		l833:
 			position = position0
 			return false
 		},
-		/* 119 HtmlBlockCloseTh <- ('<' Spnl '/' ((&[T] 'TH') | (&[t] 'th')) Spnl '>') */
+		/* 78 TitleDouble <- ('"' < (!('"' Sp ((&[)] ')') | (&[\n\r] Newline))) .)* > '"') */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l523
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l523
-			}
-			if !matchChar('/') {
-				goto l523
+			if !matchChar('"') {
+				goto l838
 			}
+			begin = position
+		l839:
 			{
-				if position == len(p.Buffer) {
-					goto l523
-				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++ // matchString(`TH`)
-					if !matchChar('H') {
-						goto l523
+				position840 := position
+				{
+					position841 := position
+					if !matchChar('"') {
+						goto l841
 					}
-					break
-				case 't':
-					position++ // matchString(`th`)
-					if !matchChar('h') {
-						goto l523
+					if !p.rules[ruleSp]() {
+						goto l841
 					}
-					break
-				default:
-					goto l523
+					{
+						if position == len(p.Buffer) {
+							goto l841
+						}
+						switch p.Buffer[position] {
+						case ')':
+							position++ // matchChar
+							break
+						case '\n', '\r':
+							if !p.rules[ruleNewline]() {
+								goto l841
+							}
+							break
+						default:
+							goto l841
+						}
+					}
+					goto l840
+				l841:
+					position = position841
 				}
+				if !matchDot() {
+					goto l840
+				}
+				goto l839
+			l840:
+				position = position840
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l523
-			}
-			if !matchChar('>') {
-				goto l523
+			end = position
+			if !matchChar('"') {
+				goto l838
 			}
 			return true
-		l523:
+		l838:
 			position = position0
 			return false
 		},
-		/* 120 HtmlBlockTh <- (HtmlBlockOpenTh (HtmlBlockTh / (!HtmlBlockCloseTh .))* HtmlBlockCloseTh) */
+		/* 79 AutoLink <- (AutoLinkUrl / AutoLinkEmail) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenTh]() {
-				goto l525
-			}
-		l526:
-			{
-				position527 := position
-				if !p.rules[ruleHtmlBlockTh]() {
-					goto l529
-				}
-				goto l528
-			l529:
-				if !p.rules[ruleHtmlBlockCloseTh]() {
-					goto l530
-				}
-				goto l527
-			l530:
-				if !matchDot() {
-					goto l527
-				}
-			l528:
-				goto l526
-			l527:
-				position = position527
+			if !p.rules[ruleAutoLinkUrl]() {
+				goto l845
 			}
-			if !p.rules[ruleHtmlBlockCloseTh]() {
-				goto l525
+			goto l844
+		l845:
+			if !p.rules[ruleAutoLinkEmail]() {
+				goto l843
 			}
+		l844:
 			return true
-		l525:
-			position = position0
+		l843:
 			return false
 		},
-		/* 121 HtmlBlockOpenThead <- ('<' Spnl ((&[T] 'THEAD') | (&[t] 'thead')) Spnl HtmlAttribute* '>') */
+		/* 80 AutoLinkUrl <- (&{!p.extension.NoAutolinks} '<' < [A-Za-z]+ '://' (!Newline !'>' .)+ > '>' {   yy = p.mkLink(p.mkString(yytext), yytext, "") }) */
 		func() bool {
 			position0 := position
+			if !(!p.extension.NoAutolinks) {
+				goto l846
+			}
 			if !matchChar('<') {
-				goto l531
+				goto l846
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l531
+			begin = position
+			if !matchClass(2) {
+				goto l846
+			}
+		l847:
+			if !matchClass(2) {
+				goto l848
+			}
+			goto l847
+		l848:
+			if !matchString("://") {
+				goto l846
 			}
+			if !p.rules[ruleNewline]() {
+				goto l851
+			}
+			goto l846
+		l851:
+			if peekChar('>') {
+				goto l846
+			}
+			if !matchDot() {
+				goto l846
+			}
+		l849:
 			{
-				if position == len(p.Buffer) {
-					goto l531
+				position850 := position
+				if !p.rules[ruleNewline]() {
+					goto l852
 				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++
-					if !matchString("HEAD") {
-						goto l531
-					}
-					break
-				case 't':
-					position++
-					if !matchString("head") {
-						goto l531
-					}
-					break
-				default:
-					goto l531
+				goto l850
+			l852:
+				if peekChar('>') {
+					goto l850
 				}
+				if !matchDot() {
+					goto l850
+				}
+				goto l849
+			l850:
+				position = position850
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l531
-			}
-		l533:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l534
-			}
-			goto l533
-		l534:
+			end = position
 			if !matchChar('>') {
-				goto l531
+				goto l846
 			}
+			do(75)
 			return true
-		l531:
+		l846:
 			position = position0
 			return false
 		},
-		/* 122 HtmlBlockCloseThead <- ('<' Spnl '/' ((&[T] 'THEAD') | (&[t] 'thead')) Spnl '>') */
+		/* 81 AutoLinkEmail <- (&{!p.extension.NoAutolinkEmail} '<' 'mailto:'? < [-A-Za-z0-9+_./!%~$]+ '@' (!Newline !'>' .)+ > '>' {
+                    yy = p.mkLink(p.mkString(yytext), "mailto:"+yytext, "")
+                }) */
 		func() bool {
 			position0 := position
+			if !(!p.extension.NoAutolinkEmail) {
+				goto l853
+			}
 			if !matchChar('<') {
-				goto l535
+				goto l853
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l535
+			if !matchString("mailto:") {
+				goto l854
 			}
-			if !matchChar('/') {
-				goto l535
+		l854:
+			begin = position
+			if !matchClass(3) {
+				goto l853
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l535
-				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++
-					if !matchString("HEAD") {
-						goto l535
-					}
-					break
-				case 't':
-					position++
-					if !matchString("head") {
-						goto l535
-					}
-					break
-				default:
-					goto l535
-				}
+		l856:
+			if !matchClass(3) {
+				goto l857
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l535
+			goto l856
+		l857:
+			if !matchChar('@') {
+				goto l853
 			}
-			if !matchChar('>') {
-				goto l535
+			if !p.rules[ruleNewline]() {
+				goto l860
 			}
-			return true
-		l535:
-			position = position0
-			return false
-		},
-		/* 123 HtmlBlockThead <- (HtmlBlockOpenThead (HtmlBlockThead / (!HtmlBlockCloseThead .))* HtmlBlockCloseThead) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenThead]() {
-				goto l537
+			goto l853
+		l860:
+			if peekChar('>') {
+				goto l853
 			}
-		l538:
+			if !matchDot() {
+				goto l853
+			}
+		l858:
 			{
-				position539 := position
-				if !p.rules[ruleHtmlBlockThead]() {
-					goto l541
+				position859 := position
+				if !p.rules[ruleNewline]() {
+					goto l861
 				}
-				goto l540
-			l541:
-				if !p.rules[ruleHtmlBlockCloseThead]() {
-					goto l542
+				goto l859
+			l861:
+				if peekChar('>') {
+					goto l859
 				}
-				goto l539
-			l542:
 				if !matchDot() {
-					goto l539
+					goto l859
 				}
-			l540:
-				goto l538
-			l539:
-				position = position539
+				goto l858
+			l859:
+				position = position859
 			}
-			if !p.rules[ruleHtmlBlockCloseThead]() {
-				goto l537
+			end = position
+			if !matchChar('>') {
+				goto l853
 			}
+			do(76)
 			return true
-		l537:
+		l853:
 			position = position0
 			return false
 		},
-		/* 124 HtmlBlockOpenTr <- ('<' Spnl ((&[T] 'TR') | (&[t] 'tr')) Spnl HtmlAttribute* '>') */
+		/* 82 Reference <- (NonindentSpace !'[]' Label ':' Spnl RefSrc RefTitle BlankLines { yy = p.mkLink(l.children, s.contents.str, t.contents.str)
+              s = nil
+              t = nil
+              l = nil
+              yy.key = REFERENCE }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l543
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 3)
+			if !p.rules[ruleNonindentSpace]() {
+				goto l862
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l543
+			if !matchString("[]") {
+				goto l863
 			}
-			{
-				if position == len(p.Buffer) {
-					goto l543
-				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++ // matchString(`TR`)
-					if !matchChar('R') {
-						goto l543
-					}
-					break
-				case 't':
-					position++ // matchString(`tr`)
-					if !matchChar('r') {
-						goto l543
-					}
-					break
-				default:
-					goto l543
-				}
+			goto l862
+		l863:
+			if !p.rules[ruleLabel]() {
+				goto l862
+			}
+			doarg(yySet, -2)
+			if !matchChar(':') {
+				goto l862
 			}
 			if !p.rules[ruleSpnl]() {
-				goto l543
+				goto l862
 			}
-		l545:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l546
+			if !p.rules[ruleRefSrc]() {
+				goto l862
 			}
-			goto l545
-		l546:
-			if !matchChar('>') {
-				goto l543
+			doarg(yySet, -3)
+			if !p.rules[ruleRefTitle]() {
+				goto l862
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleBlankLines]() {
+				goto l862
 			}
+			do(77)
+			doarg(yyPop, 3)
 			return true
-		l543:
-			position = position0
+		l862:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 125 HtmlBlockCloseTr <- ('<' Spnl '/' ((&[T] 'TR') | (&[t] 'tr')) Spnl '>') */
+		/* 83 Label <- ('[' ((!'^' &{p.extension.Notes}) / (&. &{!p.extension.Notes})) StartList (!']' Inline { a = cons(yy, a) })* ']' { yy = p.mkList(LIST, a) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l547
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l547
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !matchChar('[') {
+				goto l866
 			}
-			if !matchChar('/') {
-				goto l547
+			if peekChar('^') {
+				goto l868
 			}
+			if !(p.extension.Notes) {
+				goto l868
+			}
+			goto l867
+		l868:
+			if !(position < len(p.Buffer)) {
+				goto l866
+			}
+			if !(!p.extension.Notes) {
+				goto l866
+			}
+		l867:
+			if !p.rules[ruleStartList]() {
+				goto l866
+			}
+			doarg(yySet, -1)
+		l869:
 			{
-				if position == len(p.Buffer) {
-					goto l547
+				position870 := position
+				if peekChar(']') {
+					goto l870
 				}
-				switch p.Buffer[position] {
-				case 'T':
-					position++ // matchString(`TR`)
-					if !matchChar('R') {
-						goto l547
-					}
-					break
-				case 't':
-					position++ // matchString(`tr`)
-					if !matchChar('r') {
-						goto l547
-					}
-					break
-				default:
-					goto l547
+				if !p.rules[ruleInline]() {
+					goto l870
 				}
+				do(78)
+				goto l869
+			l870:
+				position = position870
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l547
-			}
-			if !matchChar('>') {
-				goto l547
+			if !matchChar(']') {
+				goto l866
 			}
+			do(79)
+			doarg(yyPop, 1)
 			return true
-		l547:
-			position = position0
+		l866:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 126 HtmlBlockTr <- (HtmlBlockOpenTr (HtmlBlockTr / (!HtmlBlockCloseTr .))* HtmlBlockCloseTr) */
+		/* 84 RefSrc <- ((AngleSource / (< Nonspacechar+ > { yy = p.mkString(yytext) })) { yy.key = HTML }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleHtmlBlockOpenTr]() {
-				goto l549
-			}
-		l550:
+			position0, thunkPosition0 := position, thunkPosition
 			{
-				position551 := position
-				if !p.rules[ruleHtmlBlockTr]() {
-					goto l553
+				position1489, thunkPosition1489 := position, thunkPosition
+				if !p.rules[ruleAngleSource]() {
+					goto l1490
 				}
-				goto l552
-			l553:
-				if !p.rules[ruleHtmlBlockCloseTr]() {
-					goto l554
+				goto l872
+			l1490:
+				position, thunkPosition = position1489, thunkPosition1489
+				begin = position
+				if !p.rules[ruleNonspacechar]() {
+					goto l871
 				}
-				goto l551
-			l554:
-				if !matchDot() {
-					goto l551
+			l1491:
+				if !p.rules[ruleNonspacechar]() {
+					goto l1492
 				}
-			l552:
-				goto l550
-			l551:
-				position = position551
-			}
-			if !p.rules[ruleHtmlBlockCloseTr]() {
-				goto l549
+				goto l1491
+			l1492:
+				end = position
+				do(80)
 			}
+		l872:
+			do(181)
 			return true
-		l549:
-			position = position0
+		l871:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 127 HtmlBlockOpenScript <- ('<' Spnl ((&[S] 'SCRIPT') | (&[s] 'script')) Spnl HtmlAttribute* '>') */
+		/* 85 RefTitle <- ((RefTitleSingle / RefTitleDouble / RefTitleParens / EmptyTitle) { yy = p.mkString(yytext) }) */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l555
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l555
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l555
-				}
-				switch p.Buffer[position] {
-				case 'S':
-					position++
-					if !matchString("CRIPT") {
-						goto l555
-					}
-					break
-				case 's':
-					position++
-					if !matchString("cript") {
-						goto l555
-					}
-					break
-				default:
-					goto l555
-				}
+			if !p.rules[ruleRefTitleSingle]() {
+				goto l876
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l555
+			goto l875
+		l876:
+			if !p.rules[ruleRefTitleDouble]() {
+				goto l877
 			}
-		l557:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l558
+			goto l875
+		l877:
+			if !p.rules[ruleRefTitleParens]() {
+				goto l878
 			}
-			goto l557
-		l558:
-			if !matchChar('>') {
-				goto l555
+			goto l875
+		l878:
+			if !p.rules[ruleEmptyTitle]() {
+				goto l874
 			}
+		l875:
+			do(81)
 			return true
-		l555:
+		l874:
 			position = position0
 			return false
 		},
-		/* 128 HtmlBlockCloseScript <- ('<' Spnl '/' ((&[S] 'SCRIPT') | (&[s] 'script')) Spnl '>') */
+		/* 86 EmptyTitle <- (< '' >) */
+		func() bool {
+			begin = position
+			end = position
+			return true
+		},
+		/* 87 RefTitleSingle <- (Spnl '\'' < (!((&[\'] ('\'' Sp Newline)) | (&[\n\r] Newline)) .)* > '\'') */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l559
-			}
 			if !p.rules[ruleSpnl]() {
-				goto l559
+				goto l880
 			}
-			if !matchChar('/') {
-				goto l559
+			if !matchChar('\'') {
+				goto l880
 			}
+			begin = position
+		l881:
 			{
-				if position == len(p.Buffer) {
-					goto l559
-				}
-				switch p.Buffer[position] {
-				case 'S':
-					position++
-					if !matchString("CRIPT") {
-						goto l559
-					}
-					break
-				case 's':
-					position++
-					if !matchString("cript") {
-						goto l559
+				position882 := position
+				{
+					position883 := position
+					{
+						if position == len(p.Buffer) {
+							goto l883
+						}
+						switch p.Buffer[position] {
+						case '\'':
+							position++ // matchChar
+							if !p.rules[ruleSp]() {
+								goto l883
+							}
+							if !p.rules[ruleNewline]() {
+								goto l883
+							}
+							break
+						case '\n', '\r':
+							if !p.rules[ruleNewline]() {
+								goto l883
+							}
+							break
+						default:
+							goto l883
+						}
 					}
-					break
-				default:
-					goto l559
+					goto l882
+				l883:
+					position = position883
 				}
+				if !matchDot() {
+					goto l882
+				}
+				goto l881
+			l882:
+				position = position882
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l559
-			}
-			if !matchChar('>') {
-				goto l559
+			end = position
+			if !matchChar('\'') {
+				goto l880
 			}
 			return true
-		l559:
+		l880:
 			position = position0
 			return false
 		},
-		/* 129 HtmlBlockScript <- (HtmlBlockOpenScript (!HtmlBlockCloseScript .)* HtmlBlockCloseScript) */
+		/* 88 RefTitleDouble <- (Spnl '"' < (!((&[\"] ('"' Sp Newline)) | (&[\n\r] Newline)) .)* > '"') */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenScript]() {
-				goto l561
+			if !p.rules[ruleSpnl]() {
+				goto l885
 			}
-		l562:
+			if !matchChar('"') {
+				goto l885
+			}
+			begin = position
+		l886:
 			{
-				position563 := position
-				if !p.rules[ruleHtmlBlockCloseScript]() {
-					goto l564
+				position887 := position
+				{
+					position888 := position
+					{
+						if position == len(p.Buffer) {
+							goto l888
+						}
+						switch p.Buffer[position] {
+						case '"':
+							position++ // matchChar
+							if !p.rules[ruleSp]() {
+								goto l888
+							}
+							if !p.rules[ruleNewline]() {
+								goto l888
+							}
+							break
+						case '\n', '\r':
+							if !p.rules[ruleNewline]() {
+								goto l888
+							}
+							break
+						default:
+							goto l888
+						}
+					}
+					goto l887
+				l888:
+					position = position888
 				}
-				goto l563
-			l564:
 				if !matchDot() {
-					goto l563
+					goto l887
 				}
-				goto l562
-			l563:
-				position = position563
+				goto l886
+			l887:
+				position = position887
 			}
-			if !p.rules[ruleHtmlBlockCloseScript]() {
-				goto l561
+			end = position
+			if !matchChar('"') {
+				goto l885
 			}
 			return true
-		l561:
+		l885:
 			position = position0
 			return false
 		},
-		/* 130 HtmlBlockOpenHead <- ('<' Spnl ((&[H] 'HEAD') | (&[h] 'head')) Spnl HtmlAttribute* '>') */
+		/* 89 RefTitleParens <- (Spnl '(' < (!((&[)] (')' Sp Newline)) | (&[\n\r] Newline)) .)* > ')') */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l565
-			}
 			if !p.rules[ruleSpnl]() {
-				goto l565
+				goto l890
 			}
+			if !matchChar('(') {
+				goto l890
+			}
+			begin = position
+		l891:
 			{
-				if position == len(p.Buffer) {
-					goto l565
-				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++
-					if !matchString("EAD") {
-						goto l565
-					}
-					break
-				case 'h':
-					position++
-					if !matchString("ead") {
-						goto l565
+				position892 := position
+				{
+					position893 := position
+					{
+						if position == len(p.Buffer) {
+							goto l893
+						}
+						switch p.Buffer[position] {
+						case ')':
+							position++ // matchChar
+							if !p.rules[ruleSp]() {
+								goto l893
+							}
+							if !p.rules[ruleNewline]() {
+								goto l893
+							}
+							break
+						case '\n', '\r':
+							if !p.rules[ruleNewline]() {
+								goto l893
+							}
+							break
+						default:
+							goto l893
+						}
 					}
-					break
-				default:
-					goto l565
+					goto l892
+				l893:
+					position = position893
 				}
+				if !matchDot() {
+					goto l892
+				}
+				goto l891
+			l892:
+				position = position892
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l565
-			}
-		l567:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l568
-			}
-			goto l567
-		l568:
-			if !matchChar('>') {
-				goto l565
+			end = position
+			if !matchChar(')') {
+				goto l890
 			}
 			return true
-		l565:
+		l890:
 			position = position0
 			return false
 		},
-		/* 131 HtmlBlockCloseHead <- ('<' Spnl '/' ((&[H] 'HEAD') | (&[h] 'head')) Spnl '>') */
+		/* 90 References <- (StartList ((Reference { a = cons(b, a) }) / SkipBlock)* { p.indexReferences(reverse(a)) } commit) */
 		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l569
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l569
-			}
-			if !matchChar('/') {
-				goto l569
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleStartList]() {
+				goto l895
 			}
+			doarg(yySet, -2)
+		l896:
 			{
-				if position == len(p.Buffer) {
-					goto l569
-				}
-				switch p.Buffer[position] {
-				case 'H':
-					position++
-					if !matchString("EAD") {
-						goto l569
+				position897, thunkPosition897 := position, thunkPosition
+				{
+					position898, thunkPosition898 := position, thunkPosition
+					if !p.rules[ruleReference]() {
+						goto l899
 					}
-					break
-				case 'h':
-					position++
-					if !matchString("ead") {
-						goto l569
+					doarg(yySet, -1)
+					do(82)
+					goto l898
+				l899:
+					position, thunkPosition = position898, thunkPosition898
+					if !p.rules[ruleSkipBlock]() {
+						goto l897
 					}
-					break
-				default:
-					goto l569
 				}
+			l898:
+				goto l896
+			l897:
+				position, thunkPosition = position897, thunkPosition897
 			}
-			if !p.rules[ruleSpnl]() {
-				goto l569
-			}
-			if !matchChar('>') {
-				goto l569
+			do(83)
+			if !(commit(thunkPosition0)) {
+				goto l895
 			}
+			doarg(yyPop, 2)
 			return true
-		l569:
-			position = position0
+		l895:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 132 HtmlBlockHead <- (HtmlBlockOpenHead (!HtmlBlockCloseHead .)* HtmlBlockCloseHead) */
+		/* 91 Ticks1 <- ('`' !'`') */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleHtmlBlockOpenHead]() {
-				goto l571
-			}
-		l572:
-			{
-				position573 := position
-				if !p.rules[ruleHtmlBlockCloseHead]() {
-					goto l574
-				}
-				goto l573
-			l574:
-				if !matchDot() {
-					goto l573
-				}
-				goto l572
-			l573:
-				position = position573
+			if !matchChar('`') {
+				goto l900
 			}
-			if !p.rules[ruleHtmlBlockCloseHead]() {
-				goto l571
+			if peekChar('`') {
+				goto l900
 			}
 			return true
-		l571:
+		l900:
 			position = position0
 			return false
 		},
-		/* 133 HtmlBlockInTags <- (HtmlBlockAddress / HtmlBlockBlockquote / HtmlBlockCenter / HtmlBlockDir / HtmlBlockDiv / HtmlBlockDl / HtmlBlockFieldset / HtmlBlockForm / HtmlBlockH1 / HtmlBlockH2 / HtmlBlockH3 / HtmlBlockH4 / HtmlBlockH5 / HtmlBlockH6 / HtmlBlockMenu / HtmlBlockNoframes / HtmlBlockNoscript / HtmlBlockOl / HtmlBlockP / HtmlBlockPre / HtmlBlockTable / HtmlBlockUl / HtmlBlockDd / HtmlBlockDt / HtmlBlockFrameset / HtmlBlockLi / HtmlBlockTbody / HtmlBlockTd / HtmlBlockTfoot / HtmlBlockTh / HtmlBlockThead / HtmlBlockTr / HtmlBlockScript / HtmlBlockHead) */
-		func() bool {
-			if !p.rules[ruleHtmlBlockAddress]() {
-				goto l577
-			}
-			goto l576
-		l577:
-			if !p.rules[ruleHtmlBlockBlockquote]() {
-				goto l578
-			}
-			goto l576
-		l578:
-			if !p.rules[ruleHtmlBlockCenter]() {
-				goto l579
-			}
-			goto l576
-		l579:
-			if !p.rules[ruleHtmlBlockDir]() {
-				goto l580
-			}
-			goto l576
-		l580:
-			if !p.rules[ruleHtmlBlockDiv]() {
-				goto l581
-			}
-			goto l576
-		l581:
-			if !p.rules[ruleHtmlBlockDl]() {
-				goto l582
-			}
-			goto l576
-		l582:
-			if !p.rules[ruleHtmlBlockFieldset]() {
-				goto l583
-			}
-			goto l576
-		l583:
-			if !p.rules[ruleHtmlBlockForm]() {
-				goto l584
-			}
-			goto l576
-		l584:
-			if !p.rules[ruleHtmlBlockH1]() {
-				goto l585
-			}
-			goto l576
-		l585:
-			if !p.rules[ruleHtmlBlockH2]() {
-				goto l586
-			}
-			goto l576
-		l586:
-			if !p.rules[ruleHtmlBlockH3]() {
-				goto l587
-			}
-			goto l576
-		l587:
-			if !p.rules[ruleHtmlBlockH4]() {
-				goto l588
-			}
-			goto l576
-		l588:
-			if !p.rules[ruleHtmlBlockH5]() {
-				goto l589
-			}
-			goto l576
-		l589:
-			if !p.rules[ruleHtmlBlockH6]() {
-				goto l590
-			}
-			goto l576
-		l590:
-			if !p.rules[ruleHtmlBlockMenu]() {
-				goto l591
-			}
-			goto l576
-		l591:
-			if !p.rules[ruleHtmlBlockNoframes]() {
-				goto l592
-			}
-			goto l576
-		l592:
-			if !p.rules[ruleHtmlBlockNoscript]() {
-				goto l593
-			}
-			goto l576
-		l593:
-			if !p.rules[ruleHtmlBlockOl]() {
-				goto l594
-			}
-			goto l576
-		l594:
-			if !p.rules[ruleHtmlBlockP]() {
-				goto l595
-			}
-			goto l576
-		l595:
-			if !p.rules[ruleHtmlBlockPre]() {
-				goto l596
-			}
-			goto l576
-		l596:
-			if !p.rules[ruleHtmlBlockTable]() {
-				goto l597
-			}
-			goto l576
-		l597:
-			if !p.rules[ruleHtmlBlockUl]() {
-				goto l598
-			}
-			goto l576
-		l598:
-			if !p.rules[ruleHtmlBlockDd]() {
-				goto l599
-			}
-			goto l576
-		l599:
-			if !p.rules[ruleHtmlBlockDt]() {
-				goto l600
-			}
-			goto l576
-		l600:
-			if !p.rules[ruleHtmlBlockFrameset]() {
-				goto l601
-			}
-			goto l576
-		l601:
-			if !p.rules[ruleHtmlBlockLi]() {
-				goto l602
-			}
-			goto l576
-		l602:
-			if !p.rules[ruleHtmlBlockTbody]() {
-				goto l603
-			}
-			goto l576
-		l603:
-			if !p.rules[ruleHtmlBlockTd]() {
-				goto l604
-			}
-			goto l576
-		l604:
-			if !p.rules[ruleHtmlBlockTfoot]() {
-				goto l605
-			}
-			goto l576
-		l605:
-			if !p.rules[ruleHtmlBlockTh]() {
-				goto l606
-			}
-			goto l576
-		l606:
-			if !p.rules[ruleHtmlBlockThead]() {
-				goto l607
-			}
-			goto l576
-		l607:
-			if !p.rules[ruleHtmlBlockTr]() {
-				goto l608
-			}
-			goto l576
-		l608:
-			if !p.rules[ruleHtmlBlockScript]() {
-				goto l609
+		/* 92 Ticks2 <- ('``' !'`') */
+		func() bool {
+			position0 := position
+			if !matchString("``") {
+				goto l901
 			}
-			goto l576
-		l609:
-			if !p.rules[ruleHtmlBlockHead]() {
-				goto l575
+			if peekChar('`') {
+				goto l901
 			}
-		l576:
 			return true
-		l575:
+		l901:
+			position = position0
 			return false
 		},
-		/* 134 HtmlBlock <- (&'<' < (HtmlBlockInTags / HtmlComment / HtmlBlockSelfClosing) > BlankLine+ {   if p.extension.FilterHTML {
-                    yy = p.mkList(LIST, nil)
-                } else {
-                    yy = p.mkString(yytext)
-                    yy.key = HTMLBLOCK
-                }
-            }) */
+		/* 93 Ticks3 <- ('```' !'`') */
 		func() bool {
 			position0 := position
-			if !peekChar('<') {
-				goto l610
-			}
-			begin = position
-			if !p.rules[ruleHtmlBlockInTags]() {
-				goto l612
-			}
-			goto l611
-		l612:
-			if !p.rules[ruleHtmlComment]() {
-				goto l613
-			}
-			goto l611
-		l613:
-			if !p.rules[ruleHtmlBlockSelfClosing]() {
-				goto l610
-			}
-		l611:
-			end = position
-			if !p.rules[ruleBlankLine]() {
-				goto l610
+			if !matchString("```") {
+				goto l902
 			}
-		l614:
-			if !p.rules[ruleBlankLine]() {
-				goto l615
+			if peekChar('`') {
+				goto l902
 			}
-			goto l614
-		l615:
-			do(41)
 			return true
-		l610:
+		l902:
 			position = position0
 			return false
 		},
-		/* 135 HtmlBlockSelfClosing <- ('<' Spnl HtmlBlockType Spnl HtmlAttribute* '/' Spnl '>') */
+		/* 94 Ticks4 <- ('````' !'`') */
 		func() bool {
 			position0 := position
-			if !matchChar('<') {
-				goto l616
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l616
-			}
-			if !p.rules[ruleHtmlBlockType]() {
-				goto l616
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l616
-			}
-		l617:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l618
-			}
-			goto l617
-		l618:
-			if !matchChar('/') {
-				goto l616
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l616
+			if !matchString("````") {
+				goto l903
 			}
-			if !matchChar('>') {
-				goto l616
+			if peekChar('`') {
+				goto l903
 			}
 			return true
-		l616:
+		l903:
 			position = position0
 			return false
 		},
-		/* 136 HtmlBlockType <- ('dir' / 'div' / 'dl' / 'fieldset' / 'form' / 'h1' / 'h2' / 'h3' / 'h4' / 'h5' / 'h6' / 'noframes' / 'p' / 'table' / 'dd' / 'tbody' / 'td' / 'tfoot' / 'th' / 'thead' / 'DIR' / 'DIV' / 'DL' / 'FIELDSET' / 'FORM' / 'H1' / 'H2' / 'H3' / 'H4' / 'H5' / 'H6' / 'NOFRAMES' / 'P' / 'TABLE' / 'DD' / 'TBODY' / 'TD' / 'TFOOT' / 'TH' / 'THEAD' / ((&[S] 'SCRIPT') | (&[T] 'TR') | (&[L] 'LI') | (&[F] 'FRAMESET') | (&[D] 'DT') | (&[U] 'UL') | (&[P] 'PRE') | (&[O] 'OL') | (&[N] 'NOSCRIPT') | (&[M] 'MENU') | (&[I] 'ISINDEX') | (&[H] 'HR') | (&[C] 'CENTER') | (&[B] 'BLOCKQUOTE') | (&[A] 'ADDRESS') | (&[s] 'script') | (&[t] 'tr') | (&[l] 'li') | (&[f] 'frameset') | (&[d] 'dt') | (&[u] 'ul') | (&[p] 'pre') | (&[o] 'ol') | (&[n] 'noscript') | (&[m] 'menu') | (&[i] 'isindex') | (&[h] 'hr') | (&[c] 'center') | (&[b] 'blockquote') | (&[a] 'address'))) */
+		/* 95 Ticks5 <- ('`````' !'`') */
 		func() bool {
-			if !matchString("dir") {
-				goto l621
-			}
-			goto l620
-		l621:
-			if !matchString("div") {
-				goto l622
+			position0 := position
+			if !matchString("`````") {
+				goto l904
 			}
-			goto l620
-		l622:
-			if !matchString("dl") {
-				goto l623
+			if peekChar('`') {
+				goto l904
 			}
-			goto l620
-		l623:
-			if !matchString("fieldset") {
-				goto l624
+			return true
+		l904:
+			position = position0
+			return false
+		},
+		/* 96 Code <- (&{!p.extension.NoCode} ((Ticks1 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks1 '`'+)) | (&[\t\n\r ] (!(Sp Ticks1) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks1) / (Ticks2 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks2 '`'+)) | (&[\t\n\r ] (!(Sp Ticks2) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks2) / (Ticks3 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks3 '`'+)) | (&[\t\n\r ] (!(Sp Ticks3) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks3) / (Ticks4 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks4 '`'+)) | (&[\t\n\r ] (!(Sp Ticks4) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks4) / (Ticks5 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks5 '`'+)) | (&[\t\n\r ] (!(Sp Ticks5) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks5)) { yy = p.mkString(yytext); yy.key = CODE }) */
+		func() bool {
+			position0 := position
+			if !(!p.extension.NoCode) {
+				goto l905
 			}
-			goto l620
-		l624:
-			if !matchString("form") {
-				goto l625
-			}
-			goto l620
-		l625:
-			if !matchString("h1") {
-				goto l626
-			}
-			goto l620
-		l626:
-			if !matchString("h2") {
-				goto l627
-			}
-			goto l620
-		l627:
-			if !matchString("h3") {
-				goto l628
-			}
-			goto l620
-		l628:
-			if !matchString("h4") {
-				goto l629
-			}
-			goto l620
-		l629:
-			if !matchString("h5") {
-				goto l630
-			}
-			goto l620
-		l630:
-			if !matchString("h6") {
-				goto l631
-			}
-			goto l620
-		l631:
-			if !matchString("noframes") {
-				goto l632
-			}
-			goto l620
-		l632:
-			if !matchChar('p') {
-				goto l633
-			}
-			goto l620
-		l633:
-			if !matchString("table") {
-				goto l634
-			}
-			goto l620
-		l634:
-			if !matchString("dd") {
-				goto l635
-			}
-			goto l620
-		l635:
-			if !matchString("tbody") {
-				goto l636
-			}
-			goto l620
-		l636:
-			if !matchString("td") {
-				goto l637
-			}
-			goto l620
-		l637:
-			if !matchString("tfoot") {
-				goto l638
-			}
-			goto l620
-		l638:
-			if !matchString("th") {
-				goto l639
-			}
-			goto l620
-		l639:
-			if !matchString("thead") {
-				goto l640
-			}
-			goto l620
-		l640:
-			if !matchString("DIR") {
-				goto l641
-			}
-			goto l620
-		l641:
-			if !matchString("DIV") {
-				goto l642
-			}
-			goto l620
-		l642:
-			if !matchString("DL") {
-				goto l643
-			}
-			goto l620
-		l643:
-			if !matchString("FIELDSET") {
-				goto l644
-			}
-			goto l620
-		l644:
-			if !matchString("FORM") {
-				goto l645
-			}
-			goto l620
-		l645:
-			if !matchString("H1") {
-				goto l646
-			}
-			goto l620
-		l646:
-			if !matchString("H2") {
-				goto l647
-			}
-			goto l620
-		l647:
-			if !matchString("H3") {
-				goto l648
-			}
-			goto l620
-		l648:
-			if !matchString("H4") {
-				goto l649
-			}
-			goto l620
-		l649:
-			if !matchString("H5") {
-				goto l650
-			}
-			goto l620
-		l650:
-			if !matchString("H6") {
-				goto l651
-			}
-			goto l620
-		l651:
-			if !matchString("NOFRAMES") {
-				goto l652
-			}
-			goto l620
-		l652:
-			if !matchChar('P') {
-				goto l653
-			}
-			goto l620
-		l653:
-			if !matchString("TABLE") {
-				goto l654
-			}
-			goto l620
-		l654:
-			if !matchString("DD") {
-				goto l655
-			}
-			goto l620
-		l655:
-			if !matchString("TBODY") {
-				goto l656
-			}
-			goto l620
-		l656:
-			if !matchString("TD") {
-				goto l657
-			}
-			goto l620
-		l657:
-			if !matchString("TFOOT") {
-				goto l658
-			}
-			goto l620
-		l658:
-			if !matchString("TH") {
-				goto l659
-			}
-			goto l620
-		l659:
-			if !matchString("THEAD") {
-				goto l660
-			}
-			goto l620
-		l660:
-			{
-				if position == len(p.Buffer) {
-					goto l619
-				}
-				switch p.Buffer[position] {
-				case 'S':
-					position++
-					if !matchString("CRIPT") {
-						goto l619
-					}
-					break
-				case 'T':
-					position++ // matchString(`TR`)
-					if !matchChar('R') {
-						goto l619
-					}
-					break
-				case 'L':
-					position++ // matchString(`LI`)
-					if !matchChar('I') {
-						goto l619
-					}
-					break
-				case 'F':
-					position++
-					if !matchString("RAMESET") {
-						goto l619
-					}
-					break
-				case 'D':
-					position++ // matchString(`DT`)
-					if !matchChar('T') {
-						goto l619
-					}
-					break
-				case 'U':
-					position++ // matchString(`UL`)
-					if !matchChar('L') {
-						goto l619
-					}
-					break
-				case 'P':
-					position++
-					if !matchString("RE") {
-						goto l619
-					}
-					break
-				case 'O':
-					position++ // matchString(`OL`)
-					if !matchChar('L') {
-						goto l619
-					}
-					break
-				case 'N':
-					position++
-					if !matchString("OSCRIPT") {
-						goto l619
-					}
-					break
-				case 'M':
-					position++
-					if !matchString("ENU") {
-						goto l619
-					}
-					break
-				case 'I':
-					position++
-					if !matchString("SINDEX") {
-						goto l619
-					}
-					break
-				case 'H':
-					position++ // matchString(`HR`)
-					if !matchChar('R') {
-						goto l619
-					}
-					break
-				case 'C':
-					position++
-					if !matchString("ENTER") {
-						goto l619
-					}
-					break
-				case 'B':
-					position++
-					if !matchString("LOCKQUOTE") {
-						goto l619
-					}
-					break
-				case 'A':
-					position++
-					if !matchString("DDRESS") {
-						goto l619
-					}
-					break
-				case 's':
-					position++
-					if !matchString("cript") {
-						goto l619
-					}
-					break
-				case 't':
-					position++ // matchString(`tr`)
-					if !matchChar('r') {
-						goto l619
-					}
-					break
-				case 'l':
-					position++ // matchString(`li`)
-					if !matchChar('i') {
-						goto l619
-					}
-					break
-				case 'f':
-					position++
-					if !matchString("rameset") {
-						goto l619
-					}
-					break
-				case 'd':
-					position++ // matchString(`dt`)
-					if !matchChar('t') {
-						goto l619
-					}
-					break
-				case 'u':
-					position++ // matchString(`ul`)
-					if !matchChar('l') {
-						goto l619
-					}
-					break
-				case 'p':
-					position++
-					if !matchString("re") {
-						goto l619
-					}
-					break
-				case 'o':
-					position++ // matchString(`ol`)
-					if !matchChar('l') {
-						goto l619
-					}
-					break
-				case 'n':
-					position++
-					if !matchString("oscript") {
-						goto l619
-					}
-					break
-				case 'm':
-					position++
-					if !matchString("enu") {
-						goto l619
-					}
-					break
-				case 'i':
-					position++
-					if !matchString("sindex") {
-						goto l619
-					}
-					break
-				case 'h':
-					position++ // matchString(`hr`)
-					if !matchChar('r') {
-						goto l619
-					}
-					break
-				case 'c':
-					position++
-					if !matchString("enter") {
-						goto l619
-					}
-					break
-				case 'b':
-					position++
-					if !matchString("lockquote") {
-						goto l619
-					}
-					break
-				case 'a':
-					position++
-					if !matchString("ddress") {
-						goto l619
-					}
-					break
-				default:
-					goto l619
-				}
-			}
-		l620:
-			return true
-		l619:
-			return false
-		},
-		/* 137 StyleOpen <- ('<' Spnl ((&[S] 'STYLE') | (&[s] 'style')) Spnl HtmlAttribute* '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l662
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l662
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l662
-				}
-				switch p.Buffer[position] {
-				case 'S':
-					position++
-					if !matchString("TYLE") {
-						goto l662
-					}
-					break
-				case 's':
-					position++
-					if !matchString("tyle") {
-						goto l662
-					}
-					break
-				default:
-					goto l662
-				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l662
-			}
-		l664:
-			if !p.rules[ruleHtmlAttribute]() {
-				goto l665
-			}
-			goto l664
-		l665:
-			if !matchChar('>') {
-				goto l662
-			}
-			return true
-		l662:
-			position = position0
-			return false
-		},
-		/* 138 StyleClose <- ('<' Spnl '/' ((&[S] 'STYLE') | (&[s] 'style')) Spnl '>') */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l666
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l666
-			}
-			if !matchChar('/') {
-				goto l666
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l666
-				}
-				switch p.Buffer[position] {
-				case 'S':
-					position++
-					if !matchString("TYLE") {
-						goto l666
-					}
-					break
-				case 's':
-					position++
-					if !matchString("tyle") {
-						goto l666
-					}
-					break
-				default:
-					goto l666
-				}
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l666
-			}
-			if !matchChar('>') {
-				goto l666
-			}
-			return true
-		l666:
-			position = position0
-			return false
-		},
-		/* 139 InStyleTags <- (StyleOpen (!StyleClose .)* StyleClose) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleStyleOpen]() {
-				goto l668
-			}
-		l669:
-			{
-				position670 := position
-				if !p.rules[ruleStyleClose]() {
-					goto l671
-				}
-				goto l670
-			l671:
-				if !matchDot() {
-					goto l670
-				}
-				goto l669
-			l670:
-				position = position670
-			}
-			if !p.rules[ruleStyleClose]() {
-				goto l668
-			}
-			return true
-		l668:
-			position = position0
-			return false
-		},
-		/* 140 StyleBlock <- (< InStyleTags > BlankLine* {   if p.extension.FilterStyles {
-                        yy = p.mkList(LIST, nil)
-                    } else {
-                        yy = p.mkString(yytext)
-                        yy.key = HTMLBLOCK
-                    }
-                }) */
-		func() bool {
-			position0 := position
-			begin = position
-			if !p.rules[ruleInStyleTags]() {
-				goto l672
-			}
-			end = position
-		l673:
-			if !p.rules[ruleBlankLine]() {
-				goto l674
-			}
-			goto l673
-		l674:
-			do(42)
-			return true
-		l672:
-			position = position0
-			return false
-		},
-		/* 141 Inlines <- (StartList ((!Endline Inline { a = cons(yy, a) }) / (Endline &Inline { a = cons(c, a) }))+ Endline? { yy = p.mkList(LIST, a) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !p.rules[ruleStartList]() {
-				goto l675
-			}
-			doarg(yySet, -1)
-			{
-				position678 := position
-				if !p.rules[ruleEndline]() {
-					goto l680
-				}
-				goto l679
-			l680:
-				if !p.rules[ruleInline]() {
-					goto l679
-				}
-				do(43)
-				goto l678
-			l679:
-				position = position678
-				if !p.rules[ruleEndline]() {
-					goto l675
-				}
-				doarg(yySet, -2)
-				{
-					position681 := position
-					if !p.rules[ruleInline]() {
-						goto l675
-					}
-					position = position681
-				}
-				do(44)
-			}
-		l678:
-		l676:
-			{
-				position677, thunkPosition677 := position, thunkPosition
-				{
-					position682 := position
-					if !p.rules[ruleEndline]() {
-						goto l684
-					}
-					goto l683
-				l684:
-					if !p.rules[ruleInline]() {
-						goto l683
-					}
-					do(43)
-					goto l682
-				l683:
-					position = position682
-					if !p.rules[ruleEndline]() {
-						goto l677
-					}
-					doarg(yySet, -2)
-					{
-						position685 := position
-						if !p.rules[ruleInline]() {
-							goto l677
-						}
-						position = position685
-					}
-					do(44)
-				}
-			l682:
-				goto l676
-			l677:
-				position, thunkPosition = position677, thunkPosition677
-			}
-			if !p.rules[ruleEndline]() {
-				goto l686
-			}
-		l686:
-			do(45)
-			doarg(yyPop, 2)
-			return true
-		l675:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 142 Inline <- (Str / Endline / UlOrStarLine / Space / Strong / Emph / Image / Link / NoteReference / InlineNote / Code / RawHtml / Entity / EscapedChar / Smart / Symbol) */
-		func() bool {
-			if !p.rules[ruleStr]() {
-				goto l690
-			}
-			goto l689
-		l690:
-			if !p.rules[ruleEndline]() {
-				goto l691
-			}
-			goto l689
-		l691:
-			if !p.rules[ruleUlOrStarLine]() {
-				goto l692
-			}
-			goto l689
-		l692:
-			if !p.rules[ruleSpace]() {
-				goto l693
-			}
-			goto l689
-		l693:
-			if !p.rules[ruleStrong]() {
-				goto l694
-			}
-			goto l689
-		l694:
-			if !p.rules[ruleEmph]() {
-				goto l695
-			}
-			goto l689
-		l695:
-			if !p.rules[ruleImage]() {
-				goto l696
-			}
-			goto l689
-		l696:
-			if !p.rules[ruleLink]() {
-				goto l697
-			}
-			goto l689
-		l697:
-			if !p.rules[ruleNoteReference]() {
-				goto l698
-			}
-			goto l689
-		l698:
-			if !p.rules[ruleInlineNote]() {
-				goto l699
-			}
-			goto l689
-		l699:
-			if !p.rules[ruleCode]() {
-				goto l700
-			}
-			goto l689
-		l700:
-			if !p.rules[ruleRawHtml]() {
-				goto l701
-			}
-			goto l689
-		l701:
-			if !p.rules[ruleEntity]() {
-				goto l702
-			}
-			goto l689
-		l702:
-			if !p.rules[ruleEscapedChar]() {
-				goto l703
-			}
-			goto l689
-		l703:
-			if !p.rules[ruleSmart]() {
-				goto l704
-			}
-			goto l689
-		l704:
-			if !p.rules[ruleSymbol]() {
-				goto l688
-			}
-		l689:
-			return true
-		l688:
-			return false
-		},
-		/* 143 Space <- (Spacechar+ { yy = p.mkString(" ")
-          yy.key = SPACE }) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleSpacechar]() {
-				goto l705
-			}
-		l706:
-			if !p.rules[ruleSpacechar]() {
-				goto l707
-			}
-			goto l706
-		l707:
-			do(46)
-			return true
-		l705:
-			position = position0
-			return false
-		},
-		/* 144 Str <- (StartList < NormalChar+ > { a = cons(p.mkString(yytext), a) } (StrChunk { a = cons(yy, a) })* { if a.next == nil { yy = a; } else { yy = p.mkList(LIST, a) } }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l708
-			}
-			doarg(yySet, -1)
-			begin = position
-			if !p.rules[ruleNormalChar]() {
-				goto l708
-			}
-		l709:
-			if !p.rules[ruleNormalChar]() {
-				goto l710
-			}
-			goto l709
-		l710:
-			end = position
-			do(47)
-		l711:
-			{
-				position712, thunkPosition712 := position, thunkPosition
-				if !p.rules[ruleStrChunk]() {
-					goto l712
-				}
-				do(48)
-				goto l711
-			l712:
-				position, thunkPosition = position712, thunkPosition712
-			}
-			do(49)
-			doarg(yyPop, 1)
-			return true
-		l708:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 145 StrChunk <- ((< (NormalChar / ('_'+ &Alphanumeric))+ > { yy = p.mkString(yytext) }) / AposChunk) */
-		func() bool {
-			position0 := position
-			{
-				position714 := position
-				begin = position
-				if !p.rules[ruleNormalChar]() {
-					goto l719
-				}
-				goto l718
-			l719:
-				if !matchChar('_') {
-					goto l715
-				}
-			l720:
-				if !matchChar('_') {
-					goto l721
-				}
-				goto l720
-			l721:
-				{
-					position722 := position
-					if !p.rules[ruleAlphanumeric]() {
-						goto l715
-					}
-					position = position722
-				}
-			l718:
-			l716:
-				{
-					position717 := position
-					if !p.rules[ruleNormalChar]() {
-						goto l724
-					}
-					goto l723
-				l724:
-					if !matchChar('_') {
-						goto l717
-					}
-				l725:
-					if !matchChar('_') {
-						goto l726
-					}
-					goto l725
-				l726:
-					{
-						position727 := position
-						if !p.rules[ruleAlphanumeric]() {
-							goto l717
-						}
-						position = position727
-					}
-				l723:
-					goto l716
-				l717:
-					position = position717
-				}
-				end = position
-				do(50)
-				goto l714
-			l715:
-				position = position714
-				if !p.rules[ruleAposChunk]() {
-					goto l713
-				}
-			}
-		l714:
-			return true
-		l713:
-			position = position0
-			return false
-		},
-		/* 146 AposChunk <- (&{p.extension.Smart} '\'' &Alphanumeric { yy = p.mkElem(APOSTROPHE) }) */
-		func() bool {
-			position0 := position
-			if !(p.extension.Smart) {
-				goto l728
-			}
-			if !matchChar('\'') {
-				goto l728
-			}
-			{
-				position729 := position
-				if !p.rules[ruleAlphanumeric]() {
-					goto l728
-				}
-				position = position729
-			}
-			do(51)
-			return true
-		l728:
-			position = position0
-			return false
-		},
-		/* 147 EscapedChar <- ('\\' !Newline < [-\\`|*_{}[\]()#+.!><] > { yy = p.mkString(yytext) }) */
-		func() bool {
-			position0 := position
-			if !matchChar('\\') {
-				goto l730
-			}
-			if !p.rules[ruleNewline]() {
-				goto l731
-			}
-			goto l730
-		l731:
-			begin = position
-			if !matchClass(1) {
-				goto l730
-			}
-			end = position
-			do(52)
-			return true
-		l730:
-			position = position0
-			return false
-		},
-		/* 148 Entity <- ((HexEntity / DecEntity / CharEntity) { yy = p.mkString(yytext); yy.key = HTML }) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleHexEntity]() {
-				goto l734
-			}
-			goto l733
-		l734:
-			if !p.rules[ruleDecEntity]() {
-				goto l735
-			}
-			goto l733
-		l735:
-			if !p.rules[ruleCharEntity]() {
-				goto l732
-			}
-		l733:
-			do(53)
-			return true
-		l732:
-			position = position0
-			return false
-		},
-		/* 149 Endline <- (LineBreak / TerminalEndline / NormalEndline) */
-		func() bool {
-			if !p.rules[ruleLineBreak]() {
-				goto l738
-			}
-			goto l737
-		l738:
-			if !p.rules[ruleTerminalEndline]() {
-				goto l739
-			}
-			goto l737
-		l739:
-			if !p.rules[ruleNormalEndline]() {
-				goto l736
-			}
-		l737:
-			return true
-		l736:
-			return false
-		},
-		/* 150 NormalEndline <- (Sp Newline !BlankLine !'>' !AtxStart !(Line ((&[\-] '-'+) | (&[=] '='+)) Newline) { yy = p.mkString("\n")
-                    yy.key = SPACE }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			if !p.rules[ruleSp]() {
-				goto l740
-			}
-			if !p.rules[ruleNewline]() {
-				goto l740
-			}
-			if !p.rules[ruleBlankLine]() {
-				goto l741
-			}
-			goto l740
-		l741:
-			if peekChar('>') {
-				goto l740
-			}
-			if !p.rules[ruleAtxStart]() {
-				goto l742
-			}
-			goto l740
-		l742:
-			{
-				position743, thunkPosition743 := position, thunkPosition
-				if !p.rules[ruleLine]() {
-					goto l743
-				}
-				{
-					if position == len(p.Buffer) {
-						goto l743
-					}
-					switch p.Buffer[position] {
-					case '-':
-						if !matchChar('-') {
-							goto l743
-						}
-					l745:
-						if !matchChar('-') {
-							goto l746
-						}
-						goto l745
-					l746:
-						break
-					case '=':
-						if !matchChar('=') {
-							goto l743
-						}
-					l747:
-						if !matchChar('=') {
-							goto l748
-						}
-						goto l747
-					l748:
-						break
-					default:
-						goto l743
-					}
-				}
-				if !p.rules[ruleNewline]() {
-					goto l743
-				}
-				goto l740
-			l743:
-				position, thunkPosition = position743, thunkPosition743
-			}
-			do(54)
-			return true
-		l740:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 151 TerminalEndline <- (Sp Newline !. { yy = nil }) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleSp]() {
-				goto l749
-			}
-			if !p.rules[ruleNewline]() {
-				goto l749
-			}
-			if (position < len(p.Buffer)) {
-				goto l749
-			}
-			do(55)
-			return true
-		l749:
-			position = position0
-			return false
-		},
-		/* 152 LineBreak <- ('  ' NormalEndline { yy = p.mkElem(LINEBREAK) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			if !matchString("  ") {
-				goto l750
-			}
-			if !p.rules[ruleNormalEndline]() {
-				goto l750
-			}
-			do(56)
-			return true
-		l750:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 153 Symbol <- (< SpecialChar > { yy = p.mkString(yytext) }) */
-		func() bool {
-			position0 := position
-			begin = position
-			if !p.rules[ruleSpecialChar]() {
-				goto l751
-			}
-			end = position
-			do(57)
-			return true
-		l751:
-			position = position0
-			return false
-		},
-		/* 154 UlOrStarLine <- ((UlLine / StarLine) { yy = p.mkString(yytext) }) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleUlLine]() {
-				goto l754
-			}
-			goto l753
-		l754:
-			if !p.rules[ruleStarLine]() {
-				goto l752
-			}
-		l753:
-			do(58)
-			return true
-		l752:
-			position = position0
-			return false
-		},
-		/* 155 StarLine <- ((&[*] (< '****' '*'* >)) | (&[\t ] (< Spacechar '*'+ &Spacechar >))) */
-		func() bool {
-			position0 := position
-			{
-				if position == len(p.Buffer) {
-					goto l755
-				}
-				switch p.Buffer[position] {
-				case '*':
-					begin = position
-					if !matchString("****") {
-						goto l755
-					}
-				l757:
-					if !matchChar('*') {
-						goto l758
-					}
-					goto l757
-				l758:
-					end = position
-					break
-				case '\t', ' ':
-					begin = position
-					if !p.rules[ruleSpacechar]() {
-						goto l755
-					}
-					if !matchChar('*') {
-						goto l755
-					}
-				l759:
-					if !matchChar('*') {
-						goto l760
-					}
-					goto l759
-				l760:
-					{
-						position761 := position
-						if !p.rules[ruleSpacechar]() {
-							goto l755
-						}
-						position = position761
-					}
-					end = position
-					break
-				default:
-					goto l755
-				}
-			}
-			return true
-		l755:
-			position = position0
-			return false
-		},
-		/* 156 UlLine <- ((&[_] (< '____' '_'* >)) | (&[\t ] (< Spacechar '_'+ &Spacechar >))) */
-		func() bool {
-			position0 := position
-			{
-				if position == len(p.Buffer) {
-					goto l762
-				}
-				switch p.Buffer[position] {
-				case '_':
-					begin = position
-					if !matchString("____") {
-						goto l762
-					}
-				l764:
-					if !matchChar('_') {
-						goto l765
-					}
-					goto l764
-				l765:
-					end = position
-					break
-				case '\t', ' ':
-					begin = position
-					if !p.rules[ruleSpacechar]() {
-						goto l762
-					}
-					if !matchChar('_') {
-						goto l762
-					}
-				l766:
-					if !matchChar('_') {
-						goto l767
-					}
-					goto l766
-				l767:
-					{
-						position768 := position
-						if !p.rules[ruleSpacechar]() {
-							goto l762
-						}
-						position = position768
-					}
-					end = position
-					break
-				default:
-					goto l762
-				}
-			}
-			return true
-		l762:
-			position = position0
-			return false
-		},
-		/* 157 Emph <- ((&[_] EmphUl) | (&[*] EmphStar)) */
-		func() bool {
-			{
-				if position == len(p.Buffer) {
-					goto l769
-				}
-				switch p.Buffer[position] {
-				case '_':
-					if !p.rules[ruleEmphUl]() {
-						goto l769
-					}
-					break
-				case '*':
-					if !p.rules[ruleEmphStar]() {
-						goto l769
-					}
-					break
-				default:
-					goto l769
-				}
-			}
-			return true
-		l769:
-			return false
-		},
-		/* 158 Whitespace <- ((&[\n\r] Newline) | (&[\t ] Spacechar)) */
-		func() bool {
-			{
-				if position == len(p.Buffer) {
-					goto l771
-				}
-				switch p.Buffer[position] {
-				case '\n', '\r':
-					if !p.rules[ruleNewline]() {
-						goto l771
-					}
-					break
-				case '\t', ' ':
-					if !p.rules[ruleSpacechar]() {
-						goto l771
-					}
-					break
-				default:
-					goto l771
-				}
-			}
-			return true
-		l771:
-			return false
-		},
-		/* 159 EmphStar <- ('*' !Whitespace StartList ((!'*' Inline { a = cons(b, a) }) / (StrongStar { a = cons(b, a) }))+ '*' { yy = p.mkList(EMPH, a) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !matchChar('*') {
-				goto l773
-			}
-			if !p.rules[ruleWhitespace]() {
-				goto l774
-			}
-			goto l773
-		l774:
-			if !p.rules[ruleStartList]() {
-				goto l773
-			}
-			doarg(yySet, -2)
-			{
-				position777, thunkPosition777 := position, thunkPosition
-				if peekChar('*') {
-					goto l778
-				}
-				if !p.rules[ruleInline]() {
-					goto l778
-				}
-				doarg(yySet, -1)
-				do(59)
-				goto l777
-			l778:
-				position, thunkPosition = position777, thunkPosition777
-				if !p.rules[ruleStrongStar]() {
-					goto l773
-				}
-				doarg(yySet, -1)
-				do(60)
-			}
-		l777:
-		l775:
-			{
-				position776, thunkPosition776 := position, thunkPosition
-				{
-					position779, thunkPosition779 := position, thunkPosition
-					if peekChar('*') {
-						goto l780
-					}
-					if !p.rules[ruleInline]() {
-						goto l780
-					}
-					doarg(yySet, -1)
-					do(59)
-					goto l779
-				l780:
-					position, thunkPosition = position779, thunkPosition779
-					if !p.rules[ruleStrongStar]() {
-						goto l776
-					}
-					doarg(yySet, -1)
-					do(60)
-				}
-			l779:
-				goto l775
-			l776:
-				position, thunkPosition = position776, thunkPosition776
-			}
-			if !matchChar('*') {
-				goto l773
-			}
-			do(61)
-			doarg(yyPop, 2)
-			return true
-		l773:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 160 EmphUl <- ('_' !Whitespace StartList ((!'_' Inline { a = cons(b, a) }) / (StrongUl { a = cons(b, a) }))+ '_' { yy = p.mkList(EMPH, a) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !matchChar('_') {
-				goto l781
-			}
-			if !p.rules[ruleWhitespace]() {
-				goto l782
-			}
-			goto l781
-		l782:
-			if !p.rules[ruleStartList]() {
-				goto l781
-			}
-			doarg(yySet, -2)
-			{
-				position785, thunkPosition785 := position, thunkPosition
-				if peekChar('_') {
-					goto l786
-				}
-				if !p.rules[ruleInline]() {
-					goto l786
-				}
-				doarg(yySet, -1)
-				do(62)
-				goto l785
-			l786:
-				position, thunkPosition = position785, thunkPosition785
-				if !p.rules[ruleStrongUl]() {
-					goto l781
-				}
-				doarg(yySet, -1)
-				do(63)
-			}
-		l785:
-		l783:
-			{
-				position784, thunkPosition784 := position, thunkPosition
-				{
-					position787, thunkPosition787 := position, thunkPosition
-					if peekChar('_') {
-						goto l788
-					}
-					if !p.rules[ruleInline]() {
-						goto l788
-					}
-					doarg(yySet, -1)
-					do(62)
-					goto l787
-				l788:
-					position, thunkPosition = position787, thunkPosition787
-					if !p.rules[ruleStrongUl]() {
-						goto l784
-					}
-					doarg(yySet, -1)
-					do(63)
-				}
-			l787:
-				goto l783
-			l784:
-				position, thunkPosition = position784, thunkPosition784
-			}
-			if !matchChar('_') {
-				goto l781
-			}
-			do(64)
-			doarg(yyPop, 2)
-			return true
-		l781:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 161 Strong <- ((&[_] StrongUl) | (&[*] StrongStar)) */
-		func() bool {
-			{
-				if position == len(p.Buffer) {
-					goto l789
-				}
-				switch p.Buffer[position] {
-				case '_':
-					if !p.rules[ruleStrongUl]() {
-						goto l789
-					}
-					break
-				case '*':
-					if !p.rules[ruleStrongStar]() {
-						goto l789
-					}
-					break
-				default:
-					goto l789
-				}
-			}
-			return true
-		l789:
-			return false
-		},
-		/* 162 StrongStar <- ('**' !Whitespace StartList (!'**' Inline { a = cons(b, a) })+ '**' { yy = p.mkList(STRONG, a) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !matchString("**") {
-				goto l791
-			}
-			if !p.rules[ruleWhitespace]() {
-				goto l792
-			}
-			goto l791
-		l792:
-			if !p.rules[ruleStartList]() {
-				goto l791
-			}
-			doarg(yySet, -2)
-			if !matchString("**") {
-				goto l795
-			}
-			goto l791
-		l795:
-			if !p.rules[ruleInline]() {
-				goto l791
-			}
-			doarg(yySet, -1)
-			do(65)
-		l793:
-			{
-				position794, thunkPosition794 := position, thunkPosition
-				if !matchString("**") {
-					goto l796
-				}
-				goto l794
-			l796:
-				if !p.rules[ruleInline]() {
-					goto l794
-				}
-				doarg(yySet, -1)
-				do(65)
-				goto l793
-			l794:
-				position, thunkPosition = position794, thunkPosition794
-			}
-			if !matchString("**") {
-				goto l791
-			}
-			do(66)
-			doarg(yyPop, 2)
-			return true
-		l791:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 163 StrongUl <- ('__' !Whitespace StartList (!'__' Inline { a = cons(b, a) })+ '__' { yy = p.mkList(STRONG, a) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !matchString("__") {
-				goto l797
-			}
-			if !p.rules[ruleWhitespace]() {
-				goto l798
-			}
-			goto l797
-		l798:
-			if !p.rules[ruleStartList]() {
-				goto l797
-			}
-			doarg(yySet, -2)
-			if !matchString("__") {
-				goto l801
-			}
-			goto l797
-		l801:
-			if !p.rules[ruleInline]() {
-				goto l797
-			}
-			doarg(yySet, -1)
-			do(67)
-		l799:
-			{
-				position800, thunkPosition800 := position, thunkPosition
-				if !matchString("__") {
-					goto l802
-				}
-				goto l800
-			l802:
-				if !p.rules[ruleInline]() {
-					goto l800
-				}
-				doarg(yySet, -1)
-				do(67)
-				goto l799
-			l800:
-				position, thunkPosition = position800, thunkPosition800
-			}
-			if !matchString("__") {
-				goto l797
-			}
-			do(68)
-			doarg(yyPop, 2)
-			return true
-		l797:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 164 Image <- ('!' (ExplicitLink / ReferenceLink) {	if yy.key == LINK {
-			yy.key = IMAGE
-		} else {
-			result := yy
-			yy.children = cons(p.mkString("!"), result.children)
-		}
-	}) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			if !matchChar('!') {
-				goto l803
-			}
-			if !p.rules[ruleExplicitLink]() {
-				goto l805
-			}
-			goto l804
-		l805:
-			if !p.rules[ruleReferenceLink]() {
-				goto l803
-			}
-		l804:
-			do(69)
-			return true
-		l803:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 165 Link <- (ExplicitLink / ReferenceLink / AutoLink) */
-		func() bool {
-			if !p.rules[ruleExplicitLink]() {
-				goto l808
-			}
-			goto l807
-		l808:
-			if !p.rules[ruleReferenceLink]() {
-				goto l809
-			}
-			goto l807
-		l809:
-			if !p.rules[ruleAutoLink]() {
-				goto l806
-			}
-		l807:
-			return true
-		l806:
-			return false
-		},
-		/* 166 ReferenceLink <- (ReferenceLinkDouble / ReferenceLinkSingle) */
-		func() bool {
-			if !p.rules[ruleReferenceLinkDouble]() {
-				goto l812
-			}
-			goto l811
-		l812:
-			if !p.rules[ruleReferenceLinkSingle]() {
-				goto l810
-			}
-		l811:
-			return true
-		l810:
-			return false
-		},
-		/* 167 ReferenceLinkDouble <- (Label < Spnl > !'[]' Label {
-                           if match, found := p.findReference(b.children); found {
-                               yy = p.mkLink(a.children, match.url, match.title);
-                               a = nil
-                               b = nil
-                           } else {
-                               result := p.mkElem(LIST)
-                               result.children = cons(p.mkString("["), cons(a, cons(p.mkString("]"), cons(p.mkString(yytext),
-                                                   cons(p.mkString("["), cons(b, p.mkString("]")))))))
-                               yy = result
-                           }
-                       }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !p.rules[ruleLabel]() {
-				goto l813
-			}
-			doarg(yySet, -1)
-			begin = position
-			if !p.rules[ruleSpnl]() {
-				goto l813
-			}
-			end = position
-			if !matchString("[]") {
-				goto l814
-			}
-			goto l813
-		l814:
-			if !p.rules[ruleLabel]() {
-				goto l813
-			}
-			doarg(yySet, -2)
-			do(70)
-			doarg(yyPop, 2)
-			return true
-		l813:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 168 ReferenceLinkSingle <- (Label < (Spnl '[]')? > {
-                           if match, found := p.findReference(a.children); found {
-                               yy = p.mkLink(a.children, match.url, match.title)
-                               a = nil
-                           } else {
-                               result := p.mkElem(LIST)
-                               result.children = cons(p.mkString("["), cons(a, cons(p.mkString("]"), p.mkString(yytext))));
-                               yy = result
-                           }
-                       }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleLabel]() {
-				goto l815
-			}
-			doarg(yySet, -1)
-			begin = position
-			{
-				position816 := position
-				if !p.rules[ruleSpnl]() {
-					goto l816
-				}
-				if !matchString("[]") {
-					goto l816
-				}
-				goto l817
-			l816:
-				position = position816
-			}
-		l817:
-			end = position
-			do(71)
-			doarg(yyPop, 1)
-			return true
-		l815:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 169 ExplicitLink <- (Label '(' Sp Source Spnl Title Sp ')' { yy = p.mkLink(l.children, s.contents.str, t.contents.str)
-                  s = nil
-                  t = nil
-                  l = nil }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 3)
-			if !p.rules[ruleLabel]() {
-				goto l818
-			}
-			doarg(yySet, -1)
-			if !matchChar('(') {
-				goto l818
-			}
-			if !p.rules[ruleSp]() {
-				goto l818
-			}
-			if !p.rules[ruleSource]() {
-				goto l818
-			}
-			doarg(yySet, -3)
-			if !p.rules[ruleSpnl]() {
-				goto l818
-			}
-			if !p.rules[ruleTitle]() {
-				goto l818
-			}
-			doarg(yySet, -2)
-			if !p.rules[ruleSp]() {
-				goto l818
-			}
-			if !matchChar(')') {
-				goto l818
-			}
-			do(72)
-			doarg(yyPop, 3)
-			return true
-		l818:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 170 Source <- ((('<' < SourceContents > '>') / (< SourceContents >)) { yy = p.mkString(yytext) }) */
-		func() bool {
-			position0 := position
-			{
-				position820 := position
-				if !matchChar('<') {
-					goto l821
-				}
-				begin = position
-				if !p.rules[ruleSourceContents]() {
-					goto l821
-				}
-				end = position
-				if !matchChar('>') {
-					goto l821
-				}
-				goto l820
-			l821:
-				position = position820
-				begin = position
-				if !p.rules[ruleSourceContents]() {
-					goto l819
-				}
-				end = position
-			}
-		l820:
-			do(73)
-			return true
-		l819:
-			position = position0
-			return false
-		},
-		/* 171 SourceContents <- ((!'(' !')' !'>' Nonspacechar)+ / ('(' SourceContents ')'))* */
-		func() bool {
-		l823:
-			{
-				position824 := position
-				if position == len(p.Buffer) {
-					goto l826
-				}
-				switch p.Buffer[position] {
-				case '(', ')', '>':
-					goto l826
-				default:
-					if !p.rules[ruleNonspacechar]() {
-						goto l826
-					}
-				}
-			l827:
-				if position == len(p.Buffer) {
-					goto l828
-				}
-				switch p.Buffer[position] {
-				case '(', ')', '>':
-					goto l828
-				default:
-					if !p.rules[ruleNonspacechar]() {
-						goto l828
-					}
-				}
-				goto l827
-			l828:
-				goto l825
-			l826:
-				if !matchChar('(') {
-					goto l824
-				}
-				if !p.rules[ruleSourceContents]() {
-					goto l824
-				}
-				if !matchChar(')') {
-					goto l824
-				}
-			l825:
-				goto l823
-			l824:
-				position = position824
-			}
-			return true
-		},
-		/* 172 Title <- ((TitleSingle / TitleDouble / (< '' >)) { yy = p.mkString(yytext) }) */
-		func() bool {
-			if !p.rules[ruleTitleSingle]() {
-				goto l831
-			}
-			goto l830
-		l831:
-			if !p.rules[ruleTitleDouble]() {
-				goto l832
-			}
-			goto l830
-		l832:
-			begin = position
-			end = position
-		l830:
-			do(74)
-			return true
-		},
-		/* 173 TitleSingle <- ('\'' < (!('\'' Sp ((&[)] ')') | (&[\n\r] Newline))) .)* > '\'') */
-		func() bool {
-			position0 := position
-			if !matchChar('\'') {
-				goto l833
-			}
-			begin = position
-		l834:
-			{
-				position835 := position
-				{
-					position836 := position
-					if !matchChar('\'') {
-						goto l836
-					}
-					if !p.rules[ruleSp]() {
-						goto l836
-					}
-					{
-						if position == len(p.Buffer) {
-							goto l836
-						}
-						switch p.Buffer[position] {
-						case ')':
-							position++ // matchChar
-							break
-						case '\n', '\r':
-							if !p.rules[ruleNewline]() {
-								goto l836
-							}
-							break
-						default:
-							goto l836
-						}
-					}
-					goto l835
-				l836:
-					position = position836
-				}
-				if !matchDot() {
-					goto l835
-				}
-				goto l834
-			l835:
-				position = position835
-			}
-			end = position
-			if !matchChar('\'') {
-				goto l833
-			}
-			return true
-		l833:
-			position = position0
-			return false
-		},
-		/* 174 TitleDouble <- ('"' < (!('"' Sp ((&[)] ')') | (&[\n\r] Newline))) .)* > '"') */
-		func() bool {
-			position0 := position
-			if !matchChar('"') {
-				goto l838
-			}
-			begin = position
-		l839:
-			{
-				position840 := position
-				{
-					position841 := position
-					if !matchChar('"') {
-						goto l841
-					}
-					if !p.rules[ruleSp]() {
-						goto l841
-					}
-					{
-						if position == len(p.Buffer) {
-							goto l841
-						}
-						switch p.Buffer[position] {
-						case ')':
-							position++ // matchChar
-							break
-						case '\n', '\r':
-							if !p.rules[ruleNewline]() {
-								goto l841
-							}
-							break
-						default:
-							goto l841
-						}
-					}
-					goto l840
-				l841:
-					position = position841
-				}
-				if !matchDot() {
-					goto l840
-				}
-				goto l839
-			l840:
-				position = position840
-			}
-			end = position
-			if !matchChar('"') {
-				goto l838
-			}
-			return true
-		l838:
-			position = position0
-			return false
-		},
-		/* 175 AutoLink <- (AutoLinkUrl / AutoLinkEmail) */
-		func() bool {
-			if !p.rules[ruleAutoLinkUrl]() {
-				goto l845
-			}
-			goto l844
-		l845:
-			if !p.rules[ruleAutoLinkEmail]() {
-				goto l843
-			}
-		l844:
-			return true
-		l843:
-			return false
-		},
-		/* 176 AutoLinkUrl <- ('<' < [A-Za-z]+ '://' (!Newline !'>' .)+ > '>' {   yy = p.mkLink(p.mkString(yytext), yytext, "") }) */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l846
-			}
-			begin = position
-			if !matchClass(2) {
-				goto l846
-			}
-		l847:
-			if !matchClass(2) {
-				goto l848
-			}
-			goto l847
-		l848:
-			if !matchString("://") {
-				goto l846
-			}
-			if !p.rules[ruleNewline]() {
-				goto l851
-			}
-			goto l846
-		l851:
-			if peekChar('>') {
-				goto l846
-			}
-			if !matchDot() {
-				goto l846
-			}
-		l849:
-			{
-				position850 := position
-				if !p.rules[ruleNewline]() {
-					goto l852
-				}
-				goto l850
-			l852:
-				if peekChar('>') {
-					goto l850
-				}
-				if !matchDot() {
-					goto l850
-				}
-				goto l849
-			l850:
-				position = position850
-			}
-			end = position
-			if !matchChar('>') {
-				goto l846
-			}
-			do(75)
-			return true
-		l846:
-			position = position0
-			return false
-		},
-		/* 177 AutoLinkEmail <- ('<' 'mailto:'? < [-A-Za-z0-9+_./!%~$]+ '@' (!Newline !'>' .)+ > '>' {
-                    yy = p.mkLink(p.mkString(yytext), "mailto:"+yytext, "")
-                }) */
-		func() bool {
-			position0 := position
-			if !matchChar('<') {
-				goto l853
-			}
-			if !matchString("mailto:") {
-				goto l854
-			}
-		l854:
-			begin = position
-			if !matchClass(3) {
-				goto l853
-			}
-		l856:
-			if !matchClass(3) {
-				goto l857
-			}
-			goto l856
-		l857:
-			if !matchChar('@') {
-				goto l853
-			}
-			if !p.rules[ruleNewline]() {
-				goto l860
-			}
-			goto l853
-		l860:
-			if peekChar('>') {
-				goto l853
-			}
-			if !matchDot() {
-				goto l853
-			}
-		l858:
-			{
-				position859 := position
-				if !p.rules[ruleNewline]() {
-					goto l861
-				}
-				goto l859
-			l861:
-				if peekChar('>') {
-					goto l859
-				}
-				if !matchDot() {
-					goto l859
-				}
-				goto l858
-			l859:
-				position = position859
-			}
-			end = position
-			if !matchChar('>') {
-				goto l853
-			}
-			do(76)
-			return true
-		l853:
-			position = position0
-			return false
-		},
-		/* 178 Reference <- (NonindentSpace !'[]' Label ':' Spnl RefSrc RefTitle BlankLine+ { yy = p.mkLink(l.children, s.contents.str, t.contents.str)
-              s = nil
-              t = nil
-              l = nil
-              yy.key = REFERENCE }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 3)
-			if !p.rules[ruleNonindentSpace]() {
-				goto l862
-			}
-			if !matchString("[]") {
-				goto l863
-			}
-			goto l862
-		l863:
-			if !p.rules[ruleLabel]() {
-				goto l862
-			}
-			doarg(yySet, -2)
-			if !matchChar(':') {
-				goto l862
-			}
-			if !p.rules[ruleSpnl]() {
-				goto l862
-			}
-			if !p.rules[ruleRefSrc]() {
-				goto l862
-			}
-			doarg(yySet, -3)
-			if !p.rules[ruleRefTitle]() {
-				goto l862
-			}
-			doarg(yySet, -1)
-			if !p.rules[ruleBlankLine]() {
-				goto l862
-			}
-		l864:
-			if !p.rules[ruleBlankLine]() {
-				goto l865
-			}
-			goto l864
-		l865:
-			do(77)
-			doarg(yyPop, 3)
-			return true
-		l862:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 179 Label <- ('[' ((!'^' &{p.extension.Notes}) / (&. &{!p.extension.Notes})) StartList (!']' Inline { a = cons(yy, a) })* ']' { yy = p.mkList(LIST, a) }) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !matchChar('[') {
-				goto l866
-			}
-			if peekChar('^') {
-				goto l868
-			}
-			if !(p.extension.Notes) {
-				goto l868
-			}
-			goto l867
-		l868:
-			if !(position < len(p.Buffer)) {
-				goto l866
-			}
-			if !(!p.extension.Notes) {
-				goto l866
-			}
-		l867:
-			if !p.rules[ruleStartList]() {
-				goto l866
-			}
-			doarg(yySet, -1)
-		l869:
-			{
-				position870 := position
-				if peekChar(']') {
-					goto l870
-				}
-				if !p.rules[ruleInline]() {
-					goto l870
-				}
-				do(78)
-				goto l869
-			l870:
-				position = position870
-			}
-			if !matchChar(']') {
-				goto l866
-			}
-			do(79)
-			doarg(yyPop, 1)
-			return true
-		l866:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 180 RefSrc <- (< Nonspacechar+ > { yy = p.mkString(yytext)
-           yy.key = HTML }) */
-		func() bool {
-			position0 := position
-			begin = position
-			if !p.rules[ruleNonspacechar]() {
-				goto l871
-			}
-		l872:
-			if !p.rules[ruleNonspacechar]() {
-				goto l873
-			}
-			goto l872
-		l873:
-			end = position
-			do(80)
-			return true
-		l871:
-			position = position0
-			return false
-		},
-		/* 181 RefTitle <- ((RefTitleSingle / RefTitleDouble / RefTitleParens / EmptyTitle) { yy = p.mkString(yytext) }) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleRefTitleSingle]() {
-				goto l876
-			}
-			goto l875
-		l876:
-			if !p.rules[ruleRefTitleDouble]() {
-				goto l877
-			}
-			goto l875
-		l877:
-			if !p.rules[ruleRefTitleParens]() {
-				goto l878
-			}
-			goto l875
-		l878:
-			if !p.rules[ruleEmptyTitle]() {
-				goto l874
-			}
-		l875:
-			do(81)
-			return true
-		l874:
-			position = position0
-			return false
-		},
-		/* 182 EmptyTitle <- (< '' >) */
-		func() bool {
-			begin = position
-			end = position
-			return true
-		},
-		/* 183 RefTitleSingle <- (Spnl '\'' < (!((&[\'] ('\'' Sp Newline)) | (&[\n\r] Newline)) .)* > '\'') */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleSpnl]() {
-				goto l880
-			}
-			if !matchChar('\'') {
-				goto l880
-			}
-			begin = position
-		l881:
-			{
-				position882 := position
-				{
-					position883 := position
-					{
-						if position == len(p.Buffer) {
-							goto l883
-						}
-						switch p.Buffer[position] {
-						case '\'':
-							position++ // matchChar
-							if !p.rules[ruleSp]() {
-								goto l883
-							}
-							if !p.rules[ruleNewline]() {
-								goto l883
-							}
-							break
-						case '\n', '\r':
-							if !p.rules[ruleNewline]() {
-								goto l883
-							}
-							break
-						default:
-							goto l883
-						}
-					}
-					goto l882
-				l883:
-					position = position883
-				}
-				if !matchDot() {
-					goto l882
-				}
-				goto l881
-			l882:
-				position = position882
-			}
-			end = position
-			if !matchChar('\'') {
-				goto l880
-			}
-			return true
-		l880:
-			position = position0
-			return false
-		},
-		/* 184 RefTitleDouble <- (Spnl '"' < (!((&[\"] ('"' Sp Newline)) | (&[\n\r] Newline)) .)* > '"') */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleSpnl]() {
-				goto l885
-			}
-			if !matchChar('"') {
-				goto l885
-			}
-			begin = position
-		l886:
-			{
-				position887 := position
-				{
-					position888 := position
-					{
-						if position == len(p.Buffer) {
-							goto l888
-						}
-						switch p.Buffer[position] {
-						case '"':
-							position++ // matchChar
-							if !p.rules[ruleSp]() {
-								goto l888
-							}
-							if !p.rules[ruleNewline]() {
-								goto l888
-							}
-							break
-						case '\n', '\r':
-							if !p.rules[ruleNewline]() {
-								goto l888
-							}
-							break
-						default:
-							goto l888
-						}
-					}
-					goto l887
-				l888:
-					position = position888
-				}
-				if !matchDot() {
-					goto l887
-				}
-				goto l886
-			l887:
-				position = position887
-			}
-			end = position
-			if !matchChar('"') {
-				goto l885
-			}
-			return true
-		l885:
-			position = position0
-			return false
-		},
-		/* 185 RefTitleParens <- (Spnl '(' < (!((&[)] (')' Sp Newline)) | (&[\n\r] Newline)) .)* > ')') */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleSpnl]() {
-				goto l890
-			}
-			if !matchChar('(') {
-				goto l890
-			}
-			begin = position
-		l891:
-			{
-				position892 := position
-				{
-					position893 := position
-					{
-						if position == len(p.Buffer) {
-							goto l893
-						}
-						switch p.Buffer[position] {
-						case ')':
-							position++ // matchChar
-							if !p.rules[ruleSp]() {
-								goto l893
-							}
-							if !p.rules[ruleNewline]() {
-								goto l893
-							}
-							break
-						case '\n', '\r':
-							if !p.rules[ruleNewline]() {
-								goto l893
-							}
-							break
-						default:
-							goto l893
-						}
-					}
-					goto l892
-				l893:
-					position = position893
-				}
-				if !matchDot() {
-					goto l892
-				}
-				goto l891
-			l892:
-				position = position892
-			}
-			end = position
-			if !matchChar(')') {
-				goto l890
-			}
-			return true
-		l890:
-			position = position0
-			return false
-		},
-		/* 186 References <- (StartList ((Reference { a = cons(b, a) }) / SkipBlock)* { p.references = reverse(a) } commit) */
-		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !p.rules[ruleStartList]() {
-				goto l895
-			}
-			doarg(yySet, -2)
-		l896:
-			{
-				position897, thunkPosition897 := position, thunkPosition
-				{
-					position898, thunkPosition898 := position, thunkPosition
-					if !p.rules[ruleReference]() {
-						goto l899
-					}
-					doarg(yySet, -1)
-					do(82)
-					goto l898
-				l899:
-					position, thunkPosition = position898, thunkPosition898
-					if !p.rules[ruleSkipBlock]() {
-						goto l897
-					}
-				}
-			l898:
-				goto l896
-			l897:
-				position, thunkPosition = position897, thunkPosition897
-			}
-			do(83)
-			if !(commit(thunkPosition0)) {
-				goto l895
-			}
-			doarg(yyPop, 2)
-			return true
-		l895:
-			position, thunkPosition = position0, thunkPosition0
-			return false
-		},
-		/* 187 Ticks1 <- ('`' !'`') */
-		func() bool {
-			position0 := position
-			if !matchChar('`') {
-				goto l900
-			}
-			if peekChar('`') {
-				goto l900
-			}
-			return true
-		l900:
-			position = position0
-			return false
-		},
-		/* 188 Ticks2 <- ('``' !'`') */
-		func() bool {
-			position0 := position
-			if !matchString("``") {
-				goto l901
-			}
-			if peekChar('`') {
-				goto l901
-			}
-			return true
-		l901:
-			position = position0
-			return false
-		},
-		/* 189 Ticks3 <- ('```' !'`') */
-		func() bool {
-			position0 := position
-			if !matchString("```") {
-				goto l902
-			}
-			if peekChar('`') {
-				goto l902
-			}
-			return true
-		l902:
-			position = position0
-			return false
-		},
-		/* 190 Ticks4 <- ('````' !'`') */
-		func() bool {
-			position0 := position
-			if !matchString("````") {
-				goto l903
-			}
-			if peekChar('`') {
-				goto l903
-			}
-			return true
-		l903:
-			position = position0
-			return false
-		},
-		/* 191 Ticks5 <- ('`````' !'`') */
-		func() bool {
-			position0 := position
-			if !matchString("`````") {
-				goto l904
-			}
-			if peekChar('`') {
-				goto l904
-			}
-			return true
-		l904:
-			position = position0
-			return false
-		},
-		/* 192 Code <- (((Ticks1 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks1 '`'+)) | (&[\t\n\r ] (!(Sp Ticks1) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks1) / (Ticks2 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks2 '`'+)) | (&[\t\n\r ] (!(Sp Ticks2) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks2) / (Ticks3 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks3 '`'+)) | (&[\t\n\r ] (!(Sp Ticks3) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks3) / (Ticks4 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks4 '`'+)) | (&[\t\n\r ] (!(Sp Ticks4) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks4) / (Ticks5 Sp < ((!'`' Nonspacechar)+ / ((&[`] (!Ticks5 '`'+)) | (&[\t\n\r ] (!(Sp Ticks5) ((&[\n\r] (Newline !BlankLine)) | (&[\t ] Spacechar))))))+ > Sp Ticks5)) { yy = p.mkString(yytext); yy.key = CODE }) */
-		func() bool {
-			position0 := position
 			{
 				position906 := position
 				if !p.rules[ruleTicks1]() {
@@ -10786,13 +7036,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 193 RawHtml <- (< (HtmlComment / HtmlBlockScript / HtmlTag) > {   if p.extension.FilterHTML {
-                    yy = p.mkList(LIST, nil)
-                } else {
-                    yy = p.mkString(yytext)
-                    yy.key = HTML
-                }
-            }) */
+		/* 97 RawHtml <- (< (HtmlComment / HtmlBlockScript / HtmlSpecial / HtmlTag) > { yy = p.rawHTML(yytext, HTML) }) */
 		func() bool {
 			position0 := position
 			begin = position
@@ -10806,6 +7050,11 @@ func (p *yyParser) Init() {
 			}
 			goto l1032
 		l1034:
+			if !p.rules[ruleHtmlSpecial]() {
+				goto l1431
+			}
+			goto l1032
+		l1431:
 			if !p.rules[ruleHtmlTag]() {
 				goto l1031
 			}
@@ -10817,7 +7066,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 194 BlankLine <- (Sp Newline) */
+		/* 98 BlankLine <- (Sp Newline) */
 		func() bool {
 			position0 := position
 			if !p.rules[ruleSp]() {
@@ -10831,7 +7080,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 195 Quoted <- ((&[\'] ('\'' (!'\'' .)* '\'')) | (&[\"] ('"' (!'"' .)* '"'))) */
+		/* 99 Quoted <- ((&[\'] ('\'' (!'\'' .)* '\'')) | (&[\"] ('"' (!'"' .)* '"'))) */
 		func() bool {
 			position0 := position
 			{
@@ -10884,7 +7133,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 196 HtmlAttribute <- (((&[\-] '-') | (&[0-9A-Za-z] [A-Za-z0-9]))+ Spnl ('=' Spnl (Quoted / (!'>' Nonspacechar)+))? Spnl) */
+		/* 100 HtmlAttribute <- (((&[\-] '-') | (&[0-9A-Za-z] [A-Za-z0-9]))+ Spnl ('=' Spnl (Quoted / (!'>' Nonspacechar)+))? Spnl) */
 		func() bool {
 			position0 := position
 			{
@@ -10963,7 +7212,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 197 HtmlComment <- ('<!--' (!'-->' .)* '-->') */
+		/* 101 HtmlComment <- ('<!--' (!'-->' .)* '-->') */
 		func() bool {
 			position0 := position
 			if !matchString("<!--") {
@@ -10992,7 +7241,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 198 HtmlTag <- ('<' Spnl '/'? [A-Za-z0-9]+ Spnl HtmlAttribute* '/'? Spnl '>') */
+		/* 102 HtmlTag <- ('<' Spnl '/'? [A-Za-z0-9]+ Spnl HtmlAttribute* '/'? Spnl '>') */
 		func() bool {
 			position0 := position
 			if !matchChar('<') {
@@ -11032,7 +7281,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 199 Eof <- !. */
+		/* 103 Eof <- !. */
 		func() bool {
 			if (position < len(p.Buffer)) {
 				goto l1062
@@ -11041,28 +7290,27 @@ func (p *yyParser) Init() {
 		l1062:
 			return false
 		},
-		/* 200 Spacechar <- ((&[\t] '\t') | (&[ ] ' ')) */
+		/* 104 Spacechar <- (' ' / '\t' / &{p.unicodeSpaceChar(&position)}) */
 		func() bool {
-			{
-				if position == len(p.Buffer) {
-					goto l1063
-				}
-				switch p.Buffer[position] {
-				case '\t':
-					position++ // matchChar
-					break
-				case ' ':
-					position++ // matchChar
-					break
-				default:
-					goto l1063
-				}
+			if !matchChar(' ') {
+				goto l1510
+			}
+			goto l1509
+		l1510:
+			if !matchChar('\t') {
+				goto l1511
 			}
+			goto l1509
+		l1511:
+			if !(p.unicodeSpaceChar(&position)) {
+				goto l1063
+			}
+		l1509:
 			return true
 		l1063:
 			return false
 		},
-		/* 201 Nonspacechar <- (!Spacechar !Newline .) */
+		/* 105 Nonspacechar <- (!Spacechar !Newline .) */
 		func() bool {
 			position0 := position
 			if !p.rules[ruleSpacechar]() {
@@ -11083,7 +7331,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 202 Newline <- ((&[\r] ('\r' '\n'?)) | (&[\n] '\n')) */
+		/* 106 Newline <- ((&[\r] ('\r' '\n'?)) | (&[\n] '\n')) */
 		func() bool {
 			position0 := position
 			{
@@ -11107,17 +7355,16 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 203 Sp <- Spacechar* */
+		/* 107 Sp <- (&{p.skipSpace(&position)}) */
 		func() bool {
-		l1071:
-			if !p.rules[ruleSpacechar]() {
-				goto l1072
+			if !(p.skipSpace(&position)) {
+				goto l1071
 			}
-			goto l1071
-		l1072:
 			return true
+		l1071:
+			return false
 		},
-		/* 204 Spnl <- (Sp (Newline Sp)?) */
+		/* 108 Spnl <- (Sp (Newline Sp)?) */
 		func() bool {
 			position0 := position
 			if !p.rules[ruleSp]() {
@@ -11141,7 +7388,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 205 SpecialChar <- ('\'' / '"' / ((&[\\] '\\') | (&[#] '#') | (&[!] '!') | (&[<] '<') | (&[)] ')') | (&[(] '(') | (&[\]] ']') | (&[\[] '[') | (&[&] '&') | (&[`] '`') | (&[_] '_') | (&[*] '*') | (&[\"\'\-.^] ExtendedSpecialChar))) */
+		/* 109 SpecialChar <- ('\'' / '"' / ((&[\\] '\\') | (&[#] '#') | (&[!] '!') | (&[<] '<') | (&[)] ')') | (&[(] '(') | (&[\]] ']') | (&[\[] '[') | (&[&] '&') | (&[`] '`') | (&[_] '_') | (&[*] '*') | (&[\"\'\-.^] ExtendedSpecialChar))) */
 		func() bool {
 			if !matchChar('\'') {
 				goto l1078
@@ -11205,7 +7452,7 @@ func (p *yyParser) Init() {
 		l1076:
 			return false
 		},
-		/* 206 NormalChar <- (!((&[\n\r] Newline) | (&[\t ] Spacechar) | (&[!-#&-*\-.<\[-`] SpecialChar)) .) */
+		/* 110 NormalChar <- (!((&[\n\r] Newline) | (&[\t ] Spacechar) | (&[!-#&-*\-.<\[-`] SpecialChar)) .) */
 		func() bool {
 			position0 := position
 			{
@@ -11239,7 +7486,7 @@ func (p *yyParser) Init() {
 			position = position0
 			return false
 		},
-		/* 207 Alphanumeric <- ((&[\377] '\377') | (&[\376] '\376') | (&[\375] '\375') | (&[\374] '\374') | (&[\373] '\373') | (&[\372] '\372') | (&[\371] '\371') | (&[\370] '\370') | (&[\367] '\367') | (&[\366] '\366') | (&[\365] '\365') | (&[\364] '\364') | (&[\363] '\363') | (&[\362] '\362') | (&[\361] '\361') | (&[\360] '\360') | (&[\357] '\357') | (&[\356] '\356') | (&[\355] '\355') | (&[\354] '\354') | (&[\353] '\353') | (&[\352] '\352') | (&[\351] '\351') | (&[\350] '\350') | (&[\347] '\347') | (&[\346] '\346') | (&[\345] '\345') | (&[\344] '\344') | (&[\343] '\343') | (&[\342] '\342') | (&[\341] '\341') | (&[\340] '\340') | (&[\337] '\337') | (&[\336] '\336') | (&[\335] '\335') | (&[\334] '\334') | (&[\333] '\333') | (&[\332] '\332') | (&[\331] '\331') | (&[\330] '\330') | (&[\327] '\327') | (&[\326] '\326') | (&[\325] '\325') | (&[\324] '\324') | (&[\323] '\323') | (&[\322] '\322') | (&[\321] '\321') | (&[\320] '\320') | (&[\317] '\317') | (&[\316] '\316') | (&[\315] '\315') | (&[\314] '\314') | (&[\313] '\313') | (&[\312] '\312') | (&[\311] '\311') | (&[\310] '\310') | (&[\307] '\307') | (&[\306] '\306') | (&[\305] '\305') | (&[\304] '\304') | (&[\303] '\303') | (&[\302] '\302') | (&[\301] '\301') | (&[\300] '\300') | (&[\277] '\277') | (&[\276] '\276') | (&[\275] '\275') | (&[\274] '\274') | (&[\273] '\273') | (&[\272] '\272') | (&[\271] '\271') | (&[\270] '\270') | (&[\267] '\267') | (&[\266] '\266') | (&[\265] '\265') | (&[\264] '\264') | (&[\263] '\263') | (&[\262] '\262') | (&[\261] '\261') | (&[\260] '\260') | (&[\257] '\257') | (&[\256] '\256') | (&[\255] '\255') | (&[\254] '\254') | (&[\253] '\253') | (&[\252] '\252') | (&[\251] '\251') | (&[\250] '\250') | (&[\247] '\247') | (&[\246] '\246') | (&[\245] '\245') | (&[\244] '\244') | (&[\243] '\243') | (&[\242] '\242') | (&[\241] '\241') | (&[\240] '\240') | (&[\237] '\237') | (&[\236] '\236') | (&[\235] '\235') | (&[\234] '\234') | (&[\233] '\233') | (&[\232] '\232') | (&[\231] '\231') | (&[\230] '\230') | (&[\227] '\227') | (&[\226] '\226') | (&[\225] '\225') | (&[\224] '\224') | (&[\223] '\223') | (&[\222] '\222') | (&[\221] '\221') | (&[\220] '\220') | (&[\217] '\217') | (&[\216] '\216') | (&[\215] '\215') | (&[\214] '\214') | (&[\213] '\213') | (&[\212] '\212') | (&[\211] '\211') | (&[\210] '\210') | (&[\207] '\207') | (&[\206] '\206') | (&[\205] '\205') | (&[\204] '\204') | (&[\203] '\203') | (&[\202] '\202') | (&[\201] '\201') | (&[\200] '\200') | (&[0-9A-Za-z] [0-9A-Za-z])) */
+		/* 111 Alphanumeric <- ((&[\377] '\377') | (&[\376] '\376') | (&[\375] '\375') | (&[\374] '\374') | (&[\373] '\373') | (&[\372] '\372') | (&[\371] '\371') | (&[\370] '\370') | (&[\367] '\367') | (&[\366] '\366') | (&[\365] '\365') | (&[\364] '\364') | (&[\363] '\363') | (&[\362] '\362') | (&[\361] '\361') | (&[\360] '\360') | (&[\357] '\357') | (&[\356] '\356') | (&[\355] '\355') | (&[\354] '\354') | (&[\353] '\353') | (&[\352] '\352') | (&[\351] '\351') | (&[\350] '\350') | (&[\347] '\347') | (&[\346] '\346') | (&[\345] '\345') | (&[\344] '\344') | (&[\343] '\343') | (&[\342] '\342') | (&[\341] '\341') | (&[\340] '\340') | (&[\337] '\337') | (&[\336] '\336') | (&[\335] '\335') | (&[\334] '\334') | (&[\333] '\333') | (&[\332] '\332') | (&[\331] '\331') | (&[\330] '\330') | (&[\327] '\327') | (&[\326] '\326') | (&[\325] '\325') | (&[\324] '\324') | (&[\323] '\323') | (&[\322] '\322') | (&[\321] '\321') | (&[\320] '\320') | (&[\317] '\317') | (&[\316] '\316') | (&[\315] '\315') | (&[\314] '\314') | (&[\313] '\313') | (&[\312] '\312') | (&[\311] '\311') | (&[\310] '\310') | (&[\307] '\307') | (&[\306] '\306') | (&[\305] '\305') | (&[\304] '\304') | (&[\303] '\303') | (&[\302] '\302') | (&[\301] '\301') | (&[\300] '\300') | (&[\277] '\277') | (&[\276] '\276') | (&[\275] '\275') | (&[\274] '\274') | (&[\273] '\273') | (&[\272] '\272') | (&[\271] '\271') | (&[\270] '\270') | (&[\267] '\267') | (&[\266] '\266') | (&[\265] '\265') | (&[\264] '\264') | (&[\263] '\263') | (&[\262] '\262') | (&[\261] '\261') | (&[\260] '\260') | (&[\257] '\257') | (&[\256] '\256') | (&[\255] '\255') | (&[\254] '\254') | (&[\253] '\253') | (&[\252] '\252') | (&[\251] '\251') | (&[\250] '\250') | (&[\247] '\247') | (&[\246] '\246') | (&[\245] '\245') | (&[\244] '\244') | (&[\243] '\243') | (&[\242] '\242') | (&[\241] '\241') | (&[\240] '\240') | (&[\237] '\237') | (&[\236] '\236') | (&[\235] '\235') | (&[\234] '\234') | (&[\233] '\233') | (&[\232] '\232') | (&[\231] '\231') | (&[\230] '\230') | (&[\227] '\227') | (&[\226] '\226') | (&[\225] '\225') | (&[\224] '\224') | (&[\223] '\223') | (&[\222] '\222') | (&[\221] '\221') | (&[\220] '\220') | (&[\217] '\217') | (&[\216] '\216') | (&[\215] '\215') | (&[\214] '\214') | (&[\213] '\213') | (&[\212] '\212') | (&[\211] '\211') | (&[\210] '\210') | (&[\207] '\207') | (&[\206] '\206') | (&[\205] '\205') | (&[\204] '\204') | (&[\203] '\203') | (&[\202] '\202') | (&[\201] '\201') | (&[\200] '\200') | (&[0-9A-Za-z] [0-9A-Za-z])) */
 		func() bool {
 			{
 				if position == len(p.Buffer) {
@@ -11637,1950 +7884,3293 @@ func (p *yyParser) Init() {
 				}
 			}
 			return true
-		l1084:
+		l1084:
+			return false
+		},
+		/* 112 AlphanumericAscii <- [A-Za-z0-9] */
+		func() bool {
+			if !matchClass(5) {
+				goto l1086
+			}
+			return true
+		l1086:
+			return false
+		},
+		/* 113 Digit <- [0-9] */
+		func() bool {
+			if !matchClass(0) {
+				goto l1087
+			}
+			return true
+		l1087:
+			return false
+		},
+		/* 114 HexEntity <- (< '&' '#' [Xx] [0-9a-fA-F]+ ';' >) */
+		func() bool {
+			position0 := position
+			begin = position
+			if !matchChar('&') {
+				goto l1088
+			}
+			if !matchChar('#') {
+				goto l1088
+			}
+			if !matchClass(6) {
+				goto l1088
+			}
+			if !matchClass(7) {
+				goto l1088
+			}
+		l1089:
+			if !matchClass(7) {
+				goto l1090
+			}
+			goto l1089
+		l1090:
+			if !matchChar(';') {
+				goto l1088
+			}
+			end = position
+			return true
+		l1088:
+			position = position0
+			return false
+		},
+		/* 115 DecEntity <- (< '&' '#' [0-9]+ > ';' >) */
+		func() bool {
+			position0 := position
+			begin = position
+			if !matchChar('&') {
+				goto l1091
+			}
+			if !matchChar('#') {
+				goto l1091
+			}
+			if !matchClass(0) {
+				goto l1091
+			}
+		l1092:
+			if !matchClass(0) {
+				goto l1093
+			}
+			goto l1092
+		l1093:
+			end = position
+			if !matchChar(';') {
+				goto l1091
+			}
+			end = position
+			return true
+		l1091:
+			position = position0
+			return false
+		},
+		/* 116 CharEntity <- (< '&' [A-Za-z0-9]+ ';' >) */
+		func() bool {
+			position0 := position
+			begin = position
+			if !matchChar('&') {
+				goto l1094
+			}
+			if !matchClass(5) {
+				goto l1094
+			}
+		l1095:
+			if !matchClass(5) {
+				goto l1096
+			}
+			goto l1095
+		l1096:
+			if !matchChar(';') {
+				goto l1094
+			}
+			end = position
+			return true
+		l1094:
+			position = position0
+			return false
+		},
+		/* 117 NonindentSpace <- ('   ' / '  ' / ' ' / '') */
+		func() bool {
+			if !matchString("   ") {
+				goto l1099
+			}
+			goto l1098
+		l1099:
+			if !matchString("  ") {
+				goto l1100
+			}
+			goto l1098
+		l1100:
+			if !matchChar(' ') {
+				goto l1101
+			}
+			goto l1098
+		l1101:
+		l1098:
+			return true
+		},
+		/* 118 Indent <- ((&[ ] '    ') | (&[\t] '\t')) */
+		func() bool {
+			{
+				if position == len(p.Buffer) {
+					goto l1102
+				}
+				switch p.Buffer[position] {
+				case ' ':
+					position++
+					if !matchString("   ") {
+						goto l1102
+					}
+					break
+				case '\t':
+					position++ // matchChar
+					break
+				default:
+					goto l1102
+				}
+			}
+			return true
+		l1102:
+			return false
+		},
+		/* 119 IndentedLine <- (Indent Line) */
+		func() bool {
+			position0 := position
+			if !p.rules[ruleIndent]() {
+				goto l1104
+			}
+			if !p.rules[ruleLine]() {
+				goto l1104
+			}
+			return true
+		l1104:
+			position = position0
+			return false
+		},
+		/* 120 OptionallyIndentedLine <- (Indent? Line) */
+		func() bool {
+			position0 := position
+			if !p.rules[ruleIndent]() {
+				goto l1106
+			}
+		l1106:
+			if !p.rules[ruleLine]() {
+				goto l1105
+			}
+			return true
+		l1105:
+			position = position0
+			return false
+		},
+		/* 121 StartList <- (&. { yy = nil }) */
+		func() bool {
+			if !(position < len(p.Buffer)) {
+				goto l1108
+			}
+			do(86)
+			return true
+		l1108:
+			return false
+		},
+		/* 122 Line <- (RawLine { yy = p.mkString(yytext) }) */
+		func() bool {
+			position0 := position
+			if !p.rules[ruleRawLine]() {
+				goto l1109
+			}
+			do(87)
+			return true
+		l1109:
+			position = position0
+			return false
+		},
+		/* 123 RawLine <- ((< &{p.skipLine(&position)} Newline >) / (< .+ > Eof)) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			{
+				position1112, thunkPosition1112 := position, thunkPosition
+				begin = position
+				if !(p.skipLine(&position)) {
+					goto l1113
+				}
+				if !p.rules[ruleNewline]() {
+					goto l1113
+				}
+				end = position
+				goto l1111
+			l1113:
+				position, thunkPosition = position1112, thunkPosition1112
+				begin = position
+				if !matchDot() {
+					goto l1110
+				}
+			l1114:
+				if !matchDot() {
+					goto l1115
+				}
+				goto l1114
+			l1115:
+				end = position
+				if !p.rules[ruleEof]() {
+					goto l1110
+				}
+			}
+		l1111:
+			return true
+		l1110:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 124 SkipBlock <- (HtmlBlock / ((!'#' !SetextBottom1 !SetextBottom2 !BlankLine RawLine)+ BlankLine*) / BlankLine+ / RawLine) */
+		func() bool {
+			position0 := position
+			{
+				position1118 := position
+				if !p.rules[ruleHtmlBlock]() {
+					goto l1119
+				}
+				goto l1118
+			l1119:
+				if peekChar('#') {
+					goto l1120
+				}
+				if !p.rules[ruleSetextBottom1]() {
+					goto l1123
+				}
+				goto l1120
+			l1123:
+				if !p.rules[ruleSetextBottom2]() {
+					goto l1124
+				}
+				goto l1120
+			l1124:
+				if !p.rules[ruleBlankLine]() {
+					goto l1125
+				}
+				goto l1120
+			l1125:
+				if !p.rules[ruleRawLine]() {
+					goto l1120
+				}
+			l1121:
+				{
+					position1122 := position
+					if peekChar('#') {
+						goto l1122
+					}
+					if !p.rules[ruleSetextBottom1]() {
+						goto l1126
+					}
+					goto l1122
+				l1126:
+					if !p.rules[ruleSetextBottom2]() {
+						goto l1127
+					}
+					goto l1122
+				l1127:
+					if !p.rules[ruleBlankLine]() {
+						goto l1128
+					}
+					goto l1122
+				l1128:
+					if !p.rules[ruleRawLine]() {
+						goto l1122
+					}
+					goto l1121
+				l1122:
+					position = position1122
+				}
+			l1129:
+				if !p.rules[ruleBlankLine]() {
+					goto l1130
+				}
+				goto l1129
+			l1130:
+				goto l1118
+			l1120:
+				position = position1118
+				if !p.rules[ruleBlankLine]() {
+					goto l1131
+				}
+			l1132:
+				if !p.rules[ruleBlankLine]() {
+					goto l1133
+				}
+				goto l1132
+			l1133:
+				goto l1118
+			l1131:
+				position = position1118
+				if !p.rules[ruleRawLine]() {
+					goto l1117
+				}
+			}
+		l1118:
+			return true
+		l1117:
+			position = position0
+			return false
+		},
+		/* 125 ExtendedSpecialChar <- ((&{p.extension.Smart} ('.' / '-' / '\'' / '"')) / (&{p.extension.Notes} '^') / (&{p.extension.Mark} '=') / (&{p.extension.Critic} ('{' / '+' / '-' / '~' / '=' / '>')) / (&{p.extension.Placeholders} '{') / (&{p.extension.Comments} '%') / (&{p.extension.isInlineTrigger(p.Buffer, position)} .)) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			{
+				position1366, thunkPosition1366 := position, thunkPosition
+				if !(p.extension.Smart) {
+					goto l1367
+				}
+				if !matchChar('.') {
+					goto l1369
+				}
+				goto l1368
+			l1369:
+				if !matchChar('-') {
+					goto l1377
+				}
+				goto l1368
+			l1377:
+				if !matchChar('\'') {
+					goto l1378
+				}
+				goto l1368
+			l1378:
+				if !matchChar('"') {
+					goto l1367
+				}
+			l1368:
+				goto l1362
+			l1367:
+				position, thunkPosition = position1366, thunkPosition1366
+				if !(p.extension.Notes) {
+					goto l1379
+				}
+				if !matchChar('^') {
+					goto l1379
+				}
+				goto l1362
+			l1379:
+				position, thunkPosition = position1366, thunkPosition1366
+				if !(p.extension.Mark) {
+					goto l1380
+				}
+				if !matchChar('=') {
+					goto l1380
+				}
+				goto l1362
+			l1380:
+				position, thunkPosition = position1366, thunkPosition1366
+				if !(p.extension.Critic) {
+					goto l1381
+				}
+				if !matchChar('{') {
+					goto l1383
+				}
+				goto l1382
+			l1383:
+				if !matchChar('+') {
+					goto l1384
+				}
+				goto l1382
+			l1384:
+				if !matchChar('-') {
+					goto l1432
+				}
+				goto l1382
+			l1432:
+				if !matchChar('~') {
+					goto l1512
+				}
+				goto l1382
+			l1512:
+				if !matchChar('=') {
+					goto l1513
+				}
+				goto l1382
+			l1513:
+				if !matchChar('>') {
+					goto l1381
+				}
+			l1382:
+				goto l1362
+			l1381:
+				position, thunkPosition = position1366, thunkPosition1366
+				if !(p.extension.Placeholders) {
+					goto l1530
+				}
+				if !matchChar('{') {
+					goto l1530
+				}
+				goto l1362
+			l1530:
+				position, thunkPosition = position1366, thunkPosition1366
+				if !(p.extension.Comments) {
+					goto l1531
+				}
+				if !matchChar('%') {
+					goto l1531
+				}
+				goto l1362
+			l1531:
+				position, thunkPosition = position1366, thunkPosition1366
+				if !(p.extension.isInlineTrigger(p.Buffer, position)) {
+					goto l1134
+				}
+				if !matchDot() {
+					goto l1134
+				}
+			}
+		l1362:
+			return true
+		l1134:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 126 Smart <- (&{p.extension.Smart} (Ellipsis / Dash / Prime / SingleQuoted / DoubleQuoted / Apostrophe)) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.Smart) {
+				goto l1137
+			}
+			if !p.rules[ruleEllipsis]() {
+				goto l1139
+			}
+			goto l1138
+		l1139:
+			if !p.rules[ruleDash]() {
+				goto l1457
+			}
+			goto l1138
+		l1457:
+			if !p.rules[rulePrime]() {
+				goto l1458
+			}
+			goto l1138
+		l1458:
+			if !p.rules[ruleSingleQuoted]() {
+				goto l1459
+			}
+			goto l1138
+		l1459:
+			if !p.rules[ruleDoubleQuoted]() {
+				goto l1460
+			}
+			goto l1138
+		l1460:
+			if !p.rules[ruleApostrophe]() {
+				goto l1137
+			}
+		l1138:
+			return true
+		l1137:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 127 Apostrophe <- ('\'' { yy = p.mkElem(APOSTROPHE) }) */
+		func() bool {
+			position0 := position
+			if !matchChar('\'') {
+				goto l1141
+			}
+			do(88)
+			return true
+		l1141:
+			position = position0
+			return false
+		},
+		/* 128 Ellipsis <- (('...' / '. . .') { yy = p.mkElem(ELLIPSIS) }) */
+		func() bool {
+			position0 := position
+			if !matchString("...") {
+				goto l1144
+			}
+			goto l1143
+		l1144:
+			if !matchString(". . .") {
+				goto l1142
+			}
+		l1143:
+			do(89)
+			return true
+		l1142:
+			position = position0
+			return false
+		},
+		/* 129 Dash <- (EmDash / EnDash) */
+		func() bool {
+			if !p.rules[ruleEmDash]() {
+				goto l1147
+			}
+			goto l1146
+		l1147:
+			if !p.rules[ruleEnDash]() {
+				goto l1145
+			}
+		l1146:
+			return true
+		l1145:
+			return false
+		},
+		/* 130 EnDash <- ('-' &[0-9] { yy = p.mkElem(ENDASH) }) */
+		func() bool {
+			position0 := position
+			if !matchChar('-') {
+				goto l1148
+			}
+			if !peekClass(0) {
+				goto l1148
+			}
+			do(90)
+			return true
+		l1148:
+			position = position0
+			return false
+		},
+		/* 131 EmDash <- (('---' / '--') { yy = p.mkElem(EMDASH) }) */
+		func() bool {
+			position0 := position
+			if !matchString("---") {
+				goto l1151
+			}
+			goto l1150
+		l1151:
+			if !matchString("--") {
+				goto l1149
+			}
+		l1150:
+			do(91)
+			return true
+		l1149:
+			position = position0
 			return false
 		},
-		/* 208 AlphanumericAscii <- [A-Za-z0-9] */
+		/* 132 SingleQuoteStart <- ('\'' !(Spacechar / Newline) !(Digit Digit ('s' / !Alphanumeric))) */
 		func() bool {
-			if !matchClass(5) {
-				goto l1086
+			position0, thunkPosition0 := position, thunkPosition
+			if !matchChar('\'') {
+				goto l1152
+			}
+			{
+				position1153, thunkPosition1153 := position, thunkPosition
+				if !p.rules[ruleSpacechar]() {
+					goto l1463
+				}
+				goto l1462
+			l1463:
+				if !p.rules[ruleNewline]() {
+					goto l1461
+				}
+			l1462:
+				goto l1152
+			l1461:
+				position, thunkPosition = position1153, thunkPosition1153
+			}
+			{
+				position1464, thunkPosition1464 := position, thunkPosition
+				if !p.rules[ruleDigit]() {
+					goto l1465
+				}
+				if !p.rules[ruleDigit]() {
+					goto l1465
+				}
+				{
+					position1467, thunkPosition1467 := position, thunkPosition
+					if !matchChar('s') {
+						goto l1468
+					}
+					goto l1466
+				l1468:
+					position, thunkPosition = position1467, thunkPosition1467
+					if !p.rules[ruleAlphanumeric]() {
+						goto l1469
+					}
+					goto l1465
+				l1469:
+				}
+			l1466:
+				goto l1152
+			l1465:
+				position, thunkPosition = position1464, thunkPosition1464
 			}
 			return true
-		l1086:
+		l1152:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 209 Digit <- [0-9] */
+		/* 133 SingleQuoteEnd <- ('\'' !Alphanumeric) */
 		func() bool {
-			if !matchClass(0) {
-				goto l1087
+			position0 := position
+			if !matchChar('\'') {
+				goto l1155
+			}
+			if !p.rules[ruleAlphanumeric]() {
+				goto l1156
 			}
+			goto l1155
+		l1156:
 			return true
-		l1087:
+		l1155:
+			position = position0
 			return false
 		},
-		/* 210 HexEntity <- (< '&' '#' [Xx] [0-9a-fA-F]+ ';' >) */
+		/* 134 SingleQuoted <- (SingleQuoteStart StartList (!SingleQuoteEnd Inline { a = cons(b, a) })+ SingleQuoteEnd { yy = p.mkList(SINGLEQUOTED, a) }) */
 		func() bool {
-			position0 := position
-			begin = position
-			if !matchChar('&') {
-				goto l1088
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleSingleQuoteStart]() {
+				goto l1157
 			}
-			if !matchChar('#') {
-				goto l1088
+			if !p.rules[ruleStartList]() {
+				goto l1157
 			}
-			if !matchClass(6) {
-				goto l1088
+			doarg(yySet, -2)
+			if !p.rules[ruleSingleQuoteEnd]() {
+				goto l1160
 			}
-			if !matchClass(7) {
-				goto l1088
+			goto l1157
+		l1160:
+			if !p.rules[ruleInline]() {
+				goto l1157
 			}
-		l1089:
-			if !matchClass(7) {
-				goto l1090
+			doarg(yySet, -1)
+			do(92)
+		l1158:
+			{
+				position1159, thunkPosition1159 := position, thunkPosition
+				if !p.rules[ruleSingleQuoteEnd]() {
+					goto l1161
+				}
+				goto l1159
+			l1161:
+				if !p.rules[ruleInline]() {
+					goto l1159
+				}
+				doarg(yySet, -1)
+				do(92)
+				goto l1158
+			l1159:
+				position, thunkPosition = position1159, thunkPosition1159
 			}
-			goto l1089
-		l1090:
-			if !matchChar(';') {
-				goto l1088
+			if !p.rules[ruleSingleQuoteEnd]() {
+				goto l1157
 			}
-			end = position
+			do(93)
+			doarg(yyPop, 2)
 			return true
-		l1088:
-			position = position0
+		l1157:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 211 DecEntity <- (< '&' '#' [0-9]+ > ';' >) */
+		/* 135 DoubleQuoteStart <- '"' */
 		func() bool {
-			position0 := position
-			begin = position
-			if !matchChar('&') {
-				goto l1091
-			}
-			if !matchChar('#') {
-				goto l1091
-			}
-			if !matchClass(0) {
-				goto l1091
-			}
-		l1092:
-			if !matchClass(0) {
-				goto l1093
-			}
-			goto l1092
-		l1093:
-			end = position
-			if !matchChar(';') {
-				goto l1091
+			if !matchChar('"') {
+				goto l1162
 			}
-			end = position
 			return true
-		l1091:
-			position = position0
+		l1162:
 			return false
 		},
-		/* 212 CharEntity <- (< '&' [A-Za-z0-9]+ ';' >) */
+		/* 136 DoubleQuoteEnd <- (&{!p.prime(position)} '"') */
 		func() bool {
-			position0 := position
-			begin = position
-			if !matchChar('&') {
-				goto l1094
-			}
-			if !matchClass(5) {
-				goto l1094
-			}
-		l1095:
-			if !matchClass(5) {
-				goto l1096
+			position0, thunkPosition0 := position, thunkPosition
+			if !(!p.prime(position)) {
+				goto l1163
 			}
-			goto l1095
-		l1096:
-			if !matchChar(';') {
-				goto l1094
+			if !matchChar('"') {
+				goto l1163
 			}
-			end = position
 			return true
-		l1094:
-			position = position0
+		l1163:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 213 NonindentSpace <- ('   ' / '  ' / ' ' / '') */
+		/* 137 DoubleQuoted <- (DoubleQuoteStart StartList (!DoubleQuoteEnd Inline { a = cons(b, a) })+ DoubleQuoteEnd { yy = p.mkList(DOUBLEQUOTED, a) }) */
 		func() bool {
-			if !matchString("   ") {
-				goto l1099
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleDoubleQuoteStart]() {
+				goto l1164
 			}
-			goto l1098
-		l1099:
-			if !matchString("  ") {
-				goto l1100
+			if !p.rules[ruleStartList]() {
+				goto l1164
 			}
-			goto l1098
-		l1100:
-			if !matchChar(' ') {
-				goto l1101
+			doarg(yySet, -2)
+			if !p.rules[ruleDoubleQuoteEnd]() {
+				goto l1165
 			}
-			goto l1098
-		l1101:
-		l1098:
-			return true
-		},
-		/* 214 Indent <- ((&[ ] '    ') | (&[\t] '\t')) */
-		func() bool {
+			goto l1164
+		l1165:
+			if !p.rules[ruleInline]() {
+				goto l1164
+			}
+			doarg(yySet, -1)
+			do(94)
+		l1166:
 			{
-				if position == len(p.Buffer) {
-					goto l1102
+				position1473, thunkPosition1473 := position, thunkPosition
+				if !p.rules[ruleDoubleQuoteEnd]() {
+					goto l1474
 				}
-				switch p.Buffer[position] {
-				case ' ':
-					position++
-					if !matchString("   ") {
-						goto l1102
-					}
-					break
-				case '\t':
-					position++ // matchChar
-					break
-				default:
-					goto l1102
+				goto l1473
+			l1474:
+				if !p.rules[ruleInline]() {
+					goto l1473
 				}
+				doarg(yySet, -1)
+				do(94)
+				goto l1166
+			l1473:
+				position, thunkPosition = position1473, thunkPosition1473
+			}
+			if !p.rules[ruleDoubleQuoteEnd]() {
+				goto l1164
 			}
+			do(95)
+			doarg(yyPop, 2)
 			return true
-		l1102:
+		l1164:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 138 NoteReference <- (&{p.extension.Notes} RawNoteReference {
+                    if match, ok := p.find_note(ref.contents.str); ok {
+                        yy = p.mkElem(NOTE)
+                        yy.children = match.children
+                        yy.contents.str = ""
+                    } else if p.extension.UndefinedNotes {
+                        yy = p.mkString(ref.contents.str)
+                        yy.key = UNDEFNOTE
+                        p.undefNotes = append(p.undefNotes, ref.contents.str)
+                    } else {
+                        yy = p.mkString("[^"+ref.contents.str+"]")
+                    }
+                }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !(p.extension.Notes) {
+				goto l1167
+			}
+			if !p.rules[ruleRawNoteReference]() {
+				goto l1167
+			}
+			doarg(yySet, -1)
+			do(96)
+			doarg(yyPop, 1)
+			return true
+		l1167:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 215 IndentedLine <- (Indent Line) */
+		/* 139 RawNoteReference <- ('[^' < (!Newline !']' .)+ > ']' { yy = p.mkString(yytext) }) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleIndent]() {
-				goto l1104
+			if !matchString("[^") {
+				goto l1168
+			}
+			begin = position
+			if !p.rules[ruleNewline]() {
+				goto l1171
+			}
+			goto l1168
+		l1171:
+			if peekChar(']') {
+				goto l1168
+			}
+			if !matchDot() {
+				goto l1168
+			}
+		l1169:
+			{
+				position1170 := position
+				if !p.rules[ruleNewline]() {
+					goto l1172
+				}
+				goto l1170
+			l1172:
+				if peekChar(']') {
+					goto l1170
+				}
+				if !matchDot() {
+					goto l1170
+				}
+				goto l1169
+			l1170:
+				position = position1170
 			}
-			if !p.rules[ruleLine]() {
-				goto l1104
+			end = position
+			if !matchChar(']') {
+				goto l1168
 			}
+			do(97)
 			return true
-		l1104:
+		l1168:
 			position = position0
 			return false
 		},
-		/* 216 OptionallyIndentedLine <- (Indent? Line) */
+		/* 140 Note <- (&{p.extension.Notes} NonindentSpace RawNoteReference ':' Sp StartList (RawNoteBlock { a = cons(yy, a) }) (&Indent RawNoteBlock { a = cons(yy, a) })* {   yy = p.mkList(NOTE, a)
+                    yy.contents.str = ref.contents.str
+                }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleIndent]() {
-				goto l1106
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !(p.extension.Notes) {
+				goto l1173
 			}
-		l1106:
-			if !p.rules[ruleLine]() {
-				goto l1105
+			if !p.rules[ruleNonindentSpace]() {
+				goto l1173
 			}
-			return true
-		l1105:
-			position = position0
-			return false
-		},
-		/* 217 StartList <- (&. { yy = nil }) */
-		func() bool {
-			if !(position < len(p.Buffer)) {
-				goto l1108
+			if !p.rules[ruleRawNoteReference]() {
+				goto l1173
 			}
-			do(86)
-			return true
-		l1108:
-			return false
-		},
-		/* 218 Line <- (RawLine { yy = p.mkString(yytext) }) */
-		func() bool {
-			position0 := position
-			if !p.rules[ruleRawLine]() {
-				goto l1109
+			doarg(yySet, -2)
+			if !matchChar(':') {
+				goto l1173
 			}
-			do(87)
+			if !p.rules[ruleSp]() {
+				goto l1173
+			}
+			if !p.rules[ruleStartList]() {
+				goto l1173
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleRawNoteBlock]() {
+				goto l1173
+			}
+			do(98)
+		l1174:
+			{
+				position1175, thunkPosition1175 := position, thunkPosition
+				{
+					position1176 := position
+					if !p.rules[ruleIndent]() {
+						goto l1175
+					}
+					position = position1176
+				}
+				if !p.rules[ruleRawNoteBlock]() {
+					goto l1175
+				}
+				do(99)
+				goto l1174
+			l1175:
+				position, thunkPosition = position1175, thunkPosition1175
+			}
+			do(100)
+			doarg(yyPop, 2)
 			return true
-		l1109:
-			position = position0
+		l1173:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 219 RawLine <- ((< (!'\r' !'\n' .)* Newline >) / (< .+ > !.)) */
+		/* 141 InlineNote <- (&{p.extension.Notes} '^[' StartList (!']' Inline { a = cons(yy, a) })+ ']' { yy = p.mkList(NOTE, a)
+                  yy.contents.str = ""
+                  p.inlineNotes = true }) */
 		func() bool {
-			position0 := position
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !(p.extension.Notes) {
+				goto l1177
+			}
+			if !matchString("^[") {
+				goto l1177
+			}
+			if !p.rules[ruleStartList]() {
+				goto l1177
+			}
+			doarg(yySet, -1)
+			if peekChar(']') {
+				goto l1177
+			}
+			if !p.rules[ruleInline]() {
+				goto l1177
+			}
+			do(101)
+		l1178:
 			{
-				position1111 := position
-				begin = position
-			l1113:
-				if position == len(p.Buffer) {
-					goto l1114
-				}
-				switch p.Buffer[position] {
-				case '\r', '\n':
-					goto l1114
-				default:
-					position++
-				}
-				goto l1113
-			l1114:
-				if !p.rules[ruleNewline]() {
-					goto l1112
-				}
-				end = position
-				goto l1111
-			l1112:
-				position = position1111
-				begin = position
-				if !matchDot() {
-					goto l1110
-				}
-			l1115:
-				if !matchDot() {
-					goto l1116
+				position1179 := position
+				if peekChar(']') {
+					goto l1179
 				}
-				goto l1115
-			l1116:
-				end = position
-				if (position < len(p.Buffer)) {
-					goto l1110
+				if !p.rules[ruleInline]() {
+					goto l1179
 				}
+				do(101)
+				goto l1178
+			l1179:
+				position = position1179
 			}
-		l1111:
+			if !matchChar(']') {
+				goto l1177
+			}
+			do(102)
+			doarg(yyPop, 1)
 			return true
-		l1110:
-			position = position0
+		l1177:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 220 SkipBlock <- (HtmlBlock / ((!'#' !SetextBottom1 !SetextBottom2 !BlankLine RawLine)+ BlankLine*) / BlankLine+ / RawLine) */
+		/* 142 Notes <- (StartList ((Note { a = cons(b, a) }) / (BlockQuoteRaw { a = cons(b, a) }) / (ListItem { a = cons(b, a) }) / SkipBlock)* { p.indexNotes(reverse(a)) } commit) */
 		func() bool {
-			position0 := position
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleStartList]() {
+				goto l1180
+			}
+			doarg(yySet, -1)
+		l1181:
 			{
-				position1118 := position
-				if !p.rules[ruleHtmlBlock]() {
-					goto l1119
-				}
-				goto l1118
-			l1119:
-				if peekChar('#') {
-					goto l1120
-				}
-				if !p.rules[ruleSetextBottom1]() {
-					goto l1123
-				}
-				goto l1120
-			l1123:
-				if !p.rules[ruleSetextBottom2]() {
-					goto l1124
-				}
-				goto l1120
-			l1124:
-				if !p.rules[ruleBlankLine]() {
-					goto l1125
-				}
-				goto l1120
-			l1125:
-				if !p.rules[ruleRawLine]() {
-					goto l1120
-				}
-			l1121:
+				position1182, thunkPosition1182 := position, thunkPosition
 				{
-					position1122 := position
-					if peekChar('#') {
-						goto l1122
-					}
-					if !p.rules[ruleSetextBottom1]() {
-						goto l1126
+					position1184, thunkPosition1184 := position, thunkPosition
+					if !p.rules[ruleNote]() {
+						goto l1518
 					}
-					goto l1122
-				l1126:
-					if !p.rules[ruleSetextBottom2]() {
-						goto l1127
+					doarg(yySet, -2)
+					do(103)
+					goto l1183
+				l1518:
+					position, thunkPosition = position1184, thunkPosition1184
+					if !p.rules[ruleBlockQuoteRaw]() {
+						goto l1519
 					}
-					goto l1122
-				l1127:
-					if !p.rules[ruleBlankLine]() {
-						goto l1128
+					doarg(yySet, -2)
+					do(104)
+					goto l1183
+				l1519:
+					position, thunkPosition = position1184, thunkPosition1184
+					if !p.rules[ruleListItem]() {
+						goto l1520
 					}
-					goto l1122
-				l1128:
-					if !p.rules[ruleRawLine]() {
-						goto l1122
+					doarg(yySet, -2)
+					do(172)
+					goto l1183
+				l1520:
+					position, thunkPosition = position1184, thunkPosition1184
+					if !p.rules[ruleSkipBlock]() {
+						goto l1182
 					}
-					goto l1121
-				l1122:
-					position = position1122
-				}
-			l1129:
-				if !p.rules[ruleBlankLine]() {
-					goto l1130
-				}
-				goto l1129
-			l1130:
-				goto l1118
-			l1120:
-				position = position1118
-				if !p.rules[ruleBlankLine]() {
-					goto l1131
 				}
-			l1132:
+			l1183:
+				goto l1181
+			l1182:
+				position, thunkPosition = position1182, thunkPosition1182
+			}
+			do(173)
+			if !(commit(thunkPosition0)) {
+				goto l1180
+			}
+			doarg(yyPop, 2)
+			return true
+		l1180:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 143 RawNoteBlock <- (StartList (!BlankLine OptionallyIndentedLine { a = cons(yy, a) })+ (< BlankLine* > { a = cons(p.mkString(yytext), a) }) {   yy = p.mkStringFromList(a, true)
+                    yy.key = RAW
+                }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l1185
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleBlankLine]() {
+				goto l1188
+			}
+			goto l1185
+		l1188:
+			if !p.rules[ruleOptionallyIndentedLine]() {
+				goto l1185
+			}
+			do(105)
+		l1186:
+			{
+				position1187 := position
 				if !p.rules[ruleBlankLine]() {
-					goto l1133
+					goto l1189
 				}
-				goto l1132
-			l1133:
-				goto l1118
-			l1131:
-				position = position1118
-				if !p.rules[ruleRawLine]() {
-					goto l1117
+				goto l1187
+			l1189:
+				if !p.rules[ruleOptionallyIndentedLine]() {
+					goto l1187
 				}
+				do(105)
+				goto l1186
+			l1187:
+				position = position1187
 			}
-		l1118:
+			begin = position
+		l1190:
+			if !p.rules[ruleBlankLine]() {
+				goto l1191
+			}
+			goto l1190
+		l1191:
+			end = position
+			do(106)
+			do(107)
+			doarg(yyPop, 1)
 			return true
-		l1117:
-			position = position0
+		l1185:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 221 ExtendedSpecialChar <- ((&[^] (&{p.extension.Notes} '^')) | (&[\"\'\-.] (&{p.extension.Smart} ((&[\"] '"') | (&[\'] '\'') | (&[\-] '-') | (&[.] '.'))))) */
+		/* 144 DefinitionList <- (&{p.extension.Dlists} StartList (Definition { a = cons(yy, a) })+ { yy = p.mkList(DEFINITIONLIST, a) }) */
 		func() bool {
-			position0 := position
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !(p.extension.Dlists) {
+				goto l1192
+			}
+			if !p.rules[ruleStartList]() {
+				goto l1192
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleDefinition]() {
+				goto l1192
+			}
+			do(108)
+		l1193:
 			{
-				if position == len(p.Buffer) {
-					goto l1134
-				}
-				switch p.Buffer[position] {
-				case '^':
-					if !(p.extension.Notes) {
-						goto l1134
-					}
-					if !matchChar('^') {
-						goto l1134
-					}
-					break
-				default:
-					if !(p.extension.Smart) {
-						goto l1134
-					}
-					{
-						if position == len(p.Buffer) {
-							goto l1134
-						}
-						switch p.Buffer[position] {
-						case '"':
-							position++ // matchChar
-							break
-						case '\'':
-							position++ // matchChar
-							break
-						case '-':
-							position++ // matchChar
-							break
-						case '.':
-							position++ // matchChar
-							break
-						default:
-							goto l1134
-						}
-					}
+				position1194, thunkPosition1194 := position, thunkPosition
+				if !p.rules[ruleDefinition]() {
+					goto l1194
 				}
+				do(108)
+				goto l1193
+			l1194:
+				position, thunkPosition = position1194, thunkPosition1194
 			}
+			do(109)
+			doarg(yyPop, 1)
 			return true
-		l1134:
-			position = position0
+		l1192:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 222 Smart <- (&{p.extension.Smart} (SingleQuoted / ((&[\'] Apostrophe) | (&[\"] DoubleQuoted) | (&[\-] Dash) | (&[.] Ellipsis)))) */
+		/* 145 Definition <- (&(NonindentSpace !Defmark Nonspacechar RawLine BlankLine? Defmark) StartList (DListTitle { a = cons(yy, a) })+ (DefTight / DefLoose) {
+				for e := yy.children; e != nil; e = e.next {
+					e.key = DEFDATA
+				}
+				a = cons(yy, a)
+			} { yy = p.mkList(LIST, a) }) */
 		func() bool {
-			if !(p.extension.Smart) {
-				goto l1137
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			{
+				position1196 := position
+				if !p.rules[ruleNonindentSpace]() {
+					goto l1195
+				}
+				if !p.rules[ruleDefmark]() {
+					goto l1197
+				}
+				goto l1195
+			l1197:
+				if !p.rules[ruleNonspacechar]() {
+					goto l1195
+				}
+				if !p.rules[ruleRawLine]() {
+					goto l1195
+				}
+				if !p.rules[ruleBlankLine]() {
+					goto l1198
+				}
+			l1198:
+				if !p.rules[ruleDefmark]() {
+					goto l1195
+				}
+				position = position1196
 			}
-			if !p.rules[ruleSingleQuoted]() {
-				goto l1139
+			if !p.rules[ruleStartList]() {
+				goto l1195
 			}
-			goto l1138
-		l1139:
+			doarg(yySet, -1)
+			if !p.rules[ruleDListTitle]() {
+				goto l1195
+			}
+			do(110)
+		l1200:
 			{
-				if position == len(p.Buffer) {
-					goto l1137
-				}
-				switch p.Buffer[position] {
-				case '\'':
-					if !p.rules[ruleApostrophe]() {
-						goto l1137
-					}
-					break
-				case '"':
-					if !p.rules[ruleDoubleQuoted]() {
-						goto l1137
-					}
-					break
-				case '-':
-					if !p.rules[ruleDash]() {
-						goto l1137
-					}
-					break
-				case '.':
-					if !p.rules[ruleEllipsis]() {
-						goto l1137
-					}
-					break
-				default:
-					goto l1137
+				position1201, thunkPosition1201 := position, thunkPosition
+				if !p.rules[ruleDListTitle]() {
+					goto l1201
 				}
+				do(110)
+				goto l1200
+			l1201:
+				position, thunkPosition = position1201, thunkPosition1201
 			}
-		l1138:
+			if !p.rules[ruleDefTight]() {
+				goto l1203
+			}
+			goto l1202
+		l1203:
+			if !p.rules[ruleDefLoose]() {
+				goto l1195
+			}
+		l1202:
+			do(111)
+			do(112)
+			doarg(yyPop, 1)
 			return true
-		l1137:
+		l1195:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 223 Apostrophe <- ('\'' { yy = p.mkElem(APOSTROPHE) }) */
+		/* 146 DListTitle <- (NonindentSpace !Defmark &Nonspacechar StartList (!Endline Inline { a = cons(yy, a) })+ Sp Newline {	yy = p.mkList(LIST, a)
+				yy.key = DEFTITLE
+			}) */
 		func() bool {
-			position0 := position
-			if !matchChar('\'') {
-				goto l1141
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleNonindentSpace]() {
+				goto l1204
 			}
-			do(88)
+			if !p.rules[ruleDefmark]() {
+				goto l1205
+			}
+			goto l1204
+		l1205:
+			{
+				position1206 := position
+				if !p.rules[ruleNonspacechar]() {
+					goto l1204
+				}
+				position = position1206
+			}
+			if !p.rules[ruleStartList]() {
+				goto l1204
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleEndline]() {
+				goto l1209
+			}
+			goto l1204
+		l1209:
+			if !p.rules[ruleInline]() {
+				goto l1204
+			}
+			do(113)
+		l1207:
+			{
+				position1208 := position
+				if !p.rules[ruleEndline]() {
+					goto l1210
+				}
+				goto l1208
+			l1210:
+				if !p.rules[ruleInline]() {
+					goto l1208
+				}
+				do(113)
+				goto l1207
+			l1208:
+				position = position1208
+			}
+			if !p.rules[ruleSp]() {
+				goto l1204
+			}
+			if !p.rules[ruleNewline]() {
+				goto l1204
+			}
+			do(114)
+			doarg(yyPop, 1)
 			return true
-		l1141:
-			position = position0
+		l1204:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 224 Ellipsis <- (('...' / '. . .') { yy = p.mkElem(ELLIPSIS) }) */
+		/* 147 DefTight <- (&{!p.extension.DefBlankLine} &Defmark (DefListTight / DefListLoose)) */
 		func() bool {
-			position0 := position
-			if !matchString("...") {
-				goto l1144
+			position0, thunkPosition0 := position, thunkPosition
+			if !(!p.extension.DefBlankLine) {
+				goto l1333
 			}
-			goto l1143
-		l1144:
-			if !matchString(". . .") {
-				goto l1142
+			{
+				position1334, thunkPosition1334 := position, thunkPosition
+				if !p.rules[ruleDefmark]() {
+					goto l1333
+				}
+				position, thunkPosition = position1334, thunkPosition1334
 			}
-		l1143:
-			do(89)
+			{
+				position1336, thunkPosition1336 := position, thunkPosition
+				if !p.rules[ruleDefListTight]() {
+					goto l1337
+				}
+				goto l1335
+			l1337:
+				position, thunkPosition = position1336, thunkPosition1336
+				if !p.rules[ruleDefListLoose]() {
+					goto l1333
+				}
+			}
+		l1335:
 			return true
-		l1142:
-			position = position0
+		l1333:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 225 Dash <- (EmDash / EnDash) */
+		/* 148 DefLoose <- (BlankLine &Defmark DefListLoose) */
 		func() bool {
-			if !p.rules[ruleEmDash]() {
-				goto l1147
+			position0, thunkPosition0 := position, thunkPosition
+			if !p.rules[ruleBlankLine]() {
+				goto l1338
 			}
-			goto l1146
-		l1147:
-			if !p.rules[ruleEnDash]() {
-				goto l1145
+			{
+				position1339, thunkPosition1339 := position, thunkPosition
+				if !p.rules[ruleDefmark]() {
+					goto l1338
+				}
+				position, thunkPosition = position1339, thunkPosition1339
+			}
+			if !p.rules[ruleDefListLoose]() {
+				goto l1338
 			}
-		l1146:
 			return true
-		l1145:
+		l1338:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 226 EnDash <- ('-' &[0-9] { yy = p.mkElem(ENDASH) }) */
+		/* 149 Defmark <- (NonindentSpace DefMarkChar Spacechar+) */
 		func() bool {
 			position0 := position
-			if !matchChar('-') {
-				goto l1148
+			if !p.rules[ruleNonindentSpace]() {
+				goto l1215
 			}
-			if !peekClass(0) {
-				goto l1148
+			if !p.rules[ruleDefMarkChar]() {
+				goto l1215
 			}
-			do(90)
+			if !p.rules[ruleSpacechar]() {
+				goto l1215
+			}
+		l1217:
+			if !p.rules[ruleSpacechar]() {
+				goto l1218
+			}
+			goto l1217
+		l1218:
 			return true
-		l1148:
+		l1215:
 			position = position0
 			return false
 		},
-		/* 227 EmDash <- (('---' / '--') { yy = p.mkElem(EMDASH) }) */
+		/* 150 DefMarker <- (&{p.extension.Dlists} Defmark) */
 		func() bool {
-			position0 := position
-			if !matchString("---") {
-				goto l1151
+			if !(p.extension.Dlists) {
+				goto l1219
 			}
-			goto l1150
-		l1151:
-			if !matchString("--") {
-				goto l1149
+			if !p.rules[ruleDefmark]() {
+				goto l1219
 			}
-		l1150:
-			do(91)
 			return true
-		l1149:
-			position = position0
+		l1219:
 			return false
 		},
-		/* 228 SingleQuoteStart <- ('\'' !((&[\n\r] Newline) | (&[\t ] Spacechar))) */
+		/* 151 Table <- (StartList StartList StartList (TableCaption { b = cons(yy, b); c = yy })? ((TableBody { yy.key = TABLEHEAD; a = cons(yy, a) } SeparatorLine { append_list(yy, a) }) / (SeparatorLine { a = yy })) (TableBody { a = cons(yy, a) }) (BlankLine !TableCaption TableBody { a = cons(yy, a) } &(TableCaption / BlankLine))* ((TableCaption { b = cons(yy, b) } &BlankLines) / &BlankLines) {
+        if b != nil { append_list(b,a) }
+        yy = p.mkList(TABLE, a)
+        p.normalizeTable(yy, c != nil)
+    }) */
 		func() bool {
-			position0 := position
-			if !matchChar('\'') {
-				goto l1152
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 3)
+			if !p.rules[ruleStartList]() {
+				goto l1220
+			}
+			doarg(yySet, -2)
+			if !p.rules[ruleStartList]() {
+				goto l1220
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleStartList]() {
+				goto l1220
 			}
+			doarg(yySet, -3)
 			{
-				if position == len(p.Buffer) {
-					goto l1153
+				position1221, thunkPosition1221 := position, thunkPosition
+				if !p.rules[ruleTableCaption]() {
+					goto l1221
 				}
-				switch p.Buffer[position] {
-				case '\n', '\r':
-					if !p.rules[ruleNewline]() {
-						goto l1153
+				do(115)
+				goto l1222
+			l1221:
+				position, thunkPosition = position1221, thunkPosition1221
+			}
+		l1222:
+			{
+				position1227, thunkPosition1227 := position, thunkPosition
+				if !p.rules[ruleTableBody]() {
+					goto l1228
+				}
+				do(116)
+				if !p.rules[ruleSeparatorLine]() {
+					goto l1228
+				}
+				do(117)
+				goto l1223
+			l1228:
+				position, thunkPosition = position1227, thunkPosition1227
+				if !p.rules[ruleSeparatorLine]() {
+					goto l1220
+				}
+				do(118)
+			}
+		l1223:
+			if !p.rules[ruleTableBody]() {
+				goto l1220
+			}
+			do(119)
+		l1229:
+			{
+				position1558, thunkPosition1558 := position, thunkPosition
+				if !p.rules[ruleBlankLine]() {
+					goto l1558
+				}
+				if !p.rules[ruleTableCaption]() {
+					goto l1560
+				}
+				goto l1558
+			l1560:
+				if !p.rules[ruleTableBody]() {
+					goto l1558
+				}
+				do(120)
+				{
+					position1562, thunkPosition1562 := position, thunkPosition
+					if !p.rules[ruleTableCaption]() {
+						goto l1565
 					}
-					break
-				case '\t', ' ':
-					if !p.rules[ruleSpacechar]() {
-						goto l1153
+					goto l1563
+				l1565:
+					if !p.rules[ruleBlankLine]() {
+						goto l1558
 					}
-					break
-				default:
-					goto l1153
+				l1563:
+					position, thunkPosition = position1562, thunkPosition1562
+				}
+				goto l1229
+			l1558:
+				position, thunkPosition = position1558, thunkPosition1558
+			}
+			{
+				position1612, thunkPosition1612 := position, thunkPosition
+				if !p.rules[ruleTableCaption]() {
+					goto l1613
+				}
+				do(121)
+				{
+					position1614, thunkPosition1614 := position, thunkPosition
+					if !p.rules[ruleBlankLines]() {
+						goto l1613
+					}
+					position, thunkPosition = position1614, thunkPosition1614
+				}
+				goto l1611
+			l1613:
+				position, thunkPosition = position1612, thunkPosition1612
+				{
+					position1615, thunkPosition1615 := position, thunkPosition
+					if !p.rules[ruleBlankLines]() {
+						goto l1220
+					}
+					position, thunkPosition = position1615, thunkPosition1615
 				}
 			}
-			goto l1152
-		l1153:
+		l1611:
+			do(177)
+			doarg(yyPop, 3)
 			return true
-		l1152:
-			position = position0
+		l1220:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 229 SingleQuoteEnd <- ('\'' !Alphanumeric) */
+		/* 152 TableBody <- (StartList (TableRow { a = cons(yy, a) })+ { yy = p.mkList(TABLEBODY, a) }) */
 		func() bool {
-			position0 := position
-			if !matchChar('\'') {
-				goto l1155
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
+			if !p.rules[ruleStartList]() {
+				goto l1233
 			}
-			if !p.rules[ruleAlphanumeric]() {
-				goto l1156
+			doarg(yySet, -1)
+			if !p.rules[ruleTableRow]() {
+				goto l1233
 			}
-			goto l1155
-		l1156:
+			do(122)
+		l1234:
+			{
+				position1235, thunkPosition1235 := position, thunkPosition
+				if !p.rules[ruleTableRow]() {
+					goto l1235
+				}
+				do(122)
+				goto l1234
+			l1235:
+				position, thunkPosition = position1235, thunkPosition1235
+			}
+			do(123)
+			doarg(yyPop, 1)
 			return true
-		l1155:
-			position = position0
+		l1233:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 230 SingleQuoted <- (SingleQuoteStart StartList (!SingleQuoteEnd Inline { a = cons(b, a) })+ SingleQuoteEnd { yy = p.mkList(SINGLEQUOTED, a) }) */
+		/* 153 TableRow <- (StartList (!SeparatorLine &TableLine '|'? (TableCell { a = cons(yy, a) })+) Sp Newline { yy = p.mkList(TABLEROW, a) }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !p.rules[ruleSingleQuoteStart]() {
-				goto l1157
-			}
+			doarg(yyPush, 1)
 			if !p.rules[ruleStartList]() {
-				goto l1157
-			}
-			doarg(yySet, -2)
-			if !p.rules[ruleSingleQuoteEnd]() {
-				goto l1160
-			}
-			goto l1157
-		l1160:
-			if !p.rules[ruleInline]() {
-				goto l1157
+				goto l1236
 			}
 			doarg(yySet, -1)
-			do(92)
-		l1158:
+			if !p.rules[ruleSeparatorLine]() {
+				goto l1237
+			}
+			goto l1236
+		l1237:
 			{
-				position1159, thunkPosition1159 := position, thunkPosition
-				if !p.rules[ruleSingleQuoteEnd]() {
-					goto l1161
+				position1238 := position
+				if !p.rules[ruleTableLine]() {
+					goto l1236
 				}
-				goto l1159
-			l1161:
-				if !p.rules[ruleInline]() {
-					goto l1159
+				position = position1238
+			}
+			matchChar('|')
+			if !p.rules[ruleTableCell]() {
+				goto l1236
+			}
+			do(124)
+		l1239:
+			{
+				position1240 := position
+				if !p.rules[ruleTableCell]() {
+					goto l1240
 				}
-				doarg(yySet, -1)
-				do(92)
-				goto l1158
-			l1159:
-				position, thunkPosition = position1159, thunkPosition1159
+				do(124)
+				goto l1239
+			l1240:
+				position = position1240
 			}
-			if !p.rules[ruleSingleQuoteEnd]() {
-				goto l1157
+			if !p.rules[ruleSp]() {
+				goto l1236
 			}
-			do(93)
-			doarg(yyPop, 2)
+			if !p.rules[ruleNewline]() {
+				goto l1236
+			}
+			do(125)
+			doarg(yyPop, 1)
 			return true
-		l1157:
+		l1236:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 231 DoubleQuoteStart <- '"' */
+		/* 154 TableLine <- ((!Newline !'|' .)* '|') */
 		func() bool {
-			if !matchChar('"') {
-				goto l1162
+			position0 := position
+		l1242:
+			{
+				position1243 := position
+				if !p.rules[ruleNewline]() {
+					goto l1244
+				}
+				goto l1243
+			l1244:
+				if peekChar('|') {
+					goto l1243
+				}
+				if !matchDot() {
+					goto l1243
+				}
+				goto l1242
+			l1243:
+				position = position1243
 			}
-			return true
-		l1162:
-			return false
-		},
-		/* 232 DoubleQuoteEnd <- '"' */
-		func() bool {
-			if !matchChar('"') {
-				goto l1163
+			if !matchChar('|') {
+				goto l1241
 			}
 			return true
-		l1163:
+		l1241:
+			position = position0
 			return false
 		},
-		/* 233 DoubleQuoted <- ('"' StartList (!'"' Inline { a = cons(b, a) })+ '"' { yy = p.mkList(DOUBLEQUOTED, a) }) */
+		/* 155 TableCell <- (ExtendedCell / EmptyCell / FullCell) */
 		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !matchChar('"') {
-				goto l1164
-			}
-			if !p.rules[ruleStartList]() {
-				goto l1164
-			}
-			doarg(yySet, -2)
-			if peekChar('"') {
-				goto l1164
-			}
-			if !p.rules[ruleInline]() {
-				goto l1164
+			if !p.rules[ruleExtendedCell]() {
+				goto l1247
 			}
-			doarg(yySet, -1)
-			do(94)
-		l1165:
-			{
-				position1166, thunkPosition1166 := position, thunkPosition
-				if peekChar('"') {
-					goto l1166
-				}
-				if !p.rules[ruleInline]() {
-					goto l1166
-				}
-				doarg(yySet, -1)
-				do(94)
-				goto l1165
-			l1166:
-				position, thunkPosition = position1166, thunkPosition1166
+			goto l1246
+		l1247:
+			if !p.rules[ruleEmptyCell]() {
+				goto l1248
 			}
-			if !matchChar('"') {
-				goto l1164
+			goto l1246
+		l1248:
+			if !p.rules[ruleFullCell]() {
+				goto l1245
 			}
-			do(95)
-			doarg(yyPop, 2)
+		l1246:
 			return true
-		l1164:
-			position, thunkPosition = position0, thunkPosition0
+		l1245:
 			return false
 		},
-		/* 234 NoteReference <- (&{p.extension.Notes} RawNoteReference {
-                    if match, ok := p.find_note(ref.contents.str); ok {
-                        yy = p.mkElem(NOTE)
-                        yy.children = match.children
-                        yy.contents.str = ""
-                    } else {
-                        yy = p.mkString("[^"+ref.contents.str+"]")
-                    }
-                }) */
+		/* 156 ExtendedCell <- ((EmptyCell / FullCell) < '|'+ > {
+        span := p.mkString(yytext)
+        span.key = CELLSPAN
+        span.next = yy.children
+        yy.children = span
+    }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !(p.extension.Notes) {
-				goto l1167
+			if !p.rules[ruleEmptyCell]() {
+				goto l1251
 			}
-			if !p.rules[ruleRawNoteReference]() {
-				goto l1167
+			goto l1250
+		l1251:
+			if !p.rules[ruleFullCell]() {
+				goto l1249
 			}
-			doarg(yySet, -1)
-			do(96)
-			doarg(yyPop, 1)
+		l1250:
+			begin = position
+			if !matchChar('|') {
+				goto l1249
+			}
+		l1252:
+			if !matchChar('|') {
+				goto l1253
+			}
+			goto l1252
+		l1253:
+			end = position
+			do(126)
 			return true
-		l1167:
+		l1249:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 235 RawNoteReference <- ('[^' < (!Newline !']' .)+ > ']' { yy = p.mkString(yytext) }) */
+		/* 157 CellStr <- (< (!'|' NormalChar) ((!'|' NormalChar) / ('_'+ &Alphanumeric))* > { yy = p.mkString(yytext) }) */
 		func() bool {
 			position0 := position
-			if !matchString("[^") {
-				goto l1168
-			}
 			begin = position
-			if !p.rules[ruleNewline]() {
-				goto l1171
-			}
-			goto l1168
-		l1171:
-			if peekChar(']') {
-				goto l1168
+			if peekChar('|') {
+				goto l1254
 			}
-			if !matchDot() {
-				goto l1168
+			if !p.rules[ruleNormalChar]() {
+				goto l1254
 			}
-		l1169:
+		l1255:
 			{
-				position1170 := position
-				if !p.rules[ruleNewline]() {
-					goto l1172
+				position1256 := position
+				if peekChar('|') {
+					goto l1258
 				}
-				goto l1170
-			l1172:
-				if peekChar(']') {
-					goto l1170
+				if !p.rules[ruleNormalChar]() {
+					goto l1258
 				}
-				if !matchDot() {
-					goto l1170
+				goto l1257
+			l1258:
+				if !matchChar('_') {
+					goto l1256
 				}
-				goto l1169
-			l1170:
-				position = position1170
+			l1259:
+				if !matchChar('_') {
+					goto l1260
+				}
+				goto l1259
+			l1260:
+				{
+					position1261 := position
+					if !p.rules[ruleAlphanumeric]() {
+						goto l1256
+					}
+					position = position1261
+				}
+			l1257:
+				goto l1255
+			l1256:
+				position = position1256
 			}
 			end = position
-			if !matchChar(']') {
-				goto l1168
-			}
-			do(97)
+			do(127)
 			return true
-		l1168:
+		l1254:
 			position = position0
 			return false
 		},
-		/* 236 Note <- (&{p.extension.Notes} NonindentSpace RawNoteReference ':' Sp StartList (RawNoteBlock { a = cons(yy, a) }) (&Indent RawNoteBlock { a = cons(yy, a) })* {   yy = p.mkList(NOTE, a)
-                    yy.contents.str = ref.contents.str
-                }) */
+		/* 158 FullCell <- (Sp StartList ((&{p.extension.TableBlocks} < &{p.cellText(&position)} > { a = p.mkElem(RAW); a.contents.str = yytext }) / (((!CellDivider CellStr) / (!Newline !Endline !CellDivider !Str !(Sp &CellDivider) Inline)) { a = cons(yy, a) })+) Sp CellDivider? { yy = p.mkList(TABLECELL, a) }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !(p.extension.Notes) {
-				goto l1173
-			}
-			if !p.rules[ruleNonindentSpace]() {
-				goto l1173
-			}
-			if !p.rules[ruleRawNoteReference]() {
-				goto l1173
-			}
-			doarg(yySet, -2)
-			if !matchChar(':') {
-				goto l1173
-			}
+			doarg(yyPush, 1)
 			if !p.rules[ruleSp]() {
-				goto l1173
+				goto l1262
 			}
 			if !p.rules[ruleStartList]() {
-				goto l1173
+				goto l1262
 			}
 			doarg(yySet, -1)
-			if !p.rules[ruleRawNoteBlock]() {
-				goto l1173
-			}
-			do(98)
-		l1174:
 			{
-				position1175, thunkPosition1175 := position, thunkPosition
+				position1264, thunkPosition1264 := position, thunkPosition
+				if !(p.extension.TableBlocks) {
+					goto l1265
+				}
+				begin = position
+				if !(p.cellText(&position)) {
+					goto l1265
+				}
+				end = position
+				do(128)
+				goto l1263
+			l1265:
+				position, thunkPosition = position1264, thunkPosition1264
 				{
-					position1176 := position
-					if !p.rules[ruleIndent]() {
-						goto l1175
+					position1267, thunkPosition1267 := position, thunkPosition
+					if !p.rules[ruleCellDivider]() {
+						goto l1269
+					}
+					goto l1268
+				l1269:
+					if !p.rules[ruleCellStr]() {
+						goto l1268
+					}
+					goto l1266
+				l1268:
+					position, thunkPosition = position1267, thunkPosition1267
+					if !p.rules[ruleNewline]() {
+						goto l1270
+					}
+					goto l1262
+				l1270:
+					if !p.rules[ruleEndline]() {
+						goto l1271
+					}
+					goto l1262
+				l1271:
+					if !p.rules[ruleCellDivider]() {
+						goto l1272
+					}
+					goto l1262
+				l1272:
+					if !p.rules[ruleStr]() {
+						goto l1273
+					}
+					goto l1262
+				l1273:
+					{
+						position1274, thunkPosition1274 := position, thunkPosition
+						if !p.rules[ruleSp]() {
+							goto l1275
+						}
+						{
+							position1276, thunkPosition1276 := position, thunkPosition
+							if !p.rules[ruleCellDivider]() {
+								goto l1275
+							}
+							position, thunkPosition = position1276, thunkPosition1276
+						}
+						goto l1262
+					l1275:
+						position, thunkPosition = position1274, thunkPosition1274
+					}
+					if !p.rules[ruleInline]() {
+						goto l1262
 					}
-					position = position1176
 				}
-				if !p.rules[ruleRawNoteBlock]() {
-					goto l1175
+			l1266:
+				do(129)
+			l1572:
+				{
+					position1573, thunkPosition1573 := position, thunkPosition
+					{
+						position1575, thunkPosition1575 := position, thunkPosition
+						if !p.rules[ruleCellDivider]() {
+							goto l1577
+						}
+						goto l1576
+					l1577:
+						if !p.rules[ruleCellStr]() {
+							goto l1576
+						}
+						goto l1574
+					l1576:
+						position, thunkPosition = position1575, thunkPosition1575
+						if !p.rules[ruleNewline]() {
+							goto l1578
+						}
+						goto l1573
+					l1578:
+						if !p.rules[ruleEndline]() {
+							goto l1579
+						}
+						goto l1573
+					l1579:
+						if !p.rules[ruleCellDivider]() {
+							goto l1580
+						}
+						goto l1573
+					l1580:
+						if !p.rules[ruleStr]() {
+							goto l1581
+						}
+						goto l1573
+					l1581:
+						{
+							position1582, thunkPosition1582 := position, thunkPosition
+							if !p.rules[ruleSp]() {
+								goto l1583
+							}
+							{
+								position1584, thunkPosition1584 := position, thunkPosition
+								if !p.rules[ruleCellDivider]() {
+									goto l1583
+								}
+								position, thunkPosition = position1584, thunkPosition1584
+							}
+							goto l1573
+						l1583:
+							position, thunkPosition = position1582, thunkPosition1582
+						}
+						if !p.rules[ruleInline]() {
+							goto l1573
+						}
+					}
+				l1574:
+					do(129)
+					goto l1572
+				l1573:
+					position, thunkPosition = position1573, thunkPosition1573
 				}
-				do(99)
-				goto l1174
-			l1175:
-				position, thunkPosition = position1175, thunkPosition1175
 			}
-			do(100)
-			doarg(yyPop, 2)
+		l1263:
+			if !p.rules[ruleSp]() {
+				goto l1262
+			}
+			if !p.rules[ruleCellDivider]() {
+				goto l1585
+			}
+		l1585:
+			do(179)
+			doarg(yyPop, 1)
 			return true
-		l1173:
+		l1262:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 237 InlineNote <- (&{p.extension.Notes} '^[' StartList (!']' Inline { a = cons(yy, a) })+ ']' { yy = p.mkList(NOTE, a)
-                  yy.contents.str = "" }) */
+		/* 159 EmptyCell <- (Sp '|' { yy = p.mkElem(TABLECELL) }) */
 		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !(p.extension.Notes) {
-				goto l1177
+			position0 := position
+			if !p.rules[ruleSp]() {
+				goto l1277
 			}
-			if !matchString("^[") {
-				goto l1177
+			if !matchChar('|') {
+				goto l1277
 			}
+			do(130)
+			return true
+		l1277:
+			position = position0
+			return false
+		},
+		/* 160 SeparatorLine <- (StartList &TableLine '|'? (AlignmentCell { a = cons(yy, a) })+ Sp Newline {
+        yy = p.mkStringFromList(a, false);
+        yy.key = TABLESEPARATOR;
+    }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 1)
 			if !p.rules[ruleStartList]() {
-				goto l1177
+				goto l1278
 			}
 			doarg(yySet, -1)
-			if peekChar(']') {
-				goto l1177
+			{
+				position1279 := position
+				if !p.rules[ruleTableLine]() {
+					goto l1278
+				}
+				position = position1279
 			}
-			if !p.rules[ruleInline]() {
-				goto l1177
+			matchChar('|')
+			if !p.rules[ruleAlignmentCell]() {
+				goto l1278
 			}
-			do(101)
-		l1178:
+			do(131)
+		l1280:
 			{
-				position1179 := position
-				if peekChar(']') {
-					goto l1179
-				}
-				if !p.rules[ruleInline]() {
-					goto l1179
+				position1281 := position
+				if !p.rules[ruleAlignmentCell]() {
+					goto l1281
 				}
-				do(101)
-				goto l1178
-			l1179:
-				position = position1179
+				do(131)
+				goto l1280
+			l1281:
+				position = position1281
 			}
-			if !matchChar(']') {
-				goto l1177
+			if !p.rules[ruleSp]() {
+				goto l1278
 			}
-			do(102)
+			if !p.rules[ruleNewline]() {
+				goto l1278
+			}
+			do(132)
 			doarg(yyPop, 1)
 			return true
-		l1177:
+		l1278:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 238 Notes <- (StartList ((Note { a = cons(b, a) }) / SkipBlock)* { p.notes = reverse(a) } commit) */
+		/* 161 AlignmentCell <- (Sp (!'|' (LeftAlignWrap / CenterAlignWrap / RightAlignWrap / LeftAlign / ((&[\-] RightAlign) | (&[:] CenterAlign)))) Sp '|'?) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !p.rules[ruleStartList]() {
-				goto l1180
+			if !p.rules[ruleSp]() {
+				goto l1282
 			}
-			doarg(yySet, -1)
-		l1181:
+			if peekChar('|') {
+				goto l1282
+			}
+			if !p.rules[ruleLeftAlignWrap]() {
+				goto l1284
+			}
+			goto l1283
+		l1284:
+			if !p.rules[ruleCenterAlignWrap]() {
+				goto l1285
+			}
+			goto l1283
+		l1285:
+			if !p.rules[ruleRightAlignWrap]() {
+				goto l1286
+			}
+			goto l1283
+		l1286:
+			if !p.rules[ruleLeftAlign]() {
+				goto l1287
+			}
+			goto l1283
+		l1287:
 			{
-				position1182, thunkPosition1182 := position, thunkPosition
-				{
-					position1183, thunkPosition1183 := position, thunkPosition
-					if !p.rules[ruleNote]() {
-						goto l1184
+				if position == len(p.Buffer) {
+					goto l1282
+				}
+				switch p.Buffer[position] {
+				case '-':
+					if !p.rules[ruleRightAlign]() {
+						goto l1282
 					}
-					doarg(yySet, -2)
-					do(103)
-					goto l1183
-				l1184:
-					position, thunkPosition = position1183, thunkPosition1183
-					if !p.rules[ruleSkipBlock]() {
-						goto l1182
+					break
+				case ':':
+					if !p.rules[ruleCenterAlign]() {
+						goto l1282
 					}
+					break
+				default:
+					goto l1282
 				}
-			l1183:
-				goto l1181
-			l1182:
-				position, thunkPosition = position1182, thunkPosition1182
 			}
-			do(104)
-			if !(commit(thunkPosition0)) {
-				goto l1180
+		l1283:
+			if !p.rules[ruleSp]() {
+				goto l1282
 			}
-			doarg(yyPop, 2)
+			matchChar('|')
 			return true
-		l1180:
+		l1282:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 239 RawNoteBlock <- (StartList (!BlankLine OptionallyIndentedLine { a = cons(yy, a) })+ (< BlankLine* > { a = cons(p.mkString(yytext), a) }) {   yy = p.mkStringFromList(a, true)
-                    yy.key = RAW
-                }) */
+		/* 162 LeftAlignWrap <- (':'? '-'+ '+' &(!'-' !':') { yy = p.mkString("L");}) */
 		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l1185
+			position0 := position
+			matchChar(':')
+			if !matchChar('-') {
+				goto l1289
 			}
-			doarg(yySet, -1)
-			if !p.rules[ruleBlankLine]() {
-				goto l1188
+		l1290:
+			if !matchChar('-') {
+				goto l1291
 			}
-			goto l1185
-		l1188:
-			if !p.rules[ruleOptionallyIndentedLine]() {
-				goto l1185
+			goto l1290
+		l1291:
+			if !matchChar('+') {
+				goto l1289
 			}
-			do(105)
-		l1186:
-			{
-				position1187 := position
-				if !p.rules[ruleBlankLine]() {
-					goto l1189
-				}
-				goto l1187
-			l1189:
-				if !p.rules[ruleOptionallyIndentedLine]() {
-					goto l1187
-				}
-				do(105)
-				goto l1186
-			l1187:
-				position = position1187
+			if peekChar('-') {
+				goto l1289
 			}
-			begin = position
-		l1190:
-			if !p.rules[ruleBlankLine]() {
-				goto l1191
+			if peekChar(':') {
+				goto l1289
 			}
-			goto l1190
-		l1191:
-			end = position
-			do(106)
-			do(107)
-			doarg(yyPop, 1)
+			do(133)
 			return true
-		l1185:
-			position, thunkPosition = position0, thunkPosition0
+		l1289:
+			position = position0
 			return false
 		},
-		/* 240 DefinitionList <- (&{p.extension.Dlists} StartList (Definition { a = cons(yy, a) })+ { yy = p.mkList(DEFINITIONLIST, a) }) */
+		/* 163 LeftAlign <- (':'? '-'+ &(!'-' !':') { yy = p.mkString("l");}) */
 		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !(p.extension.Dlists) {
-				goto l1192
+			position0 := position
+			matchChar(':')
+			if !matchChar('-') {
+				goto l1293
 			}
-			if !p.rules[ruleStartList]() {
-				goto l1192
+		l1294:
+			if !matchChar('-') {
+				goto l1295
 			}
-			doarg(yySet, -1)
-			if !p.rules[ruleDefinition]() {
-				goto l1192
+			goto l1294
+		l1295:
+			if peekChar('-') {
+				goto l1293
 			}
-			do(108)
-		l1193:
-			{
-				position1194, thunkPosition1194 := position, thunkPosition
-				if !p.rules[ruleDefinition]() {
-					goto l1194
-				}
-				do(108)
-				goto l1193
-			l1194:
-				position, thunkPosition = position1194, thunkPosition1194
+			if peekChar(':') {
+				goto l1293
 			}
-			do(109)
-			doarg(yyPop, 1)
+			do(134)
 			return true
-		l1192:
-			position, thunkPosition = position0, thunkPosition0
+		l1293:
+			position = position0
 			return false
 		},
-		/* 241 Definition <- (&(NonindentSpace !Defmark Nonspacechar RawLine BlankLine? Defmark) StartList (DListTitle { a = cons(yy, a) })+ (DefTight / DefLoose) {
-				for e := yy.children; e != nil; e = e.next {
-					e.key = DEFDATA
-				}
-				a = cons(yy, a)
-			} { yy = p.mkList(LIST, a) }) */
+		/* 164 CenterAlignWrap <- (':' '-'* '+' ':' &(!'-' !':') { yy = p.mkString("C");}) */
 		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			{
-				position1196 := position
-				if !p.rules[ruleNonindentSpace]() {
-					goto l1195
-				}
-				if !p.rules[ruleDefmark]() {
-					goto l1197
-				}
-				goto l1195
-			l1197:
-				if !p.rules[ruleNonspacechar]() {
-					goto l1195
-				}
-				if !p.rules[ruleRawLine]() {
-					goto l1195
-				}
-				if !p.rules[ruleBlankLine]() {
-					goto l1198
-				}
-			l1198:
-				if !p.rules[ruleDefmark]() {
-					goto l1195
-				}
-				position = position1196
+			position0 := position
+			if !matchChar(':') {
+				goto l1297
+			}
+		l1298:
+			if !matchChar('-') {
+				goto l1299
+			}
+			goto l1298
+		l1299:
+			if !matchChar('+') {
+				goto l1297
+			}
+			if !matchChar(':') {
+				goto l1297
 			}
-			if !p.rules[ruleStartList]() {
-				goto l1195
+			if peekChar('-') {
+				goto l1297
 			}
-			doarg(yySet, -1)
-			if !p.rules[ruleDListTitle]() {
-				goto l1195
+			if peekChar(':') {
+				goto l1297
 			}
-			do(110)
-		l1200:
-			{
-				position1201, thunkPosition1201 := position, thunkPosition
-				if !p.rules[ruleDListTitle]() {
-					goto l1201
-				}
-				do(110)
-				goto l1200
-			l1201:
-				position, thunkPosition = position1201, thunkPosition1201
+			do(135)
+			return true
+		l1297:
+			position = position0
+			return false
+		},
+		/* 165 CenterAlign <- (':' '-'* ':' &(!'-' !':') { yy = p.mkString("c");}) */
+		func() bool {
+			position0 := position
+			if !matchChar(':') {
+				goto l1301
 			}
-			if !p.rules[ruleDefTight]() {
-				goto l1203
+		l1302:
+			if !matchChar('-') {
+				goto l1303
 			}
-			goto l1202
-		l1203:
-			if !p.rules[ruleDefLoose]() {
-				goto l1195
+			goto l1302
+		l1303:
+			if !matchChar(':') {
+				goto l1301
 			}
-		l1202:
-			do(111)
-			do(112)
-			doarg(yyPop, 1)
+			if peekChar('-') {
+				goto l1301
+			}
+			if peekChar(':') {
+				goto l1301
+			}
+			do(136)
 			return true
-		l1195:
-			position, thunkPosition = position0, thunkPosition0
+		l1301:
+			position = position0
 			return false
 		},
-		/* 242 DListTitle <- (NonindentSpace !Defmark &Nonspacechar StartList (!Endline Inline { a = cons(yy, a) })+ Sp Newline {	yy = p.mkList(LIST, a)
-				yy.key = DEFTITLE
-			}) */
+		/* 166 RightAlignWrap <- ('-'+ ':' '+' &(!'-' !':') { yy = p.mkString("R");}) */
 		func() bool {
-			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleNonindentSpace]() {
-				goto l1204
+			position0 := position
+			if !matchChar('-') {
+				goto l1305
 			}
-			if !p.rules[ruleDefmark]() {
-				goto l1205
+		l1306:
+			if !matchChar('-') {
+				goto l1307
 			}
-			goto l1204
-		l1205:
-			{
-				position1206 := position
-				if !p.rules[ruleNonspacechar]() {
-					goto l1204
-				}
-				position = position1206
+			goto l1306
+		l1307:
+			if !matchChar(':') {
+				goto l1305
 			}
-			if !p.rules[ruleStartList]() {
-				goto l1204
+			if !matchChar('+') {
+				goto l1305
 			}
-			doarg(yySet, -1)
-			if !p.rules[ruleEndline]() {
-				goto l1209
+			if peekChar('-') {
+				goto l1305
 			}
-			goto l1204
-		l1209:
-			if !p.rules[ruleInline]() {
-				goto l1204
+			if peekChar(':') {
+				goto l1305
 			}
-			do(113)
-		l1207:
-			{
-				position1208 := position
-				if !p.rules[ruleEndline]() {
-					goto l1210
-				}
-				goto l1208
-			l1210:
-				if !p.rules[ruleInline]() {
-					goto l1208
-				}
-				do(113)
-				goto l1207
-			l1208:
-				position = position1208
+			do(137)
+			return true
+		l1305:
+			position = position0
+			return false
+		},
+		/* 167 RightAlign <- ('-'+ ':' &(!'-' !':') { yy = p.mkString("r");}) */
+		func() bool {
+			position0 := position
+			if !matchChar('-') {
+				goto l1309
 			}
-			if !p.rules[ruleSp]() {
-				goto l1204
+		l1310:
+			if !matchChar('-') {
+				goto l1311
 			}
-			if !p.rules[ruleNewline]() {
-				goto l1204
+			goto l1310
+		l1311:
+			if !matchChar(':') {
+				goto l1309
 			}
-			do(114)
-			doarg(yyPop, 1)
+			if peekChar('-') {
+				goto l1309
+			}
+			if peekChar(':') {
+				goto l1309
+			}
+			do(138)
 			return true
-		l1204:
-			position, thunkPosition = position0, thunkPosition0
+		l1309:
+			position = position0
 			return false
 		},
-		/* 243 DefTight <- (&Defmark ListTight) */
+		/* 168 CellDivider <- '|' */
 		func() bool {
-			{
-				position1212 := position
-				if !p.rules[ruleDefmark]() {
-					goto l1211
-				}
-				position = position1212
-			}
-			if !p.rules[ruleListTight]() {
-				goto l1211
+			if !matchChar('|') {
+				goto l1313
 			}
 			return true
-		l1211:
+		l1313:
 			return false
 		},
-		/* 244 DefLoose <- (BlankLine &Defmark ListLoose) */
+		/* 169 TableCaption <- (StartList Label (Label { b = c; b.key = TABLELABEL;})? Sp Newline {
+    yy = a
+    yy.key = TABLECAPTION
+    if b != nil && b.key == TABLELABEL {
+        b.next = yy.children
+        yy.children = b
+    }
+}) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleBlankLine]() {
-				goto l1213
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 3)
+			if !p.rules[ruleStartList]() {
+				goto l1314
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleLabel]() {
+				goto l1314
 			}
+			doarg(yySet, -2)
 			{
-				position1214 := position
-				if !p.rules[ruleDefmark]() {
-					goto l1213
+				position1315, thunkPosition1315 := position, thunkPosition
+				if !p.rules[ruleLabel]() {
+					goto l1315
 				}
-				position = position1214
+				doarg(yySet, -3)
+				do(139)
+				goto l1316
+			l1315:
+				position, thunkPosition = position1315, thunkPosition1315
 			}
-			if !p.rules[ruleListLoose]() {
-				goto l1213
+		l1316:
+			if !p.rules[ruleSp]() {
+				goto l1314
+			}
+			if !p.rules[ruleNewline]() {
+				goto l1314
 			}
+			do(140)
+			doarg(yyPop, 3)
 			return true
-		l1213:
-			position = position0
+		l1314:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 245 Defmark <- (NonindentSpace ((&[~] '~') | (&[:] ':')) Spacechar+) */
+		/* 170 DefMarkChar <- (&{p.extension.isDefMarker(p.Buffer[position:])} . Utf8Tail*) */
 		func() bool {
 			position0 := position
-			if !p.rules[ruleNonindentSpace]() {
-				goto l1215
-			}
-			{
-				if position == len(p.Buffer) {
-					goto l1215
-				}
-				switch p.Buffer[position] {
-				case '~':
-					position++ // matchChar
-					break
-				case ':':
-					position++ // matchChar
-					break
-				default:
-					goto l1215
-				}
+			if !(p.extension.isDefMarker(p.Buffer[position:])) {
+				goto l1317
 			}
-			if !p.rules[ruleSpacechar]() {
-				goto l1215
+			if !matchDot() {
+				goto l1317
 			}
-		l1217:
-			if !p.rules[ruleSpacechar]() {
-				goto l1218
+		l1318:
+			if !p.rules[ruleUtf8Tail]() {
+				goto l1319
 			}
-			goto l1217
-		l1218:
+			goto l1318
+		l1319:
 			return true
-		l1215:
+		l1317:
 			position = position0
 			return false
 		},
-		/* 246 DefMarker <- (&{p.extension.Dlists} Defmark) */
+		/* 171 Utf8Tail <- (&{position < len(p.Buffer) && p.Buffer[position]&0xc0 == 0x80} .) */
 		func() bool {
-			if !(p.extension.Dlists) {
-				goto l1219
+			if !(position < len(p.Buffer) && p.Buffer[position]&0xc0 == 0x80) {
+				goto l1320
 			}
-			if !p.rules[ruleDefmark]() {
-				goto l1219
+			if !matchDot() {
+				goto l1320
 			}
 			return true
-		l1219:
+		l1320:
 			return false
 		},
-		/* 247 Table <- (StartList StartList (TableCaption { b = cons(yy, b) })? TableBody { yy.key = TABLEHEAD; a = cons(yy, a) } (SeparatorLine { append_list(yy, a) }) (TableBody { a = cons(yy, a) }) (BlankLine !TableCaption TableBody { a = cons(yy, a) } &(TableCaption / BlankLine))* ((TableCaption { b = cons(yy, b) } &BlankLine) / &BlankLine) {
-        if b != nil { append_list(b,a) }
-        yy = p.mkList(TABLE, a)
-    }) */
+		/* 172 DefListTight <- (StartList (DefItemTight { a = cons(yy, a) })+ BlankLine* !DefMarker { yy = p.mkList(LIST, a) }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 2)
-			if !p.rules[ruleStartList]() {
-				goto l1220
-			}
-			doarg(yySet, -2)
+			doarg(yyPush, 1)
 			if !p.rules[ruleStartList]() {
-				goto l1220
+				goto l1340
 			}
 			doarg(yySet, -1)
-			{
-				position1221, thunkPosition1221 := position, thunkPosition
-				if !p.rules[ruleTableCaption]() {
-					goto l1221
-				}
-				do(115)
-				goto l1222
-			l1221:
-				position, thunkPosition = position1221, thunkPosition1221
-			}
-		l1222:
-			if !p.rules[ruleTableBody]() {
-				goto l1220
-			}
-			do(116)
-			if !p.rules[ruleSeparatorLine]() {
-				goto l1220
+			if !p.rules[ruleDefItemTight]() {
+				goto l1340
 			}
-			do(117)
-			if !p.rules[ruleTableBody]() {
-				goto l1220
-			}
-			do(118)
-		l1223:
+			do(141)
+		l1341:
 			{
-				position1224, thunkPosition1224 := position, thunkPosition
-				if !p.rules[ruleBlankLine]() {
-					goto l1224
-				}
-				if !p.rules[ruleTableCaption]() {
-					goto l1225
-				}
-				goto l1224
-			l1225:
-				if !p.rules[ruleTableBody]() {
-					goto l1224
-				}
-				do(119)
-				{
-					position1226, thunkPosition1226 := position, thunkPosition
-					if !p.rules[ruleTableCaption]() {
-						goto l1228
-					}
-					goto l1227
-				l1228:
-					if !p.rules[ruleBlankLine]() {
-						goto l1224
-					}
-				l1227:
-					position, thunkPosition = position1226, thunkPosition1226
+				position1342, thunkPosition1342 := position, thunkPosition
+				if !p.rules[ruleDefItemTight]() {
+					goto l1342
 				}
-				goto l1223
-			l1224:
-				position, thunkPosition = position1224, thunkPosition1224
+				do(141)
+				goto l1341
+			l1342:
+				position, thunkPosition = position1342, thunkPosition1342
+			}
+		l1343:
+			if !p.rules[ruleBlankLine]() {
+				goto l1344
+			}
+			goto l1343
+		l1344:
+			if !p.rules[ruleDefMarker]() {
+				goto l1345
+			}
+			goto l1340
+		l1345:
+			do(142)
+			doarg(yyPop, 1)
+			return true
+		l1340:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 173 DefListLoose <- (StartList (DefItem BlankLine* {
+                  markLoose(b)
+                  a = cons(b, a)
+              })+ { yy = p.mkList(LIST, a)
+              yy.loose = true }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !p.rules[ruleStartList]() {
+				goto l1346
+			}
+			doarg(yySet, -1)
+			if !p.rules[ruleDefItem]() {
+				goto l1346
 			}
+			doarg(yySet, -2)
+		l1347:
+			if !p.rules[ruleBlankLine]() {
+				goto l1348
+			}
+			goto l1347
+		l1348:
+			do(143)
+		l1349:
 			{
-				position1229, thunkPosition1229 := position, thunkPosition
-				if !p.rules[ruleTableCaption]() {
-					goto l1230
+				position1350, thunkPosition1350 := position, thunkPosition
+				if !p.rules[ruleDefItem]() {
+					goto l1350
 				}
-				do(120)
-				{
-					position1231 := position
-					if !p.rules[ruleBlankLine]() {
-						goto l1230
-					}
-					position = position1231
-				}
-				goto l1229
-			l1230:
-				position, thunkPosition = position1229, thunkPosition1229
-				{
-					position1232 := position
-					if !p.rules[ruleBlankLine]() {
-						goto l1220
-					}
-					position = position1232
+				doarg(yySet, -2)
+			l1351:
+				if !p.rules[ruleBlankLine]() {
+					goto l1352
 				}
+				goto l1351
+			l1352:
+				do(143)
+				goto l1349
+			l1350:
+				position, thunkPosition = position1350, thunkPosition1350
 			}
-		l1229:
-			do(121)
+			do(144)
 			doarg(yyPop, 2)
 			return true
-		l1220:
+		l1346:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 248 TableBody <- (StartList (TableRow { a = cons(yy, a) })+ { yy = p.mkList(TABLEBODY, a) }) */
+		/* 174 DefItem <- (DefMarker StartList ListBlock { a = cons(yy, a) } (ListContinuationBlock { a = cons(yy, a) })* {
+               yy = p.mkListItem(a)
+            }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
 			doarg(yyPush, 1)
+			if !p.rules[ruleDefMarker]() {
+				goto l1353
+			}
 			if !p.rules[ruleStartList]() {
-				goto l1233
+				goto l1353
 			}
 			doarg(yySet, -1)
-			if !p.rules[ruleTableRow]() {
-				goto l1233
+			if !p.rules[ruleListBlock]() {
+				goto l1353
 			}
-			do(122)
-		l1234:
+			do(145)
+		l1354:
 			{
-				position1235, thunkPosition1235 := position, thunkPosition
-				if !p.rules[ruleTableRow]() {
-					goto l1235
+				position1355, thunkPosition1355 := position, thunkPosition
+				if !p.rules[ruleListContinuationBlock]() {
+					goto l1355
 				}
-				do(122)
-				goto l1234
-			l1235:
-				position, thunkPosition = position1235, thunkPosition1235
+				do(146)
+				goto l1354
+			l1355:
+				position, thunkPosition = position1355, thunkPosition1355
 			}
-			do(123)
+			do(147)
 			doarg(yyPop, 1)
 			return true
-		l1233:
+		l1353:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 249 TableRow <- (StartList (!SeparatorLine &TableLine '|'? (TableCell { a = cons(yy, a) })+) Sp Newline { yy = p.mkList(TABLEROW, a) }) */
+		/* 175 DefItemTight <- (DefMarker StartList ListBlock { a = cons(yy, a) } (!BlankLine ListContinuationBlock { a = cons(yy, a) })* !ListContinuationBlock {
+               yy = p.mkListItem(a)
+            }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
 			doarg(yyPush, 1)
+			if !p.rules[ruleDefMarker]() {
+				goto l1356
+			}
 			if !p.rules[ruleStartList]() {
-				goto l1236
+				goto l1356
 			}
 			doarg(yySet, -1)
-			if !p.rules[ruleSeparatorLine]() {
-				goto l1237
+			if !p.rules[ruleListBlock]() {
+				goto l1356
 			}
-			goto l1236
-		l1237:
+			do(148)
+		l1357:
 			{
-				position1238 := position
-				if !p.rules[ruleTableLine]() {
-					goto l1236
+				position1358, thunkPosition1358 := position, thunkPosition
+				if !p.rules[ruleBlankLine]() {
+					goto l1359
 				}
-				position = position1238
-			}
-			matchChar('|')
-			if !p.rules[ruleTableCell]() {
-				goto l1236
-			}
-			do(124)
-		l1239:
-			{
-				position1240 := position
-				if !p.rules[ruleTableCell]() {
-					goto l1240
+				goto l1358
+			l1359:
+				if !p.rules[ruleListContinuationBlock]() {
+					goto l1358
 				}
-				do(124)
-				goto l1239
-			l1240:
-				position = position1240
-			}
-			if !p.rules[ruleSp]() {
-				goto l1236
+				do(149)
+				goto l1357
+			l1358:
+				position, thunkPosition = position1358, thunkPosition1358
 			}
-			if !p.rules[ruleNewline]() {
-				goto l1236
+			if !p.rules[ruleListContinuationBlock]() {
+				goto l1360
 			}
-			do(125)
+			goto l1356
+		l1360:
+			do(150)
 			doarg(yyPop, 1)
 			return true
-		l1236:
+		l1356:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 250 TableLine <- ((!Newline !'|' .)* '|') */
+		/* 176 Mark <- (&{p.extension.Mark} '==' !Whitespace StartList (!'==' Inline { a = cons(b, a) })+ '==' { yy = p.mkList(MARK, a) }) */
 		func() bool {
-			position0 := position
-		l1242:
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !(p.extension.Mark) {
+				goto l1370
+			}
+			if !matchString("==") {
+				goto l1370
+			}
+			if !p.rules[ruleWhitespace]() {
+				goto l1371
+			}
+			goto l1370
+		l1371:
+			if !p.rules[ruleStartList]() {
+				goto l1370
+			}
+			doarg(yySet, -1)
+			if !matchString("==") {
+				goto l1372
+			}
+			goto l1370
+		l1372:
+			if !p.rules[ruleInline]() {
+				goto l1370
+			}
+			doarg(yySet, -2)
+			do(151)
+		l1373:
 			{
-				position1243 := position
-				if !p.rules[ruleNewline]() {
-					goto l1244
-				}
-				goto l1243
-			l1244:
-				if peekChar('|') {
-					goto l1243
+				position1374, thunkPosition1374 := position, thunkPosition
+				if !matchString("==") {
+					goto l1375
 				}
-				if !matchDot() {
-					goto l1243
+				goto l1374
+			l1375:
+				if !p.rules[ruleInline]() {
+					goto l1374
 				}
-				goto l1242
-			l1243:
-				position = position1243
+				doarg(yySet, -2)
+				do(151)
+				goto l1373
+			l1374:
+				position, thunkPosition = position1374, thunkPosition1374
 			}
-			if !matchChar('|') {
-				goto l1241
+			if !matchString("==") {
+				goto l1370
 			}
+			do(152)
+			doarg(yyPop, 2)
 			return true
-		l1241:
-			position = position0
+		l1370:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 251 TableCell <- (ExtendedCell / EmptyCell / FullCell) */
+		/* 177 Critic <- (&{p.extension.Critic} (CriticIns / CriticDel / CriticSub / CriticHighlight / CriticComment)) */
 		func() bool {
-			if !p.rules[ruleExtendedCell]() {
-				goto l1247
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.Critic) {
+				goto l1385
 			}
-			goto l1246
-		l1247:
-			if !p.rules[ruleEmptyCell]() {
-				goto l1248
+			if !p.rules[ruleCriticIns]() {
+				goto l1387
 			}
-			goto l1246
-		l1248:
-			if !p.rules[ruleFullCell]() {
-				goto l1245
+			goto l1386
+		l1387:
+			if !p.rules[ruleCriticDel]() {
+				goto l1388
 			}
-		l1246:
+			goto l1386
+		l1388:
+			if !p.rules[ruleCriticSub]() {
+				goto l1389
+			}
+			goto l1386
+		l1389:
+			if !p.rules[ruleCriticHighlight]() {
+				goto l1390
+			}
+			goto l1386
+		l1390:
+			if !p.rules[ruleCriticComment]() {
+				goto l1385
+			}
+		l1386:
 			return true
-		l1245:
+		l1385:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 252 ExtendedCell <- ((EmptyCell / FullCell) < '|'+ > {
-        span := p.mkString(yytext)
-        span.key = CELLSPAN
-        span.next = yy.children
-        yy.children = span
-    }) */
+		/* 178 CriticIns <- ('{++' StartList (!'++}' Inline { a = cons(b, a) })* '++}' { yy = p.mkList(CRITICINS, a) }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			if !p.rules[ruleEmptyCell]() {
-				goto l1251
+			doarg(yyPush, 2)
+			if !matchString("{++") {
+				goto l1391
 			}
-			goto l1250
-		l1251:
-			if !p.rules[ruleFullCell]() {
-				goto l1249
+			if !p.rules[ruleStartList]() {
+				goto l1391
 			}
-		l1250:
-			begin = position
-			if !matchChar('|') {
-				goto l1249
+			doarg(yySet, -1)
+		l1392:
+			{
+				position1393, thunkPosition1393 := position, thunkPosition
+				if !matchString("++}") {
+					goto l1394
+				}
+				goto l1393
+			l1394:
+				if !p.rules[ruleInline]() {
+					goto l1393
+				}
+				doarg(yySet, -2)
+				do(153)
+				goto l1392
+			l1393:
+				position, thunkPosition = position1393, thunkPosition1393
 			}
-		l1252:
-			if !matchChar('|') {
-				goto l1253
+			if !matchString("++}") {
+				goto l1391
 			}
-			goto l1252
-		l1253:
-			end = position
-			do(126)
+			do(154)
+			doarg(yyPop, 2)
 			return true
-		l1249:
+		l1391:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 253 CellStr <- (< (!'|' NormalChar) ((!'|' NormalChar) / ('_'+ &Alphanumeric))* > { yy = p.mkString(yytext) }) */
+		/* 179 CriticDel <- ('{--' StartList (!'--}' Inline { a = cons(b, a) })* '--}' { yy = p.mkList(CRITICDEL, a) }) */
 		func() bool {
-			position0 := position
-			begin = position
-			if peekChar('|') {
-				goto l1254
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 2)
+			if !matchString("{--") {
+				goto l1395
 			}
-			if !p.rules[ruleNormalChar]() {
-				goto l1254
+			if !p.rules[ruleStartList]() {
+				goto l1395
 			}
-		l1255:
+			doarg(yySet, -1)
+		l1396:
 			{
-				position1256 := position
-				if peekChar('|') {
-					goto l1258
+				position1397, thunkPosition1397 := position, thunkPosition
+				if !matchString("--}") {
+					goto l1398
 				}
-				if !p.rules[ruleNormalChar]() {
-					goto l1258
+				goto l1397
+			l1398:
+				if !p.rules[ruleInline]() {
+					goto l1397
 				}
-				goto l1257
-			l1258:
-				if !matchChar('_') {
-					goto l1256
+				doarg(yySet, -2)
+				do(155)
+				goto l1396
+			l1397:
+				position, thunkPosition = position1397, thunkPosition1397
+			}
+			if !matchString("--}") {
+				goto l1395
+			}
+			do(156)
+			doarg(yyPop, 2)
+			return true
+		l1395:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 180 CriticSub <- ('{~~' StartList (!'~>' Inline { a = cons(b, a) })* '~>' StartList (!'~~}' Inline { c = cons(b, c) })* '~~}' { yy = p.mkElem(CRITICSUB)
+              yy.children = cons(p.mkList(CRITICDEL, a), p.mkList(CRITICINS, c)) }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			doarg(yyPush, 3)
+			if !matchString("{~~") {
+				goto l1399
+			}
+			if !p.rules[ruleStartList]() {
+				goto l1399
+			}
+			doarg(yySet, -1)
+		l1400:
+			{
+				position1401, thunkPosition1401 := position, thunkPosition
+				if !matchString("~>") {
+					goto l1402
 				}
-			l1259:
-				if !matchChar('_') {
-					goto l1260
+				goto l1401
+			l1402:
+				if !p.rules[ruleInline]() {
+					goto l1401
 				}
-				goto l1259
-			l1260:
-				{
-					position1261 := position
-					if !p.rules[ruleAlphanumeric]() {
-						goto l1256
-					}
-					position = position1261
+				doarg(yySet, -2)
+				do(157)
+				goto l1400
+			l1401:
+				position, thunkPosition = position1401, thunkPosition1401
+			}
+			if !matchString("~>") {
+				goto l1399
+			}
+			if !p.rules[ruleStartList]() {
+				goto l1399
+			}
+			doarg(yySet, -3)
+		l1403:
+			{
+				position1404, thunkPosition1404 := position, thunkPosition
+				if !matchString("~~}") {
+					goto l1405
 				}
-			l1257:
-				goto l1255
-			l1256:
-				position = position1256
+				goto l1404
+			l1405:
+				if !p.rules[ruleInline]() {
+					goto l1404
+				}
+				doarg(yySet, -2)
+				do(158)
+				goto l1403
+			l1404:
+				position, thunkPosition = position1404, thunkPosition1404
 			}
-			end = position
-			do(127)
+			if !matchString("~~}") {
+				goto l1399
+			}
+			do(159)
+			doarg(yyPop, 3)
 			return true
-		l1254:
-			position = position0
+		l1399:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 254 FullCell <- (Sp StartList (((!'|' CellStr) / (!Newline !Endline !'|' !Str !(Sp &'|') Inline)) { a = cons(yy, a) })+ Sp '|'? { yy = p.mkList(TABLECELL, a) }) */
+		/* 181 CriticHighlight <- ('{==' StartList (!'==}' Inline { a = cons(b, a) })* '==}' { yy = p.mkList(CRITICHIGHLIGHT, a) }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleSp]() {
-				goto l1262
+			doarg(yyPush, 2)
+			if !matchString("{==") {
+				goto l1406
 			}
 			if !p.rules[ruleStartList]() {
-				goto l1262
+				goto l1406
 			}
 			doarg(yySet, -1)
-			if peekChar('|') {
-				goto l1266
+		l1407:
+			{
+				position1408, thunkPosition1408 := position, thunkPosition
+				if !matchString("==}") {
+					goto l1409
+				}
+				goto l1408
+			l1409:
+				if !p.rules[ruleInline]() {
+					goto l1408
+				}
+				doarg(yySet, -2)
+				do(160)
+				goto l1407
+			l1408:
+				position, thunkPosition = position1408, thunkPosition1408
 			}
-			if !p.rules[ruleCellStr]() {
-				goto l1266
+			if !matchString("==}") {
+				goto l1406
 			}
-			goto l1265
-		l1266:
-			if !p.rules[ruleNewline]() {
-				goto l1267
+			do(161)
+			doarg(yyPop, 2)
+			return true
+		l1406:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 182 CriticComment <- ('{>>' < (!'<<}' .)* > '<<}' { yy = p.mkString(yytext)
+              yy.key = CRITICCOMMENT }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			if !matchString("{>>") {
+				goto l1410
 			}
-			goto l1262
-		l1267:
-			if !p.rules[ruleEndline]() {
-				goto l1268
+			begin = position
+		l1411:
+			{
+				position1412, thunkPosition1412 := position, thunkPosition
+				if !matchString("<<}") {
+					goto l1413
+				}
+				goto l1412
+			l1413:
+				if !matchDot() {
+					goto l1412
+				}
+				goto l1411
+			l1412:
+				position, thunkPosition = position1412, thunkPosition1412
 			}
-			goto l1262
-		l1268:
-			if peekChar('|') {
-				goto l1262
+			end = position
+			if !matchString("<<}") {
+				goto l1410
 			}
-			if !p.rules[ruleStr]() {
-				goto l1269
+			do(162)
+			return true
+		l1410:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 183 HtmlSpecial <- (HtmlCdata / HtmlProcessing / HtmlDeclaration) */
+		func() bool {
+			if !p.rules[ruleHtmlCdata]() {
+				goto l1416
+			}
+			goto l1415
+		l1416:
+			if !p.rules[ruleHtmlProcessing]() {
+				goto l1417
+			}
+			goto l1415
+		l1417:
+			if !p.rules[ruleHtmlDeclaration]() {
+				goto l1414
+			}
+		l1415:
+			return true
+		l1414:
+			return false
+		},
+		/* 184 HtmlCdata <- ('<![CDATA[' (!']]>' .)* ']]>') */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			if !matchString("<![CDATA[") {
+				goto l1418
 			}
-			goto l1262
-		l1269:
+		l1419:
 			{
-				position1270 := position
-				if !p.rules[ruleSp]() {
-					goto l1270
+				position1420, thunkPosition1420 := position, thunkPosition
+				if !matchString("]]>") {
+					goto l1421
 				}
-				if !peekChar('|') {
-					goto l1270
+				goto l1420
+			l1421:
+				if !matchDot() {
+					goto l1420
 				}
-				goto l1262
-			l1270:
-				position = position1270
+				goto l1419
+			l1420:
+				position, thunkPosition = position1420, thunkPosition1420
 			}
-			if !p.rules[ruleInline]() {
-				goto l1262
+			if !matchString("]]>") {
+				goto l1418
 			}
-		l1265:
-			do(128)
-		l1263:
+			return true
+		l1418:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 185 HtmlProcessing <- ('<?' (!'?>' .)* '?>') */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			if !matchString("<?") {
+				goto l1422
+			}
+		l1423:
 			{
-				position1264, thunkPosition1264 := position, thunkPosition
-				if peekChar('|') {
-					goto l1272
-				}
-				if !p.rules[ruleCellStr]() {
-					goto l1272
-				}
-				goto l1271
-			l1272:
-				if !p.rules[ruleNewline]() {
-					goto l1273
-				}
-				goto l1264
-			l1273:
-				if !p.rules[ruleEndline]() {
-					goto l1274
-				}
-				goto l1264
-			l1274:
-				if peekChar('|') {
-					goto l1264
+				position1424, thunkPosition1424 := position, thunkPosition
+				if !matchString("?>") {
+					goto l1425
 				}
-				if !p.rules[ruleStr]() {
-					goto l1275
+				goto l1424
+			l1425:
+				if !matchDot() {
+					goto l1424
 				}
-				goto l1264
-			l1275:
-				{
-					position1276 := position
-					if !p.rules[ruleSp]() {
-						goto l1276
-					}
-					if !peekChar('|') {
-						goto l1276
-					}
-					goto l1264
-				l1276:
-					position = position1276
+				goto l1423
+			l1424:
+				position, thunkPosition = position1424, thunkPosition1424
+			}
+			if !matchString("?>") {
+				goto l1422
+			}
+			return true
+		l1422:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 186 HtmlDeclaration <- ('<!' [A-Za-z] (!'>' .)* '>') */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			if !matchString("<!") {
+				goto l1426
+			}
+			if !matchClass(2) {
+				goto l1426
+			}
+		l1427:
+			{
+				position1428, thunkPosition1428 := position, thunkPosition
+				if !matchChar('>') {
+					goto l1429
 				}
-				if !p.rules[ruleInline]() {
-					goto l1264
+				goto l1428
+			l1429:
+				if !matchDot() {
+					goto l1428
 				}
-			l1271:
-				do(128)
-				goto l1263
-			l1264:
-				position, thunkPosition = position1264, thunkPosition1264
+				goto l1427
+			l1428:
+				position, thunkPosition = position1428, thunkPosition1428
 			}
-			if !p.rules[ruleSp]() {
-				goto l1262
+			if !matchChar('>') {
+				goto l1426
 			}
-			matchChar('|')
-			do(129)
-			doarg(yyPop, 1)
 			return true
-		l1262:
+		l1426:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 255 EmptyCell <- (Sp '|' { yy = p.mkElem(TABLECELL) }) */
+		/* 187 ExtInline <- (< &{p.inlineExtension(&position)} > { yy = p.mkInlineExtension(yytext) }) */
 		func() bool {
-			position0 := position
-			if !p.rules[ruleSp]() {
-				goto l1277
+			position0, thunkPosition0 := position, thunkPosition
+			begin = position
+			if !(p.inlineExtension(&position)) {
+				goto l1434
 			}
-			if !matchChar('|') {
-				goto l1277
+			end = position
+			do(163)
+			return true
+		l1434:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 188 ExtBlock <- (< &{p.blockExtension(&position)} > BlankLine* { yy = p.mkBlockExtension(yytext) }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			begin = position
+			if !(p.blockExtension(&position)) {
+				goto l1435
 			}
-			do(130)
+			end = position
+		l1436:
+			if !p.rules[ruleBlankLine]() {
+				goto l1437
+			}
+			goto l1436
+		l1437:
+			do(164)
 			return true
-		l1277:
-			position = position0
+		l1435:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 256 SeparatorLine <- (StartList &TableLine '|'? (AlignmentCell { a = cons(yy, a) })+ Sp Newline {
-        yy = p.mkStringFromList(a, false);
-        yy.key = TABLESEPARATOR;
-    }) */
+		/* 189 Container <- (< &{p.containerBlock(&position)} > BlankLine* { yy = p.mkContainer(yytext) }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 1)
-			if !p.rules[ruleStartList]() {
-				goto l1278
+			begin = position
+			if !(p.containerBlock(&position)) {
+				goto l1440
 			}
-			doarg(yySet, -1)
-			{
-				position1279 := position
-				if !p.rules[ruleTableLine]() {
-					goto l1278
-				}
-				position = position1279
+			end = position
+		l1441:
+			if !p.rules[ruleBlankLine]() {
+				goto l1442
 			}
-			matchChar('|')
-			if !p.rules[ruleAlignmentCell]() {
-				goto l1278
+			goto l1441
+		l1442:
+			do(165)
+			return true
+		l1440:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 190 LineTail <- (< &{p.lineTail(&position)} > { yy = p.mkString(yytext) }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			begin = position
+			if !(p.lineTail(&position)) {
+				goto l1444
 			}
-			do(131)
-		l1280:
-			{
-				position1281 := position
-				if !p.rules[ruleAlignmentCell]() {
-					goto l1281
-				}
-				do(131)
-				goto l1280
-			l1281:
-				position = position1281
+			end = position
+			do(166)
+			return true
+		l1444:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 191 ListMarker <- (< (DefMarker / Bullet / Enumerator) > { yy = p.mkString(strings.TrimSpace(yytext)) }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			begin = position
+			if !p.rules[ruleDefMarker]() {
+				goto l1448
 			}
-			if !p.rules[ruleSp]() {
-				goto l1278
+			goto l1447
+		l1448:
+			if !p.rules[ruleBullet]() {
+				goto l1449
 			}
-			if !p.rules[ruleNewline]() {
-				goto l1278
+			goto l1447
+		l1449:
+			if !p.rules[ruleEnumerator]() {
+				goto l1446
 			}
-			do(132)
-			doarg(yyPop, 1)
+		l1447:
+			end = position
+			do(167)
 			return true
-		l1278:
+		l1446:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 257 AlignmentCell <- (Sp (!'|' (LeftAlignWrap / CenterAlignWrap / RightAlignWrap / LeftAlign / ((&[\-] RightAlign) | (&[:] CenterAlign)))) Sp '|'?) */
+		/* 192 Prime <- (&{p.prime(position)} < ('\'' / '"') > { yy = p.mkString(primes[yytext]) }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			if !p.rules[ruleSp]() {
-				goto l1282
+			if !(p.prime(position)) {
+				goto l1470
 			}
-			if peekChar('|') {
-				goto l1282
+			begin = position
+			if !matchChar('\'') {
+				goto l1472
 			}
-			if !p.rules[ruleLeftAlignWrap]() {
-				goto l1284
+			goto l1471
+		l1472:
+			if !matchChar('"') {
+				goto l1470
 			}
-			goto l1283
-		l1284:
-			if !p.rules[ruleCenterAlignWrap]() {
-				goto l1285
+		l1471:
+			end = position
+			do(168)
+			return true
+		l1470:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 193 TableRef <- (&{p.extension.TableRefs} '[#' < (!']' Nonspacechar)+ > ']' { yy = p.tableRef(yytext) }) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.TableRefs) {
+				goto l1475
 			}
-			goto l1283
-		l1285:
-			if !p.rules[ruleRightAlignWrap]() {
-				goto l1286
+			if !matchString("[#") {
+				goto l1475
 			}
-			goto l1283
-		l1286:
-			if !p.rules[ruleLeftAlign]() {
-				goto l1287
+			begin = position
+			if !matchChar(']') {
+				goto l1476
 			}
-			goto l1283
-		l1287:
+			goto l1475
+		l1476:
+			if !p.rules[ruleNonspacechar]() {
+				goto l1475
+			}
+		l1477:
 			{
-				if position == len(p.Buffer) {
-					goto l1282
+				position1478, thunkPosition1478 := position, thunkPosition
+				if !matchChar(']') {
+					goto l1479
 				}
-				switch p.Buffer[position] {
-				case '-':
-					if !p.rules[ruleRightAlign]() {
-						goto l1282
-					}
-					break
-				case ':':
-					if !p.rules[ruleCenterAlign]() {
-						goto l1282
-					}
-					break
-				default:
-					goto l1282
+				goto l1478
+			l1479:
+				if !p.rules[ruleNonspacechar]() {
+					goto l1478
 				}
+				goto l1477
+			l1478:
+				position, thunkPosition = position1478, thunkPosition1478
 			}
-		l1283:
-			if !p.rules[ruleSp]() {
-				goto l1282
+			end = position
+			if !matchChar(']') {
+				goto l1475
 			}
-			matchChar('|')
+			do(169)
 			return true
-		l1282:
+		l1475:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 258 LeftAlignWrap <- (':'? '-'+ '+' &(!'-' !':') { yy = p.mkString("L");}) */
+		/* 194 Hashtag <- (&{p.extension.Hashtags && p.tagStart(position)} '#' < (Alphanumeric / [-_/])+ > { yy = p.hashtag(yytext) }) */
 		func() bool {
-			position0 := position
-			matchChar(':')
-			if !matchChar('-') {
-				goto l1289
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.Hashtags && p.tagStart(position)) {
+				goto l1482
 			}
-		l1290:
-			if !matchChar('-') {
-				goto l1291
+			if !matchChar('#') {
+				goto l1482
 			}
-			goto l1290
-		l1291:
-			if !matchChar('+') {
-				goto l1289
+			begin = position
+			if !p.rules[ruleAlphanumeric]() {
+				goto l1484
 			}
-			if peekChar('-') {
-				goto l1289
+			goto l1483
+		l1484:
+			if !matchClass(8) {
+				goto l1482
 			}
-			if peekChar(':') {
-				goto l1289
+		l1483:
+		l1485:
+			{
+				position1486, thunkPosition1486 := position, thunkPosition
+				if !p.rules[ruleAlphanumeric]() {
+					goto l1488
+				}
+				goto l1487
+			l1488:
+				if !matchClass(8) {
+					goto l1486
+				}
+			l1487:
+				goto l1485
+			l1486:
+				position, thunkPosition = position1486, thunkPosition1486
+			}
+			end = position
+			do(170)
+			return true
+		l1482:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 195 NormalChars <- (&{p.normalChars(&position)}) */
+		func() bool {
+			if !(p.normalChars(&position)) {
+				goto l1507
 			}
-			do(133)
 			return true
-		l1289:
-			position = position0
+		l1507:
 			return false
 		},
-		/* 259 LeftAlign <- (':'? '-'+ &(!'-' !':') { yy = p.mkString("l");}) */
+		/* 196 TracePosition <- (&{p.tracePosition(position)}) */
 		func() bool {
-			position0 := position
-			matchChar(':')
-			if !matchChar('-') {
-				goto l1293
-			}
-		l1294:
-			if !matchChar('-') {
-				goto l1295
-			}
-			goto l1294
-		l1295:
-			if peekChar('-') {
-				goto l1293
-			}
-			if peekChar(':') {
-				goto l1293
+			if !(p.tracePosition(position)) {
+				goto l1508
 			}
-			do(134)
 			return true
-		l1293:
-			position = position0
+		l1508:
 			return false
 		},
-		/* 260 CenterAlignWrap <- (':' '-'* '+' ':' &(!'-' !':') { yy = p.mkString("C");}) */
+		/* 197 Placeholder <- (&{p.extension.Placeholders} '{{' Sp < [A-Za-z_] [A-Za-z0-9_.-]* > Sp '}}' { yy = p.mkElem(PLACEHOLDER); yy.contents.str = yytext }) */
 		func() bool {
-			position0 := position
-			if !matchChar(':') {
-				goto l1297
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.Placeholders) {
+				goto l1515
 			}
-		l1298:
-			if !matchChar('-') {
-				goto l1299
+			if !matchString("{{") {
+				goto l1515
 			}
-			goto l1298
-		l1299:
-			if !matchChar('+') {
-				goto l1297
+			if !p.rules[ruleSp]() {
+				goto l1515
 			}
-			if !matchChar(':') {
-				goto l1297
+			begin = position
+			if !matchClass(9) {
+				goto l1515
 			}
-			if peekChar('-') {
-				goto l1297
+		l1516:
+			if !matchClass(10) {
+				goto l1517
 			}
-			if peekChar(':') {
-				goto l1297
+			goto l1516
+		l1517:
+			end = position
+			if !p.rules[ruleSp]() {
+				goto l1515
 			}
-			do(135)
+			if !matchString("}}") {
+				goto l1515
+			}
+			do(171)
 			return true
-		l1297:
-			position = position0
+		l1515:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 261 CenterAlign <- (':' '-'* ':' &(!'-' !':') { yy = p.mkString("c");}) */
+		/* 198 Citation <- (&{p.extension.Citations} '[' < &{p.citationText(&position)} > ']' !('(' / '[') { yy = p.mkElem(CITATION); yy.contents.str = yytext }) */
 		func() bool {
-			position0 := position
-			if !matchChar(':') {
-				goto l1301
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.Citations) {
+				goto l1522
 			}
-		l1302:
-			if !matchChar('-') {
-				goto l1303
+			if !matchChar('[') {
+				goto l1522
 			}
-			goto l1302
-		l1303:
-			if !matchChar(':') {
-				goto l1301
+			begin = position
+			if !(p.citationText(&position)) {
+				goto l1522
 			}
-			if peekChar('-') {
-				goto l1301
+			end = position
+			if !matchChar(']') {
+				goto l1522
 			}
-			if peekChar(':') {
-				goto l1301
+			{
+				position1523, thunkPosition1523 := position, thunkPosition
+				if !matchChar('(') {
+					goto l1526
+				}
+				goto l1525
+			l1526:
+				if !matchChar('[') {
+					goto l1524
+				}
+			l1525:
+				goto l1522
+			l1524:
+				position, thunkPosition = position1523, thunkPosition1523
 			}
-			do(136)
+			do(174)
 			return true
-		l1301:
-			position = position0
+		l1522:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 262 RightAlignWrap <- ('-'+ ':' '+' &(!'-' !':') { yy = p.mkString("R");}) */
+		/* 199 Comment <- (&{p.extension.Comments} (('%%' < (!'%%' !(Newline BlankLine) .)* > '%%') / ('<!---' < (!'--->' !(Newline BlankLine) .)* > '--->')) { yy = p.mkElem(COMMENT); yy.contents.str = yytext }) */
 		func() bool {
-			position0 := position
-			if !matchChar('-') {
-				goto l1305
-			}
-		l1306:
-			if !matchChar('-') {
-				goto l1307
-			}
-			goto l1306
-		l1307:
-			if !matchChar(':') {
-				goto l1305
-			}
-			if !matchChar('+') {
-				goto l1305
-			}
-			if peekChar('-') {
-				goto l1305
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.Comments) {
+				goto l1532
 			}
-			if peekChar(':') {
-				goto l1305
+			{
+				position1534, thunkPosition1534 := position, thunkPosition
+				if !matchString("%%") {
+					goto l1535
+				}
+				begin = position
+			l1536:
+				{
+					position1537, thunkPosition1537 := position, thunkPosition
+					if !matchString("%%") {
+						goto l1538
+					}
+					goto l1537
+				l1538:
+					{
+						position1539, thunkPosition1539 := position, thunkPosition
+						if !p.rules[ruleNewline]() {
+							goto l1540
+						}
+						if !p.rules[ruleBlankLine]() {
+							goto l1540
+						}
+						goto l1537
+					l1540:
+						position, thunkPosition = position1539, thunkPosition1539
+					}
+					if !matchDot() {
+						goto l1537
+					}
+					goto l1536
+				l1537:
+					position, thunkPosition = position1537, thunkPosition1537
+				}
+				end = position
+				if !matchString("%%") {
+					goto l1535
+				}
+				goto l1533
+			l1535:
+				position, thunkPosition = position1534, thunkPosition1534
+				if !matchString("<!---") {
+					goto l1532
+				}
+				begin = position
+			l1541:
+				{
+					position1542, thunkPosition1542 := position, thunkPosition
+					if !matchString("--->") {
+						goto l1543
+					}
+					goto l1542
+				l1543:
+					{
+						position1544, thunkPosition1544 := position, thunkPosition
+						if !p.rules[ruleNewline]() {
+							goto l1545
+						}
+						if !p.rules[ruleBlankLine]() {
+							goto l1545
+						}
+						goto l1542
+					l1545:
+						position, thunkPosition = position1544, thunkPosition1544
+					}
+					if !matchDot() {
+						goto l1542
+					}
+					goto l1541
+				l1542:
+					position, thunkPosition = position1542, thunkPosition1542
+				}
+				end = position
+				if !matchString("--->") {
+					goto l1532
+				}
 			}
-			do(137)
+		l1533:
+			do(175)
 			return true
-		l1305:
-			position = position0
+		l1532:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 263 RightAlign <- ('-'+ ':' &(!'-' !':') { yy = p.mkString("r");}) */
+		/* 200 CommentBlock <- (&{p.extension.Comments} NonindentSpace (('%%' < (!'%%' .)* > '%%') / ('<!---' < (!'--->' .)* > '--->')) Sp Newline BlankLine* { yy = p.mkElem(COMMENTBLOCK); yy.contents.str = yytext }) */
 		func() bool {
-			position0 := position
-			if !matchChar('-') {
-				goto l1309
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.Comments) {
+				goto l1546
 			}
-		l1310:
-			if !matchChar('-') {
-				goto l1311
+			if !p.rules[ruleNonindentSpace]() {
+				goto l1546
 			}
-			goto l1310
-		l1311:
-			if !matchChar(':') {
-				goto l1309
+			{
+				position1548, thunkPosition1548 := position, thunkPosition
+				if !matchString("%%") {
+					goto l1549
+				}
+				begin = position
+			l1550:
+				{
+					position1551, thunkPosition1551 := position, thunkPosition
+					if !matchString("%%") {
+						goto l1552
+					}
+					goto l1551
+				l1552:
+					if !matchDot() {
+						goto l1551
+					}
+					goto l1550
+				l1551:
+					position, thunkPosition = position1551, thunkPosition1551
+				}
+				end = position
+				if !matchString("%%") {
+					goto l1549
+				}
+				goto l1547
+			l1549:
+				position, thunkPosition = position1548, thunkPosition1548
+				if !matchString("<!---") {
+					goto l1546
+				}
+				begin = position
+			l1553:
+				{
+					position1554, thunkPosition1554 := position, thunkPosition
+					if !matchString("--->") {
+						goto l1555
+					}
+					goto l1554
+				l1555:
+					if !matchDot() {
+						goto l1554
+					}
+					goto l1553
+				l1554:
+					position, thunkPosition = position1554, thunkPosition1554
+				}
+				end = position
+				if !matchString("--->") {
+					goto l1546
+				}
 			}
-			if peekChar('-') {
-				goto l1309
+		l1547:
+			if !p.rules[ruleSp]() {
+				goto l1546
 			}
-			if peekChar(':') {
-				goto l1309
+			if !p.rules[ruleNewline]() {
+				goto l1546
 			}
-			do(138)
+		l1556:
+			if !p.rules[ruleBlankLine]() {
+				goto l1557
+			}
+			goto l1556
+		l1557:
+			do(176)
 			return true
-		l1309:
-			position = position0
+		l1546:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 264 CellDivider <- '|' */
+		/* 201 GridTable <- (&{p.extension.GridTables} &'+' < &{p.gridTableText(&position)} > BlankLine* { yy = p.gridTable(yytext) }) */
 		func() bool {
-			if !matchChar('|') {
-				goto l1313
+			position0, thunkPosition0 := position, thunkPosition
+			if !(p.extension.GridTables) {
+				goto l1568
+			}
+			{
+				position1569, thunkPosition1569 := position, thunkPosition
+				if !matchChar('+') {
+					goto l1568
+				}
+				position, thunkPosition = position1569, thunkPosition1569
+			}
+			begin = position
+			if !(p.gridTableText(&position)) {
+				goto l1568
+			}
+			end = position
+		l1570:
+			if !p.rules[ruleBlankLine]() {
+				goto l1571
 			}
+			goto l1570
+		l1571:
+			do(178)
 			return true
-		l1313:
+		l1568:
+			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
-		/* 265 TableCaption <- (StartList Label (Label { b = c; b.key = TABLELABEL;})? Sp Newline {
-    yy = a
-    yy.key = TABLECAPTION
-    if b != nil && b.key == TABLELABEL {
-        b.next = yy.children
-        yy.children = b
-    }
-}) */
+		/* 202 AngleSource <- ('<' < (!'>' !'<' !Newline .)* > '>' { yy = p.mkString(strings.ReplaceAll(yytext, " ", "%20")) }) */
 		func() bool {
 			position0, thunkPosition0 := position, thunkPosition
-			doarg(yyPush, 3)
-			if !p.rules[ruleStartList]() {
-				goto l1314
-			}
-			doarg(yySet, -1)
-			if !p.rules[ruleLabel]() {
-				goto l1314
+			if !matchChar('<') {
+				goto l1605
 			}
-			doarg(yySet, -2)
+			begin = position
+		l1606:
 			{
-				position1315, thunkPosition1315 := position, thunkPosition
-				if !p.rules[ruleLabel]() {
-					goto l1315
+				position1607, thunkPosition1607 := position, thunkPosition
+				if !matchChar('>') {
+					goto l1608
 				}
-				doarg(yySet, -3)
-				do(139)
-				goto l1316
-			l1315:
-				position, thunkPosition = position1315, thunkPosition1315
+				goto l1607
+			l1608:
+				if !matchChar('<') {
+					goto l1609
+				}
+				goto l1607
+			l1609:
+				if !p.rules[ruleNewline]() {
+					goto l1610
+				}
+				goto l1607
+			l1610:
+				if !matchDot() {
+					goto l1607
+				}
+				goto l1606
+			l1607:
+				position, thunkPosition = position1607, thunkPosition1607
 			}
-		l1316:
-			if !p.rules[ruleSp]() {
-				goto l1314
+			end = position
+			if !matchChar('>') {
+				goto l1605
 			}
-			if !p.rules[ruleNewline]() {
-				goto l1314
+			do(180)
+			return true
+		l1605:
+			position, thunkPosition = position0, thunkPosition0
+			return false
+		},
+		/* 203 BlankLines <- (BlankLine+ / (!. &{p.looseEnd})) */
+		func() bool {
+			position0, thunkPosition0 := position, thunkPosition
+			{
+				position1618, thunkPosition1618 := position, thunkPosition
+				if !p.rules[ruleBlankLine]() {
+					goto l1619
+				}
+			l1620:
+				if !p.rules[ruleBlankLine]() {
+					goto l1621
+				}
+				goto l1620
+			l1621:
+				goto l1617
+			l1619:
+				position, thunkPosition = position1618, thunkPosition1618
+				if !matchDot() {
+					goto l1622
+				}
+				goto l1616
+			l1622:
+				if !(p.looseEnd) {
+					goto l1616
+				}
 			}
-			do(140)
-			doarg(yyPop, 3)
+		l1617:
 			return true
-		l1314:
+		l1616:
 			position, thunkPosition = position0, thunkPosition0
 			return false
 		},
@@ -13667,6 +11257,24 @@ func (p *yyParser) mkStringFromList(list *element, extra_newline bool) (result *
 	return
 }
 
+// rawHTML returns the element for raw HTML text, which, depending
+// on the extensions, is passed through, dropped, or shown as text.
+func (p *yyParser) rawHTML(text string, key int) (el *element) {
+	switch p.extension.rawHTMLPolicy() {
+	case DropHTML:
+		el = p.mkList(LIST, nil)
+	case EscapeHTML:
+		el = p.mkString(text)
+		if key == HTMLBLOCK {
+			el = p.mkList(PARA, el)
+		}
+	default:
+		el = p.mkString(text)
+		el.key = key
+	}
+	return
+}
+
 /* p.mkList - makes new list with key 'key' and children the reverse of 'lst'.
  * This is designed to be used with cons to build lists in a parser action.
  * The reversing is necessary because cons adds to the head of a list.
@@ -13685,58 +11293,63 @@ func (p *yyParser) mkLink(label *element, url, title string) (el *element) {
 	return
 }
 
-/* match_inlines - returns true if inline lists match (case-insensitive...)
- */
-func match_inlines(l1, l2 *element) bool {
-	for l1 != nil && l2 != nil {
-		if l1.key != l2.key {
-			return false
-		}
-		switch l1.key {
-		case SPACE, LINEBREAK, ELLIPSIS, EMDASH, ENDASH, APOSTROPHE:
-			break
-		case CODE, STR, HTML:
-			if strings.ToUpper(l1.contents.str) != strings.ToUpper(l2.contents.str) {
-				return false
-			}
-		case EMPH, STRONG, LIST, SINGLEQUOTED, DOUBLEQUOTED:
-			if !match_inlines(l1.children, l2.children) {
-				return false
-			}
-		case LINK, IMAGE:
-			return false /* No links or images within links */
-		default:
-			log.Fatalf("match_inlines encountered unknown key = %d\n", l1.key)
-		}
-		l1 = l1.next
-		l2 = l2.next
-	}
-	return l1 == nil && l2 == nil /* return true if both lists exhausted */
-}
-
 /* find_reference - return true if link found in references matching label.
  * 'link' is modified with the matching url and title.
  */
-func (p *yyParser) findReference(label *element) (*link, bool) {
-	for cur := p.references; cur != nil; cur = cur.next {
-		l := cur.contents.link
-		if match_inlines(label, l.label) {
-			return l, true
-		}
+func (p *yyParser) findReference(label *element) (Reference, bool) {
+	if key, ok := p.extension.labelKey(label); ok {
+		return p.refs.Lookup(key)
 	}
-	return nil, false
+	return Reference{}, false
 }
 
 /* find_note - return true if note found in notes matching label.
  * if found, 'result' is set to point to matched note.
  */
 func (p *yyParser) find_note(label string) (*element, bool) {
-	for el := p.notes; el != nil; el = el.next {
-		if label == el.contents.str {
-			return el, true
+	el, ok := p.notes[label]
+	return el, ok
+}
+
+// indexReferences adds the references in list to the reference
+// store. Of references with equal labels, the first or the last
+// one is used, depending on the duplicate policy.
+func (p *yyParser) indexReferences(list *element) {
+	p.refs.Reset()
+	seen := make(map[string]bool)
+	for ; list != nil; list = list.next {
+		l := list.contents.link
+		if key, ok := p.extension.labelKey(l.label); ok {
+			if !seen[key] || p.extension.DuplicateRefs == LastWins {
+				p.refs.Define(key, Reference{l.url, l.title})
+			}
+			seen[key] = true
+		}
+	}
+}
+
+// indexNotes adds the footnotes in list to the map of notes. Of
+// notes with equal labels, the first one is used. The contents of
+// block quotes and list items, also found in list, are kept in
+// noteBlocks, since they may contain notes, too.
+func (p *yyParser) indexNotes(list *element) {
+	if p.notes == nil {
+		p.notes = make(map[string]*element)
+	}
+	for ; list != nil; list = list.next {
+		switch list.key {
+		case NOTE:
+			if _, dup := p.notes[list.contents.str]; !dup {
+				p.notes[list.contents.str] = list
+			}
+		case RAW:
+			p.noteBlocks = append(p.noteBlocks, list.contents.str)
+		case LISTITEM:
+			for c := list.children; c != nil; c = c.next {
+				p.noteBlocks = append(p.noteBlocks, c.contents.str)
+			}
 		}
 	}
-	return nil, false
 }
 
 /* print tree of elements, for debugging only.
@@ -13748,7 +11361,7 @@ func print_tree(w io.Writer, elt *element, indent int) {
 		for i := 0; i < indent; i++ {
 			fmt.Fprint(w, "\t")
 		}
-		key = keynames[elt.key]
+		key = keyName(elt.key)
 		if key == "" {
 			key = "?"
 		}
@@ -13765,41 +11378,65 @@ func print_tree(w io.Writer, elt *element, indent int) {
 }
 
 var keynames = [numVAL]string{
-	LIST:           "LIST",
-	RAW:            "RAW",
-	SPACE:          "SPACE",
-	LINEBREAK:      "LINEBREAK",
-	ELLIPSIS:       "ELLIPSIS",
-	EMDASH:         "EMDASH",
-	ENDASH:         "ENDASH",
-	APOSTROPHE:     "APOSTROPHE",
-	SINGLEQUOTED:   "SINGLEQUOTED",
-	DOUBLEQUOTED:   "DOUBLEQUOTED",
-	STR:            "STR",
-	LINK:           "LINK",
-	IMAGE:          "IMAGE",
-	CODE:           "CODE",
-	HTML:           "HTML",
-	EMPH:           "EMPH",
-	STRONG:         "STRONG",
-	PLAIN:          "PLAIN",
-	PARA:           "PARA",
-	LISTITEM:       "LISTITEM",
-	BULLETLIST:     "BULLETLIST",
-	ORDEREDLIST:    "ORDEREDLIST",
-	H1:             "H1",
-	H2:             "H2",
-	H3:             "H3",
-	H4:             "H4",
-	H5:             "H5",
-	H6:             "H6",
-	BLOCKQUOTE:     "BLOCKQUOTE",
-	VERBATIM:       "VERBATIM",
-	HTMLBLOCK:      "HTMLBLOCK",
-	HRULE:          "HRULE",
-	REFERENCE:      "REFERENCE",
-	NOTE:           "NOTE",
-	DEFINITIONLIST: "DEFINITIONLIST",
-	DEFTITLE:       "DEFTITLE",
-	DEFDATA:        "DEFDATA",
+	LIST:            "LIST",
+	RAW:             "RAW",
+	SPACE:           "SPACE",
+	LINEBREAK:       "LINEBREAK",
+	ELLIPSIS:        "ELLIPSIS",
+	EMDASH:          "EMDASH",
+	ENDASH:          "ENDASH",
+	APOSTROPHE:      "APOSTROPHE",
+	SINGLEQUOTED:    "SINGLEQUOTED",
+	DOUBLEQUOTED:    "DOUBLEQUOTED",
+	STR:             "STR",
+	LINK:            "LINK",
+	IMAGE:           "IMAGE",
+	CODE:            "CODE",
+	HTML:            "HTML",
+	EMPH:            "EMPH",
+	STRONG:          "STRONG",
+	PLAIN:           "PLAIN",
+	PARA:            "PARA",
+	LISTITEM:        "LISTITEM",
+	BULLETLIST:      "BULLETLIST",
+	ORDEREDLIST:     "ORDEREDLIST",
+	H1:              "H1",
+	H2:              "H2",
+	H3:              "H3",
+	H4:              "H4",
+	H5:              "H5",
+	H6:              "H6",
+	BLOCKQUOTE:      "BLOCKQUOTE",
+	VERBATIM:        "VERBATIM",
+	HTMLBLOCK:       "HTMLBLOCK",
+	HRULE:           "HRULE",
+	REFERENCE:       "REFERENCE",
+	NOTE:            "NOTE",
+	TABLE:           "TABLE",
+	TABLEHEAD:       "TABLEHEAD",
+	TABLEBODY:       "TABLEBODY",
+	TABLEROW:        "TABLEROW",
+	TABLECELL:       "TABLECELL",
+	CELLSPAN:        "CELLSPAN",
+	TABLECAPTION:    "TABLECAPTION",
+	TABLELABEL:      "TABLELABEL",
+	TABLESEPARATOR:  "TABLESEPARATOR",
+	DEFINITIONLIST:  "DEFINITIONLIST",
+	DEFTITLE:        "DEFTITLE",
+	DEFDATA:         "DEFDATA",
+	MARK:            "MARK",
+	CRITICINS:       "CRITICINS",
+	CRITICDEL:       "CRITICDEL",
+	CRITICSUB:       "CRITICSUB",
+	CRITICHIGHLIGHT: "CRITICHIGHLIGHT",
+	CRITICCOMMENT:   "CRITICCOMMENT",
+	UNDEFNOTE:       "UNDEFNOTE",
+	EXTBLOCK:        "EXTBLOCK",
+	CONTAINER:       "CONTAINER",
+	FIGURE:          "FIGURE",
+	PLACEHOLDER:     "PLACEHOLDER",
+	CITATION:        "CITATION",
+	COMMENT:         "COMMENT",
+	COMMENTBLOCK:    "COMMENTBLOCK",
+	ROWSPAN:         "ROWSPAN",
 }
@@ -0,0 +1,154 @@
+package markdown
+
+// Updating rendered pages block by block, e.g. for live previews
+
+import (
+	"strings"
+)
+
+// HTMLBlocks renders tree, like Render with ToHTMLWithOptions, but
+// returns the HTML of each top-level block separately, omitting
+// blocks that print nothing, like link reference definitions. The
+// section listing the notes, if any, is the last block. Editors
+// showing a live preview may wrap each block into an element of its
+// own, like a div, so that DiffHTMLBlocks can address it by index.
+func HTMLBlocks(tree *Node, opt *HTMLOptions) []string {
+	o := HTMLOptions{}
+	if opt != nil {
+		o = *opt
+	}
+	o.NoFinalNewline = true
+	f := &blocksOut{}
+	f.htmlOut = ToHTMLWithOptions(&f.buf, &o).(*htmlOut)
+	Render(tree, f)
+	return f.blocks
+}
+
+// blocksOut collects the output of an htmlOut block by block.
+type blocksOut struct {
+	*htmlOut
+	buf    strings.Builder
+	blocks []string
+}
+
+func (f *blocksOut) FormatBlock(tree *element) {
+	f.htmlOut.FormatBlock(tree)
+	f.finish(false)
+	f.next()
+}
+
+func (f *blocksOut) Finish() {
+	f.htmlOut.Finish()
+	f.next()
+}
+
+// next ends the current block.
+func (f *blocksOut) next() {
+	if f.buf.Len() != 0 {
+		f.blocks = append(f.blocks, f.buf.String())
+		f.buf.Reset()
+	}
+}
+
+// A PatchOp is an operation changing the blocks of a rendered page.
+type PatchOp int
+
+const (
+	PatchInsert  PatchOp = iota // insert HTML as the block at Index, before the one there, if any
+	PatchReplace                // replace the block at Index by HTML
+	PatchDelete                 // remove the block at Index
+)
+
+func (op PatchOp) String() string {
+	switch op {
+	case PatchInsert:
+		return "insert"
+	case PatchReplace:
+		return "replace"
+	}
+	return "delete"
+}
+
+// A Patch changes one block of a rendered page.
+type Patch struct {
+	Op    PatchOp
+	Index int
+	HTML  string // the new block, unless Op is PatchDelete
+}
+
+// DiffHTML returns the patches that turn the page rendered from
+// prev into the page rendered from cur, both rendered block by
+// block using HTMLBlocks with opt.
+func DiffHTML(prev, cur *Node, opt *HTMLOptions) []Patch {
+	return DiffHTMLBlocks(HTMLBlocks(prev, opt), HTMLBlocks(cur, opt))
+}
+
+// DiffHTMLBlocks returns a minimal list of patches that turn the
+// blocks prev into the blocks cur, as returned by HTMLBlocks. The
+// patches are applied in order; the Index of a patch refers to the
+// blocks as changed by the patches before it. Blocks whose HTML
+// changes are replaced, rather than removed and inserted anew, so
+// that the remaining blocks keep their elements.
+func DiffHTMLBlocks(prev, cur []string) (patches []Patch) {
+	/* the common prefix and suffix, usually all but the block being edited, are not compared pairwise */
+	pre := 0
+	for pre < len(prev) && pre < len(cur) && prev[pre] == cur[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(prev)-pre && suf < len(cur)-pre && prev[len(prev)-1-suf] == cur[len(cur)-1-suf] {
+		suf++
+	}
+	x, y := prev[pre:len(prev)-suf], cur[pre:len(cur)-suf]
+
+	/* lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:] */
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	/* runs of removed and inserted blocks between common ones are paired as replacements */
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		if i < len(x) && j < len(y) && x[i] == y[j] {
+			i++
+			j++
+			continue
+		}
+		i0, j0 := i, j
+		for i < len(x) || j < len(y) {
+			if i < len(x) && j < len(y) && x[i] == y[j] {
+				break
+			}
+			if j == len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1] {
+				i++
+			} else {
+				j++
+			}
+		}
+		del, ins := i-i0, j-j0
+		k := 0
+		for ; k < del && k < ins; k++ {
+			patches = append(patches, Patch{PatchReplace, pre + j0 + k, y[j0+k]})
+		}
+		for ; k < ins; k++ {
+			patches = append(patches, Patch{PatchInsert, pre + j0 + k, y[j0+k]})
+		}
+		for ; k < del; k++ {
+			patches = append(patches, Patch{Op: PatchDelete, Index: pre + j})
+		}
+	}
+	return patches
+}
@@ -0,0 +1,141 @@
+package markdown
+
+// Source positions
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Position locates a byte of the source text. Lines and columns
+// start at 1; columns count bytes, with tabs expanded to the next
+// multiple of four columns, as the parser sees them. The offset is
+// that of the source, though: a tab, or a NUL byte, which the parser
+// sees as several bytes, has a single offset, and a byte order mark
+// is counted.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int
+	Col    int
+
+	off int /* Offset in the text as parsed. */
+}
+
+// A Span is the range of source text a block has been parsed from.
+// End is the position of the block's last byte, trailing blank
+// lines excluded.
+type Span struct {
+	Start, End Position
+}
+
+// String formats s like "line:col-line:col".
+func (s Span) String() string {
+	return strconv.Itoa(s.Start.Line) + ":" + strconv.Itoa(s.Start.Col) + "-" +
+		strconv.Itoa(s.End.Line) + ":" + strconv.Itoa(s.End.Col)
+}
+
+// A spanFormatter wants to know the source span of each
+// top-level block before it is formatted.
+type spanFormatter interface {
+	setSpan(Span)
+}
+
+// An offsetEdit records that n bytes of the text as parsed, from
+// off on, have been made of srcN bytes of the source, from src on,
+// like the spaces a tab has been expanded to.
+type offsetEdit struct {
+	off, n    int
+	src, srcN int
+}
+
+// An offsetMap lists the edits made to a source by preformat,
+// ordered by offset. A nil map leaves offsets unchanged.
+type offsetMap []offsetEdit
+
+// source returns the offset in the source of the byte at off
+// in the text as parsed. Bytes made by an edit have the offset
+// of its first byte.
+func (m offsetMap) source(off int) int {
+	i := sort.Search(len(m), func(i int) bool { return m[i].off > off }) - 1
+	if i < 0 {
+		return off
+	}
+	e := m[i]
+	if off < e.off+e.n {
+		return e.src
+	}
+	return e.src + e.srcN + off - e.off - e.n
+}
+
+// parsed returns the offset in the text as parsed of the byte
+// at off in the source, the inverse of source.
+func (m offsetMap) parsed(off int) int {
+	i := sort.Search(len(m), func(i int) bool { return m[i].src > off }) - 1
+	if i < 0 {
+		return off
+	}
+	e := m[i]
+	if off < e.src+e.srcN {
+		return e.off
+	}
+	return e.off + e.n + off - e.src - e.srcN
+}
+
+// lineCounter converts byte offsets of the text as parsed
+// into positions. Offsets must be passed in increasing order.
+type lineCounter struct {
+	src       string
+	m         offsetMap
+	off       int
+	line      int
+	lineStart int
+}
+
+func newLineCounter(src string, m offsetMap) *lineCounter {
+	return &lineCounter{src: src, m: m, line: 1}
+}
+
+func (c *lineCounter) pos(off int) Position {
+	for ; c.off < off; c.off++ {
+		if c.src[c.off] == '\n' {
+			c.line++
+			c.lineStart = c.off + 1
+		}
+	}
+	return Position{Offset: c.m.source(off), Line: c.line, Col: off - c.lineStart + 1, off: off}
+}
+
+// posAfter returns the position of an offset not before base,
+// without advancing the counter.
+func (c *lineCounter) posAfter(base Position, off int) Position {
+	pos := base
+	for i := base.off; i < off; i++ {
+		if c.src[i] == '\n' {
+			pos.Line++
+			pos.Col = 0
+		}
+		pos.Col++
+	}
+	pos.Offset, pos.off = c.m.source(off), off
+	return pos
+}
+
+// span returns the span of the block parsed from src[start:end],
+// excluding leading and trailing blank lines.
+func (c *lineCounter) span(start, end int) (s Span, ok bool) {
+	text := c.src[start:end]
+	trimmed := strings.TrimLeft(text, " \t\r\n")
+	if trimmed == "" {
+		return s, false
+	}
+	// start at the beginning of the first non-blank line
+	i := len(text) - len(trimmed)
+	if nl := strings.LastIndexByte(text[:i], '\n'); nl != -1 {
+		start += nl + 1
+	}
+	end = start + len(strings.TrimRight(c.src[start:end], " \t\r\n"))
+	s.Start = c.pos(start)
+	s.End = c.pos(end - 1)
+	return s, true
+}
@@ -0,0 +1,138 @@
+package markdown
+
+// Lookup of link references
+
+import (
+	"strings"
+	"unicode"
+)
+
+// A Reference is the destination of a reference-style link,
+// as defined by a line like `[label]: url "title"`.
+type Reference struct {
+	URL   string
+	Title string
+}
+
+// A ReferenceStore holds the link references of a document,
+// indexed by key. For labels consisting of plain text and spaces,
+// the key is the upper-cased label text, e.g. "GO HOME" for both
+// [Go home] and [go HOME], unless Extensions.LabelMatch, or
+// Extensions.NormalizeLabel select a different mapping.
+//
+// The parser calls Reset at the start of each document, before
+// the document's references are defined.
+type ReferenceStore interface {
+	Reset()
+	Define(key string, ref Reference)
+	Lookup(key string) (ref Reference, ok bool)
+}
+
+// mapStore is the default ReferenceStore.
+type mapStore map[string]Reference
+
+func (m mapStore) Reset() {
+	for k := range m {
+		delete(m, k)
+	}
+}
+
+func (m mapStore) Define(key string, ref Reference) {
+	m[key] = ref
+}
+
+func (m mapStore) Lookup(key string) (ref Reference, ok bool) {
+	ref, ok = m[key]
+	return
+}
+
+// SetReferenceStore makes the parser keep link references in s,
+// which may, for example, provide references shared by a set of
+// documents.
+func (p *Parser) SetReferenceStore(s ReferenceStore) {
+	p.yy.state.refs = s
+}
+
+// A LabelMatch selects how reference labels are compared.
+type LabelMatch int
+
+const (
+	UpperCaseLabels LabelMatch = iota // compare upper-cased labels, like peg-markdown
+	FoldedLabels                      // compare labels using Unicode case folding, like CommonMark
+)
+
+// labelKey returns the key of a link label; two labels match
+// if their keys are equal. Labels containing links, images, or
+// notes cannot be matched, in which case ok is false.
+func (x *Extensions) labelKey(label *element) (key string, ok bool) {
+	fold := strings.ToUpper
+	if x.LabelMatch == FoldedLabels {
+		fold = foldCase
+	}
+	if norm := x.NormalizeLabel; norm != nil {
+		f := fold
+		fold = func(s string) string { return f(norm(s)) }
+	}
+	var b strings.Builder
+	if !writeLabelKey(&b, label, fold) {
+		return "", false
+	}
+	return b.String(), true
+}
+
+func writeLabelKey(b *strings.Builder, l *element, fold func(string) string) bool {
+	for ; l != nil; l = l.next {
+		switch l.key {
+		case STR:
+			b.WriteString(fold(l.contents.str))
+		case SPACE:
+			b.WriteByte(' ')
+		case LINEBREAK, ELLIPSIS, EMDASH, ENDASH, APOSTROPHE:
+			b.WriteString("\x00" + keyName(l.key) + "\x00")
+		case CODE, HTML, CRITICCOMMENT:
+			b.WriteString("\x00" + keyName(l.key) + "(")
+			b.WriteString(fold(l.contents.str))
+			b.WriteString("\x00)")
+		case LIST:
+			if !writeLabelKey(b, l.children, fold) {
+				return false
+			}
+		case EMPH, STRONG, MARK, SINGLEQUOTED, DOUBLEQUOTED,
+			CRITICINS, CRITICDEL, CRITICSUB, CRITICHIGHLIGHT:
+			b.WriteString("\x00" + keyName(l.key) + "(")
+			if !writeLabelKey(b, l.children, fold) {
+				return false
+			}
+			b.WriteString("\x00)")
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// foldCase maps each rune of s to the smallest rune of its
+// case folding orbit, so that strings equal under simple
+// Unicode case folding, see strings.EqualFold, are mapped
+// to the same string.
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		return min
+	}, s)
+}
+
+// imageAlt gives an image in reference style without alternative
+// text the label of its reference instead, like "logo" for
+// ![][logo], which is kept as the reference, too.
+func (p *yyParser) imageAlt(img *element) {
+	l := img.contents.link
+	if l.ref != "" && strings.TrimSpace(plainText(l.label)) == "" {
+		l.label = p.mkString(l.ref)
+	}
+}
@@ -0,0 +1,123 @@
+package markdown
+
+// Skipping runs of plain text, spaces, and line contents in a single
+// step, instead of a rule call per byte
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// specialChars returns the set of bytes that NormalChar doesn't
+// match: special characters, according to the extensions, white
+// space, and line endings.
+func (x *Extensions) specialChars() (set [256]bool) {
+	chars := "*_`&[]()<!#\\'\" \t\r\n"
+	if x.Smart {
+		chars += ".-'\""
+	}
+	if x.Notes {
+		chars += "^"
+	}
+	if x.Mark {
+		chars += "="
+	}
+	if x.Critic {
+		chars += "{+-~=>"
+	}
+	if x.Placeholders {
+		chars += "{"
+	}
+	if x.Comments {
+		chars += "%"
+	}
+	for i := range x.Inline {
+		chars += x.Inline[i].triggers()
+	}
+	if x.UnicodeSpaces {
+		/* first bytes of the UTF-8 encodings of Unicode spaces */
+		chars += "\xc2\xe1\xe2\xe3"
+	}
+	for i := 0; i < len(chars); i++ {
+		set[chars[i]] = true
+	}
+	return set
+}
+
+// normalChars is used as a predicate by the grammar, like LineTail;
+// it advances *pos past a run of bytes matching NormalChar, and
+// reports whether there is one.
+func (p *yyParser) normalChars(pos *int) bool {
+	s := p.Buffer
+	i := *pos
+	for i < len(s) {
+		if p.state.special[s[i]] {
+			if s[i] < utf8.RuneSelf || unicodeSpace(s[i:]) > 0 {
+				break
+			}
+		}
+		i++
+	}
+	if i == *pos {
+		return false
+	}
+	*pos = i
+	return true
+}
+
+// skipSpace is used as a predicate by the grammar; it advances *pos
+// past spaces and tabs, and Unicode spaces, if enabled.
+func (p *yyParser) skipSpace(pos *int) bool {
+	s := p.Buffer
+	i := *pos
+	for i < len(s) {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+		} else if n := p.unicodeSpace(s[i:]); n > 0 {
+			i += n
+		} else {
+			break
+		}
+	}
+	*pos = i
+	return true
+}
+
+// unicodeSpaceChar is used as a predicate by the grammar; it
+// advances *pos past a Unicode space other than a space, if
+// Extensions.UnicodeSpaces is set.
+func (p *yyParser) unicodeSpaceChar(pos *int) bool {
+	n := p.unicodeSpace(p.Buffer[*pos:])
+	*pos += n
+	return n > 0
+}
+
+func (p *yyParser) unicodeSpace(s string) int {
+	if !p.extension.UnicodeSpaces || s == "" || s[0] < utf8.RuneSelf {
+		return 0
+	}
+	return unicodeSpace(s)
+}
+
+// unicodeSpace returns the length of the space separator, like
+// U+00A0 or U+3000, starting s, or 0.
+func unicodeSpace(s string) int {
+	r, n := utf8.DecodeRuneInString(s)
+	if r == ' ' || !unicode.Is(unicode.Zs, r) {
+		return 0
+	}
+	return n
+}
+
+// skipLine is used as a predicate by the grammar; it advances *pos
+// to the end of its line, i.e. to the next '\r' or '\n', or the end
+// of the text.
+func (p *yyParser) skipLine(pos *int) bool {
+	if n := strings.IndexAny(p.Buffer[*pos:], "\r\n"); n != -1 {
+		*pos += n
+	} else {
+		*pos = len(p.Buffer)
+	}
+	return true
+}
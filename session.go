@@ -0,0 +1,136 @@
+package markdown
+
+// Parsing sets of documents sharing references and notes
+
+import (
+	"io"
+)
+
+// A Session parses the fragments of a larger document, like the
+// chapters of a book, or the pages of a wiki that include a common
+// file of link definitions. Link references and, if Extensions.Notes
+// is set, footnotes defined in one fragment may be referred to from
+// any other fragment. Definitions within a fragment take precedence
+// over those of other fragments; of the latter, the one of the
+// first fragment is used, unless Extensions.DuplicateRefs is
+// LastWins.
+type Session struct {
+	p     *Parser
+	srcs  []string
+	diags [][]Diagnostic /* Problems found while expanding include directives, by fragment. */
+	maps  []sourceMap    /* Files the fragments have been read from, if any have been included. */
+	offs  []offsetMap    /* Edits made to the fragments while preformatting them. */
+	refs  mapStore       /* References of all fragments; nil if outdated. */
+	cur   int            /* Fragment being parsed. */
+}
+
+// NewSession returns a session parsing fragments using p,
+// which must not be used otherwise while the session is active.
+func (p *Parser) NewSession() *Session {
+	return &Session{p: p}
+}
+
+// Add reads a fragment, and returns its index.
+func (s *Session) Add(src io.Reader) int {
+	s.srcs = append(s.srcs, s.p.source(src))
+	s.diags = append(s.diags, append([]Diagnostic(nil), s.p.includeDiags...))
+	s.maps = append(s.maps, s.p.srcMap)
+	s.offs = append(s.offs, s.p.offsets)
+	s.refs = nil
+	return len(s.srcs) - 1
+}
+
+// Len returns the number of fragments.
+func (s *Session) Len() int {
+	return len(s.srcs)
+}
+
+// Markdown parses the fragment with index i, like Parser.Markdown.
+// Notes defined in other fragments are numbered and printed by the
+// formatter like those of the fragment; see HTMLOptions.Notes for
+// collecting the notes of all fragments at a single place.
+func (s *Session) Markdown(i int, f Formatter) {
+	p := s.p
+	if s.refs == nil {
+		s.indexReferences()
+	}
+	saved := p.yy.state.refs
+	p.yy.state.refs = &sessionRefs{local: make(mapStore), shared: s.refs}
+	p.session = s
+	s.cur = i
+	p.includeDiags = s.diags[i]
+	p.srcMap = s.maps[i]
+	p.offsets = s.offs[i]
+	defer func() {
+		p.yy.state.refs = saved
+		p.session = nil
+		p.includeDiags = nil
+		p.srcMap = nil
+		p.offsets = nil
+	}()
+	p.markdown(s.srcs[i], f)
+}
+
+// indexReferences collects the references of all fragments.
+func (s *Session) indexReferences() {
+	p := s.p
+	saved := p.yy.state.refs
+	pos := p.yy.state.heap.Pos()
+	s.refs = make(mapStore)
+	for _, src := range s.srcs {
+		local := make(mapStore)
+		p.yy.state.refs = local
+		p.parseRule(ruleReferences, src)
+		for key, ref := range local {
+			if _, dup := s.refs[key]; !dup || p.yy.extension.DuplicateRefs == LastWins {
+				s.refs[key] = ref
+			}
+		}
+	}
+	p.yy.state.heap.setPos(pos)
+	p.yy.state.refs = saved
+}
+
+// addNotes adds the notes of the other fragments to those
+// of the fragment being parsed.
+func (s *Session) addNotes() {
+	p := s.p
+	notes := p.yy.state.notes
+	if notes == nil {
+		notes = make(map[string]*element)
+	}
+	for i, src := range s.srcs {
+		if i == s.cur {
+			continue
+		}
+		p.parseNotes(src)
+		for label, note := range p.yy.state.notes {
+			if _, dup := notes[label]; !dup {
+				notes[label] = note
+			}
+		}
+	}
+	p.yy.state.notes = notes
+}
+
+// sessionRefs looks up references of the current
+// fragment first, then those of all fragments.
+type sessionRefs struct {
+	local  mapStore
+	shared mapStore
+}
+
+func (r *sessionRefs) Reset() {
+	r.local.Reset()
+}
+
+func (r *sessionRefs) Define(key string, ref Reference) {
+	r.local.Define(key, ref)
+}
+
+func (r *sessionRefs) Lookup(key string) (ref Reference, ok bool) {
+	if ref, ok = r.local.Lookup(key); ok {
+		return
+	}
+	return r.shared.Lookup(key)
+}
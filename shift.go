@@ -0,0 +1,62 @@
+package markdown
+
+// Shifting heading levels, for embedding documents
+
+// ShiftHeadings returns a formatter that passes blocks to f, with
+// the levels of all headings increased by n, so that a document can
+// be embedded as a section of a larger page; with n = 1, for
+// instance, "# Title" is formatted as <h2>. Levels are clamped to
+// the range 1 to 6. A negative n lowers the levels.
+func ShiftHeadings(f Formatter, n int) Formatter {
+	return &shiftFormatter{f: f, n: n}
+}
+
+type shiftFormatter struct {
+	f     Formatter
+	n     int
+	notes map[*element]bool /* Note bodies already shifted. */
+}
+
+func (s *shiftFormatter) FormatBlock(tree *element) {
+	s.shift(tree)
+	s.f.FormatBlock(tree)
+}
+
+func (s *shiftFormatter) Finish() {
+	s.f.Finish()
+	s.notes = nil
+}
+
+func (s *shiftFormatter) setSpan(span Span) {
+	if sf, ok := s.f.(spanFormatter); ok {
+		sf.setSpan(span)
+	}
+}
+
+// shift changes the levels of the headings in a list of elements.
+func (s *shiftFormatter) shift(list *element) {
+	for elt := list; elt != nil; elt = elt.next {
+		switch elt.key {
+		case H1, H2, H3, H4, H5, H6:
+			level := elt.key - H1 + 1 + s.n
+			if level < 1 {
+				level = 1
+			} else if level > 6 {
+				level = 6
+			}
+			elt.key = H1 + level - 1
+		case NOTE:
+			/* note bodies may be referenced repeatedly */
+			if body := elt.children; body != nil {
+				if s.notes[body] {
+					continue
+				}
+				if s.notes == nil {
+					s.notes = make(map[*element]bool)
+				}
+				s.notes[body] = true
+			}
+		}
+		s.shift(elt.children)
+	}
+}
@@ -0,0 +1,112 @@
+package markdown
+
+// Splitting documents into sections, e.g. for EPUB chapters
+
+import (
+	"io"
+	"strings"
+)
+
+// A Section is a part of a document, as returned by Parser.Split.
+type Section struct {
+	Title string // text of the heading starting the section, if any
+
+	// Markdown is the source text of the section, followed by the
+	// link reference definitions of the other sections, so that it
+	// can be converted on its own.
+	Markdown string
+
+	// HTML is the section converted to HTML. It ends with the
+	// notes referenced within the section.
+	HTML string
+}
+
+// Split parses a document, like Markdown, and splits it into
+// sections, each starting at a heading of the given level, or
+// of a higher one, like a level 1 heading, if level is 2. Text
+// preceding the first such heading forms a section without a
+// title. Each section is converted using opt, which may be nil.
+// References to link definitions in other sections are resolved.
+func (p *Parser) Split(src io.Reader, level int, opt *HTMLOptions) []Section {
+	s := p.source(src)
+	f := &splitOut{src: s, level: level, opt: opt}
+	p.markdown(s, f)
+	return f.sections
+}
+
+type splitOut struct {
+	src   string
+	level int
+	opt   *HTMLOptions
+
+	sections []Section
+	refs     []splitRef
+	span     Span
+
+	md   strings.Builder /* Source text of the current section. */
+	html strings.Builder
+	f    *htmlOut /* Formatter of the current section, if any. */
+}
+
+func (s *splitOut) setSpan(span Span) {
+	s.span = span
+}
+
+func (s *splitOut) FormatBlock(tree *element) {
+	if tree.key >= H1 && tree.key < H1+s.level {
+		s.endSection()
+	}
+	if s.f == nil {
+		s.sections = append(s.sections, Section{})
+		if tree.key >= H1 && tree.key <= H6 {
+			s.sections[len(s.sections)-1].Title = plainText(tree.children)
+		}
+		s.f = ToHTMLWithOptions(&s.html, s.opt).(*htmlOut)
+	}
+	text := s.src[s.span.Start.off : s.span.End.off+1]
+	if tree.key == REFERENCE {
+		s.refs = append(s.refs, splitRef{text, len(s.sections) - 1})
+	}
+	if s.md.Len() > 0 {
+		s.md.WriteString("\n\n")
+	}
+	s.md.WriteString(text)
+	s.f.setSpan(s.span)
+	s.f.FormatBlock(tree)
+}
+
+// endSection finishes the current section, if there is one.
+func (s *splitOut) endSection() {
+	if s.f == nil {
+		return
+	}
+	s.f.Finish()
+	sec := &s.sections[len(s.sections)-1]
+	sec.Markdown = s.md.String() + "\n"
+	sec.HTML = s.html.String()
+	s.md.Reset()
+	s.html.Reset()
+	s.f = nil
+}
+
+// A splitRef is a link reference definition.
+type splitRef struct {
+	text    string
+	section int /* Index of the section containing it. */
+}
+
+func (s *splitOut) Finish() {
+	s.endSection()
+	for i := range s.sections {
+		var b strings.Builder
+		for _, ref := range s.refs {
+			if ref.section != i {
+				b.WriteString("\n" + ref.text)
+			}
+		}
+		if b.Len() > 0 {
+			s.sections[i].Markdown += b.String() + "\n"
+		}
+	}
+	s.refs = nil
+}
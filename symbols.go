@@ -0,0 +1,227 @@
+package markdown
+
+// Document symbols and folding ranges, for editors
+
+import (
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A SymbolKind classifies document symbols.
+type SymbolKind int
+
+const (
+	HeadingSymbol   SymbolKind = iota
+	ReferenceSymbol            // link reference definition
+	NoteSymbol                 // footnote definition
+)
+
+// A Symbol is a named part of a document, as listed in the
+// outline view of an editor.
+type Symbol struct {
+	Kind   SymbolKind
+	Name   string // heading text, or label
+	Level  int    // level of a heading
+	Anchor string // identifier of a heading, see HTMLOptions.HeadingIDs
+	Span   Span
+}
+
+// A FoldingKind classifies folding ranges.
+type FoldingKind int
+
+const (
+	SectionFold FoldingKind = iota // a heading and the blocks up to the next heading of the same or a higher level
+	ListFold
+	CodeFold
+)
+
+// A FoldingRange is a part of a document, spanning
+// several lines, that an editor may collapse.
+type FoldingRange struct {
+	Kind FoldingKind
+	Span Span
+}
+
+// DocumentSymbols receives the symbols and folding ranges
+// of a document, ordered by their start positions. Only
+// top-level blocks are taken into account.
+type DocumentSymbols struct {
+	Symbols []Symbol
+	Folds   []FoldingRange
+}
+
+type symbolsOut struct {
+	d        *DocumentSymbols
+	span     Span
+	lastEnd  Position
+	sections []Symbol /* Headings of the open sections. */
+	anchors  anchors
+}
+
+// ToSymbols returns a formatter that collects the symbols and
+// folding ranges of a document into d.
+func ToSymbols(d *DocumentSymbols) Formatter {
+	return &symbolsOut{d: d}
+}
+
+func (f *symbolsOut) setSpan(s Span) {
+	f.span = s
+}
+
+func (f *symbolsOut) FormatBlock(tree *element) {
+	for ; tree != nil; tree = tree.next {
+		f.block(tree)
+	}
+	f.lastEnd = f.span.End
+}
+
+func (f *symbolsOut) block(elt *element) {
+	switch elt.key {
+	case H1, H2, H3, H4, H5, H6:
+		level := elt.key - H1 + 1
+		f.closeSections(level)
+		sym := Symbol{Kind: HeadingSymbol, Name: plainText(elt.children), Level: level, Span: f.span}
+		if f.anchors == nil {
+			f.anchors = make(anchors)
+		}
+		sym.Anchor = f.anchors.add(sym.Name)
+		f.d.Symbols = append(f.d.Symbols, sym)
+		f.sections = append(f.sections, sym)
+	case REFERENCE:
+		f.d.Symbols = append(f.d.Symbols, Symbol{Kind: ReferenceSymbol, Name: plainText(elt.contents.link.label), Span: f.span})
+	case NOTE:
+		f.d.Symbols = append(f.d.Symbols, Symbol{Kind: NoteSymbol, Name: elt.contents.str, Span: f.span})
+	case BULLETLIST, ORDEREDLIST, DEFINITIONLIST:
+		f.fold(ListFold, f.span)
+	case VERBATIM:
+		f.fold(CodeFold, f.span)
+	}
+}
+
+// closeSections ends the sections of the given level or below.
+func (f *symbolsOut) closeSections(level int) {
+	for i := len(f.sections) - 1; i >= 0 && f.sections[i].Level >= level; i-- {
+		f.fold(SectionFold, Span{f.sections[i].Span.Start, f.lastEnd})
+		f.sections = f.sections[:i]
+	}
+}
+
+func (f *symbolsOut) fold(kind FoldingKind, s Span) {
+	if s.End.Line > s.Start.Line {
+		f.d.Folds = append(f.d.Folds, FoldingRange{kind, s})
+	}
+}
+
+func (f *symbolsOut) Finish() {
+	f.closeSections(1)
+	sort.SliceStable(f.d.Folds, func(i, j int) bool {
+		return f.d.Folds[i].Span.Start.Offset < f.d.Folds[j].Span.Start.Offset
+	})
+	f.lastEnd = Position{}
+	f.anchors = nil
+}
+
+// Definitions returns the symbols of the given kind, for completion
+// in editors: the headings, whose anchors may be referred to like
+// "#anchor", or the definitions of reference or note labels. Of
+// labels defined more than once, the first definition is returned.
+func (d *DocumentSymbols) Definitions(kind SymbolKind) (list []Symbol) {
+	seen := make(map[string]bool)
+	for _, sym := range d.Symbols {
+		if sym.Kind != kind {
+			continue
+		}
+		key := sym.Name
+		switch kind {
+		case HeadingSymbol:
+			key = sym.Anchor
+		case ReferenceSymbol:
+			key = strings.ToUpper(strings.Join(strings.Fields(key), " "))
+		}
+		if !seen[key] {
+			seen[key] = true
+			list = append(list, sym)
+		}
+	}
+	return list
+}
+
+// anchors generates identifiers for headings.
+type anchors map[string]int
+
+// add returns the identifier of a heading with the given text,
+// like GitHub: the text in lower case, with spaces turned into
+// hyphens, and punctuation other than hyphens and underscores
+// removed. Repeated identifiers get a numeric suffix.
+func (a anchors) add(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteByte('-')
+		}
+	}
+	id := b.String()
+	if id == "" {
+		id = "section"
+	}
+	n := a[id]
+	a[id]++
+	if n > 0 {
+		id += "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// plainText returns the text of a list of inline
+// elements, without markup.
+func plainText(list *element) string {
+	var b strings.Builder
+	writePlainText(&b, list)
+	return b.String()
+}
+
+func writePlainText(b *strings.Builder, list *element) {
+	for ; list != nil; list = list.next {
+		switch list.key {
+		case STR, CODE:
+			b.WriteString(list.contents.str)
+		case SPACE, LINEBREAK:
+			b.WriteByte(' ')
+		case ELLIPSIS:
+			b.WriteString("…")
+		case EMDASH:
+			b.WriteString("—")
+		case ENDASH:
+			b.WriteString("–")
+		case APOSTROPHE:
+			b.WriteString("’")
+		case SINGLEQUOTED:
+			b.WriteString("‘")
+			writePlainText(b, list.children)
+			b.WriteString("’")
+		case DOUBLEQUOTED:
+			b.WriteString("“")
+			writePlainText(b, list.children)
+			b.WriteString("”")
+		case HTML:
+			if s := list.contents.str; strings.HasPrefix(s, "&") {
+				b.WriteString(html.UnescapeString(s))
+			}
+		case LINK, IMAGE:
+			writePlainText(b, list.contents.link.label)
+		case PLACEHOLDER:
+			b.WriteString("{{" + list.contents.str + "}}")
+		case CITATION:
+			b.WriteString("[" + list.contents.str + "]")
+		case NOTE, CRITICCOMMENT, COMMENT:
+		default:
+			writePlainText(b, list.children)
+		}
+	}
+}
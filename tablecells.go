@@ -0,0 +1,114 @@
+package markdown
+
+// Table cells containing blocks
+
+import (
+	"strings"
+)
+
+// cellText is used as a predicate by the grammar, with
+// Extensions.TableBlocks, at the start of the contents of a cell of
+// a pipe table; it advances *pos to the end of the contents, before
+// trailing spaces, and reports whether there are any. As with
+// inline contents, a '|' within a code span, or escaped by a
+// backslash, doesn't end the cell.
+func (p *yyParser) cellText(pos *int) bool {
+	s := p.Buffer[*pos:]
+	i := 0
+scan:
+	for i < len(s) {
+		switch s[i] {
+		case '\n', '\r', '|':
+			break scan
+		case '\\':
+			i++
+			if i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case '`':
+			n := 1
+			for i+n < len(s) && s[i+n] == '`' {
+				n++
+			}
+			i += n
+			if j := closingTicks(s[i:], n); j != -1 {
+				i += j + n
+			}
+		default:
+			i++
+		}
+	}
+	end := len(strings.TrimRight(s[:i], " \t"))
+	if end == 0 {
+		return false
+	}
+	*pos += end
+	return true
+}
+
+// closingTicks returns the offset in the rest of the line s of a
+// run of n backticks, closing a code span, or -1.
+func closingTicks(s string, n int) int {
+	for i := 0; i < len(s) && s[i] != '\n'; {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && s[j] == '`' {
+			j++
+		}
+		if j-i == n {
+			return i
+		}
+		i = j
+	}
+	return -1
+}
+
+// rawCell returns the RAW element holding the text of a table
+// cell parsed with Extensions.TableBlocks, or nil.
+func rawCell(cell *element) *element {
+	c := cell.children
+	if c != nil && c.key == CELLSPAN {
+		c = c.next
+	}
+	if c != nil && c.key == RAW {
+		return c
+	}
+	return nil
+}
+
+// endRawCells terminates the text of the RAW cells of a part of a
+// table, once rows have been joined, so that it is parsed into
+// blocks. Cells of several paragraphs end with a paragraph, rather
+// than plain text.
+func endRawCells(part *element) {
+	for row := part.children; row != nil; row = row.next {
+		for cell := row.children; cell != nil; cell = cell.next {
+			if raw := rawCell(cell); raw != nil {
+				raw.contents.str += "\n"
+				raw.loose = strings.Contains(raw.contents.str, "\n\n")
+			}
+		}
+	}
+}
+
+// cellBlocks returns the blocks contained in a table cell, like one
+// of a grid table, or nil, if it contains inlines.
+func cellBlocks(cell *element) *element {
+	c := cell.children
+	for c != nil && (c.key == CELLSPAN || c.key == ROWSPAN) {
+		c = c.next
+	}
+	if c == nil || c.key != LIST || c.next != nil || c.children == nil {
+		return nil
+	}
+	switch c.children.key {
+	case PARA, PLAIN, H1, H2, H3, H4, H5, H6, BLOCKQUOTE, VERBATIM, HTMLBLOCK, HRULE,
+		BULLETLIST, ORDEREDLIST, DEFINITIONLIST, TABLE, EXTBLOCK, CONTAINER, FIGURE,
+		REFERENCE, COMMENTBLOCK:
+		return c.children
+	}
+	return nil
+}
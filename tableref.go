@@ -0,0 +1,58 @@
+package markdown
+
+// Cross-references to tables
+
+import (
+	"strconv"
+)
+
+// tableNumbers numbers the captioned top-level tables of the
+// preformatted text s in advance, since a cross-reference may
+// precede the table it refers to. If several tables have the
+// same label, references refer to the first one.
+func (p *Parser) tableNumbers(s string) map[string]int {
+	nums := make(map[string]int)
+	n := 0
+	p.prescan(s, func(tree *element) {
+		if tree.key != TABLE {
+			return
+		}
+		if c := tableCaption(tree); c != nil {
+			n++
+			if label := captionLabel(c); label != "" && nums[label] == 0 {
+				nums[label] = n
+			}
+		}
+	})
+	return nums
+}
+
+// tableCaption returns the caption of a table, or nil.
+func tableCaption(table *element) *element {
+	for c := table.children; c != nil; c = c.next {
+		if c.key == TABLECAPTION {
+			return c
+		}
+	}
+	return nil
+}
+
+// captionLabel returns the label of a table caption, which is
+// taken from the caption text, if no label is given explicitly.
+func captionLabel(caption *element) string {
+	if caption.children != nil && caption.children.key == TABLELABEL {
+		return labelFromElementList(caption.children.children)
+	}
+	return labelFromElementList(caption.children)
+}
+
+// tableRef returns a link to the table with the given label, named
+// after the number of the table, or the text of the reference, if
+// there is no such table.
+func (p *yyParser) tableRef(label string) *element {
+	n := p.state.tables[labelFromString(label)]
+	if n == 0 {
+		return p.mkString("[#" + label + "]")
+	}
+	return p.mkLink(p.mkString("Table "+strconv.Itoa(n)), "#"+labelFromString(label), "")
+}
@@ -0,0 +1,379 @@
+package markdown
+
+// Normalizing the rows of tables
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TableRows selects how table rows are handled whose cells span
+// fewer or more columns than the separator line has.
+type TableRows int
+
+const (
+	KeepRows    TableRows = iota // keep rows as they are
+	LenientRows                  // pad short rows with empty cells, append extra cells to the last one
+	StrictRows                   // like LenientRows, and report such rows as diagnostics
+)
+
+// A tableProblem describes a row of a table that does not span
+// the columns of its separator line.
+type tableProblem struct {
+	line int // index of the row among the non-blank lines of the table
+	msg  string
+}
+
+// normalizeTable joins continued rows of a table, if
+// Extensions.TableContinuation is set, and makes each row span the
+// columns of its separator line, according to Extensions.TableRows.
+// Header rows spanning a different number of columns than the
+// separator line, and rows spanning more columns, are reported;
+// with StrictRows, all rows that don't fit are. leadingCaption
+// tells whether the caption of the table precedes its rows.
+func (p *yyParser) normalizeTable(table *element, leadingCaption bool) {
+	lines := tableLines(table, leadingCaption)
+	if p.extension.TableContinuation {
+		for part := table.children; part != nil; part = part.next {
+			if part.key == TABLEHEAD || part.key == TABLEBODY {
+				p.joinRows(part)
+			}
+		}
+	}
+	cols := 0
+	for part := table.children; part != nil; part = part.next {
+		if part.key == TABLESEPARATOR {
+			cols = len(part.contents.str)
+		}
+	}
+	if cols == 0 {
+		return
+	}
+	n := 0
+	for part := table.children; part != nil; part = part.next {
+		if part.key != TABLEHEAD && part.key != TABLEBODY {
+			continue
+		}
+		for row := part.children; row != nil; row = row.next {
+			n++
+			had := rowSpan(row)
+			if had != cols && p.extension.TableRows != KeepRows {
+				p.normalizeRow(row, cols)
+			}
+			msg := ""
+			switch {
+			case had == cols:
+			case p.extension.TableRows == StrictRows:
+				msg = "table row " + strconv.Itoa(n) + ": " + strconv.Itoa(had) + " columns, expected " + strconv.Itoa(cols)
+			case part.key == TABLEHEAD:
+				msg = "table header row " + strconv.Itoa(n) + ": " + strconv.Itoa(had) + " columns, separator line has " + strconv.Itoa(cols)
+			case had > cols:
+				msg = "table row " + strconv.Itoa(n) + ": " + strconv.Itoa(had) + " columns, separator line has " + strconv.Itoa(cols)
+			}
+			if msg != "" {
+				p.state.tableRows = append(p.state.tableRows, tableProblem{lines[row], msg})
+			}
+		}
+		if p.extension.TableRowspan {
+			p.spanRows(part)
+		}
+		if p.extension.TableBlocks {
+			endRawCells(part)
+		}
+	}
+}
+
+// tableLines returns the index of each row of a table among its
+// non-blank lines, before continued rows are joined.
+func tableLines(table *element, leadingCaption bool) map[*element]int {
+	lines := make(map[*element]int)
+	i := 0
+	if leadingCaption {
+		i++
+	}
+	/* the separator line, preceding the head in the tree, follows it in the source */
+	for _, key := range []int{TABLEHEAD, TABLESEPARATOR, TABLEBODY} {
+		for part := table.children; part != nil; part = part.next {
+			switch {
+			case part.key != key:
+			case key == TABLESEPARATOR:
+				i++
+			default:
+				for row := part.children; row != nil; row = row.next {
+					lines[row] = i
+					i++
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// rowSpan returns the number of columns a table row spans.
+func rowSpan(row *element) (n int) {
+	for cell := row.children; cell != nil; cell = cell.next {
+		n += cellSpan(cell)
+	}
+	return n
+}
+
+// normalizeRow adjusts a table row to the given number of columns.
+// The column span of a cell reaching beyond the last column is
+// reduced.
+func (p *yyParser) normalizeRow(row *element, cols int) {
+	var last *element /* Last cell within the columns. */
+	col := 0
+	for cell := row.children; cell != nil; cell = cell.next {
+		n := cellSpan(cell)
+		if col+n >= cols {
+			if col+n > cols {
+				setCellSpan(cell, cols-col)
+			}
+			p.foldCells(cell)
+			return
+		}
+		col += n
+		last = cell
+	}
+	for ; col < cols; col++ {
+		cell := p.mkElem(TABLECELL)
+		if last == nil {
+			row.children = cell
+		} else {
+			last.next = cell
+		}
+		last = cell
+	}
+}
+
+// spanRows makes each cell of a part of a table containing only
+// "^^" extend the cell above it, spanning the same columns, by a
+// row. The cell above gets a ROWSPAN child holding the number of
+// rows it spans; in the cells it covers, the ROWSPAN holds "0",
+// and replaces their contents.
+func (p *yyParser) spanRows(part *element) {
+	var above map[int]*element /* Cells of the previous row by column, the spanning ones for those covered. */
+	for row := part.children; row != nil; row = row.next {
+		cur := make(map[int]*element)
+		col := 0
+		for cell := row.children; cell != nil; cell = cell.next {
+			cur[col] = cell
+			if top := above[col]; top != nil && cellSpan(top) == cellSpan(cell) && rowMark(cell) {
+				p.setRowSpan(top, cellRows(top)+1)
+				p.setRowSpan(cell, 0)
+				cur[col] = top
+			}
+			col += cellSpan(cell)
+		}
+		above = cur
+	}
+}
+
+// rowMark reports whether a table cell contains only "^^".
+func rowMark(cell *element) bool {
+	s := ""
+	for c := cell.children; c != nil; c = c.next {
+		switch c.key {
+		case CELLSPAN:
+		case STR, RAW:
+			s += c.contents.str
+		default:
+			return false
+		}
+	}
+	return s == "^^"
+}
+
+// cellRows returns the number of rows a table cell spans, which
+// is 0 for a cell covered by one above.
+func cellRows(cell *element) int {
+	c := cell.children
+	if c != nil && c.key == CELLSPAN {
+		c = c.next
+	}
+	if c == nil || c.key != ROWSPAN {
+		return 1
+	}
+	n, _ := strconv.Atoi(c.contents.str)
+	return n
+}
+
+// setRowSpan makes a table cell span n rows, following its
+// CELLSPAN, if any. A cell spanning 0 rows loses its contents.
+func (p *yyParser) setRowSpan(cell *element, n int) {
+	var prev *element
+	c := cell.children
+	if c != nil && c.key == CELLSPAN {
+		prev, c = c, c.next
+	}
+	if c == nil || c.key != ROWSPAN {
+		span := p.mkElem(ROWSPAN)
+		span.next = c
+		if prev == nil {
+			cell.children = span
+		} else {
+			prev.next = span
+		}
+		c = span
+	}
+	c.contents.str = strconv.Itoa(n)
+	if n == 0 {
+		c.next = nil
+	}
+}
+
+// foldCells appends the contents of the cells following cell to
+// it, separated by " | ", as they would appear in the source.
+// Empty cells are dropped.
+func (p *yyParser) foldCells(cell *element) {
+	tail := cell.children
+	for tail != nil && tail.next != nil {
+		tail = tail.next
+	}
+	for extra := cell.next; extra != nil; extra = extra.next {
+		content := extra.children
+		if content != nil && content.key == CELLSPAN {
+			content = content.next
+		}
+		if content == nil {
+			continue
+		}
+		if tail != nil && tail.key == RAW && content.key == RAW {
+			tail.contents.str += " | " + content.contents.str
+			continue
+		}
+		if tail != nil && tail.key != CELLSPAN {
+			sep := p.mkString(" | ")
+			tail.next = sep
+			tail = sep
+		}
+		if tail == nil {
+			cell.children = content
+		} else {
+			tail.next = content
+		}
+		for tail = content; tail.next != nil; tail = tail.next {
+		}
+	}
+	cell.next = nil
+}
+
+// cellSpan returns the number of columns a table cell spans.
+func cellSpan(cell *element) int {
+	if c := cell.children; c != nil && c.key == CELLSPAN {
+		return len(c.contents.str) + 1
+	}
+	return 1
+}
+
+// setCellSpan makes a table cell span n columns.
+func setCellSpan(cell *element, n int) {
+	if cell.children == nil || cell.children.key != CELLSPAN {
+		return
+	}
+	if n <= 1 {
+		cell.children = cell.children.next
+		return
+	}
+	cell.children.contents.str = strings.Repeat("|", n-1)
+}
+
+// joinRows joins each row of a table head or body that has a cell
+// ending in " \" with the following row: the cells of the latter are
+// appended to the cells of the same columns, after a line break.
+func (p *yyParser) joinRows(part *element) {
+	for row := part.children; row != nil; row = row.next {
+		for row.next != nil && continued(row) {
+			next := row.next
+			row.next = next.next
+			p.joinRow(row, next)
+		}
+	}
+}
+
+// continued reports whether a cell of a table row ends in " \",
+// and removes these marks.
+func continued(row *element) (cont bool) {
+	for cell := row.children; cell != nil; cell = cell.next {
+		if raw := rawCell(cell); raw != nil {
+			/* the text may have been joined already */
+			s := raw.contents.str
+			if line := s[strings.LastIndexByte(s, '\n')+1:]; line == "\\" || strings.HasSuffix(line, " \\") {
+				cont = true
+				raw.contents.str = strings.TrimRight(s[:len(s)-1], " ")
+			}
+			continue
+		}
+		var prev, last *element
+		for c := cell.children; c != nil; c = c.next {
+			if c.next != nil && c.next.next == nil {
+				prev = c
+			}
+			last = c
+		}
+		if prev != nil && prev.key == SPACE && last.key == STR && last.contents.str == "\\" {
+			cont = true
+			if cell.children == prev {
+				cell.children = nil
+			} else {
+				for c := cell.children; c != nil; c = c.next {
+					if c.next == prev {
+						c.next = nil
+					}
+				}
+			}
+		}
+	}
+	return cont
+}
+
+// joinRow appends the contents of the cells of next to those of
+// the same columns of row.
+func (p *yyParser) joinRow(row, next *element) {
+	var last *element /* Last cell of row. */
+	cell := row.children
+	for c := next.children; c != nil; {
+		nc := c.next
+		if cell == nil {
+			/* next has more cells */
+			c.next = nil
+			if last == nil {
+				row.children = c
+			} else {
+				last.next = c
+			}
+			last = c
+			c = nc
+			continue
+		}
+		content := c.children
+		if content != nil && content.key == CELLSPAN {
+			content = content.next
+		}
+		if raw := rawCell(cell); raw != nil {
+			/* an empty line separates paragraphs */
+			raw.contents.str += "\n"
+			if content != nil {
+				raw.contents.str += content.contents.str
+			}
+		} else if content != nil {
+			tail := cell.children
+			for tail != nil && tail.next != nil {
+				tail = tail.next
+			}
+			if tail == nil {
+				cell.children = content
+			} else {
+				if tail.key != CELLSPAN {
+					br := p.mkElem(LINEBREAK)
+					tail.next = br
+					tail = br
+				}
+				tail.next = content
+			}
+		}
+		last = cell
+		cell = cell.next
+		c = nc
+	}
+}
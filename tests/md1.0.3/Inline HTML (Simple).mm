@@ -37,4 +37,3 @@ Hr's:
 .P
  
 .P
-
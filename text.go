@@ -0,0 +1,278 @@
+package markdown
+
+// Plain text output, e.g. for the text part of email messages
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type textOut struct {
+	baseWriter
+	started bool
+
+	notes    []*element       /* Bodies of the notes referenced so far. */
+	noteNums map[*element]int /* Numbers of the notes, by body. */
+}
+
+// ToText returns a formatter that writes the document as plain text,
+// like the text alternative of an HTML email: markup is removed,
+// headings are underlined, list items and block quotes are marked
+// like in Markdown, link URLs are shown in parentheses after the
+// link text, and footnotes are listed at the end. Raw HTML is
+// omitted.
+func ToText(w io.Writer) Formatter {
+	f := new(textOut)
+	f.baseWriter = newBaseWriter(w, "")
+	return f
+}
+
+func (f *textOut) FormatBlock(tree *element) {
+	f.write(f.blocks(tree))
+}
+
+func (f *textOut) Finish() {
+	if len(f.notes) != 0 {
+		var b strings.Builder
+		b.WriteString("----")
+		/* notes referenced from within notes are appended while printing */
+		for i := 0; i < len(f.notes); i++ {
+			b.WriteString("\n\n")
+			b.WriteString(indent(f.blocks(f.notes[i]), "["+strconv.Itoa(i+1)+"] "))
+		}
+		f.write(b.String())
+	}
+	f.notes = nil
+	f.noteNums = nil
+	f.started = false
+	f.finish(true)
+}
+
+// write prints the text of a top-level block.
+func (f *textOut) write(s string) {
+	if s == "" {
+		return
+	}
+	if f.started {
+		f.WriteString("\n\n")
+	}
+	f.WriteString(s)
+	f.started = true
+}
+
+// blocks returns the text of a list of block elements.
+func (f *textOut) blocks(list *element) string {
+	var b strings.Builder
+	prev := -1
+	for ; list != nil; list = list.next {
+		s := f.block(list)
+		if s == "" {
+			continue
+		}
+		switch {
+		case prev == PLAIN, prev == DEFTITLE:
+			b.WriteString("\n")
+		case prev != -1:
+			b.WriteString("\n\n")
+		}
+		b.WriteString(s)
+		prev = list.key
+	}
+	return b.String()
+}
+
+// block returns the text of a block element.
+func (f *textOut) block(elt *element) string {
+	switch elt.key {
+	case PARA, PLAIN:
+		return f.inline(elt.children)
+	case FIGURE:
+		img := elt.children
+		text := f.inline(img)
+		if img.next != nil {
+			text += "\n" + f.inline(img.next.children)
+		} else if title := img.contents.link.title; title != "" {
+			text += "\n" + title
+		}
+		return text
+	case H1, H2:
+		text := f.inline(elt.children)
+		c := "="
+		if elt.key == H2 {
+			c = "-"
+		}
+		return text + "\n" + strings.Repeat(c, utf8.RuneCountInString(text))
+	case H3, H4, H5, H6:
+		return strings.Repeat("#", elt.key-H1+1) + " " + f.inline(elt.children)
+	case BLOCKQUOTE:
+		return prefixLines(f.blocks(elt.children), "> ", "> ")
+	case BULLETLIST, ORDEREDLIST:
+		var b strings.Builder
+		n := listStart(elt)
+		for item := elt.children; item != nil; item = item.next {
+			if item != elt.children {
+				b.WriteString("\n")
+				if elt.loose {
+					b.WriteString("\n")
+				}
+			}
+			marker := "- "
+			if elt.key == ORDEREDLIST {
+				marker = strconv.Itoa(n) + ". "
+				n++
+			}
+			b.WriteString(indent(f.blocks(item.children), marker))
+		}
+		return b.String()
+	case DEFTITLE:
+		return f.inline(elt.children)
+	case DEFDATA:
+		return prefixLines(f.blocks(elt.children), "    ", "    ")
+	case VERBATIM:
+		return prefixLines(strings.TrimRight(elt.contents.str, "\n"), "    ", "    ")
+	case HRULE:
+		return "----"
+	case CONTAINER:
+		s := f.blocks(elt.children)
+		if title := elt.contents.link.title; title != "" && s != "" {
+			return title + "\n\n" + s
+		} else if title != "" {
+			return title
+		}
+		return s
+	case DEFINITIONLIST, EXTBLOCK, LIST:
+		return f.blocks(elt.children)
+	case TABLE:
+		var rows []string
+		for part := elt.children; part != nil; part = part.next {
+			switch part.key {
+			case TABLECAPTION:
+				rows = append(rows, f.inline(part.children))
+			case TABLEHEAD, TABLEBODY:
+				for row := part.children; row != nil; row = row.next {
+					var cells []string
+					for cell := row.children; cell != nil; cell = cell.next {
+						if blocks := cellBlocks(cell); blocks != nil {
+							cells = append(cells, strings.ReplaceAll(f.blocks(blocks), "\n\n", "\n"))
+						} else {
+							cells = append(cells, f.inline(cell.children))
+						}
+					}
+					rows = append(rows, strings.Join(cells, " | "))
+				}
+			}
+		}
+		return strings.Join(rows, "\n")
+	case HTMLBLOCK, REFERENCE:
+		/* Nonprinting */
+	case NOTE:
+		/* Note definitions have been incorporated into the notes list */
+	default:
+		if info, ok := ElementKind(elt.key).info(); ok && info.fallback != FallbackOmit {
+			return elt.contents.str
+		}
+	}
+	return ""
+}
+
+// inline returns the text of a list of inline elements.
+func (f *textOut) inline(list *element) string {
+	var b strings.Builder
+	f.writeInline(&b, list)
+	return strings.TrimSpace(b.String())
+}
+
+func (f *textOut) writeInline(b *strings.Builder, list *element) {
+	for ; list != nil; list = list.next {
+		switch list.key {
+		case STR, CODE, SPACE:
+			b.WriteString(list.contents.str)
+		case LINEBREAK:
+			b.WriteString("\n")
+		case ELLIPSIS:
+			b.WriteString("…")
+		case EMDASH:
+			b.WriteString("—")
+		case ENDASH:
+			b.WriteString("–")
+		case APOSTROPHE:
+			b.WriteString("’")
+		case SINGLEQUOTED:
+			b.WriteString("‘")
+			f.writeInline(b, list.children)
+			b.WriteString("’")
+		case DOUBLEQUOTED:
+			b.WriteString("“")
+			f.writeInline(b, list.children)
+			b.WriteString("”")
+		case LINK:
+			f.writeInline(b, list.contents.link.label)
+			if showURL(list.contents.link) {
+				b.WriteString(" (" + list.contents.link.url + ")")
+			}
+		case IMAGE:
+			b.WriteString("[")
+			f.writeInline(b, list.contents.link.label)
+			b.WriteString("]")
+		case EMPH, STRONG, MARK, CRITICINS, CRITICSUB, CRITICHIGHLIGHT, LIST:
+			f.writeInline(b, list.children) /* changes are shown accepted */
+		case HTML, CRITICDEL, CRITICCOMMENT, COMMENT:
+		case NOTE:
+			if list.contents.str == "" {
+				b.WriteString("[" + strconv.Itoa(f.noteNum(list.children)) + "]")
+			}
+		case UNDEFNOTE:
+			b.WriteString("[^" + list.contents.str + "]")
+		case PLACEHOLDER:
+			b.WriteString("{{" + list.contents.str + "}}")
+		case CITATION:
+			b.WriteString("[" + list.contents.str + "]")
+		case TABLELABEL, CELLSPAN, ROWSPAN:
+		default:
+			if info, ok := ElementKind(list.key).info(); ok && info.fallback != FallbackOmit {
+				b.WriteString(list.contents.str)
+			}
+		}
+	}
+}
+
+// noteNum returns the number of a note, which is assigned at
+// its first reference.
+func (f *textOut) noteNum(body *element) int {
+	if n, ok := f.noteNums[body]; ok {
+		return n
+	}
+	if f.noteNums == nil {
+		f.noteNums = make(map[*element]int)
+	}
+	f.notes = append(f.notes, body)
+	f.noteNums[body] = len(f.notes)
+	return len(f.notes)
+}
+
+// indent prefixes the first line of s with marker, and the
+// following lines with as many spaces.
+func indent(s, marker string) string {
+	return prefixLines(s, marker, strings.Repeat(" ", utf8.RuneCountInString(marker)))
+}
+
+// prefixLines prefixes the first line of s with first, and the
+// following lines with rest; trailing white space is omitted
+// from empty lines.
+func prefixLines(s, first, rest string) string {
+	var b strings.Builder
+	prefix := first
+	for s != "" {
+		line, more := cutLine(s)
+		if strings.TrimSpace(line) == "" {
+			b.WriteString(strings.TrimRight(prefix, " "))
+		} else {
+			b.WriteString(prefix)
+		}
+		b.WriteString(line)
+		s, prefix = more, rest
+	}
+	return b.String()
+}
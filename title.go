@@ -0,0 +1,101 @@
+package markdown
+
+// Reconciling a document's first heading with a title given separately
+
+import (
+	"strings"
+)
+
+// A TitleMode selects what ReconcileTitle does with the title of
+// a document.
+type TitleMode int
+
+const (
+	// Drop the first heading, if it is a level 1 heading with
+	// the same text as the title.
+	StripTitle TitleMode = iota
+
+	// Print the title as a level 1 heading, unless the document
+	// starts with a level 1 heading.
+	InjectTitle
+)
+
+// ReconcileTitle returns a formatter that passes blocks to f, making
+// sure that a title known from elsewhere, like the front matter of a
+// document, is not shown twice, or, with InjectTitle, not missing,
+// if pages are printed by a program that also shows the titles, or
+// takes them from the documents, respectively. Titles are compared
+// ignoring markup, case, and differences in white space.
+func ReconcileTitle(f Formatter, title string, mode TitleMode) Formatter {
+	return &titleFormatter{f: f, title: title, mode: mode}
+}
+
+type titleFormatter struct {
+	f     Formatter
+	title string
+	mode  TitleMode
+
+	done    bool /* The first printing block has been seen. */
+	span    Span
+	hasSpan bool
+}
+
+func (t *titleFormatter) FormatBlock(tree *element) {
+	if !t.done && !nonprinting(tree) {
+		t.done = true
+		isTitle := tree.key == H1
+		switch t.mode {
+		case StripTitle:
+			if isTitle && t.title != "" && sameTitle(plainText(tree.children), t.title) {
+				t.hasSpan = false
+				return
+			}
+		case InjectTitle:
+			if !isTitle {
+				t.inject()
+			}
+		}
+	}
+	if t.hasSpan {
+		t.f.(spanFormatter).setSpan(t.span)
+		t.hasSpan = false
+	}
+	t.f.FormatBlock(tree)
+}
+
+func (t *titleFormatter) Finish() {
+	if !t.done && t.mode == InjectTitle {
+		t.inject()
+	}
+	t.f.Finish()
+	t.done = false
+}
+
+func (t *titleFormatter) setSpan(span Span) {
+	if _, ok := t.f.(spanFormatter); ok {
+		t.span = span
+		t.hasSpan = true
+	}
+}
+
+// inject prints the title as a level 1 heading.
+func (t *titleFormatter) inject() {
+	if t.title == "" {
+		return
+	}
+	h := &element{key: H1}
+	h.children = &element{key: STR, contents: contents{str: t.title}}
+	t.f.FormatBlock(h)
+}
+
+// nonprinting reports whether a top-level block produces no
+// output, like a link reference, or the definition of a note.
+func nonprinting(tree *element) bool {
+	return tree.key == REFERENCE || tree.key == NOTE && tree.contents.str != ""
+}
+
+// sameTitle compares titles, ignoring case and
+// differences in white space.
+func sameTitle(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
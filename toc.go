@@ -0,0 +1,124 @@
+package markdown
+
+// Tables of contents, inserted at [TOC] markers
+
+import (
+	"strings"
+)
+
+// A tocHeading is a top-level heading listed in tables of contents.
+type tocHeading struct {
+	level  int
+	text   string
+	anchor string
+}
+
+// tocState keeps the headings of a document while its
+// blocks are formatted.
+type tocState struct {
+	headings []tocHeading
+	n        int /* Headings formatted so far. */
+}
+
+// tocMarker reports whether the source text of a paragraph is a
+// marker for a table of contents: "[TOC]" for the whole document,
+// or "[TOC local]" for the subsections of the current section.
+func tocMarker(src string) (local, ok bool) {
+	switch strings.Join(strings.Fields(src), " ") {
+	case "[TOC]":
+		return false, true
+	case "[TOC local]":
+		return true, true
+	}
+	return false, false
+}
+
+// tocHeadings collects the top-level headings of the preformatted
+// text s in advance, since a table of contents may precede the
+// headings it lists.
+func (p *Parser) tocHeadings(s string) *tocState {
+	t := new(tocState)
+	a := make(anchors)
+	p.prescan(s, func(tree *element) {
+		if tree.key >= H1 && tree.key <= H6 {
+			text := plainText(tree.children)
+			t.headings = append(t.headings, tocHeading{tree.key - H1 + 1, text, a.add(text)})
+		}
+	})
+	return t
+}
+
+// prescan parses the top-level blocks of the preformatted text s,
+// and passes each to f, which must not keep it. Only top-level
+// blocks are parsed completely, the content of block quotes and
+// list items is not processed.
+func (p *Parser) prescan(s string, f func(tree *element)) {
+	savedPos := p.yy.state.heap.Pos()
+	for {
+		tree := p.parseRule(ruleDocblock, s)
+		if tree == nil {
+			break
+		}
+		s = p.yy.ResetBuffer("")
+		f(tree)
+		p.yy.state.heap.setPos(savedPos)
+	}
+	p.yy.state.inlineNotes = false
+	p.yy.state.undefNotes = nil
+	p.yy.state.tableRows = nil
+}
+
+// block returns a top-level block to be formatted, which is a
+// table of contents, if the block is a marker with source text src.
+func (t *tocState) block(p *yyParser, tree *element, src string) *element {
+	switch tree.key {
+	case H1, H2, H3, H4, H5, H6:
+		t.n++
+	case PARA:
+		local, ok := tocMarker(src)
+		if !ok {
+			break
+		}
+		hs := t.headings
+		if local && t.n > 0 {
+			/* the subsections of the section the marker is in */
+			level := hs[t.n-1].level
+			hs = hs[t.n:]
+			for i, h := range hs {
+				if h.level <= level {
+					hs = hs[:i]
+					break
+				}
+			}
+		}
+		if toc := t.list(p, hs); toc != nil {
+			return toc
+		}
+		return p.mkList(LIST, nil)
+	}
+	return tree
+}
+
+// list returns a bullet list of links to the headings, with the
+// headings of lower levels nested below the preceding ones.
+func (t *tocState) list(p *yyParser, hs []tocHeading) *element {
+	var items *element
+	for len(hs) > 0 {
+		h := hs[0]
+		n := 1
+		for n < len(hs) && hs[n].level > h.level {
+			n++
+		}
+		item := p.mkElem(LISTITEM)
+		item.contents.str = "-"
+		item.children = p.mkList(PLAIN, p.mkLink(p.mkString(h.text), "#"+h.anchor, ""))
+		item.children.next = t.list(p, hs[1:n])
+		item.next = items
+		items = item
+		hs = hs[n:]
+	}
+	if items == nil {
+		return nil
+	}
+	return p.mkList(BULLETLIST, items)
+}
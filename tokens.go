@@ -0,0 +1,542 @@
+package markdown
+
+// Classification of the source text, for syntax highlighting
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// A TokenKind classifies a range of the source text.
+type TokenKind int
+
+const (
+	HeadingMarkerToken TokenKind = iota // "#", or the underline of a setext heading
+	HeadingToken                        // text of a heading
+	EmphasisToken                       // emphasized text
+	StrongToken                         // strongly emphasized text
+	DelimiterToken                      // emphasis delimiters, brackets of links, table separators, etc.
+	CodeToken                           // code spans, including their backticks, and lines of code blocks
+	LinkTextToken                       // text of links and images
+	URLToken                            // destinations of links and references, and autolinks
+	TitleToken                          // titles of links and references
+	LabelToken                          // labels of references and notes
+	ListMarkerToken                     // bullets, numbers of ordered lists, and definition markers
+	QuoteMarkerToken                    // ">" of block quotes
+	RuleToken                           // horizontal rules
+	HTMLToken                           // raw HTML
+	EntityToken                         // character entities
+	EscapeToken                         // backslashes escaping punctuation
+	CommentToken                        // comments, and CriticMarkup comments
+
+	noToken TokenKind = -1
+)
+
+// A Token is a classified range of the source text.
+type Token struct {
+	Kind TokenKind
+	Span Span
+}
+
+// Tokens parses a document, like Markdown, and classifies the parts
+// of its source text that are not plain text, as needed for semantic
+// highlighting in editors. The tokens are derived from the parsed
+// elements, which are located in the source text of their top-level
+// block. Bytes not covered by a token are plain text, or white space.
+// Tokens are ordered by their positions, and don't overlap, except
+// for text nested in other text, like emphasis within a heading,
+// which is classified by the innermost element.
+func (p *Parser) Tokens(src io.Reader) []Token {
+	s := p.source(src)
+	f := &tokensOut{p: p, t: tokenizer{src: s}, lines: newLineCounter(s, p.offsets)}
+	p.markdown(s, f)
+	return f.list
+}
+
+type tokensOut struct {
+	p     *Parser
+	t     tokenizer
+	lines *lineCounter
+	span  Span
+	list  []Token
+}
+
+func (f *tokensOut) setSpan(s Span) {
+	f.span = s
+}
+
+func (f *tokensOut) FormatBlock(tree *element) {
+	t := &f.t
+	t.notes = f.p.yy.state.notes
+	t.block(tree, f.span)
+	pos := f.span.Start
+	for _, tok := range t.toks {
+		pos = f.lines.posAfter(pos, tok.start)
+		f.list = append(f.list, Token{tok.kind, Span{pos, f.lines.posAfter(pos, tok.end-1)}})
+	}
+}
+
+func (f *tokensOut) Finish() {
+}
+
+type rawToken struct {
+	kind       TokenKind
+	start, end int
+}
+
+// A tokenCtx determines how text and markup within
+// an element are classified.
+type tokenCtx struct {
+	text   TokenKind /* kind of text, like STR elements */
+	markup TokenKind /* kind of other non-space bytes */
+}
+
+// A tokenizer locates elements in the source text. Bytes between
+// the text of elements are classified as markup of the element
+// containing them.
+type tokenizer struct {
+	src    string
+	cur    int /* offset of the next byte to be classified */
+	end    int /* end of the text the current element may occupy */
+	quotes int /* nesting of block quotes */
+	notes  map[string]*element
+	toks   []rawToken
+
+	/* If set, extent is called with the range of the source text of each element located. */
+	extent func(elt *element, start, end int)
+	start  int /* start of the bytes classified for the current element, or -1 */
+}
+
+// block classifies the source text of a top-level block, found
+// at span, into toks, ordered by position.
+func (t *tokenizer) block(tree *element, span Span) {
+	t.cur = span.Start.off
+	t.end = span.End.off + 1
+	t.toks = t.toks[:0]
+	for ; tree != nil; tree = tree.next {
+		ctx := t.context(tree, tokenCtx{noToken, noToken})
+		t.elem(tree, ctx)
+		t.gap(t.end, ctx)
+	}
+	sort.SliceStable(t.toks, func(i, j int) bool {
+		return t.toks[i].start < t.toks[j].start
+	})
+}
+
+func (t *tokenizer) add(kind TokenKind, start, end int) {
+	if end <= start {
+		return
+	}
+	if t.start == -1 || start < t.start {
+		t.start = start
+	}
+	if kind != noToken {
+		t.toks = append(t.toks, rawToken{kind, start, end})
+	}
+}
+
+// context returns the context of the children of elt,
+// a child of an element with context ctx.
+func (t *tokenizer) context(elt *element, ctx tokenCtx) tokenCtx {
+	switch elt.key {
+	case H1, H2, H3, H4, H5, H6:
+		return tokenCtx{HeadingToken, HeadingMarkerToken}
+	case EMPH:
+		return tokenCtx{EmphasisToken, DelimiterToken}
+	case STRONG:
+		return tokenCtx{StrongToken, DelimiterToken}
+	case LINK, IMAGE:
+		return tokenCtx{LinkTextToken, DelimiterToken}
+	case MARK, CRITICINS, CRITICDEL, CRITICSUB, CRITICHIGHLIGHT,
+		TABLE, TABLECAPTION, REFERENCE, NOTE, CONTAINER:
+		ctx.markup = DelimiterToken
+	case PARA, PLAIN, FIGURE, BLOCKQUOTE, BULLETLIST, ORDEREDLIST, DEFINITIONLIST:
+		ctx.markup = noToken
+	}
+	return ctx
+}
+
+// find returns the offset of s in the text left, or -1.
+func (t *tokenizer) find(s string) int {
+	if s == "" || t.cur >= t.end {
+		return -1
+	}
+	i := strings.Index(t.src[t.cur:t.end], s)
+	if i == -1 {
+		return -1
+	}
+	return t.cur + i
+}
+
+// text classifies s, found in the text left, as text of ctx.
+func (t *tokenizer) text(s string, ctx tokenCtx) bool {
+	i := t.find(s)
+	if i == -1 {
+		return false
+	}
+	t.gap(i, ctx)
+	t.add(ctx.text, i, i+len(s))
+	t.cur = i + len(s)
+	return true
+}
+
+// gap classifies the bytes up to offset end as markup of ctx.
+func (t *tokenizer) gap(end int, ctx tokenCtx) {
+	for i := t.cur; i < end; {
+		c := t.src[i]
+		switch {
+		case c == ' ' || c == '\n' || c == '\r' || c == '\t':
+			i++
+		case c == '>' && t.quotes > 0 && t.atLineStart(i):
+			j := i
+			for j < end && t.src[j] == '>' {
+				j++
+			}
+			t.add(QuoteMarkerToken, i, j)
+			i = j
+		case c == '\\' && i+1 < len(t.src) && strings.IndexByte("-\\`|*_{}[]()#+.!><", t.src[i+1]) != -1:
+			t.add(EscapeToken, i, i+1)
+			i++
+		default:
+			j := i + 1
+			for j < end && strings.IndexByte(" \n\r\t\\", t.src[j]) == -1 {
+				j++
+			}
+			t.add(ctx.markup, i, j)
+			i = j
+		}
+	}
+	if end > t.cur {
+		t.cur = end
+	}
+}
+
+// atLineStart reports whether only spaces and quote
+// markers precede src[i] on its line.
+func (t *tokenizer) atLineStart(i int) bool {
+	for i--; i >= 0 && t.src[i] != '\n'; i-- {
+		if t.src[i] != ' ' && t.src[i] != '>' {
+			return false
+		}
+	}
+	return true
+}
+
+// delim classifies a run of bytes from set at the
+// current offset as delimiters.
+func (t *tokenizer) delim(set string) {
+	i := t.cur
+	for i < t.end && strings.IndexByte(set, t.src[i]) != -1 {
+		i++
+	}
+	t.add(DelimiterToken, t.cur, i)
+	t.cur = i
+}
+
+// skip moves past s, found in the text left, classifying
+// the bytes before as markup of ctx.
+func (t *tokenizer) skip(s string, ctx tokenCtx) {
+	if i := t.find(s); i != -1 {
+		t.gap(i, ctx)
+		t.add(noToken, i, i+len(s))
+		t.cur = i + len(s)
+	}
+}
+
+func (t *tokenizer) list(elt *element, ctx tokenCtx) {
+	for ; elt != nil; elt = elt.next {
+		t.elem(elt, t.context(elt, ctx))
+	}
+}
+
+// elem classifies the source text of elt; ctx is the
+// context of its children.
+func (t *tokenizer) elem(elt *element, ctx tokenCtx) {
+	if t.extent == nil {
+		t.classify(elt, ctx)
+		return
+	}
+	outer := t.start
+	t.start = -1
+	t.classify(elt, ctx)
+	if t.start != -1 {
+		t.extent(elt, t.start, t.cur)
+	}
+	if outer != -1 && (t.start == -1 || outer < t.start) {
+		t.start = outer
+	}
+}
+
+// classify classifies the source text of elt, like elem.
+func (t *tokenizer) classify(elt *element, ctx tokenCtx) {
+	switch elt.key {
+	case STR:
+		t.text(elt.contents.str, ctx)
+	case SPACE, LINEBREAK:
+	case ELLIPSIS:
+		t.skip(".", ctx)
+		for t.cur < t.end && (t.src[t.cur] == '.' || t.src[t.cur] == ' ' && t.src[t.cur+1] == '.') {
+			t.cur++
+		}
+	case EMDASH:
+		t.skip("--", ctx)
+		if t.cur < t.end && t.src[t.cur] == '-' {
+			t.cur++
+		}
+	case ENDASH:
+		t.skip("-", ctx)
+	case APOSTROPHE:
+		t.skip("'", ctx)
+	case SINGLEQUOTED:
+		t.list(elt.children, ctx)
+		t.skip("'", ctx)
+	case DOUBLEQUOTED:
+		t.list(elt.children, ctx)
+		t.skip(`"`, ctx)
+	case CODE:
+		t.code(elt.contents.str)
+	case HTML:
+		kind := HTMLToken
+		if strings.HasPrefix(elt.contents.str, "&") {
+			kind = EntityToken
+		}
+		t.text(elt.contents.str, tokenCtx{kind, ctx.markup})
+	case LINK, IMAGE:
+		t.link(elt, ctx)
+	case EMPH, STRONG:
+		t.list(elt.children, ctx)
+		t.delim("*_")
+	case MARK:
+		t.list(elt.children, ctx)
+		t.delim("=")
+	case CRITICINS:
+		t.list(elt.children, ctx)
+		t.delim("+~}")
+	case CRITICDEL:
+		t.list(elt.children, ctx)
+		t.delim("-~>}")
+	case CRITICHIGHLIGHT:
+		t.list(elt.children, ctx)
+		t.delim("=}")
+	case CRITICCOMMENT:
+		t.text(elt.contents.str, tokenCtx{CommentToken, DelimiterToken})
+		t.delim("<}")
+	case COMMENT, COMMENTBLOCK:
+		t.text(elt.contents.str, tokenCtx{CommentToken, DelimiterToken})
+		t.delim("%->")
+	case NOTE:
+		if elt.contents.str != "" {
+			t.label("[^", elt.contents.str, "]:")
+			body := elt.children
+			if note, ok := t.notes[elt.contents.str]; ok && note.contents.str == elt.contents.str {
+				/* bodies of notes are parsed in advance */
+				body = note.children
+			}
+			t.list(body, ctx)
+		} else {
+			t.noteRef(elt, ctx)
+		}
+	case UNDEFNOTE:
+		t.label("[^", elt.contents.str, "]")
+	case PLACEHOLDER:
+		t.label("{{", elt.contents.str, "}}")
+	case CITATION:
+		t.label("[", elt.contents.str, "]")
+	case REFERENCE:
+		t.reference(elt)
+	case VERBATIM:
+		t.lines(elt.contents.str, CodeToken)
+	case HTMLBLOCK:
+		t.lines(elt.contents.str, HTMLToken)
+	case HRULE:
+		for t.cur < t.end && (t.src[t.cur] == ' ' || t.src[t.cur] == '\n') {
+			t.cur++
+		}
+		i := strings.IndexByte(t.src[t.cur:t.end], '\n')
+		if i == -1 {
+			i = t.end - t.cur
+		}
+		t.add(RuleToken, t.cur, t.cur+i)
+		t.cur += i
+	case LISTITEM, DEFDATA:
+		t.listMarker(elt)
+		t.list(elt.children, ctx)
+	case BLOCKQUOTE:
+		t.quotes++
+		t.list(elt.children, ctx)
+		t.quotes--
+	default:
+		t.list(elt.children, ctx)
+	}
+}
+
+// code classifies a code span, with its backticks.
+func (t *tokenizer) code(s string) {
+	i := t.find(s)
+	if i == -1 {
+		return
+	}
+	start := i
+	for start > t.cur && (t.src[start-1] == '`' || t.src[start-1] == ' ') {
+		start--
+	}
+	for start < i && t.src[start] == ' ' {
+		start++
+	}
+	end := i + len(s)
+	for end < t.end && (t.src[end] == '`' || t.src[end] == ' ' && end+1 < t.end && t.src[end+1] == '`') {
+		end++
+	}
+	t.gap(start, tokenCtx{noToken, noToken})
+	t.add(CodeToken, start, end)
+	t.cur = end
+}
+
+// lines classifies the source lines of a block's text.
+func (t *tokenizer) lines(s string, kind TokenKind) {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			t.text(line, tokenCtx{kind, noToken})
+		}
+	}
+}
+
+// label classifies a label enclosed by open and close.
+func (t *tokenizer) label(open, label, close string) {
+	i := t.find(open + label + close)
+	if i == -1 {
+		return
+	}
+	t.gap(i, tokenCtx{noToken, noToken})
+	t.add(DelimiterToken, i, i+len(open))
+	i += len(open)
+	t.add(LabelToken, i, i+len(label))
+	i += len(label)
+	t.add(DelimiterToken, i, i+len(close))
+	t.cur = i + len(close)
+}
+
+// noteRef classifies a note reference, or an inline note.
+func (t *tokenizer) noteRef(elt *element, ctx tokenCtx) {
+	ref, inline := t.find("[^"), t.find("^[")
+	if inline == -1 || ref != -1 && ref < inline {
+		if ref == -1 {
+			return
+		}
+		k := strings.IndexByte(t.src[ref:t.end], ']')
+		if k == -1 {
+			return
+		}
+		t.label("[^", t.src[ref+2:ref+k], "]")
+		return
+	}
+	t.gap(inline, ctx)
+	t.add(DelimiterToken, inline, inline+2)
+	t.cur = inline + 2
+	t.list(elt.children, tokenCtx{ctx.text, DelimiterToken})
+	t.delim("]")
+}
+
+// listMarker classifies the marker of a list item,
+// or of a definition.
+func (t *tokenizer) listMarker(elt *element) {
+	i := t.cur
+	for i < t.end && strings.IndexByte(" \n>", t.src[i]) != -1 {
+		i++
+	}
+	t.gap(i, tokenCtx{noToken, noToken})
+	if m := elt.contents.str; m != "" && strings.HasPrefix(t.src[i:t.end], m) {
+		t.add(ListMarkerToken, i, i+len(m))
+		t.cur = i + len(m)
+		return
+	}
+	j := i
+	for j < t.end && strings.IndexByte("0123456789", t.src[j]) != -1 {
+		j++
+	}
+	switch {
+	case j > i && j < t.end && t.src[j] == '.':
+		j++
+	case j == i && j < t.end && strings.IndexByte("*+-:~", t.src[j]) != -1:
+		j++
+	default:
+		return
+	}
+	t.add(ListMarkerToken, i, j)
+	t.cur = j
+}
+
+// link classifies a link or an image, with its destination,
+// or its reference label.
+func (t *tokenizer) link(elt *element, ctx tokenCtx) {
+	l := elt.contents.link
+	j, lt := t.find("["), t.find("<")
+	if lt != -1 && (j == -1 || lt < j) && l.label != nil && l.label.key == STR &&
+		strings.HasPrefix(t.src[lt+1:t.end], strings.TrimPrefix(l.url, "mailto:")) {
+		/* autolink */
+		gt := strings.IndexByte(t.src[lt:t.end], '>')
+		if gt == -1 {
+			return
+		}
+		t.gap(lt, tokenCtx{noToken, noToken})
+		t.add(DelimiterToken, lt, lt+1)
+		t.add(URLToken, lt+1, lt+gt)
+		t.add(DelimiterToken, lt+gt, lt+gt+1)
+		t.cur = lt + gt + 1
+		return
+	}
+	if j == -1 {
+		return
+	}
+	k := closingBracket(t.src[:t.end], j)
+	if k == -1 {
+		return
+	}
+	start := j
+	if elt.key == IMAGE && j > t.cur && t.src[j-1] == '!' {
+		start--
+	}
+	t.gap(start, tokenCtx{noToken, noToken})
+	t.add(DelimiterToken, start, j+1)
+	t.cur = j + 1
+	end := t.end
+	t.end = k
+	t.list(l.label, ctx)
+	t.gap(k, ctx)
+	t.end = end
+	t.add(DelimiterToken, k, k+1)
+	t.cur = k + 1
+
+	if t.cur < t.end && t.src[t.cur] == '(' {
+		close := t.cur
+		t.text(l.url, tokenCtx{URLToken, DelimiterToken})
+		t.text(l.title, tokenCtx{TitleToken, DelimiterToken})
+		if i := strings.IndexByte(t.src[t.cur:t.end], ')'); i != -1 {
+			close = t.cur + i
+		}
+		t.gap(close+1, tokenCtx{noToken, DelimiterToken})
+		return
+	}
+	if a := spnl(t.src[:t.end], t.cur); a < t.end && t.src[a] == '[' {
+		if n := closingBracket(t.src[:t.end], a); n != -1 {
+			t.label("[", t.src[a+1:n], "]")
+		}
+	}
+}
+
+// reference classifies a reference definition.
+func (t *tokenizer) reference(elt *element) {
+	l := elt.contents.link
+	j := t.find("[")
+	if j == -1 {
+		return
+	}
+	k := closingBracket(t.src[:t.end], j)
+	if k == -1 {
+		return
+	}
+	t.label("[", t.src[j+1:k], "]")
+	t.text(l.url, tokenCtx{URLToken, DelimiterToken})
+	t.text(l.title, tokenCtx{TitleToken, DelimiterToken})
+}
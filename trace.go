@@ -0,0 +1,125 @@
+package markdown
+
+// Tracing the rules of the grammar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SetTrace makes the parser write a line to w each time it tries a
+// rule of the grammar in parser.leg, with the position in the text
+// being parsed and the text following it, and another line when
+// the rule has matched, with the range of the text consumed, or
+// failed. Lines are indented according to the nesting of rules.
+// Positions are byte offsets into the text passed to the grammar,
+// which, for the contents of lists, quotes, and notes, is the text
+// of the block without its markers and indentation. Tracing, meant
+// for debugging extensions, slows down parsing considerably;
+// SetTrace(nil) turns it off.
+func (p *Parser) SetTrace(w io.Writer) {
+	p.trace = w
+	p.traceRules()
+}
+
+// traceRules replaces the rules of the grammar by ones writing
+// trace lines, if p.trace is set, or restores the original ones.
+func (p *Parser) traceRules() {
+	if p.untraced == nil {
+		p.untraced = append([]func() bool(nil), p.yy.rules[:]...)
+	}
+	for i, rule := range p.untraced {
+		if p.trace != nil && i != ruleTracePosition {
+			rule = p.traced(i, rule)
+		}
+		p.yy.rules[i] = rule
+	}
+}
+
+func (p *Parser) traced(rule int, match func() bool) func() bool {
+	name := fmt.Sprint("rule ", rule)
+	if rule < len(ruleNames) {
+		name = ruleNames[rule]
+	}
+	return func() bool {
+		indent := strings.Repeat("  ", p.traceDepth)
+		start := p.tracePosition()
+		text := p.yy.Buffer[start:]
+		if len(text) > 16 {
+			text = text[:16] + "..."
+		}
+		fmt.Fprintf(p.trace, "%s%s %d %q\n", indent, name, start, text)
+		p.traceDepth++
+		ok := match()
+		p.traceDepth--
+		if ok {
+			fmt.Fprintf(p.trace, "%s%s %d-%d ok\n", indent, name, start, p.tracePosition())
+		} else {
+			fmt.Fprintf(p.trace, "%s%s %d fail\n", indent, name, start)
+		}
+		return ok
+	}
+}
+
+// tracePosition returns the current position of the generated
+// parser, which is local to it, by means of the TracePosition rule.
+func (p *Parser) tracePosition() int {
+	p.yy.rules[ruleTracePosition]()
+	return p.yy.state.tracePos
+}
+
+func (p *yyParser) tracePosition(pos int) bool {
+	p.state.tracePos = pos
+	return true
+}
+
+// ruleNames lists the names of the rules, in the order of
+// their constants in parser.leg.go.
+var ruleNames = [...]string{
+	"Doc", "Docblock", "Block", "Para", "Plain", "AtxInline", "AtxStart",
+	"AtxHeading", "SetextHeading", "SetextBottom1", "SetextBottom2",
+	"SetextHeading1", "SetextHeading2", "Heading", "BlockQuote",
+	"BlockQuoteRaw", "NonblankIndentedLine", "VerbatimChunk", "Verbatim",
+	"HorizontalRule", "Bullet", "BulletList", "ListTight", "ListLoose",
+	"ListItem", "ListItemTight", "ListBlock", "ListContinuationBlock",
+	"Enumerator", "OrderedList", "ListBlockLine", "HtmlBlockOpenScript",
+	"HtmlBlockCloseScript", "HtmlBlockScript", "HtmlBlockOpenHead",
+	"HtmlBlockCloseHead", "HtmlBlockHead", "HtmlBlockInTags", "HtmlBlock",
+	"HtmlBlockSelfClosing", "HtmlBlockType", "StyleOpen", "StyleClose",
+	"InStyleTags", "StyleBlock", "Inlines", "Inline", "Space", "Str",
+	"StrChunk", "AposChunk", "EscapedChar", "Entity", "Endline",
+	"NormalEndline", "TerminalEndline", "LineBreak", "Symbol",
+	"UlOrStarLine", "StarLine", "UlLine", "Emph", "Whitespace",
+	"EmphStar", "EmphUl", "Strong", "StrongStar", "StrongUl", "Image",
+	"Link", "ReferenceLink", "ReferenceLinkDouble", "ReferenceLinkSingle",
+	"ExplicitLink", "Source", "SourceContents", "Title", "TitleSingle",
+	"TitleDouble", "AutoLink", "AutoLinkUrl", "AutoLinkEmail",
+	"Reference", "Label", "RefSrc", "RefTitle", "EmptyTitle",
+	"RefTitleSingle", "RefTitleDouble", "RefTitleParens", "References",
+	"Ticks1", "Ticks2", "Ticks3", "Ticks4", "Ticks5", "Code", "RawHtml",
+	"BlankLine", "Quoted", "HtmlAttribute", "HtmlComment", "HtmlTag",
+	"Eof", "Spacechar", "Nonspacechar", "Newline", "Sp", "Spnl",
+	"SpecialChar", "NormalChar", "Alphanumeric", "AlphanumericAscii",
+	"Digit", "HexEntity", "DecEntity", "CharEntity", "NonindentSpace",
+	"Indent", "IndentedLine", "OptionallyIndentedLine", "StartList",
+	"Line", "RawLine", "SkipBlock", "ExtendedSpecialChar", "Smart",
+	"Apostrophe", "Ellipsis", "Dash", "EnDash", "EmDash",
+	"SingleQuoteStart", "SingleQuoteEnd", "SingleQuoted",
+	"DoubleQuoteStart", "DoubleQuoteEnd", "DoubleQuoted", "NoteReference",
+	"RawNoteReference", "Note", "InlineNote", "Notes", "RawNoteBlock",
+	"DefinitionList", "Definition", "DListTitle", "DefTight", "DefLoose",
+	"Defmark", "DefMarker", "Table", "TableBody", "TableRow", "TableLine",
+	"TableCell", "ExtendedCell", "CellStr", "FullCell", "EmptyCell",
+	"SeparatorLine", "AlignmentCell", "LeftAlignWrap", "LeftAlign",
+	"CenterAlignWrap", "CenterAlign", "RightAlignWrap", "RightAlign",
+	"CellDivider", "TableCaption", "DefMarkChar", "Utf8Tail",
+	"DefListTight", "DefListLoose", "DefItem", "DefItemTight", "Mark",
+	"Critic", "CriticIns", "CriticDel", "CriticSub", "CriticHighlight",
+	"CriticComment", "HtmlSpecial", "HtmlCdata", "HtmlProcessing",
+	"HtmlDeclaration", "ExtInline", "ExtBlock", "Container", "LineTail",
+	"ListMarker", "Prime", "TableRef", "Hashtag", "NormalChars",
+	"TracePosition", "Placeholder", "Citation", "Comment", "CommentBlock", "GridTable",
+	"AngleSource",
+	"BlankLines",
+}
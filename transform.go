@@ -0,0 +1,227 @@
+package markdown
+
+// Transforming document trees between parsing and rendering
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// A Transformer changes a document tree, as returned by
+// Parser.Parse, before it is rendered.
+type Transformer func(tree *Node)
+
+// Render applies the transformers to tree, in order, and formats
+// the result using f. The children of tree are passed to f as the
+// top-level blocks of the document. Formatters keep their state to
+// themselves, and Render changes tree only through transformers, so
+// that without them one tree may be rendered by several goroutines
+// at a time, e.g. to HTML and to text.
+func Render(tree *Node, f Formatter, transformers ...Transformer) {
+	for _, t := range transformers {
+		t(tree)
+	}
+	notes := make(map[*Node]*element)
+	for _, n := range tree.Children {
+		f.FormatBlock(newElements([]*Node{n}, notes))
+	}
+	f.Finish()
+}
+
+// newElements returns a list of elements built from nodes. Note
+// bodies shared by NOTE nodes are converted only once, and stay
+// shared, so that formatters number them as single notes.
+func newElements(nodes []*Node, notes map[*Node]*element) (list *element) {
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i]
+		elt := &element{key: int(n.Kind), loose: n.Loose, next: list}
+		elt.contents.str = n.Text
+		children := &elt.children
+		switch {
+		case n.Kind == LINK || n.Kind == IMAGE || n.Kind == REFERENCE:
+			elt.contents.link = &link{url: n.URL, title: n.Title, ref: n.Ref}
+			children = &elt.contents.link.label
+		case n.Kind == CONTAINER || n.URL != "" || n.Title != "":
+			elt.contents.link = &link{url: n.URL, title: n.Title}
+		}
+		if n.Kind == NOTE && len(n.Children) > 0 {
+			body, ok := notes[n.Children[0]]
+			if !ok {
+				body = newElements(n.Children, notes)
+				notes[n.Children[0]] = body
+			}
+			*children = body
+		} else {
+			*children = newElements(n.Children, notes)
+		}
+		list = elt
+	}
+	return list
+}
+
+// Walk calls fn for n and the nodes it contains, depth first. The
+// children of a node are skipped if fn returns false. Note bodies
+// shared by several NOTE nodes are visited once.
+func (n *Node) Walk(fn func(*Node) bool) {
+	n.walk(fn, make(map[*Node]bool))
+}
+
+func (n *Node) walk(fn func(*Node) bool, seen map[*Node]bool) {
+	if !fn(n) {
+		return
+	}
+	if n.Kind == NOTE && len(n.Children) > 0 {
+		if seen[n.Children[0]] {
+			return
+		}
+		seen[n.Children[0]] = true
+	}
+	for _, c := range n.Children {
+		c.walk(fn, seen)
+	}
+}
+
+// ShiftHeadingLevels returns a transformer that adds n, which may
+// be negative, to the levels of headings, like ShiftHeadings.
+// Levels are kept within the range from 1 to 6.
+func ShiftHeadingLevels(n int) Transformer {
+	return func(tree *Node) {
+		tree.Walk(func(c *Node) bool {
+			if c.Kind >= H1 && c.Kind <= H6 {
+				level := int(c.Kind-H1) + 1 + n
+				switch {
+				case level < 1:
+					level = 1
+				case level > 6:
+					level = 6
+				}
+				c.Kind = H1 + ElementKind(level-1)
+			}
+			return true
+		})
+	}
+}
+
+// AbsoluteLinks returns a transformer that resolves the URLs of
+// links and images relative to base. Fragments, like "#notes",
+// referring to the document itself, and URLs that cannot be
+// parsed are left as they are.
+func AbsoluteLinks(base *url.URL) Transformer {
+	return func(tree *Node) {
+		tree.Walk(func(n *Node) bool {
+			if n.Kind != LINK && n.Kind != IMAGE || n.URL == "" || strings.HasPrefix(n.URL, "#") {
+				return true
+			}
+			if u, err := url.Parse(n.URL); err == nil {
+				n.URL = base.ResolveReference(u).String()
+			}
+			return true
+		})
+	}
+}
+
+// FillPlaceholders returns a transformer that replaces placeholders,
+// see Extensions.Placeholders, by the values of their names, as
+// text. Within the URLs and titles of links and images, occurrences
+// of "{{name}}" are replaced too. Placeholders with names missing
+// from values are kept.
+func FillPlaceholders(values map[string]string) Transformer {
+	var pairs []string
+	for name, v := range values {
+		pairs = append(pairs, "{{"+name+"}}", v)
+	}
+	r := strings.NewReplacer(pairs...)
+	return func(tree *Node) {
+		tree.Walk(func(n *Node) bool {
+			switch n.Kind {
+			case PLACEHOLDER:
+				if v, ok := values[n.Text]; ok {
+					n.Kind, n.Text = STR, v
+				}
+			case LINK, IMAGE:
+				n.URL, n.Title = r.Replace(n.URL), r.Replace(n.Title)
+			}
+			return true
+		})
+	}
+}
+
+// InlineImages returns a transformer that replaces the URLs of
+// images with data: URLs containing the images, as returned by
+// load. The media type is derived from the extension of the URL,
+// or else from the content; types of images common on the web are
+// recognized, others are given as application/octet-stream. Images
+// that cannot be loaded keep their URLs.
+func InlineImages(load func(url string) ([]byte, error)) Transformer {
+	return func(tree *Node) {
+		tree.Walk(func(n *Node) bool {
+			if n.Kind != IMAGE || n.URL == "" || strings.HasPrefix(n.URL, "data:") {
+				return true
+			}
+			b, err := load(n.URL)
+			if err != nil {
+				return true
+			}
+			typ := ""
+			if u, err := url.Parse(n.URL); err == nil {
+				typ = imageTypes[strings.ToLower(path.Ext(u.Path))]
+			}
+			if typ == "" {
+				typ = sniffImage(b)
+			}
+			n.URL = "data:" + typ + ";base64," + base64.StdEncoding.EncodeToString(b)
+			return true
+		})
+	}
+}
+
+/*
+Media types are looked up in tables of their own, rather than using
+packages mime and net/http, which would read system files, and add
+much code to programs, like those compiled to WebAssembly.
+*/
+
+var imageTypes = map[string]string{
+	".apng": "image/apng",
+	".avif": "image/avif",
+	".bmp":  "image/bmp",
+	".gif":  "image/gif",
+	".ico":  "image/x-icon",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+}
+
+// sniffImage returns the media type of an image, derived from
+// its content.
+func sniffImage(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(b, []byte("\xff\xd8\xff")):
+		return "image/jpeg"
+	case bytes.HasPrefix(b, []byte("GIF87a")), bytes.HasPrefix(b, []byte("GIF89a")):
+		return "image/gif"
+	case len(b) >= 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == "WEBP":
+		return "image/webp"
+	case bytes.HasPrefix(b, []byte("BM")):
+		return "image/bmp"
+	case bytes.HasPrefix(b, []byte("\x00\x00\x01\x00")):
+		return "image/x-icon"
+	case len(b) >= 12 && string(b[4:12]) == "ftypavif":
+		return "image/avif"
+	}
+	if len(b) > 512 {
+		b = b[:512]
+	}
+	if s := string(bytes.TrimLeft(b, " \t\r\n")); strings.HasPrefix(s, "<svg") ||
+		strings.HasPrefix(s, "<?xml") && strings.Contains(s, "<svg") {
+		return "image/svg+xml"
+	}
+	return "application/octet-stream"
+}
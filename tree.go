@@ -0,0 +1,247 @@
+package markdown
+
+// Document trees, and their comparison
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Node is an element of a document tree, as returned by
+// Parser.Parse. Unlike the elements passed to formatters, which
+// the parser reuses, nodes stay valid, and may be changed.
+type Node struct {
+	Kind     ElementKind
+	Text     string  // content of text elements, like STR and CODE, or the marker of a list item
+	URL      string  // target of a LINK or IMAGE
+	Title    string  // title of a LINK, IMAGE, or CONTAINER
+	Ref      string  // label of the reference of a LINK or IMAGE in reference style
+	Loose    bool    // set for loose lists and their items
+	Children []*Node // the blocks or inlines contained; the label of a LINK, IMAGE, or REFERENCE
+}
+
+// Parse parses a document, like Markdown, and returns its tree:
+// a node of kind LIST, whose children are the top-level blocks.
+// The body of a note is shared by the NOTE nodes of its references,
+// and of its definition, which has the label as Text.
+func (p *Parser) Parse(src io.Reader) *Node {
+	f := &treeOut{root: &Node{Kind: LIST}, p: p}
+	p.Markdown(src, f)
+	return f.root
+}
+
+type treeOut struct {
+	root  *Node
+	notes map[*element][]*Node /* Copies of note bodies. */
+	arena *nodeArena           /* Allocates nodes, if set. */
+	elems map[*element]*Node   /* Copies of the elements, if set. */
+	p     *Parser              /* Holds the parsed bodies of note definitions. */
+}
+
+func (f *treeOut) FormatBlock(tree *element) {
+	f.root.Children = append(f.root.Children, f.nodes(tree)...)
+}
+
+func (f *treeOut) Finish() {
+	f.notes = nil
+}
+
+// nodes returns copies of a list of elements.
+func (f *treeOut) nodes(list *element) (nodes []*Node) {
+	if f.arena != nil && list != nil {
+		k := 0
+		for l := list; l != nil; l = l.next {
+			k++
+		}
+		nodes = f.arena.children(k)
+	}
+	for ; list != nil; list = list.next {
+		n := f.node()
+		n.Kind, n.Text, n.Loose = ElementKind(list.key), list.contents.str, list.loose
+		if f.elems != nil {
+			f.elems[list] = n
+		}
+		children := list.children
+		if l := list.contents.link; l != nil {
+			n.URL, n.Title, n.Ref = l.url, l.title, l.ref
+			switch list.key {
+			case LINK, IMAGE, REFERENCE:
+				children = l.label
+			}
+		}
+		if list.key == NOTE && list.contents.str != "" && f.p != nil {
+			/* a definition, its children are the unparsed blocks */
+			children = nil
+			if note := f.p.yy.state.notes[list.contents.str]; note != nil {
+				children = note.children
+			}
+		}
+		if list.key == NOTE && children != nil {
+			body, ok := f.notes[children]
+			if !ok {
+				body = f.nodes(children)
+				if f.notes == nil {
+					f.notes = make(map[*element][]*Node)
+				}
+				f.notes[children] = body
+			}
+			n.Children = body
+		} else {
+			n.Children = f.nodes(children)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// node returns a new node.
+func (f *treeOut) node() *Node {
+	if f.arena != nil {
+		return f.arena.node()
+	}
+	return new(Node)
+}
+
+// Equal reports whether two trees are the same.
+func (n *Node) Equal(m *Node) bool {
+	if n == nil || m == nil {
+		return n == m
+	}
+	if n.Kind != m.Kind || n.Text != m.Text || n.URL != m.URL || n.Title != m.Title || n.Ref != m.Ref ||
+		n.Loose != m.Loose || len(n.Children) != len(m.Children) {
+		return false
+	}
+	for i, c := range n.Children {
+		if !c.Equal(m.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the tree as text, one node per line, indented
+// according to its depth.
+func (n *Node) String() string {
+	var b strings.Builder
+	for _, l := range n.lines(nil, 0) {
+		b.WriteString(l.String())
+	}
+	return b.String()
+}
+
+// A treeLine is a node, as written by Node.String.
+type treeLine struct {
+	depth int
+	text  string
+}
+
+func (l treeLine) String() string {
+	return strings.Repeat("  ", l.depth) + l.text + "\n"
+}
+
+func (n *Node) lines(list []treeLine, depth int) []treeLine {
+	if n == nil {
+		return list
+	}
+	s := n.Kind.String()
+	if n.Text != "" {
+		s += " " + strconv.Quote(n.Text)
+	}
+	if n.URL != "" {
+		s += " url=" + strconv.Quote(n.URL)
+	}
+	if n.Title != "" {
+		s += " title=" + strconv.Quote(n.Title)
+	}
+	if n.Ref != "" {
+		s += " ref=" + strconv.Quote(n.Ref)
+	}
+	if n.Loose {
+		s += " loose"
+	}
+	list = append(list, treeLine{depth, s})
+	for _, c := range n.Children {
+		list = c.lines(list, depth+1)
+	}
+	return list
+}
+
+// Diff returns the differences between two trees, or "", if they
+// are equal. Nodes are written as by Node.String; those only found
+// in a are marked by "-", those only in b by "+". Nodes present in
+// both trees are shown if they contain changes.
+func Diff(a, b *Node) string {
+	x, y := a.lines(nil, 0), b.lines(nil, 0)
+
+	/* the common prefix and suffix are not compared line by line */
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	if pre == len(x) && pre == len(y) {
+		return ""
+	}
+	mx, my := x[pre:len(x)-suf], y[pre:len(y)-suf]
+
+	/* lcs[i][j] is the length of the longest common subsequence of mx[i:] and my[j:] */
+	lcs := make([][]int, len(mx)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(my)+1)
+	}
+	for i := len(mx) - 1; i >= 0; i-- {
+		for j := len(my) - 1; j >= 0; j-- {
+			switch {
+			case mx[i] == my[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	var ctx []treeLine /* Enclosing nodes of the current one. */
+	var shown []bool   /* Whether they have been written. */
+	line := func(op string, l treeLine) {
+		if l.depth < len(ctx) {
+			ctx, shown = ctx[:l.depth], shown[:l.depth]
+		}
+		if op != " " {
+			for i, c := range ctx {
+				if !shown[i] {
+					out.WriteString(" " + c.String())
+					shown[i] = true
+				}
+			}
+			out.WriteString(op + l.String())
+		}
+		ctx = append(ctx, l)
+		shown = append(shown, op != " ")
+	}
+	for _, l := range x[:pre] {
+		line(" ", l)
+	}
+	i, j := 0, 0
+	for i < len(mx) || j < len(my) {
+		switch {
+		case i < len(mx) && j < len(my) && mx[i] == my[j]:
+			line(" ", mx[i])
+			i++
+			j++
+		case j == len(my) || i < len(mx) && lcs[i+1][j] >= lcs[i][j+1]:
+			line("-", mx[i])
+			i++
+		default:
+			line("+", my[j])
+			j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,102 @@
+package markdown
+
+// Text truncation that respects grapheme clusters
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Truncate returns the longest prefix of s consisting of at most
+// n grapheme clusters, and whether s has been shortened. Combining
+// sequences, emoji modifier and ZWJ sequences, flags, and CR LF
+// pairs are never split.
+func Truncate(s string, n int) (string, bool) {
+	i := 0
+	for ; n > 0 && i < len(s); n-- {
+		i += clusterLen(s[i:])
+	}
+	return s[:i], i < len(s)
+}
+
+// TruncateBytes returns the longest prefix of s that is at most
+// n bytes long and does not end within a grapheme cluster, and
+// whether s has been shortened.
+func TruncateBytes(s string, n int) (string, bool) {
+	i := 0
+	for i < len(s) {
+		c := clusterLen(s[i:])
+		if i+c > n {
+			break
+		}
+		i += c
+	}
+	return s[:i], i < len(s)
+}
+
+// GraphemeCount returns the number of grapheme clusters in s.
+func GraphemeCount(s string) (n int) {
+	for i := 0; i < len(s); n++ {
+		i += clusterLen(s[i:])
+	}
+	return
+}
+
+// clusterLen returns the length in bytes of the grapheme
+// cluster at the start of s. This is an approximation of the
+// extended grapheme clusters of Unicode Standard Annex #29
+// that covers the common cases without needing property tables.
+func clusterLen(s string) int {
+	r, i := utf8.DecodeRuneInString(s)
+	switch {
+	case r == '\r':
+		if len(s) > 1 && s[1] == '\n' {
+			return 2
+		}
+		return 1
+	case r < ' ' || r == utf8.RuneError && i == 1:
+		return i
+	case isRegionalIndicator(r):
+		if r2, w := utf8.DecodeRuneInString(s[i:]); isRegionalIndicator(r2) {
+			i += w
+		}
+	}
+	for i < len(s) {
+		r, w := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case isExtend(r):
+			i += w
+		case r == '\u200d': // zero width joiner
+			i += w
+			if i < len(s) {
+				_, w = utf8.DecodeRuneInString(s[i:])
+				i += w
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// isExtend reports whether r continues the preceding
+// grapheme cluster.
+func isExtend(r rune) bool {
+	switch {
+	case r < 0x300:
+		return false
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // variation selectors
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // emoji skin tone modifiers
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tags, as used by subdivision flags
+		return true
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}